@@ -0,0 +1,222 @@
+// Package gollm provides a high-level interface for interacting with Language Learning Models (LLMs).
+package gollm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yockii/gollm_cn/llm"
+)
+
+// ChatTarget is a provider and model a ChatSession can send its turns to.
+type ChatTarget struct {
+	// Name identifies the target in the session's switch records, e.g. "openai/gpt-4o"
+	Name string
+	// LLM answers the turns routed to the target
+	LLM LLM
+}
+
+// ChatSwitch records a change of the target serving a ChatSession, so a UI
+// can tell the user that the answers now come from another model.
+type ChatSwitch struct {
+	Turn   int       // Number of the turn, starting at 1, first served by To
+	From   string    // Name of the target that served the previous turn
+	To     string    // Name of the target that served this turn
+	Reason string    // Error that made From unavailable, or why the session returned
+	Time   time.Time // When the switch happened
+}
+
+// ChatSessionOption configures a ChatSession.
+type ChatSessionOption func(*ChatSession)
+
+// WithStickyCooldown sets how long a session that failed over stays on its
+// fallback target before trying its original target again. Defaults to 10
+// minutes.
+func WithStickyCooldown(cooldown time.Duration) ChatSessionOption {
+	return func(s *ChatSession) {
+		s.cooldown = cooldown
+	}
+}
+
+// ChatSession is a conversation routed over a fallback chain of targets. The
+// session sticks to the target that served it, since switching models
+// mid-conversation changes tone and formatting. It only fails over to the
+// next target of the chain when the current one is unavailable: the request
+// could not be sent, the API returned an error, or the rate limit was hit.
+// Other errors, such as invalid input, are returned without failing over.
+// After a failover the session returns to its original target once the
+// cool-down has elapsed and the original target answers again.
+//
+// The conversation history is kept in a single llm.Memory shared by all
+// targets, so a fallback target continues the same conversation.
+type ChatSession struct {
+	targets  []ChatTarget
+	memory   *llm.Memory
+	cooldown time.Duration
+	now      func() time.Time
+
+	mutex      sync.Mutex
+	current    int          // Index of the target serving the session
+	failedOver time.Time    // When the session last left or failed to regain targets[0]
+	turns      int          // Number of turns answered
+	switches   []ChatSwitch // Target changes, oldest first
+}
+
+// NewChatSession creates a session that prefers targets in the given order,
+// starting with targets[0], and keeps up to maxTokens of history. Tokens are
+// counted with the tokenizer of the first target's model.
+//
+// Returns:
+//   - The session
+//   - Error if no targets are given or the memory cannot be created
+//
+// Example:
+//
+//	session, err := NewChatSession([]ChatTarget{
+//	    {Name: "openai/gpt-4o", LLM: openAI},
+//	    {Name: "anthropic/claude-3-5-sonnet", LLM: claude},
+//	}, 4000, WithStickyCooldown(5*time.Minute))
+//
+//	answer, err := session.Send(ctx, "你好")
+//	for _, s := range session.Switches() {
+//	    fmt.Printf("第 %d 轮起由 %s 回答（%s）\n", s.Turn, s.To, s.Reason)
+//	}
+func NewChatSession(targets []ChatTarget, maxTokens int, opts ...ChatSessionOption) (*ChatSession, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("chat session needs at least one target")
+	}
+	memory, err := llm.NewMemory(maxTokens, targets[0].LLM.GetModel(), targets[0].LLM.GetLogger())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat memory: %w", err)
+	}
+	s := &ChatSession{
+		targets:  append([]ChatTarget(nil), targets...),
+		memory:   memory,
+		cooldown: 10 * time.Minute,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Send adds message to the conversation and returns the answer of the
+// session's target, failing over along the chain while targets are
+// unavailable. The answer is added to the conversation as well. If no target
+// answers, the conversation is left as it was, so the message can be sent
+// again. Turns of a session are sent one at a time.
+//
+// Returns:
+//   - The answer
+//   - The last target's error if no target is available
+//   - The target's error if it fails for another reason than being unavailable
+func (s *ChatSession) Send(ctx context.Context, message string, opts ...llm.GenerateOption) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	history := s.memory.GetMessages()
+	s.memory.Add("user", message)
+	prompt := llm.NewPrompt(s.memory.GetPrompt())
+
+	returning := s.current != 0 && s.now().Sub(s.failedOver) >= s.cooldown
+	var lastErr error
+	for _, i := range s.routeOrder(returning) {
+		response, err := s.targets[i].LLM.Generate(ctx, prompt, opts...)
+		if err == nil {
+			s.turns++
+			s.moveTo(i, lastErr)
+			s.memory.Add("assistant", response)
+			return response, nil
+		}
+		if ctx.Err() != nil || !isUnavailable(err) {
+			s.memory.SetMessages(history)
+			return "", err
+		}
+		s.targets[i].LLM.GetLogger().Warn("Chat target unavailable", "target", s.targets[i].Name, "error", err)
+		lastErr = err
+		if i == 0 && returning {
+			// The original target is still unavailable; wait another cool-down.
+			s.failedOver = s.now()
+		}
+	}
+	s.memory.SetMessages(history)
+	return "", fmt.Errorf("no chat target available: %w", lastErr)
+}
+
+// routeOrder returns the indexes of the targets to try for a turn: the
+// current target first, or the original target when the session is returning
+// to it, followed by the rest of the chain in order.
+func (s *ChatSession) routeOrder(returning bool) []int {
+	first := s.current
+	if returning {
+		first = 0
+	}
+	order := []int{first}
+	for i := range s.targets {
+		if i != first {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// moveTo makes target i serve the session and records the switch, if any.
+// cause is the error of the last unavailable target, nil when the session
+// returns to its original target after the cool-down.
+func (s *ChatSession) moveTo(i int, cause error) {
+	if i == s.current {
+		return
+	}
+	reason := "cool-down elapsed, original target available again"
+	if cause != nil {
+		reason = cause.Error()
+	}
+	s.switches = append(s.switches, ChatSwitch{
+		Turn:   s.turns,
+		From:   s.targets[s.current].Name,
+		To:     s.targets[i].Name,
+		Reason: reason,
+		Time:   s.now(),
+	})
+	if s.current == 0 {
+		s.failedOver = s.now()
+	}
+	s.current = i
+}
+
+// Target returns the name of the target serving the session.
+func (s *ChatSession) Target() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.targets[s.current].Name
+}
+
+// Switches returns the target changes of the session, oldest first.
+func (s *ChatSession) Switches() []ChatSwitch {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]ChatSwitch(nil), s.switches...)
+}
+
+// Messages returns a copy of the conversation history.
+func (s *ChatSession) Messages() []llm.MemoryMessage {
+	return s.memory.GetMessages()
+}
+
+// isUnavailable reports whether err means the target could not answer at
+// all, as opposed to rejecting the request.
+func isUnavailable(err error) bool {
+	var llmErr *llm.LLMError
+	if !errors.As(err, &llmErr) {
+		return false
+	}
+	switch llmErr.Type {
+	case llm.ErrorTypeRequest, llm.ErrorTypeAPI, llm.ErrorTypeRateLimit, llm.ErrorTypeAuthentication:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package gollm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/llm"
+)
+
+func newTestChatSession(t *testing.T, targets ...*fakeLLM) (*ChatSession, *time.Time) {
+	chain := make([]ChatTarget, len(targets))
+	for i, target := range targets {
+		chain[i] = ChatTarget{Name: target.model, LLM: target}
+	}
+	session, err := NewChatSession(chain, 1000, WithStickyCooldown(time.Minute))
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	session.now = func() time.Time { return now }
+	return session, &now
+}
+
+func TestChatSession_StaysOnTarget(t *testing.T) {
+	primary := &fakeLLM{model: "primary", response: "主模型"}
+	fallback := &fakeLLM{model: "fallback", response: "备用模型"}
+	session, _ := newTestChatSession(t, primary, fallback)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		answer, err := session.Send(ctx, "你好")
+		require.NoError(t, err)
+		assert.Equal(t, "主模型", answer)
+	}
+	assert.Equal(t, 3, primary.callCount())
+	assert.Equal(t, 0, fallback.callCount())
+	assert.Empty(t, session.Switches())
+	assert.Len(t, session.Messages(), 6)
+}
+
+func TestChatSession_FailoverIsSticky(t *testing.T) {
+	primary := &fakeLLM{model: "primary", response: "主模型"}
+	fallback := &fakeLLM{model: "fallback", response: "备用模型"}
+	session, now := newTestChatSession(t, primary, fallback)
+	ctx := context.Background()
+
+	_, err := session.Send(ctx, "第一轮")
+	require.NoError(t, err)
+
+	primary.err = llm.NewLLMError(llm.ErrorTypeAPI, "API error: status code 503", nil)
+	answer, err := session.Send(ctx, "第二轮")
+	require.NoError(t, err)
+	assert.Equal(t, "备用模型", answer)
+	assert.Equal(t, "fallback", session.Target())
+
+	switches := session.Switches()
+	require.Len(t, switches, 1)
+	assert.Equal(t, 2, switches[0].Turn)
+	assert.Equal(t, "primary", switches[0].From)
+	assert.Equal(t, "fallback", switches[0].To)
+	assert.Contains(t, switches[0].Reason, "503")
+
+	// The primary recovers, but the session stays on the fallback until the
+	// cool-down has elapsed.
+	primary.err = nil
+	*now = now.Add(30 * time.Second)
+	answer, err = session.Send(ctx, "第三轮")
+	require.NoError(t, err)
+	assert.Equal(t, "备用模型", answer)
+	assert.Equal(t, 2, primary.callCount())
+
+	*now = now.Add(time.Minute)
+	answer, err = session.Send(ctx, "第四轮")
+	require.NoError(t, err)
+	assert.Equal(t, "主模型", answer)
+	assert.Equal(t, "primary", session.Target())
+
+	switches = session.Switches()
+	require.Len(t, switches, 2)
+	assert.Equal(t, 4, switches[1].Turn)
+	assert.Equal(t, "fallback", switches[1].From)
+	assert.Contains(t, switches[1].Reason, "cool-down")
+}
+
+func TestChatSession_ReturnWaitsWhileOriginalUnavailable(t *testing.T) {
+	primary := &fakeLLM{model: "primary", err: llm.NewLLMError(llm.ErrorTypeRateLimit, "rate limit", nil)}
+	fallback := &fakeLLM{model: "fallback", response: "备用模型"}
+	session, now := newTestChatSession(t, primary, fallback)
+	ctx := context.Background()
+
+	_, err := session.Send(ctx, "第一轮")
+	require.NoError(t, err)
+	assert.Equal(t, 1, primary.callCount())
+
+	*now = now.Add(2 * time.Minute)
+	_, err = session.Send(ctx, "第二轮")
+	require.NoError(t, err)
+	assert.Equal(t, 2, primary.callCount(), "original target retried after the cool-down")
+	assert.Len(t, session.Switches(), 1, "no switch recorded for a failed return")
+
+	_, err = session.Send(ctx, "第三轮")
+	require.NoError(t, err)
+	assert.Equal(t, 2, primary.callCount(), "failed return starts another cool-down")
+}
+
+func TestChatSession_DoesNotFailOverOnInvalidInput(t *testing.T) {
+	primary := &fakeLLM{model: "primary", err: llm.NewLLMError(llm.ErrorTypeInvalidInput, "prompt too long", nil)}
+	fallback := &fakeLLM{model: "fallback", response: "备用模型"}
+	session, _ := newTestChatSession(t, primary, fallback)
+
+	_, err := session.Send(context.Background(), "你好")
+	require.Error(t, err)
+	assert.Equal(t, 0, fallback.callCount())
+	assert.Equal(t, "primary", session.Target())
+}
+
+func TestChatSession_AllTargetsUnavailable(t *testing.T) {
+	unavailable := llm.NewLLMError(llm.ErrorTypeRequest, "failed to send request", nil)
+	session, _ := newTestChatSession(t,
+		&fakeLLM{model: "primary", err: unavailable},
+		&fakeLLM{model: "fallback", err: unavailable},
+	)
+
+	_, err := session.Send(context.Background(), "你好")
+	assert.ErrorContains(t, err, "no chat target available")
+	assert.Empty(t, session.Switches())
+}
+
+func TestChatSession_FailedTurnIsRolledBack(t *testing.T) {
+	primary := &fakeLLM{model: "primary", err: llm.NewLLMError(llm.ErrorTypeInvalidInput, "prompt too long", nil)}
+	session, _ := newTestChatSession(t, primary)
+	ctx := context.Background()
+
+	_, err := session.Send(ctx, "你好")
+	require.Error(t, err)
+	assert.Empty(t, session.Messages(), "the failed turn is not kept")
+
+	primary.err = nil
+	primary.response = "你好！"
+	answer, err := session.Send(ctx, "你好")
+	require.NoError(t, err)
+	assert.Equal(t, "你好！", answer)
+	assert.Equal(t, []string{"user: 你好", "assistant: 你好！"}, chatTurns(session))
+
+	primary.err = llm.NewLLMError(llm.ErrorTypeAPI, "API error: status code 503", nil)
+	_, err = session.Send(ctx, "在吗")
+	assert.ErrorContains(t, err, "no chat target available")
+	assert.Equal(t, []string{"user: 你好", "assistant: 你好！"}, chatTurns(session))
+}
+
+// chatTurns returns the messages of session as "role: content".
+func chatTurns(session *ChatSession) []string {
+	var turns []string
+	for _, m := range session.Messages() {
+		turns = append(turns, m.Role+": "+m.Content)
+	}
+	return turns
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// batchInput is a line of the -batch file.
+type batchInput struct {
+	ID     string `json:"id"`
+	Prompt string `json:"prompt"`
+}
+
+// batchOutput is a line written by -batch.
+type batchOutput struct {
+	ID       string `json:"id"`
+	Response string `json:"response"`
+	Error    string `json:"error,omitempty"`
+}
+
+// readBatch parses JSON lines of batchInput, skipping blank lines.
+func readBatch(in io.Reader) ([]batchInput, error) {
+	var inputs []batchInput
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxPromptBytes)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var input batchInput
+		if err := json.Unmarshal([]byte(text), &input); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", line, err)
+		}
+		if strings.TrimSpace(input.Prompt) == "" {
+			return nil, fmt.Errorf("line %d: prompt is empty", line)
+		}
+		inputs = append(inputs, input)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch: %w", err)
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("batch has no prompts")
+	}
+	return inputs, nil
+}
+
+// batchWriter writes the results of a batch as JSON lines. In order, a
+// result waits until the results of the prompts before it are written.
+type batchWriter struct {
+	mu        sync.Mutex
+	enc       *json.Encoder
+	inputs    []batchInput
+	unordered bool
+	pending   map[int]gollm.BatchResult
+	written   []bool
+	next      int // Index of the next result to write in order
+	err       error
+}
+
+// add writes result, or keeps it until it is its turn. Only the first
+// result of each prompt is kept.
+func (w *batchWriter) add(result gollm.BatchResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, ok := w.pending[result.Index]; ok || w.written[result.Index] {
+		return
+	}
+	if w.unordered {
+		w.write(result)
+		return
+	}
+	w.pending[result.Index] = result
+	for {
+		next, ok := w.pending[w.next]
+		if !ok {
+			return
+		}
+		delete(w.pending, w.next)
+		w.write(next)
+		w.next++
+	}
+}
+
+func (w *batchWriter) write(result gollm.BatchResult) {
+	w.written[result.Index] = true
+	output := batchOutput{ID: w.inputs[result.Index].ID, Response: result.Response}
+	if result.Err != nil {
+		output.Error = result.Err.Error()
+	}
+	if err := w.enc.Encode(output); err != nil && w.err == nil {
+		w.err = fmt.Errorf("failed to write result: %w", err)
+	}
+}
+
+// runBatch runs the prompts of the JSON lines in in as a batch, with
+// SubmitBatch, and writes a line with the response or error of each to out,
+// in input order unless unordered. Prompts run as individual calls, at most
+// concurrency at once, unless native asks for the provider's batch API.
+// Results are written as soon as they are known. A prompt without a result,
+// because the batch failed as a whole, is written with the batch error, which
+// is also returned. The batch ID of a native batch is written to stderr.
+func runBatch(ctx context.Context, l gollm.LLM, in io.Reader, out, stderr io.Writer, concurrency int, unordered, native bool) error {
+	inputs, err := readBatch(in)
+	if err != nil {
+		return err
+	}
+	prompts := make([]*gollm.Prompt, len(inputs))
+	for i, input := range inputs {
+		prompts[i] = gollm.NewPrompt(input.Prompt)
+	}
+
+	w := &batchWriter{
+		enc:       json.NewEncoder(out),
+		inputs:    inputs,
+		unordered: unordered,
+		pending:   make(map[int]gollm.BatchResult),
+		written:   make([]bool, len(inputs)),
+	}
+	w.enc.SetEscapeHTML(false)
+	opts := []gollm.BatchOption{
+		gollm.WithBatchConcurrency(concurrency),
+		gollm.WithBatchResultHandler(w.add),
+	}
+	if !native {
+		opts = append(opts, gollm.WithBatchIndividualCalls())
+	}
+	job, err := gollm.SubmitBatch(ctx, l, prompts, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to submit batch: %w", err)
+	}
+	if job.ID != "" {
+		fmt.Fprintf(stderr, "Batch %s submitted with %d prompts\n", job.ID, len(prompts))
+	}
+	results, batchErr := job.Wait(ctx)
+	for _, result := range results {
+		w.add(result)
+	}
+	if batchErr != nil {
+		for i := range inputs {
+			w.add(gollm.BatchResult{Index: i, Err: batchErr})
+		}
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if batchErr != nil {
+		return fmt.Errorf("batch failed: %w", batchErr)
+	}
+	return nil
+}
+
+// batchFromFile runs the batch of the file at path, or of stdin for "-".
+func batchFromFile(path string, l gollm.LLM, concurrency int, unordered, native bool) error {
+	in := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to read batch file: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	return runBatch(context.Background(), l, in, os.Stdout, os.Stderr, concurrency, unordered, native)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// echoLLM answers each prompt with the prompt itself, failing prompts that
+// contain "fail". Calls for prompts in wait block until release is closed.
+type echoLLM struct {
+	gollm.LLM
+	wait    map[string]bool
+	release chan struct{}
+}
+
+func (l *echoLLM) Generate(ctx context.Context, prompt *gollm.Prompt, opts ...gollm.GenerateOption) (string, error) {
+	if l.wait[prompt.Input] {
+		<-l.release
+	}
+	if strings.Contains(prompt.Input, "fail") {
+		return "", errors.New("rate limited")
+	}
+	return "echo: " + prompt.Input, nil
+}
+
+func (l *echoLLM) GetLogger() utils.Logger {
+	return utils.NewSlogLogger(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestRunBatch(t *testing.T) {
+	in := `{"id": "a", "prompt": "first"}
+
+{"id": "b", "prompt": "please fail"}
+{"id": "c", "prompt": "<third>"}
+`
+	var out, stderr bytes.Buffer
+	err := runBatch(context.Background(), &echoLLM{}, strings.NewReader(in), &out, &stderr, 2, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"a","response":"echo: first"}
+{"id":"b","response":"","error":"rate limited"}
+{"id":"c","response":"echo: <third>"}
+`, out.String())
+	assert.Empty(t, stderr.String(), "batches of individual calls have no batch ID")
+}
+
+func TestRunBatch_Order(t *testing.T) {
+	in := "{\"id\": \"slow\", \"prompt\": \"slow\"}\n{\"id\": \"fast\", \"prompt\": \"fast\"}\n"
+	for _, unordered := range []bool{false, true} {
+		l := &echoLLM{wait: map[string]bool{"slow": true}, release: make(chan struct{})}
+		pr, pw := io.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- runBatch(context.Background(), l, strings.NewReader(in), pw, io.Discard, 2, unordered, false)
+			pw.Close()
+		}()
+
+		if unordered {
+			// The fast result is written while the slow prompt still runs
+			line := make([]byte, len(`{"id":"fast","response":"echo: fast"}`+"\n"))
+			_, err := io.ReadFull(pr, line)
+			require.NoError(t, err)
+			assert.Equal(t, `{"id":"fast","response":"echo: fast"}`+"\n", string(line))
+			close(l.release)
+			rest, _ := io.ReadAll(pr)
+			assert.Equal(t, `{"id":"slow","response":"echo: slow"}`+"\n", string(rest))
+		} else {
+			close(l.release)
+			all, _ := io.ReadAll(pr)
+			assert.Equal(t, `{"id":"slow","response":"echo: slow"}`+"\n"+`{"id":"fast","response":"echo: fast"}`+"\n", string(all))
+		}
+		require.NoError(t, <-done)
+	}
+}
+
+func TestRunBatch_InvalidInput(t *testing.T) {
+	run := func(in string) error {
+		return runBatch(context.Background(), &echoLLM{}, strings.NewReader(in), io.Discard, io.Discard, 1, false, false)
+	}
+	assert.ErrorContains(t, run(`{"id": "a", "prompt": "hi"}`+"\n{oops}\n"), "line 2: invalid JSON")
+	assert.ErrorContains(t, run("\n"+`{"id": "a", "prompt": " "}`), "line 2: prompt is empty")
+	assert.ErrorContains(t, run("\n\n"), "batch has no prompts")
+}
+
+func TestRunBatch_OpenAI(t *testing.T) {
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+			t.Errorf("unexpected request %s %s; the batch API is opt-in", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mutex.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mutex.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+		w.Write([]byte(`{"choices": [{"message": {"content": "好的"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+	l, err := gollm.NewLLM(
+		gollm.SetProvider("openai"),
+		gollm.SetModel("gpt-4o-mini"),
+		gollm.SetAPIKey("sk-test-0123456789abcdefghij"),
+		gollm.SetEndpoint(server.URL),
+		gollm.SetMaxRetries(0),
+		gollm.SetLogLevel(gollm.LogLevelOff),
+	)
+	require.NoError(t, err)
+
+	var in strings.Builder
+	for i := 0; i < 6; i++ {
+		fmt.Fprintf(&in, "{\"id\": \"%d\", \"prompt\": \"问题 %d\"}\n", i, i)
+	}
+	var out, stderr bytes.Buffer
+	require.NoError(t, runBatch(context.Background(), l, strings.NewReader(in.String()), &out, &stderr, 2, false, false))
+	assert.Equal(t, 6, strings.Count(out.String(), `"response":"好的"`))
+	assert.Empty(t, stderr.String(), "no native batch is submitted")
+	assert.Equal(t, 2, maxInFlight, "-concurrency bounds the calls")
+}
@@ -5,21 +5,29 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
 	gollm "github.com/yockii/gollm_cn"
 	"github.com/yockii/gollm_cn/optimizer"
 	"github.com/yockii/gollm_cn/presets"
+	"github.com/yockii/gollm_cn/progress"
 	"github.com/yockii/gollm_cn/utils"
 )
 
 func main() {
+	// "gollm version" reports the build and checks the configuration
+	if len(os.Args) > 1 && os.Args[1] == "version" {
+		os.Exit(runVersion(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Existing flags
-	promptType := flag.String("type", "raw", "提示类型 (raw, qa, cot, summarize, optimize)")
+	promptType := flag.String("type", "raw", "提示类型 (raw, qa, cot, summarize, correct, optimize)")
 	verbose := flag.Bool("verbose", false, "显示详细输出，包括完整提示")
-	provider := flag.String("provider", "", "LLM 提供者 (anthropic, openai, groq, mistral, ollama, cohere)")
+	provider := flag.String("provider", "", "LLM 提供者 (anthropic, openai, groq, mistral, ollama, cohere, gemini)")
 	model := flag.String("model", "", "LLM 模型")
 	temperature := flag.Float64("temperature", -1, "LLM 温度")
 	maxTokens := flag.Int("max-tokens", 0, "LLM 最大 tokens")
@@ -29,14 +37,57 @@ func main() {
 	retryDelay := flag.Duration("retry-delay", time.Second*2, "重试之间的延迟")
 	debugLevel := flag.String("debug-level", "warn", "调试级别 (debug, info, warn, error)")
 	outputFormat := flag.String("output-format", "", "结构化响应的输出格式 (json)")
+	templateFile := flag.String("template", "", "从模板文件（.tmpl 或 .yaml）生成提示，变量用 -var 指定；仅用于 -type raw")
+	vars := templateVars{}
+	flag.Var(vars, "var", "模板变量，格式为 key=value，可重复指定")
+	promptFile := flag.String("prompt-file", "", "从文件读取提示，- 表示从标准输入读取；也可以用 - 作为参数，或在没有参数时通过管道输入")
+	summarizeStrategy := flag.String("summarize-strategy", "map-reduce", "超过一个分块的长文本的总结策略 (map-reduce, refine)，用于 -type summarize")
 
 	// New flags for prompt optimization
 	optimizeGoal := flag.String("optimize-goal", "提高提示的清晰度和有效性", "优化目标")
 	optimizeIterations := flag.Int("optimize-iterations", 5, "优化迭代次数")
 	optimizeMemory := flag.Int("optimize-memory", 2, "记住的先前迭代次数")
+	publish := flag.String("publish", "", "将优化结果发布为模板版本，格式为 name@version（仅用于 -type optimize）")
+	templateDir := flag.String("template-dir", "templates", "-publish 写入模板文件的目录")
+
+	// Progress of long-running presets
+	progressStyle := flag.String("progress", "", "长时间任务的进度显示方式 (none, plain, rich)，默认终端使用 rich，否则使用 plain")
+	inputPrice := flag.Float64("input-price", 0, "每百万输入 tokens 的价格（美元），用于在进度中显示费用")
+	outputPrice := flag.Float64("output-price", 0, "每百万输出 tokens 的价格（美元），用于在进度中显示费用")
+
+	// Interactive conversation
+	replMode := flag.Bool("repl", false, "启动交互式对话，流式输出回答并保留对话历史；支持 /reset、/system <text>、/save <file>，Ctrl-C 取消当前回答")
+
+	// JSON-lines batches
+	batchFile := flag.String("batch", "", "以批处理方式运行 JSONL 文件中的提示（每行 {\"id\", \"prompt\"}），每行输出 {\"id\", \"response\", \"error\"}；- 表示从标准输入读取")
+	concurrency := flag.Int("concurrency", 5, "-batch 同时运行的提示数")
+	nativeBatch := flag.Bool("native-batch", false, "-batch 使用提供者的批处理 API（如 OpenAI），费用减半但可能需要长达 24 小时，且忽略 -concurrency")
+	unordered := flag.Bool("unordered", false, "-batch 按完成顺序而不是输入顺序输出结果")
+
+	// Template linting
+	lintDir := flag.String("lint", "", "检查该目录下的模板（*.tmpl 及含 template 键的 *.yaml/*.yml；缺失的部分模板、缺失的基础模板、循环引用）后退出")
 
 	flag.Parse()
 
+	// Linting only inspects template files and needs no LLM client
+	if *lintDir != "" {
+		os.Exit(lintTemplates(*lintDir, os.Stdout, os.Stderr))
+	}
+
+	// Reject a malformed -publish before spending an optimization run on it
+	if *publish != "" {
+		if _, _, err := parseTemplateRef(*publish); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	reporter, err := newProgressWriter(*progressStyle, os.Stderr, *inputPrice, *outputPrice)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Prepare configuration options
 	configOpts := prepareConfigOptions(provider, model, temperature, maxTokens, timeout, apiKey, maxRetries, retryDelay, debugLevel)
 
@@ -47,13 +98,47 @@ func main() {
 		os.Exit(1)
 	}
 
-	if len(flag.Args()) < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <prompt>\n", os.Args[0])
+	if *replMode {
+		// Ctrl-C cancels the answer being generated instead of exiting
+		interrupts := make(chan os.Signal, 1)
+		signal.Notify(interrupts, os.Interrupt)
+		if err := runREPL(context.Background(), llmClient, os.Stdin, os.Stdout, interrupts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *batchFile != "" {
+		if err := batchFromFile(*batchFile, llmClient, *concurrency, *unordered, *nativeBatch); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	var templated *gollm.Prompt
+	if *templateFile != "" {
+		templated, err = templatePrompt(*templateFile, vars, flag.Args(), *promptType)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if templated == nil && len(flag.Args()) < 1 && *promptFile == "" && !isPiped(os.Stdin) {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] <prompt | ->\n       %s -prompt-file <path> [flags]\n       %s -template <file> [-var key=value]... [flags]\n       %s -repl [flags]\n       %s -batch <file.jsonl | -> [flags]\n       %s version [-check] [-json]\n", os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0], os.Args[0])
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
-	rawPrompt := strings.Join(flag.Args(), " ")
+	var rawPrompt string
+	if templated != nil {
+		rawPrompt = templated.Input
+	} else if rawPrompt, err = readPrompt(flag.Args(), *promptFile, os.Stdin); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	ctx := context.Background()
 
 	var response string
@@ -65,7 +150,25 @@ func main() {
 	case "cot":
 		response, err = presets.ChainOfThought(ctx, llmClient, rawPrompt)
 	case "summarize":
-		response, err = presets.Summarize(ctx, llmClient, rawPrompt)
+		// Texts of a single chunk keep the one-call Summarize preset
+		if presets.SummaryChunks(rawPrompt) > 1 {
+			response, err = presets.SummarizeLong(ctx, llmClient, rawPrompt,
+				presets.WithStrategy(presets.SummaryStrategy(*summarizeStrategy)),
+				presets.WithSummaryProgress(reporter),
+			)
+		} else {
+			response, err = presets.Summarize(ctx, llmClient, rawPrompt)
+		}
+	case "correct":
+		var result *presets.CorrectionResult
+		result, err = presets.Correct(ctx, llmClient, rawPrompt)
+		if err == nil {
+			// Show what changed, or the text itself if nothing did
+			response = result.UnifiedDiff()
+			if response == "" {
+				response = result.Corrected
+			}
+		}
 	case "optimize":
 		optimizer := optimizer.NewPromptOptimizer(
 			llmClient,
@@ -77,13 +180,23 @@ func main() {
 			optimizer.WithIterations(*optimizeIterations),
 			optimizer.WithMemorySize(*optimizeMemory),
 		)
-		optimizedPrompt, err := optimizer.OptimizePrompt(ctx)
+		optimizeCtx, recorder := gollm.WithUsageRecorder(ctx)
+		tracker := progress.NewTracker(reporter, "optimize", *optimizeIterations)
+		optimizer.WithIterationCallback(iterationProgress(tracker, recorder))
+		optimizedPrompt, err := optimizer.OptimizePrompt(optimizeCtx)
+		tracker.Finish()
+		if err == nil && *publish != "" {
+			err = publishOptimized(*templateDir, *publish, optimizer.GetOptimizationHistory(), optimizedPrompt)
+		}
 		if err == nil {
 			response = optimizedPrompt.Input
 			fullPrompt = fmt.Sprintf("Initial Prompt: %s\nOptimization Goal: %s\nMemory Size: %d", rawPrompt, *optimizeGoal, *optimizeMemory)
 		}
 	default:
-		prompt := gollm.NewPrompt(rawPrompt)
+		prompt := templated
+		if prompt == nil {
+			prompt = gollm.NewPrompt(rawPrompt)
+		}
 		if *outputFormat == "json" {
 			prompt.Apply(gollm.WithOutput("Please provide your response in JSON format."))
 		}
@@ -99,6 +212,56 @@ func main() {
 	printResponse(*verbose, *promptType, fullPrompt, rawPrompt, response, *outputFormat)
 }
 
+// maxPromptBytes is the size limit of prompts read from a file or stdin.
+const maxPromptBytes = 64 << 20
+
+// readPrompt returns the prompt of the command line: the contents of
+// promptFile if set, stdin if promptFile or the only argument is "-" or there
+// are no arguments, and the arguments joined by spaces otherwise. Surrounding
+// whitespace and a UTF-8 byte order mark are removed from prompts read from a
+// file or stdin.
+func readPrompt(args []string, promptFile string, stdin io.Reader) (string, error) {
+	if promptFile != "" && len(args) > 0 {
+		return "", fmt.Errorf("-prompt-file cannot be combined with prompt arguments")
+	}
+	var source string
+	var r io.Reader
+	switch {
+	case promptFile == "-" || (promptFile == "" && (len(args) == 0 || len(args) == 1 && args[0] == "-")):
+		source, r = "stdin", stdin
+	case promptFile != "":
+		f, err := os.Open(promptFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read prompt file: %w", err)
+		}
+		defer f.Close()
+		source, r = promptFile, f
+	default:
+		return strings.Join(args, " "), nil
+	}
+
+	// Read one byte more than the limit to tell a prompt of exactly the limit
+	// from a larger one
+	data, err := io.ReadAll(io.LimitReader(r, maxPromptBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt from %s: %w", source, err)
+	}
+	if len(data) > maxPromptBytes {
+		return "", fmt.Errorf("prompt from %s is larger than %d MiB", source, maxPromptBytes>>20)
+	}
+	prompt := strings.TrimSpace(strings.TrimPrefix(string(data), "\ufeff"))
+	if prompt == "" {
+		return "", fmt.Errorf("prompt from %s is empty", source)
+	}
+	return prompt, nil
+}
+
+// isPiped reports whether f is a pipe or a file rather than a terminal.
+func isPiped(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
 func prepareConfigOptions(provider, model *string, temperature *float64, maxTokens *int, timeout *time.Duration, apiKey *string, maxRetries *int, retryDelay *time.Duration, debugLevel *string) []gollm.ConfigOption {
 	var configOpts []gollm.ConfigOption
 
@@ -137,7 +300,10 @@ func printResponse(verbose bool, promptType, fullPrompt, rawPrompt, response, ou
 
 	if outputFormat == "json" {
 		var jsonResponse interface{}
-		err := json.Unmarshal([]byte(response), &jsonResponse)
+		repaired, err := utils.RepairJSON(response)
+		if err == nil {
+			err = json.Unmarshal([]byte(repaired), &jsonResponse)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error parsing JSON response: %v\n", err)
 			fmt.Println(response) // Print raw response if JSON parsing fails
@@ -162,3 +328,91 @@ func getLogLevel(level string) gollm.LogLevel {
 		return gollm.LogLevelWarn
 	}
 }
+
+// lintTemplates loads the templates under dir into a template registry, as
+// TemplateRegistry.LoadDir does, and reports missing partials, missing base templates, cycles and invalid child
+// templates. It returns the process exit code: 0 when no issues are found,
+// 1 otherwise.
+func lintTemplates(dir string, stdout, stderr io.Writer) int {
+	registry := gollm.NewTemplateRegistry()
+	if err := registry.LoadDir(dir); err != nil {
+		fmt.Fprintf(stderr, "Error loading templates: %v\n", err)
+		return 1
+	}
+	issues := registry.Lint()
+	for _, issue := range issues {
+		fmt.Fprintln(stderr, issue.String())
+	}
+	if len(issues) > 0 {
+		return 1
+	}
+	fmt.Fprintf(stdout, "%d templates OK\n", len(registry.Names()))
+	return 0
+}
+
+// newProgressWriter returns the reporter for the -progress style, which
+// defaults to rich on a terminal and plain otherwise. Prices are in dollars
+// per million tokens; a zero price leaves the cost out.
+func newProgressWriter(style string, stderr *os.File, inputPrice, outputPrice float64) (*progress.Writer, error) {
+	if style == "" {
+		style = string(progress.StylePlain)
+		if info, err := stderr.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			style = string(progress.StyleRich)
+		}
+	}
+	parsed, err := progress.ParseStyle(style)
+	if err != nil {
+		return nil, err
+	}
+	return progress.NewWriter(stderr, parsed, progress.WithPrices(inputPrice, outputPrice)), nil
+}
+
+// iterationProgress returns an optimizer callback that reports each iteration
+// to tracker with the tokens recorded since the previous one.
+func iterationProgress(tracker *progress.Tracker, recorder *gollm.UsageRecorder) optimizer.IterationCallback {
+	var previous gollm.TokenUsage
+	return func(iteration int, entry optimizer.OptimizationEntry) {
+		usage := recorder.Usage()
+		tracker.ItemDone(usage.InputTokens-previous.InputTokens, usage.OutputTokens-previous.OutputTokens)
+		previous = usage
+	}
+}
+
+// parseTemplateRef splits a "name@version" reference.
+func parseTemplateRef(ref string) (name, version string, err error) {
+	name, version, ok := strings.Cut(ref, "@")
+	if !ok || name == "" || version == "" || strings.Contains(version, "@") {
+		return "", "", fmt.Errorf("invalid template reference %q, expected name@version", ref)
+	}
+	return name, version, nil
+}
+
+// publishOptimized publishes the history entry of the optimized prompt as the
+// template version ref and writes it to dir, where later runs load it with
+// LoadDir. Versions already in dir are never overwritten.
+func publishOptimized(dir, ref string, history []optimizer.OptimizationEntry, optimized *gollm.Prompt) error {
+	name, version, err := parseTemplateRef(ref)
+	if err != nil {
+		return err
+	}
+	var winner *optimizer.OptimizationEntry
+	for i := range history {
+		if history[i].Prompt == optimized {
+			winner = &history[i]
+		}
+	}
+	if winner == nil {
+		return fmt.Errorf("optimized prompt not found in optimization history")
+	}
+
+	registry := gollm.NewTemplateRegistry()
+	if _, err := os.Stat(dir); err == nil {
+		if err := registry.LoadDir(dir); err != nil {
+			return fmt.Errorf("failed to load templates: %w", err)
+		}
+	}
+	if err := optimizer.PublishResult(registry, name, version, *winner); err != nil {
+		return err
+	}
+	return registry.WriteFile(dir, gollm.VersionedTemplateName(name, version))
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/optimizer"
+	"github.com/yockii/gollm_cn/progress"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestLintTemplates(t *testing.T) {
+	t.Run("valid templates", func(t *testing.T) {
+		dir := writeTemplates(t, map[string]string{
+			"partials/safety.tmpl": "安全",
+			"base.tmpl":            "{{template \"partials/safety\" .}}{{block \"task\" .}}{{end}}",
+			"child.tmpl":           "{{/* extends \"base\" */}}{{define \"task\"}}T{{end}}",
+			"settings.yaml":        "provider: openai\n",
+		})
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, lintTemplates(dir, &stdout, &stderr))
+		assert.Contains(t, stdout.String(), "3 templates OK")
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("missing partial", func(t *testing.T) {
+		dir := writeTemplates(t, map[string]string{
+			"qa.tmpl": "{{template \"partials/missing\" .}}",
+		})
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 1, lintTemplates(dir, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "partials/missing")
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 1, lintTemplates(filepath.Join(t.TempDir(), "nope"), &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "Error loading templates")
+	})
+}
+
+func TestPublishOptimized(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "templates")
+	optimized := gollm.NewPrompt("请用三句话总结 {{文章}}")
+	history := []optimizer.OptimizationEntry{
+		{Prompt: gollm.NewPrompt("总结"), Assessment: optimizer.PromptAssessment{OverallScore: 9}},
+		{Prompt: optimized, Assessment: optimizer.PromptAssessment{OverallScore: 16, OverallGrade: "A-"}, RunID: "run-1"},
+	}
+
+	require.NoError(t, publishOptimized(dir, "summarize@1.0.0", history, optimized))
+	err := publishOptimized(dir, "summarize@1.0.0", history, optimized)
+	assert.ErrorIs(t, err, gollm.ErrTemplateVersionExists)
+	assert.Error(t, publishOptimized(dir, "summarize", history, optimized))
+
+	registry := gollm.NewTemplateRegistry()
+	require.NoError(t, registry.LoadDir(dir))
+	pt, ok := registry.Get("summarize@1.0.0")
+	require.True(t, ok)
+	assert.Equal(t, "run-1", pt.Metadata[optimizer.MetadataRunID])
+	assert.Equal(t, "16", pt.Metadata[optimizer.MetadataScore])
+	prompt, err := registry.Execute("summarize@1.0.0", nil)
+	require.NoError(t, err)
+	assert.Equal(t, optimized.Input, prompt.Input)
+}
+
+func TestNewProgressWriter(t *testing.T) {
+	_, err := newProgressWriter("fancy", os.Stderr, 0, 0)
+	assert.ErrorContains(t, err, "unknown progress style")
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "log"))
+	require.NoError(t, err)
+	defer f.Close()
+	w, err := newProgressWriter("", f, 0, 0)
+	require.NoError(t, err)
+	w.Report(progress.Update{Phase: "optimize", Total: 1})
+	content, err := os.ReadFile(f.Name())
+	require.NoError(t, err)
+	assert.Equal(t, "optimize 0/1 (0%) | tokens 0 in / 0 out | elapsed 0s\n", string(content), "plain when not a terminal")
+}
+
+func TestReadPrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "article.txt")
+	require.NoError(t, os.WriteFile(path, []byte("\ufeff第一段\n\n第二段\n"), 0o644))
+	stdin := func() *strings.Reader { return strings.NewReader("  来自管道的文章\n") }
+
+	prompt, err := readPrompt([]string{"解释", "递归"}, "", stdin())
+	require.NoError(t, err)
+	assert.Equal(t, "解释 递归", prompt)
+	prompt, err = readPrompt([]string{"-"}, "", stdin())
+	require.NoError(t, err)
+	assert.Equal(t, "来自管道的文章", prompt)
+	prompt, err = readPrompt(nil, "", stdin())
+	require.NoError(t, err)
+	assert.Equal(t, "来自管道的文章", prompt)
+	prompt, err = readPrompt(nil, "-", stdin())
+	require.NoError(t, err)
+	assert.Equal(t, "来自管道的文章", prompt)
+	prompt, err = readPrompt(nil, path, stdin())
+	require.NoError(t, err)
+	assert.Equal(t, "第一段\n\n第二段", prompt)
+
+	_, err = readPrompt([]string{"总结"}, path, stdin())
+	assert.EqualError(t, err, "-prompt-file cannot be combined with prompt arguments")
+	_, err = readPrompt(nil, filepath.Join(t.TempDir(), "missing.txt"), stdin())
+	assert.ErrorContains(t, err, "failed to read prompt file: open ")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = readPrompt(nil, "", strings.NewReader(" \n"))
+	assert.EqualError(t, err, "prompt from stdin is empty")
+	_, err = readPrompt(nil, "", io.LimitReader(infiniteReader{}, maxPromptBytes+10))
+	assert.EqualError(t, err, "prompt from stdin is larger than 64 MiB")
+}
+
+// infiniteReader reads an endless stream of "a".
+type infiniteReader struct{}
+
+func (infiniteReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+	return len(p), nil
+}
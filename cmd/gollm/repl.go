@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+const replHelp = `Commands:
+  /reset          clear the conversation, keeping the system prompt
+  /system <text>  set the system prompt; /system alone removes it
+  /save <file>    save the conversation as JSON
+  /exit           leave (or press Ctrl-D)
+Ctrl-C cancels the response being generated.`
+
+// streamer is the part of gollm.LLM the REPL needs.
+type streamer interface {
+	Stream(ctx context.Context, prompt *gollm.Prompt, opts ...gollm.StreamOption) (gollm.TokenStream, error)
+}
+
+// replMessage is a turn of a REPL conversation.
+type replMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// replTranscript is the file written by /save.
+type replTranscript struct {
+	SystemPrompt string        `json:"system_prompt,omitempty"`
+	Messages     []replMessage `json:"messages"`
+}
+
+// repl is an interactive conversation with an LLM.
+type repl struct {
+	llm        streamer
+	out        io.Writer
+	interrupts <-chan os.Signal
+	system     string
+	history    []replMessage
+}
+
+// runREPL reads messages from in until EOF or /exit, streaming each answer to
+// out. Every message is sent with the conversation so far, in the
+// "role: content" lines LLMWithMemory uses. A signal on interrupts cancels
+// the answer being generated; the cancelled turn is dropped from the
+// conversation and the REPL waits for the next message.
+func runREPL(ctx context.Context, l streamer, in io.Reader, out io.Writer, interrupts <-chan os.Signal) error {
+	r := &repl{llm: l, out: out, interrupts: interrupts}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	fmt.Fprintln(out, "Type a message, or /help for commands.")
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case line == "/exit" || line == "/quit":
+			return nil
+		case strings.HasPrefix(line, "/"):
+			r.command(line)
+		default:
+			r.send(ctx, line)
+		}
+	}
+}
+
+// command runs a meta-command.
+func (r *repl) command(line string) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "/help":
+		fmt.Fprintln(r.out, replHelp)
+	case "/reset":
+		r.history = nil
+		fmt.Fprintln(r.out, "Conversation cleared.")
+	case "/system":
+		r.system = arg
+		if arg == "" {
+			fmt.Fprintln(r.out, "System prompt removed.")
+		} else {
+			fmt.Fprintln(r.out, "System prompt set.")
+		}
+	case "/save":
+		if arg == "" {
+			fmt.Fprintln(r.out, "Usage: /save <file>")
+			return
+		}
+		if err := r.save(arg); err != nil {
+			fmt.Fprintf(r.out, "Error: %v\n", err)
+			return
+		}
+		fmt.Fprintf(r.out, "Saved %d messages to %s.\n", len(r.history), arg)
+	default:
+		fmt.Fprintf(r.out, "Unknown command %s, type /help for commands.\n", name)
+	}
+}
+
+// send streams the answer to message and adds both to the conversation,
+// unless the answer fails or is cancelled.
+func (r *repl) send(ctx context.Context, message string) {
+	// Drop interrupts received while waiting for input, so they do not
+	// cancel this answer.
+	for drained := false; !drained; {
+		select {
+		case <-r.interrupts:
+		default:
+			drained = true
+		}
+	}
+	turnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-r.interrupts:
+			cancel()
+		case <-turnCtx.Done():
+		}
+	}()
+
+	answer, err := r.stream(turnCtx, message)
+	fmt.Fprintln(r.out)
+	switch {
+	case err != nil && turnCtx.Err() != nil && ctx.Err() == nil:
+		fmt.Fprintln(r.out, "[cancelled]")
+	case err != nil:
+		fmt.Fprintf(r.out, "Error generating response: %v\n", err)
+	default:
+		r.history = append(r.history, replMessage{Role: "user", Content: message}, replMessage{Role: "assistant", Content: answer})
+	}
+}
+
+// stream sends message with the conversation so far and writes the answer to
+// out as it arrives.
+func (r *repl) stream(ctx context.Context, message string) (string, error) {
+	var transcript strings.Builder
+	for _, m := range append(r.history, replMessage{Role: "user", Content: message}) {
+		fmt.Fprintf(&transcript, "%s: %s\n", m.Role, m.Content)
+	}
+	prompt := gollm.NewPrompt(transcript.String())
+	if r.system != "" {
+		prompt.Apply(gollm.WithSystemPrompt(r.system, ""))
+	}
+
+	stream, err := r.llm.Stream(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+	var answer strings.Builder
+	for {
+		token, err := stream.Next(ctx)
+		if errors.Is(err, io.EOF) {
+			return answer.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		answer.WriteString(token.Text)
+		fmt.Fprint(r.out, token.Text)
+	}
+}
+
+// save writes the system prompt and the conversation to path as JSON.
+func (r *repl) save(path string) error {
+	data, err := json.MarshalIndent(replTranscript{SystemPrompt: r.system, Messages: append([]replMessage{}, r.history...)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
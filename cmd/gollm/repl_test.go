@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// scriptedStreamer streams its answers token by token, one answer per call.
+// An answer of "" blocks until the call is cancelled.
+type scriptedStreamer struct {
+	answers []string
+	prompts []*gollm.Prompt
+	started chan struct{}
+}
+
+func (s *scriptedStreamer) Stream(ctx context.Context, prompt *gollm.Prompt, opts ...gollm.StreamOption) (gollm.TokenStream, error) {
+	s.prompts = append(s.prompts, prompt)
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	if answer == "" {
+		s.started <- struct{}{}
+	}
+	return &scriptedStream{tokens: strings.Fields(answer), blocking: answer == ""}, nil
+}
+
+type scriptedStream struct {
+	tokens   []string
+	blocking bool
+}
+
+func (s *scriptedStream) Next(ctx context.Context) (*gollm.StreamToken, error) {
+	if s.blocking {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if len(s.tokens) == 0 {
+		return nil, io.EOF
+	}
+	token := s.tokens[0]
+	s.tokens = s.tokens[1:]
+	return &gollm.StreamToken{Text: token}, nil
+}
+
+func (s *scriptedStream) Close() error { return nil }
+
+func TestRunREPL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.json")
+	l := &scriptedStreamer{answers: []string{"你好 ！", "再见"}}
+	in := strings.NewReader("/system 你是一名助手。\n你好\n\n/unknown\n再见\n/save " + path + "\n/reset\n/exit\n不会发送\n")
+	var out bytes.Buffer
+
+	require.NoError(t, runREPL(context.Background(), l, in, &out, nil))
+	assert.Contains(t, out.String(), "> 你好！\n")
+	assert.Contains(t, out.String(), "Unknown command /unknown")
+	assert.Contains(t, out.String(), "Saved 4 messages to "+path)
+	assert.Contains(t, out.String(), "Conversation cleared.")
+	require.Len(t, l.prompts, 2)
+	assert.Equal(t, "user: 你好\nassistant: 你好！\nuser: 再见\n", l.prompts[1].Input)
+	assert.Equal(t, "你是一名助手。", l.prompts[1].SystemPrompt)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var saved replTranscript
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, replTranscript{SystemPrompt: "你是一名助手。", Messages: []replMessage{
+		{Role: "user", Content: "你好"},
+		{Role: "assistant", Content: "你好！"},
+		{Role: "user", Content: "再见"},
+		{Role: "assistant", Content: "再见"},
+	}}, saved)
+}
+
+func TestRunREPL_Interrupt(t *testing.T) {
+	l := &scriptedStreamer{answers: []string{"", "好的"}, started: make(chan struct{})}
+	interrupts := make(chan os.Signal, 1)
+	inReader, inWriter := io.Pipe()
+	var out bytes.Buffer
+	done := make(chan error)
+	go func() {
+		done <- runREPL(context.Background(), l, inReader, &out, interrupts)
+	}()
+
+	_, err := io.WriteString(inWriter, "写一篇长文\n")
+	require.NoError(t, err)
+	<-l.started
+	interrupts <- os.Interrupt
+	_, err = io.WriteString(inWriter, "简短一点\n")
+	require.NoError(t, err)
+	require.NoError(t, inWriter.Close())
+	require.NoError(t, <-done)
+
+	assert.Contains(t, out.String(), "[cancelled]")
+	require.Len(t, l.prompts, 2)
+	assert.Equal(t, "user: 简短一点\n", l.prompts[1].Input, "the cancelled turn is dropped")
+}
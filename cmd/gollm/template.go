@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// templateVars collects repeated -var key=value flags.
+type templateVars map[string]string
+
+func (v templateVars) String() string {
+	pairs := make([]string, 0, len(v))
+	for key, value := range v {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v templateVars) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("invalid variable %q, expected key=value", pair)
+	}
+	v[strings.TrimSpace(key)] = value
+	return nil
+}
+
+// templatePrompt executes the template file at path with vars. Only -type raw
+// sends the prompt as it is, with the directives and output format the
+// template declares, so other types are rejected rather than losing them.
+func templatePrompt(path string, vars templateVars, args []string, promptType string) (*gollm.Prompt, error) {
+	if len(args) > 0 {
+		return nil, fmt.Errorf("-template cannot be combined with prompt arguments")
+	}
+	if promptType != "raw" {
+		return nil, fmt.Errorf("-template can only be used with -type raw, got %q", promptType)
+	}
+	pt, err := gollm.LoadPromptTemplateFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(vars))
+	for key, value := range vars {
+		data[key] = value
+	}
+	prompt, err := pt.Execute(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute template %s: %w", path, err)
+	}
+	return prompt, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"text/tabwriter"
+
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/providers"
+)
+
+// Compatibility check statuses. Any check with checkError makes
+// "gollm version --check" exit with status 1.
+const (
+	checkOK      = "ok"
+	checkWarning = "warning"
+	checkError   = "error"
+)
+
+// versionReport is printed by "gollm version".
+type versionReport struct {
+	Version   string                   `json:"version"`
+	GoVersion string                   `json:"go_version"`
+	Revision  string                   `json:"revision,omitempty"`
+	Providers []providers.ProviderInfo `json:"providers"`
+	Provider  string                   `json:"provider,omitempty"`
+	Model     string                   `json:"model,omitempty"`
+	Checks    []compatibilityCheck     `json:"checks,omitempty"`
+}
+
+// compatibilityCheck is one entry of the report printed by "gollm version --check".
+type compatibilityCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// runVersion implements "gollm version [-check] [-json]". It returns the
+// process exit code: 1 when the arguments are invalid, the configuration
+// cannot be loaded or a compatibility check fails, 0 otherwise.
+func runVersion(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("version", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	check := flags.Bool("check", false, "检查当前配置（LLM_PROVIDER、LLM_MODEL、API 密钥）与本版本的兼容性")
+	asJSON := flags.Bool("json", false, "以 JSON 格式输出")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	report := newVersionReport()
+	if *check {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error loading configuration: %v\n", err)
+			return 1
+		}
+		report.Provider, report.Model = cfg.Provider, cfg.Model
+		report.Checks = checkCompatibility(cfg)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(stderr, "Error encoding report: %v\n", err)
+			return 1
+		}
+	} else {
+		printVersionReport(stdout, report)
+	}
+
+	for _, c := range report.Checks {
+		if c.Status == checkError {
+			return 1
+		}
+	}
+	return 0
+}
+
+// newVersionReport fills in the build and provider information.
+func newVersionReport() versionReport {
+	report := versionReport{Version: "unknown", Providers: providers.KnownProviders()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return report
+	}
+	report.Version, report.GoVersion = info.Main.Version, info.GoVersion
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			report.Revision = setting.Value
+		}
+	}
+	return report
+}
+
+// checkCompatibility checks the configured provider and model against the
+// providers and models this build knows about.
+func checkCompatibility(cfg *config.Config) []compatibilityCheck {
+	info, ok := providers.LookupProvider(cfg.Provider)
+	if !ok {
+		return []compatibilityCheck{{
+			Name:    "provider",
+			Status:  checkError,
+			Message: fmt.Sprintf("provider %q is not compiled into this build", cfg.Provider),
+		}}
+	}
+	checks := []compatibilityCheck{{
+		Name:    "provider",
+		Status:  checkOK,
+		Message: fmt.Sprintf("%s (API %s)", info.Name, info.APIVersion),
+	}}
+
+	switch {
+	case !info.RequiresAPIKey:
+		checks = append(checks, compatibilityCheck{Name: "api_key", Status: checkOK, Message: "not required"})
+	case cfg.APIKeys[cfg.Provider] == "":
+		checks = append(checks, compatibilityCheck{Name: "api_key", Status: checkError, Message: fmt.Sprintf("no API key configured for %s", cfg.Provider)})
+	default:
+		checks = append(checks, compatibilityCheck{Name: "api_key", Status: checkOK, Message: "configured"})
+	}
+
+	if window, ok := llm.ContextWindow(cfg.Model); ok {
+		checks = append(checks, compatibilityCheck{Name: "model", Status: checkOK, Message: fmt.Sprintf("%s (context window %d tokens)", cfg.Model, window)})
+	} else {
+		checks = append(checks, compatibilityCheck{Name: "model", Status: checkWarning, Message: fmt.Sprintf("%s is not a known model; its context window is unknown", cfg.Model)})
+	}
+	return checks
+}
+
+// printVersionReport prints the report as aligned text.
+func printVersionReport(w io.Writer, report versionReport) {
+	fmt.Fprintf(w, "gollm %s", report.Version)
+	if report.GoVersion != "" {
+		fmt.Fprintf(w, " (%s", report.GoVersion)
+		if report.Revision != "" {
+			fmt.Fprintf(w, ", revision %s", report.Revision)
+		}
+		fmt.Fprint(w, ")")
+	}
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "\nProviders:")
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, p := range report.Providers {
+		fmt.Fprintf(table, "  %s\tAPI %s\t%s\n", p.Name, p.APIVersion, p.DefaultEndpoint)
+	}
+	table.Flush()
+
+	if len(report.Checks) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nCompatibility (provider %s, model %s):\n", report.Provider, report.Model)
+	table = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range report.Checks {
+		fmt.Fprintf(table, "  [%s]\t%s\t%s\n", c.Status, c.Name, c.Message)
+	}
+	table.Flush()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunVersion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runVersion(nil, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "gollm ")
+	assert.Contains(t, stdout.String(), "anthropic")
+	assert.Contains(t, stdout.String(), "API 2023-06-01")
+	assert.NotContains(t, stdout.String(), "Compatibility")
+}
+
+func TestRunVersion_CheckJSON(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "openai")
+	t.Setenv("LLM_MODEL", "gpt-4o-mini")
+	t.Setenv("OPENAI_API_KEY", "test-key")
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, 0, runVersion([]string{"-check", "-json"}, &stdout, &stderr), stderr.String())
+	var report versionReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	assert.Equal(t, "openai", report.Provider)
+	assert.NotEmpty(t, report.Providers)
+	for _, c := range report.Checks {
+		assert.Equal(t, checkOK, c.Status, c.Name)
+	}
+}
+
+func TestRunVersion_CheckFailures(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "openai")
+	t.Setenv("LLM_MODEL", "my-custom-model")
+	t.Setenv("OPENAI_API_KEY", "")
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, runVersion([]string{"-check"}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "[error]    api_key")
+	assert.Contains(t, stdout.String(), "[warning]  model")
+
+	t.Setenv("LLM_PROVIDER", "watsonx")
+	stdout.Reset()
+	assert.Equal(t, 1, runVersion([]string{"-check"}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), `provider "watsonx" is not compiled into this build`)
+}
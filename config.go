@@ -5,6 +5,7 @@ package gollm
 
 import (
 	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/persona"
 	"github.com/yockii/gollm_cn/utils"
 )
 
@@ -36,6 +37,9 @@ type (
 	//   cfg = ApplyOptions(cfg, SetLogLevel(LogLevelInfo))
 	LogLevel = utils.LogLevel
 
+	// Logger is the logger gollm writes to; NewSlogLogger adapts a slog.Handler to it.
+	Logger = utils.Logger
+
 	// MemoryOption configures the memory settings for conversation history.
 	// It controls how much context is retained between interactions.
 	//
@@ -43,6 +47,45 @@ type (
 	//   cfg := NewConfig()
 	//   cfg = ApplyOptions(cfg, SetMemory(MemoryOption{MaxHistory: 10}))
 	MemoryOption = config.MemoryOption
+
+	// EmbeddingFunc returns the embedding of a text for SetSemanticCache.
+	//
+	// Example usage:
+	//   cfg = ApplyOptions(cfg, SetSemanticCache(embed, 0.95))
+	EmbeddingFunc = utils.EmbeddingFunc
+
+	// Tracer observes LLM calls so tracing backends can be plugged in.
+	// See contrib/gollmotel for an OpenTelemetry implementation.
+	Tracer = utils.Tracer
+
+	// CallSpan, CallInfo and CallResult describe a traced call.
+	CallSpan   = utils.CallSpan
+	CallInfo   = utils.CallInfo
+	CallResult = utils.CallResult
+
+	// MetricsCollector records request counts, latency, token usage and errors.
+	// See contrib/gollmprom for a Prometheus implementation.
+	MetricsCollector = utils.MetricsCollector
+
+	// DebugRecorder writes the raw request and response bodies of API calls as
+	// newline-delimited DebugRecords; see SetDebugRecorder and ReplayRecording.
+	DebugRecorder = utils.DebugRecorder
+	DebugRecord   = utils.DebugRecord
+
+	// ConstantRetry, ExponentialBackoff and JitteredExponentialBackoff are the
+	// built-in RetryStrategy implementations for SetRetryStrategy.
+	//
+	// Example usage:
+	//   SetRetryStrategy(ExponentialBackoff{MaxRetries: 4, InitialDelay: time.Second})
+	ConstantRetry              = utils.ConstantRetry
+	ExponentialBackoff         = utils.ExponentialBackoff
+	JitteredExponentialBackoff = utils.JitteredExponentialBackoff
+
+	// Persona is a brand persona applied to every generation; see SetPersona.
+	//
+	// Example usage:
+	//   SetPersona(Persona{Name: "小助手", Tone: []string{"亲切"}, ForbiddenPhrases: []string{"亲"}})
+	Persona = persona.Persona
 )
 
 // Re-export core configuration functions
@@ -58,6 +101,38 @@ var (
 	//   }
 	LoadConfig = config.LoadConfig
 
+	// LoadConfigFile reads a YAML or JSON configuration file and returns the equivalent
+	// configuration options. String values may reference environment variables as ${NAME}.
+	//
+	// Example usage:
+	//   opts, err := LoadConfigFile("config.yaml")
+	//   if err != nil {
+	//       log.Fatal(err)
+	//   }
+	//   llm, err := NewLLM(opts...)
+	LoadConfigFile = config.LoadConfigFile
+
+	// ConfigFromEnv reads the keys of LoadConfigFile from environment variables with a
+	// prefix, such as MYAPP_PROVIDER and MYAPP_MAX_TOKENS, and returns them as options.
+	//
+	// Example usage:
+	//   opts, err := ConfigFromEnv("MYAPP")
+	//   if err != nil {
+	//       log.Fatal(err)
+	//   }
+	//   llm, err := NewLLM(opts...)
+	ConfigFromEnv = config.ConfigFromEnv
+
+	// LoadPersonaFromFile reads a Persona from a YAML or JSON file, for SetPersona or
+	// WithPersona.
+	//
+	// Example usage:
+	//   p, err := LoadPersonaFromFile("personas/security.yaml")
+	//   if err != nil {
+	//       log.Fatal(err)
+	//   }
+	LoadPersonaFromFile = persona.LoadFromFile
+
 	// ApplyOptions applies a series of ConfigOption functions to a Config instance.
 	// This enables fluent configuration updates using the builder pattern.
 	//
@@ -74,16 +149,23 @@ var (
 	// Provider configuration
 	SetProvider = config.SetProvider // Sets the LLM provider (e.g., "openai", "anthropic")
 	SetModel    = config.SetModel    // Sets the model name for the selected provider
-	SetEndpoint = config.SetEndpoint // Sets the endpoint URL for Ollama local deployment
+	SetEndpoint = config.SetEndpoint // Sets the base URL of the provider's API, same as SetBaseURL
+	SetBaseURL  = config.SetBaseURL  // Overrides the provider's base URL, e.g. for a proxy or vLLM server
 	SetAPIKey   = config.SetAPIKey   // Sets the API key for the current provider
 
+	SetMistralEmbeddingModel = config.SetMistralEmbeddingModel // Sets the Mistral model used by Embed
+	SetAzureDeployment       = config.SetAzureDeployment       // Sets the Azure OpenAI deployment for the "azure" provider
+	SetAzureAPIVersion       = config.SetAzureAPIVersion       // Sets the Azure OpenAI API version for the "azure" provider
+
 	// Generation parameters
 	SetTemperature      = config.SetTemperature      // Controls randomness in generation (0.0-1.0)
 	SetMaxTokens        = config.SetMaxTokens        // Sets maximum tokens to generate
 	SetTopP             = config.SetTopP             // Controls nucleus sampling
+	SetTopK             = config.SetTopK             // Limits sampling to the k most likely tokens
 	SetFrequencyPenalty = config.SetFrequencyPenalty // Penalizes frequent token usage
 	SetPresencePenalty  = config.SetPresencePenalty  // Penalizes repeated tokens
 	SetSeed             = config.SetSeed             // Sets random seed for reproducible generation
+	SetStopSequences    = config.SetStopSequences    // Sets sequences that stop generation
 
 	// Advanced generation parameters
 	SetMinP          = config.SetMinP          // Sets minimum probability threshold
@@ -95,18 +177,37 @@ var (
 	SetTfsZ          = config.SetTfsZ          // Sets tail-free sampling parameter
 
 	// Runtime configuration
-	SetTimeout      = config.SetTimeout      // Sets request timeout duration
-	SetMaxRetries   = config.SetMaxRetries   // Sets maximum retry attempts
-	SetRetryDelay   = config.SetRetryDelay   // Sets delay between retries
-	SetLogLevel     = config.SetLogLevel     // Sets logging verbosity
-	SetExtraHeaders = config.SetExtraHeaders // Sets additional HTTP headers
+	SetTimeout       = config.SetTimeout       // Sets request timeout duration
+	SetMaxRetries    = config.SetMaxRetries    // Sets maximum retry attempts
+	SetRetryDelay    = config.SetRetryDelay    // Sets delay between retries
+	SetRetryStrategy = config.SetRetryStrategy // Sets how failed calls are retried
+	SetLogLevel      = config.SetLogLevel      // Sets logging verbosity; deprecated, use WithSlogHandler
+	WithSlogHandler  = config.WithSlogHandler  // Logs through a slog.Handler
+	SetExtraHeaders  = config.SetExtraHeaders  // Sets additional HTTP headers
+	SetHTTPClient    = config.SetHTTPClient    // Sets the HTTP client for proxies, TLS or test transports
+	SetTracer        = config.SetTracer        // Observes Generate and Stream calls for tracing
+	SetMetrics       = config.SetMetrics       // Records metrics for Generate and Stream calls
+	SetDebugRecorder = config.SetDebugRecorder // Records raw API request and response bodies
 
 	// Feature toggles
-	SetEnableCaching = config.SetEnableCaching // Enables/disables response caching
-	SetMemory        = config.SetMemory        // Configures conversation memory
+	SetEnableCaching       = config.SetEnableCaching       // Enables/disables response caching
+	SetMemory              = config.SetMemory              // Configures conversation memory
+	SetMemorySummarization = config.SetMemorySummarization // Condenses old memory turns into summaries
+	SetSemanticCache       = config.SetSemanticCache       // Reuses responses of semantically equivalent prompts
+	SetStrictMode          = config.SetStrictMode          // Refuses silent fallbacks and repairs
+	SetPersona             = config.SetPersona             // Applies a brand persona to every generation
 
 	// Configuration creation
 	NewConfig = config.NewConfig // Creates a new Config with default values
+
+	// Logging
+	NewSlogLogger        = utils.NewSlogLogger        // Creates a Logger writing to a slog.Handler
+	NewDefaultSlogLogger = utils.NewDefaultSlogLogger // Creates a Logger writing slog text records to stderr
+
+	// Debug recordings
+	NewDebugRecorder = utils.NewDebugRecorder // Creates a recorder for SetDebugRecorder
+	ReadRecording    = utils.ReadRecording    // Reads the records written by a DebugRecorder
+	ReplayRecording  = utils.ReplayRecording  // Serves recorded responses to reproduce failures
 )
 
 // LogLevel constants define available logging verbosity levels
@@ -4,11 +4,14 @@
 package config
 
 import (
+	"log/slog"
+	"net/http"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
+	"github.com/yockii/gollm_cn/persona"
 	"github.com/yockii/gollm_cn/utils"
 )
 
@@ -18,6 +21,21 @@ type MemoryOption struct {
 	// MaxTokens specifies the maximum number of tokens to retain in memory
 	// for context in subsequent interactions.
 	MaxTokens int
+
+	// SummarizeAfterTurns, when positive, condenses the oldest half of the
+	// turns into a summary once memory holds more turns than this.
+	SummarizeAfterTurns int
+}
+
+// SemanticCacheOption configures the semantic cache set with
+// SetSemanticCache.
+type SemanticCacheOption struct {
+	// Embed returns the embedding of a prompt input.
+	Embed utils.EmbeddingFunc
+
+	// Threshold is the cosine similarity, from 0 to 1, above which two inputs
+	// are considered the same question.
+	Threshold float64
 }
 
 // Config represents the complete configuration for LLM interactions.
@@ -28,10 +46,13 @@ type MemoryOption struct {
 // Environment Variables:
 //   - LLM_PROVIDER: LLM provider name (default: "anthropic")
 //   - LLM_MODEL: Model name (default: "claude-3-opus-20240229")
-//   - OLLAMA_ENDPOINT: Ollama API endpoint (default: "http://localhost:11434")
+//   - LLM_ENDPOINT: Base URL of the provider's API, see SetBaseURL (default:
+//     the provider's own, e.g. "http://localhost:11434" for Ollama)
 //   - LLM_TEMPERATURE: Generation temperature (default: 0.7)
 //   - LLM_MAX_TOKENS: Maximum tokens to generate (default: 100)
-//   - LLM_TOP_P: Top-p sampling parameter (default: 0.9)
+//   - LLM_TOP_P: Top-p sampling parameter, greater than 0 (default: 1.0, not
+//     sent; see SetTopP for the change from 0.9)
+//   - LLM_TOP_K: Top-k sampling parameter (default: unset, not sent)
 //   - LLM_FREQUENCY_PENALTY: Token frequency penalty (default: 0.0)
 //   - LLM_PRESENCE_PENALTY: Token presence penalty (default: 0.0)
 //   - LLM_TIMEOUT: Request timeout duration (default: 30s)
@@ -50,21 +71,26 @@ type MemoryOption struct {
 //   - LLM_MIROSTAT_ETA: Mirostat learning rate
 //   - LLM_MIROSTAT_TAU: Mirostat target entropy
 //   - LLM_TFS_Z: Tail-free sampling parameter
+//   - LLM_MISTRAL_EMBEDDING_MODEL: Mistral model used for embeddings
+//   - LLM_AZURE_DEPLOYMENT: Azure OpenAI deployment name
+//   - LLM_AZURE_API_VERSION: Azure OpenAI API version
 type Config struct {
 	Provider              string            `env:"LLM_PROVIDER" envDefault:"anthropic" validate:"required"`
 	Model                 string            `env:"LLM_MODEL" envDefault:"claude-3-5-haiku-latest" validate:"required"`
-	Endpoint              string            `env:"LLM_ENDPOINT" envDefault:"http://localhost:11434"`
+	Endpoint              string            `env:"LLM_ENDPOINT" validate:"omitempty,http_url"`
 	Temperature           float64           `env:"LLM_TEMPERATURE" envDefault:"0.7" validate:"gte=0,lte=1"`
 	MaxTokens             int               `env:"LLM_MAX_TOKENS" envDefault:"100"`
-	TopP                  float64           `env:"LLM_TOP_P" envDefault:"0.9" validate:"gte=0,lte=1"`
-	FrequencyPenalty      float64           `env:"LLM_FREQUENCY_PENALTY" envDefault:"0.0"`
-	PresencePenalty       float64           `env:"LLM_PRESENCE_PENALTY" envDefault:"0.0"`
+	TopP                  float64           `env:"LLM_TOP_P" envDefault:"1.0" validate:"gt=0,lte=1"`
+	TopK                  *int              `env:"LLM_TOP_K"`
+	FrequencyPenalty      float64           `env:"LLM_FREQUENCY_PENALTY" envDefault:"0.0" validate:"gte=-2,lte=2"`
+	PresencePenalty       float64           `env:"LLM_PRESENCE_PENALTY" envDefault:"0.0" validate:"gte=-2,lte=2"`
 	Timeout               time.Duration     `env:"LLM_TIMEOUT" envDefault:"30s"`
 	MaxRetries            int               `env:"LLM_MAX_RETRIES" envDefault:"3"`
 	RetryDelay            time.Duration     `env:"LLM_RETRY_DELAY" envDefault:"2s"`
 	APIKeys               map[string]string `validate:"required,apikey"`
 	LogLevel              utils.LogLevel    `env:"LLM_LOG_LEVEL" envDefault:"WARN"`
 	Seed                  *int              `env:"LLM_SEED"`
+	StopSequences         []string          `env:"LLM_STOP_SEQUENCES" envSeparator:"," validate:"max=4,dive,required"`
 	MinP                  *float64          `env:"LLM_MIN_P" envDefault:"0.05"`
 	RepeatPenalty         *float64          `env:"LLM_REPEAT_PENALTY" envDefault:"1.1"`
 	RepeatLastN           *int              `env:"LLM_REPEAT_LAST_N" envDefault:"64"`
@@ -78,6 +104,18 @@ type Config struct {
 	EnableCaching         bool `env:"LLM_ENABLE_CACHING" envDefault:"false"`
 	EnableStreaming       bool `env:"LLM_ENABLE_STREAMING" envDefault:"false"`
 	MemoryOption          *MemoryOption
+	SemanticCache         *SemanticCacheOption
+	SlogHandler           slog.Handler
+	Tracer                utils.Tracer
+	Metrics               utils.MetricsCollector
+	DebugRecorder         *utils.DebugRecorder
+	HTTPClient            *http.Client
+	RetryStrategy         utils.RetryStrategy
+	StrictMode            bool `env:"LLM_STRICT_MODE" envDefault:"false"`
+	Persona               *persona.Persona
+	MistralEmbeddingModel string `env:"LLM_MISTRAL_EMBEDDING_MODEL"`
+	AzureDeployment       string `env:"LLM_AZURE_DEPLOYMENT"`
+	AzureAPIVersion       string `env:"LLM_AZURE_API_VERSION"`
 }
 
 // LoadConfig creates a new Config instance, loading values from environment
@@ -148,6 +186,7 @@ func NewConfig() *Config {
 		Model:        "gpt-4o-mini",
 		Temperature:  0.7,
 		MaxTokens:    300,
+		TopP:         1.0,
 		Timeout:      30 * time.Second,
 		MaxRetries:   3,
 		RetryDelay:   2 * time.Second,
@@ -178,13 +217,35 @@ func SetModel(model string) ConfigOption {
 	}
 }
 
-// SetEndpoint sets the Ollama API endpoint.
+// SetEndpoint sets the base URL of the provider's API. It is the same as
+// SetBaseURL.
 func SetEndpoint(endpoint string) ConfigOption {
 	return func(c *Config) {
 		c.Endpoint = endpoint
 	}
 }
 
+// SetBaseURL overrides the base URL of the selected provider's API, for
+// requests routed through a proxy such as LiteLLM or sent to an
+// OpenAI-compatible server such as vLLM or Azure OpenAI. The provider appends
+// its own paths, such as "/chat/completions" for OpenAI or "/api/generate"
+// for Ollama, so the base URL is what precedes them, with or without a
+// trailing slash. Query parameters, such as Azure's api-version, are kept.
+// The URL must be an absolute http or https URL; NewLLM fails otherwise.
+//
+// Example:
+//
+//	llm, err := gollm.NewLLM(
+//	    gollm.SetProvider("openai"),
+//	    gollm.SetModel("Qwen/Qwen2.5-7B-Instruct"),
+//	    gollm.SetBaseURL("http://localhost:8000/v1"),
+//	)
+func SetBaseURL(url string) ConfigOption {
+	return func(c *Config) {
+		c.Endpoint = url
+	}
+}
+
 // SetTemperature sets the generation temperature.
 func SetTemperature(temperature float64) ConfigOption {
 	return func(c *Config) {
@@ -219,7 +280,8 @@ func SetAPIKey(apiKey string) ConfigOption {
 	}
 }
 
-// SetMaxRetries sets the maximum number of retry attempts.
+// SetMaxRetries sets the maximum number of retry attempts. Without a
+// RetryStrategy, MaxRetries and RetryDelay form a utils.ConstantRetry.
 func SetMaxRetries(maxRetries int) ConfigOption {
 	return func(c *Config) {
 		c.MaxRetries = maxRetries
@@ -233,22 +295,99 @@ func SetRetryDelay(retryDelay time.Duration) ConfigOption {
 	}
 }
 
+// SetRetryStrategy sets how failed calls are retried, replacing the constant
+// delay formed by SetMaxRetries and SetRetryDelay.
+//
+// Example:
+//
+//	SetRetryStrategy(utils.JitteredExponentialBackoff{
+//	    MaxRetries:   5,
+//	    InitialDelay: time.Second,
+//	    MaxDelay:     30 * time.Second,
+//	})
+func SetRetryStrategy(rs utils.RetryStrategy) ConfigOption {
+	return func(c *Config) {
+		c.RetryStrategy = rs
+	}
+}
+
 // SetLogLevel sets the logging verbosity.
+//
+// Deprecated: Use WithSlogHandler and set the level on the handler.
+// SetLogLevel will be removed in the next major version.
 func SetLogLevel(level utils.LogLevel) ConfigOption {
 	return func(c *Config) {
 		c.LogLevel = level
 	}
 }
 
+// WithSlogHandler makes the LLM log through h, so its records join the
+// application's structured logs. The handler's level decides which records
+// are kept; SetLogLevel is ignored, though UpdateLogLevel still drops the
+// records below the level it is given.
+//
+// Example:
+//
+//	llm, err := gollm.NewLLM(
+//	    gollm.SetProvider("openai"),
+//	    gollm.WithSlogHandler(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo})),
+//	)
+func WithSlogHandler(h slog.Handler) ConfigOption {
+	return func(c *Config) {
+		c.SlogHandler = h
+	}
+}
+
 // SetMemory sets the conversation memory settings.
 func SetMemory(maxTokens int) ConfigOption {
 	return func(c *Config) {
+		summarizeAfterTurns := 0
+		if c.MemoryOption != nil {
+			summarizeAfterTurns = c.MemoryOption.SummarizeAfterTurns
+		}
 		c.MemoryOption = &MemoryOption{
-			MaxTokens: maxTokens,
+			MaxTokens:           maxTokens,
+			SummarizeAfterTurns: summarizeAfterTurns,
 		}
 	}
 }
 
+// SetMemorySummarization makes conversation memory condense its oldest half
+// of turns into a summary turn once it holds more than maxTurns turns, instead
+// of only dropping old messages when it runs out of tokens. It requires
+// SetMemory, in either order.
+func SetMemorySummarization(maxTurns int) ConfigOption {
+	return func(c *Config) {
+		if c.MemoryOption == nil {
+			c.MemoryOption = &MemoryOption{}
+		}
+		c.MemoryOption.SummarizeAfterTurns = maxTurns
+	}
+}
+
+// SetSemanticCache caches responses and answers a prompt from the cache when
+// its input is semantically equivalent to that of a cached prompt: the cosine
+// similarity of their embeddings is at least threshold, e.g. 0.95. Only
+// prompts that are otherwise identical, with the same directives, system
+// prompt, schema and call options, share cached responses. Streaming calls
+// are not cached.
+//
+// Example:
+//
+//	embed := func(ctx context.Context, text string) ([]float64, error) {
+//	    embeddings, err := embedder.Embed(ctx, []string{text})
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return embeddings[0], nil
+//	}
+//	llm, err := gollm.NewLLM(config.SetSemanticCache(embed, 0.95))
+func SetSemanticCache(embeddings utils.EmbeddingFunc, threshold float64) ConfigOption {
+	return func(c *Config) {
+		c.SemanticCache = &SemanticCacheOption{Embed: embeddings, Threshold: threshold}
+	}
+}
+
 // SetExtraHeaders sets additional HTTP headers.
 func SetExtraHeaders(headers map[string]string) ConfigOption {
 	return func(c *Config) {
@@ -268,21 +407,35 @@ func WithStream(enableStreaming bool) ConfigOption {
 	}
 }
 
-// SetTopP sets the top-p sampling parameter.
+// SetTopP sets the top-p sampling parameter, in (0, 1]. A value of 1, the
+// default, leaves nucleus sampling to the provider's default and is not sent;
+// 0 fails validation when the client is created.
+//
+// Breaking change: the default used to be 0.9. It was only sent to Ollama,
+// whose own default is also 0.9. Pass SetTopP(0.9) to keep that value; it is
+// then sent to every provider.
 func SetTopP(topP float64) ConfigOption {
 	return func(c *Config) {
 		c.TopP = topP
 	}
 }
 
-// SetFrequencyPenalty sets the token frequency penalty.
+// SetTopK limits sampling to the k most likely tokens. Providers without top-k
+// sampling, such as OpenAI, ignore it.
+func SetTopK(k int) ConfigOption {
+	return func(c *Config) {
+		c.TopK = &k
+	}
+}
+
+// SetFrequencyPenalty sets the token frequency penalty, in [-2, 2].
 func SetFrequencyPenalty(penalty float64) ConfigOption {
 	return func(c *Config) {
 		c.FrequencyPenalty = penalty
 	}
 }
 
-// SetPresencePenalty sets the token presence penalty.
+// SetPresencePenalty sets the token presence penalty, in [-2, 2].
 func SetPresencePenalty(penalty float64) ConfigOption {
 	return func(c *Config) {
 		c.PresencePenalty = penalty
@@ -296,6 +449,15 @@ func SetSeed(seed int) ConfigOption {
 	}
 }
 
+// SetStopSequences sets sequences that stop generation when produced, unless
+// a prompt or call sets its own. At most 4 non-empty sequences are accepted,
+// the limit of OpenAI, so the configuration works with every provider.
+func SetStopSequences(sequences ...string) ConfigOption {
+	return func(c *Config) {
+		c.StopSequences = sequences
+	}
+}
+
 // SetMinP sets the minimum token probability threshold.
 func SetMinP(minP float64) ConfigOption {
 	return func(c *Config) {
@@ -345,6 +507,115 @@ func SetTfsZ(z float64) ConfigOption {
 	}
 }
 
+// SetTracer sets a tracer that observes every Generate and Stream call.
+// Use contrib/gollmotel for OpenTelemetry, or implement utils.Tracer directly.
+func SetTracer(tracer utils.Tracer) ConfigOption {
+	return func(c *Config) {
+		c.Tracer = tracer
+	}
+}
+
+// SetMetrics sets a collector that records request counts, latency, token
+// usage and errors for every Generate and Stream call. Use contrib/gollmprom
+// for Prometheus, or implement utils.MetricsCollector directly.
+func SetMetrics(collector utils.MetricsCollector) ConfigOption {
+	return func(c *Config) {
+		c.Metrics = collector
+	}
+}
+
+// SetDebugRecorder records the raw request and response bodies of every API
+// call to the recorder's writer, for replay with utils.ReplayRecording.
+func SetDebugRecorder(recorder *utils.DebugRecorder) ConfigOption {
+	return func(c *Config) {
+		c.DebugRecorder = recorder
+	}
+}
+
+// SetHTTPClient sets the HTTP client used for every API call, for instance to
+// route requests through a proxy, customise TLS or tune the connection pool.
+// The client's Timeout is kept if set; otherwise the SetTimeout timeout
+// applies. Deadlines and cancellation of the call's context apply in either
+// case. Without a client, a default one with the SetTimeout timeout is used.
+//
+// Example:
+//
+//	client := &http.Client{
+//	    Transport: &http.Transport{
+//	        Proxy:           http.ProxyURL(proxyURL),
+//	        MaxIdleConns:    100,
+//	        TLSClientConfig: &tls.Config{RootCAs: pool},
+//	    },
+//	}
+//	llm, err := gollm.NewLLM(config.SetHTTPClient(client))
+func SetHTTPClient(client *http.Client) ConfigOption {
+	return func(c *Config) {
+		c.HTTPClient = client
+	}
+}
+
+// SetMistralEmbeddingModel sets the model the Mistral provider uses for
+// Embed calls. Embeddings use their own model and endpoint, so the model set
+// with SetModel keeps serving Generate. Defaults to "mistral-embed".
+func SetMistralEmbeddingModel(model string) ConfigOption {
+	return func(c *Config) {
+		c.MistralEmbeddingModel = model
+	}
+}
+
+// SetAzureDeployment sets the Azure OpenAI deployment that serves requests of
+// the "azure" provider. Azure routes requests by deployment name, so the model
+// set with SetModel is only used to count tokens. Required for "azure".
+//
+// Example:
+//
+//	llm, err := gollm.NewLLM(
+//	    gollm.SetProvider("azure"),
+//	    gollm.SetModel("gpt-4o"),
+//	    gollm.SetBaseURL("https://my-resource.openai.azure.com"),
+//	    gollm.SetAzureDeployment("gpt-4o-prod"),
+//	    gollm.SetAzureAPIVersion("2024-10-21"),
+//	    gollm.SetAPIKey(os.Getenv("AZURE_API_KEY")),
+//	)
+func SetAzureDeployment(deployment string) ConfigOption {
+	return func(c *Config) {
+		c.AzureDeployment = deployment
+	}
+}
+
+// SetAzureAPIVersion sets the Azure OpenAI API version sent with requests of
+// the "azure" provider, such as "2024-10-21". Required for "azure".
+func SetAzureAPIVersion(version string) ConfigOption {
+	return func(c *Config) {
+		c.AzureAPIVersion = version
+	}
+}
+
+// SetStrictMode enables or disables strict mode for every call made with the
+// client. In strict mode silent fallbacks and repairs are refused with an
+// *llm.InterventionError instead of being applied.
+func SetStrictMode(strict bool) ConfigOption {
+	return func(c *Config) {
+		c.StrictMode = strict
+	}
+}
+
+// SetPersona sets a persona that every Generate and GenerateWithSchema call
+// of the client applies, including the calls made by presets. Its
+// instructions are placed before the system prompt, so prompt caching keeps
+// working as long as the persona does not change. A response that uses one of
+// its forbidden phrases is rewritten once, and the call fails if the rewrite
+// still uses one. Streams are not covered, since a stream cannot be rewritten
+// once it has been delivered.
+//
+// Use llm.WithoutPersona to leave the persona out of a call, and
+// llm.WithPersonaLanguage to apply one of its language variants.
+func SetPersona(p persona.Persona) ConfigOption {
+	return func(c *Config) {
+		c.Persona = &p
+	}
+}
+
 // ApplyOptions applies a series of ConfigOption functions to a Config instance.
 // This enables fluent configuration updates using the builder pattern.
 //
@@ -0,0 +1,343 @@
+// Package config provides the core configuration system for the gollm library,
+// enabling flexible and type-safe configuration of Language Learning Model (LLM)
+// interactions through environment variables and programmatic options.
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yockii/gollm_cn/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig mirrors the keys accepted in a configuration file. Pointer fields
+// distinguish keys that are absent from keys explicitly set to a zero value.
+// Keep it in sync with knownFileKeys and config.schema.json.
+type fileConfig struct {
+	Provider              *string           `yaml:"provider"`
+	Model                 *string           `yaml:"model"`
+	Endpoint              *string           `yaml:"endpoint"`
+	BaseURL               *string           `yaml:"base_url"`
+	APIKey                *string           `yaml:"api_key"`
+	Temperature           *float64          `yaml:"temperature"`
+	MaxTokens             *int              `yaml:"max_tokens"`
+	TopP                  *float64          `yaml:"top_p"`
+	TopK                  *int              `yaml:"top_k"`
+	FrequencyPenalty      *float64          `yaml:"frequency_penalty"`
+	PresencePenalty       *float64          `yaml:"presence_penalty"`
+	Seed                  *int              `yaml:"seed"`
+	StopSequences         []string          `yaml:"stop_sequences"`
+	MinP                  *float64          `yaml:"min_p"`
+	RepeatPenalty         *float64          `yaml:"repeat_penalty"`
+	RepeatLastN           *int              `yaml:"repeat_last_n"`
+	Mirostat              *int              `yaml:"mirostat"`
+	MirostatEta           *float64          `yaml:"mirostat_eta"`
+	MirostatTau           *float64          `yaml:"mirostat_tau"`
+	TfsZ                  *float64          `yaml:"tfs_z"`
+	Timeout               *string           `yaml:"timeout"`
+	MaxRetries            *int              `yaml:"max_retries"`
+	RetryDelay            *string           `yaml:"retry_delay"`
+	LogLevel              *string           `yaml:"log_level"`
+	Memory                *int              `yaml:"memory"`
+	MemorySummarization   *int              `yaml:"memory_summarization"`
+	ExtraHeaders          map[string]string `yaml:"extra_headers"`
+	EnableCaching         *bool             `yaml:"enable_caching"`
+	EnableStreaming       *bool             `yaml:"enable_streaming"`
+	StrictMode            *bool             `yaml:"strict_mode"`
+	MistralEmbeddingModel *string           `yaml:"mistral_embedding_model"`
+	AzureDeployment       *string           `yaml:"azure_deployment"`
+	AzureAPIVersion       *string           `yaml:"azure_api_version"`
+}
+
+// knownFileKeys lists the keys understood by LoadConfigFile and ConfigFromEnv.
+var knownFileKeys = map[string]bool{
+	"provider":                true,
+	"model":                   true,
+	"endpoint":                true,
+	"base_url":                true,
+	"api_key":                 true,
+	"temperature":             true,
+	"max_tokens":              true,
+	"top_p":                   true,
+	"top_k":                   true,
+	"frequency_penalty":       true,
+	"presence_penalty":        true,
+	"seed":                    true,
+	"stop_sequences":          true,
+	"min_p":                   true,
+	"repeat_penalty":          true,
+	"repeat_last_n":           true,
+	"mirostat":                true,
+	"mirostat_eta":            true,
+	"mirostat_tau":            true,
+	"tfs_z":                   true,
+	"timeout":                 true,
+	"max_retries":             true,
+	"retry_delay":             true,
+	"log_level":               true,
+	"memory":                  true,
+	"memory_summarization":    true,
+	"extra_headers":           true,
+	"enable_caching":          true,
+	"enable_streaming":        true,
+	"strict_mode":             true,
+	"mistral_embedding_model": true,
+	"azure_deployment":        true,
+	"azure_api_version":       true,
+}
+
+// LoadConfigFile reads a YAML or JSON configuration file and converts it into
+// ConfigOptions that can be passed to NewLLM. Only keys present in the file
+// produce options, so values not mentioned keep their defaults.
+//
+// Each key corresponds to the ConfigOption of the same name, e.g. max_tokens
+// to SetMaxTokens, memory to SetMemory and base_url to SetBaseURL. Options
+// taking Go values, such as SetTracer, SetRetryStrategy and SetPersona, have
+// no key. Durations use Go syntax ("30s", "2m"). String values may reference
+// environment variables as ${NAME}, which keeps secrets such as API keys out
+// of the file. Unknown keys are reported as warnings and otherwise ignored.
+//
+// The JSON Schema of the file is config/config.schema.json in the gollm
+// repository. Editors using the YAML language server pick it up from a
+// "# yaml-language-server: $schema=..." comment at the top of the file.
+//
+// Example file:
+//
+//	provider: openai
+//	model: gpt-4o-mini
+//	temperature: 0.2
+//	api_key: ${OPENAI_API_KEY}
+//	stop_sequences: ["\n\n"]
+//	timeout: 45s
+//	log_level: info
+//
+// Example usage:
+//
+//	opts, err := LoadConfigFile("config/production.yaml")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	cfg := NewConfig()
+//	ApplyOptions(cfg, opts...)
+func LoadConfigFile(path string) ([]ConfigOption, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	return parseConfigFile(path, data, utils.NewLogger(utils.LogLevelWarn))
+}
+
+// parseConfigFile converts the content of a configuration file into options.
+// YAML is a superset of JSON, so a single decoder handles both formats.
+func parseConfigFile(path string, data []byte, logger utils.Logger) ([]ConfigOption, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	unknown := make([]string, 0)
+	for key := range raw {
+		if !knownFileKeys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		logger.Warn("Ignoring unknown config file key", "key", key, "file", path)
+	}
+
+	var fc fileConfig
+	if err := yaml.Unmarshal(data, &fc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return fc.options("config file " + path)
+}
+
+// ConfigFromEnv reads the configuration keys of LoadConfigFile from
+// environment variables named after them with the given prefix, such as
+// MYAPP_PROVIDER, MYAPP_MODEL and MYAPP_MAX_TOKENS for the prefix "MYAPP".
+// stop_sequences is a comma-separated list and extra_headers a
+// comma-separated list of name=value pairs. Only variables that are set
+// produce options, so it combines with LoadConfigFile and explicit options.
+//
+// Unlike LoadConfig, which reads fixed LLM_* variables into a Config,
+// ConfigFromEnv returns options, which lets several clients of one process
+// read differently prefixed variables.
+//
+// Example usage:
+//
+//	opts, err := ConfigFromEnv("SUMMARIZER")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	llm, err := gollm.NewLLM(opts...)
+func ConfigFromEnv(prefix string) ([]ConfigOption, error) {
+	prefix = strings.TrimSuffix(strings.ToUpper(prefix), "_") + "_"
+	keys := make([]string, 0, len(knownFileKeys))
+	for key := range knownFileKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// The values are decoded as plain YAML scalars, so numbers and booleans
+	// are parsed the same way as in a configuration file.
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		value, ok := os.LookupEnv(prefix + strings.ToUpper(key))
+		if !ok {
+			continue
+		}
+		node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		switch key {
+		case "stop_sequences":
+			node = &yaml.Node{Kind: yaml.SequenceNode}
+			for _, sequence := range strings.Split(value, ",") {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: sequence})
+			}
+		case "extra_headers":
+			node = &yaml.Node{Kind: yaml.MappingNode}
+			for _, pair := range strings.Split(value, ",") {
+				name, headerValue, found := strings.Cut(pair, "=")
+				if !found {
+					return nil, fmt.Errorf("invalid %s%s: %q is not a name=value pair", prefix, strings.ToUpper(key), pair)
+				}
+				node.Content = append(node.Content,
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strings.TrimSpace(name)},
+					&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: strings.TrimSpace(headerValue)},
+				)
+			}
+		}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+	}
+
+	var fc fileConfig
+	if err := mapping.Decode(&fc); err != nil {
+		return nil, fmt.Errorf("failed to parse environment variables with prefix %s: %w", prefix, err)
+	}
+	return fc.options("environment variables with prefix " + prefix)
+}
+
+// options converts the keys that are set into options. source names where
+// they come from in error messages.
+func (fc *fileConfig) options(source string) ([]ConfigOption, error) {
+	var opts []ConfigOption
+	if fc.Provider != nil {
+		opts = append(opts, SetProvider(os.ExpandEnv(*fc.Provider)))
+	}
+	if fc.Model != nil {
+		opts = append(opts, SetModel(os.ExpandEnv(*fc.Model)))
+	}
+	if fc.Endpoint != nil {
+		opts = append(opts, SetEndpoint(os.ExpandEnv(*fc.Endpoint)))
+	}
+	if fc.BaseURL != nil {
+		opts = append(opts, SetBaseURL(os.ExpandEnv(*fc.BaseURL)))
+	}
+	if fc.APIKey != nil {
+		opts = append(opts, SetAPIKey(os.ExpandEnv(*fc.APIKey)))
+	}
+	if fc.Temperature != nil {
+		opts = append(opts, SetTemperature(*fc.Temperature))
+	}
+	if fc.MaxTokens != nil {
+		opts = append(opts, SetMaxTokens(*fc.MaxTokens))
+	}
+	if fc.TopP != nil {
+		opts = append(opts, SetTopP(*fc.TopP))
+	}
+	if fc.TopK != nil {
+		opts = append(opts, SetTopK(*fc.TopK))
+	}
+	if fc.FrequencyPenalty != nil {
+		opts = append(opts, SetFrequencyPenalty(*fc.FrequencyPenalty))
+	}
+	if fc.PresencePenalty != nil {
+		opts = append(opts, SetPresencePenalty(*fc.PresencePenalty))
+	}
+	if fc.Seed != nil {
+		opts = append(opts, SetSeed(*fc.Seed))
+	}
+	if fc.StopSequences != nil {
+		opts = append(opts, SetStopSequences(fc.StopSequences...))
+	}
+	if fc.MinP != nil {
+		opts = append(opts, SetMinP(*fc.MinP))
+	}
+	if fc.RepeatPenalty != nil {
+		opts = append(opts, SetRepeatPenalty(*fc.RepeatPenalty))
+	}
+	if fc.RepeatLastN != nil {
+		opts = append(opts, SetRepeatLastN(*fc.RepeatLastN))
+	}
+	if fc.Mirostat != nil {
+		opts = append(opts, SetMirostat(*fc.Mirostat))
+	}
+	if fc.MirostatEta != nil {
+		opts = append(opts, SetMirostatEta(*fc.MirostatEta))
+	}
+	if fc.MirostatTau != nil {
+		opts = append(opts, SetMirostatTau(*fc.MirostatTau))
+	}
+	if fc.TfsZ != nil {
+		opts = append(opts, SetTfsZ(*fc.TfsZ))
+	}
+	if fc.Timeout != nil {
+		timeout, err := time.ParseDuration(os.ExpandEnv(*fc.Timeout))
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout in %s: %w", source, err)
+		}
+		opts = append(opts, SetTimeout(timeout))
+	}
+	if fc.MaxRetries != nil {
+		opts = append(opts, SetMaxRetries(*fc.MaxRetries))
+	}
+	if fc.RetryDelay != nil {
+		delay, err := time.ParseDuration(os.ExpandEnv(*fc.RetryDelay))
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry_delay in %s: %w", source, err)
+		}
+		opts = append(opts, SetRetryDelay(delay))
+	}
+	if fc.LogLevel != nil {
+		var level utils.LogLevel
+		if err := level.UnmarshalText([]byte(os.ExpandEnv(*fc.LogLevel))); err != nil {
+			return nil, fmt.Errorf("invalid log_level in %s: %w", source, err)
+		}
+		opts = append(opts, SetLogLevel(level))
+	}
+	if fc.Memory != nil {
+		opts = append(opts, SetMemory(*fc.Memory))
+	}
+	if fc.MemorySummarization != nil {
+		opts = append(opts, SetMemorySummarization(*fc.MemorySummarization))
+	}
+	if fc.ExtraHeaders != nil {
+		headers := make(map[string]string, len(fc.ExtraHeaders))
+		for name, value := range fc.ExtraHeaders {
+			headers[name] = os.ExpandEnv(value)
+		}
+		opts = append(opts, SetExtraHeaders(headers))
+	}
+	if fc.EnableCaching != nil {
+		opts = append(opts, SetEnableCaching(*fc.EnableCaching))
+	}
+	if fc.EnableStreaming != nil {
+		opts = append(opts, WithStream(*fc.EnableStreaming))
+	}
+	if fc.StrictMode != nil {
+		opts = append(opts, SetStrictMode(*fc.StrictMode))
+	}
+	if fc.MistralEmbeddingModel != nil {
+		opts = append(opts, SetMistralEmbeddingModel(os.ExpandEnv(*fc.MistralEmbeddingModel)))
+	}
+	if fc.AzureDeployment != nil {
+		opts = append(opts, SetAzureDeployment(os.ExpandEnv(*fc.AzureDeployment)))
+	}
+	if fc.AzureAPIVersion != nil {
+		opts = append(opts, SetAzureAPIVersion(os.ExpandEnv(*fc.AzureAPIVersion)))
+	}
+	return opts, nil
+}
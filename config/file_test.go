@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestParseConfigFile_YAML(t *testing.T) {
+	t.Setenv("TEST_GOLLM_API_KEY", "secret-key")
+	data := []byte(`
+provider: openai
+model: gpt-4o-mini
+temperature: 0.2
+max_tokens: 500
+api_key: ${TEST_GOLLM_API_KEY}
+timeout: 45s
+max_retries: 5
+retry_delay: 1s
+log_level: debug
+`)
+	logger := new(utils.MockLogger)
+	opts, err := parseConfigFile("config.yaml", data, logger)
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	ApplyOptions(cfg, opts...)
+	assert.Equal(t, "openai", cfg.Provider)
+	assert.Equal(t, "gpt-4o-mini", cfg.Model)
+	assert.Equal(t, 0.2, cfg.Temperature)
+	assert.Equal(t, 500, cfg.MaxTokens)
+	assert.Equal(t, "secret-key", cfg.APIKeys["openai"])
+	assert.Equal(t, 45*time.Second, cfg.Timeout)
+	assert.Equal(t, 5, cfg.MaxRetries)
+	assert.Equal(t, time.Second, cfg.RetryDelay)
+	assert.Equal(t, utils.LogLevelDebug, cfg.LogLevel)
+	logger.AssertNotCalled(t, "Warn", mock.Anything, mock.Anything)
+}
+
+func TestParseConfigFile_JSONWithUnknownKey(t *testing.T) {
+	data := []byte(`{"provider": "anthropic", "temprature": 0.5}`)
+	logger := new(utils.MockLogger)
+	logger.On("Warn", "Ignoring unknown config file key", []interface{}{"key", "temprature", "file", "config.json"}).Return()
+
+	opts, err := parseConfigFile("config.json", data, logger)
+	require.NoError(t, err)
+	logger.AssertExpectations(t)
+
+	cfg := NewConfig()
+	ApplyOptions(cfg, opts...)
+	assert.Equal(t, "anthropic", cfg.Provider)
+	assert.Equal(t, 0.7, cfg.Temperature)
+}
+
+func TestParseConfigFile_InvalidValues(t *testing.T) {
+	_, err := parseConfigFile("config.yaml", []byte("timeout: soon"), utils.NewLogger(utils.LogLevelOff))
+	assert.ErrorContains(t, err, "invalid timeout")
+
+	_, err = parseConfigFile("config.yaml", []byte("log_level: loud"), utils.NewLogger(utils.LogLevelOff))
+	assert.ErrorContains(t, err, "invalid log_level")
+}
+
+func TestLoadConfigFile_MissingFile(t *testing.T) {
+	_, err := LoadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read config file")
+}
+
+func TestParseConfigFile_AllKeys(t *testing.T) {
+	t.Setenv("TEST_GOLLM_TRACE_ID", "trace-1")
+	data := []byte(`
+provider: azure
+model: gpt-4o
+base_url: https://my-resource.openai.azure.com
+azure_deployment: gpt-4o-prod
+azure_api_version: "2024-10-21"
+top_k: 40
+frequency_penalty: 0.5
+seed: 7
+stop_sequences: ["\n\n", END]
+memory: 4096
+memory_summarization: 10
+extra_headers:
+  X-Trace-Id: ${TEST_GOLLM_TRACE_ID}
+strict_mode: true
+`)
+	opts, err := parseConfigFile("config.yaml", data, utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	ApplyOptions(cfg, opts...)
+	assert.Equal(t, "https://my-resource.openai.azure.com", cfg.Endpoint)
+	assert.Equal(t, "gpt-4o-prod", cfg.AzureDeployment)
+	assert.Equal(t, "2024-10-21", cfg.AzureAPIVersion)
+	assert.Equal(t, 40, *cfg.TopK)
+	assert.Equal(t, 0.5, cfg.FrequencyPenalty)
+	assert.Equal(t, 7, *cfg.Seed)
+	assert.Equal(t, []string{"\n\n", "END"}, cfg.StopSequences)
+	assert.Equal(t, &MemoryOption{MaxTokens: 4096, SummarizeAfterTurns: 10}, cfg.MemoryOption)
+	assert.Equal(t, "trace-1", cfg.ExtraHeaders["X-Trace-Id"])
+	assert.True(t, cfg.StrictMode)
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("MYAPP_PROVIDER", "openai")
+	t.Setenv("MYAPP_MODEL", "4")
+	t.Setenv("MYAPP_MAX_TOKENS", "500")
+	t.Setenv("MYAPP_TEMPERATURE", "0.2")
+	t.Setenv("MYAPP_TIMEOUT", "45s")
+	t.Setenv("MYAPP_ENABLE_CACHING", "true")
+	t.Setenv("MYAPP_STOP_SEQUENCES", "END,###")
+	t.Setenv("MYAPP_EXTRA_HEADERS", "X-Team=search, X-Env=prod")
+	t.Setenv("OTHER_MODEL", "ignored")
+
+	opts, err := ConfigFromEnv("myapp_")
+	require.NoError(t, err)
+	cfg := NewConfig()
+	ApplyOptions(cfg, opts...)
+	assert.Equal(t, "openai", cfg.Provider)
+	assert.Equal(t, "4", cfg.Model, "numeric-looking strings stay strings")
+	assert.Equal(t, 500, cfg.MaxTokens)
+	assert.Equal(t, 0.2, cfg.Temperature)
+	assert.Equal(t, 45*time.Second, cfg.Timeout)
+	assert.True(t, cfg.EnableCaching)
+	assert.Equal(t, []string{"END", "###"}, cfg.StopSequences)
+	assert.Equal(t, map[string]string{"X-Team": "search", "X-Env": "prod"}, cfg.ExtraHeaders)
+	assert.Equal(t, 3, cfg.MaxRetries, "unset variables keep the defaults")
+
+	t.Setenv("MYAPP_MAX_TOKENS", "many")
+	_, err = ConfigFromEnv("MYAPP")
+	assert.ErrorContains(t, err, "prefix MYAPP_")
+	t.Setenv("MYAPP_MAX_TOKENS", "500")
+	t.Setenv("MYAPP_EXTRA_HEADERS", "X-Team")
+	_, err = ConfigFromEnv("MYAPP")
+	assert.ErrorContains(t, err, "invalid MYAPP_EXTRA_HEADERS")
+}
+
+func TestConfigSchema_MatchesFileKeys(t *testing.T) {
+	data, err := os.ReadFile("config.schema.json")
+	require.NoError(t, err)
+	var schema struct {
+		Properties map[string]interface{} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	fields := reflect.TypeOf(fileConfig{})
+	tags := make(map[string]bool, fields.NumField())
+	for i := 0; i < fields.NumField(); i++ {
+		tags[fields.Field(i).Tag.Get("yaml")] = true
+	}
+	assert.Equal(t, knownFileKeys, tags)
+	for key := range knownFileKeys {
+		assert.Contains(t, schema.Properties, key)
+	}
+	assert.Len(t, schema.Properties, len(knownFileKeys))
+}
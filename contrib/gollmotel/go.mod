@@ -0,0 +1,20 @@
+module github.com/yockii/gollm_cn/contrib/gollmotel
+
+go 1.22.5
+
+require (
+	github.com/yockii/gollm_cn v0.0.0
+	go.opentelemetry.io/otel v1.32.0
+	go.opentelemetry.io/otel/trace v1.32.0
+)
+
+require (
+	github.com/caarlos0/env/v11 v11.3.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/stretchr/objx v0.5.2 // indirect
+	github.com/stretchr/testify v1.10.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/yockii/gollm_cn => ../..
@@ -0,0 +1,85 @@
+// Package gollmotel provides OpenTelemetry tracing for gollm. It lives in its
+// own module so that applications which do not enable tracing do not depend
+// on OpenTelemetry.
+//
+// Example:
+//
+//	tracer := otel.Tracer("my-service")
+//	llm, err := gollm.NewLLM(
+//	    gollm.SetProvider("openai"),
+//	    gollmotel.WithTracing(tracer),
+//	)
+//
+// Each Generate, GenerateWithSchema and Stream call produces a client span
+// named "gollm.<operation>" that is a child of the span in the call's context.
+// Spans carry the GenAI semantic convention attributes gen_ai.system,
+// gen_ai.operation.name, gen_ai.request.model, gen_ai.usage.input_tokens and
+// gen_ai.usage.output_tokens, plus gollm.attempts and gollm.latency_ms.
+// Failed calls record the error and set the span status to Error.
+package gollmotel
+
+import (
+	"context"
+
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracing returns a configuration option that traces every LLM call with
+// the given OpenTelemetry tracer.
+func WithTracing(tracer trace.Tracer) config.ConfigOption {
+	return config.SetTracer(NewTracer(tracer))
+}
+
+// NewTracer adapts an OpenTelemetry tracer to gollm's utils.Tracer interface.
+func NewTracer(tracer trace.Tracer) utils.Tracer {
+	return &otelTracer{tracer: tracer}
+}
+
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+func (t *otelTracer) StartCall(ctx context.Context, info utils.CallInfo) (context.Context, utils.CallSpan) {
+	ctx, span := t.tracer.Start(ctx, "gollm."+info.Operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.system", info.Provider),
+			attribute.String("gen_ai.operation.name", info.Operation),
+			attribute.String("gen_ai.request.model", info.Model),
+			attribute.Bool("gollm.shadow", info.Shadow),
+		),
+	)
+	return ctx, &otelSpan{span: span}
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s *otelSpan) End(result utils.CallResult) {
+	s.span.SetAttributes(
+		attribute.Int("gollm.attempts", result.Attempts),
+		attribute.Int64("gollm.latency_ms", result.Latency.Milliseconds()),
+	)
+	if result.InputTokens > 0 {
+		s.span.SetAttributes(attribute.Int("gen_ai.usage.input_tokens", result.InputTokens))
+	}
+	if result.OutputTokens > 0 {
+		s.span.SetAttributes(attribute.Int("gen_ai.usage.output_tokens", result.OutputTokens))
+	}
+	if result.CacheCreationInputTokens > 0 {
+		s.span.SetAttributes(attribute.Int("gen_ai.usage.cache_creation_input_tokens", result.CacheCreationInputTokens))
+	}
+	if result.CacheReadInputTokens > 0 {
+		s.span.SetAttributes(attribute.Int("gen_ai.usage.cache_read_input_tokens", result.CacheReadInputTokens))
+	}
+	if result.Err != nil {
+		s.span.RecordError(result.Err)
+		s.span.SetStatus(codes.Error, result.Err.Error())
+	}
+	s.span.End()
+}
@@ -0,0 +1,111 @@
+// Package gollmprom provides Prometheus metrics for gollm. It lives in its own
+// module so that applications which do not export metrics do not depend on
+// the Prometheus client.
+//
+// Example:
+//
+//	collector, err := gollmprom.NewCollector(prometheus.DefaultRegisterer)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	llm, err := gollm.NewLLM(
+//	    gollm.SetProvider("openai"),
+//	    gollmprom.WithMetrics(collector),
+//	)
+//
+// The collector registers the following metrics, all labeled by provider,
+// model and shadow ("true" for shadow traffic sent by gollm.NewShadow):
+//
+//	gollm_requests_total            counter of Generate, GenerateWithSchema and Stream calls
+//	gollm_request_duration_seconds  histogram of call latency, including retries
+//	gollm_tokens_total              counter of tokens reported by the provider, by type (input, output)
+//	gollm_request_errors_total      counter of failed calls, by error type
+package gollmprom
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// WithMetrics returns a configuration option that records metrics for every
+// LLM call with the given collector.
+func WithMetrics(collector *Collector) config.ConfigOption {
+	return config.SetMetrics(collector)
+}
+
+// Collector implements utils.MetricsCollector with Prometheus metrics.
+type Collector struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	tokens   *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+}
+
+var _ utils.MetricsCollector = (*Collector)(nil)
+
+// NewCollector creates a Collector and registers its metrics with reg. Pass
+// prometheus.DefaultRegisterer to expose them on the default /metrics handler.
+// It returns an error if the metrics are already registered with reg.
+func NewCollector(reg prometheus.Registerer) (*Collector, error) {
+	c := &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gollm_requests_total",
+			Help: "Total number of LLM calls.",
+		}, []string{"provider", "model", "shadow"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "gollm_request_duration_seconds",
+			Help:    "Duration of LLM calls in seconds, including retries.",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"provider", "model", "shadow"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gollm_tokens_total",
+			Help: "Total number of tokens reported by LLM providers.",
+		}, []string{"provider", "model", "shadow", "type"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "gollm_request_errors_total",
+			Help: "Total number of failed LLM calls.",
+		}, []string{"provider", "model", "shadow", "error_type"}),
+	}
+	for _, collector := range []prometheus.Collector{c.requests, c.duration, c.tokens, c.errors} {
+		if err := reg.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// RecordRequest implements utils.MetricsCollector.
+func (c *Collector) RecordRequest(info utils.CallInfo) {
+	c.requests.WithLabelValues(info.Provider, info.Model, shadowLabel(info)).Inc()
+}
+
+// RecordLatency implements utils.MetricsCollector.
+func (c *Collector) RecordLatency(info utils.CallInfo, latency time.Duration) {
+	c.duration.WithLabelValues(info.Provider, info.Model, shadowLabel(info)).Observe(latency.Seconds())
+}
+
+// RecordTokens implements utils.MetricsCollector.
+func (c *Collector) RecordTokens(info utils.CallInfo, inputTokens, outputTokens int) {
+	if inputTokens > 0 {
+		c.tokens.WithLabelValues(info.Provider, info.Model, shadowLabel(info), "input").Add(float64(inputTokens))
+	}
+	if outputTokens > 0 {
+		c.tokens.WithLabelValues(info.Provider, info.Model, shadowLabel(info), "output").Add(float64(outputTokens))
+	}
+}
+
+// RecordError implements utils.MetricsCollector.
+func (c *Collector) RecordError(info utils.CallInfo, errorType string) {
+	c.errors.WithLabelValues(info.Provider, info.Model, shadowLabel(info), errorType).Inc()
+}
+
+// shadowLabel returns the "shadow" label value, "true" for shadow traffic
+// mirrored to a candidate model so it can be excluded from production
+// dashboards.
+func shadowLabel(info utils.CallInfo) string {
+	return strconv.FormatBool(info.Shadow)
+}
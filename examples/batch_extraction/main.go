@@ -0,0 +1,121 @@
+// Command batch_extraction extracts structured records from a JSONL file of
+// texts and writes them back out as JSONL.
+//
+// Each input line is an object with an "id" and a "text":
+//
+//	{"id": "1", "text": "杭州是中国浙江省的省会，人口约 1200 万。"}
+//
+// Each output line carries the id, the extracted record or the error, and the
+// tokens used:
+//
+//	{"id": "1", "record": {"name": "杭州", "country": "中国", "population": 12000000}, "usage": {...}}
+//
+// Usage:
+//
+//	go run ./examples/batch_extraction cities.jsonl > records.jsonl
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/presets"
+)
+
+// City is the record extracted from each text.
+type City struct {
+	Name       string `json:"name" validate:"required"`
+	Country    string `json:"country" validate:"required"`
+	Population int    `json:"population" validate:"gte=0"`
+}
+
+type inputLine struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+type outputLine struct {
+	ID     string           `json:"id"`
+	Record *City            `json:"record,omitempty"`
+	Error  string           `json:"error,omitempty"`
+	Usage  gollm.TokenUsage `json:"usage"`
+}
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatalf("Usage: %s <input.jsonl>", os.Args[0])
+	}
+	inputs, err := readInputs(os.Args[1])
+	if err != nil {
+		log.Fatalf("Failed to read input: %v", err)
+	}
+
+	llmClient, err := gollm.NewLLM(gollm.SetMaxRetries(3))
+	if err != nil {
+		log.Fatalf("Failed to create LLM client: %v", err)
+	}
+
+	// Ctrl-C stops starting new extractions; finished ones are still written.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	texts := make([]string, len(inputs))
+	for i, input := range inputs {
+		texts[i] = input.Text
+	}
+	results, err := presets.ExtractStructuredDataBatch[City](ctx, llmClient, texts,
+		presets.WithConcurrency(8),
+		presets.WithExtractionRetries(1),
+	)
+	if err != nil {
+		log.Printf("Batch stopped early: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	var failed int
+	var usage gollm.TokenUsage
+	for _, r := range results {
+		line := outputLine{ID: inputs[r.Index].ID, Record: r.Result, Usage: r.Usage}
+		if r.Err != nil {
+			line.Error = r.Err.Error()
+			failed++
+		}
+		usage.InputTokens += r.Usage.InputTokens
+		usage.OutputTokens += r.Usage.OutputTokens
+		if err := encoder.Encode(line); err != nil {
+			log.Fatalf("Failed to write output: %v", err)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d records, %d failed, %d input and %d output tokens\n",
+		len(results), failed, usage.InputTokens, usage.OutputTokens)
+}
+
+// readInputs reads one inputLine per non-empty line of the file at path.
+func readInputs(path string) ([]inputLine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var inputs []inputLine
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var input inputLine
+		if err := json.Unmarshal(scanner.Bytes(), &input); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, scanner.Err()
+}
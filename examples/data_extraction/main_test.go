@@ -145,13 +145,15 @@ func TestExtractReview(t *testing.T) {
 
 		review := MovieReview{}
 		extracted, err := presets.ExtractStructuredData[MovieReview](ctx, llm, text,
-			gollm.WithDirectives(
-				"Extract all relevant information from the text",
-				"Ensure the output is a valid JSON object",
-				"Do not include any backticks or formatting in the response",
-				"Return only the JSON object",
+			presets.WithPromptOptions(
+				gollm.WithDirectives(
+					"Extract all relevant information from the text",
+					"Ensure the output is a valid JSON object",
+					"Do not include any backticks or formatting in the response",
+					"Return only the JSON object",
+				),
+				gollm.WithOutput("JSON object only"),
 			),
-			gollm.WithOutput("JSON object only"),
 		)
 		assert.NoError(t, err, "Should extract review without validation")
 		assert.NotNil(t, extracted, "Review should not be nil")
@@ -173,13 +175,15 @@ func TestExtractReview(t *testing.T) {
 
 		review := MovieReviewValidated{}
 		extracted, err := presets.ExtractStructuredData[MovieReviewValidated](ctx, llm, text,
-			gollm.WithDirectives(
-				"Extract all relevant information from the text",
-				"Ensure the output is a valid JSON object",
-				"Do not include any backticks or formatting in the response",
-				"Return only the JSON object",
+			presets.WithPromptOptions(
+				gollm.WithDirectives(
+					"Extract all relevant information from the text",
+					"Ensure the output is a valid JSON object",
+					"Do not include any backticks or formatting in the response",
+					"Return only the JSON object",
+				),
+				gollm.WithOutput("JSON object only"),
 			),
-			gollm.WithOutput("JSON object only"),
 		)
 		assert.NoError(t, err, "Should extract review with validation")
 		assert.NotNil(t, extracted, "Review should not be nil")
@@ -213,13 +217,15 @@ func TestExtractReview(t *testing.T) {
 		// Extract both reviews concurrently
 		go func() {
 			extracted1, err1 = presets.ExtractStructuredData[MovieReview](ctx, llm, text,
-				gollm.WithDirectives(
-					"Extract all relevant information from the text",
-					"Ensure the output is a valid JSON object",
-					"Do not include any backticks or formatting in the response",
-					"Return only the JSON object",
+				presets.WithPromptOptions(
+					gollm.WithDirectives(
+						"Extract all relevant information from the text",
+						"Ensure the output is a valid JSON object",
+						"Do not include any backticks or formatting in the response",
+						"Return only the JSON object",
+					),
+					gollm.WithOutput("JSON object only"),
 				),
-				gollm.WithOutput("JSON object only"),
 			)
 			if err1 == nil && extracted1 != nil {
 				review1 = *extracted1
@@ -229,13 +235,15 @@ func TestExtractReview(t *testing.T) {
 
 		go func() {
 			extracted2, err2 = presets.ExtractStructuredData[MovieReviewValidated](ctx, llm, text,
-				gollm.WithDirectives(
-					"Extract all relevant information from the text",
-					"Ensure the output is a valid JSON object",
-					"Do not include any backticks or formatting in the response",
-					"Return only the JSON object",
+				presets.WithPromptOptions(
+					gollm.WithDirectives(
+						"Extract all relevant information from the text",
+						"Ensure the output is a valid JSON object",
+						"Do not include any backticks or formatting in the response",
+						"Return only the JSON object",
+					),
+					gollm.WithOutput("JSON object only"),
 				),
-				gollm.WithOutput("JSON object only"),
 			)
 			if err2 == nil && extracted2 != nil {
 				review2 = *extracted2
@@ -279,37 +287,43 @@ func TestExtractReview(t *testing.T) {
 	t.Run("error_handling", func(t *testing.T) {
 		// Test with empty text
 		_, err := presets.ExtractStructuredData[MovieReview](ctx, llm, "",
-			gollm.WithDirectives(
-				"Extract all relevant information from the text",
-				"Ensure the output is a valid JSON object",
-				"Do not include any backticks or formatting in the response",
-				"Return only the JSON object",
+			presets.WithPromptOptions(
+				gollm.WithDirectives(
+					"Extract all relevant information from the text",
+					"Ensure the output is a valid JSON object",
+					"Do not include any backticks or formatting in the response",
+					"Return only the JSON object",
+				),
+				gollm.WithOutput("JSON object only"),
 			),
-			gollm.WithOutput("JSON object only"),
 		)
 		assert.Error(t, err, "Should fail with empty text")
 
 		// Test with invalid text
 		_, err = presets.ExtractStructuredData[MovieReview](ctx, llm, "Not a movie review",
-			gollm.WithDirectives(
-				"Extract all relevant information from the text",
-				"Ensure the output is a valid JSON object",
-				"Do not include any backticks or formatting in the response",
-				"Return only the JSON object",
+			presets.WithPromptOptions(
+				gollm.WithDirectives(
+					"Extract all relevant information from the text",
+					"Ensure the output is a valid JSON object",
+					"Do not include any backticks or formatting in the response",
+					"Return only the JSON object",
+				),
+				gollm.WithOutput("JSON object only"),
 			),
-			gollm.WithOutput("JSON object only"),
 		)
 		assert.Error(t, err, "Should fail with invalid text")
 
 		// Test with nil context
 		_, err = presets.ExtractStructuredData[MovieReview](nil, llm, "Some text",
-			gollm.WithDirectives(
-				"Extract all relevant information from the text",
-				"Ensure the output is a valid JSON object",
-				"Do not include any backticks or formatting in the response",
-				"Return only the JSON object",
+			presets.WithPromptOptions(
+				gollm.WithDirectives(
+					"Extract all relevant information from the text",
+					"Ensure the output is a valid JSON object",
+					"Do not include any backticks or formatting in the response",
+					"Return only the JSON object",
+				),
+				gollm.WithOutput("JSON object only"),
 			),
-			gollm.WithOutput("JSON object only"),
 		)
 		assert.Error(t, err, "Should fail with nil context")
 	})
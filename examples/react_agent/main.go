@@ -0,0 +1,178 @@
+// Command react_agent answers a question with presets.ReActAgent, using a
+// calculator tool and a mock search tool.
+//
+// Usage:
+//
+//	OPENAI_API_KEY=... go run ./examples/react_agent
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/presets"
+)
+
+// searchResults are the canned results of the mock search tool.
+var searchResults = map[string]string{
+	"高铁票价": "北京南到上海虹桥的 G1 次高铁二等座票价为 553 元，一等座为 933 元。",
+	"人口":   "2023 年末上海市常住人口为 2487 万人。",
+}
+
+func main() {
+	llm, err := gollm.NewLLM(
+		gollm.SetProvider("openai"),
+		gollm.SetModel("gpt-4o-mini"),
+		gollm.SetAPIKey(os.Getenv("OPENAI_API_KEY")),
+		gollm.SetMaxTokens(500),
+		gollm.SetLogLevel(gollm.LogLevelDebug),
+	)
+	if err != nil {
+		log.Fatalf("Failed to create LLM client: %v", err)
+	}
+
+	search := gollm.Tool{
+		Type: "function",
+		Function: gollm.Function{
+			Name:        "search",
+			Description: "搜索网页，返回相关的事实",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query": map[string]interface{}{"type": "string", "description": "搜索关键词"},
+				},
+				"required": []string{"query"},
+			},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			query, _ := args["query"].(string)
+			for keyword, result := range searchResults {
+				if strings.Contains(query, keyword) {
+					return result, nil
+				}
+			}
+			return "没有找到相关结果。", nil
+		},
+	}
+	calculator := gollm.Tool{
+		Type: "function",
+		Function: gollm.Function{
+			Name:        "calculator",
+			Description: "计算算术表达式，支持 + - * / 和括号",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"expression": map[string]interface{}{"type": "string", "description": "算术表达式，例如 (553 + 933) * 2"},
+				},
+				"required": []string{"expression"},
+			},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			expression, _ := args["expression"].(string)
+			value, err := evaluate(expression)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatFloat(value, 'f', -1, 64), nil
+		},
+	}
+
+	result, err := presets.ReActAgent(context.Background(), llm,
+		"一家三口从北京坐高铁去上海，两人坐二等座、一人坐一等座，一共要花多少钱？",
+		[]gollm.Tool{search, calculator},
+		presets.WithMaxSteps(6),
+	)
+	if err != nil {
+		log.Fatalf("Agent failed: %v", err)
+	}
+
+	for i, step := range result.Steps {
+		fmt.Printf("第 %d 步：%s\n", i+1, step.Thought)
+		for _, action := range step.Actions {
+			fmt.Printf("  %s %v -> %s\n", action.Tool, action.Arguments, action.Observation)
+		}
+	}
+	fmt.Printf("\n答案：%s\n使用 %d 个 token\n", result.Answer, result.Usage.InputTokens+result.Usage.OutputTokens)
+}
+
+// evaluate computes an arithmetic expression of numbers, + - * / and
+// parentheses.
+func evaluate(expression string) (float64, error) {
+	p := &parser{input: strings.ReplaceAll(expression, " ", "")}
+	value, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+	return value, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) sum() (float64, error) {
+	value, err := p.product()
+	for err == nil && p.pos < len(p.input) && strings.IndexByte("+-", p.input[p.pos]) >= 0 {
+		op := p.input[p.pos]
+		p.pos++
+		var rhs float64
+		if rhs, err = p.product(); op == '+' {
+			value += rhs
+		} else {
+			value -= rhs
+		}
+	}
+	return value, err
+}
+
+func (p *parser) product() (float64, error) {
+	value, err := p.operand()
+	for err == nil && p.pos < len(p.input) && strings.IndexByte("*/", p.input[p.pos]) >= 0 {
+		op := p.input[p.pos]
+		p.pos++
+		var rhs float64
+		if rhs, err = p.operand(); err != nil {
+			break
+		}
+		if op == '*' {
+			value *= rhs
+		} else if rhs == 0 {
+			return 0, fmt.Errorf("division by zero")
+		} else {
+			value /= rhs
+		}
+	}
+	return value, err
+}
+
+func (p *parser) operand() (float64, error) {
+	if p.pos < len(p.input) && p.input[p.pos] == '(' {
+		p.pos++
+		value, err := p.sum()
+		if err != nil {
+			return 0, err
+		}
+		if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+			return 0, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return value, nil
+	}
+	start := p.pos
+	for p.pos < len(p.input) && (p.input[p.pos] == '.' || p.input[p.pos] >= '0' && p.input[p.pos] <= '9') {
+		p.pos++
+	}
+	if start == p.pos {
+		return 0, fmt.Errorf("expected a number at position %d", start)
+	}
+	return strconv.ParseFloat(p.input[start:p.pos], 64)
+}
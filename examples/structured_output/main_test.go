@@ -61,11 +61,11 @@ func TestBasicStructuredOutput(t *testing.T) {
 	He enjoys hiking, photography, and playing guitar in his free time.`
 
 	person, err := presets.ExtractStructuredData[TestPersonInfo](ctx, llm, text,
-		gollm.WithDirectives(
+		presets.WithPromptOptions(gollm.WithDirectives(
 			"Extract information into JSON format",
 			"Ensure the output is valid JSON",
 			"Do not include markdown formatting",
-		),
+		)),
 	)
 	require.NoError(t, err)
 	require.NotNil(t, person)
@@ -87,12 +87,12 @@ func TestComplexStructuredOutput(t *testing.T) {
 	She prefers to be contacted by email.`
 
 	person, err := presets.ExtractStructuredData[ComplexPerson](ctx, llm, text,
-		gollm.WithDirectives(
+		presets.WithPromptOptions(gollm.WithDirectives(
 			"Extract information into JSON format",
 			"Ensure the output is valid JSON",
 			"Do not include markdown formatting",
 			"Include all contact and address information",
-		),
+		)),
 	)
 	require.NoError(t, err)
 	require.NotNil(t, person)
@@ -120,21 +120,21 @@ func TestStructuredOutputValidation(t *testing.T) {
 
 	// Test with insufficient information
 	_, err := presets.ExtractStructuredData[TestPersonInfo](ctx, llm, "Just a random text without any relevant information.",
-		gollm.WithDirectives("Do not include markdown formatting"),
+		presets.WithPromptOptions(gollm.WithDirectives("Do not include markdown formatting")),
 	)
 	assert.Error(t, err, "Should error with insufficient information")
 
 	// Test with invalid age
 	text := `Invalid Person is a -5 year old student who likes reading.`
 	_, err = presets.ExtractStructuredData[TestPersonInfo](ctx, llm, text,
-		gollm.WithDirectives("Do not include markdown formatting"),
+		presets.WithPromptOptions(gollm.WithDirectives("Do not include markdown formatting")),
 	)
 	assert.Error(t, err, "Should error with invalid age")
 
 	// Test with too many hobbies
 	text = `Bob likes too many things: reading, writing, arithmetic, cooking, baking, gaming, swimming, running, cycling, and yoga.`
 	_, err = presets.ExtractStructuredData[TestPersonInfo](ctx, llm, text,
-		gollm.WithDirectives("Do not include markdown formatting"),
+		presets.WithPromptOptions(gollm.WithDirectives("Do not include markdown formatting")),
 	)
 	assert.Error(t, err, "Should error with too many hobbies")
 }
@@ -0,0 +1,44 @@
+// Package gollm provides strict mode for Language Learning Model interactions.
+// This file re-exports the fallback policy that decides whether the library may
+// silently repair model output or fall back to defaults.
+package gollm
+
+import (
+	"github.com/yockii/gollm_cn/llm"
+)
+
+type (
+	// Intervention names a silent fallback or repair, see the Intervention constants.
+	Intervention = llm.Intervention
+
+	// InterventionError is returned in strict mode instead of applying an intervention.
+	//
+	// Example usage:
+	//   var interventionErr *InterventionError
+	//   if errors.As(err, &interventionErr) {
+	//       log.Printf("refused %s: %s", interventionErr.Intervention, interventionErr.Detail)
+	//   }
+	InterventionError = llm.InterventionError
+
+	// FallbackPolicy decides whether interventions may be applied for a call.
+	FallbackPolicy = llm.FallbackPolicy
+)
+
+// Interventions affected by strict mode.
+const (
+	InterventionJSONExtraction     = llm.InterventionJSONExtraction     // Stripping fences or prose around JSON responses
+	InterventionGradeNormalization = llm.InterventionGradeNormalization // Converting numeric optimizer grades to letters
+	InterventionTokenizerFallback  = llm.InterventionTokenizerFallback  // Counting memory or truncation tokens with the gpt-4o tokenizer
+)
+
+var (
+	// WithStrictMode returns a context that enables strict mode for calls made
+	// with it. Use SetStrictMode to enable it for every call of a client.
+	WithStrictMode = llm.WithStrictMode
+
+	// IsStrictMode reports whether a context was created by WithStrictMode.
+	IsStrictMode = llm.IsStrictMode
+
+	// NewFallbackPolicy creates a fallback policy, e.g. for custom presets.
+	NewFallbackPolicy = llm.NewFallbackPolicy
+)
@@ -47,10 +47,10 @@ type llmImpl struct {
 	config   *config.Config
 }
 
-// SetSystemPrompt sets the system prompt for the LLM.
+// SetSystemPrompt sets a system prompt that is sent with every call. A system
+// prompt set on the Prompt itself with WithSystemPrompt takes precedence.
 func (l *llmImpl) SetSystemPrompt(prompt string, cacheType CacheType) {
-	newPrompt := NewPrompt(prompt, WithSystemPrompt(prompt, cacheType))
-	l.SetOption("system_prompt", newPrompt)
+	l.SetOption("system_prompt", prompt)
 }
 
 // GetProvider returns the provider of the LLM.
@@ -80,8 +80,10 @@ func (l *llmImpl) SetOption(key string, value interface{}) {
 	l.logger.Debug("Option set successfully")
 }
 
+// SetEndpoint updates the base URL of the provider's API, see SetBaseURL.
 func (l *llmImpl) SetEndpoint(endpoint string) {
 	l.provider.SetEndpoint(endpoint)
+	l.LLM.SetEndpoint(endpoint)
 }
 
 // GetPromptJSONSchema generates and returns the JSON schema for the Prompt.
@@ -123,6 +125,47 @@ func (l *llmImpl) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.
 	return response, nil
 }
 
+// Embed forwards to the internal LLM if it is an Embedder.
+func (l *llmImpl) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	embedder, ok := l.LLM.(llm.Embedder)
+	if !ok {
+		return nil, llm.NewLLMError(llm.ErrorTypeUnsupported, "LLM cannot create embeddings", llm.ErrProviderDoesNotSupportEmbeddings)
+	}
+	return embedder.Embed(ctx, inputs)
+}
+
+// SubmitBatch forwards to the internal LLM if it is a Batcher, and otherwise
+// runs the prompts as concurrent Generate calls.
+func (l *llmImpl) SubmitBatch(ctx context.Context, prompts []*llm.Prompt, opts ...llm.BatchOption) (*llm.BatchJob, error) {
+	return llm.SubmitBatch(ctx, l.LLM, prompts, opts...)
+}
+
+// Rerank forwards to the internal LLM if it is a Reranker.
+func (l *llmImpl) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RankedDocument, error) {
+	reranker, ok := l.LLM.(llm.Reranker)
+	if !ok {
+		return nil, llm.NewLLMError(llm.ErrorTypeUnsupported, "LLM cannot rerank documents", llm.ErrProviderDoesNotSupportRerank)
+	}
+	return reranker.Rerank(ctx, query, documents, topN)
+}
+
+// GetLastLogprobs forwards to the internal LLM if it is a LogprobsReporter.
+func (l *llmImpl) GetLastLogprobs() []TokenLogprob {
+	if reporter, ok := l.LLM.(llm.LogprobsReporter); ok {
+		return reporter.GetLastLogprobs()
+	}
+	return nil
+}
+
+// Moderate forwards to the internal LLM if it is a Moderator.
+func (l *llmImpl) Moderate(ctx context.Context, text string) (*ModerationResult, error) {
+	moderator, ok := l.LLM.(llm.Moderator)
+	if !ok {
+		return nil, llm.NewLLMError(llm.ErrorTypeUnsupported, "LLM cannot moderate text", nil)
+	}
+	return moderator.Moderate(ctx, text)
+}
+
 // NewLLM creates a new LLM instance with the specified configuration options.
 // It supports memory management, caching, and provider-specific optimizations.
 // If memory options are provided, it creates an LLM instance with conversation memory.
@@ -132,11 +175,13 @@ func (l *llmImpl) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.
 // 2. Initializes logging system with appropriate verbosity
 // 3. Sets up provider-specific optimizations (e.g., Anthropic caching headers)
 // 4. Creates and configures the base LLM instance
-// 5. Optionally enables conversation memory if specified in config
+// 5. Optionally enables the semantic cache and conversation memory if
+// specified in config
 //
 // Returns an error if:
 // - Configuration loading fails
 // - Provider initialization fails
+// - The semantic cache settings are invalid (if the cache is enabled)
 // - Memory setup fails (if memory option is enabled)
 func NewLLM(opts ...ConfigOption) (LLM, error) {
 	cfg, err := LoadConfig()
@@ -154,6 +199,9 @@ func NewLLM(opts ...ConfigOption) (LLM, error) {
 	}
 
 	logger := utils.NewLogger(cfg.LogLevel)
+	if cfg.SlogHandler != nil {
+		logger = utils.NewSlogLogger(cfg.SlogHandler)
+	}
 
 	if cfg.Provider == "anthropic" && cfg.EnableCaching {
 		if cfg.ExtraHeaders == nil {
@@ -173,8 +221,17 @@ func NewLLM(opts ...ConfigOption) (LLM, error) {
 		return nil, fmt.Errorf("failed to get provider: %w", err)
 	}
 
+	generator := baseLLM
+	if cfg.SemanticCache != nil {
+		cached, err := llm.NewLLMWithSemanticCache(baseLLM, cfg.SemanticCache.Embed, cfg.SemanticCache.Threshold, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create semantic cache: %w", err)
+		}
+		generator = cached
+	}
+
 	llmInstance := &llmImpl{
-		LLM:      baseLLM,
+		LLM:      generator,
 		provider: provider,
 		logger:   logger,
 		model:    cfg.Model,
@@ -182,13 +239,47 @@ func NewLLM(opts ...ConfigOption) (LLM, error) {
 	}
 
 	if cfg.MemoryOption != nil {
-		llmWithMemory, err := llm.NewLLMWithMemory(baseLLM, cfg.MemoryOption.MaxTokens, cfg.Model, logger)
+		if cfg.MemoryOption.MaxTokens <= 0 && cfg.MemoryOption.SummarizeAfterTurns > 0 {
+			return nil, fmt.Errorf("memory summarization requires SetMemory")
+		}
+		// The cache sits below memory, so it compares whole conversations
+		// rather than their last messages.
+		llmWithMemory, err := llm.NewLLMWithMemory(generator, cfg.MemoryOption.MaxTokens, cfg.Model, logger)
 		if err != nil {
 			logger.Error("Failed to create LLM with memory", "error", err)
 			return nil, fmt.Errorf("failed to create LLM with memory: %w", err)
 		}
+		if cfg.MemoryOption.SummarizeAfterTurns > 0 {
+			llmWithMemory.SetSummarizer(llm.NewConversationSummarizer(baseLLM, cfg.MemoryOption.SummarizeAfterTurns))
+		}
 		llmInstance.LLM = llmWithMemory
 	}
 
 	return llmInstance, nil
 }
+
+// NewLLMFromConfig creates a new LLM instance from a YAML or JSON
+// configuration file (see LoadConfigFile for its keys). Options loaded from
+// the file are applied first, so the overrides passed to this function win
+// over the file's values.
+//
+// Example usage:
+//
+//	llm, err := NewLLMFromConfig("config/production.yaml", SetLogLevel(LogLevelDebug))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+func NewLLMFromConfig(path string, overrides ...ConfigOption) (LLM, error) {
+	fileOpts, err := config.LoadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewLLM(append(fileOpts, overrides...)...)
+}
+
+// NewLLMFromFile creates a new LLM instance from a YAML or JSON configuration file.
+//
+// Deprecated: Use NewLLMFromConfig, which behaves the same.
+func NewLLMFromFile(path string, opts ...ConfigOption) (LLM, error) {
+	return NewLLMFromConfig(path, opts...)
+}
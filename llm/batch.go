@@ -0,0 +1,625 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// Statuses of a finished BatchJob. Native batches end with the status
+// reported by the provider, e.g. BatchStatusCompleted or "expired".
+const (
+	BatchStatusCompleted = "completed" // Every prompt was run; some may have failed
+	BatchStatusFailed    = "failed"    // The batch could not be run or followed
+	BatchStatusCancelled = "cancelled" // The batch was stopped with BatchJob.Cancel
+)
+
+// Batcher is implemented by LLMs that can run a batch of prompts. It is not
+// part of the LLM interface; use SubmitBatch, which falls back to concurrent
+// Generate calls for LLMs that are not Batchers.
+type Batcher interface {
+	// SubmitBatch starts running prompts and returns the job running them.
+	SubmitBatch(ctx context.Context, prompts []*Prompt, opts ...BatchOption) (*BatchJob, error)
+}
+
+// BatchOption configures SubmitBatch.
+type BatchOption func(*batchConfig)
+
+type batchConfig struct {
+	concurrency  int
+	pollInterval time.Duration
+	callbackURL  string
+	generateOpts []GenerateOption
+	onResult     func(BatchResult)
+	individual   bool
+	client       *http.Client
+	logger       utils.Logger
+}
+
+// WithBatchConcurrency limits how many prompts a batch runs at the same time
+// when it falls back to individual calls. The default is 5; values below 1
+// are treated as 1.
+func WithBatchConcurrency(n int) BatchOption {
+	return func(c *batchConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithBatchPollInterval sets how often the status of a native batch is
+// checked. The default is 30 seconds.
+func WithBatchPollInterval(d time.Duration) BatchOption {
+	return func(c *batchConfig) {
+		c.pollInterval = d
+	}
+}
+
+// WithBatchCallbackURL makes the job POST a BatchNotification, as JSON, to
+// url when it finishes, so that a service learns of the results without
+// waiting for them. Failed notifications are logged and not retried.
+func WithBatchCallbackURL(url string) BatchOption {
+	return func(c *batchConfig) {
+		c.callbackURL = url
+	}
+}
+
+// WithBatchGenerateOptions applies generate options, such as WithTemperature,
+// to every prompt of the batch.
+func WithBatchGenerateOptions(opts ...GenerateOption) BatchOption {
+	return func(c *batchConfig) {
+		c.generateOpts = append(c.generateOpts, opts...)
+	}
+}
+
+// WithBatchResultHandler calls fn with the result of each prompt as soon as it
+// is known, in the order the prompts finish, so that results can be written
+// out before the whole batch is done. Batches of individual calls finish
+// prompts one by one and call fn concurrently; native batches call fn for
+// every prompt once the batch has ended. All calls return before Wait does.
+func WithBatchResultHandler(fn func(BatchResult)) BatchOption {
+	return func(c *batchConfig) {
+		c.onResult = fn
+	}
+}
+
+// WithBatchIndividualCalls runs the batch as individual Generate calls, at
+// most WithBatchConcurrency at once, even when the provider has a batch API.
+// Results then arrive within minutes instead of hours, at the usual price.
+func WithBatchIndividualCalls() BatchOption {
+	return func(c *batchConfig) {
+		c.individual = true
+	}
+}
+
+// BatchResult is the outcome of one prompt of a batch.
+type BatchResult struct {
+	Index    int        // Position of the prompt in the batch
+	Response string     // The response, empty if Err is set
+	Err      error      // Why the prompt failed, if it did
+	Usage    TokenUsage // Tokens used for the prompt, as reported by the provider
+}
+
+// BatchNotification is sent to the URL set with WithBatchCallbackURL when a
+// batch finishes.
+type BatchNotification struct {
+	BatchID string `json:"batch_id"`        // The provider's batch ID, empty for batches of individual calls
+	Status  string `json:"status"`          // How the batch ended, e.g. BatchStatusCompleted
+	Total   int    `json:"total"`           // Number of prompts
+	Failed  int    `json:"failed"`          // Number of prompts that failed
+	Error   string `json:"error,omitempty"` // Why the batch failed as a whole, if it did
+}
+
+// BatchJob is a batch of prompts started by SubmitBatch. It runs in the
+// background until every prompt has a result or it is canceled with Cancel.
+type BatchJob struct {
+	ID string // The provider's batch ID, empty for batches of individual calls
+
+	total   int
+	cancel  context.CancelFunc
+	done    chan struct{}
+	results []BatchResult
+	err     error
+}
+
+// newBatchJob returns a job of total prompts and the context it runs with,
+// which outlives ctx and is canceled by Cancel.
+func newBatchJob(ctx context.Context, id string, total int) (*BatchJob, context.Context) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	return &BatchJob{ID: id, total: total, cancel: cancel, done: make(chan struct{})}, ctx
+}
+
+// Cancel stops the job. Calls in progress are canceled and prompts not yet
+// run fail; a native batch stops being polled and is canceled at the
+// provider. The job then finishes with BatchStatusCancelled, and Wait returns
+// the results known so far with an error wrapping context.Canceled. Cancel
+// does not wait for the job to finish; it does nothing once it has.
+func (j *BatchJob) Cancel() {
+	j.cancel()
+}
+
+// Done returns a channel closed when the job has finished.
+func (j *BatchJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait waits for the job to finish and returns its results. Canceling ctx
+// stops waiting, not the job.
+//
+// Returns:
+//   - One result per prompt, in the order of the prompts. The error of each
+//     prompt is in its result
+//   - An error if the batch did not complete, e.g. because the provider
+//     rejected it or it expired, with the results of the prompts that ran;
+//     ctx.Err() if ctx is done first
+func (j *BatchJob) Wait(ctx context.Context) ([]BatchResult, error) {
+	select {
+	case <-j.done:
+		return j.results, j.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// finish records the outcome of the job, wakes its waiters and sends the
+// notification set with WithBatchCallbackURL.
+func (j *BatchJob) finish(ctx context.Context, cfg *batchConfig, status string, results []BatchResult, err error) {
+	j.results, j.err = results, err
+	close(j.done)
+	j.cancel()
+	if cfg.callbackURL == "" {
+		return
+	}
+	notification := BatchNotification{BatchID: j.ID, Status: status, Total: j.total}
+	for _, result := range results {
+		if result.Err != nil {
+			notification.Failed++
+		}
+	}
+	if err != nil {
+		notification.Error = err.Error()
+	}
+	body, _ := json.Marshal(notification)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	req, reqErr := http.NewRequestWithContext(ctx, "POST", cfg.callbackURL, bytes.NewReader(body))
+	if reqErr != nil {
+		cfg.logger.Warn("Failed to send batch notification", "url", cfg.callbackURL, "error", reqErr)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, reqErr := cfg.client.Do(req)
+	if reqErr != nil {
+		cfg.logger.Warn("Failed to send batch notification", "url", cfg.callbackURL, "error", reqErr)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		cfg.logger.Warn("Batch notification rejected", "url", cfg.callbackURL, "status", resp.StatusCode)
+	}
+}
+
+// newBatchConfig applies opts over the defaults.
+func newBatchConfig(opts []BatchOption, client *http.Client, logger utils.Logger) *batchConfig {
+	cfg := &batchConfig{concurrency: 5, pollInterval: 30 * time.Second, client: client, logger: logger}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.concurrency < 1 {
+		cfg.concurrency = 1
+	}
+	return cfg
+}
+
+// checkBatch rejects empty batches and nil prompts.
+func checkBatch(prompts []*Prompt) error {
+	if len(prompts) == 0 {
+		return NewLLMError(ErrorTypeInvalidInput, "no prompts to batch", nil)
+	}
+	for i, prompt := range prompts {
+		if prompt == nil {
+			return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("prompt %d is nil", i), nil)
+		}
+	}
+	return nil
+}
+
+// SubmitBatch starts running prompts with l and returns the job running them;
+// call Wait on it for the results. LLMs that are Batchers run the batch
+// themselves, with the provider's batch API if it has one. Others run each
+// prompt with Generate, concurrently (see WithBatchConcurrency).
+//
+// Example:
+//
+//	job, err := llm.SubmitBatch(ctx, client, prompts)
+//	results, err := job.Wait(ctx)
+func SubmitBatch(ctx context.Context, l LLM, prompts []*Prompt, opts ...BatchOption) (*BatchJob, error) {
+	if batcher, ok := l.(Batcher); ok {
+		return batcher.SubmitBatch(ctx, prompts, opts...)
+	}
+	if err := checkBatch(prompts); err != nil {
+		return nil, err
+	}
+	return submitCalls(ctx, l, prompts, newBatchConfig(opts, http.DefaultClient, l.GetLogger())), nil
+}
+
+// submitCalls runs a batch as individual Generate calls.
+func submitCalls(ctx context.Context, l LLM, prompts []*Prompt, cfg *batchConfig) *BatchJob {
+	job, ctx := newBatchJob(ctx, "", len(prompts))
+	go func() {
+		results := make([]BatchResult, len(prompts))
+		slots := make(chan struct{}, cfg.concurrency)
+		var wg sync.WaitGroup
+		for i, prompt := range prompts {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				results[i] = BatchResult{Index: i, Err: ctx.Err()}
+				if cfg.onResult != nil {
+					cfg.onResult(results[i])
+				}
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer func() {
+					<-slots
+					wg.Done()
+				}()
+				callCtx, recorder := WithUsageRecorder(ctx)
+				response, err := l.Generate(callCtx, prompt, cfg.generateOpts...)
+				results[i] = BatchResult{Index: i, Response: response, Err: err, Usage: recorder.Usage()}
+				if cfg.onResult != nil {
+					cfg.onResult(results[i])
+				}
+			}()
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			job.finish(ctx, cfg, BatchStatusCancelled, results, fmt.Errorf("batch cancelled: %w", ctx.Err()))
+			return
+		}
+		job.finish(ctx, cfg, BatchStatusCompleted, results, nil)
+	}()
+	return job
+}
+
+// SubmitBatch starts running prompts and returns the job running them; call
+// Wait on it for the results. Providers with a batch API (OpenAI) receive the
+// prompts as one batch, which costs less but may take up to a day; the job
+// polls it in the background (see WithBatchPollInterval). Other providers,
+// and batches with WithBatchIndividualCalls, run each prompt with Generate,
+// concurrently (see WithBatchConcurrency).
+// Either way the prompts are prepared as for Generate, and the job outlives
+// ctx, which bounds only the submission; stop it with BatchJob.Cancel. Batches sent to a batch API cannot
+// use structured output, and responses are not checked for the persona's
+// forbidden phrases.
+//
+// Returns:
+//   - The job running the batch; its ID is the provider's batch ID
+//   - ErrorTypeInvalidInput if prompts is empty or holds a nil or invalid
+//     prompt
+//   - Other error types as per Generate if the batch cannot be submitted
+//
+// Example:
+//
+//	job, err := client.SubmitBatch(ctx, prompts, WithBatchCallbackURL("https://example.com/batch-done"))
+//	if err != nil {
+//	    return err
+//	}
+//	results, err := job.Wait(ctx)
+//	for _, r := range results {
+//	    if r.Err != nil {
+//	        log.Printf("prompt %d: %v", r.Index, r.Err)
+//	        continue
+//	    }
+//	    fmt.Println(r.Response)
+//	}
+func (l *LLMImpl) SubmitBatch(ctx context.Context, prompts []*Prompt, opts ...BatchOption) (*BatchJob, error) {
+	if err := checkBatch(prompts); err != nil {
+		return nil, err
+	}
+	cfg := newBatchConfig(opts, l.client, l.logger)
+	batcher, ok := l.Provider.(providers.Batcher)
+	if !ok || !batcher.SupportsBatch() || cfg.individual {
+		return submitCalls(ctx, l, prompts, cfg), nil
+	}
+
+	var lines bytes.Buffer
+	prefilled := make([]bool, len(prompts))
+	for i, prompt := range prompts {
+		config := &GenerateConfig{}
+		for _, opt := range cfg.generateOpts {
+			opt(config)
+		}
+		if config.StructuredOutput != nil {
+			return nil, NewLLMError(ErrorTypeInvalidInput, "structured output is not supported in batches", nil)
+		}
+		prepared, _, overrides, err := l.prepareCall(ctx, prompt, config, false)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %d: %w", i, err)
+		}
+		options := mergeOptions(l.Options, overrides)
+		if len(prepared.Tools) > 0 {
+			options["tools"] = prepared.Tools
+		}
+		if len(prepared.ToolChoice) > 0 {
+			options["tool_choice"] = prepared.ToolChoice
+		}
+		body, err := l.Provider.PrepareRequest(l.providerPrompt(prepared, options), options)
+		if err != nil {
+			return nil, NewLLMError(ErrorTypeRequest, fmt.Sprintf("failed to prepare request of prompt %d", i), err)
+		}
+		_, prefilled[i] = options["response_prefix"]
+		line, _ := json.Marshal(map[string]interface{}{
+			"custom_id": strconv.Itoa(i),
+			"method":    "POST",
+			"url":       batcher.BatchRequestURL(),
+			"body":      json.RawMessage(body),
+		})
+		lines.Write(line)
+		lines.WriteByte('\n')
+	}
+
+	fileID, err := l.uploadBatchFile(ctx, batcher, lines.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	request, _ := json.Marshal(map[string]interface{}{
+		"input_file_id":     fileID,
+		"endpoint":          batcher.BatchRequestURL(),
+		"completion_window": "24h",
+	})
+	body, err := l.batchRequest(ctx, "POST", batcher.BatchURL("batches"), bytes.NewReader(request), "")
+	if err != nil {
+		return nil, err
+	}
+	var batch batchStatus
+	if err := json.Unmarshal(body, &batch); err != nil || batch.ID == "" {
+		return nil, NewLLMError(ErrorTypeResponse, "failed to parse batch", err)
+	}
+	l.logger.Debug("Batch submitted", "id", batch.ID, "prompts", len(prompts))
+
+	job, jobCtx := newBatchJob(ctx, batch.ID, len(prompts))
+	go l.pollBatch(jobCtx, batcher, job, prompts, prefilled, cfg)
+	return job, nil
+}
+
+// batchStatus is a batch as returned by the batches endpoint.
+type batchStatus struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	OutputFileID string `json:"output_file_id"`
+	ErrorFileID  string `json:"error_file_id"`
+	Errors       *struct {
+		Data []struct {
+			Message string `json:"message"`
+		} `json:"data"`
+	} `json:"errors"`
+}
+
+// ended reports whether the batch will not change anymore.
+func (b *batchStatus) ended() bool {
+	switch b.Status {
+	case "completed", "failed", "expired", "cancelled":
+		return true
+	}
+	return false
+}
+
+// uploadBatchFile uploads the JSONL file of a batch and returns its ID.
+func (l *LLMImpl) uploadBatchFile(ctx context.Context, batcher providers.Batcher, content []byte) (string, error) {
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	writer.WriteField("purpose", "batch")
+	part, _ := writer.CreateFormFile("file", "batch.jsonl")
+	part.Write(content)
+	writer.Close()
+
+	body, err := l.batchRequest(ctx, "POST", batcher.BatchURL("files"), &form, writer.FormDataContentType())
+	if err != nil {
+		return "", err
+	}
+	var file struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &file); err != nil || file.ID == "" {
+		return "", NewLLMError(ErrorTypeResponse, "failed to parse uploaded batch file", err)
+	}
+	return file.ID, nil
+}
+
+// pollBatch checks the status of a batch every poll interval until it ends,
+// then finishes job with its results. Failed checks are retried like Generate
+// calls. When ctx is canceled, the batch is canceled at the provider.
+func (l *LLMImpl) pollBatch(ctx context.Context, batcher providers.Batcher, job *BatchJob, prompts []*Prompt, prefilled []bool, cfg *batchConfig) {
+	strategy := l.retryStrategy()
+	failures := 0
+	for {
+		delay := cfg.pollInterval
+		body, err := l.batchRequest(ctx, "GET", batcher.BatchURL("batches/"+job.ID), nil, "")
+		var batch batchStatus
+		if err == nil {
+			if err = json.Unmarshal(body, &batch); err != nil {
+				err = NewLLMError(ErrorTypeResponse, "failed to parse batch", err)
+			}
+		}
+		if err == nil {
+			failures = 0
+			if batch.ended() {
+				results, err := l.batchResults(ctx, batcher, &batch, prompts, prefilled)
+				if cfg.onResult != nil {
+					for _, result := range results {
+						cfg.onResult(result)
+					}
+				}
+				job.finish(ctx, cfg, batch.Status, results, err)
+				return
+			}
+		} else {
+			if ctx.Err() != nil {
+				l.cancelBatch(ctx, batcher, job, prompts, cfg)
+				return
+			}
+			failures++
+			l.logger.Warn("Batch status check failed", "id", job.ID, "error", err, "attempt", failures)
+			retryDelay, retry := strategy.NextDelay(failures, err)
+			if !isRetryable(err) || !retry {
+				job.finish(ctx, cfg, BatchStatusFailed, nil, fmt.Errorf("failed to check batch %s: %w", job.ID, err))
+				return
+			}
+			delay = max(delay, retryDelay)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			l.cancelBatch(ctx, batcher, job, prompts, cfg)
+			return
+		}
+	}
+}
+
+// cancelBatch asks the provider to cancel the batch of job and finishes job
+// as cancelled. A failed cancel request is logged, not retried.
+func (l *LLMImpl) cancelBatch(ctx context.Context, batcher providers.Batcher, job *BatchJob, prompts []*Prompt, cfg *batchConfig) {
+	err := fmt.Errorf("batch %s cancelled: %w", job.ID, ctx.Err())
+	requestCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	if _, reqErr := l.batchRequest(requestCtx, "POST", batcher.BatchURL("batches/"+job.ID+"/cancel"), nil, ""); reqErr != nil {
+		l.logger.Warn("Failed to cancel batch", "id", job.ID, "error", reqErr)
+	}
+	results := make([]BatchResult, len(prompts))
+	for i := range results {
+		results[i] = BatchResult{Index: i, Err: err}
+		if cfg.onResult != nil {
+			cfg.onResult(results[i])
+		}
+	}
+	job.finish(ctx, cfg, BatchStatusCancelled, results, err)
+}
+
+// batchResults downloads the results of an ended batch. Prompts without a
+// result get an error giving the batch's status.
+func (l *LLMImpl) batchResults(ctx context.Context, batcher providers.Batcher, batch *batchStatus, prompts []*Prompt, prefilled []bool) ([]BatchResult, error) {
+	results := make([]BatchResult, len(prompts))
+	for i := range results {
+		results[i] = BatchResult{Index: i, Err: NewLLMError(ErrorTypeAPI, fmt.Sprintf("batch %s ended with status %s without a result", batch.ID, batch.Status), nil)}
+	}
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		content, err := l.batchRequest(ctx, "GET", batcher.BatchURL("files/"+fileID+"/content"), nil, "")
+		if err != nil {
+			return results, fmt.Errorf("failed to download results of batch %s: %w", batch.ID, err)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(nil, 64*1024*1024)
+		for scanner.Scan() {
+			if i, result, ok := l.parseBatchLine(scanner.Bytes(), prompts, prefilled); ok {
+				results[i] = result
+			}
+		}
+	}
+	if batch.Status != BatchStatusCompleted {
+		message := "batch " + batch.ID + " " + batch.Status
+		if batch.Errors != nil && len(batch.Errors.Data) > 0 {
+			message += ": " + batch.Errors.Data[0].Message
+		}
+		return results, NewLLMError(ErrorTypeAPI, message, nil)
+	}
+	return results, nil
+}
+
+// parseBatchLine parses a line of a batch's output or error file into the
+// result of the prompt it belongs to, reporting false for lines that belong
+// to none.
+func (l *LLMImpl) parseBatchLine(line []byte, prompts []*Prompt, prefilled []bool) (int, BatchResult, bool) {
+	var output struct {
+		CustomID string `json:"custom_id"`
+		Response *struct {
+			StatusCode int             `json:"status_code"`
+			Body       json.RawMessage `json:"body"`
+		} `json:"response"`
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(line, &output); err != nil {
+		return 0, BatchResult{}, false
+	}
+	i, err := strconv.Atoi(output.CustomID)
+	if err != nil || i < 0 || i >= len(prompts) {
+		return 0, BatchResult{}, false
+	}
+	result := BatchResult{Index: i}
+	switch {
+	case output.Error != nil:
+		result.Err = NewLLMError(ErrorTypeAPI, strings.TrimPrefix(output.Error.Code+": "+output.Error.Message, ": "), nil)
+	case output.Response == nil:
+		result.Err = NewLLMError(ErrorTypeResponse, "batch result has no response", nil)
+	case output.Response.StatusCode != http.StatusOK:
+		result.Err = l.apiError(output.Response.StatusCode, output.Response.Body)
+	default:
+		var body map[string]interface{}
+		json.Unmarshal(output.Response.Body, &body)
+		var usage tokenUsage
+		usage.record(body)
+		result.Usage = TokenUsage{
+			InputTokens:              usage.input,
+			OutputTokens:             usage.output,
+			CacheCreationInputTokens: usage.cacheCreation,
+			CacheReadInputTokens:     usage.cacheRead,
+		}
+		response, err := l.Provider.ParseResponse(output.Response.Body)
+		if err != nil {
+			result.Err = NewLLMError(ErrorTypeResponse, "failed to parse response", err)
+			break
+		}
+		result.Response = responseAfterPrefix(response, prompts[i].ResponsePrefix, prefilled[i])
+	}
+	return i, result, true
+}
+
+// batchRequest makes a request to the batch API and returns the body of its
+// successful response. contentType overrides the provider's Content-Type
+// header when set.
+func (l *LLMImpl) batchRequest(ctx context.Context, method, url string, body io.Reader, contentType string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to create request", err)
+	}
+	for k, v := range l.Provider.Headers() {
+		req.Header.Set(k, v)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to send request", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "failed to read response body", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, l.apiError(resp.StatusCode, respBody)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,315 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// fakeBatchAPI serves OpenAI's files and batches endpoints for one batch,
+// which is in progress at the first status check and ends with status at
+// the second.
+type fakeBatchAPI struct {
+	t       *testing.T
+	status  string
+	output  string
+	errors  string
+	mutex   sync.Mutex
+	lines   []map[string]interface{}
+	checks  int
+	created map[string]interface{}
+}
+
+func (f *fakeBatchAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/files":
+		assert.Equal(f.t, "test-key", strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		assert.Equal(f.t, "batch", r.FormValue("purpose"))
+		file, _, err := r.FormFile("file")
+		require.NoError(f.t, err)
+		content, _ := io.ReadAll(file)
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			var request map[string]interface{}
+			require.NoError(f.t, json.Unmarshal([]byte(line), &request))
+			f.lines = append(f.lines, request)
+		}
+		w.Write([]byte(`{"id": "file-in"}`))
+	case r.Method == "POST" && r.URL.Path == "/batches":
+		json.NewDecoder(r.Body).Decode(&f.created)
+		w.Write([]byte(`{"id": "batch-1", "status": "validating"}`))
+	case r.Method == "GET" && r.URL.Path == "/batches/batch-1":
+		f.checks++
+		if f.checks == 1 {
+			w.Write([]byte(`{"id": "batch-1", "status": "in_progress"}`))
+			return
+		}
+		status, _ := json.Marshal(map[string]interface{}{
+			"id": "batch-1", "status": f.status, "output_file_id": "file-out", "error_file_id": "file-err",
+			"errors": map[string]interface{}{"data": []map[string]string{{"message": "input file is invalid"}}},
+		})
+		w.Write(status)
+	case r.Method == "GET" && r.URL.Path == "/files/file-out/content":
+		w.Write([]byte(f.output))
+	case r.Method == "GET" && r.URL.Path == "/files/file-err/content":
+		w.Write([]byte(f.errors))
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSubmitBatch_OpenAI(t *testing.T) {
+	api := &fakeBatchAPI{
+		t:      t,
+		status: "completed",
+		output: `{"custom_id": "0", "response": {"status_code": 200, "body": {"choices": [{"message": {"content": "苹果是红色的"}}], "usage": {"prompt_tokens": 12, "completion_tokens": 5}}}, "error": null}
+{"custom_id": "2", "response": {"status_code": 400, "body": {"error": {"message": "context length exceeded", "code": "context_length_exceeded"}}}, "error": null}
+`,
+		errors: `{"custom_id": "1", "response": null, "error": {"code": "batch_expired", "message": "This request could not be executed before the completion window expired."}}
+`,
+	}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+	notifications := make(chan BatchNotification, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification BatchNotification
+		json.NewDecoder(r.Body).Decode(&notification)
+		notifications <- notification
+	}))
+	t.Cleanup(callback.Close)
+
+	l, _ := newRespondingLLM(t, "openai", "gpt-4o-mini", "")
+	l.SetEndpoint(server.URL)
+	batcher, ok := l.(Batcher)
+	require.True(t, ok)
+	job, err := batcher.SubmitBatch(context.Background(), []*Prompt{
+		NewPrompt("苹果是什么颜色？"),
+		NewPrompt("香蕉是什么颜色？"),
+		NewPrompt("葡萄是什么颜色？", WithSystemPrompt("简短回答", CacheTypeEphemeral)),
+	}, WithBatchPollInterval(time.Millisecond), WithBatchCallbackURL(callback.URL),
+		WithBatchGenerateOptions(WithTemperature(0.2)))
+	require.NoError(t, err)
+	assert.Equal(t, "batch-1", job.ID)
+
+	results, err := job.Wait(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, BatchResult{Index: 0, Response: "苹果是红色的", Usage: TokenUsage{InputTokens: 12, OutputTokens: 5}}, results[0])
+	assert.ErrorContains(t, results[1].Err, "batch_expired: This request could not be executed")
+	assert.ErrorIs(t, results[2].Err, ErrContextLengthExceeded)
+
+	require.Len(t, api.lines, 3)
+	assert.Equal(t, "0", api.lines[0]["custom_id"])
+	assert.Equal(t, "/v1/chat/completions", api.lines[0]["url"])
+	body := api.lines[2]["body"].(map[string]interface{})
+	assert.Equal(t, "gpt-4o-mini", body["model"])
+	assert.Equal(t, 0.2, body["temperature"])
+	assert.Contains(t, body["messages"], map[string]interface{}{"role": "system", "content": "简短回答"})
+	assert.Equal(t, map[string]interface{}{"input_file_id": "file-in", "endpoint": "/v1/chat/completions", "completion_window": "24h"}, api.created)
+
+	select {
+	case notification := <-notifications:
+		assert.Equal(t, BatchNotification{BatchID: "batch-1", Status: BatchStatusCompleted, Total: 3, Failed: 2}, notification)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification received")
+	}
+}
+
+func TestSubmitBatch_OpenAIBatchFailed(t *testing.T) {
+	api := &fakeBatchAPI{t: t, status: "failed"}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	l, _ := newRespondingLLM(t, "openai", "gpt-4o-mini", "")
+	l.SetEndpoint(server.URL)
+	job, err := SubmitBatch(context.Background(), l, []*Prompt{NewPrompt("你好")}, WithBatchPollInterval(time.Millisecond))
+	require.NoError(t, err)
+	results, err := job.Wait(context.Background())
+	assert.ErrorContains(t, err, "batch batch-1 failed: input file is invalid")
+	require.Len(t, results, 1)
+	assert.ErrorContains(t, results[0].Err, "ended with status failed without a result")
+
+	_, err = SubmitBatch(context.Background(), l, []*Prompt{NewPrompt("你好")},
+		WithBatchGenerateOptions(WithStructuredOutput(json.RawMessage(`{"type": "object"}`))))
+	assert.ErrorContains(t, err, "structured output is not supported in batches")
+}
+
+func TestSubmitBatch_IndividualCalls(t *testing.T) {
+	l, _ := newRespondingLLM(t, "anthropic", "claude-3-5-haiku-latest",
+		`{"content":[{"type":"text","text":"好的"}],"usage":{"input_tokens":10,"output_tokens":2}}`)
+
+	job, err := SubmitBatch(context.Background(), l, []*Prompt{NewPrompt("一"), NewPrompt("二"), NewPrompt("三")}, WithBatchConcurrency(2))
+	require.NoError(t, err)
+	assert.Empty(t, job.ID)
+	results, err := job.Wait(context.Background())
+	require.NoError(t, err)
+	for i, result := range results {
+		assert.Equal(t, BatchResult{Index: i, Response: "好的", Usage: TokenUsage{InputTokens: 10, OutputTokens: 2}}, result)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	job, err = SubmitBatch(ctx, l, []*Prompt{NewPrompt("一")})
+	require.NoError(t, err)
+	_, err = job.Wait(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	<-job.Done()
+	results, err = job.Wait(context.Background())
+	require.NoError(t, err, "the job outlives the context it was submitted with")
+	assert.Equal(t, "好的", results[0].Response)
+}
+
+func TestSubmitBatch_ResultHandler(t *testing.T) {
+	l, _ := newRespondingLLM(t, "anthropic", "claude-3-5-haiku-latest",
+		`{"content":[{"type":"text","text":"好的"}],"usage":{"input_tokens":10,"output_tokens":2}}`)
+
+	var mutex sync.Mutex
+	handled := make(map[int]BatchResult)
+	job, err := SubmitBatch(context.Background(), l, []*Prompt{NewPrompt("一"), NewPrompt("二"), NewPrompt("三")},
+		WithBatchConcurrency(2),
+		WithBatchResultHandler(func(result BatchResult) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			handled[result.Index] = result
+		}),
+	)
+	require.NoError(t, err)
+	results, err := job.Wait(context.Background())
+	require.NoError(t, err)
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Len(t, handled, 3, "every result is handled before Wait returns")
+	for _, result := range results {
+		assert.Equal(t, result, handled[result.Index])
+	}
+}
+
+func TestSubmitBatch_InvalidInput(t *testing.T) {
+	l, _ := newRespondingLLM(t, "openai", "gpt-4o-mini", "")
+	_, err := SubmitBatch(context.Background(), l, nil)
+	assert.ErrorContains(t, err, "no prompts to batch")
+	_, err = SubmitBatch(context.Background(), l, []*Prompt{NewPrompt("一"), nil})
+	assert.ErrorContains(t, err, "prompt 1 is nil")
+}
+
+func TestSubmitBatch_AzureIndividualCalls(t *testing.T) {
+	var mutex sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		paths = append(paths, r.URL.Path)
+		mutex.Unlock()
+		w.Write([]byte(`{"choices": [{"message": {"content": "好的"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("azure"),
+		config.SetModel("gpt-4o"),
+		config.SetAPIKey("azure-key"),
+		config.SetBaseURL(server.URL),
+		config.SetAzureDeployment("gpt-4o-prod"),
+		config.SetAzureAPIVersion("2024-10-21"),
+		config.SetMaxRetries(0),
+	)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+
+	job, err := SubmitBatch(context.Background(), l, []*Prompt{NewPrompt("一"), NewPrompt("二")})
+	require.NoError(t, err)
+	assert.Empty(t, job.ID, "Azure batches fall back to individual calls")
+	results, err := job.Wait(context.Background())
+	require.NoError(t, err)
+	for _, result := range results {
+		assert.Equal(t, "好的", result.Response)
+	}
+	assert.Equal(t, []string{"/openai/deployments/gpt-4o-prod/chat/completions", "/openai/deployments/gpt-4o-prod/chat/completions"}, paths)
+}
+
+func TestSubmitBatch_CancelIndividualCalls(t *testing.T) {
+	started := make(chan struct{}, 3)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body) // The server notices the canceled request once the body is read
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+	l, _ := newRespondingLLM(t, "anthropic", "claude-3-5-haiku-latest", "")
+	l.SetEndpoint(server.URL)
+
+	job, err := SubmitBatch(context.Background(), l, []*Prompt{NewPrompt("一"), NewPrompt("二"), NewPrompt("三")}, WithBatchConcurrency(1))
+	require.NoError(t, err)
+	<-started
+	job.Cancel()
+	results, err := job.Wait(context.Background())
+	assert.ErrorIs(t, err, context.Canceled)
+	require.Len(t, results, 3)
+	for _, result := range results {
+		assert.Error(t, result.Err)
+	}
+	assert.ErrorIs(t, results[2].Err, context.Canceled, "prompts not yet run fail")
+	assert.Len(t, started, 0, "no call starts after the job is canceled")
+}
+
+func TestSubmitBatch_CancelOpenAI(t *testing.T) {
+	api := &fakeBatchAPI{t: t}
+	cancelled := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/batches/batch-1":
+			w.Write([]byte(`{"id": "batch-1", "status": "in_progress"}`))
+		case r.Method == "POST" && r.URL.Path == "/batches/batch-1/cancel":
+			cancelled <- struct{}{}
+			w.Write([]byte(`{"id": "batch-1", "status": "cancelling"}`))
+		default:
+			api.ServeHTTP(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	notifications := make(chan BatchNotification, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification BatchNotification
+		json.NewDecoder(r.Body).Decode(&notification)
+		notifications <- notification
+	}))
+	t.Cleanup(callback.Close)
+
+	l, _ := newRespondingLLM(t, "openai", "gpt-4o-mini", "")
+	l.SetEndpoint(server.URL)
+	job, err := SubmitBatch(context.Background(), l, []*Prompt{NewPrompt("你好")},
+		WithBatchPollInterval(time.Hour), WithBatchCallbackURL(callback.URL))
+	require.NoError(t, err)
+	job.Cancel()
+
+	results, err := job.Wait(context.Background())
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "batch batch-1 cancelled")
+	require.Len(t, results, 1)
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+	select {
+	case <-cancelled:
+	default:
+		t.Error("the batch was not canceled at the provider")
+	}
+	select {
+	case notification := <-notifications:
+		assert.Equal(t, BatchStatusCancelled, notification.Status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification received")
+	}
+}
@@ -0,0 +1,75 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ContextChunk is a piece of retrieved content injected into a prompt by
+// WithContextInjection.
+type ContextChunk struct {
+	Content string  `json:"content"`         // Text of the chunk
+	Source  string  `json:"source"`          // Where the chunk comes from, cited after it
+	Score   float64 `json:"score,omitempty"` // Retrieval relevance; higher is more relevant
+}
+
+// WithContextInjection adds retrieved chunks, such as the results of a vector
+// search, to the prompt as a "Retrieved context" block sent before the rest of
+// the prompt. Each chunk is followed by a "[Source: ...]" line so the model
+// can cite it. Chunks are added by descending score, skipping any that would
+// exceed maxTokens, so the most relevant chunks that fit are kept. Unlike
+// WithSystemPrompt, the block is meant to change from call to call.
+//
+// Tokens are counted with the gpt-4o tokenizer, or estimated at four bytes per
+// token if it cannot be loaded, since the prompt does not know its model yet.
+//
+// Parameters:
+//   - chunks: The retrieved chunks
+//   - maxTokens: Token budget of the block; 0 or less for no limit
+//
+// Example:
+//
+//	chunks := []ContextChunk{
+//	    {Content: "退货需在收货后 7 天内申请。", Source: "售后政策.md", Score: 0.92},
+//	    {Content: "生鲜商品不支持无理由退货。", Source: "FAQ.md", Score: 0.81},
+//	}
+//	prompt := NewPrompt("买的水果可以退吗？", WithContextInjection(chunks, 2000))
+func WithContextInjection(chunks []ContextChunk, maxTokens int) PromptOption {
+	return func(p *Prompt) {
+		sorted := append([]ContextChunk(nil), chunks...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].Score > sorted[j].Score
+		})
+
+		count := countTokens
+		if encoding, err := encodingForModel("gpt-4o"); err == nil {
+			count = func(text string) int { return len(encoding.Encode(text, nil, nil)) }
+		}
+
+		var blocks []string
+		used := 0
+		for _, chunk := range sorted {
+			block := strings.TrimSpace(chunk.Content)
+			if block == "" {
+				continue
+			}
+			if chunk.Source != "" {
+				block += fmt.Sprintf("\n[Source: %s]", chunk.Source)
+			}
+			tokens := count(block + "\n\n")
+			if maxTokens > 0 && used+tokens > maxTokens {
+				continue
+			}
+			used += tokens
+			blocks = append(blocks, block)
+		}
+		p.RetrievedContext = strings.Join(blocks, "\n\n")
+	}
+}
+
+// countTokens estimates the number of tokens of text at four bytes per token.
+func countTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,35 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithContextInjection(t *testing.T) {
+	useRuneTokenizer(t)
+	chunks := []ContextChunk{
+		{Content: "生鲜商品不支持无理由退货。", Source: "FAQ.md", Score: 0.81},
+		{Content: "退货需在收货后 7 天内申请。", Source: "售后政策.md", Score: 0.92},
+		{Content: "  ", Source: "empty.md", Score: 0.99},
+		{Content: "会员可享受免费退货运费。", Score: 0.5},
+	}
+
+	prompt := NewPrompt("买的水果可以退吗？", WithContextInjection(chunks, 0))
+	assert.Equal(t, "退货需在收货后 7 天内申请。\n[Source: 售后政策.md]\n\n"+
+		"生鲜商品不支持无理由退货。\n[Source: FAQ.md]\n\n"+
+		"会员可享受免费退货运费。", prompt.RetrievedContext)
+	assert.True(t, strings.HasPrefix(prompt.String(), "Retrieved context:\n退货需在收货后"))
+	assert.Equal(t, 4, len(chunks), "the chunks are not modified")
+	assert.Equal(t, "FAQ.md", chunks[0].Source)
+
+	// With the rune tokenizer, the blocks and their separators are 35, 32 and
+	// 14 tokens; chunks over the budget are skipped, lowest scores first.
+	prompt = NewPrompt("买的水果可以退吗？", WithContextInjection(chunks, 70))
+	assert.Equal(t, "退货需在收货后 7 天内申请。\n[Source: 售后政策.md]\n\n"+
+		"生鲜商品不支持无理由退货。\n[Source: FAQ.md]", prompt.RetrievedContext)
+	prompt = NewPrompt("买的水果可以退吗？", WithContextInjection(chunks, 50))
+	assert.Equal(t, "退货需在收货后 7 天内申请。\n[Source: 售后政策.md]\n\n"+
+		"会员可享受免费退货运费。", prompt.RetrievedContext)
+}
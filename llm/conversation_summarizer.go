@@ -0,0 +1,111 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ConversationSummarizer keeps a conversation memory short by condensing its
+// oldest turns into a single summary turn once the memory holds more than a
+// given number of turns. The summary keeps the named entities, decisions and
+// open questions of the condensed turns, so later answers can still refer to
+// them while using far fewer tokens than the turns themselves.
+type ConversationSummarizer struct {
+	llm      LLM
+	maxTurns int
+}
+
+// NewConversationSummarizer creates a summarizer that condenses the oldest
+// half of a memory's turns whenever it holds more than maxTurns turns. Attach
+// it with LLMWithMemory.SetSummarizer, or configure it for a client with
+// config.SetMemorySummarization.
+//
+// Parameters:
+//   - l: LLM that writes the summaries; it should not be the LLMWithMemory
+//     being summarized, or the summary requests would enter its history
+//   - maxTurns: Number of turns, user and assistant messages alike, kept
+//     before summarizing; at least 2
+//
+// Example:
+//
+//	chat, _ := llm.NewLLMWithMemory(base, 8000, "gpt-4o", logger)
+//	chat.SetSummarizer(llm.NewConversationSummarizer(base, 20))
+func NewConversationSummarizer(l LLM, maxTurns int) *ConversationSummarizer {
+	if maxTurns < 2 {
+		maxTurns = 2
+	}
+	return &ConversationSummarizer{llm: l, maxTurns: maxTurns}
+}
+
+// Summarize condenses the oldest half of the turns of memory into a summary
+// turn (see MemoryMessage.SummaryTurn) if memory holds more than the
+// summarizer's maximum number of turns. A previous summary among the oldest
+// turns is folded into the new one.
+//
+// Returns:
+//   - Whether memory was summarized
+//   - Error if the summary could not be generated; memory is then unchanged
+func (s *ConversationSummarizer) Summarize(ctx context.Context, memory *Memory) (bool, error) {
+	messages := memory.GetMessages()
+	if len(messages) <= s.maxTurns {
+		return false, nil
+	}
+	oldest := messages[:len(messages)/2]
+
+	var transcript strings.Builder
+	for _, msg := range oldest {
+		role := msg.Role
+		if msg.SummaryTurn {
+			role = "summary"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", role, msg.Content)
+	}
+	prompt := NewPrompt(
+		"将以下对话的早期部分压缩为一段摘要，供后续对话参考：\n\n"+transcript.String(),
+		WithDirectives(
+			"保留提到的所有命名实体，如人名、地名、组织、产品和数字",
+			"保留已经做出的决定及其理由",
+			"保留尚未解决的问题和待办事项",
+			"如果对话中包含 summary 摘要，将其内容合并到新的摘要中",
+			"只输出摘要，不要添加评论",
+		),
+	)
+	summary, err := s.llm.Generate(ctx, prompt)
+	if err != nil {
+		return false, fmt.Errorf("failed to summarize conversation: %w", err)
+	}
+	return memory.replaceOldest(oldest, strings.TrimSpace(summary)), nil
+}
+
+// replaceOldest replaces the messages of oldest, which must be the first
+// messages of the memory, with a summary turn. It returns false, leaving the
+// memory unchanged, if the memory no longer starts with oldest, as after a
+// concurrent Clear or truncation.
+func (m *Memory) replaceOldest(oldest []MemoryMessage, summary string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if len(m.messages) < len(oldest) {
+		return false
+	}
+	removed := 0
+	for i, msg := range oldest {
+		if m.messages[i] != msg {
+			return false
+		}
+		removed += msg.Tokens
+	}
+
+	turn := MemoryMessage{
+		Role:        "assistant",
+		Content:     summary,
+		Tokens:      len(m.encoding.Encode(summary, nil, nil)),
+		SummaryTurn: true,
+	}
+	m.messages = append([]MemoryMessage{turn}, m.messages[len(oldest):]...)
+	m.totalTokens += turn.Tokens - removed
+	m.logger.Debug("Summarized memory", "summarized_messages", len(oldest), "removed_tokens", removed, "summary_tokens", turn.Tokens, "total_tokens", m.totalTokens)
+	return true
+}
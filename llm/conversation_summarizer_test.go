@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// replyLLM answers Generate calls with its responses in order, or with err,
+// and records the prompts.
+type replyLLM struct {
+	LLM
+	responses []string
+	prompts   []*Prompt
+	err       error
+}
+
+func (r *replyLLM) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, error) {
+	r.prompts = append(r.prompts, prompt)
+	if r.err != nil {
+		return "", r.err
+	}
+	response := r.responses[0]
+	r.responses = r.responses[1:]
+	return response, nil
+}
+
+func (r *replyLLM) FallbackPolicy(ctx context.Context) *FallbackPolicy {
+	return NewFallbackPolicy(false, nil)
+}
+
+func TestConversationSummarizer(t *testing.T) {
+	useRuneTokenizer(t)
+	chat := &replyLLM{responses: []string{"你好，张三", "好的，选 A 方案"}}
+	summarizer := &replyLLM{responses: []string{"张三打招呼。\n"}}
+	l, err := NewLLMWithMemory(chat, 1000, "gpt-4o", utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+	l.SetSummarizer(NewConversationSummarizer(summarizer, 3))
+
+	_, err = l.Generate(context.Background(), NewPrompt("我是张三"))
+	require.NoError(t, err)
+	assert.Empty(t, summarizer.prompts, "two turns are within the limit")
+
+	_, err = l.Generate(context.Background(), NewPrompt("用 A 方案吧"))
+	require.NoError(t, err)
+	require.Len(t, summarizer.prompts, 1)
+	assert.Contains(t, summarizer.prompts[0].Input, "user: 我是张三\nassistant: 你好，张三\n")
+	assert.NotContains(t, summarizer.prompts[0].Input, "A 方案")
+	assert.Contains(t, summarizer.prompts[0].Directives, "保留已经做出的决定及其理由")
+
+	messages := l.GetMemory()
+	assert.Equal(t, []MemoryMessage{
+		{Role: "assistant", Content: "张三打招呼。", Tokens: 6, SummaryTurn: true},
+		{Role: "user", Content: "用 A 方案吧", Tokens: 7},
+		{Role: "assistant", Content: "好的，选 A 方案", Tokens: 9},
+	}, messages)
+	assert.Equal(t, 22, l.memory.totalTokens)
+	assert.Equal(t, "summary of earlier conversation: 张三打招呼。\nuser: 用 A 方案吧\nassistant: 好的，选 A 方案\n", l.memory.GetPrompt())
+}
+
+func TestConversationSummarizer_KeepsTurnsOnError(t *testing.T) {
+	useRuneTokenizer(t)
+	chat := &replyLLM{responses: []string{"一", "二"}}
+	l, err := NewLLMWithMemory(chat, 1000, "gpt-4o", utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+	l.SetSummarizer(NewConversationSummarizer(&replyLLM{err: errors.New("unavailable")}, 2))
+
+	_, err = l.Generate(context.Background(), NewPrompt("1"))
+	require.NoError(t, err)
+	_, err = l.Generate(context.Background(), NewPrompt("2"))
+	require.NoError(t, err, "a failed summary does not fail the call")
+	assert.Len(t, l.GetMemory(), 4)
+}
+
+func TestMemoryReplaceOldest_Stale(t *testing.T) {
+	useRuneTokenizer(t)
+	m, err := NewMemory(1000, "gpt-4o", utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+	m.Add("user", "a")
+	m.Add("assistant", "b")
+	oldest := m.GetMessages()[:1]
+	m.Clear()
+	m.Add("user", "c")
+
+	assert.False(t, m.replaceOldest(oldest, "summary"))
+	assert.Equal(t, "user: c\n", m.GetPrompt())
+}
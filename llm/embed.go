@@ -0,0 +1,128 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/yockii/gollm_cn/providers"
+)
+
+// ErrProviderDoesNotSupportEmbeddings is returned by Embed for providers
+// without an embeddings endpoint.
+var ErrProviderDoesNotSupportEmbeddings = errors.New("provider does not support embeddings")
+
+// Embedder is implemented by LLMs that can create embeddings. It is not part
+// of the LLM interface, so implementations of LLM need not support it; check
+// for it with a type assertion.
+//
+// Example:
+//
+//	embedder, ok := client.(llm.Embedder)
+//	if !ok {
+//	    return errors.New("client cannot create embeddings")
+//	}
+//	embeddings, err := embedder.Embed(ctx, texts)
+type Embedder interface {
+	// Embed returns one embedding vector per input, in input order.
+	// Returns ErrProviderDoesNotSupportEmbeddings if the provider has no
+	// embeddings endpoint.
+	Embed(ctx context.Context, inputs []string) ([][]float64, error)
+}
+
+// embed calls Embed on l if it is an Embedder, for LLMs wrapping another.
+func embed(ctx context.Context, l LLM, inputs []string) ([][]float64, error) {
+	embedder, ok := l.(Embedder)
+	if !ok {
+		return nil, NewLLMError(ErrorTypeUnsupported, "LLM cannot create embeddings", ErrProviderDoesNotSupportEmbeddings)
+	}
+	return embedder.Embed(ctx, inputs)
+}
+
+// Embed returns one embedding vector per input, in input order. Embeddings are
+// requested from the provider's embeddings endpoint with its embedding model
+// (see config.SetMistralEmbeddingModel), not the model used for generation.
+// Failed requests are retried like Generate calls.
+//
+// Returns:
+//   - The embeddings
+//   - ErrorTypeInvalidInput if inputs is empty
+//   - ErrProviderDoesNotSupportEmbeddings, as an ErrorTypeUnsupported
+//     LLMError, if the provider has no embeddings endpoint
+//   - Other error types as per Generate
+//
+// Example:
+//
+//	embeddings, err := llm.Embed(ctx, []string{"今天天气很好", "明天会下雨吗"})
+func (l *LLMImpl) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	if len(inputs) == 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "no inputs to embed", nil)
+	}
+	embedder, ok := l.Provider.(providers.Embedder)
+	if !ok {
+		return nil, NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("provider %s cannot create embeddings", l.Provider.Name()), ErrProviderDoesNotSupportEmbeddings)
+	}
+
+	strategy := l.retryStrategy()
+	var lastErr error
+	attempt := 1
+	for ; ; attempt++ {
+		embeddings, err := l.attemptEmbed(ctx, embedder, inputs)
+		if err == nil {
+			return embeddings, nil
+		}
+		lastErr = err
+		l.logger.Warn("Embedding attempt failed", "error", err, "attempt", attempt)
+		if !isRetryable(err) {
+			break
+		}
+		delay, retry := strategy.NextDelay(attempt, err)
+		if !retry {
+			break
+		}
+		if err := l.wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("failed to embed after %d attempts: %w", attempt, lastErr)
+}
+
+// attemptEmbed makes a single embeddings request.
+func (l *LLMImpl) attemptEmbed(ctx context.Context, embedder providers.Embedder, inputs []string) ([][]float64, error) {
+	reqBody, err := embedder.PrepareEmbeddingRequest(inputs)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to prepare embedding request", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", embedder.EmbeddingEndpoint(), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to create request", err)
+	}
+	for k, v := range l.Provider.Headers() {
+		req.Header.Set(k, v)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to send request", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "failed to read response body", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, l.apiError(resp.StatusCode, body)
+	}
+
+	embeddings, err := embedder.ParseEmbeddingResponse(body)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "failed to parse embedding response", err)
+	}
+	if len(embeddings) != len(inputs) {
+		return nil, NewLLMError(ErrorTypeResponse, fmt.Sprintf("got %d embeddings for %d inputs", len(embeddings), len(inputs)), nil)
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,62 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestEmbed_Mistral(t *testing.T) {
+	var path string
+	var last map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &last))
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0.5,1]},{"index":0,"embedding":[0.25,-1]}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("mistral"),
+		config.SetModel("mistral-large-latest"),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetMaxRetries(0),
+		config.SetTimeout(5*time.Second),
+	)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+
+	embedder, ok := l.(Embedder)
+	require.True(t, ok)
+	embeddings, err := embedder.Embed(context.Background(), []string{"你好", "世界"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{{0.25, -1}, {0.5, 1}}, embeddings)
+	assert.Equal(t, "/embeddings", path)
+	assert.Equal(t, "mistral-embed", last["model"])
+
+	_, err = embedder.Embed(context.Background(), []string{"你好", "世界", "！"})
+	assert.ErrorContains(t, err, "got 2 embeddings for 3 inputs")
+}
+
+func TestEmbed_Unsupported(t *testing.T) {
+	l, _ := newCapturingLLM(t, config.SetProvider("anthropic"), config.SetAPIKey("test-key"))
+
+	_, err := l.(Embedder).Embed(context.Background(), []string{"你好"})
+	assert.ErrorIs(t, err, ErrProviderDoesNotSupportEmbeddings)
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+}
@@ -0,0 +1,121 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// Intervention names a silent fallback or repair that the library may apply
+// to keep a call working when the model or environment misbehaves.
+type Intervention string
+
+const (
+	// InterventionJSONExtraction strips markdown code fences or surrounding
+	// prose from a response, and repairs malformed JSON, to recover the JSON
+	// inside it. Used by the optimizer's assessment and improvement steps, by
+	// presets.CompareModels, and by the extraction and classification presets.
+	InterventionJSONExtraction Intervention = "json_extraction"
+
+	// InterventionGradeNormalization converts a numeric grade returned where
+	// the optimizer asked for a letter grade into the closest letter grade.
+	InterventionGradeNormalization Intervention = "grade_normalization"
+
+	// InterventionTokenizerFallback counts tokens with the gpt-4o encoding
+	// when the configured model has no known tokenizer. Used by conversation
+	// memory and by WithAutoTruncate.
+	InterventionTokenizerFallback Intervention = "tokenizer_fallback"
+)
+
+// InterventionError is returned in strict mode in place of applying an intervention.
+type InterventionError struct {
+	Intervention Intervention // The intervention that was refused
+	Detail       string       // What triggered it
+}
+
+func (e *InterventionError) Error() string {
+	return fmt.Sprintf("strict mode: refused %s intervention: %s", e.Intervention, e.Detail)
+}
+
+// FallbackPolicy decides whether interventions may be applied. Every fallback
+// and repair in the library goes through a policy, so strict mode covers all
+// of them.
+//
+// Strict mode is enabled for all calls of a client with config.SetStrictMode,
+// or for a single call by passing a context from WithStrictMode.
+type FallbackPolicy struct {
+	strict bool
+	logger utils.Logger
+}
+
+// NewFallbackPolicy creates a policy. Applied interventions are logged as
+// warnings when logger is not nil.
+func NewFallbackPolicy(strict bool, logger utils.Logger) *FallbackPolicy {
+	return &FallbackPolicy{strict: strict, logger: logger}
+}
+
+// Strict reports whether the policy refuses interventions.
+func (p *FallbackPolicy) Strict() bool {
+	return p.strict
+}
+
+// Allow returns an *InterventionError in strict mode and nil otherwise, in
+// which case the caller applies the intervention.
+func (p *FallbackPolicy) Allow(intervention Intervention, detail string) error {
+	if p.strict {
+		return &InterventionError{Intervention: intervention, Detail: detail}
+	}
+	if p.logger != nil {
+		p.logger.Warn("Applying fallback", "intervention", string(intervention), "detail", detail)
+	}
+	return nil
+}
+
+// Repair returns repaired if the intervention is allowed, or an
+// *InterventionError in strict mode. When repaired equals original after
+// trimming whitespace nothing was changed and original is returned in both modes.
+//
+// Example:
+//
+//	cleaned, err := policy.Repair(InterventionJSONExtraction, response, cleanJSON(response))
+func (p *FallbackPolicy) Repair(intervention Intervention, original, repaired string) (string, error) {
+	if strings.TrimSpace(original) == strings.TrimSpace(repaired) {
+		return original, nil
+	}
+	detail := fmt.Sprintf("%q would be rewritten to %q", truncateForLog(original), truncateForLog(repaired))
+	if err := p.Allow(intervention, detail); err != nil {
+		return "", err
+	}
+	return repaired, nil
+}
+
+// truncateForLog shortens s to keep errors and log lines readable.
+func truncateForLog(s string) string {
+	const limit = 80
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit]) + "..."
+}
+
+type strictModeKey struct{}
+
+// WithStrictMode returns a context that enables strict mode for calls made
+// with it, regardless of the client configuration.
+//
+// Example:
+//
+//	optimizedPrompt, err := promptOptimizer.OptimizePrompt(WithStrictMode(ctx))
+func WithStrictMode(ctx context.Context) context.Context {
+	return context.WithValue(ctx, strictModeKey{}, true)
+}
+
+// IsStrictMode reports whether ctx was created by WithStrictMode.
+func IsStrictMode(ctx context.Context) bool {
+	strict, _ := ctx.Value(strictModeKey{}).(bool)
+	return strict
+}
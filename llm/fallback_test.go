@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestFallbackPolicy_Repair(t *testing.T) {
+	original := "结果如下：\n```json\n{\"a\": 1}\n```"
+	repaired := `{"a": 1}`
+
+	lenient := NewFallbackPolicy(false, utils.NewLogger(utils.LogLevelOff))
+	result, err := lenient.Repair(InterventionJSONExtraction, original, repaired)
+	require.NoError(t, err)
+	assert.Equal(t, repaired, result)
+
+	strict := NewFallbackPolicy(true, nil)
+	_, err = strict.Repair(InterventionJSONExtraction, original, repaired)
+	var interventionErr *InterventionError
+	require.True(t, errors.As(err, &interventionErr))
+	assert.Equal(t, InterventionJSONExtraction, interventionErr.Intervention)
+	assert.Contains(t, err.Error(), "strict mode")
+
+	// Nothing to repair is never an intervention.
+	result, err = strict.Repair(InterventionJSONExtraction, " {\"a\": 1}\n", repaired)
+	require.NoError(t, err)
+	assert.Equal(t, " {\"a\": 1}\n", result)
+}
+
+func TestFallbackPolicy_StrictModeSources(t *testing.T) {
+	ctx := context.Background()
+
+	l, _ := newCapturingLLM(t)
+	assert.False(t, l.FallbackPolicy(ctx).Strict())
+	assert.True(t, l.FallbackPolicy(WithStrictMode(ctx)).Strict())
+
+	strictClient, _ := newCapturingLLM(t, config.SetStrictMode(true))
+	assert.True(t, strictClient.FallbackPolicy(ctx).Strict())
+}
+
+func TestNewLLMWithMemory_StrictTokenizerFallback(t *testing.T) {
+	l, _ := newCapturingLLM(t, config.SetStrictMode(true))
+
+	_, err := NewLLMWithMemory(l, 1000, "unknown-model", utils.NewLogger(utils.LogLevelOff))
+	var interventionErr *InterventionError
+	require.True(t, errors.As(err, &interventionErr))
+	assert.Equal(t, InterventionTokenizerFallback, interventionErr.Intervention)
+}
@@ -0,0 +1,255 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// FewShotExample is a labeled example of a FewShotSet: an input and the
+// output expected for it.
+type FewShotExample struct {
+	Input  string `json:"input"`
+	Output string `json:"output"`
+}
+
+// String formats the example as it is added to a prompt.
+func (e FewShotExample) String() string {
+	return fmt.Sprintf("Input: %s\nOutput: %s", e.Input, e.Output)
+}
+
+// FewShotSet is a pool of examples of a task, from which WithFewShot selects
+// the examples of each prompt. By default the first examples are selected;
+// WithFewShotSeed selects random ones and WithFewShotEmbedding the ones most
+// similar to the prompt input. A FewShotSet is safe for concurrent use.
+type FewShotSet struct {
+	examples  []FewShotExample
+	seed      *int64
+	embed     utils.EmbeddingFunc
+	maxTokens int
+	logger    utils.Logger
+
+	mutex      sync.Mutex
+	embeddings [][]float64 // Embeddings of the examples, computed on first use
+}
+
+// FewShotOption configures a FewShotSet.
+type FewShotOption func(*FewShotSet)
+
+// WithFewShotSeed selects random examples, shuffled with seed, so that every
+// prompt gets the same selection.
+func WithFewShotSeed(seed int64) FewShotOption {
+	return func(s *FewShotSet) {
+		s.seed = &seed
+	}
+}
+
+// WithFewShotEmbedding selects the examples whose inputs are most similar to
+// the prompt input, by the cosine similarity of their embeddings, in
+// FewShotSet.Select. The examples are embedded once, on first use. It takes
+// precedence over WithFewShotSeed.
+func WithFewShotEmbedding(embed utils.EmbeddingFunc) FewShotOption {
+	return func(s *FewShotSet) {
+		s.embed = embed
+	}
+}
+
+// WithFewShotMaxTokens sets the token budget of the selected examples.
+// Examples that would exceed it are skipped, so the best examples that fit
+// are kept. Tokens are counted as by WithContextInjection.
+func WithFewShotMaxTokens(maxTokens int) FewShotOption {
+	return func(s *FewShotSet) {
+		s.maxTokens = maxTokens
+	}
+}
+
+// WithFewShotLogger sets the logger of the warnings of WithFewShot. The
+// default logs warnings to stdout.
+func WithFewShotLogger(logger utils.Logger) FewShotOption {
+	return func(s *FewShotSet) {
+		s.logger = logger
+	}
+}
+
+// NewFewShotSet creates a pool of examples.
+//
+// Example:
+//
+//	set := NewFewShotSet([]FewShotExample{
+//	    {Input: "物流太慢了，等了一周", Output: "负面"},
+//	    {Input: "包装精美，很满意", Output: "正面"},
+//	    {Input: "还行吧，中规中矩", Output: "中性"},
+//	}, WithFewShotEmbedding(embed), WithFewShotMaxTokens(500))
+//	examples, err := set.Select(ctx, input, 2)
+//	if err != nil {
+//	    return err
+//	}
+//	prompt := NewPrompt(input, WithFewShotExamples(examples))
+func NewFewShotSet(examples []FewShotExample, opts ...FewShotOption) *FewShotSet {
+	s := &FewShotSet{
+		examples: append([]FewShotExample(nil), examples...),
+		logger:   utils.NewLogger(utils.LogLevelWarn),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Examples returns the examples of the set, in order.
+func (s *FewShotSet) Examples() []FewShotExample {
+	return append([]FewShotExample(nil), s.examples...)
+}
+
+// Select returns up to k examples for a prompt with the given input, best
+// first, within the token budget of the set. k of 0 or less selects every
+// example that fits.
+//
+// Returns:
+//   - The selected examples
+//   - Any error embedding the input or the examples
+func (s *FewShotSet) Select(ctx context.Context, input string, k int) ([]FewShotExample, error) {
+	order, err := s.order(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return s.fit(order, k), nil
+}
+
+// order returns the indices of the examples, best first.
+func (s *FewShotSet) order(ctx context.Context, input string) ([]int, error) {
+	if s.embed == nil || len(s.examples) == 0 {
+		return s.sequence(), nil
+	}
+	embeddings, err := s.exampleEmbeddings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	embedding, err := s.embed(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed input: %w", err)
+	}
+	similarity := make([]float64, len(embeddings))
+	for i, example := range embeddings {
+		similarity[i] = cosineSimilarity(embedding, example)
+	}
+	order := make([]int, len(s.examples))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return similarity[order[i]] > similarity[order[j]]
+	})
+	return order, nil
+}
+
+// sequence returns the indices of the examples in order, shuffled if the
+// set has a seed.
+func (s *FewShotSet) sequence() []int {
+	if s.seed != nil {
+		return rand.New(rand.NewSource(*s.seed)).Perm(len(s.examples))
+	}
+	order := make([]int, len(s.examples))
+	for i := range order {
+		order[i] = i
+	}
+	return order
+}
+
+// exampleEmbeddings returns the embeddings of the example inputs, embedding
+// them on first use. A failed embedding is retried on the next call.
+func (s *FewShotSet) exampleEmbeddings(ctx context.Context) ([][]float64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.embeddings != nil {
+		return s.embeddings, nil
+	}
+	embeddings := make([][]float64, len(s.examples))
+	for i, example := range s.examples {
+		embedding, err := s.embed(ctx, example.Input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed example %d: %w", i+1, err)
+		}
+		embeddings[i] = embedding
+	}
+	s.embeddings = embeddings
+	return embeddings, nil
+}
+
+// fit returns the first k examples of order that fit in the token budget.
+func (s *FewShotSet) fit(order []int, k int) []FewShotExample {
+	count := countTokens
+	if s.maxTokens > 0 {
+		if encoding, err := encodingForModel("gpt-4o"); err == nil {
+			count = func(text string) int { return len(encoding.Encode(text, nil, nil)) }
+		}
+	}
+
+	var selected []FewShotExample
+	used := 0
+	for _, i := range order {
+		if k > 0 && len(selected) == k {
+			break
+		}
+		if s.maxTokens > 0 {
+			tokens := count("- " + s.examples[i].String() + "\n")
+			if used+tokens > s.maxTokens {
+				continue
+			}
+			used += tokens
+		}
+		selected = append(selected, s.examples[i])
+	}
+	return selected
+}
+
+// WithFewShot adds k examples selected from set to the prompt, as
+// WithExamples does, so that prompts of a task are built from one pool of
+// examples. It selects the first examples of the set, or random ones with
+// WithFewShotSeed, and makes no calls: a set with WithFewShotEmbedding is
+// selected from as if it had none, with a warning. Select the examples most
+// similar to the input with FewShotSet.Select and add them with
+// WithFewShotExamples instead. A nil set adds no examples.
+//
+// Parameters:
+//   - set: The pool of examples
+//   - k: The number of examples to add; 0 or less for every example that fits
+//
+// Example:
+//
+//	prompt := NewPrompt("判断评论的情感：质量不错，就是发货慢", WithFewShot(set, 3))
+func WithFewShot(set *FewShotSet, k int) PromptOption {
+	return func(p *Prompt) {
+		if set == nil {
+			return
+		}
+		if set.embed != nil {
+			set.logger.Warn("WithFewShot does not select examples by embedding; use FewShotSet.Select and WithFewShotExamples",
+				"fallback", "set order")
+		}
+		WithFewShotExamples(set.fit(set.sequence(), k))(p)
+	}
+}
+
+// WithFewShotExamples adds examples, such as those selected by
+// FewShotSet.Select, to the prompt as WithExamples does.
+//
+// Example:
+//
+//	examples, err := set.Select(ctx, input, 3)
+//	if err != nil {
+//	    return err
+//	}
+//	prompt := NewPrompt(input, WithFewShotExamples(examples))
+func WithFewShotExamples(examples []FewShotExample) PromptOption {
+	return func(p *Prompt) {
+		for _, example := range examples {
+			p.Examples = append(p.Examples, example.String())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+var reviewExamples = []FewShotExample{
+	{Input: "物流太慢了，等了一周", Output: "负面"},
+	{Input: "包装精美，很满意", Output: "正面"},
+	{Input: "还行吧，中规中矩", Output: "中性"},
+}
+
+func TestWithFewShot(t *testing.T) {
+	prompt := NewPrompt("判断评论的情感", WithFewShot(NewFewShotSet(reviewExamples), 2))
+	assert.Equal(t, []string{
+		"Input: 物流太慢了，等了一周\nOutput: 负面",
+		"Input: 包装精美，很满意\nOutput: 正面",
+	}, prompt.Examples)
+
+	set := NewFewShotSet(reviewExamples, WithFewShotSeed(7))
+	perm := rand.New(rand.NewSource(7)).Perm(3)
+	want := []string{reviewExamples[perm[0]].String(), reviewExamples[perm[1]].String()}
+	assert.Equal(t, want, NewPrompt("判断评论的情感", WithFewShot(set, 2)).Examples)
+	assert.Equal(t, want, NewPrompt("另一条评论", WithFewShot(set, 2)).Examples, "a seed gives the same selection")
+
+	assert.Len(t, NewPrompt("判断评论的情感", WithFewShot(set, 0)).Examples, 3)
+}
+
+func TestFewShotSet_Similar(t *testing.T) {
+	vectors := map[string][]float64{
+		reviewExamples[0].Input: {1, 0, 0},
+		reviewExamples[1].Input: {0, 1, 0},
+		reviewExamples[2].Input: {0, 0, 1},
+		"发货慢，但质量还行":             {0.9, 0.1, 0.5},
+	}
+	calls := 0
+	embed := func(ctx context.Context, text string) ([]float64, error) {
+		calls++
+		return vectors[text], nil
+	}
+	var logs bytes.Buffer
+	set := NewFewShotSet(reviewExamples, WithFewShotEmbedding(embed), WithFewShotSeed(7),
+		WithFewShotLogger(utils.NewSlogLogger(slog.NewTextHandler(&logs, nil))))
+
+	selected, err := set.Select(context.Background(), "发货慢，但质量还行", 2)
+	require.NoError(t, err)
+	prompt := NewPrompt("发货慢，但质量还行", WithFewShotExamples(selected))
+	assert.Equal(t, []string{reviewExamples[0].String(), reviewExamples[2].String()}, prompt.Examples)
+	selected, err = set.Select(context.Background(), "发货慢，但质量还行", 1)
+	require.NoError(t, err)
+	assert.Equal(t, reviewExamples[:1], selected)
+	assert.Equal(t, 5, calls, "the examples are embedded once")
+
+	perm := rand.New(rand.NewSource(7)).Perm(3)
+	prompt = NewPrompt("发货慢，但质量还行", WithFewShot(set, 1))
+	assert.Equal(t, []string{reviewExamples[perm[0]].String()}, prompt.Examples, "WithFewShot falls back to the seeded order")
+	assert.Equal(t, 5, calls, "WithFewShot makes no calls")
+	assert.Contains(t, logs.String(), "WithFewShot does not select examples by embedding")
+
+	failing := NewFewShotSet(reviewExamples, WithFewShotEmbedding(func(ctx context.Context, text string) ([]float64, error) {
+		return nil, errors.New("embeddings unavailable")
+	}))
+	_, err = failing.Select(context.Background(), "发货慢", 1)
+	assert.ErrorContains(t, err, "failed to embed example 1: embeddings unavailable")
+}
+
+func TestWithFewShot_NilSet(t *testing.T) {
+	assert.Empty(t, NewPrompt("判断评论的情感", WithFewShot(nil, 2)).Examples)
+}
+
+func TestFewShotSet_MaxTokens(t *testing.T) {
+	useRuneTokenizer(t)
+
+	// With the rune tokenizer, the examples and their list markers are 31, 29
+	// and 29 tokens; examples over the budget are skipped.
+	set := NewFewShotSet(reviewExamples, WithFewShotMaxTokens(60))
+	assert.Equal(t, []string{reviewExamples[0].String(), reviewExamples[1].String()}, NewPrompt("评论", WithFewShot(set, 0)).Examples)
+	set = NewFewShotSet(reviewExamples, WithFewShotMaxTokens(30))
+	assert.Equal(t, []string{reviewExamples[1].String()}, NewPrompt("评论", WithFewShot(set, 3)).Examples)
+}
@@ -0,0 +1,271 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import "fmt"
+
+// WithTemperature overrides the client's temperature for a single Generate call.
+//
+// Example:
+//
+//	response, err := llm.Generate(ctx, prompt, WithTemperature(0.2))
+func WithTemperature(temperature float64) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Temperature = &temperature
+	}
+}
+
+// WithMaxTokens overrides the client's maximum number of generated tokens (see
+// config.SetMaxTokens) for a single Generate call, without changing the client,
+// for instance to cap a short classification made with a client configured
+// for long generations. Each provider receives it under its own parameter
+// name, such as "maxOutputTokens" for Gemini and "num_predict" for Ollama.
+//
+// Example:
+//
+//	label, err := llm.Generate(ctx, classifyPrompt, WithMaxTokens(10))
+func WithMaxTokens(maxTokens int) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.MaxTokens = &maxTokens
+	}
+}
+
+// WithModel sends a single Generate call to a different model of the same provider.
+func WithModel(model string) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Model = model
+	}
+}
+
+// WithStopSequences makes generation stop when any of the given sequences is
+// produced, for a single Generate call. Each provider receives them under its
+// own parameter name, such as "stop" for OpenAI and "stop_sequences" for
+// Anthropic. They replace the prompt's stop sequences (see
+// WithPromptStopSequences), which replace the client's (see
+// config.SetStopSequences). When there are more sequences than the provider
+// accepts (4 for OpenAI and Groq, 5 for Gemini and Cohere), only the first
+// ones are sent and a warning is logged.
+//
+// Example:
+//
+//	response, err := llm.Generate(ctx, prompt, WithStopSequences("\n\n", "END"))
+func WithStopSequences(sequences ...string) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.StopSequences = append(c.StopSequences, sequences...)
+	}
+}
+
+// WithTopP overrides the client's nucleus sampling parameter for a single
+// Generate call. It must be in (0, 1]; other values fail the call with
+// ErrorTypeInvalidInput.
+func WithTopP(topP float64) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.TopP = &topP
+	}
+}
+
+// WithTopK overrides the client's top-k sampling parameter for a single
+// Generate call. Providers without top-k sampling ignore it.
+func WithTopK(k int) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.TopK = &k
+	}
+}
+
+// WithFrequencyPenalty overrides the client's frequency penalty for a single
+// Generate call. It must be in [-2, 2]. Providers without frequency penalties
+// drop it with a debug log.
+func WithFrequencyPenalty(penalty float64) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.FrequencyPenalty = &penalty
+	}
+}
+
+// WithPresencePenalty overrides the client's presence penalty for a single
+// Generate call. It must be in [-2, 2]. Providers without presence penalties
+// drop it with a debug log.
+func WithPresencePenalty(penalty float64) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.PresencePenalty = &penalty
+	}
+}
+
+// WithRepetitionPenalty overrides the client's repetition penalty (see
+// config.SetRepeatPenalty) for a single Generate call. Providers without a
+// repetition penalty ignore it.
+func WithRepetitionPenalty(penalty float64) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.RepetitionPenalty = &penalty
+	}
+}
+
+// WithSeed overrides the client's sampling seed for a single Generate call.
+// With a temperature of 0, it makes the output of OpenAI-compatible providers
+// largely reproducible; UsageRecorder.SystemFingerprint reports the backend
+// configuration, whose changes can still alter the output. Providers without
+// seeded sampling, such as Anthropic, drop it with a debug log.
+//
+// Example:
+//
+//	ctx, recorder := llm.WithUsageRecorder(ctx)
+//	response, err := client.Generate(ctx, prompt, WithSeed(42), WithTemperature(0))
+//	fmt.Println(recorder.SystemFingerprint())
+func WithSeed(seed int) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.Seed = &seed
+	}
+}
+
+// WithAutoTruncate shortens the prompt's input for a single Generate call
+// when the prompt and the response would not fit the context window of the
+// model, as registered with RegisterContextWindow. The tokens counted for the
+// response are the call's max tokens. Truncation is logged as a warning;
+// prompts for models with an unknown context window are sent unchanged.
+//
+// Example:
+//
+//	response, err := llm.Generate(ctx, prompt, WithAutoTruncate(TruncateMiddle))
+func WithAutoTruncate(strategy TruncateStrategy) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.AutoTruncate = strategy
+	}
+}
+
+// WithoutPersona leaves the client's persona (see config.SetPersona) out of a
+// single Generate call: its instructions are not sent and the response is not
+// checked for forbidden phrases.
+func WithoutPersona() GenerateOption {
+	return func(c *GenerateConfig) {
+		c.WithoutPersona = true
+	}
+}
+
+// WithPersonaLanguage applies the variant of the client's persona for a
+// language tag, such as "en" or "zh-TW", to a single Generate call. See
+// persona.Persona.ForLanguage.
+//
+// Example:
+//
+//	response, err := llm.Generate(ctx, prompt, WithPersonaLanguage("en"))
+func WithPersonaLanguage(lang string) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.PersonaLanguage = lang
+	}
+}
+
+// WithStructuredOutput makes a single Generate call return JSON conforming to
+// the given JSON schema. Providers with native structured output (see
+// SupportsJSONSchema) receive the schema in the request, e.g. as OpenAI's
+// response_format or Ollama's format, so malformed output is rejected by the
+// provider. For other providers the schema is added to the prompt. In both
+// cases the response is validated against the schema, as with
+// GenerateWithSchema.
+//
+// Example:
+//
+//	schema, _ := llm.GenerateJSONSchema(Person{})
+//	response, err := client.Generate(ctx, prompt, WithStructuredOutput(schema))
+func WithStructuredOutput(schema []byte) GenerateOption {
+	return func(c *GenerateConfig) {
+		c.StructuredOutput = schema
+	}
+}
+
+// applyPrompt copies the prompt's sampling settings into the options the
+// call leaves unset, so the precedence is call > prompt > client. Logit biases
+// are merged per token.
+func (c *GenerateConfig) applyPrompt(prompt *Prompt) {
+	if c.TopP == nil {
+		c.TopP = prompt.TopP
+	}
+	if c.TopK == nil {
+		c.TopK = prompt.TopK
+	}
+	if c.FrequencyPenalty == nil {
+		c.FrequencyPenalty = prompt.FrequencyPenalty
+	}
+	if c.PresencePenalty == nil {
+		c.PresencePenalty = prompt.PresencePenalty
+	}
+	if c.RepetitionPenalty == nil {
+		c.RepetitionPenalty = prompt.RepetitionPenalty
+	}
+	if c.Seed == nil {
+		c.Seed = prompt.Seed
+	}
+	for token, value := range prompt.LogitBias {
+		if _, set := c.LogitBias[token]; !set {
+			if c.LogitBias == nil {
+				c.LogitBias = make(map[string]float64, len(prompt.LogitBias))
+			}
+			c.LogitBias[token] = value
+		}
+	}
+}
+
+// validateSampling checks the ranges of the sampling settings that were set:
+// top_p must be in (0, 1] and the frequency and presence penalties in [-2, 2].
+func (c *GenerateConfig) validateSampling() error {
+	if c.TopP != nil && (*c.TopP <= 0 || *c.TopP > 1) {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("top_p %v is not in (0, 1]", *c.TopP), nil)
+	}
+	if c.FrequencyPenalty != nil && (*c.FrequencyPenalty < -2 || *c.FrequencyPenalty > 2) {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("frequency_penalty %v is not in [-2, 2]", *c.FrequencyPenalty), nil)
+	}
+	if c.PresencePenalty != nil && (*c.PresencePenalty < -2 || *c.PresencePenalty > 2) {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("presence_penalty %v is not in [-2, 2]", *c.PresencePenalty), nil)
+	}
+	return nil
+}
+
+// requestOptions returns the per-call overrides as request options using the
+// OpenAI-style keys model, temperature, max_tokens, top_p, frequency_penalty,
+// presence_penalty, seed and stop, plus the Ollama-style keys top_k and
+// repeat_penalty. Providers whose APIs use different names translate them in
+// PrepareRequest and drop the ones they do not support. Only options that were
+// set are included, so client defaults apply to everything else.
+func (c *GenerateConfig) requestOptions() map[string]interface{} {
+	options := make(map[string]interface{})
+	if c.Model != "" {
+		options["model"] = c.Model
+	}
+	if c.Temperature != nil {
+		options["temperature"] = *c.Temperature
+	}
+	if c.MaxTokens != nil {
+		options["max_tokens"] = *c.MaxTokens
+	}
+	if c.TopP != nil {
+		options["top_p"] = *c.TopP
+	}
+	if c.TopK != nil {
+		options["top_k"] = *c.TopK
+	}
+	if c.FrequencyPenalty != nil {
+		options["frequency_penalty"] = *c.FrequencyPenalty
+	}
+	if c.PresencePenalty != nil {
+		options["presence_penalty"] = *c.PresencePenalty
+	}
+	if c.RepetitionPenalty != nil {
+		options["repeat_penalty"] = *c.RepetitionPenalty
+	}
+	if c.Seed != nil {
+		options["seed"] = *c.Seed
+	}
+	if len(c.StopSequences) > 0 {
+		options["stop"] = c.StopSequences
+	}
+	return options
+}
+
+// mergeOptions combines client options with per-call overrides. Overrides win.
+func mergeOptions(clientOptions, overrides map[string]interface{}) map[string]interface{} {
+	options := make(map[string]interface{}, len(clientOptions)+len(overrides))
+	for k, v := range clientOptions {
+		options[k] = v
+	}
+	for k, v := range overrides {
+		options[k] = v
+	}
+	return options
+}
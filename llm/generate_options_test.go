@@ -0,0 +1,436 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// newCapturingLLM returns an OpenAI-backed LLM pointed at a test server that
+// records the last request body.
+func newCapturingLLM(t *testing.T, opts ...config.ConfigOption) (LLM, func() map[string]interface{}) {
+	var last map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		last = nil
+		require.NoError(t, json.Unmarshal(body, &last))
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetModel("gpt-4o"),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetTemperature(0.7),
+		config.SetMaxTokens(300),
+		config.SetMaxRetries(0),
+		config.SetTimeout(5*time.Second),
+	)
+	config.ApplyOptions(cfg, opts...)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+	return l, func() map[string]interface{} { return last }
+}
+
+func TestGenerateOptions_Precedence(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t)
+	ctx := context.Background()
+
+	// Client settings apply when the call does not override them, and the
+	// provider default (no stop sequences) applies when neither sets a value.
+	_, err := l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	req := lastRequest()
+	assert.Equal(t, "gpt-4o", req["model"])
+	assert.Equal(t, 0.7, req["temperature"])
+	assert.Equal(t, float64(300), req["max_tokens"])
+	assert.NotContains(t, req, "stop")
+
+	// Call options override the client settings for that call only.
+	_, err = l.Generate(ctx, NewPrompt("你好"),
+		WithTemperature(0.2), WithMaxTokens(50), WithModel("gpt-4o-mini"), WithStopSequences("\n\n", "END"))
+	require.NoError(t, err)
+	req = lastRequest()
+	assert.Equal(t, "gpt-4o-mini", req["model"])
+	assert.Equal(t, 0.2, req["temperature"])
+	assert.Equal(t, float64(50), req["max_tokens"])
+	assert.Equal(t, []interface{}{"\n\n", "END"}, req["stop"])
+
+	_, err = l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	req = lastRequest()
+	assert.Equal(t, "gpt-4o", req["model"])
+	assert.Equal(t, 0.7, req["temperature"])
+	assert.NotContains(t, req, "stop")
+}
+
+func TestGenerateOptions_ZeroTemperatureOverride(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t)
+
+	_, err := l.Generate(context.Background(), NewPrompt("你好"), WithTemperature(0))
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), lastRequest()["temperature"])
+}
+
+func TestGenerateOptions_SamplingParameters(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t, config.SetSeed(1), config.SetTopP(0.8))
+	ctx := context.Background()
+
+	_, err := l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	req := lastRequest()
+	assert.Equal(t, float64(1), req["seed"])
+	assert.Equal(t, 0.8, req["top_p"])
+	assert.NotContains(t, req, "frequency_penalty")
+
+	_, err = l.Generate(ctx, NewPrompt("你好"),
+		WithSeed(42), WithTopP(0.3), WithFrequencyPenalty(0.5), WithPresencePenalty(0.6))
+	require.NoError(t, err)
+	req = lastRequest()
+	assert.Equal(t, float64(42), req["seed"])
+	assert.Equal(t, 0.3, req["top_p"])
+	assert.Equal(t, 0.5, req["frequency_penalty"])
+	assert.Equal(t, 0.6, req["presence_penalty"])
+
+	// OpenAI has neither top-k sampling nor a repetition penalty.
+	_, err = l.Generate(ctx, NewPrompt("你好"), WithTopK(20), WithRepetitionPenalty(1.2))
+	require.NoError(t, err)
+	req = lastRequest()
+	assert.NotContains(t, req, "top_k")
+	assert.NotContains(t, req, "repeat_penalty")
+}
+
+// newSamplingLLM returns a client of provider whose server samples each
+// response with the seed of the request, read from seedKey, as a provider
+// with seeded sampling does. Requests without a seed are sampled differently
+// each time.
+func newSamplingLLM(t *testing.T, provider, seedKey string, opts ...config.ConfigOption) LLM {
+	words := strings.Fields("春 夏 秋 冬 山 水 风 云 花 月")
+	unseeded := rand.New(rand.NewSource(time.Now().UnixNano()))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		source := unseeded
+		if seed, ok := req[seedKey].(float64); ok {
+			source = rand.New(rand.NewSource(int64(seed)))
+		}
+		var sampled strings.Builder
+		for i := 0; i < 12; i++ {
+			sampled.WriteString(words[source.Intn(len(words))])
+		}
+		response, _ := json.Marshal(map[string]interface{}{
+			"choices": []map[string]interface{}{{"message": map[string]string{"content": sampled.String()}}},
+		})
+		w.Write(response)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider(provider),
+		config.SetModel("test-model"),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetMaxRetries(0),
+		config.SetTimeout(5*time.Second),
+	)
+	config.ApplyOptions(cfg, opts...)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+	return l
+}
+
+func TestGenerateOptions_SeedReproducible(t *testing.T) {
+	ctx := context.Background()
+	for provider, seedKey := range map[string]string{"openai": "seed", "mistral": "random_seed"} {
+		t.Run(provider, func(t *testing.T) {
+			l := newSamplingLLM(t, provider, seedKey)
+			generate := func(opts ...GenerateOption) string {
+				response, err := l.Generate(ctx, NewPrompt("写一首诗"), opts...)
+				require.NoError(t, err)
+				return response
+			}
+
+			first := generate(WithSeed(42))
+			assert.Equal(t, first, generate(WithSeed(42)), "the same seed gives the same response")
+			assert.NotEqual(t, first, generate(WithSeed(7)))
+
+			seeded := newSamplingLLM(t, provider, seedKey, config.SetSeed(42))
+			response, err := seeded.Generate(ctx, NewPrompt("写一首诗"))
+			require.NoError(t, err)
+			assert.Equal(t, first, response, "a client seed works like a call seed")
+			response, err = seeded.Generate(ctx, NewPrompt("写一首诗", WithPromptSeed(42)))
+			require.NoError(t, err)
+			assert.Equal(t, first, response)
+		})
+	}
+}
+
+func TestGenerateOptions_PromptSamplingParameters(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t, config.SetTopP(0.8), config.SetFrequencyPenalty(0.1))
+	ctx := context.Background()
+	prompt := NewPrompt("你好", WithPromptTopP(0.5), WithPromptFrequencyPenalty(0.4), WithPromptPresencePenalty(0.2), WithPromptSeed(7))
+
+	_, err := l.Generate(ctx, prompt)
+	require.NoError(t, err)
+	req := lastRequest()
+	assert.Equal(t, 0.5, req["top_p"], "prompt overrides client")
+	assert.Equal(t, float64(7), req["seed"])
+	assert.Equal(t, 0.4, req["frequency_penalty"])
+	assert.Equal(t, 0.2, req["presence_penalty"])
+
+	_, err = l.Generate(ctx, prompt, WithTopP(0.3), WithSeed(42))
+	require.NoError(t, err)
+	req = lastRequest()
+	assert.Equal(t, 0.3, req["top_p"], "call overrides prompt")
+	assert.Equal(t, float64(42), req["seed"])
+	assert.Equal(t, 0.4, req["frequency_penalty"])
+
+	_, err = l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	req = lastRequest()
+	assert.Equal(t, 0.8, req["top_p"], "client applies to prompts without sampling settings")
+	assert.Equal(t, 0.1, req["frequency_penalty"])
+}
+
+func TestValidate_TopP(t *testing.T) {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("openai"), config.SetAPIKey("sk-test-key-0123456789"))
+	assert.Equal(t, 1.0, cfg.TopP)
+	assert.NoError(t, Validate(cfg))
+
+	config.ApplyOptions(cfg, config.SetTopP(0))
+	assert.Error(t, Validate(cfg), "top_p 0 is rejected rather than silently dropped")
+	config.ApplyOptions(cfg, config.SetTopP(1.5))
+	assert.Error(t, Validate(cfg))
+}
+
+func TestGenerateOptions_SamplingRanges(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t)
+	ctx := context.Background()
+
+	_, err := l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	req := lastRequest()
+	assert.NotContains(t, req, "top_p", "unset options are omitted")
+	assert.NotContains(t, req, "frequency_penalty")
+	assert.NotContains(t, req, "presence_penalty")
+
+	for name, opt := range map[string]GenerateOption{
+		"top_p":             WithTopP(1.2),
+		"frequency_penalty": WithFrequencyPenalty(-2.5),
+		"presence_penalty":  WithPresencePenalty(3),
+	} {
+		var llmErr *LLMError
+		_, err = l.Generate(ctx, NewPrompt("你好"), opt)
+		require.ErrorAs(t, err, &llmErr, name)
+		assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type, name)
+		assert.Contains(t, llmErr.Message, name)
+	}
+
+	_, err = l.Generate(ctx, NewPrompt("你好", WithPromptTopP(0)))
+	assert.Error(t, err, "prompt settings are checked as well")
+}
+
+func TestValidate_BaseURL(t *testing.T) {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("openai"), config.SetAPIKey("sk-test-key-0123456789"))
+	assert.NoError(t, Validate(cfg), "no base URL uses the provider's")
+
+	config.ApplyOptions(cfg, config.SetBaseURL("http://localhost:8000/v1/"))
+	assert.NoError(t, Validate(cfg))
+	config.ApplyOptions(cfg, config.SetBaseURL("localhost:8000"))
+	assert.Error(t, Validate(cfg))
+}
+
+func TestNewLLM_AzureRequiresDeployment(t *testing.T) {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("azure"),
+		config.SetAPIKey("azure-key"),
+		config.SetBaseURL("https://my-resource.openai.azure.com"),
+		config.SetAzureAPIVersion("2024-10-21"),
+	)
+	_, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeProvider, llmErr.Type)
+	assert.ErrorContains(t, err, "azure provider requires a deployment")
+
+	config.ApplyOptions(cfg, config.SetAzureDeployment("gpt-4o-prod"))
+	_, err = NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	assert.NoError(t, err)
+}
+
+func TestGenerateOptions_StopSequenceLimit(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t)
+	ctx := context.Background()
+
+	_, err := l.Generate(ctx, NewPrompt("你好"), WithStopSequences("\n\n", "END"))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"\n\n", "END"}, lastRequest()["stop"])
+
+	// OpenAI accepts 4 stop sequences; the rest are dropped with a warning.
+	_, err = l.Generate(ctx, NewPrompt("你好"), WithStopSequences("a", "b", "c", "d", "e"))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b", "c", "d"}, lastRequest()["stop"])
+	_, err = l.Generate(ctx, NewPrompt("你好", WithPromptStopSequences("a", "b", "c", "d", "e")))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b", "c", "d"}, lastRequest()["stop"])
+
+	var llmErr *LLMError
+	_, err = l.GenerateWithSchema(ctx, NewPrompt("你好"), map[string]interface{}{"type": "object"}, WithStopSequences(""))
+	require.ErrorAs(t, err, &llmErr)
+	assert.Contains(t, err.Error(), "stop sequences cannot be empty")
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetProvider("openai"), config.SetAPIKey("sk-test-key-0123456789"), config.SetStopSequences("a", "b", "c", "d"))
+	assert.NoError(t, Validate(cfg))
+	config.ApplyOptions(cfg, config.SetStopSequences("a", "b", "c", "d", "e"))
+	assert.Error(t, Validate(cfg), "client stop sequences are limited to 4 for every provider")
+	config.ApplyOptions(cfg, config.SetStopSequences("END", ""))
+	assert.Error(t, Validate(cfg))
+}
+
+func TestGenerateOptions_PromptStopSequences(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t, config.SetStopSequences("END"))
+	ctx := context.Background()
+	prompt := NewPrompt("列出三种水果", WithPromptStopSequences("]"))
+
+	_, err := l.Generate(ctx, prompt)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"]"}, lastRequest()["stop"], "the prompt's stop sequences override the client's")
+
+	_, err = l.Generate(ctx, prompt, WithStopSequences("\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"\n"}, lastRequest()["stop"], "the call's stop sequences override the prompt's")
+
+	_, err = l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"END"}, lastRequest()["stop"])
+}
+
+func TestWithStructuredOutput_ProviderFallback(t *testing.T) {
+	schema := []byte(`{"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}`)
+
+	cases := []struct {
+		provider string
+		native   string // request field carrying the schema, "" for the prompt fallback
+	}{
+		{"openai", "response_format"},
+		{"ollama", "format"},
+		{"mistral", "response_format"},
+		{"cohere", "response_format"},
+		{"anthropic", "system"},
+		{"groq", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.provider, func(t *testing.T) {
+			l, lastRequest := newCapturingLLM(t, config.SetProvider(tc.provider), config.SetAPIKey("test-key"))
+			assert.Equal(t, tc.native != "", l.SupportsJSONSchema())
+
+			// The test server does not answer with matching JSON; only the request matters.
+			_, _ = l.Generate(context.Background(), NewPrompt("杭州在哪里？"), WithStructuredOutput(schema))
+
+			body, err := json.Marshal(lastRequest())
+			require.NoError(t, err)
+			if tc.native != "" {
+				require.Contains(t, lastRequest(), tc.native)
+				assert.Contains(t, string(body), "city")
+			} else {
+				assert.NotContains(t, lastRequest(), "response_format")
+				assert.Contains(t, string(body), "according to this schema")
+			}
+		})
+	}
+
+	l, _ := newCapturingLLM(t)
+	_, err := l.Generate(context.Background(), NewPrompt("杭州在哪里？"), WithStructuredOutput([]byte("{")))
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+}
+
+// noSystemProvider is a provider without system messages.
+type noSystemProvider struct {
+	providers.Provider
+}
+
+func (noSystemProvider) SupportsSystemPrompt() bool { return false }
+
+func TestGenerate_SystemPrompt(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t)
+	ctx := context.Background()
+
+	prompt := NewPrompt("把这句话翻译成英文：你好",
+		WithSystemPrompt("你是一名专业翻译。", CacheTypeEphemeral),
+		WithDirectives("保持语气自然"),
+		WithExamples("早上好 -> Good morning"),
+	)
+	_, err := l.Generate(ctx, prompt)
+	require.NoError(t, err)
+
+	messages := lastRequest()["messages"].([]interface{})
+	require.Len(t, messages, 2)
+	system := messages[0].(map[string]interface{})
+	user := messages[1].(map[string]interface{})
+	assert.Equal(t, "system", system["role"])
+	assert.Equal(t, "你是一名专业翻译。", system["content"])
+	assert.NotContains(t, user["content"], "你是一名专业翻译。")
+	assert.Contains(t, user["content"], "保持语气自然")
+	assert.Contains(t, user["content"], "早上好 -> Good morning")
+
+	// The system prompt belongs to the prompt, not to the client.
+	_, err = l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	assert.Len(t, lastRequest()["messages"], 1)
+}
+
+func TestProviderPrompt_Fallback(t *testing.T) {
+	l := &LLMImpl{Provider: noSystemProvider{providers.NewOpenAIProvider("", "key", "gpt-4o", nil)}}
+	prompt := NewPrompt("你好", WithSystemPrompt("你是一名专业翻译。", ""), WithDirectives("简洁"))
+
+	options := map[string]interface{}{}
+	text := l.providerPrompt(prompt, options)
+	assert.True(t, strings.HasPrefix(text, "<system>\n你是一名专业翻译。\n</system>\n\nDirectives:\n- 简洁\n\n你好"), text)
+	assert.NotContains(t, options, "system_prompt")
+}
+
+func TestStream_PreparedLikeGenerate(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t, config.SetPersona(testPersona))
+	ctx := context.Background()
+	prompt := NewPrompt("你好", WithPromptStopSequences("END"), WithPromptTopP(0.5))
+
+	stream, err := l.Stream(ctx, prompt, WithStreamGenerateOptions(WithTemperature(0.2)))
+	require.NoError(t, err)
+	require.NoError(t, stream.Close())
+	req := lastRequest()
+	assert.Equal(t, true, req["stream"])
+	assert.Equal(t, []interface{}{"END"}, req["stop"])
+	assert.Equal(t, 0.5, req["top_p"])
+	assert.Equal(t, 0.2, req["temperature"])
+	messages := req["messages"].([]interface{})
+	system := messages[0].(map[string]interface{})
+	assert.Equal(t, "system", system["role"])
+	assert.Contains(t, system["content"], "小橙")
+
+	_, err = l.Stream(ctx, prompt, WithStreamGenerateOptions(WithStructuredOutput([]byte(`{"type":"object"}`))))
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+}
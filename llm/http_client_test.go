@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// roundTripFunc serves requests without a network, as an injected transport
+// would in tests.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newHTTPClientLLM(t *testing.T, client *http.Client) LLM {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetModel("gpt-4o"),
+		config.SetAPIKey("test-key"),
+		config.SetMaxRetries(0),
+		config.SetTimeout(5*time.Second),
+		config.SetHTTPClient(client),
+	)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+	return l
+}
+
+func TestSetHTTPClient(t *testing.T) {
+	var urls []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		urls = append(urls, req.URL.String())
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"choices":[{"message":{"content":"ok"}}]}`)),
+		}, nil
+	})}
+	l := newHTTPClientLLM(t, client)
+
+	response, err := l.Generate(context.Background(), NewPrompt("你好"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response)
+	assert.Equal(t, []string{"https://api.openai.com/v1/chat/completions"}, urls, "requests go through the injected transport")
+	assert.Zero(t, client.Timeout, "the caller's client is not modified")
+}
+
+func TestSetHTTPClient_ContextDeadline(t *testing.T) {
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})}
+	l := newHTTPClientLLM(t, client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := l.Generate(ctx, NewPrompt("你好"))
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), 5*time.Second, "the context deadline applies on top of the client")
+}
@@ -0,0 +1,110 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// ImageInput is an image sent alongside the prompt text (see WithImages).
+// Set either URL or Base64; MediaType is required with Base64.
+type ImageInput = utils.ImageInput
+
+// ErrProviderDoesNotSupportImages is returned when a prompt with images is sent
+// to a provider without vision support.
+var ErrProviderDoesNotSupportImages = errors.New("provider does not support images")
+
+// imageSupporter is implemented by providers that accept images passed as the
+// "images" option.
+type imageSupporter interface {
+	SupportsImages() bool
+}
+
+// NewImageInputFromFile reads an image file and returns it as base64 data. The
+// media type is taken from the file extension, or detected from the content
+// when the extension is unknown.
+//
+// Returns:
+//   - The image input
+//   - Error if the file cannot be read or is not an image
+//
+// Example:
+//
+//	image, err := NewImageInputFromFile("receipt.jpg")
+//	prompt := NewPrompt("这张收据的总金额是多少？", WithImages(image))
+func NewImageInputFromFile(path string) (ImageInput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImageInput{}, fmt.Errorf("failed to read image: %w", err)
+	}
+	image, err := NewImageInputFromBytes(data, mime.TypeByExtension(strings.ToLower(filepath.Ext(path))))
+	if err != nil {
+		return ImageInput{}, fmt.Errorf("file %s: %w", path, err)
+	}
+	return image, nil
+}
+
+// NewImageInputFromBytes returns image data held in memory, such as a
+// screenshot or an upload, as base64 data. An empty mediaType is detected from
+// the content.
+//
+// Returns:
+//   - The image input
+//   - Error if the data is not an image
+//
+// Example:
+//
+//	image, err := NewImageInputFromBytes(screenshot, "image/png")
+//	prompt := NewPrompt("提取截图中的文字", WithImages(image))
+func NewImageInputFromBytes(data []byte, mediaType string) (ImageInput, error) {
+	if mediaType == "" {
+		mediaType = http.DetectContentType(data)
+	}
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	if !strings.HasPrefix(mediaType, "image/") {
+		return ImageInput{}, fmt.Errorf("not an image (%s)", mediaType)
+	}
+	return ImageInput{
+		Base64:    base64.StdEncoding.EncodeToString(data),
+		MediaType: mediaType,
+	}, nil
+}
+
+// NewImageInputFromURL returns an image input referring to a URL, which the
+// provider downloads. The media type is guessed from the URL's extension and
+// left empty when unknown.
+//
+// Example:
+//
+//	prompt := NewPrompt("描述这张图片", WithImages(NewImageInputFromURL("https://example.com/cat.png")))
+func NewImageInputFromURL(imageURL string) ImageInput {
+	ext := path.Ext(imageURL)
+	if u, err := url.Parse(imageURL); err == nil {
+		ext = path.Ext(u.Path)
+	}
+	mediaType, _, _ := strings.Cut(mime.TypeByExtension(strings.ToLower(ext)), ";")
+	return ImageInput{URL: imageURL, MediaType: mediaType}
+}
+
+// checkImages returns ErrProviderDoesNotSupportImages, as an
+// ErrorTypeUnsupported LLMError, if the prompt has images and the provider
+// cannot take them.
+func (l *LLMImpl) checkImages(prompt *Prompt) error {
+	if len(prompt.Images) == 0 {
+		return nil
+	}
+	if supporter, ok := l.Provider.(imageSupporter); ok && supporter.SupportsImages() {
+		return nil
+	}
+	return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("provider %s cannot take images", l.Provider.Name()), ErrProviderDoesNotSupportImages)
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestNewImageInput(t *testing.T) {
+	dir := t.TempDir()
+	png := filepath.Join(dir, "chart")
+	require.NoError(t, os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n0000"), 0o644))
+	image, err := NewImageInputFromFile(png)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", image.MediaType, "detected from the content without an extension")
+	assert.Equal(t, "iVBORw0KGgowMDAw", image.Base64)
+
+	text := filepath.Join(dir, "notes.txt")
+	require.NoError(t, os.WriteFile(text, []byte("你好"), 0o644))
+	_, err = NewImageInputFromFile(text)
+	assert.ErrorContains(t, err, "not an image")
+	_, err = NewImageInputFromFile(filepath.Join(dir, "missing.png"))
+	assert.Error(t, err)
+
+	image, err = NewImageInputFromBytes([]byte("\x89PNG\r\n\x1a\n0000"), "")
+	require.NoError(t, err)
+	assert.Equal(t, ImageInput{Base64: "iVBORw0KGgowMDAw", MediaType: "image/png"}, image)
+	image, err = NewImageInputFromBytes([]byte("RIFF"), "image/webp")
+	require.NoError(t, err)
+	assert.Equal(t, "image/webp", image.MediaType)
+	_, err = NewImageInputFromBytes([]byte("你好"), "")
+	assert.ErrorContains(t, err, "not an image (text/plain")
+
+	assert.Equal(t, ImageInput{URL: "https://example.com/a.JPG?size=2", MediaType: "image/jpeg"}, NewImageInputFromURL("https://example.com/a.JPG?size=2"))
+	assert.Equal(t, ImageInput{URL: "https://example.com/image"}, NewImageInputFromURL("https://example.com/image"))
+}
+
+func TestWithImages(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t)
+	_, err := l.Generate(context.Background(), NewPrompt("描述这张图片", WithImages(NewImageInputFromURL("https://example.com/cat.png"))))
+	require.NoError(t, err)
+
+	req := lastRequest()
+	assert.NotContains(t, req, "images")
+	messages := req["messages"].([]interface{})
+	content := messages[len(messages)-1].(map[string]interface{})["content"].([]interface{})
+	require.Len(t, content, 2)
+	assert.Equal(t, "image_url", content[1].(map[string]interface{})["type"])
+}
+
+func TestWithImages_UnsupportedProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("mistral"),
+		config.SetModel("mistral-large-latest"),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetMaxRetries(0),
+		config.SetTimeout(5*time.Second),
+	)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+
+	prompt := NewPrompt("描述这张图片", WithImages(NewImageInputFromURL("https://example.com/cat.png")))
+	_, err = l.Generate(context.Background(), prompt)
+	assert.ErrorIs(t, err, ErrProviderDoesNotSupportImages)
+	_, err = l.GenerateWithSchema(context.Background(), prompt, map[string]interface{}{"type": "object"})
+	assert.ErrorIs(t, err, ErrProviderDoesNotSupportImages)
+	assert.Zero(t, requests)
+}
@@ -10,9 +10,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/persona"
 	"github.com/yockii/gollm_cn/providers"
 	"github.com/yockii/gollm_cn/utils"
 )
@@ -45,8 +49,8 @@ type LLM interface {
 	// SetLogLevel adjusts the logging verbosity.
 	SetLogLevel(level utils.LogLevel)
 
-	// SetEndpoint updates the API endpoint (primarily for local models).
-	// Returns ErrorTypeProvider if the provider doesn't support endpoint configuration.
+	// SetEndpoint updates the base URL of the provider's API, see
+	// config.SetBaseURL.
 	SetEndpoint(endpoint string)
 
 	// NewPrompt creates a new prompt instance.
@@ -57,6 +61,10 @@ type LLM interface {
 
 	// SupportsJSONSchema checks if the provider supports JSON schema validation.
 	SupportsJSONSchema() bool
+
+	// FallbackPolicy returns the policy for fallbacks and repairs applied to a
+	// call made with ctx, honouring both SetStrictMode and WithStrictMode.
+	FallbackPolicy(ctx context.Context) *FallbackPolicy
 }
 
 // LLMImpl implements the LLM interface and manages interactions with specific providers.
@@ -69,6 +77,9 @@ type LLMImpl struct {
 	config     *config.Config         // Configuration settings
 	MaxRetries int                    // Maximum number of retry attempts
 	RetryDelay time.Duration          // Delay between retry attempts
+
+	logprobsMutex sync.Mutex     // Guards lastLogprobs
+	lastLogprobs  []TokenLogprob // Log probabilities of the last response, see GetLastLogprobs
 }
 
 // GenerateOption is a function type for configuring generation behavior.
@@ -76,7 +87,34 @@ type GenerateOption func(*GenerateConfig)
 
 // GenerateConfig holds configuration options for text generation.
 type GenerateConfig struct {
-	UseJSONSchema bool // Whether to use JSON schema validation
+	UseJSONSchema    bool     // Whether to use JSON schema validation
+	StructuredOutput []byte   // JSON schema the response must conform to
+	Temperature      *float64 // Overrides the client temperature for this call
+	MaxTokens        *int     // Overrides the client max tokens for this call
+	Model            string   // Overrides the client model for this call
+	StopSequences    []string // Sequences that stop generation for this call
+
+	TopP              *float64 // Overrides the client top-p for this call
+	TopK              *int     // Overrides the client top-k for this call
+	FrequencyPenalty  *float64 // Overrides the client frequency penalty for this call
+	PresencePenalty   *float64 // Overrides the client presence penalty for this call
+	RepetitionPenalty *float64 // Overrides the client repetition penalty for this call
+	Seed              *int     // Overrides the client seed for this call
+
+	LogitBias map[string]float64 // Biases token strings for this call, see WithLogitBias
+
+	AutoTruncate     TruncateStrategy // Shortens the input to fit the model's context window
+	StrictValidation bool             // Lints the prompt before the call, see WithStrictValidation
+
+	WithoutPersona  bool   // Leaves the client's persona out of this call
+	PersonaLanguage string // Selects the language variant of the client's persona
+}
+
+// configValidator is implemented by providers that need settings without
+// defaults, such as the deployment of Azure OpenAI. NewLLM fails if the
+// configuration lacks them.
+type configValidator interface {
+	ValidateConfig() error
 }
 
 // NewLLM creates a new LLM instance with the specified configuration.
@@ -84,7 +122,8 @@ type GenerateConfig struct {
 //
 // Returns:
 //   - Configured LLM instance
-//   - ErrorTypeProvider if provider initialization fails
+//   - ErrorTypeProvider if provider initialization fails or the provider's
+//     settings are incomplete
 //   - ErrorTypeAuthentication if API key validation fails
 func NewLLM(cfg *config.Config, logger utils.Logger, registry *providers.ProviderRegistry) (LLM, error) {
 	extraHeaders := make(map[string]string)
@@ -105,10 +144,29 @@ func NewLLM(cfg *config.Config, logger utils.Logger, registry *providers.Provide
 	}
 
 	provider.SetDefaultOptions(cfg)
+	if v, ok := provider.(configValidator); ok {
+		if err := v.ValidateConfig(); err != nil {
+			return nil, NewLLMError(ErrorTypeProvider, "invalid provider configuration", err)
+		}
+	}
+
+	client := &http.Client{Timeout: cfg.Timeout}
+	if cfg.HTTPClient != nil {
+		// Copy the client so that the timeout and debug recorder below do not
+		// change the caller's; the copy shares its transport and connections.
+		c := *cfg.HTTPClient
+		if c.Timeout == 0 {
+			c.Timeout = cfg.Timeout
+		}
+		client = &c
+	}
+	if cfg.DebugRecorder != nil {
+		client.Transport = cfg.DebugRecorder.Transport(client.Transport)
+	}
 
 	llmClient := &LLMImpl{
 		Provider:   provider,
-		client:     &http.Client{Timeout: cfg.Timeout},
+		client:     client,
 		logger:     logger,
 		config:     cfg,
 		MaxRetries: cfg.MaxRetries,
@@ -126,11 +184,11 @@ func (l *LLMImpl) SetOption(key string, value interface{}) {
 	l.logger.Debug("Option set", key, value)
 }
 
-// SetEndpoint updates the API endpoint for the provider.
-// This is primarily used for local models like Ollama.
+// SetEndpoint updates the base URL of the provider's API, see
+// config.SetBaseURL.
 func (l *LLMImpl) SetEndpoint(endpoint string) {
-	// This is a no-op for non-Ollama providers
-	l.logger.Debug("SetEndpoint called on non-Ollama provider", "endpoint", endpoint)
+	l.logger.Debug("Setting endpoint", "endpoint", endpoint)
+	l.Provider.SetEndpoint(endpoint)
 }
 
 // SetLogLevel updates the logging verbosity level.
@@ -149,6 +207,11 @@ func (l *LLMImpl) NewPrompt(prompt string) *Prompt {
 	return &Prompt{Input: prompt}
 }
 
+// FallbackPolicy returns the fallback policy for a call made with ctx.
+func (l *LLMImpl) FallbackPolicy(ctx context.Context) *FallbackPolicy {
+	return NewFallbackPolicy(l.config.StrictMode || IsStrictMode(ctx), l.logger)
+}
+
 // SupportsJSONSchema checks if the current provider supports JSON schema validation.
 func (l *LLMImpl) SupportsJSONSchema() bool {
 	return l.Provider.SupportsJSONSchema()
@@ -163,40 +226,128 @@ func (l *LLMImpl) SupportsJSONSchema() bool {
 //   - ErrorTypeAPI for provider API errors
 //   - ErrorTypeResponse for response processing issues
 //   - ErrorTypeRateLimit if provider rate limit is exceeded
+//   - ErrProviderDoesNotSupportImages if the prompt has images the provider cannot take
+//
+// Errors of the provider's API wrap a *ProviderError, matching ErrRateLimited,
+// ErrAuthentication and so on with errors.Is. Authentication, context length
+// and content filter failures are not retried.
 func (l *LLMImpl) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, error) {
 	config := &GenerateConfig{}
 	for _, opt := range opts {
 		opt(config)
 	}
-	// Set the system prompt in the LLM's options
-	if prompt.SystemPrompt != "" {
-		l.SetOption("system_prompt", prompt.SystemPrompt)
+	if config.StructuredOutput != nil {
+		var schema map[string]interface{}
+		if err := json.Unmarshal(config.StructuredOutput, &schema); err != nil {
+			return "", NewLLMError(ErrorTypeInvalidInput, "invalid structured output schema", err)
+		}
+		return l.GenerateWithSchema(ctx, prompt, schema, opts...)
+	}
+	prompt, persona, overrides, err := l.prepareCall(ctx, prompt, config, false)
+	if err != nil {
+		return "", err
 	}
-	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
-		l.logger.Debug("Generating text", "provider", l.Provider.Name(), "prompt", prompt.String(), "system_prompt", prompt.SystemPrompt, "attempt", attempt+1)
+	ctx, trace := l.startCall(ctx, "generate", overrides)
+	strategy := l.retryStrategy()
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		trace.attempts = attempt
+		l.logger.Debug("Generating text", "provider", l.Provider.Name(), "prompt", prompt.String(), "system_prompt", prompt.SystemPrompt, "attempt", attempt)
 		// Pass the entire Prompt struct to attemptGenerate
-		result, err := l.attemptGenerate(ctx, prompt)
+		result, err := l.attemptGenerate(ctx, prompt, overrides, &trace.usage)
 		if err == nil {
-			return result, nil
-		}
-		l.logger.Warn("Generation attempt failed", "error", err, "attempt", attempt+1)
-		if attempt < l.MaxRetries {
-			l.logger.Debug("Retrying", "delay", l.RetryDelay)
-			if err := l.wait(ctx); err != nil {
+			result, err = l.guardPersona(result, persona, func(rewrite *Prompt) (string, error) {
+				return l.attemptGenerate(ctx, rewrite, overrides, &trace.usage)
+			})
+			trace.end(err)
+			if err != nil {
 				return "", err
 			}
+			return result, nil
+		}
+		lastErr = err
+		l.logger.Warn("Generation attempt failed", "error", err, "attempt", attempt)
+		if !isRetryable(err) {
+			break
+		}
+		delay, retry := strategy.NextDelay(attempt, err)
+		if !retry {
+			break
+		}
+		l.logger.Debug("Retrying", "delay", delay)
+		if err := l.wait(ctx, delay); err != nil {
+			trace.end(err)
+			return "", err
 		}
 	}
-	return "", fmt.Errorf("failed to generate after %d attempts", l.MaxRetries+1)
+	err = fmt.Errorf("failed to generate after %d attempts: %w", trace.attempts, lastErr)
+	trace.end(err)
+	return "", err
+}
+
+// prepareCall runs the steps shared by Generate, GenerateWithSchema and
+// Stream before a request is sent: it lints the prompt in strict mode, rejects
+// a response prefix in calls with a schema, checks its images, adds the
+// client's persona, truncates the input if the call asks for it, and resolves
+// the call's options against the prompt's, including its logprobs.
+//
+// Returns:
+//   - The prompt to send
+//   - The persona applied to the prompt, nil if none
+//   - The per-call request options, see GenerateConfig.requestOptions
+//   - The first error found in the prompt or the options
+func (l *LLMImpl) prepareCall(ctx context.Context, prompt *Prompt, config *GenerateConfig, withSchema bool) (*Prompt, *persona.Persona, map[string]interface{}, error) {
+	if err := l.lintPrompt(prompt, config, withSchema); err != nil {
+		return nil, nil, nil, err
+	}
+	if withSchema && prompt.ResponsePrefix != "" {
+		return nil, nil, nil, NewLLMError(ErrorTypeInvalidInput, "a response prefix cannot be combined with a JSON schema", nil)
+	}
+	if err := l.checkImages(prompt); err != nil {
+		return nil, nil, nil, err
+	}
+	p := l.activePersona(config)
+	prompt = l.withPersona(prompt, p)
+	if config.AutoTruncate != "" {
+		truncated, err := l.truncatePrompt(ctx, prompt, config.AutoTruncate, config)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		prompt = truncated
+	}
+	config.applyPrompt(prompt)
+	if err := config.validateSampling(); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := l.applyStopSequences(prompt, config); err != nil {
+		return nil, nil, nil, err
+	}
+	overrides := config.requestOptions()
+	if err := l.applyLogitBias(config, overrides); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := l.applyLogprobs(prompt, overrides); err != nil {
+		return nil, nil, nil, err
+	}
+	return prompt, p, overrides, nil
+}
+
+// retryStrategy returns the configured retry strategy, or a ConstantRetry
+// built from MaxRetries and RetryDelay when none is set.
+func (l *LLMImpl) retryStrategy() RetryStrategy {
+	if l.config.RetryStrategy != nil {
+		return l.config.RetryStrategy
+	}
+	return utils.ConstantRetry{MaxRetries: l.MaxRetries, Delay: l.RetryDelay}
 }
 
 // wait implements a cancellable delay between retry attempts.
 // Returns context.Canceled if the context is cancelled during the wait.
-func (l *LLMImpl) wait(ctx context.Context) error {
+func (l *LLMImpl) wait(ctx context.Context, delay time.Duration) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-time.After(l.RetryDelay):
+	case <-time.After(delay):
 		return nil
 	}
 }
@@ -210,12 +361,9 @@ func (l *LLMImpl) wait(ctx context.Context) error {
 //   - ErrorTypeAPI for provider API errors
 //   - ErrorTypeResponse for response processing issues
 //   - ErrorTypeRateLimit if provider rate limit is exceeded
-func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string, error) {
-	// Create a new options map that includes both l.Options and prompt-specific options
-	options := make(map[string]interface{})
-	for k, v := range l.Options {
-		options[k] = v
-	}
+func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt, overrides map[string]interface{}, usage *tokenUsage) (string, error) {
+	// Create a new options map that includes l.Options, per-call overrides and prompt-specific options
+	options := mergeOptions(l.Options, overrides)
 
 	// Add Tools and ToolChoice to options
 	if len(prompt.Tools) > 0 {
@@ -226,7 +374,7 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string,
 	}
 
 	// Prepare the request with both the user prompt and the combined options
-	reqBody, err := l.Provider.PrepareRequest(prompt.String(), options)
+	reqBody, err := l.Provider.PrepareRequest(l.providerPrompt(prompt, options), options)
 	if err != nil {
 		return "", NewLLMError(ErrorTypeRequest, "failed to prepare request", err)
 	}
@@ -255,8 +403,7 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string,
 	l.logger.Debug("Full API response", "body", string(body))
 
 	if resp.StatusCode != http.StatusOK {
-		l.logger.Error("API error", "provider", l.Provider.Name(), "status", resp.StatusCode, "body", string(body))
-		return "", NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", resp.StatusCode), nil)
+		return "", l.apiError(resp.StatusCode, body)
 	}
 
 	// Extract and log caching information
@@ -273,6 +420,7 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string,
 	}
 
 	// Process usage information regardless of format
+	usage.record(fullResponse)
 	if usage, ok := fullResponse["usage"].(map[string]interface{}); ok {
 		l.logger.Debug("Usage information", "usage", usage)
 		cacheInfo := map[string]interface{}{
@@ -288,10 +436,40 @@ func (l *LLMImpl) attemptGenerate(ctx context.Context, prompt *Prompt) (string,
 	if err != nil {
 		return "", NewLLMError(ErrorTypeResponse, "failed to parse response", err)
 	}
+	l.recordLogprobs(body, options)
+	_, prefilled := options["response_prefix"]
+	result = responseAfterPrefix(result, prompt.ResponsePrefix, prefilled)
 	l.logger.Debug("Text generated successfully", "result", result)
 	return result, nil
 }
 
+// responseAfterPrefix returns the text of a response after its prefix, so
+// that responses are the same whether the provider was prefilled with the
+// prefix or asked to start with it. A provider that was asked may echo the
+// prefix, which is removed. A prefilled provider continues the prefix without
+// its trailing whitespace, so leading whitespace is removed when the prefix
+// ends with some.
+func responseAfterPrefix(response, prefix string, prefilled bool) string {
+	trimmedPrefix := strings.TrimRightFunc(prefix, unicode.IsSpace)
+	if prefilled {
+		if trimmedPrefix != prefix {
+			return strings.TrimLeftFunc(response, unicode.IsSpace)
+		}
+		return response
+	}
+	if prefix == "" {
+		return response
+	}
+	trimmed := strings.TrimLeftFunc(response, unicode.IsSpace)
+	if rest, ok := strings.CutPrefix(trimmed, prefix); ok {
+		return rest
+	}
+	if rest, ok := strings.CutPrefix(trimmed, trimmedPrefix); ok && trimmedPrefix != "" {
+		return strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+	return response
+}
+
 // GenerateWithSchema generates text that conforms to a specific JSON schema.
 // It handles retries, logging, and error management.
 //
@@ -305,31 +483,52 @@ func (l *LLMImpl) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema
 		opt(config)
 	}
 
+	prompt, persona, overrides, err := l.prepareCall(ctx, prompt, config, true)
+	if err != nil {
+		return "", err
+	}
+
 	var result string
 	var lastErr error
 
-	for attempt := 0; attempt <= l.MaxRetries; attempt++ {
-		l.logger.Debug("Generating text with schema", "provider", l.Provider.Name(), "prompt", prompt.String(), "attempt", attempt+1)
+	ctx, trace := l.startCall(ctx, "generate_with_schema", overrides)
+	strategy := l.retryStrategy()
+	for attempt := 1; ; attempt++ {
+		trace.attempts = attempt
+		l.logger.Debug("Generating text with schema", "provider", l.Provider.Name(), "prompt", prompt.String(), "attempt", attempt)
 
-		result, _, lastErr = l.attemptGenerateWithSchema(ctx, prompt.String(), schema)
+		result, _, lastErr = l.attemptGenerateWithSchema(ctx, prompt, schema, overrides, &trace.usage)
 		if lastErr == nil {
+			result, lastErr = l.guardPersona(result, persona, func(rewrite *Prompt) (string, error) {
+				rewritten, _, err := l.attemptGenerateWithSchema(ctx, rewrite, schema, overrides, &trace.usage)
+				return rewritten, err
+			})
+			trace.end(lastErr)
+			if lastErr != nil {
+				return "", lastErr
+			}
 			return result, nil
 		}
 
-		l.logger.Warn("Generation attempt with schema failed", "error", lastErr, "attempt", attempt+1)
+		l.logger.Warn("Generation attempt with schema failed", "error", lastErr, "attempt", attempt)
 
-		if attempt < l.MaxRetries {
-			l.logger.Debug("Retrying", "delay", l.RetryDelay)
-			select {
-			case <-ctx.Done():
-				return "", ctx.Err()
-			case <-time.After(l.RetryDelay):
-				// Continue to next attempt
-			}
+		if !isRetryable(lastErr) {
+			break
+		}
+		delay, retry := strategy.NextDelay(attempt, lastErr)
+		if !retry {
+			break
+		}
+		l.logger.Debug("Retrying", "delay", delay)
+		if err := l.wait(ctx, delay); err != nil {
+			trace.end(err)
+			return "", err
 		}
 	}
 
-	return "", fmt.Errorf("failed to generate with schema after %d attempts: %w", l.MaxRetries+1, lastErr)
+	err = fmt.Errorf("failed to generate with schema after %d attempts: %w", trace.attempts, lastErr)
+	trace.end(err)
+	return "", err
 }
 
 // attemptGenerateWithSchema makes a single attempt to generate text using the provider and a JSON schema.
@@ -340,17 +539,19 @@ func (l *LLMImpl) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema
 //   - Full prompt used for generation
 //   - ErrorTypeInvalidInput for schema validation failures
 //   - Other error types as per attemptGenerate
-func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt string, schema interface{}) (string, string, error) {
+func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt *Prompt, schema interface{}, overrides map[string]interface{}, usage *tokenUsage) (string, string, error) {
 	var reqBody []byte
 	var err error
 	var fullPrompt string
 
+	options := mergeOptions(l.Options, overrides)
+	text := l.providerPrompt(prompt, options)
 	if l.SupportsJSONSchema() {
-		reqBody, err = l.Provider.PrepareRequestWithSchema(prompt, l.Options, schema)
-		fullPrompt = prompt
+		reqBody, err = l.Provider.PrepareRequestWithSchema(text, options, schema)
+		fullPrompt = text
 	} else {
-		fullPrompt = l.preparePromptWithSchema(prompt, schema)
-		reqBody, err = l.Provider.PrepareRequest(fullPrompt, l.Options)
+		fullPrompt = l.preparePromptWithSchema(text, schema)
+		reqBody, err = l.Provider.PrepareRequest(fullPrompt, options)
 	}
 
 	if err != nil {
@@ -380,8 +581,12 @@ func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt string,
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		l.logger.Error("API error", "provider", l.Provider.Name(), "status", resp.StatusCode, "body", string(body))
-		return "", fullPrompt, NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", resp.StatusCode), nil)
+		return "", fullPrompt, l.apiError(resp.StatusCode, body)
+	}
+
+	var fullResponse map[string]interface{}
+	if err := json.Unmarshal(body, &fullResponse); err == nil {
+		usage.record(fullResponse)
 	}
 
 	result, err := l.Provider.ParseResponse(body)
@@ -393,11 +598,58 @@ func (l *LLMImpl) attemptGenerateWithSchema(ctx context.Context, prompt string,
 	if err := ValidateAgainstSchema(result, schema); err != nil {
 		return "", fullPrompt, NewLLMError(ErrorTypeResponse, "response does not match schema", err)
 	}
+	l.recordLogprobs(body, options)
 
 	l.logger.Debug("Text generated successfully", "result", result)
 	return result, fullPrompt, nil
 }
 
+// promptCacher is implemented by providers that cache the prefix passed as
+// the "cached_prefix" option.
+type promptCacher interface {
+	SupportsPromptCaching() bool
+}
+
+// responsePrefiller is implemented by providers that start the response with
+// the text passed as the "response_prefix" option.
+type responsePrefiller interface {
+	SupportsResponsePrefill() bool
+}
+
+// providerPrompt returns the prompt text to send to the provider. A system
+// prompt is passed to providers with system messages as the "system_prompt"
+// option, so it is sent as a separate system message. For other providers it
+// is prepended to the text between delimiters. Likewise, a cached prefix is
+// passed to providers with prompt caching as the "cached_prefix" option and
+// is part of the text otherwise, and so is a response prefix for providers
+// that cannot prefill the response with the "response_prefix" option. Images
+// are passed as the "images" option.
+func (l *LLMImpl) providerPrompt(prompt *Prompt, options map[string]interface{}) string {
+	if cacher, ok := l.Provider.(promptCacher); ok && prompt.CachedPrefix != "" && cacher.SupportsPromptCaching() {
+		options["cached_prefix"] = prompt.CachedPrefix
+		withoutPrefix := *prompt
+		withoutPrefix.CachedPrefix = ""
+		prompt = &withoutPrefix
+	}
+	if prefiller, ok := l.Provider.(responsePrefiller); ok && prompt.ResponsePrefix != "" && prefiller.SupportsResponsePrefill() {
+		options["response_prefix"] = prompt.ResponsePrefix
+		withoutPrefix := *prompt
+		withoutPrefix.ResponsePrefix = ""
+		prompt = &withoutPrefix
+	}
+	if len(prompt.Images) > 0 {
+		options["images"] = prompt.Images
+	}
+	if prompt.SystemPrompt == "" {
+		return prompt.String()
+	}
+	if l.Provider.SupportsSystemPrompt() {
+		options["system_prompt"] = prompt.SystemPrompt
+		return prompt.userString()
+	}
+	return fmt.Sprintf("<system>\n%s\n</system>\n\n%s", prompt.SystemPrompt, prompt.userString())
+}
+
 // preparePromptWithSchema prepares a prompt with a JSON schema for providers that do not support JSON schema validation.
 // Returns the original prompt if schema marshaling fails (with a warning log).
 func (l *LLMImpl) preparePromptWithSchema(prompt string, schema interface{}) string {
@@ -410,7 +662,17 @@ func (l *LLMImpl) preparePromptWithSchema(prompt string, schema interface{}) str
 	return fmt.Sprintf("%s\n\nPlease provide your response in JSON format according to this schema:\n%s", prompt, string(schemaJSON))
 }
 
-// Stream initiates a streaming response from the LLM.
+// Stream initiates a streaming response from the LLM. The prompt is prepared
+// as for Generate: the client's persona instructions, the prompt's stop
+// sequences and sampling settings, and the options passed with
+// WithStreamGenerateOptions apply. Streamed responses are not checked for the
+// persona's forbidden phrases, since tokens are delivered as they arrive.
+//
+// Returns:
+//   - The token stream
+//   - ErrorTypeUnsupported if the provider doesn't support streaming
+//   - ErrorTypeInvalidInput for structured output options, which cannot be streamed
+//   - Errors found while preparing the prompt, as for Generate
 func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOption) (TokenStream, error) {
 	if !l.SupportsStreaming() {
 		return nil, NewLLMError(ErrorTypeUnsupported, "streaming not supported by provider", nil)
@@ -419,24 +681,53 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 	// Apply stream options
 	config := &StreamConfig{
 		BufferSize: 100,
-		RetryStrategy: &DefaultRetryStrategy{
-			MaxRetries:  l.MaxRetries,
-			InitialWait: l.RetryDelay,
-			MaxWait:     l.RetryDelay * 10,
+		RetryStrategy: utils.ExponentialBackoff{
+			MaxRetries:   l.MaxRetries,
+			InitialDelay: l.RetryDelay,
+			MaxDelay:     l.RetryDelay * 10,
 		},
 	}
+	if l.config.RetryStrategy != nil {
+		config.RetryStrategy = l.config.RetryStrategy
+	}
 	for _, opt := range opts {
 		opt(config)
 	}
+	generateConfig := &GenerateConfig{}
+	for _, opt := range config.GenerateOptions {
+		opt(generateConfig)
+	}
+	if generateConfig.UseJSONSchema || generateConfig.StructuredOutput != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "structured output is not supported when streaming", nil)
+	}
+	if prompt.Logprobs != nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "logprobs are not supported when streaming", nil)
+	}
+	prompt, _, overrides, err := l.prepareCall(ctx, prompt, generateConfig, false)
+	if err != nil {
+		return nil, err
+	}
 
 	// Prepare request with streaming enabled
-	options := make(map[string]interface{})
-	for k, v := range l.Options {
-		options[k] = v
-	}
+	options := mergeOptions(l.Options, overrides)
 	options["stream"] = true
 
-	body, err := l.Provider.PrepareStreamRequest(prompt.String(), options)
+	ctx, trace := l.startCall(ctx, "stream", overrides)
+	trace.attempts = 1
+	stream, err := l.openStream(ctx, prompt, options, config)
+	if err != nil {
+		trace.end(err)
+		return nil, err
+	}
+	if !trace.active() {
+		return stream, nil
+	}
+	return &tracedStream{TokenStream: stream, trace: trace}, nil
+}
+
+// openStream sends the streaming request and wraps the response body in a TokenStream.
+func (l *LLMImpl) openStream(ctx context.Context, prompt *Prompt, options map[string]interface{}, config *StreamConfig) (TokenStream, error) {
+	body, err := l.Provider.PrepareStreamRequest(l.providerPrompt(prompt, options), options)
 	if err != nil {
 		return nil, NewLLMError(ErrorTypeRequest, "failed to prepare stream request", err)
 	}
@@ -459,8 +750,9 @@ func (l *LLMImpl) Stream(ctx context.Context, prompt *Prompt, opts ...StreamOpti
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, NewLLMError(ErrorTypeAPI, fmt.Sprintf("API error: status code %d", resp.StatusCode), nil)
+		return nil, l.apiError(resp.StatusCode, body)
 	}
 
 	// Create and return stream
@@ -480,6 +772,7 @@ type providerStream struct {
 	buffer        []byte
 	currentIndex  int
 	retryStrategy RetryStrategy
+	retries       int
 }
 
 func newProviderStream(reader io.ReadCloser, provider providers.Provider, config *StreamConfig) *providerStream {
@@ -501,8 +794,9 @@ func (s *providerStream) Next(ctx context.Context) (*StreamToken, error) {
 		default:
 			if !s.decoder.Next() {
 				if err := s.decoder.Err(); err != nil {
-					if s.retryStrategy.ShouldRetry(err) {
-						time.Sleep(s.retryStrategy.NextDelay())
+					s.retries++
+					if delay, retry := s.retryStrategy.NextDelay(s.retries, err); retry {
+						time.Sleep(delay)
 						continue
 					}
 					return nil, err
@@ -0,0 +1,97 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// logitBiaser is implemented by providers that accept the "logit_bias" option,
+// a map from token ID to bias.
+type logitBiaser interface {
+	SupportsLogitBias() bool
+}
+
+// WithLogitBias biases the likelihood of tokens for a single Generate call.
+// Keys are token strings, such as " yes", and must each encode to exactly one
+// token of the model's tokenizer; values range from -100, which effectively
+// bans the token, to 100, which effectively forces it. The client converts the
+// strings to the model's token IDs. Providers without logit bias (Anthropic,
+// Gemini, ...) ignore it with a debug log.
+//
+// Example:
+//
+//	response, err := llm.Generate(ctx, prompt, WithLogitBias(map[string]float64{"抱歉": -100}))
+func WithLogitBias(bias map[string]float64) GenerateOption {
+	return func(c *GenerateConfig) {
+		if c.LogitBias == nil {
+			c.LogitBias = make(map[string]float64, len(bias))
+		}
+		for token, value := range bias {
+			c.LogitBias[token] = value
+		}
+	}
+}
+
+// WithPromptLogitBias biases the likelihood of tokens for every call made
+// with the prompt, as WithLogitBias does for a single call. A call's
+// WithLogitBias takes precedence for the tokens it sets; the prompt's biases
+// of other tokens still apply.
+//
+// Example:
+//
+//	prompt := NewPrompt("这条评论是正面的吗？只回答是或否", WithPromptLogitBias(map[string]float64{"是": 5, "否": 5}))
+func WithPromptLogitBias(bias map[string]float64) PromptOption {
+	return func(p *Prompt) {
+		if p.LogitBias == nil {
+			p.LogitBias = make(map[string]float64, len(bias))
+		}
+		for token, value := range bias {
+			p.LogitBias[token] = value
+		}
+	}
+}
+
+// applyLogitBias adds the call's logit bias to options as "logit_bias", with
+// token strings converted to the token IDs of the call's model.
+//
+// Returns:
+//   - ErrorTypeInvalidInput if a bias is out of range, or a token string is
+//     not exactly one token
+func (l *LLMImpl) applyLogitBias(config *GenerateConfig, options map[string]interface{}) error {
+	if len(config.LogitBias) == 0 {
+		return nil
+	}
+	tokens := make([]string, 0, len(config.LogitBias))
+	for token, value := range config.LogitBias {
+		if value < -100 || value > 100 {
+			return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("logit bias %v for %q is outside [-100, 100]", value, token), nil)
+		}
+		tokens = append(tokens, token)
+	}
+	if biaser, ok := l.Provider.(logitBiaser); !ok || !biaser.SupportsLogitBias() {
+		l.logger.Debug("Logit bias not supported by provider, ignoring it", "provider", l.Provider.Name())
+		return nil
+	}
+
+	model := l.config.Model
+	if config.Model != "" {
+		model = config.Model
+	}
+	encoding, err := encodingForModel(model)
+	if err != nil {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("failed to load tokenizer for logit bias of model %s", model), err)
+	}
+	sort.Strings(tokens)
+	bias := make(map[string]float64, len(tokens))
+	for _, token := range tokens {
+		ids := encoding.Encode(token, nil, nil)
+		if len(ids) != 1 {
+			return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("logit bias key %q is %d tokens for model %s, expected exactly one", token, len(ids), model), nil)
+		}
+		bias[strconv.Itoa(ids[0])] = config.LogitBias[token]
+	}
+	options["logit_bias"] = bias
+	return nil
+}
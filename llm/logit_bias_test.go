@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestWithLogitBias(t *testing.T) {
+	useRuneTokenizer(t)
+	l, lastRequest := newCapturingLLM(t)
+	ctx := context.Background()
+
+	_, err := l.Generate(ctx, NewPrompt("你好"), WithLogitBias(map[string]float64{"是": 5, "否": -100}))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"26159": float64(5), "21542": float64(-100)}, lastRequest()["logit_bias"])
+
+	// The test server's "ok" does not match the schema; only the request matters.
+	l.GenerateWithSchema(ctx, NewPrompt("你好"), map[string]interface{}{"type": "object"}, WithLogitBias(map[string]float64{"是": 1}))
+	assert.Equal(t, map[string]interface{}{"26159": float64(1)}, lastRequest()["logit_bias"])
+
+	_, err = l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	assert.NotContains(t, lastRequest(), "logit_bias")
+
+	var llmErr *LLMError
+	_, err = l.Generate(ctx, NewPrompt("你好"), WithLogitBias(map[string]float64{"是": 101}))
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	_, err = l.Generate(ctx, NewPrompt("你好"), WithLogitBias(map[string]float64{"是的": 5}))
+	require.ErrorAs(t, err, &llmErr)
+	assert.Contains(t, err.Error(), "expected exactly one")
+}
+
+func TestWithPromptLogitBias(t *testing.T) {
+	useRuneTokenizer(t)
+	l, lastRequest := newCapturingLLM(t)
+	ctx := context.Background()
+	prompt := NewPrompt("你好", WithPromptLogitBias(map[string]float64{"是": 5, "否": 5}))
+
+	_, err := l.Generate(ctx, prompt)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"26159": float64(5), "21542": float64(5)}, lastRequest()["logit_bias"])
+
+	_, err = l.Generate(ctx, prompt, WithLogitBias(map[string]float64{"否": -100}))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"26159": float64(5), "21542": float64(-100)}, lastRequest()["logit_bias"], "call wins per token")
+	assert.Equal(t, 5.0, prompt.LogitBias["否"], "prompt unchanged by the call")
+}
+
+func TestWithLogitBias_Unsupported(t *testing.T) {
+	var last map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		last = nil
+		require.NoError(t, json.Unmarshal(body, &last))
+		w.Write([]byte(`{"content":[{"type":"text","text":"ok"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("anthropic"),
+		config.SetModel("claude-3-5-haiku-latest"),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetMaxRetries(0),
+		config.SetTimeout(5*time.Second),
+	)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+
+	_, err = l.Generate(context.Background(), NewPrompt("你好"), WithLogitBias(map[string]float64{"是的": 5}))
+	require.NoError(t, err, "not tokenized for providers that ignore it")
+	assert.NotContains(t, last, "logit_bias")
+}
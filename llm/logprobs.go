@@ -0,0 +1,106 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yockii/gollm_cn/providers"
+)
+
+// TokenLogprob is the log probability of a token of a response, with the most
+// likely alternatives at its position (see WithLogprobs).
+type TokenLogprob = providers.TokenLogprob
+
+// ErrProviderDoesNotSupportLogprobs is returned when a prompt asking for
+// logprobs is sent to a provider that cannot return them.
+var ErrProviderDoesNotSupportLogprobs = errors.New("provider does not support logprobs")
+
+// LogprobsReporter is implemented by LLMs that keep the log probabilities of
+// their last response. It is not part of the LLM interface; check for it with
+// a type assertion.
+type LogprobsReporter interface {
+	// GetLastLogprobs returns the log probabilities of the tokens of the last
+	// response, or nil if it was not generated with WithLogprobs.
+	GetLastLogprobs() []TokenLogprob
+}
+
+// WithLogprobs asks for the log probabilities of the response's tokens, each
+// with the topK most likely alternatives at its position (0 for none), which
+// GetLastLogprobs returns after the call. The probability of a token shows
+// how confident the model was, for instance to route classifications whose
+// label has a low probability to a human. Providers with logprobs (OpenAI)
+// accept up to 20 alternatives; other providers fail with
+// ErrProviderDoesNotSupportLogprobs. Logprobs are not available when
+// streaming.
+//
+// Example:
+//
+//	prompt := NewPrompt("这条评论是正面还是负面？只回答正面或负面：\n"+review, WithLogprobs(3))
+//	label, err := llm.Generate(ctx, prompt)
+//	logprobs := llm.(LogprobsReporter).GetLastLogprobs()
+//	if len(logprobs) > 0 && logprobs[0].Probability() < 0.9 {
+//	    sendToReview(review, label)
+//	}
+func WithLogprobs(topK int) PromptOption {
+	return func(p *Prompt) {
+		p.Logprobs = &topK
+	}
+}
+
+// GetLastLogprobs returns the log probabilities of the tokens of the last
+// response, or nil if it was not generated with WithLogprobs. Calls made
+// concurrently on the same LLM overwrite each other's; use an LLM per
+// goroutine when they matter.
+func (l *LLMImpl) GetLastLogprobs() []TokenLogprob {
+	l.logprobsMutex.Lock()
+	defer l.logprobsMutex.Unlock()
+	return l.lastLogprobs
+}
+
+// lastLogprobs calls GetLastLogprobs on l if it is a LogprobsReporter, for
+// LLMs wrapping another.
+func lastLogprobs(l LLM) []TokenLogprob {
+	if reporter, ok := l.(LogprobsReporter); ok {
+		return reporter.GetLastLogprobs()
+	}
+	return nil
+}
+
+// applyLogprobs sets the "logprobs" and "top_logprobs" options if the prompt
+// asks for logprobs. It returns ErrProviderDoesNotSupportLogprobs, as an
+// ErrorTypeUnsupported LLMError, if the provider cannot return them.
+func (l *LLMImpl) applyLogprobs(prompt *Prompt, options map[string]interface{}) error {
+	if prompt.Logprobs == nil {
+		return nil
+	}
+	provider, ok := l.Provider.(providers.LogprobsProvider)
+	if !ok || !provider.SupportsLogprobs() {
+		return NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("provider %s cannot return logprobs", l.Provider.Name()), ErrProviderDoesNotSupportLogprobs)
+	}
+	topK := *prompt.Logprobs
+	if topK < 0 || topK > provider.MaxTopLogprobs() {
+		return NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("logprobs alternatives %d are not in [0, %d]", topK, provider.MaxTopLogprobs()), nil)
+	}
+	options["logprobs"] = true
+	if topK > 0 {
+		options["top_logprobs"] = topK
+	}
+	return nil
+}
+
+// recordLogprobs keeps the log probabilities of a response for
+// GetLastLogprobs, or clears them if the request did not ask for them.
+func (l *LLMImpl) recordLogprobs(body []byte, options map[string]interface{}) {
+	var logprobs []TokenLogprob
+	provider, ok := l.Provider.(providers.LogprobsProvider)
+	if requested, _ := options["logprobs"].(bool); ok && requested {
+		var err error
+		if logprobs, err = provider.ParseLogprobs(body); err != nil {
+			l.logger.Warn("Failed to parse logprobs", "error", err)
+		}
+	}
+	l.logprobsMutex.Lock()
+	defer l.logprobsMutex.Unlock()
+	l.lastLogprobs = logprobs
+}
@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestWithLogprobs(t *testing.T) {
+	l, lastRequest := newRespondingLLM(t, "openai", "gpt-4o-mini", `{"choices": [{"message": {"content": "正面"}, "logprobs": {"content": [
+		{"token": "正", "logprob": -0.05, "bytes": [230, 173, 163], "top_logprobs": [{"token": "正", "logprob": -0.05}, {"token": "负", "logprob": -3.2}]},
+		{"token": "面", "logprob": 0, "top_logprobs": [{"token": "面", "logprob": 0}]}
+	]}}]}`)
+	ctx := context.Background()
+
+	response, err := l.Generate(ctx, NewPrompt("这条评论是正面还是负面？", WithLogprobs(2)))
+	require.NoError(t, err)
+	assert.Equal(t, "正面", response)
+	assert.Equal(t, true, lastRequest()["logprobs"])
+	assert.Equal(t, float64(2), lastRequest()["top_logprobs"])
+
+	logprobs := l.(LogprobsReporter).GetLastLogprobs()
+	require.Len(t, logprobs, 2)
+	assert.Equal(t, TokenLogprob{Token: "正", Logprob: -0.05, TopLogprobs: []TokenLogprob{
+		{Token: "正", Logprob: -0.05},
+		{Token: "负", Logprob: -3.2},
+	}}, logprobs[0])
+	assert.InDelta(t, 0.951, logprobs[0].Probability(), 0.001)
+	assert.Equal(t, 1.0, logprobs[1].Probability())
+
+	_, err = l.Generate(ctx, NewPrompt("这条评论是正面还是负面？", WithLogprobs(0)))
+	require.NoError(t, err)
+	assert.Equal(t, true, lastRequest()["logprobs"])
+	assert.NotContains(t, lastRequest(), "top_logprobs")
+
+	_, err = l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	assert.NotContains(t, lastRequest(), "logprobs")
+	assert.Nil(t, l.(LogprobsReporter).GetLastLogprobs(), "cleared by a call without logprobs")
+
+	var llmErr *LLMError
+	_, err = l.Generate(ctx, NewPrompt("你好", WithLogprobs(21)))
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	_, err = l.Stream(ctx, NewPrompt("你好", WithLogprobs(1)))
+	assert.ErrorContains(t, err, "logprobs are not supported when streaming")
+
+	memory, err := NewLLMWithMemory(l, 1000, "gpt-4o-mini", utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+	_, err = memory.Generate(ctx, NewPrompt("这条评论是正面还是负面？", WithLogprobs(2)))
+	require.NoError(t, err)
+	assert.Len(t, memory.GetLastLogprobs(), 2)
+}
+
+func TestWithLogprobs_Unsupported(t *testing.T) {
+	l, _ := newRespondingLLM(t, "anthropic", "claude-3-5-haiku-latest", `{"content":[{"type":"text","text":"正面"}]}`)
+
+	_, err := l.Generate(context.Background(), NewPrompt("这条评论是正面还是负面？", WithLogprobs(2)))
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+	assert.ErrorIs(t, err, ErrProviderDoesNotSupportLogprobs)
+	assert.Nil(t, l.(LogprobsReporter).GetLastLogprobs())
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"sync"
 
-	"github.com/pkoukk/tiktoken-go"
 	"github.com/yockii/gollm_cn/utils"
 )
 
@@ -14,21 +13,22 @@ import (
 // It includes the role of the speaker, the content of the message,
 // and the number of tokens in the message for efficient memory management.
 type MemoryMessage struct {
-	Role    string // Role of the message sender (e.g., "user", "assistant")
-	Content string // The actual message content
-	Tokens  int    // Number of tokens in the message
+	Role        string // Role of the message sender (e.g., "user", "assistant")
+	Content     string // The actual message content
+	Tokens      int    // Number of tokens in the message
+	SummaryTurn bool   // Whether the message summarizes earlier turns, see ConversationSummarizer
 }
 
 // Memory manages conversation history with token-based truncation.
 // It provides thread-safe operations for adding, retrieving, and managing messages
 // while ensuring the total token count stays within specified limits.
 type Memory struct {
-	messages    []MemoryMessage    // Ordered list of conversation messages
-	mutex       sync.Mutex         // Ensures thread-safe operations
-	totalTokens int                // Current total token count
-	maxTokens   int                // Maximum allowed tokens
-	encoding    *tiktoken.Tiktoken // Token encoder for the model
-	logger      utils.Logger       // Logger for debugging and monitoring
+	messages    []MemoryMessage // Ordered list of conversation messages
+	mutex       sync.Mutex      // Ensures thread-safe operations
+	totalTokens int             // Current total token count
+	maxTokens   int             // Maximum allowed tokens
+	encoding    tokenizer       // Token encoder for the model
+	logger      utils.Logger    // Logger for debugging and monitoring
 }
 
 // NewMemory creates a new Memory instance with the specified token limit and model.
@@ -43,10 +43,17 @@ type Memory struct {
 //   - Initialized Memory instance
 //   - ErrorTypeProvider if token encoding initialization fails
 func NewMemory(maxTokens int, model string, logger utils.Logger) (*Memory, error) {
-	encoding, err := tiktoken.EncodingForModel(model)
+	return newMemory(maxTokens, model, logger, NewFallbackPolicy(false, logger))
+}
+
+// newMemory creates a Memory whose tokenizer fallback is governed by policy.
+func newMemory(maxTokens int, model string, logger utils.Logger, policy *FallbackPolicy) (*Memory, error) {
+	encoding, err := encodingForModel(model)
 	if err != nil {
-		logger.Warn("Failed to get encoding for model, defaulting to gpt-4o", "model", model, "error", err)
-		encoding, err = tiktoken.EncodingForModel("gpt-4o")
+		if err := policy.Allow(InterventionTokenizerFallback, fmt.Sprintf("no tokenizer for model %q, using gpt-4o", model)); err != nil {
+			return nil, err
+		}
+		encoding, err = encodingForModel("gpt-4o")
 		if err != nil {
 			return nil, fmt.Errorf("failed to get default encoding: %v", err)
 		}
@@ -92,7 +99,8 @@ func (m *Memory) truncate() {
 }
 
 // GetPrompt returns the entire conversation history as a formatted string.
-// Each message is formatted as "role: content\n".
+// Each message is formatted as "role: content\n"; summary turns use the role
+// "summary of earlier conversation".
 // This operation is thread-safe.
 //
 // Returns:
@@ -103,7 +111,11 @@ func (m *Memory) GetPrompt() string {
 
 	var prompt string
 	for _, msg := range m.messages {
-		prompt += fmt.Sprintf("%s: %s\n", msg.Role, msg.Content)
+		role := msg.Role
+		if msg.SummaryTurn {
+			role = "summary of earlier conversation"
+		}
+		prompt += fmt.Sprintf("%s: %s\n", role, msg.Content)
 	}
 	return prompt
 }
@@ -131,11 +143,27 @@ func (m *Memory) GetMessages() []MemoryMessage {
 	return append([]MemoryMessage(nil), m.messages...)
 }
 
+// SetMessages replaces the messages in memory, such as with a copy returned
+// by GetMessages to undo a turn, truncating them to the token limit.
+// This operation is thread-safe.
+func (m *Memory) SetMessages(messages []MemoryMessage) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.messages = append([]MemoryMessage(nil), messages...)
+	m.totalTokens = 0
+	for _, msg := range m.messages {
+		m.totalTokens += msg.Tokens
+	}
+	m.truncate()
+}
+
 // LLMWithMemory wraps an LLM instance with conversation memory capabilities.
 // It maintains conversation history and provides context for each generation.
 type LLMWithMemory struct {
-	LLM            // Underlying LLM instance
-	memory *Memory // Conversation memory manager
+	LLM                                // Underlying LLM instance
+	memory     *Memory                 // Conversation memory manager
+	summarizer *ConversationSummarizer // Condenses old turns, if set
 }
 
 // NewLLMWithMemory creates a new LLM instance with conversation memory.
@@ -150,7 +178,7 @@ type LLMWithMemory struct {
 //   - LLM instance with memory capabilities
 //   - ErrorTypeProvider if memory initialization fails
 func NewLLMWithMemory(baseLLM LLM, maxTokens int, model string, logger utils.Logger) (*LLMWithMemory, error) {
-	memory, err := NewMemory(maxTokens, model, logger)
+	memory, err := newMemory(maxTokens, model, logger, baseLLM.FallbackPolicy(context.Background()))
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +205,21 @@ func (l *LLMWithMemory) Generate(ctx context.Context, prompt *Prompt, opts ...Ge
 
 	// Create a new Prompt with the full memory context
 	memoryPrompt := &Prompt{
-		Input: fullPrompt,
+		Input:             fullPrompt,
+		SystemPrompt:      prompt.SystemPrompt,
+		CachedPrefix:      prompt.CachedPrefix,
+		ResponsePrefix:    prompt.ResponsePrefix,
+		RetrievedContext:  prompt.RetrievedContext,
+		Images:            prompt.Images,
+		StopSequences:     prompt.StopSequences,
+		TopP:              prompt.TopP,
+		TopK:              prompt.TopK,
+		FrequencyPenalty:  prompt.FrequencyPenalty,
+		PresencePenalty:   prompt.PresencePenalty,
+		RepetitionPenalty: prompt.RepetitionPenalty,
+		Seed:              prompt.Seed,
+		LogitBias:         prompt.LogitBias,
+		Logprobs:          prompt.Logprobs,
 		// Copy other fields from the original prompt if needed
 	}
 
@@ -186,15 +228,61 @@ func (l *LLMWithMemory) Generate(ctx context.Context, prompt *Prompt, opts ...Ge
 		return "", err
 	}
 
-	l.memory.Add("assistant", response)
+	l.memory.Add("assistant", prompt.ResponsePrefix+response)
+	l.summarize(ctx)
 	return response, nil
 }
 
+// SetSummarizer makes the LLM condense the oldest turns of its memory with s
+// after each response, once the memory holds more turns than s allows. A
+// failed summary is logged and the turns are kept. Pass nil to stop
+// summarizing.
+//
+// Example:
+//
+//	chat.SetSummarizer(llm.NewConversationSummarizer(base, 20))
+func (l *LLMWithMemory) SetSummarizer(s *ConversationSummarizer) {
+	l.summarizer = s
+}
+
+// summarize runs the summarizer, if any, on the memory.
+func (l *LLMWithMemory) summarize(ctx context.Context) {
+	if l.summarizer == nil {
+		return
+	}
+	if _, err := l.summarizer.Summarize(ctx, l.memory); err != nil {
+		l.memory.logger.Warn("Conversation not summarized, keeping all turns", "error", err)
+	}
+}
+
 // ClearMemory removes all messages from the conversation history.
 func (l *LLMWithMemory) ClearMemory() {
 	l.memory.Clear()
 }
 
+// Embed forwards to the wrapped LLM if it is an Embedder. Embeddings are not
+// added to the conversation history.
+func (l *LLMWithMemory) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	return embed(ctx, l.LLM, inputs)
+}
+
+// Moderate forwards to the wrapped LLM if it is a Moderator. The text is not
+// added to the conversation history.
+func (l *LLMWithMemory) Moderate(ctx context.Context, text string) (*ModerationResult, error) {
+	return moderate(ctx, l.LLM, text)
+}
+
+// Rerank forwards to the wrapped LLM if it is a Reranker. The query and documents are not
+// added to the conversation history.
+func (l *LLMWithMemory) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RankedDocument, error) {
+	return rerank(ctx, l.LLM, query, documents, topN)
+}
+
+// GetLastLogprobs forwards to the wrapped LLM if it is a LogprobsReporter.
+func (l *LLMWithMemory) GetLastLogprobs() []TokenLogprob {
+	return lastLogprobs(l.LLM)
+}
+
 // GetMemory returns a copy of all messages in the conversation history.
 //
 // Returns:
@@ -220,7 +308,21 @@ func (l *LLMWithMemory) GenerateWithSchema(ctx context.Context, prompt *Prompt,
 	fullPrompt := l.memory.GetPrompt()
 
 	memoryPrompt := &Prompt{
-		Input: fullPrompt,
+		Input:             fullPrompt,
+		SystemPrompt:      prompt.SystemPrompt,
+		CachedPrefix:      prompt.CachedPrefix,
+		ResponsePrefix:    prompt.ResponsePrefix,
+		RetrievedContext:  prompt.RetrievedContext,
+		Images:            prompt.Images,
+		StopSequences:     prompt.StopSequences,
+		TopP:              prompt.TopP,
+		TopK:              prompt.TopK,
+		FrequencyPenalty:  prompt.FrequencyPenalty,
+		PresencePenalty:   prompt.PresencePenalty,
+		RepetitionPenalty: prompt.RepetitionPenalty,
+		Seed:              prompt.Seed,
+		LogitBias:         prompt.LogitBias,
+		Logprobs:          prompt.Logprobs,
 		// Copy other fields from the original prompt if needed
 	}
 
@@ -229,6 +331,7 @@ func (l *LLMWithMemory) GenerateWithSchema(ctx context.Context, prompt *Prompt,
 		return "", err
 	}
 
-	l.memory.Add("assistant", response)
+	l.memory.Add("assistant", prompt.ResponsePrefix+response)
+	l.summarize(ctx)
 	return response, nil
 }
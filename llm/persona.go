@@ -0,0 +1,71 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yockii/gollm_cn/persona"
+)
+
+// activePersona returns the client's persona adapted to the call's language,
+// or nil when the client has none or the call leaves it out.
+func (l *LLMImpl) activePersona(config *GenerateConfig) *persona.Persona {
+	if l.config.Persona == nil || config.WithoutPersona {
+		return nil
+	}
+	p := l.config.Persona.ForLanguage(config.PersonaLanguage)
+	if p.IsZero() {
+		return nil
+	}
+	return &p
+}
+
+// withPersona returns a copy of prompt whose system prompt starts with the
+// persona's instructions, followed by the prompt's own system prompt or else
+// the client's. Keeping the persona first gives every call the same system
+// prompt prefix, which providers with prompt caching reuse.
+func (l *LLMImpl) withPersona(prompt *Prompt, p *persona.Persona) *Prompt {
+	if p == nil {
+		return prompt
+	}
+	system := prompt.SystemPrompt
+	if system == "" {
+		system, _ = l.Options["system_prompt"].(string)
+	}
+	withPersona := *prompt
+	withPersona.SystemPrompt = p.SystemPrompt()
+	if system != "" {
+		withPersona.SystemPrompt += "\n\n" + system
+	}
+	return &withPersona
+}
+
+// guardPersona checks a response for the persona's forbidden phrases. A
+// response that uses one is rewritten once with attempt; the rewrite must not
+// use any.
+//
+// Returns:
+//   - The response, or its rewrite
+//   - ErrorTypeResponse if the rewrite still uses a forbidden phrase
+func (l *LLMImpl) guardPersona(response string, p *persona.Persona, attempt func(*Prompt) (string, error)) (string, error) {
+	if p == nil {
+		return response, nil
+	}
+	violations := p.Violations(response)
+	if len(violations) == 0 {
+		return response, nil
+	}
+	l.logger.Warn("Response uses forbidden persona phrases, rewriting", "phrases", violations)
+
+	rewrite := NewPrompt(fmt.Sprintf("改写以下回复，去掉其中的这些措辞：%s\n\n回复:\n%s", strings.Join(violations, "、"), response))
+	rewrite.Apply(WithOutput("仅输出改写后的回复，保持原有的含义、语言和格式（例如 JSON 结构）不变，不要添加任何解释"))
+	rewritten, err := attempt(l.withPersona(rewrite, p))
+	if err != nil {
+		return "", fmt.Errorf("failed to rewrite response without forbidden phrases: %w", err)
+	}
+	if violations := p.Violations(rewritten); len(violations) > 0 {
+		return "", NewLLMError(ErrorTypeResponse, fmt.Sprintf("response still uses forbidden phrases after rewrite: %s", strings.Join(violations, ", ")), nil)
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/persona"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+var testPersona = persona.Persona{
+	Name:             "小橙",
+	Tone:             []string{"亲切"},
+	ForbiddenPhrases: []string{"绝对保证"},
+	Variants: map[string]persona.Persona{
+		"en": {Name: "Orange", ForbiddenPhrases: []string{"guarantee"}},
+	},
+}
+
+// newScriptedLLM returns an OpenAI client that answers with the given
+// responses in order, and a function returning the system message of every
+// request it received.
+func newScriptedLLM(t *testing.T, responses []string, opts ...config.ConfigOption) (LLM, func() []string) {
+	var mu sync.Mutex
+	var systems []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &req))
+
+		mu.Lock()
+		defer mu.Unlock()
+		system := ""
+		if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+			system = req.Messages[0].Content
+		}
+		systems = append(systems, system)
+		require.LessOrEqual(t, len(systems), len(responses), "unexpected request")
+		content, _ := json.Marshal(responses[len(systems)-1])
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%s}}],"usage":{"prompt_tokens":10,"completion_tokens":2}}`, content)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetModel("gpt-4o"),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetMaxRetries(0),
+		config.SetTimeout(5*time.Second),
+		config.SetPersona(testPersona),
+	)
+	config.ApplyOptions(cfg, opts...)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+	return l, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), systems...)
+	}
+}
+
+func TestPersona_SystemPrompt(t *testing.T) {
+	l, systems := newScriptedLLM(t, []string{"好的", "好的", "好的", "好的"})
+	l.SetOption("system_prompt", "你是客服助手")
+	ctx := context.Background()
+
+	_, err := l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	_, err = l.Generate(ctx, NewPrompt("你好", WithSystemPrompt("只回答产品问题", "")))
+	require.NoError(t, err)
+	_, err = l.Generate(ctx, NewPrompt("hello"), WithPersonaLanguage("en-GB"))
+	require.NoError(t, err)
+	_, err = l.Generate(ctx, NewPrompt("你好"), WithoutPersona())
+	require.NoError(t, err)
+
+	instructions := testPersona.ForLanguage("").SystemPrompt()
+	assert.Equal(t, []string{
+		// The persona comes first so the system prompt prefix stays cacheable.
+		instructions + "\n\n你是客服助手",
+		instructions + "\n\n只回答产品问题",
+		testPersona.ForLanguage("en").SystemPrompt() + "\n\n你是客服助手",
+		"你是客服助手",
+	}, systems())
+}
+
+func TestWithPersona(t *testing.T) {
+	expert := persona.Persona{Role: "资深 Go 工程师", ExpertiseAreas: []string{"并发"}}
+	prompt := NewPrompt("这段代码有什么问题？", WithSystemPrompt("回答要简短。", ""), WithPersona(expert))
+	assert.Equal(t, "你的角色：资深 Go 工程师。\n你的专业领域：并发。\n\n回答要简短。", prompt.SystemPrompt)
+
+	prompt = NewPrompt("这段代码有什么问题？", WithPersona(persona.Persona{}))
+	assert.Empty(t, prompt.SystemPrompt, "an empty persona adds nothing")
+}
+
+func TestPersona_Guard(t *testing.T) {
+	ctx := context.Background()
+
+	l, systems := newScriptedLLM(t, []string{"我们绝对保证准时送达", "我们会尽力准时送达"})
+	recordingCtx, recorder := WithUsageRecorder(ctx)
+	response, err := l.Generate(recordingCtx, NewPrompt("能准时送达吗？"))
+	require.NoError(t, err)
+	assert.Equal(t, "我们会尽力准时送达", response)
+	assert.Len(t, systems(), 2, "one rewrite")
+	assert.Equal(t, TokenUsage{InputTokens: 20, OutputTokens: 4}, recorder.Usage(), "the rewrite is counted")
+
+	l, _ = newScriptedLLM(t, []string{"绝对保证", "仍然绝对保证"})
+	_, err = l.Generate(ctx, NewPrompt("能准时送达吗？"))
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeResponse, llmErr.Type)
+	assert.Contains(t, err.Error(), "绝对保证")
+
+	// Forbidden phrases are not checked when the persona is left out.
+	l, _ = newScriptedLLM(t, []string{"绝对保证"})
+	response, err = l.Generate(ctx, NewPrompt("能准时送达吗？"), WithoutPersona())
+	require.NoError(t, err)
+	assert.Equal(t, "绝对保证", response)
+
+	// Rewrites of structured output are validated against the schema again.
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"answer": map[string]interface{}{"type": "string"}},
+		"required":   []string{"answer"},
+	}
+	l, _ = newScriptedLLM(t, []string{`{"answer":"绝对保证"}`, `{"answer":"尽力而为"}`})
+	response, err = l.GenerateWithSchema(ctx, NewPrompt("能准时送达吗？"), schema)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"answer":"尽力而为"}`, response)
+}
+
+func TestTemplateRegistry_FingerprintWithPersona(t *testing.T) {
+	r := NewTemplateRegistry()
+	require.NoError(t, r.Register(NewPromptTemplate("greet", "", "你好，{{.Name}}")))
+
+	plain, err := r.Fingerprint("greet")
+	require.NoError(t, err)
+	withoutPersona, err := r.FingerprintWithPersona("greet", persona.Persona{})
+	require.NoError(t, err)
+	assert.Equal(t, plain, withoutPersona)
+
+	before, err := r.FingerprintWithPersona("greet", testPersona)
+	require.NoError(t, err)
+	assert.NotEqual(t, plain, before)
+
+	changed := testPersona
+	changed.Tone = []string{"正式"}
+	after, err := r.FingerprintWithPersona("greet", changed)
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+}
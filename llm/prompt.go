@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/invopop/jsonschema"
+	"github.com/yockii/gollm_cn/persona"
 	"github.com/yockii/gollm_cn/utils"
 )
 
@@ -45,17 +45,35 @@ type ToolCall struct {
 // It includes various components like system messages, user input, context,
 // and optional elements like tools and examples.
 type Prompt struct {
-	Input           string                 `json:"input" jsonschema:"required,description=The main input text for the LLM" validate:"required"`
-	Output          string                 `json:"output,omitempty" jsonschema:"description=Specification for the expected output format"`
-	Directives      []string               `json:"directives,omitempty" jsonschema:"description=List of directives to guide the LLM"`
-	Context         string                 `json:"context,omitempty" jsonschema:"description=Additional context for the LLM"`
-	MaxLength       int                    `json:"maxLength,omitempty" jsonschema:"minimum=1,description=Maximum length of the response in words" validate:"omitempty,min=1"`
-	Examples        []string               `json:"examples,omitempty" jsonschema:"description=List of examples to guide the LLM"`
-	SystemPrompt    string                 `json:"systemPrompt,omitempty" jsonschema:"description=System prompt for the LLM"`
-	SystemCacheType CacheType              `json:"systemCacheType,omitempty" jsonschema:"description=Cache type for the system prompt"`
-	Messages        []PromptMessage        `json:"messages,omitempty" jsonschema:"description=List of messages for the conversation"`
-	Tools           []utils.Tool           `json:"tools,omitempty" jsonschema:"description=Available tools for the LLM to use"`
-	ToolChoice      map[string]interface{} `json:"tool_choice,omitempty" jsonschema:"description=Configuration for tool selection behavior"`
+	Input            string                 `json:"input" jsonschema:"required,description=The main input text for the LLM" validate:"required"`
+	Output           string                 `json:"output,omitempty" jsonschema:"description=Specification for the expected output format"`
+	Directives       []string               `json:"directives,omitempty" jsonschema:"description=List of directives to guide the LLM"`
+	Context          string                 `json:"context,omitempty" jsonschema:"description=Additional context for the LLM"`
+	MaxLength        int                    `json:"maxLength,omitempty" jsonschema:"minimum=1,description=Maximum length of the response in words" validate:"omitempty,min=1"`
+	Examples         []string               `json:"examples,omitempty" jsonschema:"description=List of examples to guide the LLM"`
+	SystemPrompt     string                 `json:"systemPrompt,omitempty" jsonschema:"description=System prompt for the LLM"`
+	SystemCacheType  CacheType              `json:"systemCacheType,omitempty" jsonschema:"description=Cache type for the system prompt"`
+	CachedPrefix     string                 `json:"cachedPrefix,omitempty" jsonschema:"description=Large stable text sent before the prompt and cached by providers that support prompt caching"`
+	ResponsePrefix   string                 `json:"responsePrefix,omitempty" jsonschema:"description=Text the response starts with, prefilled by providers that support it"`
+	RetrievedContext string                 `json:"retrievedContext,omitempty" jsonschema:"description=Retrieved chunks with their sources, sent before the prompt"`
+	Images           []utils.ImageInput     `json:"images,omitempty" jsonschema:"description=Images sent alongside the prompt text to providers with vision support"`
+	StopSequences    []string               `json:"stopSequences,omitempty" jsonschema:"description=Sequences that stop generation when produced" validate:"omitempty,dive,required"`
+	Messages         []PromptMessage        `json:"messages,omitempty" jsonschema:"description=List of messages for the conversation"`
+	Tools            []utils.Tool           `json:"tools,omitempty" jsonschema:"description=Available tools for the LLM to use"`
+	ToolChoice       map[string]interface{} `json:"tool_choice,omitempty" jsonschema:"description=Configuration for tool selection behavior"`
+
+	// Sampling settings for every call made with the prompt, see WithPromptTopP
+	TopP              *float64           `json:"topP,omitempty" jsonschema:"description=Nucleus sampling parameter" validate:"omitempty,gt=0,lte=1"`
+	TopK              *int               `json:"topK,omitempty" jsonschema:"description=Top-k sampling parameter" validate:"omitempty,min=1"`
+	FrequencyPenalty  *float64           `json:"frequencyPenalty,omitempty" jsonschema:"description=Frequency penalty" validate:"omitempty,gte=-2,lte=2"`
+	PresencePenalty   *float64           `json:"presencePenalty,omitempty" jsonschema:"description=Presence penalty" validate:"omitempty,gte=-2,lte=2"`
+	RepetitionPenalty *float64           `json:"repetitionPenalty,omitempty" jsonschema:"description=Repetition penalty"`
+	Seed              *int               `json:"seed,omitempty" jsonschema:"description=Sampling seed for reproducible output"`
+	LogitBias         map[string]float64 `json:"logitBias,omitempty" jsonschema:"description=Biases of token strings, from -100 to 100"`
+
+	// Logprobs requests the log probabilities of the response's tokens with
+	// this many alternatives each, see WithLogprobs
+	Logprobs *int `json:"logprobs,omitempty" jsonschema:"description=Number of most likely alternatives returned with the log probability of each response token" validate:"omitempty,min=0"`
 }
 
 // PromptOption is a function type that modifies a Prompt.
@@ -112,6 +130,95 @@ func WithSystemPrompt(prompt string, cacheType CacheType) PromptOption {
 	}
 }
 
+// WithPersona makes the LLM play a persona, such as an expert of a field, for
+// this prompt: the persona's instructions are put before the prompt's system
+// prompt. Apply it after WithSystemPrompt, which replaces the system prompt.
+// Unlike config.SetPersona, responses are not checked for the persona's
+// forbidden phrases; they are only instructions.
+//
+// Example:
+//
+//	prompt := NewPrompt("这段代码有什么问题？\n"+code, WithPersona(persona.Persona{
+//	    Role:           "资深 Go 工程师",
+//	    ExpertiseAreas: []string{"并发", "性能优化"},
+//	}))
+func WithPersona(p persona.Persona) PromptOption {
+	return func(prompt *Prompt) {
+		instructions := p.SystemPrompt()
+		if instructions == "" {
+			return
+		}
+		if prompt.SystemPrompt != "" {
+			instructions += "\n\n" + prompt.SystemPrompt
+		}
+		prompt.SystemPrompt = instructions
+	}
+}
+
+// WithImages adds images to the prompt for providers with vision support
+// (OpenAI, Anthropic and Gemini). Generating with images on other providers
+// fails with ErrProviderDoesNotSupportImages.
+//
+// Parameters:
+//   - images: Images created with NewImageInputFromFile or NewImageInputFromURL
+//
+// Returns:
+//   - PromptOption function that can be passed to NewPrompt
+//
+// Example:
+//
+//	image, err := NewImageInputFromFile("chart.png")
+//	prompt := NewPrompt("总结这张图表的趋势", WithImages(image))
+func WithImages(images ...ImageInput) PromptOption {
+	return func(p *Prompt) {
+		p.Images = append(p.Images, images...)
+	}
+}
+
+// WithCachedPrefix sends a large, stable text, such as retrieved documents,
+// before the rest of the prompt and marks it as cacheable. Providers with
+// prompt caching (Anthropic) cache the request up to and including the prefix,
+// so later calls with the same prefix are cheaper and faster; the usage
+// reported by WithUsageRecorder shows cache writes and reads. Other providers
+// receive the prefix as ordinary prompt text.
+//
+// Parameters:
+//   - text: The text to cache; it must be identical across calls to be reused
+//
+// Example:
+//
+//	prompt := NewPrompt("这些文档中提到了哪些风险？", WithCachedPrefix(documents))
+func WithCachedPrefix(text string) PromptOption {
+	return func(p *Prompt) {
+		p.CachedPrefix = text
+	}
+}
+
+// WithResponsePrefix makes the response start with prefix, such as "{" to
+// force a JSON object or "步骤如下：" to force a list, which improves format
+// compliance. Providers that support prefilling (Anthropic) receive prefix as
+// the start of the assistant message and continue from it. Other providers
+// are instructed to start with prefix, and the prefix is removed from the
+// response when they echo it. Either way the response is the text after the
+// prefix, so prefix+response is the full answer. Anthropic does not accept a
+// prefill ending in whitespace, so trailing whitespace is not sent to it.
+// The echoed prefix is not removed from streamed responses, and a response
+// prefix cannot be combined with a JSON schema.
+//
+// Parameters:
+//   - prefix: The text the response starts with
+//
+// Example:
+//
+//	prompt := NewPrompt("列出三种水果及其颜色，使用 JSON", WithResponsePrefix("{"))
+//	response, err := llm.Generate(ctx, prompt)
+//	data := "{" + response
+func WithResponsePrefix(prefix string) PromptOption {
+	return func(p *Prompt) {
+		p.ResponsePrefix = prefix
+	}
+}
+
 // WithMessage adds a single message to the prompt.
 //
 // Parameters:
@@ -196,6 +303,84 @@ func WithMaxLength(length int) PromptOption {
 	}
 }
 
+// WithPromptStopSequences makes generation stop when any of the given
+// sequences is produced, for every call made with the prompt. It suits
+// templated and few-shot prompts whose delimiters belong to the prompt itself,
+// such as "]" for a prompt that asks for a JSON array. WithStopSequences on a
+// call takes precedence, and the client's stop sequences (see
+// config.SetStopSequences) apply to prompts without any.
+//
+// Parameters:
+//   - sequences: Non-empty stop sequences
+//
+// Example:
+//
+//	prompt := NewPrompt("问：1+1=?\n答：2\n\n问：2+3=?\n答：", WithPromptStopSequences("\n\n"))
+func WithPromptStopSequences(sequences ...string) PromptOption {
+	return func(p *Prompt) {
+		p.StopSequences = append(p.StopSequences, sequences...)
+	}
+}
+
+// WithPromptTopP sets the nucleus sampling parameter for every call made with
+// the prompt. Like the other prompt sampling options, it overrides the
+// client's setting (see config.SetTopP) and is overridden by the same option
+// on a call (see WithTopP).
+//
+// Example:
+//
+//	prompt := NewPrompt("写一首关于秋天的诗", WithPromptTopP(0.9), WithPromptTopK(40))
+func WithPromptTopP(topP float64) PromptOption {
+	return func(p *Prompt) {
+		p.TopP = &topP
+	}
+}
+
+// WithPromptTopK sets the top-k sampling parameter for every call made with
+// the prompt. Providers without top-k sampling ignore it.
+func WithPromptTopK(k int) PromptOption {
+	return func(p *Prompt) {
+		p.TopK = &k
+	}
+}
+
+// WithPromptFrequencyPenalty sets the frequency penalty for every call made
+// with the prompt, in [-2, 2]. Providers without frequency penalties ignore it.
+func WithPromptFrequencyPenalty(penalty float64) PromptOption {
+	return func(p *Prompt) {
+		p.FrequencyPenalty = &penalty
+	}
+}
+
+// WithPromptPresencePenalty sets the presence penalty for every call made
+// with the prompt, in [-2, 2]. Providers without presence penalties ignore it.
+func WithPromptPresencePenalty(penalty float64) PromptOption {
+	return func(p *Prompt) {
+		p.PresencePenalty = &penalty
+	}
+}
+
+// WithPromptRepetitionPenalty sets the repetition penalty for every call made
+// with the prompt. Providers without a repetition penalty ignore it.
+func WithPromptRepetitionPenalty(penalty float64) PromptOption {
+	return func(p *Prompt) {
+		p.RepetitionPenalty = &penalty
+	}
+}
+
+// WithPromptSeed sets the sampling seed for every call made with the prompt,
+// as WithSeed does for a single call, which takes precedence. It suits
+// regression tests that snapshot a prompt's output with a temperature of 0.
+//
+// Example:
+//
+//	prompt := NewPrompt("用一句话介绍杭州", WithPromptSeed(42))
+func WithPromptSeed(seed int) PromptOption {
+	return func(p *Prompt) {
+		p.Seed = &seed
+	}
+}
+
 func WithJSONSchemaValidation() GenerateOption {
 	return func(c *GenerateConfig) {
 		c.UseJSONSchema = true
@@ -249,6 +434,31 @@ func (p *Prompt) String() string {
 		builder.WriteString("\n\n")
 	}
 
+	p.writeBody(&builder)
+	return builder.String()
+}
+
+// userString returns the prompt without its system prompt. It is sent as the
+// user message when the system prompt is sent as a separate system message.
+func (p *Prompt) userString() string {
+	var builder strings.Builder
+	p.writeBody(&builder)
+	return builder.String()
+}
+
+// writeBody writes every component of the prompt except the system prompt.
+func (p *Prompt) writeBody(builder *strings.Builder) {
+	if p.CachedPrefix != "" {
+		builder.WriteString(p.CachedPrefix)
+		builder.WriteString("\n\n")
+	}
+
+	if p.RetrievedContext != "" {
+		builder.WriteString("Retrieved context:\n")
+		builder.WriteString(p.RetrievedContext)
+		builder.WriteString("\n\n")
+	}
+
 	if p.Context != "" {
 		builder.WriteString("Context: ")
 		builder.WriteString(p.Context)
@@ -285,6 +495,11 @@ func (p *Prompt) String() string {
 		builder.WriteString(fmt.Sprintf("\n\nPlease limit your response to approximately %d words.", p.MaxLength))
 	}
 
+	if p.ResponsePrefix != "" {
+		builder.WriteString("\n\nStart your response with exactly the following text, with nothing before it:\n")
+		builder.WriteString(p.ResponsePrefix)
+	}
+
 	if len(p.Messages) > 0 {
 		builder.WriteString("\nMessages:\n")
 		for _, msg := range p.Messages {
@@ -294,8 +509,70 @@ func (p *Prompt) String() string {
 			}
 		}
 	}
+}
 
-	return builder.String()
+// RenderForLLM renders the prompt for embedding into another prompt, e.g. when
+// a model is asked to assess or improve it. Unlike String, which builds the
+// text sent to the model, it puts each component under a label, omits empty
+// components and leaves out caching and tool-choice settings.
+//
+// Returns:
+//   - Human-readable rendering of the prompt
+func (p *Prompt) RenderForLLM() string {
+	if p == nil {
+		return ""
+	}
+	var sections []string
+	add := func(label, body string) {
+		if strings.TrimSpace(body) != "" {
+			sections = append(sections, label+":\n"+strings.TrimSpace(body))
+		}
+	}
+	list := func(items []string) string {
+		var builder strings.Builder
+		for _, item := range items {
+			if strings.TrimSpace(item) != "" {
+				builder.WriteString("- " + strings.TrimSpace(item) + "\n")
+			}
+		}
+		return builder.String()
+	}
+
+	add("System prompt", p.SystemPrompt)
+	add("Cached prefix", p.CachedPrefix)
+	add("Retrieved context", p.RetrievedContext)
+	add("Input", p.Input)
+	if len(p.Images) > 0 {
+		add("Images", fmt.Sprintf("%d attached", len(p.Images)))
+	}
+	add("Context", p.Context)
+	add("Directives", list(p.Directives))
+	add("Output format", p.Output)
+	add("Examples", list(p.Examples))
+	if p.MaxLength > 0 {
+		add("Maximum length", fmt.Sprintf("%d words", p.MaxLength))
+	}
+	add("Response prefix", p.ResponsePrefix)
+	if len(p.Tools) > 0 {
+		tools := make([]string, len(p.Tools))
+		for i, tool := range p.Tools {
+			tools[i] = tool.Function.Name
+			if tool.Function.Description != "" {
+				tools[i] += ": " + tool.Function.Description
+			}
+		}
+		add("Tools", list(tools))
+	}
+	// NewPrompt records the input as the first user message; only render
+	// messages that add something to it.
+	if len(p.Messages) > 1 || (len(p.Messages) == 1 && p.Messages[0].Content != p.Input) {
+		messages := make([]string, len(p.Messages))
+		for i, msg := range p.Messages {
+			messages[i] = msg.Role + ": " + msg.Content
+		}
+		add("Messages", list(messages))
+	}
+	return strings.Join(sections, "\n\n")
 }
 
 // Validate checks if the prompt configuration is valid according to
@@ -311,30 +588,24 @@ func (p *Prompt) Validate() error {
 // This schema can be used for validation and documentation purposes.
 //
 // Parameters:
-//   - opts: Optional schema generation configuration
+//   - opts: Optional schema generation configuration, such as WithExpandedStruct
+//     or WithFieldDescription
 //
 // Returns:
 //   - JSON schema as bytes
-//   - Error if schema generation fails
+//   - Error if schema generation fails or an option names an unknown field
 func (p *Prompt) GenerateJSONSchema(opts ...SchemaOption) ([]byte, error) {
-	reflector := &jsonschema.Reflector{}
-	for _, opt := range opts {
-		opt(reflector)
+	cfg := newSchemaConfig(opts)
+	schema, err := cfg.reflector.Reflect(p).MarshalJSON()
+	if err != nil || !cfg.hasOverrides() {
+		return schema, err
 	}
-	schema := reflector.Reflect(p)
-	return schema.MarshalJSON()
-}
-
-// SchemaOption is a function type for configuring JSON schema generation.
-type SchemaOption func(*jsonschema.Reflector)
-
-// WithExpandedStruct enables or disables detailed structure expansion
-// in the generated JSON schema.
-//
-// Parameters:
-//   - expanded: Whether to expand nested structures
-func WithExpandedStruct(expanded bool) SchemaOption {
-	return func(r *jsonschema.Reflector) {
-		r.ExpandedStruct = expanded
+	var schemaMap map[string]interface{}
+	if err := json.Unmarshal(schema, &schemaMap); err != nil {
+		return nil, err
+	}
+	if err := cfg.apply(schemaMap); err != nil {
+		return nil, err
 	}
+	return json.Marshal(schemaMap)
 }
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestWithCachedPrefix_Anthropic(t *testing.T) {
+	var last map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		last = nil
+		require.NoError(t, json.Unmarshal(body, &last))
+		w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":20,"output_tokens":3,"cache_creation_input_tokens":0,"cache_read_input_tokens":4000}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("anthropic"),
+		config.SetModel("claude-3-5-haiku-latest"),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetMaxRetries(0),
+		config.SetTimeout(5*time.Second),
+	)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+
+	documents := strings.Repeat("检索到的文档。", 100)
+	ctx, recorder := WithUsageRecorder(context.Background())
+	_, err = l.Generate(ctx, NewPrompt("文档中提到了哪些风险？", WithCachedPrefix(documents)))
+	require.NoError(t, err)
+
+	content := last["messages"].([]interface{})[0].(map[string]interface{})["content"].([]interface{})
+	require.Len(t, content, 2)
+	prefix, rest := content[0].(map[string]interface{}), content[1].(map[string]interface{})
+	assert.Equal(t, documents, prefix["text"])
+	assert.Equal(t, map[string]interface{}{"type": "ephemeral"}, prefix["cache_control"])
+	assert.NotContains(t, rest["text"], documents)
+	assert.Contains(t, rest["text"], "文档中提到了哪些风险？")
+	assert.NotContains(t, rest, "cache_control")
+	assert.NotContains(t, last, "cached_prefix")
+
+	assert.Equal(t, TokenUsage{InputTokens: 20, OutputTokens: 3, CacheReadInputTokens: 4000}, recorder.Usage())
+}
+
+func TestWithCachedPrefix_OtherProviders(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t)
+
+	_, err := l.Generate(context.Background(), NewPrompt("文档中提到了哪些风险？", WithCachedPrefix("检索到的文档。")))
+	require.NoError(t, err)
+
+	req := lastRequest()
+	assert.NotContains(t, req, "cached_prefix")
+	messages := req["messages"].([]interface{})
+	user := messages[len(messages)-1].(map[string]interface{})["content"].(string)
+	assert.True(t, strings.HasPrefix(user, "检索到的文档。\n\n"), user)
+	assert.Contains(t, user, "文档中提到了哪些风险？")
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prompts are encoded to JSON by encoding/json through the json tags of
+// Prompt, so every field with a tag round-trips, including fields added
+// later. YAML uses the same field names: MarshalYAML and UnmarshalYAML go
+// through the JSON encoding.
+
+// MarshalYAML encodes the prompt with the field names of its JSON encoding,
+// in block style.
+//
+// Example:
+//
+//	data, err := yaml.Marshal(prompt)
+func (p *Prompt) MarshalYAML() (interface{}, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML; decoding it into a node keeps the order of the fields
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	clearYAMLStyle(&node)
+	return node.Content[0], nil
+}
+
+// UnmarshalYAML decodes a prompt encoded by MarshalYAML, or written by hand
+// with the field names of the JSON encoding.
+//
+// Example:
+//
+//	var prompt llm.Prompt
+//	err := yaml.Unmarshal([]byte("input: 总结以下文本\ndirectives:\n  - 不超过 50 字\n"), &prompt)
+func (p *Prompt) UnmarshalYAML(value *yaml.Node) error {
+	var decoded interface{}
+	if err := value.Decode(&decoded); err != nil {
+		return err
+	}
+	data, err := json.Marshal(decoded)
+	if err != nil {
+		return fmt.Errorf("failed to convert prompt to JSON: %w", err)
+	}
+	var prompt Prompt
+	if err := json.Unmarshal(data, &prompt); err != nil {
+		return err
+	}
+	*p = prompt
+	return nil
+}
+
+// Hash returns a hex-encoded SHA-256 hash of the prompt's JSON encoding, for
+// deduplicating prompts and as a cache key. Prompts with the same content
+// have the same hash across processes: the fields are encoded in a fixed
+// order and map keys sorted. Fields added to Prompt later leave the hash of
+// prompts that do not set them unchanged.
+//
+// Example:
+//
+//	key := prompt.Hash()
+//	if cached, ok := cache[key]; ok {
+//	    return cached, nil
+//	}
+func (p *Prompt) Hash() string {
+	data, err := json.Marshal(p)
+	if err != nil {
+		// Only unsupported values, such as NaN logit biases, fail to encode
+		data = []byte(fmt.Sprintf("%#v", *p))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// clearYAMLStyle resets the JSON flow and quoting styles of a node decoded
+// from JSON, so that it is encoded in the usual block style.
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
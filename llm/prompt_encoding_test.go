@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// fullPrompt returns a prompt with every kind of field set.
+func fullPrompt() *Prompt {
+	topP, seed := 0.9, 42
+	prompt := NewPrompt("总结以下文本：\n“今天天气很好。”",
+		WithSystemPrompt("你是一名助手。", CacheTypeEphemeral),
+		WithDirectives("不超过 50 字", "使用中文"),
+		WithExamples("输入：你好 -> 输出：问候"),
+		WithOutput("一句话摘要"),
+		WithContext("新闻稿"),
+		WithMaxLength(50),
+		WithPromptStopSequences("。"),
+		WithPromptTopP(topP),
+		WithPromptSeed(seed),
+		WithImages(utils.ImageInput{URL: "https://example.com/a.png"}),
+		WithTools([]utils.Tool{{Type: "function", Function: utils.Function{Name: "search", Parameters: map[string]interface{}{"type": "object"}}}}),
+		WithMessage("assistant", "好的", ""),
+	)
+	prompt.LogitBias = map[string]float64{"天气": 5, "晴": -5}
+	return prompt
+}
+
+func TestPrompt_JSONRoundTrip(t *testing.T) {
+	for name, prompt := range map[string]*Prompt{
+		"full":     fullPrompt(),
+		"minimal":  NewPrompt("你好"),
+		"no input": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := json.Marshal(prompt)
+			require.NoError(t, err)
+			var decoded Prompt
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			assert.Equal(t, prompt, &decoded)
+			assert.Equal(t, prompt.Hash(), decoded.Hash())
+		})
+	}
+}
+
+func TestPrompt_YAMLRoundTrip(t *testing.T) {
+	for name, prompt := range map[string]*Prompt{
+		"full":    fullPrompt(),
+		"minimal": NewPrompt("你好"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := yaml.Marshal(prompt)
+			require.NoError(t, err)
+			var decoded Prompt
+			require.NoError(t, yaml.Unmarshal(data, &decoded))
+			assert.Equal(t, prompt, &decoded)
+		})
+	}
+
+	data, err := yaml.Marshal(NewPrompt("你好", WithDirectives("简洁")))
+	require.NoError(t, err)
+	assert.Equal(t, "input: 你好\ndirectives:\n    - 简洁\nmessages:\n    - role: user\n      content: 你好\n", string(data),
+		"fields are in block style with their JSON names")
+
+	var prompt Prompt
+	require.NoError(t, yaml.Unmarshal([]byte("input: 总结以下文本\nmaxLength: 30\ntopP: 0.5\n"), &prompt))
+	assert.Equal(t, "总结以下文本", prompt.Input)
+	assert.Equal(t, 30, prompt.MaxLength)
+	assert.Equal(t, 0.5, *prompt.TopP)
+	assert.Error(t, yaml.Unmarshal([]byte("input: [1, 2]"), &prompt))
+}
+
+func TestPrompt_Hash(t *testing.T) {
+	hash := fullPrompt().Hash()
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, fullPrompt().Hash(), "equal prompts have equal hashes")
+
+	changed := fullPrompt()
+	changed.Directives[1] = "使用英文"
+	assert.NotEqual(t, hash, changed.Hash())
+	assert.NotEqual(t, NewPrompt("你好").Hash(), NewPrompt("您好").Hash())
+}
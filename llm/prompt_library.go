@@ -0,0 +1,158 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PromptLibrary manages named, versioned prompts on top of a PromptStore.
+//
+// Example:
+//
+//	library := NewPromptLibrary(NewFilePromptStore("prompts"))
+//	library.Save("summarize", "1.0.0", NewPrompt("请总结以下文本", WithMaxLength(100)))
+//	library.Save("summarize", "1.1.0", NewPrompt("请用要点总结以下文本", WithMaxLength(80)))
+//
+//	diff, err := library.Diff("summarize", "1.0.0", "1.1.0")
+//	for _, change := range diff.Changes {
+//	    fmt.Printf("%s: %v -> %v\n", change.Field, change.Old, change.New)
+//	}
+type PromptLibrary struct {
+	store PromptStore
+}
+
+// PromptFieldChange describes a single Prompt field that differs between two versions.
+type PromptFieldChange struct {
+	Field string      // Name of the Prompt field, e.g. "Input" or "Directives"
+	Old   interface{} // Value in the first version
+	New   interface{} // Value in the second version
+}
+
+// PromptDiff is the field-by-field comparison of two versions of a prompt.
+type PromptDiff struct {
+	Name    string              // Prompt name
+	From    string              // First version
+	To      string              // Second version
+	Changes []PromptFieldChange // Fields that differ, in Prompt field order
+}
+
+// Equal reports whether the two versions have no differing fields.
+func (d PromptDiff) Equal() bool {
+	return len(d.Changes) == 0
+}
+
+// NewPromptLibrary creates a prompt library backed by the given store.
+func NewPromptLibrary(store PromptStore) *PromptLibrary {
+	return &PromptLibrary{store: store}
+}
+
+// Save stores a prompt under the given name and version.
+func (l *PromptLibrary) Save(name, version string, p *Prompt) error {
+	if p == nil {
+		return fmt.Errorf("prompt %s@%s is nil", name, version)
+	}
+	return l.store.Save(name, version, p)
+}
+
+// Load retrieves a prompt by name and version.
+func (l *PromptLibrary) Load(name, version string) (*Prompt, error) {
+	return l.store.Load(name, version)
+}
+
+// ListVersions returns the versions saved for name, oldest first. Versions are
+// ordered segment by segment, comparing numeric segments as numbers, so
+// "1.10.0" sorts after "1.9.0" and "v2" after "v1".
+func (l *PromptLibrary) ListVersions(name string) ([]string, error) {
+	versions, err := l.store.Versions(name)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions, nil
+}
+
+// Latest returns the most recent version of the named prompt according to
+// the ordering used by ListVersions. It returns ErrPromptNotFound if the
+// prompt has no versions.
+func (l *PromptLibrary) Latest(name string) (*Prompt, string, error) {
+	versions, err := l.ListVersions(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(versions) == 0 {
+		return nil, "", fmt.Errorf("%w: %s has no versions", ErrPromptNotFound, name)
+	}
+	version := versions[len(versions)-1]
+	p, err := l.store.Load(name, version)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, version, nil
+}
+
+// Diff compares two versions of a prompt field by field.
+func (l *PromptLibrary) Diff(name, v1, v2 string) (PromptDiff, error) {
+	from, err := l.store.Load(name, v1)
+	if err != nil {
+		return PromptDiff{}, err
+	}
+	to, err := l.store.Load(name, v2)
+	if err != nil {
+		return PromptDiff{}, err
+	}
+
+	diff := PromptDiff{Name: name, From: v1, To: v2}
+	fromValue := reflect.ValueOf(*from)
+	toValue := reflect.ValueOf(*to)
+	for i := 0; i < fromValue.NumField(); i++ {
+		field := fromValue.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		oldValue := fromValue.Field(i).Interface()
+		newValue := toValue.Field(i).Interface()
+		if !reflect.DeepEqual(oldValue, newValue) {
+			diff.Changes = append(diff.Changes, PromptFieldChange{Field: field.Name, Old: oldValue, New: newValue})
+		}
+	}
+	return diff, nil
+}
+
+// compareVersions orders version strings by their dot, dash or underscore
+// separated segments, comparing digits numerically and everything else lexically.
+func compareVersions(a, b string) int {
+	split := func(v string) []string {
+		return strings.FieldsFunc(strings.TrimPrefix(v, "v"), func(r rune) bool {
+			return r == '.' || r == '-' || r == '_'
+		})
+	}
+	as, bs := split(a), split(b)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				if an < bn {
+					return -1
+				}
+				return 1
+			}
+		case as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return strings.Compare(a, b)
+}
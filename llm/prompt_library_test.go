@@ -0,0 +1,74 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptLibrary_FileStore(t *testing.T) {
+	library := NewPromptLibrary(NewFilePromptStore(t.TempDir()))
+
+	require.NoError(t, library.Save("team/summarize", "1.9.0", NewPrompt("请总结", WithMaxLength(100))))
+	require.NoError(t, library.Save("team/summarize", "1.10.0", NewPrompt("请用要点总结", WithMaxLength(100), WithDirectives("使用要点"))))
+	require.NoError(t, library.Save("team/summarize", "1.2.0", NewPrompt("总结")))
+
+	versions, err := library.ListVersions("team/summarize")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.2.0", "1.9.0", "1.10.0"}, versions)
+
+	p, err := library.Load("team/summarize", "1.9.0")
+	require.NoError(t, err)
+	assert.Equal(t, "请总结", p.Input)
+	assert.Equal(t, 100, p.MaxLength)
+
+	latest, version, err := library.Latest("team/summarize")
+	require.NoError(t, err)
+	assert.Equal(t, "1.10.0", version)
+	assert.Equal(t, "请用要点总结", latest.Input)
+
+	diff, err := library.Diff("team/summarize", "1.9.0", "1.10.0")
+	require.NoError(t, err)
+	// NewPrompt also records the input as a user message
+	require.Len(t, diff.Changes, 3)
+	assert.Equal(t, "Input", diff.Changes[0].Field)
+	assert.Equal(t, "请总结", diff.Changes[0].Old)
+	assert.Equal(t, "请用要点总结", diff.Changes[0].New)
+	assert.Equal(t, "Directives", diff.Changes[1].Field)
+	assert.Equal(t, "Messages", diff.Changes[2].Field)
+
+	same, err := library.Diff("team/summarize", "1.9.0", "1.9.0")
+	require.NoError(t, err)
+	assert.True(t, same.Equal())
+}
+
+func TestPromptLibrary_NotFoundAndInvalidNames(t *testing.T) {
+	library := NewPromptLibrary(NewFilePromptStore(t.TempDir()))
+
+	_, err := library.Load("missing", "1")
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+
+	_, err = library.ListVersions("missing")
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "empty"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty", "notes.txt"), []byte("x"), 0o644))
+	_, _, err = NewPromptLibrary(NewFilePromptStore(dir)).Latest("empty")
+	assert.ErrorIs(t, err, ErrPromptNotFound, "a prompt directory without versions has no latest version")
+
+	assert.Error(t, library.Save("../escape", "1", NewPrompt("x")))
+	assert.Error(t, library.Save("ok", "a/b", NewPrompt("x")))
+	assert.Error(t, library.Save("ok", "1", nil))
+}
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, -1, compareVersions("v1", "v2"))
+	assert.Equal(t, 1, compareVersions("1.10", "1.9"))
+	assert.Equal(t, -1, compareVersions("1.0", "1.0.1"))
+	assert.Equal(t, 0, compareVersions("2024-01-02", "2024-01-02"))
+	assert.Equal(t, -1, compareVersions("1.0-alpha", "1.0-beta"))
+}
@@ -0,0 +1,219 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severities of prompt warnings.
+const (
+	// SeverityWarning marks a likely but uncertain problem.
+	SeverityWarning = "warning"
+	// SeverityError marks a problem that almost certainly breaks the prompt.
+	SeverityError = "error"
+)
+
+// Codes of prompt warnings.
+const (
+	WarningConflictingDirectives    = "conflicting_directives"
+	WarningMissingOutputSpec        = "missing_output_spec"
+	WarningInputTooLong             = "input_too_long"
+	WarningUnsubstitutedPlaceholder = "unsubstituted_placeholder"
+)
+
+// defaultMaxInputLength is the input length, in characters, above which Lint
+// warns by default.
+const defaultMaxInputLength = 50000
+
+// PromptWarning is a problem found in a prompt by Lint.
+type PromptWarning struct {
+	Code     string // One of the Warning* codes
+	Message  string // Human-readable description of the problem
+	Severity string // SeverityWarning or SeverityError
+}
+
+func (w PromptWarning) String() string {
+	return fmt.Sprintf("%s: %s (%s)", w.Severity, w.Message, w.Code)
+}
+
+// LintOption configures Lint.
+type LintOption func(*lintConfig)
+
+type lintConfig struct {
+	maxInputLength int
+}
+
+// WithMaxInputLength sets the input length, in characters, above which Lint
+// warns. The default is 50000.
+func WithMaxInputLength(n int) LintOption {
+	return func(c *lintConfig) {
+		c.maxInputLength = n
+	}
+}
+
+// conflictingTerms are pairs of instruction groups that contradict each other
+// when both appear in a prompt's directives.
+var conflictingTerms = [][2][]string{
+	{
+		{"简洁", "简短", "简要", "concise", "brief", "short"},
+		{"详尽", "详细", "全面", "exhaustive", "detailed", "comprehensive", "in detail"},
+	},
+	{
+		{"正式", "formal"},
+		{"口语化", "随意", "casual", "informal"},
+	},
+}
+
+// structuredTerms suggest that a prompt expects structured output.
+var structuredTerms = []string{"json", "yaml", "csv", "xml", "结构化", "表格"}
+
+// placeholderPattern matches template placeholders left in a prompt, such as
+// "{{.Name}}" or "{VARIABLE}".
+var placeholderPattern = regexp.MustCompile(`\{\{\s*\.?\w*\s*\}\}|\{[A-Z][A-Z0-9_]*\}`)
+
+// Lint checks the prompt for common anti-patterns: contradictory directives,
+// a missing output specification when structured output is requested, an
+// overly long input, and template placeholders left unsubstituted. Unlike
+// Validate, which enforces the prompt's validation tags, the checks are
+// heuristics; use WithStrictValidation to run them on every Generate call.
+//
+// Parameters:
+//   - opts: Optional lint configuration, such as WithMaxInputLength
+//
+// Returns:
+//   - The problems found, in the order of the checks; empty if none
+//
+// Example:
+//
+//	prompt := NewPrompt("总结 {DOCUMENT}", WithDirectives("简洁", "提供详尽的细节"))
+//	for _, w := range prompt.Lint() {
+//	    fmt.Println(w)
+//	}
+func (p *Prompt) Lint(opts ...LintOption) []PromptWarning {
+	cfg := &lintConfig{maxInputLength: defaultMaxInputLength}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var warnings []PromptWarning
+	for _, pair := range conflictingTerms {
+		first, second := findTerm(p.Directives, pair[0]), findTerm(p.Directives, pair[1])
+		if first != "" && second != "" {
+			warnings = append(warnings, PromptWarning{
+				Code:     WarningConflictingDirectives,
+				Message:  fmt.Sprintf("directive %q contradicts directive %q", first, second),
+				Severity: SeverityWarning,
+			})
+		}
+	}
+
+	if p.Output == "" {
+		texts := append([]string{p.Input}, p.Directives...)
+		if term := findTerm(texts, structuredTerms); term != "" {
+			warnings = append(warnings, PromptWarning{
+				Code:     WarningMissingOutputSpec,
+				Message:  fmt.Sprintf("prompt asks for structured output (%q) but sets no output format; use WithOutput", term),
+				Severity: SeverityWarning,
+			})
+		}
+	}
+
+	if length := utf8.RuneCountInString(p.Input); cfg.maxInputLength > 0 && length > cfg.maxInputLength {
+		warnings = append(warnings, PromptWarning{
+			Code:     WarningInputTooLong,
+			Message:  fmt.Sprintf("input is %d characters, over the limit of %d; consider WithAutoTruncate", length, cfg.maxInputLength),
+			Severity: SeverityWarning,
+		})
+	}
+
+	texts := append([]string{p.SystemPrompt, p.Input, p.Context, p.Output}, p.Directives...)
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		for _, placeholder := range placeholderPattern.FindAllString(text, -1) {
+			if seen[placeholder] {
+				continue
+			}
+			seen[placeholder] = true
+			warnings = append(warnings, PromptWarning{
+				Code:     WarningUnsubstitutedPlaceholder,
+				Message:  fmt.Sprintf("placeholder %s was not substituted", placeholder),
+				Severity: SeverityError,
+			})
+		}
+	}
+	return warnings
+}
+
+// findTerm returns the first text containing one of terms, or "" if none does.
+// ASCII terms only match whole words, so "formal" does not match "informal".
+func findTerm(texts []string, terms []string) string {
+	for _, text := range texts {
+		lower := strings.ToLower(text)
+		for _, term := range terms {
+			if containsTerm(lower, term) {
+				return text
+			}
+		}
+	}
+	return ""
+}
+
+// containsTerm reports whether text contains term, as a whole word if term is
+// ASCII.
+func containsTerm(text, term string) bool {
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], term)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(term)
+		if term[0] >= utf8.RuneSelf || (!isWordByte(text, start-1) && !isWordByte(text, end)) {
+			return true
+		}
+		offset = end
+	}
+}
+
+// isWordByte reports whether text[i] is an ASCII letter or digit.
+func isWordByte(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+	c := text[i]
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// WithStrictValidation lints the prompt (see Prompt.Lint) before a Generate
+// call. Problems of SeverityError fail the call with ErrorTypeInvalidInput;
+// the others are logged as warnings.
+func WithStrictValidation() GenerateOption {
+	return func(c *GenerateConfig) {
+		c.StrictValidation = true
+	}
+}
+
+// lintPrompt runs Lint for a call with WithStrictValidation. The output format
+// check is skipped for calls with a schema, which specifies the output.
+func (l *LLMImpl) lintPrompt(prompt *Prompt, config *GenerateConfig, withSchema bool) error {
+	if !config.StrictValidation {
+		return nil
+	}
+	var errs []string
+	for _, w := range prompt.Lint() {
+		if withSchema && w.Code == WarningMissingOutputSpec {
+			continue
+		}
+		if w.Severity == SeverityError {
+			errs = append(errs, w.Message)
+			continue
+		}
+		l.logger.Warn("Prompt lint warning", "code", w.Code, "message", w.Message)
+	}
+	if len(errs) > 0 {
+		return NewLLMError(ErrorTypeInvalidInput, "prompt failed validation: "+strings.Join(errs, "; "), nil)
+	}
+	return nil
+}
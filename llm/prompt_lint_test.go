@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptLint(t *testing.T) {
+	prompt := NewPrompt("把 {DOCUMENT} 转换为 JSON，作者是 {{.Author}}",
+		WithDirectives("回答要简洁", "Provide exhaustive detail", "Use an informal tone"),
+	)
+	warnings := prompt.Lint(WithMaxInputLength(10))
+
+	codes := make([]string, len(warnings))
+	for i, w := range warnings {
+		codes[i] = w.Code
+	}
+	assert.Equal(t, []string{
+		WarningConflictingDirectives,
+		WarningMissingOutputSpec,
+		WarningInputTooLong,
+		WarningUnsubstitutedPlaceholder,
+		WarningUnsubstitutedPlaceholder,
+	}, codes, "\"informal\" does not count as \"formal\"")
+	assert.Equal(t, `directive "回答要简洁" contradicts directive "Provide exhaustive detail"`, warnings[0].Message)
+	assert.Equal(t, SeverityWarning, warnings[0].Severity)
+	assert.Equal(t, "placeholder {DOCUMENT} was not substituted", warnings[3].Message)
+	assert.Equal(t, SeverityError, warnings[4].Severity)
+
+	prompt = NewPrompt("把这段话转换为 JSON", WithOutput(`{"text": "..."}`), WithDirectives("简洁"))
+	assert.Empty(t, prompt.Lint())
+}
+
+func TestWithStrictValidation(t *testing.T) {
+	l, lastRequest := newCapturingLLM(t)
+	ctx := context.Background()
+	prompt := NewPrompt("总结 {DOCUMENT}")
+
+	_, err := l.Generate(ctx, prompt)
+	require.NoError(t, err, "prompts are not linted by default")
+	assert.NotNil(t, lastRequest())
+
+	_, err = l.Generate(ctx, prompt, WithStrictValidation())
+	var llmErr *LLMError
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	assert.Contains(t, err.Error(), "placeholder {DOCUMENT} was not substituted")
+
+	// Warnings do not fail the call, and a schema counts as an output format.
+	_, err = l.Generate(ctx, NewPrompt("列出 JSON 格式的城市"), WithStrictValidation())
+	require.NoError(t, err)
+	_, err = l.GenerateWithSchema(ctx, NewPrompt("城市 {NAME}"), map[string]interface{}{"type": "object"}, WithStrictValidation())
+	assert.ErrorContains(t, err, "placeholder {NAME}")
+}
@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// assertGolden compares got with testdata/name, rewriting the file when the
+// tests run with -update.
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), got)
+}
+
+func TestPrompt_RenderForLLM(t *testing.T) {
+	prompt := NewPrompt("总结这篇文章",
+		WithSystemPrompt("你是一名资深编辑。", CacheTypeEphemeral),
+		WithContext("文章发表于 2024 年。"),
+		WithDirectives("使用简洁的语言", "突出关键数据"),
+		WithOutput("一段不超过三句话的摘要"),
+		WithExamples("示例摘要：Go 1.22 改进了循环变量语义。"),
+		WithMaxLength(80),
+	)
+	assertGolden(t, "prompt_render_full.golden", prompt.RenderForLLM())
+}
+
+func TestPrompt_RenderForLLM_Minimal(t *testing.T) {
+	assertGolden(t, "prompt_render_minimal.golden", NewPrompt("Hello").RenderForLLM())
+}
+
+func TestPrompt_RenderForLLM_MessagesAndTools(t *testing.T) {
+	prompt := NewPrompt("What's the weather?",
+		WithMessage("assistant", "Which city?", ""),
+		WithMessage("user", "Berlin", ""),
+		WithTools([]utils.Tool{{
+			Type:     "function",
+			Function: utils.Function{Name: "get_weather", Description: "Get the current weather"},
+		}}),
+	)
+	assertGolden(t, "prompt_render_messages.golden", prompt.RenderForLLM())
+}
+
+func TestPrompt_RenderForLLM_Nil(t *testing.T) {
+	var prompt *Prompt
+	assert.Empty(t, prompt.RenderForLLM())
+}
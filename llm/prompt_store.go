@@ -0,0 +1,249 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrPromptNotFound is returned by a PromptStore when the requested prompt
+// name or version does not exist.
+var ErrPromptNotFound = errors.New("prompt not found")
+
+// PromptStore persists versioned prompts. Implementations must be safe to use
+// from a single PromptLibrary; concurrent access across processes depends on the
+// underlying storage.
+type PromptStore interface {
+	// Save stores the prompt under the given name and version, replacing any
+	// prompt previously saved with the same name and version.
+	Save(name, version string, p *Prompt) error
+	// Load retrieves the prompt saved under the given name and version.
+	// It returns an error wrapping ErrPromptNotFound if it does not exist.
+	Load(name, version string) (*Prompt, error)
+	// Versions lists the versions saved for the given name in no particular order.
+	Versions(name string) ([]string, error)
+}
+
+// FilePromptStore stores each prompt version as a JSON file on disk, laid out
+// as <dir>/<name>/<version>.json. Names may contain forward slashes to group
+// prompts into subdirectories, which keeps the store friendly to version control.
+type FilePromptStore struct {
+	dir string
+}
+
+// NewFilePromptStore creates a PromptStore that keeps prompts as JSON files under dir.
+// The directory is created on the first Save if it does not exist.
+//
+// Example:
+//
+//	library := NewPromptLibrary(NewFilePromptStore("prompts"))
+func NewFilePromptStore(dir string) *FilePromptStore {
+	return &FilePromptStore{dir: dir}
+}
+
+// Save writes the prompt to <dir>/<name>/<version>.json.
+func (s *FilePromptStore) Save(name, version string, p *Prompt) error {
+	path, err := s.path(name, version)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode prompt %s@%s: %w", name, version, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create prompt directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write prompt %s@%s: %w", name, version, err)
+	}
+	return nil
+}
+
+// Load reads the prompt from <dir>/<name>/<version>.json.
+func (s *FilePromptStore) Load(name, version string) (*Prompt, error) {
+	path, err := s.path(name, version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s@%s", ErrPromptNotFound, name, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt %s@%s: %w", name, version, err)
+	}
+	var p Prompt
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to decode prompt %s@%s: %w", name, version, err)
+	}
+	return &p, nil
+}
+
+// Versions lists the *.json files saved for the given name.
+func (s *FilePromptStore) Versions(name string) ([]string, error) {
+	if err := checkPromptKey(name, "name"); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(s.dir, filepath.FromSlash(name)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of prompt %s: %w", name, err)
+	}
+	var versions []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".json" {
+			versions = append(versions, strings.TrimSuffix(entry.Name(), ".json"))
+		}
+	}
+	return versions, nil
+}
+
+func (s *FilePromptStore) path(name, version string) (string, error) {
+	if err := checkPromptKey(name, "name"); err != nil {
+		return "", err
+	}
+	if err := checkPromptKey(version, "version"); err != nil {
+		return "", err
+	}
+	if strings.Contains(version, "/") {
+		return "", fmt.Errorf("prompt version %q must not contain '/'", version)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(name), version+".json"), nil
+}
+
+// checkPromptKey rejects empty names and versions and any path traversal.
+func checkPromptKey(value, kind string) error {
+	if value == "" {
+		return fmt.Errorf("prompt %s must not be empty", kind)
+	}
+	for _, part := range strings.Split(value, "/") {
+		if part == "" || part == "." || part == ".." || strings.Contains(part, `\`) {
+			return fmt.Errorf("invalid prompt %s %q", kind, value)
+		}
+	}
+	return nil
+}
+
+// SQLPromptStore stores prompts as JSON in a SQL table named gollm_prompts.
+// The statements use SQLite syntax.
+type SQLPromptStore struct {
+	db *sql.DB
+}
+
+// NewSQLitePromptStore opens the SQLite database identified by dsn and creates
+// the prompt table if needed. Like other database/sql users, gollm does not
+// bundle a driver: the application must import one that registers itself as
+// "sqlite3", for example:
+//
+//	import _ "github.com/mattn/go-sqlite3"
+//
+//	store, err := NewSQLitePromptStore("file:prompts.db")
+//
+// Returns:
+//   - The store
+//   - Error if no "sqlite3" driver is registered or the table cannot be created
+func NewSQLitePromptStore(dsn string) (*SQLPromptStore, error) {
+	if !slices.Contains(sql.Drivers(), "sqlite3") {
+		return nil, fmt.Errorf("no database/sql driver registered as \"sqlite3\": import one, such as github.com/mattn/go-sqlite3, in your application")
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prompt database: %w", err)
+	}
+	store, err := NewSQLPromptStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// NewSQLPromptStore creates a prompt store on an already opened database, which
+// allows using a driver registered under a different name. The prompt table is
+// created if it does not exist.
+func NewSQLPromptStore(db *sql.DB) (*SQLPromptStore, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS gollm_prompts (
+		name       TEXT NOT NULL,
+		version    TEXT NOT NULL,
+		prompt     TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (name, version)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prompt table: %w", err)
+	}
+	return &SQLPromptStore{db: db}, nil
+}
+
+// Save inserts or replaces the prompt row for name and version.
+func (s *SQLPromptStore) Save(name, version string, p *Prompt) error {
+	if err := checkPromptKey(name, "name"); err != nil {
+		return err
+	}
+	if err := checkPromptKey(version, "version"); err != nil {
+		return err
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode prompt %s@%s: %w", name, version, err)
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO gollm_prompts (name, version, prompt) VALUES (?, ?, ?)`, name, version, string(data)); err != nil {
+		return fmt.Errorf("failed to save prompt %s@%s: %w", name, version, err)
+	}
+	return nil
+}
+
+// Load reads the prompt row for name and version.
+func (s *SQLPromptStore) Load(name, version string) (*Prompt, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT prompt FROM gollm_prompts WHERE name = ? AND version = ?`, name, version).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s@%s", ErrPromptNotFound, name, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prompt %s@%s: %w", name, version, err)
+	}
+	var p Prompt
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return nil, fmt.Errorf("failed to decode prompt %s@%s: %w", name, version, err)
+	}
+	return &p, nil
+}
+
+// Versions lists the versions stored for name.
+func (s *SQLPromptStore) Versions(name string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT version FROM gollm_prompts WHERE name = ?`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions of prompt %s: %w", name, err)
+	}
+	defer rows.Close()
+	var versions []string
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to list versions of prompt %s: %w", name, err)
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list versions of prompt %s: %w", name, err)
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+	return versions, nil
+}
+
+// Close closes the underlying database.
+func (s *SQLPromptStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePromptDB is an in-memory database/sql driver that understands the
+// statements of SQLPromptStore, so the store is tested without a cgo SQLite
+// driver. It is registered as "sqlite3" to exercise NewSQLitePromptStore.
+type fakePromptDB struct {
+	mutex   sync.Mutex
+	prompts map[string]map[string]string // name -> version -> prompt JSON
+}
+
+var fakePromptDriver = &fakePromptDB{}
+
+func init() {
+	sql.Register("sqlite3", fakePromptDriver)
+}
+
+func (d *fakePromptDB) Open(dsn string) (driver.Conn, error) { return fakePromptConn{d}, nil }
+
+type fakePromptConn struct{ db *fakePromptDB }
+
+func (c fakePromptConn) Prepare(query string) (driver.Stmt, error) {
+	return fakePromptStmt{db: c.db, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+func (c fakePromptConn) Close() error { return nil }
+func (c fakePromptConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("transactions not supported")
+}
+
+type fakePromptStmt struct {
+	db    *fakePromptDB
+	query string
+}
+
+func (s fakePromptStmt) Close() error  { return nil }
+func (s fakePromptStmt) NumInput() int { return -1 }
+
+func (s fakePromptStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.db
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE IF NOT EXISTS gollm_prompts"):
+		if d.prompts == nil {
+			d.prompts = make(map[string]map[string]string)
+		}
+	case strings.HasPrefix(s.query, "INSERT OR REPLACE INTO gollm_prompts"):
+		name, version := args[0].(string), args[1].(string)
+		if d.prompts[name] == nil {
+			d.prompts[name] = make(map[string]string)
+		}
+		d.prompts[name][version] = args[2].(string)
+	default:
+		return nil, fmt.Errorf("unexpected statement: %s", s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakePromptStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.db
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	versions := d.prompts[args[0].(string)]
+	switch {
+	case strings.HasPrefix(s.query, "SELECT prompt FROM gollm_prompts"):
+		rows := &fakePromptRows{column: "prompt"}
+		if data, ok := versions[args[1].(string)]; ok {
+			rows.values = []string{data}
+		}
+		return rows, nil
+	case strings.HasPrefix(s.query, "SELECT version FROM gollm_prompts"):
+		rows := &fakePromptRows{column: "version"}
+		for version := range versions {
+			rows.values = append(rows.values, version)
+		}
+		sort.Strings(rows.values)
+		return rows, nil
+	}
+	return nil, fmt.Errorf("unexpected query: %s", s.query)
+}
+
+type fakePromptRows struct {
+	column string
+	values []string
+}
+
+func (r *fakePromptRows) Columns() []string { return []string{r.column} }
+func (r *fakePromptRows) Close() error      { return nil }
+func (r *fakePromptRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func TestSQLitePromptStore_RoundTrip(t *testing.T) {
+	store, err := NewSQLitePromptStore("file::memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	library := NewPromptLibrary(store)
+
+	require.NoError(t, library.Save("support/reply", "1.0.0", NewPrompt("礼貌地回复", WithMaxLength(80))))
+	require.NoError(t, library.Save("support/reply", "1.1.0", NewPrompt("礼貌且简短地回复", WithDirectives("不超过三句话"))))
+
+	versions, err := library.ListVersions("support/reply")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.0.0", "1.1.0"}, versions)
+
+	p, err := library.Load("support/reply", "1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "礼貌地回复", p.Input)
+	assert.Equal(t, 80, p.MaxLength)
+
+	// Saving an existing version replaces it.
+	require.NoError(t, library.Save("support/reply", "1.0.0", NewPrompt("回复")))
+	p, err = library.Load("support/reply", "1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "回复", p.Input)
+
+	_, err = library.Load("support/reply", "2.0.0")
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+	_, err = library.ListVersions("missing")
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+}
@@ -0,0 +1,183 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Kinds of provider failure. An error returned for a failed API call wraps a
+// *ProviderError, which matches one of these with errors.Is when the failure
+// is recognised:
+//
+//	if errors.Is(err, llm.ErrRateLimited) {
+//	    // back off
+//	}
+var (
+	// ErrRateLimited means the provider's rate limit or quota was exceeded.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrAuthentication means the API key is missing, invalid or lacks
+	// permission.
+	ErrAuthentication = errors.New("authentication failed")
+
+	// ErrContextLengthExceeded means the prompt and the requested tokens do not
+	// fit in the model's context window.
+	ErrContextLengthExceeded = errors.New("context length exceeded")
+
+	// ErrContentFiltered means the provider's safety system rejected the
+	// prompt.
+	ErrContentFiltered = errors.New("content filtered")
+
+	// ErrServerError means the provider failed or was overloaded.
+	ErrServerError = errors.New("server error")
+)
+
+// ProviderError is an error response from a provider's API. Use errors.As to
+// read the status code and the provider's own message:
+//
+//	var providerErr *llm.ProviderError
+//	if errors.As(err, &providerErr) {
+//	    log.Printf("%s returned %d: %s", providerErr.Provider, providerErr.StatusCode, providerErr.Message)
+//	}
+type ProviderError struct {
+	Provider   string // Name of the provider, e.g. "openai"
+	StatusCode int    // HTTP status code of the response
+	Message    string // The provider's error message, or the raw body if it has none
+	Kind       error  // One of ErrRateLimited, ErrAuthentication, ..., or nil if unrecognised
+}
+
+// Error implements the error interface.
+func (e *ProviderError) Error() string {
+	if e.Kind != nil {
+		return fmt.Sprintf("%s: %v (status %d): %s", e.Provider, e.Kind, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Unwrap returns the kind of the error, so that errors.Is matches it.
+func (e *ProviderError) Unwrap() error {
+	return e.Kind
+}
+
+// Phrases of the error messages and codes by which providers report failures
+// that share their status code with other errors, usually 400.
+var (
+	contextLengthPhrases = []string{
+		"context_length_exceeded",              // OpenAI, Azure, Groq, Mistral
+		"maximum context length",               // OpenAI-compatible servers
+		"prompt is too long",                   // Anthropic
+		"exceeds the maximum number of tokens", // Gemini
+		"too many tokens",                      // Cohere
+		"context window",                       // Ollama and others
+		"context length",
+	}
+	contentFilterPhrases = []string{
+		"content_filter",            // Azure
+		"content management policy", // Azure
+		"content_policy_violation",  // OpenAI
+		"safety",                    // Gemini
+	}
+)
+
+// newProviderError builds the error for a response of the given status code,
+// classifying it from the status code and the error object in the body.
+// Besides the OpenAI shape {"error": {"message", "type", "code"}}, the error
+// objects of Anthropic {"error": {"type", "message"}}, Gemini {"error":
+// {"status", "message"}}, Ollama {"error": "..."} and Mistral and Cohere
+// {"message": "..."} are understood.
+func newProviderError(provider string, status int, body []byte) *ProviderError {
+	message, code := parseErrorBody(body)
+	text := strings.ToLower(message + " " + code)
+	var kind error
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		kind = ErrAuthentication
+	case status == http.StatusTooManyRequests || strings.Contains(text, "rate_limit") || code == "RESOURCE_EXHAUSTED":
+		kind = ErrRateLimited
+	case status == http.StatusRequestEntityTooLarge || containsAny(text, contextLengthPhrases):
+		kind = ErrContextLengthExceeded
+	case status >= 500:
+		// Anthropic reports overload as 529.
+		kind = ErrServerError
+	case containsAny(text, contentFilterPhrases):
+		kind = ErrContentFiltered
+	}
+	return &ProviderError{Provider: provider, StatusCode: status, Message: message, Kind: kind}
+}
+
+// parseErrorBody returns the message and the most specific code or type of
+// the error object in body, or the body itself if it has none.
+func parseErrorBody(body []byte) (message, code string) {
+	var parsed struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return strings.TrimSpace(string(body)), ""
+	}
+	var object struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Code    interface{} `json:"code"`
+		Status  interface{} `json:"status"`
+	}
+	var text string
+	switch {
+	case json.Unmarshal(parsed.Error, &object) == nil && object.Message != "":
+		// Codes and statuses are strings or numbers, depending on the provider.
+		if status, ok := object.Status.(string); ok {
+			code = status
+		}
+		if c, ok := object.Code.(string); ok && c != "" {
+			code = c
+		}
+		if code == "" {
+			code = object.Type
+		}
+		return object.Message, code
+	case json.Unmarshal(parsed.Error, &text) == nil && text != "":
+		return text, ""
+	case parsed.Message != "":
+		return parsed.Message, ""
+	}
+	return strings.TrimSpace(string(body)), ""
+}
+
+func containsAny(text string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// apiError logs an error response of the provider and returns it as an
+// LLMError wrapping a *ProviderError.
+func (l *LLMImpl) apiError(status int, body []byte) *LLMError {
+	l.logger.Error("API error", "provider", l.Provider.Name(), "status", status, "body", string(body))
+	providerErr := newProviderError(l.Provider.Name(), status, body)
+	errType := ErrorTypeAPI
+	switch providerErr.Kind {
+	case ErrRateLimited:
+		errType = ErrorTypeRateLimit
+	case ErrAuthentication:
+		errType = ErrorTypeAuthentication
+	case ErrContextLengthExceeded, ErrContentFiltered:
+		errType = ErrorTypeInvalidInput
+	}
+	return NewLLMError(errType, fmt.Sprintf("API error: status code %d", status), providerErr)
+}
+
+// isRetryable reports whether a failed call may succeed if made again. Calls
+// rejected for their credentials or their prompt fail the same way every
+// time, so they are not retried whatever the retry strategy.
+func isRetryable(err error) bool {
+	return !errors.Is(err, ErrAuthentication) &&
+		!errors.Is(err, ErrContextLengthExceeded) &&
+		!errors.Is(err, ErrContentFiltered)
+}
@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestNewProviderError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		kind    error
+		message string
+	}{
+		{"openai rate limit", 429, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`, ErrRateLimited, "Rate limit reached"},
+		{"openai invalid key", 401, `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`, ErrAuthentication, "Incorrect API key provided"},
+		{"openai context length", 400, `{"error":{"message":"This model's maximum context length is 8192 tokens","type":"invalid_request_error","code":"context_length_exceeded"}}`, ErrContextLengthExceeded, "This model's maximum context length is 8192 tokens"},
+		{"azure content filter", 400, `{"error":{"message":"The response was filtered","code":"content_filter","status":400}}`, ErrContentFiltered, "The response was filtered"},
+		{"anthropic overloaded", 529, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, ErrServerError, "Overloaded"},
+		{"anthropic prompt too long", 400, `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`, ErrContextLengthExceeded, "prompt is too long: 210000 tokens > 200000 maximum"},
+		{"gemini quota", 429, `{"error":{"code":429,"message":"Quota exceeded","status":"RESOURCE_EXHAUSTED"}}`, ErrRateLimited, "Quota exceeded"},
+		{"ollama error string", 500, `{"error":"model runner crashed"}`, ErrServerError, "model runner crashed"},
+		{"mistral unauthorized", 401, `{"message":"Unauthorized","request_id":"abc"}`, ErrAuthentication, "Unauthorized"},
+		{"unrecognised", 400, `not json`, nil, "not json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newProviderError("test", tt.status, []byte(tt.body))
+			assert.Equal(t, tt.status, err.StatusCode)
+			assert.Equal(t, tt.message, err.Message)
+			if tt.kind == nil {
+				assert.Nil(t, err.Kind)
+			} else {
+				assert.ErrorIs(t, err, tt.kind)
+			}
+		})
+	}
+}
+
+func TestGenerate_ProviderErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":{"message":"Incorrect API key provided","code":"invalid_api_key"}}`))
+	}))
+	defer server.Close()
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetMaxRetries(3),
+		config.SetRetryDelay(time.Millisecond),
+	)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+
+	_, err = l.Generate(context.Background(), NewPrompt("你好"))
+	require.ErrorIs(t, err, ErrAuthentication)
+	assert.Equal(t, 1, calls, "authentication failures are not retried")
+
+	var providerErr *ProviderError
+	require.True(t, errors.As(err, &providerErr))
+	assert.Equal(t, "openai", providerErr.Provider)
+	assert.Equal(t, http.StatusUnauthorized, providerErr.StatusCode)
+	var llmErr *LLMError
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, ErrorTypeAuthentication, llmErr.Type)
+}
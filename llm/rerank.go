@@ -0,0 +1,165 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/yockii/gollm_cn/providers"
+)
+
+// ErrProviderDoesNotSupportRerank is returned by Rerank for providers without
+// a rerank endpoint.
+var ErrProviderDoesNotSupportRerank = errors.New("provider does not support reranking")
+
+// RankedDocument is a document ranked by Rerank.
+type RankedDocument struct {
+	Index int     // Index of the document in the documents passed to Rerank
+	Text  string  // The document
+	Score float64 // Relevance to the query, from 0 to 1
+}
+
+// Reranker is implemented by LLMs that can rank documents by their relevance
+// to a query. It is not part of the LLM interface; check for it with a type
+// assertion.
+type Reranker interface {
+	// Rerank returns the topN documents most relevant to query, most
+	// relevant first. Returns ErrProviderDoesNotSupportRerank if the provider
+	// has no rerank endpoint.
+	Rerank(ctx context.Context, query string, documents []string, topN int) ([]RankedDocument, error)
+}
+
+// rerank calls Rerank on l if it is a Reranker, for LLMs wrapping another.
+func rerank(ctx context.Context, l LLM, query string, documents []string, topN int) ([]RankedDocument, error) {
+	reranker, ok := l.(Reranker)
+	if !ok {
+		return nil, NewLLMError(ErrorTypeUnsupported, "LLM cannot rerank documents", ErrProviderDoesNotSupportRerank)
+	}
+	return reranker.Rerank(ctx, query, documents, topN)
+}
+
+// CohereRerank ranks documents by their relevance to query with Cohere's
+// rerank endpoint, returning the topN most relevant, most relevant first, so
+// that a RAG pipeline can pass only the best chunks to a generation prompt.
+// l must use the Cohere provider; a topN of 0 keeps every document.
+//
+// Returns:
+//   - The ranked documents, sorted by descending score
+//   - ErrProviderDoesNotSupportRerank, as an ErrorTypeUnsupported LLMError,
+//     if l cannot rerank
+//   - Other error types as per Rerank
+//
+// Example:
+//
+//	ranked, err := CohereRerank(ctx, cohere, "如何申请退款？", chunks, 3)
+//	for _, doc := range ranked {
+//	    context += doc.Text + "\n\n"
+//	}
+func CohereRerank(ctx context.Context, l LLM, query string, documents []string, topN int) ([]RankedDocument, error) {
+	return rerank(ctx, l, query, documents, topN)
+}
+
+// Rerank returns the topN documents most relevant to query, most relevant
+// first, ranked by the provider's rerank endpoint with its rerank model
+// (rerank-v3.5 for Cohere), not the model used for generation. A topN of 0,
+// or more than the number of documents, keeps every document. Failed
+// requests are retried like Generate calls.
+//
+// Returns:
+//   - The ranked documents, sorted by descending score
+//   - ErrorTypeInvalidInput if query or documents are empty or topN is negative
+//   - ErrProviderDoesNotSupportRerank, as an ErrorTypeUnsupported LLMError,
+//     if the provider has no rerank endpoint
+//   - Other error types as per Generate
+func (l *LLMImpl) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RankedDocument, error) {
+	if query == "" {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "query cannot be empty", nil)
+	}
+	if len(documents) == 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "no documents to rerank", nil)
+	}
+	if topN < 0 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("top n must not be negative, got %d", topN), nil)
+	}
+	if topN == 0 || topN > len(documents) {
+		topN = len(documents)
+	}
+	reranker, ok := l.Provider.(providers.Reranker)
+	if !ok {
+		return nil, NewLLMError(ErrorTypeUnsupported, fmt.Sprintf("provider %s cannot rerank documents", l.Provider.Name()), ErrProviderDoesNotSupportRerank)
+	}
+
+	strategy := l.retryStrategy()
+	var lastErr error
+	attempt := 1
+	for ; ; attempt++ {
+		ranked, err := l.attemptRerank(ctx, reranker, query, documents, topN)
+		if err == nil {
+			return ranked, nil
+		}
+		lastErr = err
+		l.logger.Warn("Rerank attempt failed", "error", err, "attempt", attempt)
+		if !isRetryable(err) {
+			break
+		}
+		delay, retry := strategy.NextDelay(attempt, err)
+		if !retry {
+			break
+		}
+		if err := l.wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("failed to rerank after %d attempts: %w", attempt, lastErr)
+}
+
+// attemptRerank makes a single rerank request.
+func (l *LLMImpl) attemptRerank(ctx context.Context, reranker providers.Reranker, query string, documents []string, topN int) ([]RankedDocument, error) {
+	reqBody, err := reranker.PrepareRerankRequest(query, documents, topN)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to prepare rerank request", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", reranker.RerankEndpoint(), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to create request", err)
+	}
+	for k, v := range l.Provider.Headers() {
+		req.Header.Set(k, v)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeRequest, "failed to send request", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "failed to read response body", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, l.apiError(resp.StatusCode, body)
+	}
+
+	results, err := reranker.ParseRerankResponse(body)
+	if err != nil {
+		return nil, NewLLMError(ErrorTypeResponse, "failed to parse rerank response", err)
+	}
+	ranked := make([]RankedDocument, 0, len(results))
+	for _, result := range results {
+		if result.Index < 0 || result.Index >= len(documents) {
+			return nil, NewLLMError(ErrorTypeResponse, fmt.Sprintf("rerank result for document %d of %d", result.Index, len(documents)), nil)
+		}
+		ranked = append(ranked, RankedDocument{Index: result.Index, Text: documents[result.Index], Score: result.Score})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	if len(ranked) > topN {
+		ranked = ranked[:topN]
+	}
+	return ranked, nil
+}
@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestCohereRerank(t *testing.T) {
+	l, lastRequest := newRespondingLLM(t, "cohere", "command-r-plus",
+		`{"results":[{"index":2,"relevance_score":0.31},{"index":0,"relevance_score":0.92}]}`)
+	ctx := context.Background()
+	documents := []string{"退款需在收货后七天内申请。", "我们的门店周一休息。", "退款会在三个工作日内到账。"}
+
+	ranked, err := CohereRerank(ctx, l, "如何申请退款？", documents, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []RankedDocument{
+		{Index: 0, Text: "退款需在收货后七天内申请。", Score: 0.92},
+		{Index: 2, Text: "退款会在三个工作日内到账。", Score: 0.31},
+	}, ranked)
+	req := lastRequest()
+	assert.Equal(t, "rerank-v3.5", req["model"])
+	assert.Equal(t, "如何申请退款？", req["query"])
+	assert.Equal(t, float64(2), req["top_n"])
+
+	_, err = CohereRerank(ctx, l, "如何申请退款？", documents, 0)
+	require.NoError(t, err)
+	assert.Equal(t, float64(3), lastRequest()["top_n"], "0 keeps every document")
+
+	memory, err := NewLLMWithMemory(l, 1000, "command-r-plus", utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+	ranked, err = CohereRerank(ctx, memory, "如何申请退款？", documents, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []RankedDocument{{Index: 0, Text: "退款需在收货后七天内申请。", Score: 0.92}}, ranked)
+
+	var llmErr *LLMError
+	_, err = CohereRerank(ctx, l, "", documents, 1)
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	_, err = CohereRerank(ctx, l, "如何申请退款？", documents, -1)
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+	_, err = CohereRerank(ctx, l, "如何申请退款？", documents[:1], 1)
+	assert.ErrorContains(t, err, "rerank result for document 2 of 1")
+}
+
+func TestCohereRerank_Unsupported(t *testing.T) {
+	l, _ := newCapturingLLM(t, config.SetProvider("anthropic"), config.SetAPIKey("test-key"))
+
+	_, err := CohereRerank(context.Background(), l, "如何申请退款？", []string{"退款需在收货后七天内申请。"}, 1)
+	assert.ErrorIs(t, err, ErrProviderDoesNotSupportRerank)
+	var llmErr *LLMError
+	require.ErrorAs(t, err, &llmErr)
+	assert.Equal(t, ErrorTypeUnsupported, llmErr.Type)
+}
+
+func TestStream_Cohere(t *testing.T) {
+	l, lastRequest := newRespondingLLM(t, "cohere", "command-r-plus", "event: message-start\n"+
+		"data: {\"type\":\"message-start\",\"delta\":{\"message\":{\"role\":\"assistant\"}}}\n\n"+
+		"event: content-delta\n"+
+		"data: {\"type\":\"content-delta\",\"index\":0,\"delta\":{\"message\":{\"content\":{\"text\":\"你\"}}}}\n\n"+
+		"event: content-delta\n"+
+		"data: {\"type\":\"content-delta\",\"index\":0,\"delta\":{\"message\":{\"content\":{\"text\":\"好！\"}}}}\n\n"+
+		"event: message-end\n"+
+		"data: {\"type\":\"message-end\",\"delta\":{\"finish_reason\":\"COMPLETE\"}}\n\n")
+	ctx := context.Background()
+
+	stream, err := l.Stream(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+	defer stream.Close()
+	var text string
+	for {
+		token, err := stream.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		text += token.Text
+	}
+	assert.Equal(t, "你好！", text)
+	assert.Equal(t, true, lastRequest()["stream"])
+}
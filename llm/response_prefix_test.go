@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// newRespondingLLM returns an LLM of provider whose API answers with body,
+// and a function returning the last request it received.
+func newRespondingLLM(t *testing.T, provider, model, body string) (LLM, func() map[string]interface{}) {
+	var last map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request, _ := io.ReadAll(r.Body)
+		last = nil
+		require.NoError(t, json.Unmarshal(request, &last))
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider(provider),
+		config.SetModel(model),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetMaxRetries(0),
+		config.SetTimeout(5*time.Second),
+	)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+	return l, func() map[string]interface{} { return last }
+}
+
+func TestWithResponsePrefix_Anthropic(t *testing.T) {
+	l, lastRequest := newRespondingLLM(t, "anthropic", "claude-3-5-haiku-latest",
+		`{"content":[{"type":"text","text":" 1. 洗菜\n2. 炒菜"}],"usage":{"input_tokens":20,"output_tokens":8}}`)
+
+	response, err := l.Generate(context.Background(), NewPrompt("怎么做番茄炒蛋？", WithResponsePrefix("步骤如下： ")))
+	require.NoError(t, err)
+	assert.Equal(t, "1. 洗菜\n2. 炒菜", response, "the prefix's trailing space is not sent, so the leading space is dropped")
+
+	req := lastRequest()
+	messages := req["messages"].([]interface{})
+	require.Len(t, messages, 2)
+	assert.Equal(t, map[string]interface{}{"role": "assistant", "content": "步骤如下："}, messages[1])
+	user := messages[0].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})["text"]
+	assert.NotContains(t, user, "步骤如下")
+	assert.NotContains(t, req, "response_prefix")
+}
+
+func TestWithResponsePrefix_OtherProviders(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"echoed", `{\"a\": 1}`, `\"a\": 1}`},
+		{"echoed after whitespace", `\n{\"a\": 1}`, `\"a\": 1}`},
+		{"not echoed", `\"a\": 1}`, `\"a\": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, lastRequest := newRespondingLLM(t, "openai", "gpt-4o",
+				`{"choices":[{"message":{"content":"`+tt.content+`"}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`)
+
+			response, err := l.Generate(context.Background(), NewPrompt("用 JSON 回答", WithResponsePrefix("{")))
+			require.NoError(t, err)
+			var want string
+			require.NoError(t, json.Unmarshal([]byte(`"`+tt.want+`"`), &want))
+			assert.Equal(t, want, response)
+
+			messages := lastRequest()["messages"].([]interface{})
+			user := messages[len(messages)-1].(map[string]interface{})["content"].(string)
+			assert.Contains(t, user, "Start your response with exactly the following text, with nothing before it:\n{")
+		})
+	}
+}
+
+func TestWithResponsePrefix_RejectedWithSchema(t *testing.T) {
+	l, _ := newRespondingLLM(t, "openai", "gpt-4o", `{"choices":[{"message":{"content":"{}"}}]}`)
+	_, err := l.GenerateWithSchema(context.Background(), NewPrompt("用 JSON 回答", WithResponsePrefix("{")), map[string]interface{}{"type": "object"})
+	assert.ErrorContains(t, err, "response prefix cannot be combined with a JSON schema")
+}
+
+func TestResponseAfterPrefix(t *testing.T) {
+	assert.Equal(t, "abc", responseAfterPrefix("abc", "", false))
+	assert.Equal(t, " x", responseAfterPrefix(" x", "Answer:", true))
+	assert.Equal(t, "x", responseAfterPrefix("Answer: x", "Answer: ", false))
+	assert.Equal(t, "x", responseAfterPrefix("Answer:\nx", "Answer: ", false))
+	assert.Equal(t, "x", responseAfterPrefix("x", "Answer: ", false))
+}
@@ -0,0 +1,76 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/providers"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// recordingStrategy retries a fixed number of times and records each call.
+type recordingStrategy struct {
+	maxRetries int
+	attempts   []int
+}
+
+func (r *recordingStrategy) NextDelay(attempt int, err error) (time.Duration, bool) {
+	r.attempts = append(r.attempts, attempt)
+	return time.Millisecond, attempt <= r.maxRetries
+}
+
+func newFailingLLM(t *testing.T, failures int, opts ...config.ConfigOption) (LLM, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg,
+		config.SetProvider("openai"),
+		config.SetAPIKey("test-key"),
+		config.SetEndpoint(server.URL),
+		config.SetRetryDelay(time.Millisecond),
+	)
+	config.ApplyOptions(cfg, opts...)
+	l, err := NewLLM(cfg, utils.NewLogger(utils.LogLevelOff), providers.NewProviderRegistry())
+	require.NoError(t, err)
+	return l, &calls
+}
+
+func TestGenerate_RetryStrategy(t *testing.T) {
+	strategy := &recordingStrategy{maxRetries: 2}
+	l, calls := newFailingLLM(t, 2, config.SetRetryStrategy(strategy))
+
+	response, err := l.Generate(context.Background(), NewPrompt("你好"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response)
+	assert.Equal(t, 3, *calls)
+	assert.Equal(t, []int{1, 2}, strategy.attempts)
+
+	strategy = &recordingStrategy{maxRetries: 1}
+	l, calls = newFailingLLM(t, 5, config.SetRetryStrategy(strategy))
+	_, err = l.Generate(context.Background(), NewPrompt("你好"))
+	assert.ErrorContains(t, err, "after 2 attempts")
+	assert.Equal(t, 2, *calls)
+}
+
+func TestGenerate_MaxRetriesFormConstantRetry(t *testing.T) {
+	l, calls := newFailingLLM(t, 5, config.SetMaxRetries(1))
+
+	_, err := l.Generate(context.Background(), NewPrompt("你好"))
+	assert.ErrorContains(t, err, "after 2 attempts")
+	assert.Equal(t, 2, *calls)
+}
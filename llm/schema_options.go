@@ -0,0 +1,170 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// SchemaOption configures the JSON schema generated by GenerateJSONSchema and
+// Prompt.GenerateJSONSchema.
+type SchemaOption func(*schemaConfig)
+
+// schemaConfig holds the settings of SchemaOptions: the reflector used for
+// prompts and the properties added to the generated schema.
+type schemaConfig struct {
+	reflector *jsonschema.Reflector
+	fields    []fieldOverride
+	defs      map[string]interface{}
+}
+
+// fieldOverride changes the schema of one field, named by its JSON path.
+type fieldOverride struct {
+	field  string
+	change func(schema map[string]interface{})
+}
+
+func newSchemaConfig(opts []SchemaOption) *schemaConfig {
+	cfg := &schemaConfig{reflector: &jsonschema.Reflector{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithExpandedStruct enables or disables detailed structure expansion
+// in the generated JSON schema of a prompt.
+//
+// Parameters:
+//   - expanded: Whether to expand nested structures
+func WithExpandedStruct(expanded bool) SchemaOption {
+	return func(c *schemaConfig) {
+		c.reflector.ExpandedStruct = expanded
+	}
+}
+
+// WithFieldDescription sets the description of a field, which guides the LLM
+// filling it in. Fields are named by their JSON name; fields of nested
+// objects, or of the objects in an array, are named by a path such as
+// "address.city" or "items.price".
+//
+// Example:
+//
+//	schema, err := GenerateJSONSchema(Order{}, WithFieldDescription("items.price", "单价，单位为元"))
+func WithFieldDescription(field, description string) SchemaOption {
+	return withFieldOverride(field, func(schema map[string]interface{}) {
+		schema["description"] = description
+	})
+}
+
+// WithFieldEnum restricts a field to the given values, named as for
+// WithFieldDescription. It replaces an enum from the field's validate tag.
+//
+// Example:
+//
+//	schema, err := GenerateJSONSchema(Ticket{}, WithFieldEnum("priority", "低", "中", "高"))
+func WithFieldEnum(field string, values ...interface{}) SchemaOption {
+	return withFieldOverride(field, func(schema map[string]interface{}) {
+		schema["enum"] = values
+	})
+}
+
+// WithFieldExample adds an example value of a field, named as for
+// WithFieldDescription, to its "examples". It may be given several times for
+// the same field.
+func WithFieldExample(field string, example interface{}) SchemaOption {
+	return withFieldOverride(field, func(schema map[string]interface{}) {
+		examples, _ := schema["examples"].([]interface{})
+		schema["examples"] = append(examples, example)
+	})
+}
+
+// WithDefs adds definitions to the "$defs" of the schema, which fields can
+// refer to with "$ref": "#/$defs/name". Definitions of the same name replace
+// generated ones.
+func WithDefs(defs map[string]interface{}) SchemaOption {
+	return func(c *schemaConfig) {
+		if c.defs == nil {
+			c.defs = make(map[string]interface{}, len(defs))
+		}
+		for name, def := range defs {
+			c.defs[name] = def
+		}
+	}
+}
+
+func withFieldOverride(field string, change func(map[string]interface{})) SchemaOption {
+	return func(c *schemaConfig) {
+		c.fields = append(c.fields, fieldOverride{field: field, change: change})
+	}
+}
+
+// hasOverrides reports whether the options change the generated schema
+// beyond the reflector's settings.
+func (c *schemaConfig) hasOverrides() bool {
+	return len(c.fields) > 0 || len(c.defs) > 0
+}
+
+// apply changes schema as the options ask, failing if one names a field the
+// schema does not have.
+func (c *schemaConfig) apply(schema map[string]interface{}) error {
+	for _, override := range c.fields {
+		field, err := schemaField(schema, override.field)
+		if err != nil {
+			return err
+		}
+		override.change(field)
+	}
+	if len(c.defs) > 0 {
+		defs, _ := schema["$defs"].(map[string]interface{})
+		if defs == nil {
+			defs = make(map[string]interface{}, len(c.defs))
+		}
+		for name, def := range c.defs {
+			defs[name] = def
+		}
+		schema["$defs"] = defs
+	}
+	return nil
+}
+
+// schemaField returns the schema of the field at path, a dot-separated list
+// of JSON names, following arrays to their items and references to the
+// definitions in "$defs".
+func schemaField(root map[string]interface{}, path string) (map[string]interface{}, error) {
+	node := root
+	for _, name := range strings.Split(path, ".") {
+		node = resolveSchemaRef(root, node)
+		for node["type"] == "array" {
+			items, ok := node["items"].(map[string]interface{})
+			if !ok {
+				break
+			}
+			node = resolveSchemaRef(root, items)
+		}
+		properties, _ := node["properties"].(map[string]interface{})
+		field, ok := properties[name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("schema has no field %q", path)
+		}
+		node = field
+	}
+	return node, nil
+}
+
+// resolveSchemaRef returns the definition node refers to, if it is a
+// reference into the "$defs" of root, and node otherwise.
+func resolveSchemaRef(root, node map[string]interface{}) map[string]interface{} {
+	ref, _ := node["$ref"].(string)
+	name, ok := strings.CutPrefix(ref, "#/$defs/")
+	if !ok {
+		return node
+	}
+	defs, _ := root["$defs"].(map[string]interface{})
+	if def, ok := defs[name].(map[string]interface{}); ok {
+		return def
+	}
+	return node
+}
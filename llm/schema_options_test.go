@@ -0,0 +1,65 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type schemaOrderItem struct {
+	Name  string  `json:"name" validate:"required"`
+	Price float64 `json:"price"`
+}
+
+type schemaOrder struct {
+	Priority string            `json:"priority" validate:"enum=low|high"`
+	Items    []schemaOrderItem `json:"items"`
+}
+
+func TestGenerateJSONSchema_Options(t *testing.T) {
+	data, err := GenerateJSONSchema(schemaOrder{},
+		WithFieldDescription("items.price", "单价，单位为元"),
+		WithFieldEnum("priority", "低", "中", "高"),
+		WithFieldExample("items.name", "苹果"),
+		WithFieldExample("items.name", "香蕉"),
+		WithDefs(map[string]interface{}{"money": map[string]interface{}{"type": "number", "minimum": 0}}),
+	)
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	properties := schema["properties"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"低", "中", "高"}, properties["priority"].(map[string]interface{})["enum"], "replaces the tag's enum")
+	item := properties["items"].(map[string]interface{})["items"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "number", "description": "单价，单位为元"}, item["price"])
+	assert.Equal(t, []interface{}{"苹果", "香蕉"}, item["name"].(map[string]interface{})["examples"])
+	assert.Equal(t, map[string]interface{}{"money": map[string]interface{}{"type": "number", "minimum": float64(0)}}, schema["$defs"])
+
+	_, err = GenerateJSONSchema(schemaOrder{}, WithFieldDescription("items.cost", "成本"))
+	assert.EqualError(t, err, `schema has no field "items.cost"`)
+
+	plain, err := GenerateJSONSchema(schemaOrder{})
+	require.NoError(t, err)
+	assert.NotContains(t, string(plain), "description")
+}
+
+func TestPromptGenerateJSONSchema_Options(t *testing.T) {
+	p := NewPrompt("你好")
+	plain, err := p.GenerateJSONSchema()
+	require.NoError(t, err)
+
+	data, err := p.GenerateJSONSchema(WithFieldDescription("input", "用户的问题"))
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &schema))
+	input := schema["$defs"].(map[string]interface{})["Prompt"].(map[string]interface{})["properties"].(map[string]interface{})["input"]
+	assert.Equal(t, "用户的问题", input.(map[string]interface{})["description"], "references are followed")
+	assert.NotContains(t, string(plain), "用户的问题")
+
+	data, err = p.GenerateJSONSchema(WithExpandedStruct(true), WithFieldDescription("input", "用户的问题"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &schema))
+	assert.Equal(t, "用户的问题", schema["properties"].(map[string]interface{})["input"].(map[string]interface{})["description"])
+}
@@ -0,0 +1,215 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// defaultSemanticCacheEntries is the number of responses kept by a semantic
+// cache; the oldest are evicted first.
+const defaultSemanticCacheEntries = 1000
+
+// LLMWithSemanticCache wraps an LLM and answers prompts whose input is
+// semantically equivalent to that of an earlier prompt with the earlier
+// response, without calling the provider. Inputs are compared by the cosine
+// similarity of their embeddings; the rest of the prompt, the call options and
+// the schema must be identical. Entries keep the embedding of the input and
+// the response, and the oldest of them are evicted once the cache holds 1000.
+// Failed calls are not cached, and a failed embedding only skips the cache.
+type LLMWithSemanticCache struct {
+	LLM
+	embed      utils.EmbeddingFunc
+	threshold  float64
+	maxEntries int
+	entries    []semanticCacheEntry
+	mutex      sync.Mutex
+	logger     utils.Logger
+}
+
+// semanticCacheEntry is a cached response. key identifies everything the
+// response depends on except the prompt input.
+type semanticCacheEntry struct {
+	key       string
+	embedding []float64
+	response  string
+}
+
+// NewLLMWithSemanticCache creates an LLM that caches the responses of base,
+// see LLMWithSemanticCache.
+//
+// Parameters:
+//   - base: The LLM answering prompts not found in the cache
+//   - embed: Returns the embedding of a prompt input
+//   - threshold: The cosine similarity, in (0, 1], from which inputs match
+//   - logger: Logger for cache hits and embedding failures
+//
+// Returns:
+//   - The caching LLM
+//   - ErrorTypeInvalidInput if embed is nil or threshold is out of range
+func NewLLMWithSemanticCache(base LLM, embed utils.EmbeddingFunc, threshold float64, logger utils.Logger) (*LLMWithSemanticCache, error) {
+	if embed == nil {
+		return nil, NewLLMError(ErrorTypeInvalidInput, "semantic cache requires an embedding function", nil)
+	}
+	if threshold <= 0 || threshold > 1 {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("semantic cache threshold must be in (0, 1], got %v", threshold), nil)
+	}
+	return &LLMWithSemanticCache{
+		LLM:        base,
+		embed:      embed,
+		threshold:  threshold,
+		maxEntries: defaultSemanticCacheEntries,
+		logger:     logger,
+	}, nil
+}
+
+// Generate returns the cached response of an equivalent prompt, or generates
+// and caches the response.
+func (l *LLMWithSemanticCache) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, error) {
+	return l.cached(ctx, prompt, nil, opts, func() (string, error) {
+		return l.LLM.Generate(ctx, prompt, opts...)
+	})
+}
+
+// GenerateWithSchema returns the cached response of an equivalent prompt with
+// the same schema, or generates and caches the response.
+func (l *LLMWithSemanticCache) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema interface{}, opts ...GenerateOption) (string, error) {
+	return l.cached(ctx, prompt, schema, opts, func() (string, error) {
+		return l.LLM.GenerateWithSchema(ctx, prompt, schema, opts...)
+	})
+}
+
+// Embed forwards to the wrapped LLM if it is an Embedder. Embeddings are not
+// cached.
+func (l *LLMWithSemanticCache) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	return embed(ctx, l.LLM, inputs)
+}
+
+// Moderate forwards to the wrapped LLM if it is a Moderator. Verdicts are not
+// cached.
+func (l *LLMWithSemanticCache) Moderate(ctx context.Context, text string) (*ModerationResult, error) {
+	return moderate(ctx, l.LLM, text)
+}
+
+// Rerank forwards to the wrapped LLM if it is a Reranker. Rankings are not cached.
+func (l *LLMWithSemanticCache) Rerank(ctx context.Context, query string, documents []string, topN int) ([]RankedDocument, error) {
+	return rerank(ctx, l.LLM, query, documents, topN)
+}
+
+// GetLastLogprobs forwards to the wrapped LLM if it is a LogprobsReporter.
+// Prompts asking for logprobs are never answered from the cache.
+func (l *LLMWithSemanticCache) GetLastLogprobs() []TokenLogprob {
+	return lastLogprobs(l.LLM)
+}
+
+// ClearCache removes all cached responses.
+func (l *LLMWithSemanticCache) ClearCache() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = nil
+}
+
+// cached looks up the response of prompt, calling generate on a miss.
+func (l *LLMWithSemanticCache) cached(ctx context.Context, prompt *Prompt, schema interface{}, opts []GenerateOption, generate func() (string, error)) (string, error) {
+	if prompt.Logprobs != nil {
+		// Cached responses have no logprobs, so prompts asking for them bypass the cache.
+		return generate()
+	}
+	key, err := semanticCacheKey(prompt, schema, opts)
+	if err != nil {
+		l.logger.Warn("Prompt not cacheable, skipping semantic cache", "error", err)
+		return generate()
+	}
+	embedding, err := l.embed(ctx, prompt.Input)
+	if err != nil {
+		l.logger.Warn("Failed to embed prompt, skipping semantic cache", "error", err)
+		return generate()
+	}
+	if response, similarity, ok := l.lookup(key, embedding); ok {
+		l.logger.Debug("Semantic cache hit", "similarity", similarity)
+		return response, nil
+	}
+
+	response, err := generate()
+	if err != nil {
+		return "", err
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.entries) >= l.maxEntries {
+		l.entries = l.entries[1:]
+	}
+	l.entries = append(l.entries, semanticCacheEntry{key: key, embedding: embedding, response: response})
+	return response, nil
+}
+
+// lookup returns the response of the most similar cached entry with the
+// given key, if its similarity reaches the threshold.
+func (l *LLMWithSemanticCache) lookup(key string, embedding []float64) (string, float64, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	best, bestSimilarity := -1, l.threshold
+	for i, entry := range l.entries {
+		if entry.key != key {
+			continue
+		}
+		if similarity := cosineSimilarity(entry.embedding, embedding); similarity >= bestSimilarity {
+			best, bestSimilarity = i, similarity
+		}
+	}
+	if best < 0 {
+		return "", 0, false
+	}
+	return l.entries[best].response, bestSimilarity, true
+}
+
+// semanticCacheKey identifies a call up to the input of its prompt: the rest
+// of the prompt, the schema and the resolved call options.
+func semanticCacheKey(prompt *Prompt, schema interface{}, opts []GenerateOption) (string, error) {
+	config := &GenerateConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	withoutInput := *prompt
+	withoutInput.Input = ""
+	// NewPrompt also sends the input as a user message.
+	withoutInput.Messages = make([]PromptMessage, len(prompt.Messages))
+	for i, m := range prompt.Messages {
+		if m.Role == "user" && m.Content == prompt.Input {
+			m.Content = ""
+		}
+		withoutInput.Messages[i] = m
+	}
+	key, err := json.Marshal(struct {
+		Prompt Prompt
+		Schema interface{}
+		Config *GenerateConfig
+	}{withoutInput, schema, config})
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
+
+// cosineSimilarity returns the cosine similarity of a and b, or 0 if their
+// lengths differ or either is zero.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// countingLLM answers every prompt with its input and counts the calls.
+type countingLLM struct {
+	LLM
+	calls int
+}
+
+func (c *countingLLM) Generate(ctx context.Context, prompt *Prompt, opts ...GenerateOption) (string, error) {
+	c.calls++
+	return "answer to " + prompt.Input, nil
+}
+
+func (c *countingLLM) GenerateWithSchema(ctx context.Context, prompt *Prompt, schema interface{}, opts ...GenerateOption) (string, error) {
+	c.calls++
+	return `{"answer":"` + prompt.Input + `"}`, nil
+}
+
+// fakeEmbeddings embeds the texts of the test as fixed vectors.
+func fakeEmbeddings(ctx context.Context, text string) ([]float64, error) {
+	switch text {
+	case "怎么退货？", "如何退货？":
+		return []float64{1, 0.1, 0}, nil
+	case "怎么换货？":
+		return []float64{0.6, 0.8, 0}, nil
+	}
+	return nil, errors.New("no embedding")
+}
+
+func TestLLMWithSemanticCache(t *testing.T) {
+	base := &countingLLM{}
+	l, err := NewLLMWithSemanticCache(base, fakeEmbeddings, 0.95, utils.NewLogger(utils.LogLevelOff))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	response, err := l.Generate(ctx, NewPrompt("怎么退货？"))
+	require.NoError(t, err)
+	assert.Equal(t, "answer to 怎么退货？", response)
+
+	response, err = l.Generate(ctx, NewPrompt("如何退货？"))
+	require.NoError(t, err)
+	assert.Equal(t, "answer to 怎么退货？", response, "an equivalent input hits the cache")
+	assert.Equal(t, 1, base.calls)
+
+	_, err = l.Generate(ctx, NewPrompt("怎么换货？"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, base.calls, "a different question misses")
+
+	_, err = l.Generate(ctx, NewPrompt("如何退货？", WithDirectives("用英文回答")))
+	require.NoError(t, err)
+	_, err = l.Generate(ctx, NewPrompt("如何退货？"), WithTemperature(0))
+	require.NoError(t, err)
+	_, err = l.GenerateWithSchema(ctx, NewPrompt("如何退货？"), map[string]interface{}{"type": "object"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, base.calls, "other directives, options or a schema miss")
+
+	_, err = l.Generate(ctx, NewPrompt("没有向量的问题"))
+	require.NoError(t, err)
+	assert.Equal(t, 6, base.calls, "an embedding failure skips the cache")
+
+	l.ClearCache()
+	_, err = l.Generate(ctx, NewPrompt("如何退货？"))
+	require.NoError(t, err)
+	assert.Equal(t, 7, base.calls)
+}
+
+func TestNewLLMWithSemanticCache_Validation(t *testing.T) {
+	_, err := NewLLMWithSemanticCache(&countingLLM{}, nil, 0.9, utils.NewLogger(utils.LogLevelOff))
+	assert.ErrorContains(t, err, "requires an embedding function")
+	_, err = NewLLMWithSemanticCache(&countingLLM{}, fakeEmbeddings, 1.5, utils.NewLogger(utils.LogLevelOff))
+	assert.ErrorContains(t, err, "threshold must be in (0, 1]")
+}
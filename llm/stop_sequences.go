@@ -0,0 +1,42 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+// stopSequenceLimiter is implemented by providers whose API limits the number
+// of stop sequences of a request. Providers without it accept any number.
+type stopSequenceLimiter interface {
+	MaxStopSequences() int
+}
+
+// applyStopSequences resolves the stop sequences of a call and checks them.
+// The call's WithStopSequences take precedence over the prompt's (see
+// WithPromptStopSequences), which are copied into config; without either, the
+// client's stop sequences (see config.SetStopSequences) are sent and checked.
+//
+// When there are more sequences than the provider accepts, only the first
+// ones are sent and a warning is logged.
+//
+// Returns:
+//   - ErrorTypeInvalidInput if a sequence is empty
+func (l *LLMImpl) applyStopSequences(prompt *Prompt, config *GenerateConfig) error {
+	if len(config.StopSequences) == 0 {
+		config.StopSequences = prompt.StopSequences
+	}
+	sequences := config.StopSequences
+	if len(sequences) == 0 && l.config != nil {
+		sequences = l.config.StopSequences
+	}
+	for _, sequence := range sequences {
+		if sequence == "" {
+			return NewLLMError(ErrorTypeInvalidInput, "stop sequences cannot be empty", nil)
+		}
+	}
+	limiter, ok := l.Provider.(stopSequenceLimiter)
+	if !ok {
+		return nil
+	}
+	if max := limiter.MaxStopSequences(); len(sequences) > max {
+		l.logger.Warn("Too many stop sequences for provider, sending the first ones", "provider", l.Provider.Name(), "given", len(sequences), "max", max, "dropped", sequences[max:])
+		config.StopSequences = sequences[:max]
+	}
+	return nil
+}
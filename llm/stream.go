@@ -5,7 +5,8 @@ import (
 	"bytes"
 	"context"
 	"io"
-	"time"
+
+	"github.com/yockii/gollm_cn/utils"
 )
 
 // StreamToken represents a single token from the streaming response.
@@ -44,44 +45,28 @@ type StreamConfig struct {
 
 	// RetryStrategy defines how to handle stream interruptions
 	RetryStrategy RetryStrategy
-}
-
-// RetryStrategy defines how to handle stream interruptions.
-type RetryStrategy interface {
-	// ShouldRetry determines if a retry should be attempted.
-	ShouldRetry(error) bool
 
-	// NextDelay returns the delay before the next retry.
-	NextDelay() time.Duration
-
-	// Reset resets the retry state.
-	Reset()
+	// GenerateOptions are applied to the streamed call as to a Generate call
+	GenerateOptions []GenerateOption
 }
 
-// DefaultRetryStrategy implements a simple exponential backoff strategy.
-type DefaultRetryStrategy struct {
-	MaxRetries  int
-	InitialWait time.Duration
-	MaxWait     time.Duration
-	attempts    int
-}
-
-func (s *DefaultRetryStrategy) ShouldRetry(err error) bool {
-	return s.attempts < s.MaxRetries
-}
-
-func (s *DefaultRetryStrategy) NextDelay() time.Duration {
-	s.attempts++
-	delay := s.InitialWait * time.Duration(1<<uint(s.attempts-1))
-	if delay > s.MaxWait {
-		delay = s.MaxWait
+// WithStreamGenerateOptions applies generate options, such as
+// WithTemperature or WithAutoTruncate, to a streamed call. Structured output
+// options are rejected, since a streamed response cannot be validated.
+//
+// Example:
+//
+//	stream, err := llm.Stream(ctx, prompt, WithStreamGenerateOptions(WithTemperature(0.2), WithStopSequences("END")))
+func WithStreamGenerateOptions(opts ...GenerateOption) StreamOption {
+	return func(c *StreamConfig) {
+		c.GenerateOptions = append(c.GenerateOptions, opts...)
 	}
-	return delay
 }
 
-func (s *DefaultRetryStrategy) Reset() {
-	s.attempts = 0
-}
+// RetryStrategy decides whether and when a failed call or interrupted stream
+// is retried. See utils.ConstantRetry, utils.ExponentialBackoff and
+// utils.JitteredExponentialBackoff.
+type RetryStrategy = utils.RetryStrategy
 
 // SSEDecoder handles Server-Sent Events (SSE) streaming
 type SSEDecoder struct {
@@ -0,0 +1,180 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateFile is the format of a *.yaml template file.
+type templateFile struct {
+	Description  string            `yaml:"description"`
+	Extends      string            `yaml:"extends"`
+	Metadata     map[string]string `yaml:"metadata"`
+	SystemPrompt string            `yaml:"system_prompt"`
+	Context      string            `yaml:"context"`
+	Directives   []string          `yaml:"directives"`
+	Examples     []string          `yaml:"examples"`
+	Output       string            `yaml:"output"`
+	MaxLength    int               `yaml:"max_length"`
+	Template     string            `yaml:"template"`
+}
+
+// LoadPromptTemplateFile reads a template from a file, named after the file
+// without its extension. A *.tmpl file holds the template text, with the
+// extends and metadata comments described at TemplateRegistry.LoadDir. A
+// *.yaml or *.yml file declares the template text along with the directives,
+// examples and output format of the prompts it generates:
+//
+//	description: 把文本翻译成指定语言
+//	system_prompt: 你是一名专业译者。
+//	directives:
+//	  - 保留原文的格式
+//	  - 专有名词不要翻译
+//	examples:
+//	  - "Hello -> 你好"
+//	output: 只输出译文
+//	max_length: 200
+//	template: |
+//	  把以下文本翻译成{{.language}}：
+//	  {{.text}}
+//
+// Returns:
+//   - The template, with its declared fields as prompt options
+//   - Error if the file cannot be read, has an unknown extension, or is not
+//     a valid template file
+//
+// Example:
+//
+//	pt, err := LoadPromptTemplateFile("templates/translate.yaml")
+//	prompt, err := pt.Execute(map[string]interface{}{"language": "中文", "text": text})
+func LoadPromptTemplateFile(path string) (*PromptTemplate, error) {
+	if !isTemplateFile(path) {
+		return nil, fmt.Errorf("template file %s must end in .tmpl, .yaml or .yml", path)
+	}
+	base := filepath.Base(path)
+	return loadTemplateFile(path, strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// LoadPromptTemplateDir returns a registry of the templates under dir, as
+// loaded by TemplateRegistry.LoadDir, so templates can use partials and
+// base templates from the same directory.
+//
+// Example:
+//
+//	registry, err := LoadPromptTemplateDir("templates")
+//	prompt, err := registry.Execute("support/reply", map[string]interface{}{"Question": question})
+func LoadPromptTemplateDir(dir string) (*TemplateRegistry, error) {
+	registry := NewTemplateRegistry()
+	if err := registry.LoadDir(dir); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// isTemplateFile reports whether path has the extension of a template file.
+func isTemplateFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".tmpl", ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// isTemplateYAML reports whether the *.yaml or *.yml file at path is a
+// template file, that is a mapping with a template key, so that other YAML
+// files kept next to templates, such as configuration, can be skipped.
+func isTemplateYAML(path string) (bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return false, fmt.Errorf("invalid YAML file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return false, nil
+	}
+	mapping := doc.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == "template" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// loadTemplateFile reads the template file at path as the template name.
+func loadTemplateFile(path, name string) (*PromptTemplate, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+	if filepath.Ext(path) != ".tmpl" {
+		return parseTemplateYAML(path, name, content)
+	}
+
+	var opts []PromptTemplateOption
+	if match := templateExtendsPattern.FindSubmatch(content); match != nil {
+		opts = append(opts, WithExtends(string(match[1])))
+	}
+	if match := templateMetadataPattern.FindSubmatch(content); match != nil {
+		var metadata map[string]string
+		if err := json.Unmarshal(match[1], &metadata); err != nil {
+			return nil, fmt.Errorf("invalid metadata in template %s: %w", path, err)
+		}
+		opts = append(opts, WithMetadata(metadata))
+	}
+	return NewPromptTemplate(name, "", string(content), opts...), nil
+}
+
+// parseTemplateYAML parses a *.yaml template file. Unknown fields are
+// rejected so that a misspelled field is not silently ignored.
+func parseTemplateYAML(path, name string, content []byte) (*PromptTemplate, error) {
+	var file templateFile
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid template %s: %w", path, err)
+	}
+	if strings.TrimSpace(file.Template) == "" {
+		return nil, fmt.Errorf("template %s has no template text", path)
+	}
+
+	var promptOpts []PromptOption
+	if file.SystemPrompt != "" {
+		promptOpts = append(promptOpts, WithSystemPrompt(file.SystemPrompt, ""))
+	}
+	if file.Context != "" {
+		promptOpts = append(promptOpts, WithContext(file.Context))
+	}
+	if len(file.Directives) > 0 {
+		promptOpts = append(promptOpts, WithDirectives(file.Directives...))
+	}
+	if len(file.Examples) > 0 {
+		promptOpts = append(promptOpts, WithExamples(file.Examples...))
+	}
+	if file.Output != "" {
+		promptOpts = append(promptOpts, WithOutput(file.Output))
+	}
+	if file.MaxLength > 0 {
+		promptOpts = append(promptOpts, WithMaxLength(file.MaxLength))
+	}
+
+	opts := []PromptTemplateOption{WithPromptOptions(promptOpts...)}
+	if file.Extends != "" {
+		opts = append(opts, WithExtends(file.Extends))
+	}
+	if len(file.Metadata) > 0 {
+		opts = append(opts, WithMetadata(file.Metadata))
+	}
+	return NewPromptTemplate(name, file.Description, file.Template, opts...), nil
+}
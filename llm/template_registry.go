@@ -0,0 +1,477 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/yockii/gollm_cn/persona"
+)
+
+var (
+	// templateRefPattern matches partial references such as {{template "partials/safety" .}}.
+	templateRefPattern = regexp.MustCompile(`{{-?\s*template\s+"([^"]+)"`)
+
+	// templateDefPattern matches named sections declared with {{define "name"}} or {{block "name" .}}.
+	templateDefPattern = regexp.MustCompile(`{{-?\s*(?:define|block)\s+"([^"]+)"`)
+
+	// templateExtendsPattern matches the {{/* extends "base" */}} header that lets
+	// a template file declare its base template. It may follow a metadata header.
+	templateExtendsPattern = regexp.MustCompile(`^\s*(?:{{-?\s*/\*\s*metadata\s.*?\*/\s*-?}}\s*)?{{-?\s*/\*\s*extends\s+"([^"]+)"\s*\*/\s*-?}}`)
+
+	// templateMetadataPattern matches the {{/* metadata {...} */ -}} header in
+	// which WriteFile stores a template's metadata as a JSON object.
+	templateMetadataPattern = regexp.MustCompile(`^\s*{{-?\s*/\*\s*metadata\s+(\{.*?\})\s*\*/\s*-?}}\n?`)
+)
+
+// TemplateRegistry stores named PromptTemplates so they can reference each other.
+// Templates in a registry may include partials with {{template "name" .}} and may
+// extend a base template (see WithExtends), overriding the base's named sections.
+//
+// Example:
+//
+//	registry := NewTemplateRegistry()
+//	registry.Register(NewPromptTemplate("partials/safety", "", "请勿输出任何有害内容。"))
+//	registry.Register(NewPromptTemplate("base", "",
+//	    "{{template \"partials/safety\" .}}\n{{block \"task\" .}}{{end}}"))
+//	registry.Register(NewPromptTemplate("summarize", "",
+//	    "{{define \"task\"}}请总结: {{.Text}}{{end}}", WithExtends("base")))
+//
+//	prompt, err := registry.Execute("summarize", map[string]interface{}{"Text": "..."})
+type TemplateRegistry struct {
+	templates map[string]*PromptTemplate
+	mutex     sync.RWMutex
+}
+
+// TemplateLintIssue describes a problem found by TemplateRegistry.Lint.
+type TemplateLintIssue struct {
+	Template string // Name of the template containing the problem
+	Message  string // Human-readable description of the problem
+}
+
+// String formats the lint issue as "template: message".
+func (i TemplateLintIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.Template, i.Message)
+}
+
+// NewTemplateRegistry creates an empty template registry.
+func NewTemplateRegistry() *TemplateRegistry {
+	return &TemplateRegistry{
+		templates: make(map[string]*PromptTemplate),
+	}
+}
+
+// Register adds a template to the registry, replacing any template with the same name.
+//
+// Returns:
+//   - Error if the template is nil or has no name
+func (r *TemplateRegistry) Register(pt *PromptTemplate) error {
+	if pt == nil || pt.Name == "" {
+		return fmt.Errorf("template must have a name")
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.templates[pt.Name] = pt
+	return nil
+}
+
+// ErrTemplateVersionExists is returned by RegisterVersion when the version is
+// already registered.
+var ErrTemplateVersionExists = errors.New("template version already exists")
+
+// VersionedTemplateName returns the registry name of version of the template
+// name, "name@version".
+func VersionedTemplateName(name, version string) string {
+	return name + "@" + version
+}
+
+// RegisterVersion adds a template as a version of pt.Name, registered under
+// VersionedTemplateName(pt.Name, version). Unlike Register, it never replaces
+// a template, so a published version stays what it was when published.
+//
+// Returns:
+//   - Error if the template has no name, the version is empty or contains "@",
+//     or ErrTemplateVersionExists if the version is already registered
+//
+// Example:
+//
+//	err := registry.RegisterVersion("1.2.0", NewPromptTemplate("summarize", "", "请总结: {{.Text}}"))
+//	prompt, err := registry.Execute("summarize@1.2.0", data)
+func (r *TemplateRegistry) RegisterVersion(version string, pt *PromptTemplate) error {
+	if pt == nil || pt.Name == "" {
+		return fmt.Errorf("template must have a name")
+	}
+	if version == "" || strings.Contains(version, "@") {
+		return fmt.Errorf("invalid template version %q", version)
+	}
+	versioned := *pt
+	versioned.Name = VersionedTemplateName(pt.Name, version)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exists := r.templates[versioned.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrTemplateVersionExists, versioned.Name)
+	}
+	r.templates[versioned.Name] = &versioned
+	return nil
+}
+
+// Versions returns the versions registered for name with RegisterVersion,
+// oldest first.
+func (r *TemplateRegistry) Versions(name string) []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var versions []string
+	for registered := range r.templates {
+		if version, ok := strings.CutPrefix(registered, name+"@"); ok {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return compareVersions(versions[i], versions[j]) < 0
+	})
+	return versions
+}
+
+// Get returns the template registered under the given name.
+func (r *TemplateRegistry) Get(name string) (*PromptTemplate, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	pt, ok := r.templates[name]
+	return pt, ok
+}
+
+// Names returns the names of all registered templates in sorted order.
+func (r *TemplateRegistry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	names := make([]string, 0, len(r.templates))
+	for name := range r.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadDir registers every *.tmpl, *.yaml and *.yml file found under dir (see
+// LoadPromptTemplateFile), skipping YAML files without a template key, which
+// are not templates. The template name is the file's path relative to
+// dir without the extension, using forward slashes, so
+// dir/partials/safety.tmpl is registered as "partials/safety".
+//
+// A file extends a base template by starting with an extends comment:
+//
+//	{{/* extends "base" */}}
+//	{{define "task"}}请总结: {{.Text}}{{end}}
+//
+// A metadata comment written by WriteFile becomes the template's Metadata.
+func (r *TemplateRegistry) LoadDir(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !isTemplateFile(path) {
+			return nil
+		}
+		if filepath.Ext(path) != ".tmpl" {
+			ok, err := isTemplateYAML(path)
+			if err != nil || !ok {
+				return err
+			}
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		pt, err := loadTemplateFile(path, filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel))))
+		if err != nil {
+			return err
+		}
+		return r.Register(pt)
+	})
+}
+
+// WriteFile writes the named template to dir so that LoadDir registers it
+// under the same name: "partials/safety" is written to
+// dir/partials/safety.tmpl. The template's metadata is kept in a comment at
+// the top of the file, which renders nothing.
+//
+// Returns:
+//   - Error if the template is not registered, the file already exists, or
+//     the file cannot be written
+func (r *TemplateRegistry) WriteFile(dir, name string) error {
+	pt, ok := r.Get(name)
+	if !ok {
+		return fmt.Errorf("template %q not found", name)
+	}
+
+	text := templateMetadataPattern.ReplaceAllString(pt.Template, "")
+	if len(pt.Metadata) > 0 {
+		metadata, err := json.Marshal(pt.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata of template %q: %w", name, err)
+		}
+		// "\/" is a valid JSON escape and keeps values from closing the comment
+		text = "{{/* metadata " + strings.ReplaceAll(string(metadata), "*/", `*\/`) + " */ -}}\n" + text
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(name)+".tmpl")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create template directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create template file: %w", err)
+	}
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write template file: %w", err)
+	}
+	return f.Close()
+}
+
+// resolvedTemplate is the flattened form of a template after following its
+// inheritance chain and collecting every partial it depends on.
+type resolvedTemplate struct {
+	chain    []*PromptTemplate // The template followed by its bases, child first
+	partials []*PromptTemplate // Partials pulled from the registry, sorted by name
+}
+
+// resolve follows the inheritance chain of the named template and collects all
+// partials it references. It fails on missing templates and on cycles, naming
+// the full chain in the error.
+func (r *TemplateRegistry) resolve(name string) (*resolvedTemplate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	res := &resolvedTemplate{}
+	seen := map[string]bool{}
+	path := []string{}
+	for current := name; current != ""; {
+		path = append(path, current)
+		if seen[current] {
+			return nil, fmt.Errorf("template inheritance cycle detected: %s", strings.Join(path, " -> "))
+		}
+		seen[current] = true
+		pt, ok := r.templates[current]
+		if !ok {
+			if current == name {
+				return nil, fmt.Errorf("template %q not found in registry", current)
+			}
+			return nil, fmt.Errorf("base template %q extended by %q is not registered", current, path[len(path)-2])
+		}
+		if pt.Extends != "" {
+			if err := checkExtendsBody(pt); err != nil {
+				return nil, err
+			}
+		}
+		res.chain = append(res.chain, pt)
+		current = pt.Extends
+	}
+
+	defined := map[string]bool{}
+	for _, pt := range res.chain {
+		defined[pt.Name] = true
+		for _, def := range templateDefinitions(pt.Template) {
+			defined[def] = true
+		}
+	}
+
+	partials := map[string]*PromptTemplate{}
+	var visit func(text string, stack []string) error
+	visit = func(text string, stack []string) error {
+		for _, ref := range templateReferences(text) {
+			for i, s := range stack {
+				if s == ref {
+					return fmt.Errorf("template partial cycle detected: %s", strings.Join(append(stack[i:], ref), " -> "))
+				}
+			}
+			if defined[ref] {
+				continue
+			}
+			partial, ok := r.templates[ref]
+			if !ok {
+				return fmt.Errorf("partial %q referenced by %q is not registered", ref, stack[len(stack)-1])
+			}
+			partials[ref] = partial
+			if err := visit(partial.Template, append(stack, ref)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, pt := range res.chain {
+		if err := visit(pt.Template, []string{pt.Name}); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(partials))
+	for partialName := range partials {
+		names = append(names, partialName)
+	}
+	sort.Strings(names)
+	for _, partialName := range names {
+		res.partials = append(res.partials, partials[partialName])
+	}
+	return res, nil
+}
+
+// Execute renders the named template with the given data, resolving partials
+// and base templates from the registry. Options of base templates are applied
+// before those of the child so that the child can override them. As with
+// PromptTemplate.Execute, missing variables are an error and values are
+// escaped.
+//
+// Returns:
+//   - Generated and configured Prompt instance
+//   - Error if the template, a base or a partial is missing, a cycle is detected,
+//     a variable is missing, or template parsing or execution fails
+func (r *TemplateRegistry) Execute(name string, data map[string]interface{}) (*Prompt, error) {
+	res, err := r.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse from the outermost base inwards: the base provides the body and
+	// later definitions override its named sections.
+	tmpl := template.New(name).Option("missingkey=error")
+	for i := len(res.chain) - 1; i >= 0; i-- {
+		if _, err := tmpl.Parse(res.chain[i].Template); err != nil {
+			return nil, fmt.Errorf("failed to parse template %q: %w", res.chain[i].Name, err)
+		}
+	}
+	for _, partial := range res.partials {
+		if _, err := tmpl.New(partial.Name).Parse(partial.Template); err != nil {
+			return nil, fmt.Errorf("failed to parse partial %q: %w", partial.Name, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, escapeTemplateData(data)); err != nil {
+		return nil, err
+	}
+
+	prompt := NewPrompt(buf.String())
+	for i := len(res.chain) - 1; i >= 0; i-- {
+		prompt.Apply(res.chain[i].Options...)
+	}
+	return prompt, nil
+}
+
+// Fingerprint returns a stable hash of the named template together with the
+// content of every base template and partial it resolves to, and of the prompt
+// fields set by the options of its inheritance chain. Any change to a partial or
+// to a base's options therefore changes the fingerprint of every template that
+// uses it.
+//
+// The registry does not cache rendered prompts or responses itself; the
+// fingerprint is meant to be used as (part of) the key of a caller's cache so
+// cached entries are invalidated when a template they depend on changes.
+// Options are compared by their effect on an empty Prompt, so options that
+// read external state at apply time are only captured as of the call.
+func (r *TemplateRegistry) Fingerprint(name string) (string, error) {
+	res, err := r.resolve(name)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, group := range [][]*PromptTemplate{res.chain, res.partials} {
+		for _, pt := range group {
+			h.Write([]byte(pt.Name))
+			h.Write([]byte{0})
+			h.Write([]byte(pt.Extends))
+			h.Write([]byte{0})
+			h.Write([]byte(pt.Template))
+			h.Write([]byte{0})
+		}
+	}
+
+	optionsEffect := NewPrompt("")
+	for i := len(res.chain) - 1; i >= 0; i-- {
+		optionsEffect.Apply(res.chain[i].Options...)
+	}
+	encoded, err := json.Marshal(optionsEffect)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode template options: %w", err)
+	}
+	h.Write(encoded)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// FingerprintWithPersona is Fingerprint for prompts sent by a client with a
+// persona (see config.SetPersona), whose instructions become part of every
+// prompt it sends. Changing the persona therefore changes the fingerprint. A
+// persona that sets nothing gives the same fingerprint as Fingerprint.
+func (r *TemplateRegistry) FingerprintWithPersona(name string, p persona.Persona) (string, error) {
+	fingerprint, err := r.Fingerprint(name)
+	if err != nil || p.IsZero() {
+		return fingerprint, err
+	}
+	sum := sha256.Sum256([]byte(fingerprint + "\x00" + p.Fingerprint()))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Lint checks every registered template and reports missing partials, missing
+// base templates, cycles, parse errors and child templates whose content
+// outside {{define}} blocks would replace the base body. Each template reports
+// at most one issue, and issues are returned in a deterministic order.
+func (r *TemplateRegistry) Lint() []TemplateLintIssue {
+	var issues []TemplateLintIssue
+	for _, name := range r.Names() {
+		if _, err := r.resolve(name); err != nil {
+			issues = append(issues, TemplateLintIssue{Template: name, Message: err.Error()})
+		}
+	}
+	return issues
+}
+
+// checkExtendsBody ensures a template that extends a base only contributes
+// {{define}} blocks. Any other content would silently replace the base body
+// when the templates are parsed into the same set.
+func checkExtendsBody(pt *PromptTemplate) error {
+	tmpl, err := template.New(pt.Name).Parse(pt.Template)
+	if err != nil {
+		return fmt.Errorf("failed to parse template %q: %w", pt.Name, err)
+	}
+	if tmpl.Tree != nil && !parse.IsEmptyTree(tmpl.Tree.Root) {
+		return fmt.Errorf("template %q extends %q but has content outside {{define}} blocks, which would replace the base body", pt.Name, pt.Extends)
+	}
+	return nil
+}
+
+// templateReferences returns the unique names referenced by {{template "..."}}
+// actions in order of first appearance.
+func templateReferences(text string) []string {
+	return uniqueSubmatches(templateRefPattern, text)
+}
+
+// templateDefinitions returns the unique names declared by {{define "..."}} or
+// {{block "..."}} actions in order of first appearance.
+func templateDefinitions(text string) []string {
+	return uniqueSubmatches(templateDefPattern, text)
+}
+
+func uniqueSubmatches(re *regexp.Regexp, text string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, match := range re.FindAllStringSubmatch(text, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,209 @@
+package llm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateRegistry_Partials(t *testing.T) {
+	r := NewTemplateRegistry()
+	require.NoError(t, r.Register(NewPromptTemplate("partials/safety", "", "请勿输出有害内容。")))
+	require.NoError(t, r.Register(NewPromptTemplate("qa", "", "{{template \"partials/safety\" .}}\n问题: {{.Question}}")))
+
+	prompt, err := r.Execute("qa", map[string]interface{}{"Question": "天空为什么是蓝色的?"})
+	require.NoError(t, err)
+	assert.Equal(t, "请勿输出有害内容。\n问题: 天空为什么是蓝色的?", prompt.Input)
+}
+
+func TestTemplateRegistry_Inheritance(t *testing.T) {
+	r := NewTemplateRegistry()
+	require.NoError(t, r.Register(NewPromptTemplate("base", "",
+		"前言\n{{block \"task\" .}}默认任务{{end}}\n结尾",
+		WithPromptOptions(WithMaxLength(100), WithDirectives("保持简洁")))))
+	require.NoError(t, r.Register(NewPromptTemplate("summarize", "",
+		"{{define \"task\"}}请总结: {{.Text}}{{end}}",
+		WithExtends("base"), WithPromptOptions(WithMaxLength(50)))))
+
+	prompt, err := r.Execute("summarize", map[string]interface{}{"Text": "文章"})
+	require.NoError(t, err)
+	assert.Equal(t, "前言\n请总结: 文章\n结尾", prompt.Input)
+	assert.Equal(t, 50, prompt.MaxLength)
+	assert.Equal(t, []string{"保持简洁"}, prompt.Directives)
+
+	prompt, err = r.Execute("base", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "前言\n默认任务\n结尾", prompt.Input)
+}
+
+func TestTemplateRegistry_Cycle(t *testing.T) {
+	r := NewTemplateRegistry()
+	require.NoError(t, r.Register(NewPromptTemplate("a", "", "{{template \"b\" .}}")))
+	require.NoError(t, r.Register(NewPromptTemplate("b", "", "{{template \"a\" .}}")))
+
+	_, err := r.Execute("a", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a -> b -> a")
+
+	require.NoError(t, r.Register(NewPromptTemplate("x", "", "", WithExtends("y"))))
+	require.NoError(t, r.Register(NewPromptTemplate("y", "", "", WithExtends("x"))))
+	_, err = r.Execute("x", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "x -> y -> x")
+}
+
+func TestTemplateRegistry_FingerprintTracksPartials(t *testing.T) {
+	r := NewTemplateRegistry()
+	require.NoError(t, r.Register(NewPromptTemplate("partials/tone", "", "语气友好")))
+	require.NoError(t, r.Register(NewPromptTemplate("greet", "", "{{template \"partials/tone\" .}}")))
+
+	before, err := r.Fingerprint("greet")
+	require.NoError(t, err)
+	again, err := r.Fingerprint("greet")
+	require.NoError(t, err)
+	assert.Equal(t, before, again)
+
+	require.NoError(t, r.Register(NewPromptTemplate("partials/tone", "", "语气正式")))
+	after, err := r.Fingerprint("greet")
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+}
+
+func TestTemplateRegistry_LintAndLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "partials"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "partials", "safety.tmpl"), []byte("安全"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ok.tmpl"), []byte("{{template \"partials/safety\" .}}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.tmpl"), []byte("{{template \"partials/missing\" .}}"), 0o644))
+
+	r := NewTemplateRegistry()
+	require.NoError(t, r.LoadDir(dir))
+	assert.Equal(t, []string{"broken", "ok", "partials/safety"}, r.Names())
+
+	issues := r.Lint()
+	require.Len(t, issues, 1)
+	assert.Equal(t, "broken", issues[0].Template)
+	assert.Contains(t, issues[0].Message, "partials/missing")
+}
+
+func TestTemplateRegistry_LintSeesInheritedDefinitions(t *testing.T) {
+	r := NewTemplateRegistry()
+	require.NoError(t, r.Register(NewPromptTemplate("base", "", "{{define \"footer\"}}F{{end}}{{block \"task\" .}}{{end}}")))
+	require.NoError(t, r.Register(NewPromptTemplate("child", "", "{{define \"task\"}}T {{template \"footer\" .}}{{end}}", WithExtends("base"))))
+
+	prompt, err := r.Execute("child", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "T F", prompt.Input)
+	assert.Empty(t, r.Lint())
+}
+
+func TestTemplateRegistry_ChildContentOutsideDefine(t *testing.T) {
+	r := NewTemplateRegistry()
+	require.NoError(t, r.Register(NewPromptTemplate("base", "", "前言 {{block \"task\" .}}{{end}}")))
+	require.NoError(t, r.Register(NewPromptTemplate("child", "", "{{define \"task\"}}T{{end}}\nextra", WithExtends("base"))))
+
+	_, err := r.Execute("child", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "outside {{define}} blocks")
+
+	issues := r.Lint()
+	require.Len(t, issues, 1)
+	assert.Equal(t, "child", issues[0].Template)
+}
+
+func TestTemplateRegistry_LoadDirExtendsHeader(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.tmpl"), []byte("前言 {{block \"task\" .}}{{end}}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "child.tmpl"), []byte("{{/* extends \"base\" */}}\n{{define \"task\"}}请总结: {{.Text}}{{end}}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orphan.tmpl"), []byte("{{/* extends \"missing\" */}}"), 0o644))
+
+	r := NewTemplateRegistry()
+	require.NoError(t, r.LoadDir(dir))
+
+	child, ok := r.Get("child")
+	require.True(t, ok)
+	assert.Equal(t, "base", child.Extends)
+
+	prompt, err := r.Execute("child", map[string]interface{}{"Text": "文章"})
+	require.NoError(t, err)
+	assert.Equal(t, "前言 请总结: 文章", prompt.Input)
+
+	issues := r.Lint()
+	require.Len(t, issues, 1)
+	assert.Equal(t, "orphan", issues[0].Template)
+	assert.Contains(t, issues[0].Message, "base template \"missing\"")
+}
+
+func TestTemplateRegistry_FingerprintTracksOptions(t *testing.T) {
+	r := NewTemplateRegistry()
+	require.NoError(t, r.Register(NewPromptTemplate("base", "", "{{block \"task\" .}}{{end}}", WithPromptOptions(WithMaxLength(100)))))
+	require.NoError(t, r.Register(NewPromptTemplate("child", "", "{{define \"task\"}}T{{end}}", WithExtends("base"))))
+
+	before, err := r.Fingerprint("child")
+	require.NoError(t, err)
+
+	require.NoError(t, r.Register(NewPromptTemplate("base", "", "{{block \"task\" .}}{{end}}", WithPromptOptions(WithMaxLength(50)))))
+	after, err := r.Fingerprint("child")
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+}
+
+func TestPromptTemplate_ExecuteRejectsExtends(t *testing.T) {
+	pt := NewPromptTemplate("child", "", "{{define \"task\"}}T{{end}}", WithExtends("base"))
+	_, err := pt.Execute(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "TemplateRegistry")
+}
+
+func TestTemplateRegistry_RegisterVersion(t *testing.T) {
+	r := NewTemplateRegistry()
+	for _, version := range []string{"1.10.0", "1.2.0", "1.9.1"} {
+		require.NoError(t, r.RegisterVersion(version, NewPromptTemplate("summarize", "", "v"+version)))
+	}
+	assert.Equal(t, []string{"1.2.0", "1.9.1", "1.10.0"}, r.Versions("summarize"))
+
+	err := r.RegisterVersion("1.2.0", NewPromptTemplate("summarize", "", "replacement"))
+	assert.ErrorIs(t, err, ErrTemplateVersionExists)
+	pt, ok := r.Get("summarize@1.2.0")
+	require.True(t, ok)
+	assert.Equal(t, "v1.2.0", pt.Template, "a published version is never replaced")
+
+	assert.Error(t, r.RegisterVersion("", NewPromptTemplate("summarize", "", "x")))
+	assert.Error(t, r.RegisterVersion("1@2", NewPromptTemplate("summarize", "", "x")))
+}
+
+func TestTemplateRegistry_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	r := NewTemplateRegistry()
+	require.NoError(t, r.Register(NewPromptTemplate("base", "", "前言 {{block \"task\" .}}{{end}}")))
+	require.NoError(t, r.RegisterVersion("1.0.0", NewPromptTemplate("greet", "", "你好，{{.Name}}",
+		WithMetadata(map[string]string{"note": "closes */ comments"}))))
+	require.NoError(t, r.Register(NewPromptTemplate("child", "", "{{/* extends \"base\" */}}{{define \"task\"}}T{{end}}",
+		WithExtends("base"), WithMetadata(map[string]string{"author": "ops"}))))
+	for _, name := range []string{"base", "greet@1.0.0", "child"} {
+		require.NoError(t, r.WriteFile(dir, name))
+	}
+	assert.Error(t, r.WriteFile(dir, "greet@1.0.0"), "existing files are not overwritten")
+
+	loaded := NewTemplateRegistry()
+	require.NoError(t, loaded.LoadDir(dir))
+	assert.Empty(t, loaded.Lint())
+
+	greet, ok := loaded.Get("greet@1.0.0")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{"note": "closes */ comments"}, greet.Metadata)
+	prompt, err := loaded.Execute("greet@1.0.0", map[string]interface{}{"Name": "小明"})
+	require.NoError(t, err)
+	assert.Equal(t, "你好，小明", prompt.Input)
+
+	child, ok := loaded.Get("child")
+	require.True(t, ok)
+	assert.Equal(t, "base", child.Extends)
+	assert.Equal(t, map[string]string{"author": "ops"}, child.Metadata)
+	prompt, err = loaded.Execute("child", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "前言 T", prompt.Input)
+}
@@ -0,0 +1,203 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// callTrace tracks a single Generate or Stream call for the configured tracer
+// and metrics collector. All methods are no-ops when neither is configured.
+type callTrace struct {
+	span     utils.CallSpan
+	metrics  utils.MetricsCollector
+	info     utils.CallInfo
+	start    time.Time
+	attempts int
+	usage    tokenUsage
+	ctx      context.Context // Context of the call, for its usage recorders
+	once     sync.Once
+}
+
+// startCall starts tracing a call if a tracer or metrics collector is
+// configured. The returned context carries the tracer's span and must be used
+// for the call.
+func (l *LLMImpl) startCall(ctx context.Context, operation string, overrides map[string]interface{}) (context.Context, *callTrace) {
+	t := &callTrace{start: time.Now(), ctx: ctx}
+	if l.config == nil || (l.config.Tracer == nil && l.config.Metrics == nil) {
+		return ctx, t
+	}
+	model := l.config.Model
+	if override, ok := overrides["model"].(string); ok {
+		model = override
+	}
+	t.info = utils.CallInfo{
+		Operation: operation,
+		Provider:  l.Provider.Name(),
+		Model:     model,
+		Shadow:    IsShadowCall(ctx),
+	}
+	if l.config.Tracer != nil {
+		ctx, t.span = l.config.Tracer.StartCall(ctx, t.info)
+	}
+	if l.config.Metrics != nil {
+		t.metrics = l.config.Metrics
+		t.metrics.RecordRequest(t.info)
+	}
+	return ctx, t
+}
+
+// active reports whether the call is traced or measured.
+func (t *callTrace) active() bool {
+	return t.span != nil || t.metrics != nil
+}
+
+// end reports the outcome of the call. Only the first call has an effect.
+func (t *callTrace) end(err error) {
+	t.once.Do(func() {
+		addUsage(t.ctx, TokenUsage{
+			InputTokens:              t.usage.input,
+			OutputTokens:             t.usage.output,
+			CacheCreationInputTokens: t.usage.cacheCreation,
+			CacheReadInputTokens:     t.usage.cacheRead,
+		})
+		recordSystemFingerprint(t.ctx, t.usage.systemFingerprint)
+		if !t.active() {
+			return
+		}
+		latency := time.Since(t.start)
+		if t.span != nil {
+			t.span.End(utils.CallResult{
+				Attempts:     t.attempts,
+				Latency:      latency,
+				InputTokens:  t.usage.input,
+				OutputTokens: t.usage.output,
+				Err:          err,
+
+				CacheCreationInputTokens: t.usage.cacheCreation,
+				CacheReadInputTokens:     t.usage.cacheRead,
+				SystemFingerprint:        t.usage.systemFingerprint,
+			})
+		}
+		if t.metrics != nil {
+			t.metrics.RecordLatency(t.info, latency)
+			if t.usage.input > 0 || t.usage.output > 0 {
+				t.metrics.RecordTokens(t.info, t.usage.input, t.usage.output)
+			}
+			if err != nil {
+				t.metrics.RecordError(t.info, errorType(err))
+			}
+		}
+	})
+}
+
+// errorType classifies err for metrics: the LLMError type if err wraps one,
+// otherwise "Canceled", "Timeout" or "UnknownError".
+func errorType(err error) string {
+	var llmErr *LLMError
+	switch {
+	case errors.As(err, &llmErr):
+		return llmErr.TypeString()
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Timeout"
+	default:
+		return "UnknownError"
+	}
+}
+
+// tokenUsage holds the token counts reported by a provider, and the
+// system_fingerprint of the last response that had one.
+type tokenUsage struct {
+	input             int
+	output            int
+	cacheCreation     int
+	cacheRead         int
+	systemFingerprint string
+}
+
+// record adds the token counts of a decoded response body, so the usage of a
+// call covers every response it received, including retries and rewrites. It
+// understands the OpenAI-style (prompt_tokens/completion_tokens),
+// Anthropic-style (input_tokens/output_tokens and the cache_creation_ and
+// cache_read_input_tokens of prompt caching), Cohere (usage.tokens), Gemini
+// (usageMetadata) and Ollama (prompt_eval_count/eval_count) formats.
+func (u *tokenUsage) record(response map[string]interface{}) {
+	if u == nil || response == nil {
+		return
+	}
+	if fingerprint, ok := response["system_fingerprint"].(string); ok && fingerprint != "" {
+		u.systemFingerprint = fingerprint
+	}
+	number := func(m map[string]interface{}, keys ...string) (int, bool) {
+		for _, key := range keys {
+			if v, ok := m[key].(float64); ok {
+				return int(v), true
+			}
+		}
+		return 0, false
+	}
+	if metadata, ok := response["usageMetadata"].(map[string]interface{}); ok {
+		if n, ok := number(metadata, "promptTokenCount"); ok {
+			u.input += n
+		}
+		if n, ok := number(metadata, "candidatesTokenCount"); ok {
+			u.output += n
+		}
+		return
+	}
+	usage, _ := response["usage"].(map[string]interface{})
+	if tokens, ok := usage["tokens"].(map[string]interface{}); ok {
+		usage = tokens
+	}
+	if usage != nil {
+		if n, ok := number(usage, "prompt_tokens", "input_tokens"); ok {
+			u.input += n
+		}
+		if n, ok := number(usage, "completion_tokens", "output_tokens"); ok {
+			u.output += n
+		}
+		if n, ok := number(usage, "cache_creation_input_tokens"); ok {
+			u.cacheCreation += n
+		}
+		if n, ok := number(usage, "cache_read_input_tokens"); ok {
+			u.cacheRead += n
+		}
+		return
+	}
+	if n, ok := number(response, "prompt_eval_count"); ok {
+		u.input += n
+	}
+	if n, ok := number(response, "eval_count"); ok {
+		u.output += n
+	}
+}
+
+// tracedStream ends the call's span when the stream finishes or is closed.
+type tracedStream struct {
+	TokenStream
+	trace *callTrace
+}
+
+func (s *tracedStream) Next(ctx context.Context) (*StreamToken, error) {
+	token, err := s.TokenStream.Next(ctx)
+	if err != nil {
+		if err == io.EOF {
+			s.trace.end(nil)
+		} else {
+			s.trace.end(err)
+		}
+	}
+	return token, err
+}
+
+func (s *tracedStream) Close() error {
+	s.trace.end(nil)
+	return s.TokenStream.Close()
+}
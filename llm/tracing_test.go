@@ -0,0 +1,215 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+type ctxKey struct{}
+
+type recordingTracer struct {
+	infos   []utils.CallInfo
+	results []utils.CallResult
+	parent  interface{}
+}
+
+func (r *recordingTracer) StartCall(ctx context.Context, info utils.CallInfo) (context.Context, utils.CallSpan) {
+	r.infos = append(r.infos, info)
+	r.parent = ctx.Value(ctxKey{})
+	return ctx, recordingSpan{r}
+}
+
+type recordingSpan struct{ r *recordingTracer }
+
+func (s recordingSpan) End(result utils.CallResult) {
+	s.r.results = append(s.r.results, result)
+}
+
+func TestTracing_Generate(t *testing.T) {
+	tracer := &recordingTracer{}
+	l, _ := newCapturingLLM(t, config.SetTracer(tracer))
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "caller-span")
+	_, err := l.Generate(ctx, NewPrompt("你好"), WithModel("gpt-4o-mini"))
+	require.NoError(t, err)
+
+	require.Len(t, tracer.infos, 1)
+	assert.Equal(t, utils.CallInfo{Operation: "generate", Provider: "openai", Model: "gpt-4o-mini"}, tracer.infos[0])
+	assert.Equal(t, "caller-span", tracer.parent)
+
+	require.Len(t, tracer.results, 1)
+	result := tracer.results[0]
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, 12, result.InputTokens)
+	assert.Equal(t, 3, result.OutputTokens)
+	assert.NoError(t, result.Err)
+	assert.Greater(t, int64(result.Latency), int64(0))
+}
+
+func TestTracing_ShadowCall(t *testing.T) {
+	tracer := &recordingTracer{}
+	l, _ := newCapturingLLM(t, config.SetTracer(tracer))
+
+	ctx, usage := WithUsageRecorder(context.Background())
+	_, err := l.Generate(WithShadowCall(ctx), NewPrompt("你好"))
+	require.NoError(t, err)
+
+	require.Len(t, tracer.infos, 1)
+	assert.True(t, tracer.infos[0].Shadow)
+	assert.Equal(t, TokenUsage{}, usage.Usage(), "shadow usage not added to the caller's recorder")
+}
+
+type recordingMetrics struct {
+	requests []utils.CallInfo
+	latency  []time.Duration
+	tokens   [][2]int
+	errors   []string
+}
+
+func (m *recordingMetrics) RecordRequest(info utils.CallInfo) {
+	m.requests = append(m.requests, info)
+}
+
+func (m *recordingMetrics) RecordLatency(info utils.CallInfo, latency time.Duration) {
+	m.latency = append(m.latency, latency)
+}
+
+func (m *recordingMetrics) RecordTokens(info utils.CallInfo, inputTokens, outputTokens int) {
+	m.tokens = append(m.tokens, [2]int{inputTokens, outputTokens})
+}
+
+func (m *recordingMetrics) RecordError(info utils.CallInfo, errorType string) {
+	m.errors = append(m.errors, errorType)
+}
+
+func TestMetrics_Generate(t *testing.T) {
+	metrics := &recordingMetrics{}
+	l, _ := newCapturingLLM(t, config.SetMetrics(metrics))
+
+	_, err := l.Generate(context.Background(), NewPrompt("你好"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []utils.CallInfo{{Operation: "generate", Provider: "openai", Model: "gpt-4o"}}, metrics.requests)
+	assert.Len(t, metrics.latency, 1)
+	assert.Equal(t, [][2]int{{12, 3}}, metrics.tokens)
+	assert.Empty(t, metrics.errors)
+}
+
+func TestMetrics_ErrorType(t *testing.T) {
+	metrics := &recordingMetrics{}
+	l, _ := newFailingLLM(t, 10, config.SetMaxRetries(1), config.SetMetrics(metrics))
+
+	_, err := l.Generate(context.Background(), NewPrompt("你好"))
+	require.Error(t, err)
+
+	assert.Len(t, metrics.requests, 1)
+	assert.Len(t, metrics.latency, 1)
+	assert.Empty(t, metrics.tokens)
+	assert.Equal(t, []string{"APIError"}, metrics.errors)
+}
+
+func TestErrorType(t *testing.T) {
+	assert.Equal(t, "RateLimitError", errorType(NewLLMError(ErrorTypeRateLimit, "slow down", nil)))
+	assert.Equal(t, "Canceled", errorType(context.Canceled))
+	assert.Equal(t, "Timeout", errorType(context.DeadlineExceeded))
+	assert.Equal(t, "UnknownError", errorType(assert.AnError))
+}
+
+func TestTokenUsage_Formats(t *testing.T) {
+	cases := map[string]struct {
+		response      map[string]interface{}
+		input, output int
+	}{
+		"openai":    {map[string]interface{}{"usage": map[string]interface{}{"prompt_tokens": 5.0, "completion_tokens": 7.0}}, 5, 7},
+		"anthropic": {map[string]interface{}{"usage": map[string]interface{}{"input_tokens": 8.0, "output_tokens": 2.0}}, 8, 2},
+		"cohere":    {map[string]interface{}{"usage": map[string]interface{}{"tokens": map[string]interface{}{"input_tokens": 4.0, "output_tokens": 1.0}}}, 4, 1},
+		"gemini":    {map[string]interface{}{"usageMetadata": map[string]interface{}{"promptTokenCount": 6.0, "candidatesTokenCount": 9.0, "totalTokenCount": 15.0}}, 6, 9},
+		"ollama":    {map[string]interface{}{"prompt_eval_count": 9.0, "eval_count": 6.0}, 9, 6},
+		"missing":   {map[string]interface{}{}, 0, 0},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var usage tokenUsage
+			usage.record(tc.response)
+			assert.Equal(t, tc.input, usage.input)
+			assert.Equal(t, tc.output, usage.output)
+		})
+	}
+}
+
+func TestTokenUsage_PromptCache(t *testing.T) {
+	var usage tokenUsage
+	usage.record(map[string]interface{}{"usage": map[string]interface{}{
+		"input_tokens": 8.0, "output_tokens": 2.0, "cache_creation_input_tokens": 3000.0, "cache_read_input_tokens": 0.0,
+	}})
+	usage.record(map[string]interface{}{"usage": map[string]interface{}{
+		"input_tokens": 8.0, "output_tokens": 2.0, "cache_creation_input_tokens": 0.0, "cache_read_input_tokens": 3000.0,
+	}})
+	assert.Equal(t, tokenUsage{input: 16, output: 4, cacheCreation: 3000, cacheRead: 3000}, usage)
+}
+
+func TestWithUsageRecorder(t *testing.T) {
+	l, _ := newCapturingLLM(t)
+	ctx, outer := WithUsageRecorder(context.Background())
+	inner, recorder := WithUsageRecorder(ctx)
+
+	_, err := l.Generate(inner, NewPrompt("你好"))
+	require.NoError(t, err)
+	_, err = l.Generate(ctx, NewPrompt("你好"))
+	require.NoError(t, err)
+
+	assert.Equal(t, TokenUsage{InputTokens: 12, OutputTokens: 3}, recorder.Usage())
+	assert.Equal(t, TokenUsage{InputTokens: 24, OutputTokens: 6}, outer.Usage())
+}
+
+func TestWithUsageRecorder_SystemFingerprint(t *testing.T) {
+	var seed interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		seed = req["seed"]
+		w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}],"system_fingerprint":"fp_44709d6fcb","usage":{"prompt_tokens":1,"completion_tokens":1}}`))
+	}))
+	defer server.Close()
+	tracer := &recordingTracer{}
+	l, _ := newCapturingLLM(t, config.SetEndpoint(server.URL), config.SetTracer(tracer))
+
+	ctx, recorder := WithUsageRecorder(context.Background())
+	assert.Empty(t, recorder.SystemFingerprint())
+	_, err := l.Generate(ctx, NewPrompt("你好"), WithSeed(42), WithTemperature(0))
+	require.NoError(t, err)
+	assert.Equal(t, float64(42), seed)
+	assert.Equal(t, "fp_44709d6fcb", recorder.SystemFingerprint())
+	require.Len(t, tracer.results, 1)
+	assert.Equal(t, "fp_44709d6fcb", tracer.results[0].SystemFingerprint)
+}
+
+func TestDebugRecorder_ReplaysGenerate(t *testing.T) {
+	var recording bytes.Buffer
+	l, _ := newCapturingLLM(t, config.SetDebugRecorder(utils.NewDebugRecorder(&recording)))
+	first, err := l.Generate(context.Background(), NewPrompt("你好"))
+	require.NoError(t, err)
+
+	records, err := utils.ReadRecording(bytes.NewReader(recording.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Contains(t, string(records[0].Request), `"model":"gpt-4o"`)
+
+	replay, err := utils.ReplayRecording(bytes.NewReader(recording.Bytes()))
+	require.NoError(t, err)
+	defer replay.Close()
+	l, _ = newCapturingLLM(t, config.SetEndpoint(replay.URL))
+	replayed, err := l.Generate(context.Background(), NewPrompt("你好"))
+	require.NoError(t, err)
+	assert.Equal(t, first, replayed)
+}
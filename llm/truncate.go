@@ -0,0 +1,201 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// TruncateStrategy selects which part of a prompt's input is dropped when the
+// prompt does not fit the model's context window.
+type TruncateStrategy string
+
+const (
+	// TruncateMiddle keeps the beginning and end of the input and drops the middle.
+	TruncateMiddle TruncateStrategy = "middle"
+	// TruncateHead drops the beginning of the input.
+	TruncateHead TruncateStrategy = "head"
+	// TruncateTail drops the end of the input.
+	TruncateTail TruncateStrategy = "tail"
+)
+
+// truncationMarker replaces the text dropped by TruncateMiddle.
+const truncationMarker = "\n[...]\n"
+
+var (
+	// contextWindows holds context window sizes by model name prefix.
+	contextWindows = map[string]int{
+		// OpenAI
+		"gpt-4o":        128000,
+		"gpt-4-turbo":   128000,
+		"gpt-4":         8192,
+		"gpt-3.5-turbo": 16385,
+		"o1":            200000,
+		"o3-mini":       200000,
+		// Anthropic
+		"claude-3": 200000,
+		// Gemini
+		"gemini-1.5-pro":   2097152,
+		"gemini-1.5-flash": 1048576,
+		"gemini-2.0-flash": 1048576,
+		// Groq
+		"llama-3.1":          128000,
+		"llama-3.3":          128000,
+		"llama3":             8192,
+		"mixtral-8x7b-32768": 32768,
+		// Mistral
+		"mistral-large":     128000,
+		"mistral-small":     32000,
+		"open-mistral-nemo": 128000,
+		// Cohere
+		"command-r": 128000,
+		// Ollama
+		"llama3.1": 128000,
+		"llama3.2": 128000,
+		"qwen2.5":  32768,
+		"gemma2":   8192,
+		"mistral":  32768,
+		// DeepSeek
+		"deepseek-chat":     64000,
+		"deepseek-reasoner": 64000,
+	}
+	contextWindowsMutex sync.RWMutex
+)
+
+// RegisterContextWindow sets the context window size, in tokens, of a model.
+// A name also applies to every model it is a prefix of, so registering
+// "claude-3" covers "claude-3-opus-20240229"; the longest matching name wins.
+// Registering a name again replaces its size.
+//
+// Example:
+//
+//	llm.RegisterContextWindow("my-finetuned-model", 32000)
+func RegisterContextWindow(model string, tokens int) {
+	contextWindowsMutex.Lock()
+	defer contextWindowsMutex.Unlock()
+	contextWindows[model] = tokens
+}
+
+// ContextWindow returns the context window size, in tokens, of a model, and
+// whether it is known. See RegisterContextWindow for how names are matched.
+func ContextWindow(model string) (int, bool) {
+	contextWindowsMutex.RLock()
+	defer contextWindowsMutex.RUnlock()
+	if tokens, ok := contextWindows[model]; ok {
+		return tokens, true
+	}
+	var names []string
+	for name := range contextWindows {
+		if strings.HasPrefix(model, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return 0, false
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	return contextWindows[names[0]], true
+}
+
+// tokenizer is the subset of *tiktoken.Tiktoken used for token counting and
+// truncation.
+type tokenizer interface {
+	Encode(text string, allowedSpecial, disallowedSpecial []string) []int
+	Decode(tokens []int) string
+}
+
+// encodingForModel loads the tokenizer of a model. Tests replace it, since
+// tiktoken downloads its encodings on first use.
+var encodingForModel = func(model string) (tokenizer, error) {
+	return tiktoken.EncodingForModel(model)
+}
+
+// truncatePrompt returns a copy of prompt whose input is shortened with the
+// given strategy so that the prompt and the response fit the model's context
+// window. The prompt is returned unchanged if it fits or the model's context
+// window is unknown.
+//
+// Returns:
+//   - The prompt to send
+//   - ErrorTypeInvalidInput if the prompt does not fit even without its input
+func (l *LLMImpl) truncatePrompt(ctx context.Context, prompt *Prompt, strategy TruncateStrategy, config *GenerateConfig) (*Prompt, error) {
+	model := l.config.Model
+	if config.Model != "" {
+		model = config.Model
+	}
+	window, ok := ContextWindow(model)
+	if !ok {
+		l.logger.Warn("Unknown context window, prompt not truncated", "model", model)
+		return prompt, nil
+	}
+	budget := window - l.config.MaxTokens
+	if config.MaxTokens != nil {
+		budget = window - *config.MaxTokens
+	}
+
+	encoding, err := encodingForModel(model)
+	if err != nil {
+		if err := l.FallbackPolicy(ctx).Allow(InterventionTokenizerFallback, fmt.Sprintf("no tokenizer for model %q, using gpt-4o", model)); err != nil {
+			return nil, err
+		}
+		if encoding, err = encodingForModel("gpt-4o"); err != nil {
+			return nil, fmt.Errorf("failed to get default encoding: %w", err)
+		}
+	}
+
+	excess := len(encoding.Encode(prompt.String(), nil, nil)) - budget
+	if excess <= 0 {
+		return prompt, nil
+	}
+	// NewPrompt also records the input as a user message, so the input may
+	// be sent more than once; every copy is shortened.
+	copies := 1
+	for _, msg := range prompt.Messages {
+		if msg.Content == prompt.Input {
+			copies++
+		}
+	}
+	input := encoding.Encode(prompt.Input, nil, nil)
+	drop := (excess + copies - 1) / copies
+	if drop >= len(input) {
+		return nil, NewLLMError(ErrorTypeInvalidInput, fmt.Sprintf("prompt exceeds the %d-token context window of %s even without its input", window, model), nil)
+	}
+
+	truncated := *prompt
+	truncated.Input = truncateTokens(encoding, input, len(input)-drop, strategy)
+	truncated.Messages = make([]PromptMessage, len(prompt.Messages))
+	for i, msg := range prompt.Messages {
+		if msg.Content == prompt.Input {
+			msg.Content = truncated.Input
+		}
+		truncated.Messages[i] = msg
+	}
+	l.logger.Warn("Prompt truncated to fit context window", "model", model, "strategy", strategy, "context_window", window, "dropped_tokens", drop)
+	return &truncated, nil
+}
+
+// truncateTokens decodes at most keep of tokens, dropping the rest as chosen
+// by strategy.
+func truncateTokens(encoding tokenizer, tokens []int, keep int, strategy TruncateStrategy) string {
+	var text string
+	switch strategy {
+	case TruncateHead:
+		text = encoding.Decode(tokens[len(tokens)-keep:])
+	case TruncateTail:
+		text = encoding.Decode(tokens[:keep])
+	default:
+		keep -= len(encoding.Encode(truncationMarker, nil, nil))
+		if keep <= 0 {
+			return ""
+		}
+		head := keep / 2
+		text = encoding.Decode(tokens[:head]) + truncationMarker + encoding.Decode(tokens[len(tokens)-(keep-head):])
+	}
+	// Token boundaries may split multi-byte characters.
+	return strings.ToValidUTF8(text, "")
+}
@@ -0,0 +1,121 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runeTokenizer encodes every rune as one token.
+type runeTokenizer struct{}
+
+func (runeTokenizer) Encode(text string, allowedSpecial, disallowedSpecial []string) []int {
+	tokens := make([]int, 0, len(text))
+	for _, r := range text {
+		tokens = append(tokens, int(r))
+	}
+	return tokens
+}
+
+func (runeTokenizer) Decode(tokens []int) string {
+	runes := make([]rune, len(tokens))
+	for i, token := range tokens {
+		runes[i] = rune(token)
+	}
+	return string(runes)
+}
+
+func useRuneTokenizer(t *testing.T) {
+	original := encodingForModel
+	encodingForModel = func(model string) (tokenizer, error) { return runeTokenizer{}, nil }
+	t.Cleanup(func() { encodingForModel = original })
+}
+
+func TestContextWindow(t *testing.T) {
+	window, ok := ContextWindow("claude-3-5-sonnet-20241022")
+	assert.True(t, ok)
+	assert.Equal(t, 200000, window)
+
+	// The longest registered prefix wins.
+	window, ok = ContextWindow("gpt-4o-mini")
+	assert.True(t, ok)
+	assert.Equal(t, 128000, window)
+	window, _ = ContextWindow("gpt-4-0613")
+	assert.Equal(t, 8192, window)
+
+	_, ok = ContextWindow("tiny-test-model")
+	assert.False(t, ok)
+	RegisterContextWindow("tiny-test-model", 400)
+	t.Cleanup(func() {
+		contextWindowsMutex.Lock()
+		delete(contextWindows, "tiny-test-model")
+		contextWindowsMutex.Unlock()
+	})
+	window, ok = ContextWindow("tiny-test-model-v2")
+	assert.True(t, ok)
+	assert.Equal(t, 400, window)
+}
+
+func TestWithAutoTruncate(t *testing.T) {
+	useRuneTokenizer(t)
+	RegisterContextWindow("truncate-test-model", 500)
+	l, lastRequest := newCapturingLLM(t)
+	ctx := context.Background()
+	input := strings.Repeat("头", 400) + strings.Repeat("尾", 400)
+
+	userContent := func() string {
+		messages := lastRequest()["messages"].([]interface{})
+		return messages[len(messages)-1].(map[string]interface{})["content"].(string)
+	}
+
+	// The client reserves 300 tokens for the response.
+	for _, tc := range []struct {
+		strategy   TruncateStrategy
+		head, tail bool
+	}{
+		{TruncateTail, true, false},
+		{TruncateHead, false, true},
+		{TruncateMiddle, true, true},
+	} {
+		t.Run(string(tc.strategy), func(t *testing.T) {
+			prompt := NewPrompt(input)
+			_, err := l.Generate(ctx, prompt, WithModel("truncate-test-model"), WithAutoTruncate(tc.strategy))
+			require.NoError(t, err)
+			content := userContent()
+			assert.LessOrEqual(t, len([]rune(content)), 200)
+			assert.Equal(t, tc.head, strings.Contains(content, "头"))
+			assert.Equal(t, tc.tail, strings.Contains(content, "尾"))
+			assert.Equal(t, tc.strategy == TruncateMiddle, strings.Contains(content, "[...]"))
+			assert.Equal(t, input, prompt.Input, "the caller's prompt must not be modified")
+		})
+	}
+
+	// Prompts that fit, and prompts for unknown models, are sent unchanged.
+	_, err := l.Generate(ctx, NewPrompt("你好"), WithModel("truncate-test-model"), WithAutoTruncate(TruncateMiddle))
+	require.NoError(t, err)
+	assert.Contains(t, userContent(), "你好")
+	_, err = l.Generate(ctx, NewPrompt(input), WithModel("unknown-test-model"), WithAutoTruncate(TruncateMiddle))
+	require.NoError(t, err)
+	assert.Contains(t, userContent(), input)
+
+	// A larger max tokens override leaves less room for the prompt.
+	_, err = l.Generate(ctx, NewPrompt(input), WithModel("truncate-test-model"), WithMaxTokens(400), WithAutoTruncate(TruncateTail))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len([]rune(userContent())), 100)
+}
+
+func TestWithAutoTruncate_PromptTooLarge(t *testing.T) {
+	useRuneTokenizer(t)
+	RegisterContextWindow("truncate-test-model", 500)
+	l, _ := newCapturingLLM(t)
+
+	prompt := NewPrompt("总结", WithContext(strings.Repeat("背景", 300)))
+	_, err := l.Generate(context.Background(), prompt, WithModel("truncate-test-model"), WithAutoTruncate(TruncateMiddle))
+	var llmErr *LLMError
+	require.True(t, errors.As(err, &llmErr))
+	assert.Equal(t, ErrorTypeInvalidInput, llmErr.Type)
+}
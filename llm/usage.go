@@ -0,0 +1,119 @@
+// Package llm provides a unified interface for interacting with various Language Learning Model providers.
+package llm
+
+import (
+	"context"
+	"sync"
+)
+
+// TokenUsage is a number of tokens reported by providers. With prompt caching
+// (see WithCachedPrefix), Anthropic reports the prompt tokens written to and
+// read from its cache separately from InputTokens.
+type TokenUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// UsageRecorder sums the token usage of the calls made with a context returned
+// by WithUsageRecorder. It is safe for concurrent use.
+type UsageRecorder struct {
+	mutex             sync.Mutex
+	usage             TokenUsage
+	systemFingerprint string
+}
+
+// Usage returns the token usage recorded so far.
+func (r *UsageRecorder) Usage() TokenUsage {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.usage
+}
+
+type usageRecorderKey struct{}
+
+// WithUsageRecorder returns a context that records the token usage of every
+// Generate call made with it, including calls made by presets and retries.
+// Recorders nest: a call also counts towards recorders of parent contexts.
+//
+// Example:
+//
+//	ctx, recorder := llm.WithUsageRecorder(ctx)
+//	summary, err := presets.Summarize(ctx, client, text)
+//	fmt.Println(recorder.Usage().OutputTokens)
+func WithUsageRecorder(ctx context.Context) (context.Context, *UsageRecorder) {
+	recorder := &UsageRecorder{}
+	// Copy so that sibling contexts do not share the backing array.
+	recorders := append([]*UsageRecorder(nil), usageRecorders(ctx)...)
+	return context.WithValue(ctx, usageRecorderKey{}, append(recorders, recorder)), recorder
+}
+
+// AddTokenUsage adds token usage to the recorders of ctx. LLM implementations
+// call it once per call; custom implementations, such as wrappers around
+// other clients, may call it to report their usage.
+func AddTokenUsage(ctx context.Context, inputTokens, outputTokens int) {
+	addUsage(ctx, TokenUsage{InputTokens: inputTokens, OutputTokens: outputTokens})
+}
+
+// addUsage adds usage, including its cache counts, to the recorders of ctx.
+func addUsage(ctx context.Context, usage TokenUsage) {
+	if usage == (TokenUsage{}) {
+		return
+	}
+	for _, r := range usageRecorders(ctx) {
+		r.mutex.Lock()
+		r.usage.InputTokens += usage.InputTokens
+		r.usage.OutputTokens += usage.OutputTokens
+		r.usage.CacheCreationInputTokens += usage.CacheCreationInputTokens
+		r.usage.CacheReadInputTokens += usage.CacheReadInputTokens
+		r.mutex.Unlock()
+	}
+}
+
+// SystemFingerprint returns the system_fingerprint of the last recorded call
+// whose response had one, or "" if none had. OpenAI-compatible providers
+// report it to identify the backend configuration that served a request: with
+// a fixed seed (see WithSeed), a changed fingerprint explains a changed
+// output.
+func (r *UsageRecorder) SystemFingerprint() string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.systemFingerprint
+}
+
+// recordSystemFingerprint stores fingerprint in the recorders of ctx, unless
+// it is empty.
+func recordSystemFingerprint(ctx context.Context, fingerprint string) {
+	if fingerprint == "" {
+		return
+	}
+	for _, r := range usageRecorders(ctx) {
+		r.mutex.Lock()
+		r.systemFingerprint = fingerprint
+		r.mutex.Unlock()
+	}
+}
+
+type shadowCallKey struct{}
+
+// WithShadowCall returns a context for shadow traffic, such as the candidate
+// calls of gollm.NewShadow. Calls made with it are tagged as shadow calls for
+// tracers and metrics collectors (see utils.CallInfo), and their token usage
+// is not added to the usage recorders of ctx, so it does not count towards
+// the caller's usage.
+func WithShadowCall(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, usageRecorderKey{}, []*UsageRecorder(nil))
+	return context.WithValue(ctx, shadowCallKey{}, true)
+}
+
+// IsShadowCall reports whether a context was created by WithShadowCall.
+func IsShadowCall(ctx context.Context) bool {
+	shadow, _ := ctx.Value(shadowCallKey{}).(bool)
+	return shadow
+}
+
+func usageRecorders(ctx context.Context) []*UsageRecorder {
+	recorders, _ := ctx.Value(usageRecorderKey{}).([]*UsageRecorder)
+	return recorders
+}
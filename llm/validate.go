@@ -115,13 +115,17 @@ func RegisterCustomValidation(tag string, fn validator.Func) error {
 
 // GenerateJSONSchema generates a JSON schema for the given struct.
 // The schema includes type information, validation rules, and nested structures.
+// Options add what struct tags cannot express, such as field descriptions,
+// enums, examples and "$defs", without changing the struct.
 //
 // Parameters:
 //   - v: The struct to generate schema for
+//   - opts: Optional schema options such as WithFieldDescription
 //
 // Returns:
 //   - []byte: The generated JSON schema
-//   - error: Any error encountered during generation
+//   - error: Any error encountered during generation, including an option
+//     naming a field the struct does not have
 //
 // Example:
 //
@@ -131,8 +135,11 @@ func RegisterCustomValidation(tag string, fn validator.Func) error {
 //	    Stop      []string `json:"stop,omitempty"`
 //	}
 //
-//	schema, err := GenerateJSONSchema(&Prompt{})
-func GenerateJSONSchema(v interface{}) ([]byte, error) {
+//	schema, err := GenerateJSONSchema(&Prompt{},
+//	    WithFieldDescription("text", "要补全的文本"),
+//	    WithFieldExample("max_tokens", 256),
+//	)
+func GenerateJSONSchema(v interface{}, opts ...SchemaOption) ([]byte, error) {
 	schema := make(map[string]interface{})
 	schema["type"] = "object"
 	properties, required, err := getStructProperties(reflect.TypeOf(v))
@@ -143,6 +150,9 @@ func GenerateJSONSchema(v interface{}) ([]byte, error) {
 	if len(required) > 0 {
 		schema["required"] = required
 	}
+	if err := newSchemaConfig(opts).apply(schema); err != nil {
+		return nil, err
+	}
 	return json.MarshalIndent(schema, "", "  ")
 }
 
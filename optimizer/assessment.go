@@ -9,6 +9,18 @@ import (
 	"github.com/yockii/gollm_cn/llm"
 )
 
+// assessmentStructure describes the JSON object an assessment must be.
+const assessmentStructure = `{
+	"metrics": [{"name": string, "value": number, "reasoning": string}, ...],
+	"strengths": [{"point": string, "example": string}, ...],
+	"weaknesses": [{"point": string, "example": string}, ...],
+	"suggestions": [{"description": string, "expectedImpact": number, "reasoning": string}, ...],
+	"overallScore": number,
+	"overallGrade": string,
+	"efficiencyScore": number,
+	"alignmentWithGoal": number
+}`
+
 // assessPrompt evaluates a prompt's quality and effectiveness using the configured LLM.
 // It performs a comprehensive analysis considering multiple factors including custom metrics,
 // optimization goals, and historical context.
@@ -39,27 +51,19 @@ func (po *PromptOptimizer) assessPrompt(ctx context.Context, prompt *llm.Prompt)
 		评估以下针对任务的提示词: %s
 
 		完整提示词结构:
-		%+v
+		%s
 
 		最近历史记录:
-		%+v
+		%s
 
-		自定义指标: %v
+		自定义指标:
+		%s
 
 		优化目标: %s
 
 		请在评估时考虑最近的历史记录。
 		以 JSON 对象的形式提供你的评估，结构如下:
-		{
-			"metrics": [{"name": string, "value": number, "reasoning": string}, ...],
-			"strengths": [{"point": string, "example": string}, ...],
-			"weaknesses": [{"point": string, "example": string}, ...],
-			"suggestions": [{"description": string, "expectedImpact": number, "reasoning": string}, ...],
-			"overallScore": number,
-			"overallGrade": string,
-			"efficiencyScore": number,
-			"alignmentWithGoal": number
-		}
+		%s
 
 		重要提示: 
 		- 请勿在你的回复中使用任何 Markdown 格式、代码块或反引号。
@@ -74,29 +78,30 @@ func (po *PromptOptimizer) assessPrompt(ctx context.Context, prompt *llm.Prompt)
 		- 根据建议的预期影响对建议进行排序（20 为最高影响）。
 		- 在你的评估中使用清晰、无术语的语言。
 		- 在提交之前，请仔细检查您的回复是否为有效的 JSON。
-	`, po.taskDesc, prompt, recentHistory, po.customMetrics, po.optimizationGoal))
-
-	// Generate assessment using LLM
-	response, err := po.llm.Generate(ctx, assessPrompt)
-	if err != nil {
-		return OptimizationEntry{}, fmt.Errorf("failed to assess prompt: %w", err)
-	}
+	`, po.taskDesc, prompt.RenderForLLM(), renderHistory(recentHistory), renderMetrics(po.customMetrics), po.optimizationGoal, assessmentStructure))
 
-	// Parse and validate assessment response
-	cleanedResponse := cleanJSONResponse(response)
+	// Generate and parse the assessment, repairing malformed JSON
 	var assessment PromptAssessment
-	err = json.Unmarshal([]byte(cleanedResponse), &assessment)
+	err := po.generateJSON(ctx, assessPrompt, assessmentStructure, po.assessmentOptions, func(response string) error {
+		cleanedResponse, err := cleanJSONResponse(po.llm.FallbackPolicy(ctx), response)
+		if err != nil {
+			return fmt.Errorf("failed to parse assessment response: %w", err)
+		}
+		assessment = PromptAssessment{}
+		if err := json.Unmarshal([]byte(cleanedResponse), &assessment); err != nil {
+			return fmt.Errorf("failed to parse assessment response: %w", err)
+		}
+		if err := llm.Validate(assessment); err != nil {
+			return fmt.Errorf("invalid assessment structure: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		po.debugManager.LogResponse(fmt.Sprintf("Raw response: %s", response))
-		return OptimizationEntry{}, fmt.Errorf("failed to parse assessment response: %w", err)
-	}
-
-	if err := llm.Validate(assessment); err != nil {
-		return OptimizationEntry{}, fmt.Errorf("invalid assessment structure: %w", err)
+		return OptimizationEntry{}, err
 	}
 
 	// Normalize grading for consistency
-	assessment.OverallGrade, err = normalizeGrade(assessment.OverallGrade, assessment.OverallScore)
+	assessment.OverallGrade, err = normalizeGrade(assessment.OverallGrade, assessment.OverallScore, po.llm.FallbackPolicy(ctx))
 	if err != nil {
 		return OptimizationEntry{}, fmt.Errorf("invalid overall grade: %w", err)
 	}
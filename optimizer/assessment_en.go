@@ -39,12 +39,13 @@ func (po *PromptOptimizer) assessPrompt_en(ctx context.Context, prompt *llm.Prom
 		Assess the following prompt for the task: %s
 
 		Full Prompt Structure:
-		%+v
+		%s
 
 		Recent History:
-		%+v
+		%s
 
-		Custom Metrics: %v
+		Custom Metrics:
+		%s
 
 		Optimization Goal: %s
 
@@ -74,16 +75,20 @@ func (po *PromptOptimizer) assessPrompt_en(ctx context.Context, prompt *llm.Prom
 		- Rank suggestions by their expected impact (20 being highest impact).
 		- Use clear, jargon-free language in your assessment.
 		- Double-check that your response is valid JSON before submitting.
-	`, po.taskDesc, prompt, recentHistory, po.customMetrics, po.optimizationGoal))
+	`, po.taskDesc, prompt.RenderForLLM(), renderHistory(recentHistory), renderMetrics(po.customMetrics), po.optimizationGoal))
 
 	// Generate assessment using LLM
-	response, err := po.llm.Generate(ctx, assessPrompt)
+	response, err := po.llm.Generate(ctx, assessPrompt, po.assessmentOptions...)
 	if err != nil {
 		return OptimizationEntry{}, fmt.Errorf("failed to assess prompt: %w", err)
 	}
 
 	// Parse and validate assessment response
-	cleanedResponse := cleanJSONResponse(response)
+	policy := po.llm.FallbackPolicy(ctx)
+	cleanedResponse, err := cleanJSONResponse(policy, response)
+	if err != nil {
+		return OptimizationEntry{}, fmt.Errorf("failed to parse assessment response: %w", err)
+	}
 	var assessment PromptAssessment
 	err = json.Unmarshal([]byte(cleanedResponse), &assessment)
 	if err != nil {
@@ -96,7 +101,7 @@ func (po *PromptOptimizer) assessPrompt_en(ctx context.Context, prompt *llm.Prom
 	}
 
 	// Normalize grading for consistency
-	assessment.OverallGrade, err = normalizeGrade(assessment.OverallGrade, assessment.OverallScore)
+	assessment.OverallGrade, err = normalizeGrade(assessment.OverallGrade, assessment.OverallScore, policy)
 	if err != nil {
 		return OptimizationEntry{}, fmt.Errorf("invalid overall grade: %w", err)
 	}
@@ -0,0 +1,133 @@
+// Package optimizer provides prompt optimization capabilities for Language Learning Models.
+package optimizer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// compareConcurrency is the number of prompts ComparePrompts assesses at
+// the same time.
+const compareConcurrency = 5
+
+// RankedPrompt is a prompt ranked by ComparePrompts.
+type RankedPrompt struct {
+	// Prompt is the compared prompt
+	Prompt *llm.Prompt
+
+	// Score is the composite score the prompts are ranked by (0-20 scale)
+	Score float64
+
+	// Assessment is the full assessment of the prompt
+	Assessment PromptAssessment
+
+	// Rank is the position of the prompt, starting at 1; prompts with the
+	// same score share a rank
+	Rank int
+}
+
+// ComparePrompts ranks existing prompts against a goal without optimizing
+// them. Each prompt is assessed as in an OptimizePrompt iteration, with the
+// given metrics, and the prompts are assessed in parallel. The composite
+// score of a prompt is the mean of its overall score, its alignment with the
+// goal, its efficiency and the mean of its metrics, all on the 0-20 scale.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeout
+//   - l: LLM assessing the prompts
+//   - prompts: The prompts to compare
+//   - goal: What the prompts are for, used as task and optimization goal
+//   - metrics: Custom metrics each prompt is assessed on, may be empty
+//   - opts: Optional optimizer options such as WithAssessmentOptions and
+//     WithMaxRetries; options about iterations have no effect
+//
+// Returns:
+//   - The prompts sorted by descending score, prompts with equal scores in
+//     their original order
+//   - Error if a prompt cannot be assessed
+//
+// Example:
+//
+//	ranked, err := optimizer.ComparePrompts(ctx, l,
+//	    []*llm.Prompt{llm.NewPrompt("总结文本"), llm.NewPrompt("用三点总结文本的要点")},
+//	    "生成准确简洁的摘要",
+//	    []optimizer.Metric{{Name: "简洁性", Description: "摘要是否简短"}},
+//	)
+//	fmt.Printf("%d. %s (%.1f)\n", ranked[0].Rank, ranked[0].Prompt.Input, ranked[0].Score)
+func ComparePrompts(ctx context.Context, l llm.LLM, prompts []*llm.Prompt, goal string, metrics []Metric, opts ...OptimizerOption) ([]RankedPrompt, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if len(prompts) == 0 {
+		return nil, fmt.Errorf("at least one prompt must be provided")
+	}
+	for i, prompt := range prompts {
+		if prompt == nil {
+			return nil, fmt.Errorf("prompt %d is nil", i)
+		}
+	}
+
+	debugManager := utils.NewDebugManager(l.GetLogger(), utils.DebugOptions{})
+	po := NewPromptOptimizer(l, debugManager, nil, goal,
+		append([]OptimizerOption{WithOptimizationGoal(goal), WithCustomMetrics(metrics...)}, opts...)...)
+
+	ranked := make([]RankedPrompt, len(prompts))
+	errs := make([]error, len(prompts))
+	slots := make(chan struct{}, compareConcurrency)
+	var wg sync.WaitGroup
+	for i, prompt := range prompts {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			entry, err := po.assessPrompt(ctx, prompt)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			ranked[i] = RankedPrompt{Prompt: prompt, Score: compositeScore(entry.Assessment), Assessment: entry.Assessment}
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("failed to assess prompt %d: %w", i, err)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+		if i > 0 && ranked[i].Score == ranked[i-1].Score {
+			ranked[i].Rank = ranked[i-1].Rank
+		}
+	}
+	return ranked, nil
+}
+
+// compositeScore returns the score ComparePrompts ranks an assessment by.
+func compositeScore(a PromptAssessment) float64 {
+	parts := []float64{a.OverallScore, a.AlignmentWithGoal, a.EfficiencyScore}
+	if len(a.Metrics) > 0 {
+		var sum float64
+		for _, m := range a.Metrics {
+			sum += m.Value
+		}
+		parts = append(parts, sum/float64(len(a.Metrics)))
+	}
+	var total float64
+	for _, p := range parts {
+		total += p
+	}
+	return total / float64(len(parts))
+}
@@ -0,0 +1,71 @@
+package optimizer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// scoringLLM assesses each prompt with the overall score listed for a text
+// the prompt contains.
+type scoringLLM struct {
+	fencedLLM
+	scores map[string]float64
+}
+
+func (s *scoringLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	for text, score := range s.scores {
+		if strings.Contains(prompt.Input, text) {
+			return fmt.Sprintf(`{
+				"metrics": [{"name": "简洁性", "value": %[1]g}],
+				"strengths": [{"point": "任务明确"}],
+				"weaknesses": [{"point": "缺少示例"}],
+				"suggestions": [{"description": "添加示例", "expectedImpact": 10}],
+				"overallScore": %[1]g,
+				"overallGrade": "B",
+				"efficiencyScore": 12,
+				"alignmentWithGoal": %[1]g
+			}`, score), nil
+		}
+	}
+	return "无法评估", nil
+}
+
+func (s *scoringLLM) GetLogger() utils.Logger {
+	return utils.NewLogger(utils.LogLevelOff)
+}
+
+func TestComparePrompts(t *testing.T) {
+	l := &scoringLLM{scores: map[string]float64{"总结文本": 10, "用三点总结": 16, "概括全文": 10}}
+	prompts := []*llm.Prompt{llm.NewPrompt("总结文本"), llm.NewPrompt("用三点总结要点"), llm.NewPrompt("概括全文")}
+
+	ranked, err := ComparePrompts(context.Background(), l, prompts, "生成简洁的摘要", []Metric{{Name: "简洁性", Description: "摘要是否简短"}})
+	require.NoError(t, err)
+	require.Len(t, ranked, 3)
+	assert.Same(t, prompts[1], ranked[0].Prompt)
+	assert.Equal(t, 1, ranked[0].Rank)
+	assert.Equal(t, 15.0, ranked[0].Score, "mean of overall 16, alignment 16, efficiency 12 and metrics 16")
+	assert.Equal(t, 16.0, ranked[0].Assessment.OverallScore)
+	assert.Same(t, prompts[0], ranked[1].Prompt, "ties keep their order")
+	assert.Same(t, prompts[2], ranked[2].Prompt)
+	assert.Equal(t, []int{2, 2}, []int{ranked[1].Rank, ranked[2].Rank}, "ties share a rank")
+	assert.Equal(t, 10.5, ranked[1].Score)
+}
+
+func TestComparePrompts_Errors(t *testing.T) {
+	l := &scoringLLM{scores: map[string]float64{"总结文本": 10}}
+	ctx := context.Background()
+
+	_, err := ComparePrompts(ctx, l, []*llm.Prompt{llm.NewPrompt("总结文本"), llm.NewPrompt("翻译文本")}, "摘要", nil, WithMaxRetries(0))
+	assert.ErrorContains(t, err, "failed to assess prompt 1")
+	_, err = ComparePrompts(ctx, l, nil, "摘要", nil)
+	assert.ErrorContains(t, err, "at least one prompt must be provided")
+	_, err = ComparePrompts(ctx, l, []*llm.Prompt{nil}, "摘要", nil)
+	assert.ErrorContains(t, err, "prompt 0 is nil")
+}
@@ -0,0 +1,176 @@
+// Package optimizer provides prompt optimization capabilities for Language Learning Models.
+package optimizer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/yockii/gollm_cn/llm"
+)
+
+// datasetMetricName is the name of the metric WithTestDataset adds to each
+// assessment.
+const datasetMetricName = "数据集准确率"
+
+// DatasetEntry is a labelled example a prompt is tested against.
+type DatasetEntry struct {
+	// Input is appended to the prompt input when the prompt is run
+	Input string `json:"input"`
+
+	// ExpectedOutput is the response the prompt should produce for Input
+	ExpectedOutput string `json:"expectedOutput"`
+}
+
+// Scorer rates a response against the expected output, from 0 (wrong) to 1
+// (correct).
+type Scorer func(got, want string) float64
+
+// ExactMatchScorer scores 1 if got equals want, ignoring surrounding
+// whitespace, and 0 otherwise.
+func ExactMatchScorer(got, want string) float64 {
+	if strings.TrimSpace(got) == strings.TrimSpace(want) {
+		return 1
+	}
+	return 0
+}
+
+// ContainsScorer scores 1 if got contains want, ignoring case and surrounding
+// whitespace, and 0 otherwise. It suits prompts whose responses wrap the
+// answer in explanations.
+func ContainsScorer(got, want string) float64 {
+	if strings.Contains(strings.ToLower(got), strings.ToLower(strings.TrimSpace(want))) {
+		return 1
+	}
+	return 0
+}
+
+// RougeScorer scores the ROUGE-L F1 of got against want: the harmonic mean of
+// the precision and recall of their longest common subsequence of tokens.
+// Words are tokens, and so is each CJK character, so the score suits Chinese
+// text as well as English.
+func RougeScorer(got, want string) float64 {
+	gotTokens, wantTokens := rougeTokens(got), rougeTokens(want)
+	if len(gotTokens) == 0 || len(wantTokens) == 0 {
+		if len(gotTokens) == len(wantTokens) {
+			return 1
+		}
+		return 0
+	}
+	lcs := lcsLength(gotTokens, wantTokens)
+	if lcs == 0 {
+		return 0
+	}
+	precision := float64(lcs) / float64(len(gotTokens))
+	recall := float64(lcs) / float64(len(wantTokens))
+	return 2 * precision * recall / (precision + recall)
+}
+
+// rougeTokens splits text into lower-case words and single CJK characters,
+// dropping punctuation.
+func rougeTokens(text string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				curr[j+1] = prev[j] + 1
+			} else {
+				curr[j+1] = max(prev[j+1], curr[j])
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// WithTestDataset makes the optimizer run each assessed prompt against every
+// entry of dataset and add the average score of the responses, scaled to
+// 0-20, to the assessment as the metric "数据集准确率". The overall score of
+// the assessment is then the average of the judge's score and this metric, so
+// the dataset counts as much as the judge when the best prompt is chosen and
+// when a numerical goal is checked. A nil scorer scores exact matches.
+//
+// Parameters:
+//   - dataset: Labelled inputs and expected outputs
+//   - scorer: Rates each response, e.g. ExactMatchScorer, ContainsScorer or RougeScorer
+func WithTestDataset(dataset []DatasetEntry, scorer Scorer) OptimizerOption {
+	return func(po *PromptOptimizer) {
+		if scorer == nil {
+			scorer = ExactMatchScorer
+		}
+		po.dataset = dataset
+		po.scorer = scorer
+	}
+}
+
+// evaluateDataset runs prompt against the test dataset and returns the
+// accuracy metric. An entry whose response fails to generate scores 0.
+func (po *PromptOptimizer) evaluateDataset(ctx context.Context, prompt *llm.Prompt) (Metric, error) {
+	var total float64
+	failed := 0
+	for i, entry := range po.dataset {
+		response, err := po.llm.Generate(ctx, datasetPrompt(prompt, entry.Input))
+		if err != nil {
+			if ctx.Err() != nil {
+				return Metric{}, ctx.Err()
+			}
+			po.debugManager.LogResponse(fmt.Sprintf("Dataset entry %d failed: %v", i+1, err))
+			failed++
+			continue
+		}
+		total += po.scorer(response, entry.ExpectedOutput)
+	}
+	accuracy := total / float64(len(po.dataset))
+	reasoning := fmt.Sprintf("%d 条测试数据的平均得分为 %.2f", len(po.dataset), accuracy)
+	if failed > 0 {
+		reasoning += fmt.Sprintf("，其中 %d 条生成失败，按 0 分计", failed)
+	}
+	return Metric{
+		Name:        datasetMetricName,
+		Description: "提示词在带标注测试数据集上的表现",
+		Value:       accuracy * 20,
+		Reasoning:   reasoning,
+	}, nil
+}
+
+// datasetPrompt returns a copy of prompt with input appended to its input,
+// and to the user message that repeats it.
+func datasetPrompt(prompt *llm.Prompt, input string) *llm.Prompt {
+	p := *prompt
+	p.Input = prompt.Input + "\n\n" + input
+	p.Messages = make([]llm.PromptMessage, len(prompt.Messages))
+	for i, m := range prompt.Messages {
+		if m.Role == "user" && m.Content == prompt.Input {
+			m.Content = p.Input
+		}
+		p.Messages[i] = m
+	}
+	return &p
+}
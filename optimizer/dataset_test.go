@@ -0,0 +1,83 @@
+package optimizer
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestScorers(t *testing.T) {
+	assert.Equal(t, 1.0, ExactMatchScorer(" 正面\n", "正面"))
+	assert.Equal(t, 0.0, ExactMatchScorer("正面。", "正面"))
+
+	assert.Equal(t, 1.0, ContainsScorer("The sentiment is Positive.", "positive"))
+	assert.Equal(t, 0.0, ContainsScorer("负面", "正面"))
+
+	assert.Equal(t, 1.0, RougeScorer("The cat sat.", "the cat sat"))
+	assert.InDelta(t, 10.0/11.0, RougeScorer("今天天气很好", "今天天气好"), 1e-9)
+	assert.InDelta(t, 2.0/3.0, RougeScorer("the cat", "the black cat sat"), 1e-9)
+	assert.Equal(t, 0.0, RougeScorer("dog", "cat"))
+	assert.Equal(t, 0.0, RougeScorer("", "cat"))
+}
+
+func TestOptimizePrompt_TestDataset(t *testing.T) {
+	debugManager := utils.NewDebugManager(utils.NewLogger(utils.LogLevelOff), utils.DebugOptions{})
+	l := &sequenceLLM{responses: []string{fencedAssessment, "正面", "情感：正面"}}
+	var entries []OptimizationEntry
+	po := NewPromptOptimizer(l, debugManager, llm.NewPrompt("判断评论的情感"), "情感分类",
+		WithRatingSystem("numerical"),
+		WithThreshold(0.5),
+		WithTestDataset([]DatasetEntry{
+			{Input: "物流很快，包装完好", ExpectedOutput: "正面"},
+			{Input: "质量太差了", ExpectedOutput: "负面"},
+		}, ContainsScorer),
+		WithIterationCallback(func(iteration int, entry OptimizationEntry) {
+			entries = append(entries, entry)
+		}),
+	)
+
+	_, err := po.OptimizePrompt(context.Background())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	metrics := entries[0].Assessment.Metrics
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "数据集准确率", metrics[1].Name)
+	assert.Equal(t, 10.0, metrics[1].Value)
+	assert.Equal(t, 12.5, entries[0].Assessment.OverallScore, "the judge's 15 averaged with the dataset's 10")
+	assert.Equal(t, "判断评论的情感\n\n物流很快，包装完好", l.prompts[1].Input)
+	assert.Equal(t, "判断评论的情感\n\n物流很快，包装完好", l.prompts[1].Messages[0].Content)
+	assert.Equal(t, "判断评论的情感", po.initialPrompt.Messages[0].Content, "the prompt is not modified")
+}
+
+func TestOptimizePrompt_TestDatasetChoosesBest(t *testing.T) {
+	debugManager := utils.NewDebugManager(utils.NewLogger(utils.LogLevelOff), utils.DebugOptions{})
+	improved := `{
+		"incrementalImprovement": {"input": "判断评论的情感，只回答正面或负面"},
+		"boldRedesign": {"input": "判断评论的情感，只回答正面或负面"},
+		"expectedImpact": {"incremental": 10, "bold": 15}
+	}`
+	l := &sequenceLLM{responses: []string{
+		strings.Replace(fencedAssessment, `"overallScore": 15`, `"overallScore": 17`, 1), "好评",
+		improved,
+		fencedAssessment, "正面",
+		improved,
+	}}
+	po := NewPromptOptimizer(l, debugManager, llm.NewPrompt("判断评论的情感"), "情感分类",
+		WithIterations(2),
+		WithTestDataset([]DatasetEntry{{Input: "物流很快，包装完好", ExpectedOutput: "正面"}}, ExactMatchScorer),
+	)
+
+	best, err := po.OptimizePrompt(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "判断评论的情感，只回答正面或负面", best.Input,
+		"the prompt the judge scores lower wins on the dataset")
+	history := po.GetOptimizationHistory()
+	require.Len(t, history, 2)
+	assert.Equal(t, 8.5, history[0].Assessment.OverallScore)
+	assert.Equal(t, 17.5, history[1].Assessment.OverallScore)
+}
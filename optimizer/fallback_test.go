@@ -0,0 +1,113 @@
+package optimizer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// fencedLLM answers every Generate call with the same response.
+type fencedLLM struct {
+	llm.LLM
+	response string
+	strict   bool
+}
+
+func (f *fencedLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	return f.response, nil
+}
+
+func (f *fencedLLM) FallbackPolicy(ctx context.Context) *llm.FallbackPolicy {
+	return llm.NewFallbackPolicy(f.strict || llm.IsStrictMode(ctx), nil)
+}
+
+const fencedAssessment = "以下是评估：\n```json\n" + `{
+	"metrics": [{"name": "清晰度", "value": 15}],
+	"strengths": [{"point": "任务明确"}],
+	"weaknesses": [{"point": "缺少示例"}],
+	"suggestions": [{"description": "添加示例", "expectedImpact": 12}],
+	"overallScore": 15,
+	"overallGrade": "A-",
+	"efficiencyScore": 14,
+	"alignmentWithGoal": 16
+}` + "\n```"
+
+func TestAssessPrompt_JSONExtraction(t *testing.T) {
+	debugManager := utils.NewDebugManager(utils.NewLogger(utils.LogLevelOff), utils.DebugOptions{})
+	po := NewPromptOptimizer(&fencedLLM{response: fencedAssessment}, debugManager, llm.NewPrompt("总结文本"), "总结")
+
+	entry, err := po.assessPrompt(context.Background(), po.initialPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "A-", entry.Assessment.OverallGrade)
+
+	_, err = po.assessPrompt(llm.WithStrictMode(context.Background()), po.initialPrompt)
+	var interventionErr *llm.InterventionError
+	require.True(t, errors.As(err, &interventionErr))
+	assert.Equal(t, llm.InterventionJSONExtraction, interventionErr.Intervention)
+}
+
+func TestGenerateImprovedPrompt_JSONExtraction(t *testing.T) {
+	response := "```json\n" + `{
+		"incrementalImprovement": {"input": "请总结文本"},
+		"boldRedesign": {"input": "用三点总结文本"},
+		"expectedImpact": {"incremental": 10, "bold": 15}
+	}` + "\n```"
+	debugManager := utils.NewDebugManager(utils.NewLogger(utils.LogLevelOff), utils.DebugOptions{})
+
+	po := NewPromptOptimizer(&fencedLLM{response: response}, debugManager, llm.NewPrompt("总结文本"), "总结")
+	improved, err := po.generateImprovedPrompt(context.Background(), OptimizationEntry{Prompt: po.initialPrompt})
+	require.NoError(t, err)
+	assert.Equal(t, "用三点总结文本", improved.Input)
+
+	po = NewPromptOptimizer(&fencedLLM{response: response, strict: true}, debugManager, llm.NewPrompt("总结文本"), "总结")
+	_, err = po.generateImprovedPrompt(context.Background(), OptimizationEntry{Prompt: po.initialPrompt})
+	var interventionErr *llm.InterventionError
+	assert.True(t, errors.As(err, &interventionErr))
+}
+
+func TestNormalizeGrade_Policy(t *testing.T) {
+	grade, err := normalizeGrade("16", 16, llm.NewFallbackPolicy(false, nil))
+	require.NoError(t, err)
+	assert.Equal(t, "A-", grade)
+
+	_, err = normalizeGrade("16", 16, llm.NewFallbackPolicy(true, nil))
+	var interventionErr *llm.InterventionError
+	require.True(t, errors.As(err, &interventionErr))
+	assert.Equal(t, llm.InterventionGradeNormalization, interventionErr.Intervention)
+
+	// Letter grades need no normalization, even in strict mode.
+	grade, err = normalizeGrade("B+", 13, llm.NewFallbackPolicy(true, nil))
+	require.NoError(t, err)
+	assert.Equal(t, "B+", grade)
+}
+
+func TestCleanJSONResponse(t *testing.T) {
+	policy := llm.NewFallbackPolicy(false, nil)
+	cases := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"already JSON", `{"score": 8}`, `{"score": 8}`},
+		{"fence with explanation", "```json\n{\"score\": 8}\n```\n评分依据是 {清晰度}。", `{"score": 8}`},
+		{"byte order mark", "\ufeff{\"score\": 8}", `{"score": 8}`},
+		{"smart quotes", `评估结果：{“grade”: “A-”}`, `{"grade": "A-"}`},
+		{"example fence first", "```text\n总结文本\n```\n```json\n{\"score\": 8}\n```", `{"score": 8}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := cleanJSONResponse(policy, tc.response)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := cleanJSONResponse(llm.NewFallbackPolicy(true, nil), `评估结果：{“grade”: “A-”}`)
+	var interventionErr *llm.InterventionError
+	assert.True(t, errors.As(err, &interventionErr), "strict mode refuses the repair")
+}
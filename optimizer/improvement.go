@@ -9,6 +9,26 @@ import (
 	"github.com/yockii/gollm_cn/llm"
 )
 
+// improvementStructure describes the JSON object with improved prompts.
+const improvementStructure = `{
+	"incrementalImprovement": {
+		"input": "改进的提示词文本",
+		"directives": ["指令1", "指令2", ...],
+		"examples": ["示例1", "示例2", ...],
+		"reasoning": "变更的解释及其与评估的联系"
+	},
+	"boldRedesign": {
+		"input": "重新设计的提示词文本",
+		"directives": ["指令1", "指令2", ...],
+		"examples": ["示例1", "示例2", ...],
+		"reasoning": "对新方法的解释及其潜在优势"
+	},
+	"expectedImpact": {
+		"incremental": number,
+		"bold": number
+	}
+}`
+
 // generateImprovedPrompt creates an enhanced version of a prompt based on its assessment
 // and optimization history. It employs a dual-strategy approach, generating both
 // incremental improvements and bold redesigns.
@@ -61,11 +81,14 @@ func (po *PromptOptimizer) generateImprovedPrompt(ctx context.Context, prevEntry
 	improvePrompt := llm.NewPrompt(fmt.Sprintf(`
 		基于以下评估和最近的历史记录，生成整个提示词结构的改进版本：
 
-		先前的提示词: %+v
-		评估: %+v
+		先前的提示词:
+		%s
+
+		评估:
+		%s
 
 		最近的历史记录:
-		%+v
+		%s
 
 		任务描述: %s
 		优化目标: %s
@@ -78,24 +101,7 @@ func (po *PromptOptimizer) generateImprovedPrompt(ctx context.Context, prevEntry
 
 		重要提示：仅以原始 JSON 对象回复。请勿使用任何 Markdown 格式、代码块或反引号。
 		JSON 对象应具有以下结构：
-		{
-			"incrementalImprovement": {
-				"input": "改进的提示词文本",
-				"directives": ["指令1", "指令2", ...],
-				"examples": ["示例1", "示例2", ...],
-				"reasoning": "变更的解释及其与评估的联系"
-			},
-			"boldRedesign": {
-				"input": "重新设计的提示词文本",
-				"directives": ["指令1", "指令2", ...],
-				"examples": ["示例1", "示例2", ...],
-				"reasoning": "对新方法的解释及其潜在优势"
-			},
-			"expectedImpact": {
-				"incremental": number,
-				"bold": number
-			}
-		}
+		%s
 
 		对于每个改进：
 		- 直接解决评估中发现的弱点。
@@ -107,24 +113,12 @@ func (po *PromptOptimizer) generateImprovedPrompt(ctx context.Context, prevEntry
 		- 以 0 到 20 的等级对每个版本的预期影响进行评级。
 
 		在提交之前，请仔细检查您的回复是否为有效的 JSON。
-	`, prevEntry.Prompt, prevEntry.Assessment, recentHistory, po.taskDesc, po.optimizationGoal))
+	`, prevEntry.Prompt.RenderForLLM(), renderAssessment(prevEntry.Assessment), renderHistory(recentHistory), po.taskDesc, po.optimizationGoal, improvementStructure))
 
 	// Log the improvement request for debugging
 	po.debugManager.LogPrompt(improvePrompt.String())
 
-	// Generate improvements using LLM
-	response, err := po.llm.Generate(ctx, improvePrompt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate improved prompt: %w", err)
-	}
-
-	// Log the raw response for debugging
-	po.debugManager.LogResponse(response)
-
-	// Extract and parse JSON response
-	cleanedResponse := cleanJSONResponse(response)
-
-	var improvedPrompts struct {
+	type improvements struct {
 		IncrementalImprovement llm.Prompt `json:"incrementalImprovement"`
 		BoldRedesign           llm.Prompt `json:"boldRedesign"`
 		ExpectedImpact         struct {
@@ -132,10 +126,26 @@ func (po *PromptOptimizer) generateImprovedPrompt(ctx context.Context, prevEntry
 			Bold        float64 `json:"bold"`
 		} `json:"expectedImpact"`
 	}
+	var improvedPrompts improvements
 
-	err = json.Unmarshal([]byte(cleanedResponse), &improvedPrompts)
+	// Generate and parse the improvements, repairing malformed JSON
+	err := po.generateJSON(ctx, improvePrompt, improvementStructure, nil, func(response string) error {
+		// Log the raw response for debugging
+		po.debugManager.LogResponse(response)
+
+		cleanedResponse, err := cleanJSONResponse(po.llm.FallbackPolicy(ctx), response)
+		if err != nil {
+			return fmt.Errorf("failed to parse improved prompts: %w", err)
+		}
+		var parsed improvements
+		if err := json.Unmarshal([]byte(cleanedResponse), &parsed); err != nil {
+			return fmt.Errorf("failed to parse improved prompts: %w", err)
+		}
+		improvedPrompts = parsed
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse improved prompts: %w", err)
+		return nil, err
 	}
 
 	// Select the improvement with higher expected impact
@@ -61,11 +61,14 @@ func (po *PromptOptimizer) generateImprovedPrompt_en(ctx context.Context, prevEn
 	improvePrompt := llm.NewPrompt(fmt.Sprintf(`
 		Based on the following assessment and recent history, generate an improved version of the entire prompt structure:
 
-		Previous prompt: %+v
-		Assessment: %+v
+		Previous prompt:
+		%s
+
+		Assessment:
+		%s
 
 		Recent History:
-		%+v
+		%s
 
 		Task Description: %s
 		Optimization Goal: %s
@@ -107,7 +110,7 @@ func (po *PromptOptimizer) generateImprovedPrompt_en(ctx context.Context, prevEn
 		- Rate the expected impact of each version on a scale of 0 to 20.
 
 		Double-check that your response is valid JSON before submitting.
-	`, prevEntry.Prompt, prevEntry.Assessment, recentHistory, po.taskDesc, po.optimizationGoal))
+	`, prevEntry.Prompt.RenderForLLM(), renderAssessment(prevEntry.Assessment), renderHistory(recentHistory), po.taskDesc, po.optimizationGoal))
 
 	// Log the improvement request for debugging
 	po.debugManager.LogPrompt(improvePrompt.String())
@@ -122,7 +125,10 @@ func (po *PromptOptimizer) generateImprovedPrompt_en(ctx context.Context, prevEn
 	po.debugManager.LogResponse(response)
 
 	// Extract and parse JSON response
-	cleanedResponse := cleanJSONResponse(response)
+	cleanedResponse, err := cleanJSONResponse(po.llm.FallbackPolicy(ctx), response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse improved prompts: %w", err)
+	}
 
 	var improvedPrompts struct {
 		IncrementalImprovement llm.Prompt `json:"incrementalImprovement"`
@@ -3,6 +3,8 @@ package optimizer
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"time"
 
@@ -167,7 +169,7 @@ func NewPromptOptimizer(llm llm.LLM, debugManager *utils.DebugManager, initialPr
 // OptimizePrompt performs iterative optimization of the initial prompt to meet the specified goal.
 //
 // The optimization process:
-// 1. Assesses the current prompt
+// 1. Assesses the current prompt, and tests it against the dataset of WithTestDataset if set
 // 2. Records assessment in history
 // 3. Checks if optimization goal is met
 // 4. Generates improved prompt if goal not met
@@ -183,6 +185,7 @@ func (po *PromptOptimizer) OptimizePrompt(ctx context.Context) (*llm.Prompt, err
 	currentPrompt := po.initialPrompt
 	var bestPrompt *llm.Prompt
 	var bestScore float64
+	po.runID = newRunID()
 
 	for i := 0; i < po.iterations; i++ {
 		var entry OptimizationEntry
@@ -206,6 +209,18 @@ func (po *PromptOptimizer) OptimizePrompt(ctx context.Context) (*llm.Prompt, err
 			return bestPrompt, fmt.Errorf("optimization failed at iteration %d after %d attempts: %w", i+1, po.maxRetries, err)
 		}
 
+		if len(po.dataset) > 0 {
+			metric, err := po.evaluateDataset(ctx, currentPrompt)
+			if err != nil {
+				return bestPrompt, fmt.Errorf("dataset evaluation failed at iteration %d: %w", i+1, err)
+			}
+			entry.Assessment.Metrics = append(entry.Assessment.Metrics, metric)
+			entry.Assessment.OverallScore = (entry.Assessment.OverallScore + metric.Value) / 2
+		}
+
+		entry.RunID = po.runID
+		entry.JudgeModel = po.judgeModel()
+		entry.AssessedAt = time.Now()
 		po.history = append(po.history, entry)
 
 		// Execute iteration callback if set
@@ -242,6 +257,24 @@ func (po *PromptOptimizer) OptimizePrompt(ctx context.Context) (*llm.Prompt, err
 	return bestPrompt, nil
 }
 
+// newRunID returns a random identifier for an optimization run.
+func newRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("run-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// judgeModel returns the model assessing prompts, or an empty string when
+// the LLM does not report one.
+func (po *PromptOptimizer) judgeModel() string {
+	if m, ok := po.llm.(interface{ GetModel() string }); ok {
+		return m.GetModel()
+	}
+	return ""
+}
+
 // GetOptimizationHistory returns the complete history of optimization attempts.
 func (po *PromptOptimizer) GetOptimizationHistory() []OptimizationEntry {
 	return po.history
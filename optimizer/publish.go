@@ -0,0 +1,86 @@
+// Package optimizer provides prompt optimization capabilities for Language Learning Models.
+package optimizer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yockii/gollm_cn/llm"
+)
+
+// Metadata keys set by PublishResult on published templates.
+const (
+	MetadataRunID      = "optimizer.run_id"
+	MetadataScore      = "optimizer.score"
+	MetadataGrade      = "optimizer.grade"
+	MetadataJudgeModel = "optimizer.judge_model"
+	MetadataAssessedAt = "optimizer.assessed_at"
+)
+
+// templateEscaper turns literal template delimiters into actions printing them,
+// so text from an optimized prompt renders verbatim.
+var templateEscaper = strings.NewReplacer("{{", `{{"{{"}}`, "}}", `{{"}}"}}`)
+
+// PublishResult registers the prompt of an optimization entry in registry as
+// version of the template name (see llm.TemplateRegistry.RegisterVersion).
+// Braces in the prompt are escaped, so executing the template reproduces the
+// prompt exactly instead of interpreting any "{{" it contains. The entry's
+// provenance is attached as template metadata under the Metadata* keys.
+//
+// Parameters:
+//   - registry: Registry to publish into
+//   - name: Template name
+//   - version: Version to publish, such as "1.2.0"
+//   - entry: Optimization entry whose prompt is published, typically the best
+//     entry of GetOptimizationHistory
+//
+// Returns:
+//   - llm.ErrTemplateVersionExists if the version is already published; it is
+//     never overwritten
+//   - Error if the entry has no prompt
+//
+// Example:
+//
+//	err := optimizer.PublishResult(registry, "summarize", "1.2.0", best)
+//	pt, _ := registry.Get("summarize@1.2.0")
+//	fmt.Println(pt.Metadata[optimizer.MetadataScore])
+func PublishResult(registry *llm.TemplateRegistry, name, version string, entry OptimizationEntry) error {
+	if entry.Prompt == nil {
+		return fmt.Errorf("optimization entry has no prompt")
+	}
+	source := entry.Prompt
+
+	metadata := map[string]string{
+		MetadataScore: strconv.FormatFloat(entry.Assessment.OverallScore, 'f', -1, 64),
+		MetadataGrade: entry.Assessment.OverallGrade,
+	}
+	if entry.RunID != "" {
+		metadata[MetadataRunID] = entry.RunID
+	}
+	if entry.JudgeModel != "" {
+		metadata[MetadataJudgeModel] = entry.JudgeModel
+	}
+	if !entry.AssessedAt.IsZero() {
+		metadata[MetadataAssessedAt] = entry.AssessedAt.UTC().Format(time.RFC3339)
+	}
+
+	pt := llm.NewPromptTemplate(name, "", templateEscaper.Replace(source.Input),
+		llm.WithPromptOptions(func(p *llm.Prompt) {
+			p.Output = source.Output
+			p.Directives = append([]string(nil), source.Directives...)
+			p.Context = source.Context
+			p.MaxLength = source.MaxLength
+			p.Examples = append([]string(nil), source.Examples...)
+			p.SystemPrompt = source.SystemPrompt
+			p.SystemCacheType = source.SystemCacheType
+			p.CachedPrefix = source.CachedPrefix
+		}),
+		llm.WithMetadata(metadata),
+	)
+	if err := registry.RegisterVersion(version, pt); err != nil {
+		return fmt.Errorf("failed to publish optimized prompt: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package optimizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/llm"
+)
+
+func TestPublishResult(t *testing.T) {
+	input := `以 JSON 返回 {"name": "..."}，不要输出 {{.Secret}} 或 }}`
+	entry := OptimizationEntry{
+		Prompt:     llm.NewPrompt(input, llm.WithDirectives("保持简洁"), llm.WithMaxLength(50)),
+		Assessment: PromptAssessment{OverallScore: 17.5, OverallGrade: "A"},
+		RunID:      "run-1",
+		JudgeModel: "gpt-4o",
+		AssessedAt: time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC),
+	}
+
+	registry := llm.NewTemplateRegistry()
+	require.NoError(t, PublishResult(registry, "extract", "1.0.0", entry))
+
+	pt, ok := registry.Get("extract@1.0.0")
+	require.True(t, ok)
+	assert.Equal(t, map[string]string{
+		MetadataRunID:      "run-1",
+		MetadataScore:      "17.5",
+		MetadataGrade:      "A",
+		MetadataJudgeModel: "gpt-4o",
+		MetadataAssessedAt: "2026-10-16T08:00:00Z",
+	}, pt.Metadata)
+
+	prompt, err := registry.Execute("extract@1.0.0", nil)
+	require.NoError(t, err)
+	assert.Equal(t, input, prompt.Input, "braces render literally")
+	assert.Equal(t, []string{"保持简洁"}, prompt.Directives)
+	assert.Equal(t, 50, prompt.MaxLength)
+
+	err = PublishResult(registry, "extract", "1.0.0", entry)
+	assert.ErrorIs(t, err, llm.ErrTemplateVersionExists)
+	assert.Error(t, PublishResult(registry, "extract", "1.0.1", OptimizationEntry{}))
+}
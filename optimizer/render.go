@@ -0,0 +1,96 @@
+// Package optimizer provides prompt optimization capabilities for Language Learning Models.
+package optimizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// renderAssessment renders an assessment for embedding into the improvement
+// prompt, listing only the components that are set.
+func renderAssessment(a PromptAssessment) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Overall score: %s/20 (grade %s)\n", formatScore(a.OverallScore), a.OverallGrade)
+	fmt.Fprintf(&builder, "Efficiency: %s/20\n", formatScore(a.EfficiencyScore))
+	fmt.Fprintf(&builder, "Alignment with goal: %s/20\n", formatScore(a.AlignmentWithGoal))
+
+	if len(a.Metrics) > 0 {
+		builder.WriteString("Metrics:\n")
+		for _, m := range a.Metrics {
+			fmt.Fprintf(&builder, "- %s: %s/20%s\n", m.Name, formatScore(m.Value), clause(m.Reasoning))
+		}
+	}
+	if len(a.Strengths) > 0 {
+		builder.WriteString("Strengths:\n")
+		for _, s := range a.Strengths {
+			fmt.Fprintf(&builder, "- %s%s\n", s.Point, example(s.Example))
+		}
+	}
+	if len(a.Weaknesses) > 0 {
+		builder.WriteString("Weaknesses:\n")
+		for _, w := range a.Weaknesses {
+			fmt.Fprintf(&builder, "- %s%s\n", w.Point, example(w.Example))
+		}
+	}
+	if len(a.Suggestions) > 0 {
+		builder.WriteString("Suggestions:\n")
+		for _, s := range a.Suggestions {
+			fmt.Fprintf(&builder, "- %s (expected impact %s/20)%s\n", s.Description, formatScore(s.ExpectedImpact), clause(s.Reasoning))
+		}
+	}
+	return strings.TrimSuffix(builder.String(), "\n")
+}
+
+// renderHistory renders optimization entries for embedding into assessment
+// and improvement prompts. Each entry shows the prompt and a summary of its
+// assessment; examples and reasoning are left out to save tokens.
+func renderHistory(entries []OptimizationEntry) string {
+	if len(entries) == 0 {
+		return "(none)"
+	}
+	var blocks []string
+	for i, entry := range entries {
+		var builder strings.Builder
+		fmt.Fprintf(&builder, "### Attempt %d\n", i+1)
+		builder.WriteString(entry.Prompt.RenderForLLM())
+		fmt.Fprintf(&builder, "\n\nResult: %s/20 (grade %s)", formatScore(entry.Assessment.OverallScore), entry.Assessment.OverallGrade)
+		for _, w := range entry.Assessment.Weaknesses {
+			fmt.Fprintf(&builder, "\n- Weakness: %s", w.Point)
+		}
+		blocks = append(blocks, builder.String())
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// renderMetrics renders custom metrics for embedding into the assessment prompt.
+func renderMetrics(metrics []Metric) string {
+	if len(metrics) == 0 {
+		return "(none)"
+	}
+	var lines []string
+	for _, m := range metrics {
+		lines = append(lines, "- "+m.Name+clause(m.Description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatScore formats a 0-20 score without trailing zeros.
+func formatScore(score float64) string {
+	return fmt.Sprintf("%g", score)
+}
+
+// clause formats optional explanatory text as a " - text" suffix.
+func clause(text string) string {
+	if text == "" {
+		return ""
+	}
+	return " - " + text
+}
+
+// example formats an optional example as a quoted suffix.
+func example(text string) string {
+	if text == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (e.g. %q)", text)
+}
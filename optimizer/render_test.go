@@ -0,0 +1,69 @@
+package optimizer
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/llm"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+// assertGolden compares got with testdata/name, rewriting the file when the
+// tests run with -update.
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), got)
+}
+
+var renderAssessmentFixture = PromptAssessment{
+	Metrics: []Metric{
+		{Name: "清晰度", Value: 15, Reasoning: "任务描述明确"},
+		{Name: "具体性", Value: 12.5},
+	},
+	Strengths:         []Strength{{Point: "目标明确", Example: "总结这篇文章"}},
+	Weaknesses:        []Weakness{{Point: "缺少输出格式"}},
+	Suggestions:       []Suggestion{{Description: "指定摘要长度", ExpectedImpact: 16, Reasoning: "减少冗长输出"}},
+	OverallScore:      14,
+	OverallGrade:      "B",
+	EfficiencyScore:   16,
+	AlignmentWithGoal: 13,
+}
+
+func TestRenderAssessment(t *testing.T) {
+	assertGolden(t, "assessment.golden", renderAssessment(renderAssessmentFixture))
+}
+
+func TestRenderHistory(t *testing.T) {
+	history := []OptimizationEntry{
+		{Prompt: llm.NewPrompt("总结这篇文章"), Assessment: renderAssessmentFixture},
+		{
+			Prompt: llm.NewPrompt("用三句话总结这篇文章", llm.WithDirectives("保留关键数据")),
+			Assessment: PromptAssessment{
+				OverallScore: 17.5,
+				OverallGrade: "A-",
+			},
+		},
+	}
+	got := renderHistory(history)
+	assert.NotContains(t, got, "0x", "history must not leak pointer addresses")
+	assertGolden(t, "history.golden", got)
+
+	assert.Equal(t, "(none)", renderHistory(nil))
+}
+
+func TestRenderMetrics(t *testing.T) {
+	assert.Equal(t, "(none)", renderMetrics(nil))
+	assert.Equal(t, "- 简洁性 - 避免冗余表述\n- 准确性",
+		renderMetrics([]Metric{{Name: "简洁性", Description: "避免冗余表述"}, {Name: "准确性"}}))
+}
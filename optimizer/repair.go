@@ -0,0 +1,57 @@
+// Package optimizer provides prompt optimization capabilities for Language Learning Models.
+package optimizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yockii/gollm_cn/llm"
+)
+
+// generateJSON generates a response to prompt and passes it to parse. While
+// parse fails, it sends the response back to the LLM asking for valid JSON
+// matching structure, up to the optimizer's maximum retries and waiting its
+// retry delay between attempts (see WithMaxRetries and WithRetryDelay), so a
+// malformed response does not abort the optimization.
+//
+// Interventions refused in strict mode are returned without repair: asking
+// the model again would hide the misbehavior strict mode exists to surface.
+//
+// Parameters:
+//   - prompt: The prompt to generate a response to
+//   - structure: Description of the expected JSON, quoted in repair requests
+//   - opts: Generation options for every call
+//   - parse: Decodes a response, returning an error if it is unusable
+//
+// Returns:
+//   - The error of the last attempt if every attempt failed
+func (po *PromptOptimizer) generateJSON(ctx context.Context, prompt *llm.Prompt, structure string, opts []llm.GenerateOption, parse func(response string) error) error {
+	response, err := po.llm.Generate(ctx, prompt, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to generate response: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := parse(response)
+		var interventionErr *llm.InterventionError
+		if err == nil || errors.As(err, &interventionErr) || attempt > po.maxRetries {
+			return err
+		}
+
+		po.debugManager.LogResponse(fmt.Sprintf("Malformed JSON response, repair attempt %d/%d: %v", attempt, po.maxRetries, err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(po.retryDelay):
+		}
+
+		repairPrompt := llm.NewPrompt(fmt.Sprintf(
+			"以下回复无法解析（%v）：\n\n%s\n\n请仅返回符合以下结构的有效 JSON，不要使用 Markdown 格式、代码块或任何其他文字：\n%s",
+			err, response, structure))
+		if response, err = po.llm.Generate(ctx, repairPrompt, opts...); err != nil {
+			return fmt.Errorf("failed to repair response: %w", err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package optimizer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// sequenceLLM answers Generate calls with its responses in order and records
+// the prompts.
+type sequenceLLM struct {
+	fencedLLM
+	responses []string
+	prompts   []*llm.Prompt
+}
+
+func (s *sequenceLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	response := s.responses[0]
+	s.responses = s.responses[1:]
+	return response, nil
+}
+
+func TestAssessPrompt_RepairsMalformedJSON(t *testing.T) {
+	debugManager := utils.NewDebugManager(utils.NewLogger(utils.LogLevelOff), utils.DebugOptions{})
+	l := &sequenceLLM{responses: []string{
+		`{"metrics": [{"name": "清晰度", "value": 15}], "overallScore": fifteen}`,
+		`{"overallScore": 15}`,
+		fencedAssessment,
+	}}
+	po := NewPromptOptimizer(l, debugManager, llm.NewPrompt("总结文本"), "总结", WithRetryDelay(0))
+
+	entry, err := po.assessPrompt(context.Background(), po.initialPrompt)
+	require.NoError(t, err)
+	assert.Equal(t, "A-", entry.Assessment.OverallGrade)
+
+	require.Len(t, l.prompts, 3)
+	assert.Contains(t, l.prompts[1].Input, "overallScore\": fifteen}")
+	assert.Contains(t, l.prompts[1].Input, `"alignmentWithGoal": number`)
+	assert.Contains(t, l.prompts[2].Input, "invalid assessment structure", "validation errors are repaired too")
+}
+
+func TestGenerateImprovedPrompt_RepairRetriesExhausted(t *testing.T) {
+	debugManager := utils.NewDebugManager(utils.NewLogger(utils.LogLevelOff), utils.DebugOptions{})
+	l := &sequenceLLM{responses: []string{"抱歉", "还是不行", "依然不行"}}
+	po := NewPromptOptimizer(l, debugManager, llm.NewPrompt("总结文本"), "总结", WithMaxRetries(2), WithRetryDelay(0))
+
+	_, err := po.generateImprovedPrompt(context.Background(), OptimizationEntry{Prompt: po.initialPrompt})
+	assert.ErrorContains(t, err, "failed to parse improved prompts")
+	assert.Len(t, l.prompts, 3, "one generation and two repairs")
+}
+
+func TestGenerateJSON_StrictModeNotRepaired(t *testing.T) {
+	debugManager := utils.NewDebugManager(utils.NewLogger(utils.LogLevelOff), utils.DebugOptions{})
+	l := &sequenceLLM{responses: []string{fencedAssessment}}
+	po := NewPromptOptimizer(l, debugManager, llm.NewPrompt("总结文本"), "总结", WithRetryDelay(0))
+
+	_, err := po.assessPrompt(llm.WithStrictMode(context.Background()), po.initialPrompt)
+	var interventionErr *llm.InterventionError
+	assert.True(t, errors.As(err, &interventionErr))
+	assert.Len(t, l.prompts, 1)
+}
@@ -92,6 +92,15 @@ type OptimizationEntry struct {
 
 	// Assessment contains the comprehensive evaluation of the prompt
 	Assessment PromptAssessment
+
+	// RunID identifies the OptimizePrompt run that produced the entry
+	RunID string
+
+	// JudgeModel is the model that assessed the prompt, when the LLM reports it
+	JudgeModel string
+
+	// AssessedAt records when the assessment was made
+	AssessedAt time.Time
 }
 
 // OptimizerOption is a function type for configuring the PromptOptimizer.
@@ -147,4 +156,16 @@ type PromptOptimizer struct {
 
 	// iterations counts the optimization steps performed
 	iterations int
+
+	// assessmentOptions override generation settings for assessment calls
+	assessmentOptions []llm.GenerateOption
+
+	// runID identifies the current OptimizePrompt run
+	runID string
+
+	// dataset holds the labelled examples each prompt is tested against
+	dataset []DatasetEntry
+
+	// scorer rates responses to the dataset against the expected outputs
+	scorer Scorer
 }
@@ -2,9 +2,12 @@
 package optimizer
 
 import (
+	"fmt"
 	"strconv"
-	"strings"
 	"time"
+
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/utils"
 )
 
 // DefaultRetryDelay is the standard duration to wait between retry attempts.
@@ -13,37 +16,25 @@ const (
 	DefaultRetryDelay = time.Second * 2
 )
 
-// cleanJSONResponse processes raw LLM responses to extract valid JSON content.
-// It handles common response formats including markdown code blocks and
-// extraneous text surrounding the JSON object.
-//
-// The function:
-// 1. Removes markdown code block delimiters
-// 2. Trims whitespace
-// 3. Extracts JSON object if embedded in other text
+// cleanJSONResponse extracts the JSON content from a raw LLM response with
+// utils.RepairJSON, which strips markdown code fences and surrounding text and
+// fixes common syntax mistakes. Whether a repaired response may be used is
+// decided by policy; in strict mode only responses that already are JSON are
+// accepted.
 //
 // Parameters:
+//   - policy: Fallback policy of the call that produced the response
 //   - response: Raw response string from the LLM
 //
 // Returns:
 //   - Clean JSON string ready for parsing
-func cleanJSONResponse(response string) string {
-	response = strings.TrimPrefix(response, "```json")
-	response = strings.TrimSuffix(response, "```")
-	response = strings.TrimSpace(response)
-
-	if strings.HasPrefix(response, "{") {
-		return response
-	}
-
-	start := strings.Index(response, "{")
-	end := strings.LastIndex(response, "}")
-
-	if start != -1 && end != -1 && end > start {
-		return response[start : end+1]
+//   - An error if no JSON can be recovered or the policy refuses the repair
+func cleanJSONResponse(policy *llm.FallbackPolicy, response string) (string, error) {
+	repaired, err := utils.RepairJSON(response)
+	if err != nil {
+		return "", err
 	}
-
-	return response
+	return policy.Repair(llm.InterventionJSONExtraction, response, repaired)
 }
 
 // WithCustomMetrics configures custom evaluation metrics for the optimizer.
@@ -135,6 +126,18 @@ func WithRetryDelay(delay time.Duration) OptimizerOption {
 	}
 }
 
+// WithAssessmentOptions sets per-call generation overrides used for the
+// optimizer's internal assessment calls, for example a cheaper model or a
+// lower token limit. Improvement calls keep the client's settings.
+//
+// Parameters:
+//   - opts: Generation options such as llm.WithModel or llm.WithMaxTokens
+func WithAssessmentOptions(opts ...llm.GenerateOption) OptimizerOption {
+	return func(po *PromptOptimizer) {
+		po.assessmentOptions = append(po.assessmentOptions, opts...)
+	}
+}
+
 // WithMemorySize sets the number of optimization entries to retain in history.
 // This affects the context available for subsequent optimization iterations.
 //
@@ -166,11 +169,12 @@ func WithMemorySize(size int) OptimizerOption {
 // Parameters:
 //   - grade: Letter grade or numeric score string
 //   - score: Numerical score (0-20 scale)
+//   - policy: Fallback policy; converting a numeric grade is refused in strict mode
 //
 // Returns:
 //   - Normalized letter grade
 //   - Error if grade format is invalid
-func normalizeGrade(grade string, score float64) (string, error) {
+func normalizeGrade(grade string, score float64, policy *llm.FallbackPolicy) (string, error) {
 	validGrades := map[string]bool{
 		"A+": true, "A": true, "A-": true,
 		"B+": true, "B": true, "B-": true,
@@ -187,6 +191,9 @@ func normalizeGrade(grade string, score float64) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if err := policy.Allow(llm.InterventionGradeNormalization, fmt.Sprintf("numeric grade %q converted to a letter grade", grade)); err != nil {
+		return "", err
+	}
 
 	switch {
 	case numericGrade >= 19:
@@ -0,0 +1,190 @@
+// Package persona defines a persona — a name, a role and expertise, tone
+// rules, a sign-off and forbidden phrases — that an LLM client applies to
+// every generation (see config.SetPersona), or a prompt to a single call (see
+// llm.WithPersona).
+package persona
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Persona describes how the LLM presents itself. Its instructions are added
+// to the system prompt of every call, and responses that use a forbidden
+// phrase are rewritten once (see config.SetPersona).
+type Persona struct {
+	// Name is the name the LLM introduces itself with.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Role is who the LLM acts as, e.g. "资深软件工程师".
+	Role string `json:"role,omitempty" yaml:"role,omitempty"`
+	// ExpertiseAreas lists the fields the LLM answers as an expert in.
+	ExpertiseAreas []string `json:"expertise_areas,omitempty" yaml:"expertise_areas,omitempty"`
+	// CommunicationStyle describes how the LLM explains things, e.g.
+	// "先给结论，再解释原因".
+	CommunicationStyle string `json:"communication_style,omitempty" yaml:"communication_style,omitempty"`
+	// Tone lists tone rules, e.g. "亲切" or "不使用感叹号".
+	Tone []string `json:"tone,omitempty" yaml:"tone,omitempty"`
+	// SignOff ends every free-text response. Structured output such as JSON
+	// is not signed.
+	SignOff string `json:"sign_off,omitempty" yaml:"sign_off,omitempty"`
+	// ForbiddenPhrases must not appear in responses. They are matched
+	// case-insensitively.
+	ForbiddenPhrases []string `json:"forbidden_phrases,omitempty" yaml:"forbidden_phrases,omitempty"`
+	// Variants adapt the persona to a language, keyed by language tag such
+	// as "en" or "zh-TW". See ForLanguage.
+	Variants map[string]Persona `json:"variants,omitempty" yaml:"variants,omitempty"`
+}
+
+// IsZero reports whether the persona sets nothing.
+func (p Persona) IsZero() bool {
+	return p.Name == "" && p.Role == "" && len(p.ExpertiseAreas) == 0 && p.CommunicationStyle == "" &&
+		len(p.Tone) == 0 && p.SignOff == "" && len(p.ForbiddenPhrases) == 0 && len(p.Variants) == 0
+}
+
+// ForLanguage returns the persona adapted to a language. The variant for the
+// language tag, or else for its primary subtag ("en" for "en-US"), replaces
+// the name, role, expertise areas, communication style, tone and sign-off it
+// sets; its forbidden phrases are added to the
+// base persona's. Tags are matched case-insensitively. The persona is
+// returned unchanged, without its variants, when no variant matches.
+func (p Persona) ForLanguage(lang string) Persona {
+	result := p
+	result.Variants = nil
+	variant, ok := p.variant(lang)
+	if !ok {
+		return result
+	}
+	if variant.Name != "" {
+		result.Name = variant.Name
+	}
+	if variant.Role != "" {
+		result.Role = variant.Role
+	}
+	if len(variant.ExpertiseAreas) > 0 {
+		result.ExpertiseAreas = variant.ExpertiseAreas
+	}
+	if variant.CommunicationStyle != "" {
+		result.CommunicationStyle = variant.CommunicationStyle
+	}
+	if len(variant.Tone) > 0 {
+		result.Tone = variant.Tone
+	}
+	if variant.SignOff != "" {
+		result.SignOff = variant.SignOff
+	}
+	result.ForbiddenPhrases = append(append([]string(nil), p.ForbiddenPhrases...), variant.ForbiddenPhrases...)
+	return result
+}
+
+// variant finds the variant for a language tag.
+func (p Persona) variant(lang string) (Persona, bool) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		return Persona{}, false
+	}
+	primary, _, _ := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-")
+	for _, candidate := range []string{lang, primary} {
+		for tag, variant := range p.Variants {
+			if strings.ToLower(tag) == candidate {
+				return variant, true
+			}
+		}
+	}
+	return Persona{}, false
+}
+
+// SystemPrompt renders the persona's instructions for the system prompt. It
+// returns "" for a persona that sets nothing but variants.
+func (p Persona) SystemPrompt() string {
+	var lines []string
+	if p.Name != "" {
+		lines = append(lines, "你是"+p.Name+"。")
+	}
+	if p.Role != "" {
+		lines = append(lines, "你的角色："+p.Role+"。")
+	}
+	if len(p.ExpertiseAreas) > 0 {
+		lines = append(lines, "你的专业领域："+strings.Join(p.ExpertiseAreas, "、")+"。")
+	}
+	if p.CommunicationStyle != "" {
+		lines = append(lines, "沟通风格："+p.CommunicationStyle)
+	}
+	if len(p.Tone) > 0 {
+		lines = append(lines, "语气要求：")
+		for _, tone := range p.Tone {
+			lines = append(lines, "- "+tone)
+		}
+	}
+	if p.SignOff != "" {
+		lines = append(lines, "以自由文本回复时，在结尾使用以下署名："+p.SignOff+"（JSON 等结构化输出不需要署名）")
+	}
+	if len(p.ForbiddenPhrases) > 0 {
+		lines = append(lines, "绝不使用以下措辞：")
+		for _, phrase := range p.ForbiddenPhrases {
+			lines = append(lines, "- "+phrase)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Violations returns the forbidden phrases that appear in text, in the order
+// they are listed.
+func (p Persona) Violations(text string) []string {
+	text = strings.ToLower(text)
+	var found []string
+	for _, phrase := range p.ForbiddenPhrases {
+		if phrase != "" && strings.Contains(text, strings.ToLower(phrase)) {
+			found = append(found, phrase)
+		}
+	}
+	return found
+}
+
+// Fingerprint returns a stable hash of the persona, including its variants,
+// or "" for a persona that sets nothing. Include it in the key of any cache
+// of responses generated with the persona.
+func (p Persona) Fingerprint() string {
+	if p.IsZero() {
+		return ""
+	}
+	// Map keys are marshaled in sorted order, so equal personas encode equally.
+	encoded, _ := json.Marshal(p)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadFromFile reads a persona from a YAML or JSON file, whose keys are the
+// json tags of Persona. Unknown keys are an error, so a misspelled key does
+// not silently drop an instruction.
+//
+// Example file:
+//
+//	name: 小安
+//	role: 网络安全顾问
+//	expertise_areas: [渗透测试, 安全合规]
+//	communication_style: 先说明风险等级，再给出修复建议
+//	tone: [严谨]
+func LoadFromFile(path string) (Persona, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Persona{}, fmt.Errorf("failed to read persona file: %w", err)
+	}
+	// YAML is a superset of JSON, so a single decoder handles both formats.
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var p Persona
+	if err := decoder.Decode(&p); err != nil {
+		return Persona{}, fmt.Errorf("failed to parse persona file %s: %w", path, err)
+	}
+	if p.IsZero() {
+		return Persona{}, fmt.Errorf("persona file %s sets nothing", path)
+	}
+	return p, nil
+}
@@ -0,0 +1,99 @@
+package persona
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func brandPersona() Persona {
+	return Persona{
+		Name:             "小橙",
+		Tone:             []string{"亲切", "简洁"},
+		SignOff:          "—— 小橙",
+		ForbiddenPhrases: []string{"亲", "绝对保证"},
+		Variants: map[string]Persona{
+			"en": {
+				Name:             "Orange",
+				SignOff:          "— Orange",
+				ForbiddenPhrases: []string{"guarantee"},
+			},
+		},
+	}
+}
+
+func TestForLanguage(t *testing.T) {
+	p := brandPersona()
+
+	en := p.ForLanguage("en-US")
+	assert.Equal(t, "Orange", en.Name)
+	assert.Equal(t, []string{"亲切", "简洁"}, en.Tone, "unset variant fields keep the base values")
+	assert.Equal(t, "— Orange", en.SignOff)
+	assert.Equal(t, []string{"亲", "绝对保证", "guarantee"}, en.ForbiddenPhrases)
+	assert.Nil(t, en.Variants)
+
+	zh := p.ForLanguage("zh")
+	assert.Equal(t, "小橙", zh.Name)
+	assert.Nil(t, zh.Variants)
+	assert.Equal(t, []string{"亲", "绝对保证"}, p.ForbiddenPhrases, "the base persona is not modified")
+}
+
+func TestSystemPrompt(t *testing.T) {
+	assert.Equal(t, "你是小橙。\n语气要求：\n- 亲切\n- 简洁\n以自由文本回复时，在结尾使用以下署名：—— 小橙（JSON 等结构化输出不需要署名）\n绝不使用以下措辞：\n- 亲\n- 绝对保证",
+		brandPersona().SystemPrompt())
+	assert.Equal(t, "", Persona{}.SystemPrompt())
+}
+
+func TestSystemPrompt_Expert(t *testing.T) {
+	p := Persona{
+		Role:               "资深软件工程师",
+		ExpertiseAreas:     []string{"并发", "性能优化"},
+		CommunicationStyle: "先给结论，再解释原因",
+		Variants:           map[string]Persona{"en": {Role: "senior software engineer"}},
+	}
+	assert.Equal(t, "你的角色：资深软件工程师。\n你的专业领域：并发、性能优化。\n沟通风格：先给结论，再解释原因", p.SystemPrompt())
+	assert.Equal(t, "senior software engineer", p.ForLanguage("en").Role)
+	assert.Equal(t, []string{"并发", "性能优化"}, p.ForLanguage("en").ExpertiseAreas)
+	assert.False(t, Persona{CommunicationStyle: "简洁"}.IsZero())
+}
+
+func TestLoadFromFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "security.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("name: 小安\nrole: 网络安全顾问\nexpertise_areas: [渗透测试, 安全合规]\ntone: [严谨]\n"), 0o600))
+	p, err := LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, Persona{Name: "小安", Role: "网络安全顾问", ExpertiseAreas: []string{"渗透测试", "安全合规"}, Tone: []string{"严谨"}}, p)
+
+	path = filepath.Join(dir, "writer.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"role": "技术文档工程师", "communication_style": "简洁"}`), 0o600))
+	p, err = LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "技术文档工程师", p.Role)
+
+	path = filepath.Join(dir, "typo.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("role: 顾问\nexpertise: [合规]\n"), 0o600))
+	_, err = LoadFromFile(path)
+	assert.ErrorContains(t, err, "expertise")
+
+	_, err = LoadFromFile(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestViolations(t *testing.T) {
+	p := brandPersona().ForLanguage("en")
+	assert.Equal(t, []string{"绝对保证", "guarantee"}, p.Violations("We GUARANTEE it, 绝对保证!"))
+	assert.Empty(t, p.Violations("您好，欢迎光临"))
+}
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t, "", Persona{}.Fingerprint())
+	assert.Equal(t, brandPersona().Fingerprint(), brandPersona().Fingerprint())
+
+	changed := brandPersona()
+	changed.Variants["en"] = Persona{Name: "Tangerine"}
+	assert.NotEqual(t, brandPersona().Fingerprint(), changed.Fingerprint(), "variants are part of the fingerprint")
+}
@@ -0,0 +1,293 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// Defaults of AnswerWithContext.
+const (
+	DefaultNoAnswer       = "根据提供的文档无法回答该问题。"
+	defaultDocumentBudget = 3000
+)
+
+// citationPattern matches a citation marker such as "[doc-3]" or
+// "[doc-1, doc-4]", or any other bracketed text; parseCitations tells them
+// apart.
+var citationPattern = regexp.MustCompile(`\[([^\[\]\n]+)\]`)
+
+// documentIDPattern matches text shaped like a document ID, such as "doc-3",
+// that is not one of the documents sent: a letter, then letters, digits and
+// punctuation including a digit.
+var documentIDPattern = regexp.MustCompile(`^[A-Za-z][\w.:/-]*\d[\w.:/-]*$`)
+
+// Document is a document AnswerWithContext can answer from, such as a search
+// result.
+type Document struct {
+	ID      string // Identifier cited in the answer, e.g. "doc-3"
+	Title   string // Optional title shown to the LLM
+	Content string // Text of the document
+}
+
+// DocumentsFromTexts returns documents with the given contents and the IDs
+// "doc-1", "doc-2" and so on, for AnswerWithContext.
+func DocumentsFromTexts(texts []string) []Document {
+	documents := make([]Document, len(texts))
+	for i, text := range texts {
+		documents[i] = Document{ID: fmt.Sprintf("doc-%d", i+1), Content: text}
+	}
+	return documents
+}
+
+// Answer is the result of AnswerWithContext.
+type Answer struct {
+	Text      string     // The answer, with inline citations such as "[doc-3]"
+	Citations []Citation // The documents cited, in order of first citation
+	NoAnswer  bool       // Whether the documents do not answer the question; Text is then the no-answer response
+	Dropped   []string   // IDs of the documents left out because they did not fit in the token budget
+}
+
+// Citation is a document cited by an Answer.
+type Citation struct {
+	DocumentID string
+	Title      string
+}
+
+// AnswerOption configures AnswerWithContext.
+type AnswerOption func(*answerConfig)
+
+type answerConfig struct {
+	budget           int
+	requireCitations bool
+	noAnswer         string
+}
+
+// WithDocumentBudget sets the token budget of the documents in the prompt.
+// The default is 3000.
+func WithDocumentBudget(tokens int) AnswerOption {
+	return func(c *answerConfig) {
+		c.budget = tokens
+	}
+}
+
+// WithNoAnswerResponse sets the response the LLM gives when the documents do
+// not answer the question, returned as the Text of an Answer with NoAnswer
+// set. The default is DefaultNoAnswer.
+func WithNoAnswerResponse(response string) AnswerOption {
+	return func(c *answerConfig) {
+		c.noAnswer = response
+	}
+}
+
+// WithRequireCitations makes AnswerWithContext ask once more if the answer
+// cites no document, and fail if the second answer cites none either. The
+// no-answer response needs no citation.
+func WithRequireCitations() AnswerOption {
+	return func(c *answerConfig) {
+		c.requireCitations = true
+	}
+}
+
+// AnswerWithContext answers question from documents only, citing the
+// documents it uses inline by ID, like "[doc-3]". Documents are given in
+// order of priority, usually the order of retrieval; those that do not fit in
+// the token budget are dropped from the end, with a warning, and listed in
+// Dropped. Citations of IDs that are not among the documents sent are
+// stripped from the answer and logged, so every citation in the result refers
+// to a real document. When the documents do not contain the answer, the LLM
+// is asked to give the no-answer response (see WithNoAnswerResponse) instead
+// of guessing, and the Answer has NoAnswer set.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for answering
+//   - question: The question to answer
+//   - documents: The documents to answer from, most important first
+//   - opts: Optional options such as WithRequireCitations and
+//     WithNoAnswerResponse
+//
+// Returns:
+//   - *Answer: The answer text and the documents it cites
+//   - error: Any error encountered, including an uncited answer when
+//     citations are required
+//
+// Example:
+//
+//	answer, err := AnswerWithContext(ctx, llm, "生鲜商品可以退货吗？", []Document{
+//	    {ID: "doc-1", Title: "售后政策", Content: "退货需在收货后 7 天内申请。"},
+//	    {ID: "doc-2", Title: "FAQ", Content: "生鲜商品不支持无理由退货。"},
+//	}, WithRequireCitations())
+//	fmt.Println(answer.Text) // 生鲜商品不支持无理由退货 [doc-2]。
+//
+// Documents without IDs or titles, such as the texts of search results, can
+// be made with DocumentsFromTexts.
+func AnswerWithContext(ctx context.Context, l gollm.LLM, question string, documents []Document, opts ...AnswerOption) (*Answer, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(question) == "" {
+		return nil, fmt.Errorf("question cannot be empty")
+	}
+	cfg := &answerConfig{budget: defaultDocumentBudget, noAnswer: DefaultNoAnswer}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	chunks, sent, dropped := packDocuments(l, documents, cfg.budget)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no documents fit in the budget of %d tokens", cfg.budget)
+	}
+	prompt := gollm.NewPrompt(question,
+		gollm.WithContextInjection(chunks, 0),
+		gollm.WithDirectives(
+			"仅依据检索到的文档回答问题，不要使用文档以外的知识",
+			"文档中没有答案时不要猜测，只回复："+cfg.noAnswer,
+			"在使用了文档内容的句子后用方括号标注文档 ID，例如 [doc-3]；同时引用多篇文档时写作 [doc-1, doc-4]",
+			"只引用上面列出的文档 ID",
+		),
+	)
+
+	answer, err := generateAnswer(ctx, l, prompt, sent, cfg.noAnswer)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.requireCitations && !answer.NoAnswer && len(answer.Citations) == 0 {
+		l.GetLogger().Warn("Answer cites no documents, retrying")
+		retry := *prompt
+		retry.Directives = append(append([]string(nil), prompt.Directives...),
+			"上一次的回答没有引用任何文档。请重新回答，并为每个依据文档的句子标注文档 ID：\n"+answer.Text)
+		answer, err = generateAnswer(ctx, l, &retry, sent, cfg.noAnswer)
+		if err != nil {
+			return nil, err
+		}
+		if !answer.NoAnswer && len(answer.Citations) == 0 {
+			return nil, fmt.Errorf("answer cites no documents after retry")
+		}
+	}
+	answer.Dropped = dropped
+	return answer, nil
+}
+
+// packDocuments returns the context chunks of the documents that fit in
+// budget tokens, in order, the documents sent by ID and the IDs of the
+// documents dropped.
+func packDocuments(l gollm.LLM, documents []Document, budget int) ([]gollm.ContextChunk, map[string]Document, []string) {
+	var chunks []gollm.ContextChunk
+	var dropped []string
+	sent := make(map[string]Document)
+	used := 0
+	for i, doc := range documents {
+		content := strings.TrimSpace(doc.Content)
+		if doc.ID == "" || content == "" {
+			continue
+		}
+		if doc.Title != "" {
+			content = "标题：" + doc.Title + "\n" + content
+		}
+		tokens := estimateTokens(content + doc.ID)
+		if used+tokens > budget {
+			for _, rest := range documents[i:] {
+				if rest.ID != "" && strings.TrimSpace(rest.Content) != "" {
+					dropped = append(dropped, rest.ID)
+				}
+			}
+			l.GetLogger().Warn("Dropped documents exceeding the token budget",
+				"budget", budget, "dropped", len(dropped))
+			break
+		}
+		used += tokens
+		chunks = append(chunks, gollm.ContextChunk{Content: content, Source: doc.ID})
+		sent[doc.ID] = doc
+	}
+	return chunks, sent, dropped
+}
+
+// generateAnswer generates the answer to prompt and parses its citations of
+// the documents sent. A response that, without its citations, is noAnswer is
+// returned as exactly noAnswer, with NoAnswer set.
+func generateAnswer(ctx context.Context, l gollm.LLM, prompt *gollm.Prompt, sent map[string]Document, noAnswer string) (*Answer, error) {
+	response, err := l.Generate(ctx, prompt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+	answer := parseCitations(l, strings.TrimSpace(response), sent)
+	if normalizeAnswer(citationPattern.ReplaceAllString(answer.Text, "")) == normalizeAnswer(noAnswer) {
+		return &Answer{Text: noAnswer, NoAnswer: true}, nil
+	}
+	return answer, nil
+}
+
+// parseCitations collects the citations of text, dropping the IDs that are
+// not among the documents sent, and markers left with none. Markers copying
+// the "[Source: doc-3]" lines of the prompt are accepted. Brackets are
+// citations only if they cite a document sent or list nothing but text shaped
+// like document IDs, so notes such as "[注]" or "[1, 2, 3]" and Markdown links,
+// whose brackets are followed by "(", are left as they are.
+func parseCitations(l gollm.LLM, text string, sent map[string]Document) *Answer {
+	answer := &Answer{}
+	cited := make(map[string]bool)
+	var out strings.Builder
+	last := 0
+	for _, m := range citationPattern.FindAllStringSubmatchIndex(text, -1) {
+		if strings.HasPrefix(text[m[1]:], "(") {
+			continue
+		}
+		ids := citedIDs(text[m[2]:m[3]], sent)
+		if ids == nil {
+			continue
+		}
+		var known []string
+		for _, id := range ids {
+			doc, ok := sent[id]
+			if !ok {
+				l.GetLogger().Warn("Stripped citation of unknown document", "id", id)
+				continue
+			}
+			known = append(known, id)
+			if !cited[id] {
+				cited[id] = true
+				answer.Citations = append(answer.Citations, Citation{DocumentID: id, Title: doc.Title})
+			}
+		}
+		if len(known) > 0 {
+			out.WriteString(text[last:m[0]])
+			out.WriteString("[" + strings.Join(known, ", ") + "]")
+		} else {
+			out.WriteString(strings.TrimRight(text[last:m[0]], " "))
+		}
+		last = m[1]
+	}
+	out.WriteString(text[last:])
+	answer.Text = out.String()
+	return answer
+}
+
+// citedIDs returns the IDs listed by the bracketed text marker, or nil if
+// marker is not a citation: if none of its IDs is among the documents sent
+// and some do not look like document IDs.
+func citedIDs(marker string, sent map[string]Document) []string {
+	marker = strings.TrimPrefix(strings.TrimSpace(marker), "Source:")
+	ids := strings.FieldsFunc(marker, func(r rune) bool {
+		return r == ',' || r == '，' || r == ';' || r == '；'
+	})
+	citation := len(ids) > 0
+	for i, id := range ids {
+		ids[i] = strings.TrimSpace(id)
+		if _, ok := sent[ids[i]]; ok {
+			return ids
+		}
+		if !documentIDPattern.MatchString(ids[i]) {
+			citation = false
+		}
+	}
+	if !citation {
+		return nil
+	}
+	return ids
+}
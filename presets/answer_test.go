@@ -0,0 +1,90 @@
+package presets
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var answerDocuments = []Document{
+	{ID: "doc-1", Title: "售后政策", Content: "退货需在收货后 7 天内申请。"},
+	{ID: "doc-2", Title: "FAQ", Content: "生鲜商品不支持无理由退货。"},
+}
+
+func TestAnswerWithContext(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"生鲜商品不支持无理由退货 [doc-2, doc-9]，其他商品需在 7 天内申请 [doc-1][Source: doc-2]。详见[政策](https://example.com) [doc-7]。",
+	}}
+
+	answer, err := AnswerWithContext(context.Background(), l, "生鲜商品可以退货吗？", answerDocuments)
+	require.NoError(t, err)
+	assert.Equal(t, "生鲜商品不支持无理由退货 [doc-2]，其他商品需在 7 天内申请 [doc-1][doc-2]。详见[政策](https://example.com)。", answer.Text)
+	assert.Equal(t, []Citation{{DocumentID: "doc-2", Title: "FAQ"}, {DocumentID: "doc-1", Title: "售后政策"}}, answer.Citations)
+	assert.Equal(t, "标题：售后政策\n退货需在收货后 7 天内申请。\n[Source: doc-1]\n\n标题：FAQ\n生鲜商品不支持无理由退货。\n[Source: doc-2]",
+		l.prompts[0].RetrievedContext)
+}
+
+func TestAnswerWithContext_NonCitationBrackets(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"生鲜商品不支持无理由退货 [doc-2]。[注] 见第 [1, 2, 3] 条 [v2] [doc-1, 注]",
+	}}
+
+	answer, err := AnswerWithContext(context.Background(), l, "生鲜商品可以退货吗？", answerDocuments)
+	require.NoError(t, err)
+	assert.Equal(t, "生鲜商品不支持无理由退货 [doc-2]。[注] 见第 [1, 2, 3] 条 [doc-1]", answer.Text,
+		"brackets that do not list document IDs are kept")
+	assert.Len(t, answer.Citations, 2)
+
+	documents := []Document{{ID: "1", Content: "退货需在收货后 7 天内申请。"}}
+	l = &scriptedLLM{responses: []string{"7 天内可以退货 [1, 2]。"}}
+	answer, err = AnswerWithContext(context.Background(), l, "多久内可以退货？", documents)
+	require.NoError(t, err)
+	assert.Equal(t, "7 天内可以退货 [1]。", answer.Text, "IDs of documents sent are citations whatever their shape")
+}
+
+func TestAnswerWithContext_Budget(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"7 天内可以退货 [doc-1]。"}}
+	documents := append(answerDocuments, Document{ID: "doc-3", Content: strings.Repeat("很长的文档", 100)})
+
+	answer, err := AnswerWithContext(context.Background(), l, "多久内可以退货？", documents, WithDocumentBudget(60))
+	require.NoError(t, err)
+	assert.Len(t, answer.Citations, 1)
+	assert.NotContains(t, l.prompts[0].RetrievedContext, "doc-3", "documents beyond the budget are dropped")
+	assert.Equal(t, []string{"doc-3"}, answer.Dropped)
+
+	_, err = AnswerWithContext(context.Background(), l, "问题", documents, WithDocumentBudget(5))
+	assert.ErrorContains(t, err, "no documents fit")
+}
+
+func TestAnswerWithContext_RequireCitations(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"生鲜商品不能退。", "生鲜商品不能退 [doc-2]。"}}
+
+	answer, err := AnswerWithContext(context.Background(), l, "生鲜商品可以退货吗？", answerDocuments, WithRequireCitations())
+	require.NoError(t, err)
+	assert.Equal(t, "生鲜商品不能退 [doc-2]。", answer.Text)
+	assert.Contains(t, l.prompts[1].Directives[len(l.prompts[1].Directives)-1], "生鲜商品不能退。")
+	assert.Len(t, l.prompts[0].Directives, 4, "the retry does not change the first prompt")
+
+	l = &scriptedLLM{responses: []string{"不能退。", "还是不能退。"}}
+	_, err = AnswerWithContext(context.Background(), l, "生鲜商品可以退货吗？", answerDocuments, WithRequireCitations())
+	assert.ErrorContains(t, err, "cites no documents after retry")
+}
+
+func TestAnswerWithContext_NoAnswer(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"根据提供的文档无法回答该问题 [doc-1]", "文档里没有提到。"}}
+
+	answer, err := AnswerWithContext(context.Background(), l, "运费谁出？", answerDocuments, WithRequireCitations())
+	require.NoError(t, err)
+	assert.Equal(t, &Answer{Text: DefaultNoAnswer, NoAnswer: true}, answer, "the no-answer response needs no citation")
+	assert.Contains(t, l.prompts[0].Directives, "文档中没有答案时不要猜测，只回复："+DefaultNoAnswer)
+
+	l = &scriptedLLM{responses: []string{"I don't know."}}
+	answer, err = AnswerWithContext(context.Background(), l, "Who pays for shipping?", DocumentsFromTexts([]string{"Returns are accepted within 7 days."}),
+		WithNoAnswerResponse("I don't know"))
+	require.NoError(t, err)
+	assert.Equal(t, &Answer{Text: "I don't know", NoAnswer: true}, answer)
+	assert.Contains(t, l.prompts[0].RetrievedContext, "[Source: doc-1]")
+}
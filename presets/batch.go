@@ -0,0 +1,38 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"sync"
+)
+
+// runBatch calls fn for items 0 to n-1, each in its own goroutine, at most
+// concurrency at a time; values below 1 are treated as 1. When ctx is
+// canceled, no new items are started. runBatch returns once the items
+// started are done, with their number: the items from started on were never
+// started.
+func runBatch(ctx context.Context, n, concurrency int, fn func(i int)) (started int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		started++
+		wg.Add(1)
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+	return started
+}
@@ -6,6 +6,7 @@ package presets
 import (
 	"context"
 	"fmt"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/yockii/gollm_cn"
@@ -143,3 +144,88 @@ func ChainOfThought(ctx context.Context, l gollm.LLM, question string, opts ...g
 	}
 	return response, nil
 }
+
+// CoTResult is the result of ChainOfThoughtStructured.
+type CoTResult struct {
+	Reasoning string `json:"reasoning" validate:"required"` // The numbered reasoning steps
+	Answer    string `json:"answer" validate:"required"`    // The final answer alone, without the reasoning
+}
+
+// ChainOfThoughtStructured reasons step by step about question like
+// ChainOfThought, but returns the reasoning and the final answer as separate
+// fields, so that the reasoning can be shown, hidden or logged apart from the
+// answer. The response is constrained to the schema of CoTResult, natively on
+// providers that support it, and a response with an empty reasoning or answer
+// is sent back to the LLM for correction once, as by ExtractStructuredData.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for generation
+//   - question: The question or problem to reason about
+//   - opts: Optional prompt configuration options, as for ChainOfThought
+//
+// Returns:
+//   - *CoTResult: The reasoning and the final answer, both non-empty
+//   - error: Any error encountered during generation or validation
+//
+// Example:
+//
+//	result, err := ChainOfThoughtStructured(ctx, llm, "(17 * 6) + (23 * 4) 等于多少？")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	log.Println("answer:", result.Answer)
+//	if showReasoning {
+//	    fmt.Println(result.Reasoning)
+//	}
+func ChainOfThoughtStructured(ctx context.Context, l gollm.LLM, question string, opts ...gollm.PromptOption) (*CoTResult, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(question) == "" {
+		return nil, fmt.Errorf("question cannot be empty")
+	}
+	if !utf8.ValidString(question) {
+		return nil, fmt.Errorf("question contains invalid UTF-8 characters")
+	}
+
+	schema, err := gollm.GenerateJSONSchema(CoTResult{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JSON schema: %w", err)
+	}
+	config := newExtractionConfig(nil)
+	prompt := gollm.NewPrompt("请针对以下问题进行思维链推理:\n\n" + question)
+	prompt.Apply(
+		gollm.WithDirectives(
+			"将问题分解为多个步骤，展示每个步骤的推理过程",
+			"reasoning 为完整的推理过程，对每个步骤进行编号 (1., 2., 等等)",
+			"answer 只写最终答案本身，不要重复推理过程",
+			"使用与此模式匹配的 JSON 对象进行响应："+string(schema),
+		),
+		gollm.WithOutput("与提供的模式匹配的 JSON 对象"),
+	)
+	prompt.Apply(opts...)
+	var generateOpts []gollm.GenerateOption
+	if l.SupportsJSONSchema() {
+		generateOpts = append(generateOpts, gollm.WithStructuredOutput(schema))
+	}
+
+	var result *CoTResult
+	err = generateCorrected(ctx, l, config, prompt, schema, "JSON 对象", generateOpts, func(response string) error {
+		parsed, err := parseExtraction[CoTResult](ctx, l, response)
+		if err != nil {
+			return err
+		}
+		parsed.Reasoning = strings.TrimSpace(parsed.Reasoning)
+		parsed.Answer = strings.TrimSpace(parsed.Answer)
+		if parsed.Reasoning == "" || parsed.Answer == "" {
+			return fmt.Errorf("reasoning and answer must both be non-empty")
+		}
+		result = parsed
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate chain of thought: %w", err)
+	}
+	return result, nil
+}
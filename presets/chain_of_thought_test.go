@@ -0,0 +1,27 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+)
+
+func TestChainOfThoughtStructured(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		`{"reasoning": "1. 17 * 6 = 102\n2. 23 * 4 = 92\n3. 102 + 92 = 194", "answer": "  "}`,
+		"```json\n{\"reasoning\": \"1. 17 * 6 = 102\\n2. 23 * 4 = 92\\n3. 102 + 92 = 194\", \"answer\": \" 194 \"}\n```",
+	}}
+
+	result, err := ChainOfThoughtStructured(context.Background(), l, "(17 * 6) + (23 * 4) 等于多少？", gollm.WithContext("只用整数运算"))
+	require.NoError(t, err)
+	assert.Equal(t, &CoTResult{Reasoning: "1. 17 * 6 = 102\n2. 23 * 4 = 92\n3. 102 + 92 = 194", Answer: "194"}, result)
+	require.Len(t, l.prompts, 2, "an empty answer is corrected")
+	assert.Equal(t, "只用整数运算", l.prompts[0].Context)
+	assert.Contains(t, l.prompts[1].Input, "reasoning and answer must both be non-empty")
+
+	_, err = ChainOfThoughtStructured(context.Background(), &scriptedLLM{}, " ")
+	assert.ErrorContains(t, err, "question cannot be empty")
+}
@@ -0,0 +1,282 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// LabelScore is a label assigned by ClassifyMultiLabel and its confidence.
+type LabelScore struct {
+	Label      string  `json:"label" validate:"required"`
+	Confidence float64 `json:"confidence" validate:"gte=0,lte=1"`
+}
+
+// labelScores is the structure the LLM is asked to fill in by
+// ClassifyMultiLabel.
+type labelScores struct {
+	Labels []LabelScore `json:"labels" validate:"dive"`
+}
+
+// Classification is the label assigned by ClassifyWithReasoning and the
+// rationale the LLM gave for it.
+type Classification struct {
+	Label     string `json:"label" validate:"required"`
+	Reasoning string `json:"reasoning" validate:"required"`
+}
+
+// ClassifyOption configures Classify, ClassifyMultiLabel and
+// ClassifyWithReasoning.
+type ClassifyOption func(*classifyConfig)
+
+type classifyConfig struct {
+	descriptions  map[string]string
+	minConfidence float64
+}
+
+// WithLabelDescriptions explains what some labels mean, for labels whose name
+// alone is ambiguous. Keys are labels, values their descriptions.
+func WithLabelDescriptions(descriptions map[string]string) ClassifyOption {
+	return func(c *classifyConfig) {
+		c.descriptions = descriptions
+	}
+}
+
+// WithMinConfidence makes ClassifyMultiLabel drop the labels whose confidence
+// is below min.
+func WithMinConfidence(min float64) ClassifyOption {
+	return func(c *classifyConfig) {
+		c.minConfidence = min
+	}
+}
+
+// Classify assigns text exactly one of labels. The response is matched against
+// the labels ignoring case, surrounding whitespace and quotes; if it matches
+// none, the LLM is asked once more before Classify fails.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for classification
+//   - text: The text to classify
+//   - labels: The allowed labels
+//   - opts: Optional classification options
+//
+// Returns:
+//   - string: The assigned label, spelled as in labels
+//   - error: Any error encountered, including a response outside labels
+//
+// Example:
+//
+//	label, err := Classify(ctx, llm, ticket, []string{"账单", "技术支持", "投诉"},
+//	    WithLabelDescriptions(map[string]string{"投诉": "对服务或员工的不满"}),
+//	)
+func Classify(ctx context.Context, l gollm.LLM, text string, labels []string, opts ...ClassifyOption) (string, error) {
+	cfg, err := newClassifyConfig(l, text, labels, opts)
+	if err != nil {
+		return "", err
+	}
+	prompt := classifyPrompt(text, labels, cfg,
+		"只能从允许的标签中选择一个最合适的标签",
+		"只输出标签本身，不要输出其他内容",
+	)
+
+	var label string
+	err = askClassification(ctx, l, prompt, nil, func(response string) error {
+		response = strings.Trim(strings.TrimSpace(response), "\"'`“”「」。.")
+		matched, ok := matchCategory(labels, response)
+		if !ok {
+			return fmt.Errorf("label %q is not one of the allowed labels", response)
+		}
+		label = matched
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return label, nil
+}
+
+// ClassifyMultiLabel assigns text every label of labels that applies, each
+// with a confidence between 0 and 1. If the response contains a label outside
+// labels, the LLM is asked once more before ClassifyMultiLabel fails.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for classification
+//   - text: The text to classify
+//   - labels: The allowed labels
+//   - opts: Optional classification options, such as WithMinConfidence
+//
+// Returns:
+//   - []LabelScore: The applicable labels, spelled as in labels and sorted by
+//     descending confidence; empty if none applies
+//   - error: Any error encountered, including a label outside labels
+//
+// Example:
+//
+//	scores, err := ClassifyMultiLabel(ctx, llm, article, []string{"科技", "金融", "体育"},
+//	    WithMinConfidence(0.5),
+//	)
+//	for _, s := range scores {
+//	    fmt.Printf("%s: %.2f\n", s.Label, s.Confidence)
+//	}
+func ClassifyMultiLabel(ctx context.Context, l gollm.LLM, text string, labels []string, opts ...ClassifyOption) ([]LabelScore, error) {
+	cfg, err := newClassifyConfig(l, text, labels, opts)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := gollm.GenerateJSONSchema(labelScores{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JSON schema: %w", err)
+	}
+	prompt := classifyPrompt(text, labels, cfg,
+		"列出所有适用于文本的标签，只能使用允许的标签，没有适用的标签时返回空列表",
+		"confidence 为 0 到 1 之间的置信度，表示该标签适用的把握",
+		"使用与此模式匹配的 JSON 对象进行响应："+string(schema),
+	)
+	var generateOpts []gollm.GenerateOption
+	if l.SupportsJSONSchema() {
+		generateOpts = append(generateOpts, gollm.WithStructuredOutput(schema))
+	}
+
+	var scores []LabelScore
+	err = askClassification(ctx, l, prompt, generateOpts, func(response string) error {
+		result, err := parseExtraction[labelScores](ctx, l, response)
+		if err != nil {
+			return err
+		}
+		seen := make(map[string]bool)
+		scores = scores[:0]
+		for _, s := range result.Labels {
+			label, ok := matchCategory(labels, s.Label)
+			if !ok {
+				return fmt.Errorf("label %q is not one of the allowed labels", s.Label)
+			}
+			if seen[label] || s.Confidence < cfg.minConfidence {
+				continue
+			}
+			seen[label] = true
+			scores = append(scores, LabelScore{Label: label, Confidence: s.Confidence})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		return scores[i].Confidence > scores[j].Confidence
+	})
+	return scores, nil
+}
+
+// ClassifyWithReasoning is like Classify, but also returns the LLM's short
+// rationale for the label, so that decisions can be audited.
+//
+// Example:
+//
+//	c, err := ClassifyWithReasoning(ctx, llm, review, []string{"正面", "负面", "中性"})
+//	log.Printf("label=%s reason=%s", c.Label, c.Reasoning)
+func ClassifyWithReasoning(ctx context.Context, l gollm.LLM, text string, labels []string, opts ...ClassifyOption) (*Classification, error) {
+	cfg, err := newClassifyConfig(l, text, labels, opts)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := gollm.GenerateJSONSchema(Classification{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JSON schema: %w", err)
+	}
+	prompt := classifyPrompt(text, labels, cfg,
+		"只能从允许的标签中选择一个最合适的标签",
+		"reasoning 用一两句话说明选择该标签的理由",
+		"使用与此模式匹配的 JSON 对象进行响应："+string(schema),
+	)
+	var generateOpts []gollm.GenerateOption
+	if l.SupportsJSONSchema() {
+		generateOpts = append(generateOpts, gollm.WithStructuredOutput(schema))
+	}
+
+	var classification *Classification
+	err = askClassification(ctx, l, prompt, generateOpts, func(response string) error {
+		result, err := parseExtraction[Classification](ctx, l, response)
+		if err != nil {
+			return err
+		}
+		label, ok := matchCategory(labels, result.Label)
+		if !ok {
+			return fmt.Errorf("label %q is not one of the allowed labels", result.Label)
+		}
+		result.Label = label
+		classification = result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return classification, nil
+}
+
+// newClassifyConfig validates the arguments common to the Classify functions
+// and applies opts.
+func newClassifyConfig(l gollm.LLM, text string, labels []string, opts []ClassifyOption) (*classifyConfig, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("at least one label must be provided")
+	}
+	cfg := &classifyConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg, nil
+}
+
+// classifyPrompt builds a prompt enumerating the allowed labels of a
+// classification of text.
+func classifyPrompt(text string, labels []string, cfg *classifyConfig, directives ...string) *gollm.Prompt {
+	var list strings.Builder
+	for _, label := range labels {
+		list.WriteString("- " + label)
+		if description := cfg.descriptions[label]; description != "" {
+			list.WriteString("：" + description)
+		}
+		list.WriteString("\n")
+	}
+	return gollm.NewPrompt(
+		fmt.Sprintf("对以下文本进行分类：\n\n%s\n\n允许的标签：\n%s", text, list.String()),
+		gollm.WithDirectives(directives...),
+	)
+}
+
+// askClassification generates a response to prompt and passes it to parse. If
+// parse fails, it asks once more, pointing out the invalid response.
+func askClassification(ctx context.Context, l gollm.LLM, prompt *gollm.Prompt, generateOpts []gollm.GenerateOption, parse func(response string) error) error {
+	response, err := l.Generate(ctx, prompt, generateOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to classify text: %w", err)
+	}
+	firstErr := parse(response)
+	if firstErr == nil {
+		return nil
+	}
+
+	retry := *prompt
+	retry.Directives = append(append([]string(nil), prompt.Directives...),
+		fmt.Sprintf("上一次的回答无效（%v），请严格按照要求重新回答：\n%s", firstErr, response))
+	response, err = l.Generate(ctx, &retry, generateOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to classify text: %w", err)
+	}
+	if err := parse(response); err != nil {
+		return fmt.Errorf("invalid classification after retry: %w", err)
+	}
+	return nil
+}
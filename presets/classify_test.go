@@ -0,0 +1,68 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	l := &scriptedLLM{responses: []string{" \"billing\"\n"}}
+
+	label, err := Classify(context.Background(), l, "我被重复扣费了", []string{"Billing", "技术支持"},
+		WithLabelDescriptions(map[string]string{"Billing": "付款、发票和退款"}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "Billing", label)
+	assert.Contains(t, l.prompts[0].Input, "- Billing：付款、发票和退款\n- 技术支持\n")
+}
+
+func TestClassify_ReasksOnceOnInvalidLabel(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"退款", "技术支持"}}
+
+	label, err := Classify(context.Background(), l, "应用打不开", []string{"账单", "技术支持"})
+	require.NoError(t, err)
+	assert.Equal(t, "技术支持", label)
+	require.Len(t, l.prompts, 2)
+	assert.Contains(t, l.prompts[1].Directives[len(l.prompts[1].Directives)-1], `label "退款" is not one of the allowed labels`)
+	assert.Len(t, l.prompts[0].Directives, 2, "the retry does not modify the original prompt")
+
+	l = &scriptedLLM{responses: []string{"退款", "售后"}}
+	_, err = Classify(context.Background(), l, "应用打不开", []string{"账单", "技术支持"})
+	assert.ErrorContains(t, err, `invalid classification after retry: label "售后"`)
+}
+
+func TestClassifyMultiLabel(t *testing.T) {
+	l := &scriptedLLM{responses: []string{`{"labels": [
+		{"label": "金融", "confidence": 0.6},
+		{"label": "科技", "confidence": 0.9},
+		{"label": "体育", "confidence": 0.1}
+	]}`}}
+
+	scores, err := ClassifyMultiLabel(context.Background(), l, "某科技公司股价大涨", []string{"科技", "金融", "体育"},
+		WithMinConfidence(0.5),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []LabelScore{{Label: "科技", Confidence: 0.9}, {Label: "金融", Confidence: 0.6}}, scores)
+}
+
+func TestClassifyWithReasoning(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		`{"label": "好评", "reasoning": "称赞了物流"}`,
+		`{"label": "正面", "reasoning": "称赞了物流速度"}`,
+	}}
+
+	c, err := ClassifyWithReasoning(context.Background(), l, "物流很快", []string{"正面", "负面"})
+	require.NoError(t, err)
+	assert.Equal(t, &Classification{Label: "正面", Reasoning: "称赞了物流速度"}, c)
+}
+
+func TestClassify_Errors(t *testing.T) {
+	_, err := Classify(context.Background(), &scriptedLLM{}, "文本", nil)
+	assert.ErrorContains(t, err, "at least one label")
+
+	_, err = Classify(context.Background(), &scriptedLLM{}, " ", []string{"a"})
+	assert.ErrorContains(t, err, "text cannot be empty")
+}
@@ -5,6 +5,7 @@ package presets
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -177,7 +178,8 @@ func CompareModels[T any](ctx context.Context, prompt string, validateFunc Valid
 			if err != nil {
 				debugLog(config, "Error generating response: %v", err)
 				// Immediately propagate API errors (like invalid keys)
-				if strings.Contains(err.Error(), "API error") {
+				var providerErr *llm.ProviderError
+				if errors.As(err, &providerErr) {
 					return nil, fmt.Errorf("API error for %s %s: %w", config.Provider, config.Model, err)
 				}
 				if attempt == 3 {
@@ -189,7 +191,10 @@ func CompareModels[T any](ctx context.Context, prompt string, validateFunc Valid
 
 			debugLog(config, "Raw response received: %s", response)
 
-			cleanedResponse := cleanResponse(response)
+			cleanedResponse, err := llmInstance.FallbackPolicy(ctx).Repair(llm.InterventionJSONExtraction, response, cleanResponse(response))
+			if err != nil {
+				return nil, fmt.Errorf("invalid response from %s %s: %w", config.Provider, config.Model, err)
+			}
 			debugLog(config, "Cleaned response: %s", cleanedResponse)
 
 			results[index].Response = cleanedResponse
@@ -0,0 +1,286 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/llm"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats ConvertFormat converts to. "yml" and "md" are accepted as aliases.
+const (
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown" // A Markdown table
+	FormatXML      = "xml"
+)
+
+// formatNames are the names of the formats in prompts.
+var formatNames = map[string]string{
+	FormatJSON:     "JSON",
+	FormatYAML:     "YAML",
+	FormatCSV:      "CSV",
+	FormatMarkdown: "Markdown 表格",
+	FormatXML:      "XML",
+}
+
+// ConversionError is returned by ConvertFormat when the LLM's output does not
+// parse in the target format or does not match the schema.
+type ConversionError struct {
+	Format string // The target format
+	Output string // The raw output of the LLM
+	Err    error  // The parse or schema error
+}
+
+// Error implements the error interface.
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("output is not valid %s: %v", e.Format, e.Err)
+}
+
+// Unwrap returns the parse or schema error.
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// ConvertOption configures ConvertFormat.
+type ConvertOption func(*convertConfig)
+
+type convertConfig struct {
+	schema           string
+	sampleRows       int
+	preserveComments bool
+}
+
+// WithSchema makes the output follow schema, given in any form the LLM
+// understands. A JSON Schema is also enforced when converting to JSON or
+// YAML: output that does not match it fails with a ConversionError.
+func WithSchema(schema string) ConvertOption {
+	return func(c *convertConfig) {
+		c.schema = schema
+	}
+}
+
+// WithSampleRows converts only the header and the first n rows of CSV input,
+// for instance to preview the conversion of a large file.
+func WithSampleRows(n int) ConvertOption {
+	return func(c *convertConfig) {
+		c.sampleRows = n
+	}
+}
+
+// WithPreserveComments asks the LLM to keep the comments of the input as YAML
+// comments. It only has an effect when converting to YAML.
+func WithPreserveComments() ConvertOption {
+	return func(c *convertConfig) {
+		c.preserveComments = true
+	}
+}
+
+// ConvertFormat converts structured data between formats, such as JSON to
+// YAML, CSV to JSON or a Markdown table to JSON. The output is parsed in the
+// target format before it is returned, so it is always valid; output that is
+// not fails with a *ConversionError holding the raw output. Markdown code
+// fences around the output are removed.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for conversion
+//   - input: The data to convert
+//   - fromFormat: The format of input; any name the LLM understands
+//   - toFormat: The target format: "json", "yaml", "csv", "markdown" or "xml"
+//   - opts: Optional options such as WithSchema
+//
+// Returns:
+//   - string: The converted data
+//   - error: Any error encountered, including a *ConversionError
+//
+// Example:
+//
+//	yamlText, err := ConvertFormat(ctx, llm, `{"name": "gollm", "tags": ["go", "llm"]}`, "json", "yaml")
+//	var convErr *ConversionError
+//	if errors.As(err, &convErr) {
+//	    log.Printf("invalid output: %s", convErr.Output)
+//	}
+func ConvertFormat(ctx context.Context, l gollm.LLM, input, fromFormat, toFormat string, opts ...ConvertOption) (string, error) {
+	if l == nil {
+		return "", fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(input) == "" {
+		return "", fmt.Errorf("input cannot be empty")
+	}
+	from, to := normalizeFormat(fromFormat), normalizeFormat(toFormat)
+	if from == "" {
+		return "", fmt.Errorf("source format cannot be empty")
+	}
+	if _, ok := formatNames[to]; !ok {
+		return "", fmt.Errorf("unsupported target format %q; use json, yaml, csv, markdown or xml", toFormat)
+	}
+	cfg := &convertConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.sampleRows > 0 && from == FormatCSV {
+		input = csvSample(input, cfg.sampleRows)
+	}
+
+	fromName := formatNames[from]
+	if fromName == "" {
+		fromName = fromFormat
+	}
+	toName := formatNames[to]
+	directives := []string{
+		fmt.Sprintf("将以下 %s 数据转换为 %s 格式", fromName, toName),
+		"保留所有数据，不要增删或修改任何值",
+		fmt.Sprintf("只输出转换后的 %s，不要添加解释", toName),
+	}
+	if cfg.schema != "" {
+		directives = append(directives, "输出必须符合以下结构：\n"+cfg.schema)
+	}
+	if cfg.preserveComments && to == FormatYAML {
+		directives = append(directives, "保留原数据中的注释，以 YAML 注释（#）写在对应的位置")
+	}
+	prompt := gollm.NewPrompt(input, gollm.WithDirectives(directives...))
+
+	response, err := l.Generate(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert %s to %s: %w", fromName, toName, err)
+	}
+	output := stripMarkdownFence(response)
+	if err := parseFormat(output, to, cfg.schema); err != nil {
+		return "", &ConversionError{Format: toName, Output: response, Err: err}
+	}
+	return output, nil
+}
+
+// normalizeFormat returns the canonical name of a format.
+func normalizeFormat(format string) string {
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case "yml":
+		return FormatYAML
+	case "md":
+		return FormatMarkdown
+	}
+	return format
+}
+
+// csvSample returns the header and the first n records of input, or input
+// itself if it has no more or does not parse.
+func csvSample(input string, n int) string {
+	reader := csv.NewReader(strings.NewReader(input))
+	reader.FieldsPerRecord = -1
+	for i := 0; i <= n; i++ {
+		if _, err := reader.Read(); err != nil {
+			return input
+		}
+	}
+	return strings.TrimRight(input[:reader.InputOffset()], "\r\n")
+}
+
+// parseFormat checks that output is valid in format and, for JSON and YAML,
+// that it matches schema if schema is a JSON Schema.
+func parseFormat(output, format, schema string) error {
+	var data interface{}
+	switch format {
+	case FormatJSON:
+		if err := json.Unmarshal([]byte(output), &data); err != nil {
+			return err
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal([]byte(output), &data); err != nil {
+			return err
+		}
+		if data == nil {
+			return errors.New("empty document")
+		}
+	case FormatCSV:
+		reader := csv.NewReader(strings.NewReader(output))
+		records, err := reader.ReadAll()
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return errors.New("no records")
+		}
+		return nil
+	case FormatMarkdown:
+		return parseMarkdownTable(output)
+	case FormatXML:
+		return parseXML(output)
+	}
+
+	var jsonSchema map[string]interface{}
+	if json.Unmarshal([]byte(schema), &jsonSchema) != nil || jsonSchema["type"] == nil {
+		return nil
+	}
+	// Validate YAML through its JSON form.
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return llm.ValidateAgainstSchema(string(encoded), jsonSchema)
+}
+
+// parseMarkdownTable checks that text is a Markdown table: a header row, a
+// delimiter row and data rows, all with the same number of cells.
+func parseMarkdownTable(text string) error {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) < 2 {
+		return errors.New("a table needs a header and a delimiter row")
+	}
+	columns := -1
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") || !strings.HasSuffix(line, "|") || len(line) < 2 {
+			return fmt.Errorf("line %d is not a table row: %q", i+1, line)
+		}
+		cells := strings.Split(line[1:len(line)-1], "|")
+		if columns < 0 {
+			columns = len(cells)
+		} else if len(cells) != columns {
+			return fmt.Errorf("line %d has %d cells, want %d", i+1, len(cells), columns)
+		}
+		if i == 1 {
+			for _, cell := range cells {
+				if strings.Trim(strings.TrimSpace(cell), ":-") != "" || !strings.Contains(cell, "-") {
+					return fmt.Errorf("line 2 is not a delimiter row: %q", line)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseXML checks that text is a well-formed XML document with a root
+// element.
+func parseXML(text string) error {
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	elements := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			elements++
+		}
+	}
+	if elements == 0 {
+		return errors.New("no root element")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertFormat(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"```yaml\nname: gollm\n# 标签\ntags:\n  - go\n  - llm\n```"}}
+
+	output, err := ConvertFormat(context.Background(), l, "{\"name\": \"gollm\", \"tags\": [\"go\", \"llm\"]}", "JSON", "yml",
+		WithPreserveComments(),
+		WithSchema(`{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "name: gollm\n# 标签\ntags:\n  - go\n  - llm", output)
+	directives := l.prompts[0].Directives
+	assert.Equal(t, "将以下 JSON 数据转换为 YAML 格式", directives[0])
+	assert.Contains(t, directives[3], `"required": ["name"]`)
+	assert.Contains(t, directives[4], "YAML 注释")
+}
+
+func TestConvertFormat_SampleRows(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"| 城市 | 人口 |\n| --- | ---: |\n| 杭州 | 1200 |"}}
+	input := "城市,人口\n杭州,1200\n\"苏州\n(江苏)\",1300\n南京,950\n"
+
+	output, err := ConvertFormat(context.Background(), l, input, "csv", "markdown", WithSampleRows(2))
+	require.NoError(t, err)
+	assert.Equal(t, "| 城市 | 人口 |\n| --- | ---: |\n| 杭州 | 1200 |", output)
+	assert.Equal(t, "城市,人口\n杭州,1200\n\"苏州\n(江苏)\",1300", l.prompts[0].Input)
+	assert.Equal(t, "将以下 CSV 数据转换为 Markdown 表格 格式", l.prompts[0].Directives[0])
+}
+
+func TestConvertFormat_InvalidOutput(t *testing.T) {
+	tests := []struct {
+		name, to, response, want string
+		opts                     []ConvertOption
+	}{
+		{"json", "json", `{"name": "gollm",}`, "invalid character", nil},
+		{"schema", "json", `{"tags": []}`, "response does not match schema", []ConvertOption{WithSchema(`{"type": "object", "required": ["name"]}`)}},
+		{"xml", "xml", "<root><item></root>", "element <item> closed by </root>", nil},
+		{"markdown", "markdown", "| a | b |\n| a | b |", "not a delimiter row", nil},
+		{"csv", "csv", "a,b\n1,2,3", "wrong number of fields", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &scriptedLLM{responses: []string{tt.response}}
+			_, err := ConvertFormat(context.Background(), l, "数据", "text", tt.to, tt.opts...)
+			var convErr *ConversionError
+			require.True(t, errors.As(err, &convErr), "got %v", err)
+			assert.Equal(t, tt.response, convErr.Output)
+			assert.ErrorContains(t, convErr.Err, tt.want)
+		})
+	}
+
+	_, err := ConvertFormat(context.Background(), &scriptedLLM{}, "数据", "json", "toml")
+	assert.ErrorContains(t, err, `unsupported target format "toml"`)
+}
@@ -0,0 +1,265 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// Correction categories requested by Correct. The LLM may report others, such
+// as "用词", which are kept as reported.
+const (
+	CorrectionSpelling    = "拼写"
+	CorrectionGrammar     = "语法"
+	CorrectionPunctuation = "标点"
+)
+
+// diffContext is the number of unchanged lines around each hunk of
+// CorrectionResult.UnifiedDiff.
+const diffContext = 3
+
+// CorrectionResult is the result of Correct.
+type CorrectionResult struct {
+	Original  string // The text passed to Correct
+	Corrected string // Original with the edits applied
+	Edits     []Edit // The edits, ordered by position
+}
+
+// Edit is a correction of a span of the text. Start and End are character
+// (rune) offsets into the original text, End exclusive, so
+// []rune(text)[Start:End] is Original.
+type Edit struct {
+	Original    string `json:"original" validate:"required"`
+	Replacement string `json:"replacement"`
+	Category    string `json:"category" validate:"required"`
+	Explanation string `json:"explanation"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+}
+
+// CorrectOption configures Correct.
+type CorrectOption func(*correctConfig)
+
+type correctConfig struct {
+	categories []string
+}
+
+// WithCorrectionCategories restricts Correct to the given categories, such as
+// CorrectionSpelling. By default spelling, grammar and punctuation are
+// corrected.
+func WithCorrectionCategories(categories ...string) CorrectOption {
+	return func(c *correctConfig) {
+		c.categories = append(c.categories, categories...)
+	}
+}
+
+// Correct fixes the spelling, grammar and punctuation of text without
+// rewriting its style, and reports every edit. As with ExtractEntities, the
+// offsets reported by the LLM are not trusted: each edit is searched for in
+// the text and placed at the occurrence closest to its reported offset.
+// Edits whose original text does not occur in the text, or that overlap an
+// earlier edit, are dropped with a debug log, so the corrected text is always
+// the original with exactly the reported edits applied.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for the correction
+//   - text: The text to correct
+//   - opts: Optional correction options
+//
+// Returns:
+//   - *CorrectionResult: The corrected text and its edits
+//   - error: Any error encountered during correction
+//
+// Example:
+//
+//	result, err := Correct(ctx, llm, "我门今天去公园玩，天气很好")
+//	fmt.Println(result.Corrected)
+//	fmt.Print(result.UnifiedDiff())
+func Correct(ctx context.Context, l gollm.LLM, text string, opts ...CorrectOption) (*CorrectionResult, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	cfg := &correctConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(cfg.categories) == 0 {
+		cfg.categories = []string{CorrectionSpelling, CorrectionGrammar, CorrectionPunctuation}
+	}
+
+	edits, err := ExtractStructuredList[Edit](ctx, l, text, WithPromptOptions(gollm.WithDirectives(
+		"找出文本中需要修改的错误，只修改以下类别："+strings.Join(cfg.categories, "、")+"；不要改变文本的风格和意思",
+		"每处修改单独列出：original 为原文中的错误片段，必须与原文完全一致；replacement 为修改后的内容",
+		"补充遗漏的内容（如缺少的标点）时，original 包含其前面的字，replacement 为补充后的内容",
+		"category 为修改的类别，explanation 简要说明修改的原因",
+		"start 为 original 第一个字符在原文中的位置，end 为最后一个字符之后的位置，均从 0 开始按字符计数",
+		"没有错误时返回空数组",
+	)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to correct text: %w", err)
+	}
+
+	runes := []rune(text)
+	placed := make([]Edit, 0, len(edits))
+	for _, e := range edits {
+		if e.Original == e.Replacement {
+			continue
+		}
+		best := -1
+		occurrences := findOccurrences(runes, []rune(e.Original))
+		for i, span := range occurrences {
+			if overlapsAny(placed, span) {
+				continue
+			}
+			if best < 0 || abs(span[0]-e.Start) < abs(occurrences[best][0]-e.Start) {
+				best = i
+			}
+		}
+		if best < 0 {
+			l.GetLogger().Debug("Dropped correction not matching the text", "original", e.Original, "replacement", e.Replacement)
+			continue
+		}
+		e.Start, e.End = occurrences[best][0], occurrences[best][1]
+		e.Original = string(runes[e.Start:e.End])
+		placed = append(placed, e)
+	}
+	sort.Slice(placed, func(i, j int) bool { return placed[i].Start < placed[j].Start })
+
+	var corrected strings.Builder
+	last := 0
+	for _, e := range placed {
+		corrected.WriteString(string(runes[last:e.Start]))
+		corrected.WriteString(e.Replacement)
+		last = e.End
+	}
+	corrected.WriteString(string(runes[last:]))
+	return &CorrectionResult{Original: text, Corrected: corrected.String(), Edits: placed}, nil
+}
+
+// overlapsAny reports whether span overlaps the span of any of edits.
+func overlapsAny(edits []Edit, span [2]int) bool {
+	for _, e := range edits {
+		if span[0] < e.End && e.Start < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// UnifiedDiff returns the changes from Original to Corrected as a line-based
+// unified diff, as printed by "diff -u", or "" if there are none.
+func (r *CorrectionResult) UnifiedDiff() string {
+	if r.Original == r.Corrected {
+		return ""
+	}
+	a := strings.Split(r.Original, "\n")
+	b := strings.Split(r.Corrected, "\n")
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	out.WriteString("--- original\n+++ corrected\n")
+	for start := 0; start < len(ops); {
+		// Skip to the next change and take the unchanged lines before it.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		from := max(0, start-diffContext)
+		// Extend the hunk until a run of unchanged lines too long to join
+		// two changes.
+		end, equal := start, 0
+		for ; end < len(ops) && equal <= 2*diffContext; end++ {
+			if ops[end].kind == ' ' {
+				equal++
+			} else {
+				equal = 0
+			}
+		}
+		end -= max(0, equal-diffContext)
+
+		aStart, bStart, aLines, bLines := ops[from].a, ops[from].b, 0, 0
+		var body strings.Builder
+		for _, op := range ops[from:end] {
+			body.WriteString(string(op.kind) + op.line + "\n")
+			if op.kind != '+' {
+				aLines++
+			}
+			if op.kind != '-' {
+				bLines++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aStart, aLines), hunkRange(bStart, bLines))
+		out.WriteString(body.String())
+		start = end
+	}
+	return out.String()
+}
+
+// diffOp is a line of a diff: kept (' '), removed ('-') or added ('+'). a and
+// b are the indexes of the line in the old and new text, or of the next line
+// for lines missing from that text.
+type diffOp struct {
+	kind byte
+	line string
+	a, b int
+}
+
+// diffLines returns the shortest edit script from a to b, computed from the
+// longest common subsequence of their lines.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], i, j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], i, j})
+			j++
+		}
+	}
+	return ops
+}
+
+// hunkRange formats the line range of a hunk as in "diff -u": 1-based start
+// and count, with the count omitted if it is 1.
+func hunkRange(start, lines int) string {
+	switch lines {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, lines)
+	}
+}
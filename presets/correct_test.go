@@ -0,0 +1,62 @@
+package presets
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrect(t *testing.T) {
+	text := "我门今天去公园玩，我门很开心\n第二行没有问题"
+	l := &scriptedLLM{responses: []string{`[
+		{"original": "我门", "replacement": "我们", "category": "拼写", "explanation": "“门”应为“们”", "start": 9, "end": 11},
+		{"original": "我门", "replacement": "我们", "category": "拼写", "explanation": "“门”应为“们”", "start": 0, "end": 2},
+		{"original": "开心", "replacement": "开心。", "category": "标点", "explanation": "句末缺少句号", "start": 40, "end": 42},
+		{"original": "不存在", "replacement": "没有", "category": "语法", "explanation": "", "start": 3, "end": 6}
+	]`}}
+
+	result, err := Correct(context.Background(), l, text, WithCorrectionCategories(CorrectionSpelling, CorrectionPunctuation))
+	require.NoError(t, err)
+	assert.Equal(t, "我们今天去公园玩，我们很开心。\n第二行没有问题", result.Corrected)
+	require.Len(t, result.Edits, 3, "an edit not in the text is dropped")
+	for _, e := range result.Edits {
+		assert.Equal(t, e.Original, string([]rune(text)[e.Start:e.End]))
+	}
+	assert.Equal(t, [][2]int{{0, 2}, {9, 11}, {12, 14}},
+		[][2]int{{result.Edits[0].Start, result.Edits[0].End}, {result.Edits[1].Start, result.Edits[1].End}, {result.Edits[2].Start, result.Edits[2].End}})
+	assert.Contains(t, l.prompts[0].Directives[0], "拼写、标点；")
+
+	assert.Equal(t, "--- original\n+++ corrected\n@@ -1,2 +1,2 @@\n-我门今天去公园玩，我门很开心\n+我们今天去公园玩，我们很开心。\n 第二行没有问题\n",
+		result.UnifiedDiff())
+}
+
+func TestCorrect_NoEdits(t *testing.T) {
+	result, err := Correct(context.Background(), &scriptedLLM{responses: []string{`[]`}}, "这句话没有错误。")
+	require.NoError(t, err)
+	assert.Equal(t, "这句话没有错误。", result.Corrected)
+	assert.Empty(t, result.UnifiedDiff())
+
+	_, err = Correct(context.Background(), &scriptedLLM{}, " ")
+	assert.ErrorContains(t, err, "text cannot be empty")
+}
+
+func TestCorrectionResult_UnifiedDiff(t *testing.T) {
+	lines := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"}
+	changed := append([]string(nil), lines...)
+	changed[0] = "A"
+	changed[11] = "L"
+	r := &CorrectionResult{Original: strings.Join(lines, "\n"), Corrected: strings.Join(changed, "\n")}
+
+	assert.Equal(t, "--- original\n+++ corrected\n"+
+		"@@ -1,4 +1,4 @@\n-a\n+A\n b\n c\n d\n"+
+		"@@ -9,4 +9,4 @@\n i\n j\n k\n-l\n+L\n", r.UnifiedDiff(), "distant changes get separate hunks")
+
+	changed[11] = "l"
+	changed[6] = "G"
+	r.Corrected = strings.Join(changed, "\n")
+	assert.Equal(t, "--- original\n+++ corrected\n"+
+		"@@ -1,10 +1,10 @@\n-a\n+A\n b\n c\n d\n e\n f\n-g\n+G\n h\n i\n j\n", r.UnifiedDiff(), "close changes share a hunk")
+}
@@ -0,0 +1,117 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// defaultArgumentCount is the number of arguments per side asked for by
+// Debate.
+const defaultArgumentCount = 3
+
+// DebateResult is the result of Debate.
+type DebateResult struct {
+	Topic        string     `json:"topic"`
+	ProArguments []Argument `json:"pro_arguments" validate:"required,min=1,dive"`
+	ConArguments []Argument `json:"con_arguments" validate:"required,min=1,dive"`
+	Synthesis    string     `json:"synthesis" validate:"required"`
+}
+
+// Argument is an argument for or against the topic of a debate.
+type Argument struct {
+	Point      string `json:"point" validate:"required"`
+	Supporting string `json:"supporting" validate:"required"`
+}
+
+// DebateOption configures Debate.
+type DebateOption func(*debateConfig)
+
+type debateConfig struct {
+	count   int
+	persona string
+}
+
+// WithArgumentCount sets the number of arguments on each side. The default is
+// 3.
+func WithArgumentCount(n int) DebateOption {
+	return func(c *debateConfig) {
+		c.count = n
+	}
+}
+
+// WithExpertPersona argues the debate from the point of view of an expert,
+// such as "economist", "scientist" or "伦理学家".
+func WithExpertPersona(persona string) DebateOption {
+	return func(c *debateConfig) {
+		c.persona = persona
+	}
+}
+
+// Debate generates balanced arguments for and against a topic, each with its
+// supporting evidence or reasoning, and a synthesis weighing the two sides.
+// It uses ExtractStructuredData, so a response with no arguments on either
+// side, or an argument without a point or support, is rejected and asked for
+// again. Extra arguments beyond the requested count are dropped.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for the debate
+//   - topic: The question or proposition to debate
+//   - opts: Optional debate options such as WithArgumentCount
+//
+// Returns:
+//   - *DebateResult: The arguments on both sides and their synthesis
+//   - error: Any error encountered during generation
+//
+// Example:
+//
+//	result, err := Debate(ctx, llm, "城市是否应该全面禁止燃油车",
+//	    WithArgumentCount(2),
+//	    WithExpertPersona("economist"),
+//	)
+//	for _, a := range result.ProArguments {
+//	    fmt.Println("+", a.Point)
+//	}
+func Debate(ctx context.Context, l gollm.LLM, topic string, opts ...DebateOption) (*DebateResult, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(topic) == "" {
+		return nil, fmt.Errorf("topic cannot be empty")
+	}
+	cfg := &debateConfig{count: defaultArgumentCount}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.count <= 0 {
+		return nil, fmt.Errorf("argument count must be positive, got %d", cfg.count)
+	}
+
+	directives := []string{
+		fmt.Sprintf("针对文本中的议题，分别给出 %d 条支持的论点（pro_arguments）和 %d 条反对的论点（con_arguments）", cfg.count, cfg.count),
+		"每条论点的 point 为论点本身，supporting 为支撑它的证据或推理",
+		"正反双方的论点同样有力，不要偏向任何一方",
+		"synthesis 为权衡双方论点后的综合分析",
+	}
+	if cfg.persona != "" {
+		directives = append(directives, "以"+cfg.persona+"的专业视角进行论证")
+	}
+
+	result, err := ExtractStructuredData[DebateResult](ctx, l, topic, WithPromptOptions(gollm.WithDirectives(directives...)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate debate: %w", err)
+	}
+	if len(result.ProArguments) < cfg.count || len(result.ConArguments) < cfg.count {
+		l.GetLogger().Warn("Debate returned fewer arguments than requested",
+			"requested", cfg.count, "pro", len(result.ProArguments), "con", len(result.ConArguments))
+	}
+	result.Topic = topic
+	result.ProArguments = result.ProArguments[:min(cfg.count, len(result.ProArguments))]
+	result.ConArguments = result.ConArguments[:min(cfg.count, len(result.ConArguments))]
+	return result, nil
+}
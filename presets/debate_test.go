@@ -0,0 +1,47 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebate(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"是", `{
+		"topic": "远程办公",
+		"pro_arguments": [
+			{"point": "节省通勤时间", "supporting": "员工平均每天通勤近一小时"},
+			{"point": "扩大招聘范围", "supporting": "不受地域限制"},
+			{"point": "多余的论点", "supporting": "超出请求的数量"}
+		],
+		"con_arguments": [
+			{"point": "协作效率下降", "supporting": "即时沟通变少"},
+			{"point": "工作与生活界限模糊", "supporting": "加班更难察觉"}
+		],
+		"synthesis": "混合办公兼顾双方"
+	}`}}
+
+	result, err := Debate(context.Background(), l, "公司是否应该全面推行远程办公", WithArgumentCount(2), WithExpertPersona("经济学家"))
+	require.NoError(t, err)
+	assert.Equal(t, "公司是否应该全面推行远程办公", result.Topic)
+	assert.Len(t, result.ProArguments, 2)
+	assert.Len(t, result.ConArguments, 2)
+	assert.Equal(t, "混合办公兼顾双方", result.Synthesis)
+	assert.Contains(t, l.prompts[1].Directives, "以经济学家的专业视角进行论证")
+}
+
+func TestDebate_OneSided(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"是",
+		`{"pro_arguments": [{"point": "更环保", "supporting": "减少排放"}], "con_arguments": [], "synthesis": "利大于弊"}`,
+		`{"pro_arguments": [{"point": "更环保", "supporting": "减少排放"}], "con_arguments": [{"point": "成本高", "supporting": "初期投入大"}], "synthesis": "需权衡"}`,
+	}}
+
+	result, err := Debate(context.Background(), l, "是否推广电动车", WithArgumentCount(1))
+	require.NoError(t, err)
+	assert.Len(t, result.ConArguments, 1, "a one-sided response is re-asked")
+
+	_, err = Debate(context.Background(), &scriptedLLM{}, "议题", WithArgumentCount(0))
+	assert.ErrorContains(t, err, "argument count must be positive")
+}
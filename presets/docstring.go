@@ -0,0 +1,206 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// DocstringStyle is the documentation comment convention GenerateDocstring follows.
+type DocstringStyle string
+
+const (
+	// DocstringGoDoc produces Go doc comments: "//" lines starting with the name.
+	DocstringGoDoc DocstringStyle = "godoc"
+	// DocstringJSDoc produces /** ... */ blocks with @param and @returns tags.
+	DocstringJSDoc DocstringStyle = "jsdoc"
+	// DocstringGoogle produces Google-style Python docstrings (Args:, Returns:, Raises:).
+	DocstringGoogle DocstringStyle = "google"
+	// DocstringNumPy produces NumPy-style docstrings with underlined sections.
+	DocstringNumPy DocstringStyle = "numpy"
+	// DocstringSphinx produces reStructuredText docstrings with :param: fields.
+	DocstringSphinx DocstringStyle = "sphinx"
+)
+
+// docstringStyleGuides describes each style to the LLM.
+var docstringStyleGuides = map[DocstringStyle]string{
+	DocstringGoDoc:  "GoDoc 风格：每行以 \"// \" 开头，第一句以函数名开头并说明其作用，参数和返回值用完整的句子描述",
+	DocstringJSDoc:  "JSDoc 风格：使用 /** ... */ 注释块，先写描述，再用 @param {类型} 名称 描述 和 @returns {类型} 描述 标注参数与返回值",
+	DocstringGoogle: "Google 风格的 Python docstring：使用三引号，先写一行摘要，再用 Args:、Returns:、Raises: 小节描述参数、返回值和异常",
+	DocstringNumPy:  "NumPy 风格的 docstring：使用三引号，先写一行摘要，再用带下划线（---）的 Parameters、Returns、Raises 小节",
+	DocstringSphinx: "Sphinx（reStructuredText）风格的 docstring：使用三引号，先写摘要，再用 :param 名称:、:type 名称:、:returns:、:rtype:、:raises: 字段",
+}
+
+// signaturePatterns match the first line of a function signature, by language.
+var signaturePatterns = map[string]*regexp.Regexp{
+	"go":         regexp.MustCompile(`^\s*func\s*(\([^)]*\)\s*)?\w+`),
+	"python":     regexp.MustCompile(`^\s*(async\s+)?def\s+\w+\s*\(`),
+	"javascript": regexp.MustCompile(`^\s*(export\s+)?(default\s+)?(async\s+)?(function\s*\*?\s*\w+\s*\(|(const|let|var)\s+\w+\s*=\s*(async\s+)?(function|\(|\w+\s*=>))|^\s*(static\s+)?(async\s+)?\w+\s*\([^)]*\)\s*(:\s*[^{]+)?\{`),
+}
+
+// languageAliases maps language names to the keys of signaturePatterns.
+var languageAliases = map[string]string{
+	"go":         "go",
+	"golang":     "go",
+	"python":     "python",
+	"py":         "python",
+	"javascript": "javascript",
+	"js":         "javascript",
+	"typescript": "javascript",
+	"ts":         "javascript",
+}
+
+// DocstringOption configures GenerateDocstring.
+type DocstringOption func(*docstringConfig)
+
+type docstringConfig struct {
+	surrounding string
+}
+
+// WithExistingContext provides code surrounding the function, such as the
+// rest of the file, so the docstring can refer to related types and follow
+// the file's conventions.
+func WithExistingContext(surrounding string) DocstringOption {
+	return func(c *docstringConfig) {
+		c.surrounding = surrounding
+	}
+}
+
+// GenerateDocstring writes a documentation comment for the function or method
+// in code. The signature is parsed from the code so the LLM documents the
+// right parameters, and markdown code fences the LLM adds are removed from
+// the result.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use
+//   - code: Source code of a single function or method
+//   - language: Programming language of the code, e.g. "go", "python" or "typescript"
+//   - style: The docstring convention to follow
+//   - opts: Optional settings such as WithExistingContext
+//
+// Returns:
+//   - string: The docstring, ready to be placed above (GoDoc, JSDoc) or at the
+//     start of (Python styles) the function
+//   - error: Any error encountered during parsing or generation
+//
+// Example:
+//
+//	doc, err := GenerateDocstring(ctx, llm, funcSource, "go", DocstringGoDoc,
+//	    WithExistingContext(fileSource),
+//	)
+func GenerateDocstring(ctx context.Context, l gollm.LLM, code, language string, style DocstringStyle, opts ...DocstringOption) (string, error) {
+	if l == nil {
+		return "", fmt.Errorf("LLM instance cannot be nil")
+	}
+	guide, ok := docstringStyleGuides[style]
+	if !ok {
+		return "", fmt.Errorf("unknown docstring style %q", style)
+	}
+	cfg := &docstringConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	signature, err := parseSignature(code, language)
+	if err != nil {
+		return "", err
+	}
+
+	prompt := gollm.NewPrompt(fmt.Sprintf("为以下 %s 函数编写文档注释。\n\n函数签名:\n%s\n\n完整代码:\n%s", language, signature, code))
+	prompt.Apply(
+		gollm.WithDirectives(
+			"遵循"+guide,
+			"准确描述函数的作用、每个参数、返回值以及可能的错误或异常",
+			"只描述代码实际做的事情，不要臆测",
+			"注释使用的自然语言与代码中已有注释保持一致；没有已有注释时使用英文",
+		),
+		gollm.WithOutput("仅输出文档注释本身，不要包含函数代码、解释或 Markdown 代码块"),
+	)
+	if strings.TrimSpace(cfg.surrounding) != "" {
+		prompt.Apply(gollm.WithContext("函数所在文件的其他代码:\n" + cfg.surrounding))
+	}
+
+	response, err := l.Generate(ctx, prompt)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate docstring: %w", err)
+	}
+	docstring := stripMarkdownFence(response)
+	if style == DocstringGoDoc {
+		docstring = commentLines(docstring)
+	}
+	return docstring, nil
+}
+
+// parseSignature returns the signature of the first function in code, from
+// its first line up to the opening brace or colon of the body. For languages
+// without a known pattern, the first non-empty line is used.
+func parseSignature(code, language string) (string, error) {
+	lines := strings.Split(strings.ReplaceAll(code, "\r\n", "\n"), "\n")
+	pattern := signaturePatterns[languageAliases[strings.ToLower(strings.TrimSpace(language))]]
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if pattern != nil && !pattern.MatchString(line) {
+			continue
+		}
+		// Signatures may span lines; collect them until the body starts.
+		var signature []string
+		for _, next := range lines[i:] {
+			if arrow := strings.Index(next, "=>"); arrow >= 0 {
+				signature = append(signature, next[:arrow+2])
+				break
+			}
+			if brace := strings.Index(next, "{"); brace >= 0 {
+				signature = append(signature, next[:brace])
+				break
+			}
+			signature = append(signature, next)
+			if strings.HasSuffix(strings.TrimSpace(next), ":") {
+				break
+			}
+		}
+		return strings.TrimSpace(strings.Join(signature, "\n")), nil
+	}
+	return "", fmt.Errorf("no %s function signature found in code", language)
+}
+
+// stripMarkdownFence returns the contents of the first markdown code block in
+// text, or text itself if it has none.
+func stripMarkdownFence(text string) string {
+	text = strings.TrimSpace(text)
+	open := strings.Index(text, "```")
+	if open < 0 {
+		return text
+	}
+	body := text[open+3:]
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 {
+		body = body[newline+1:] // Skip the language tag
+	}
+	if end := strings.Index(body, "```"); end >= 0 {
+		body = body[:end]
+	}
+	return strings.TrimSpace(body)
+}
+
+// commentLines prefixes every line that is not already a "//" comment.
+func commentLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "//"):
+			lines[i] = trimmed
+		case trimmed == "":
+			lines[i] = "//"
+		default:
+			lines[i] = "// " + trimmed
+		}
+	}
+	return strings.Join(lines, "\n")
+}
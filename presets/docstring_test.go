@@ -0,0 +1,54 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSignature(t *testing.T) {
+	cases := []struct {
+		name, language, code, want string
+	}{
+		{"go method", "go", "// leading comment\nfunc (s *Store) Get(ctx context.Context, key string) (string, error) {\n\treturn \"\", nil\n}", "func (s *Store) Get(ctx context.Context, key string) (string, error)"},
+		{"python multi-line", "python", "@cache\ndef area(width: float,\n         height: float) -> float:\n    return width * height", "def area(width: float,\n         height: float) -> float:"},
+		{"javascript function", "js", "export async function fetchUser(id) {\n  return db.get(id);\n}", "export async function fetchUser(id)"},
+		{"typescript arrow", "typescript", "const add = (a: number, b: number): number => a + b;", "const add = (a: number, b: number): number =>"},
+		{"unknown language", "rust", "fn add(a: i32, b: i32) -> i32 {\n    a + b\n}", "fn add(a: i32, b: i32) -> i32"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseSignature(tc.code, tc.language)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := parseSignature("x := 1", "go")
+	assert.ErrorContains(t, err, "no go function signature found")
+}
+
+func TestGenerateDocstring(t *testing.T) {
+	code := "func Add(a, b int) int {\n\treturn a + b\n}"
+	l := &scriptedLLM{responses: []string{"```go\nAdd returns the sum of a and b.\n\n// It never overflows silently.\n```"}}
+
+	doc, err := GenerateDocstring(context.Background(), l, code, "go", DocstringGoDoc,
+		WithExistingContext("package mathx"))
+	require.NoError(t, err)
+	assert.Equal(t, "// Add returns the sum of a and b.\n//\n// It never overflows silently.", doc)
+
+	prompt := l.prompts[0]
+	assert.Contains(t, prompt.Input, "func Add(a, b int) int\n")
+	assert.Contains(t, prompt.Context, "package mathx")
+	assert.Contains(t, prompt.Directives[0], "GoDoc")
+
+	l = &scriptedLLM{responses: []string{"```python\n\"\"\"Return the area.\n\nArgs:\n    width: The width.\n\"\"\"\n```"}}
+	doc, err = GenerateDocstring(context.Background(), l, "def area(width):\n    return width", "python", DocstringGoogle)
+	require.NoError(t, err)
+	assert.Equal(t, "\"\"\"Return the area.\n\nArgs:\n    width: The width.\n\"\"\"", doc)
+
+	_, err = GenerateDocstring(context.Background(), l, code, "go", DocstringStyle("doxygen"))
+	assert.ErrorContains(t, err, `unknown docstring style "doxygen"`)
+}
@@ -0,0 +1,178 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// EmailLength is how long the body of an email written by WriteEmail is.
+type EmailLength string
+
+// Lengths of WriteEmail.
+const (
+	EmailBrief    EmailLength = "brief"    // A few sentences
+	EmailMedium   EmailLength = "medium"   // A few short paragraphs, the default
+	EmailDetailed EmailLength = "detailed" // Several paragraphs with context and next steps
+)
+
+// emailLengthDirectives are the directives of each EmailLength.
+var emailLengthDirectives = map[EmailLength]string{
+	EmailBrief:    "正文简短，不超过 100 字，只说明目的和最关键的信息",
+	EmailMedium:   "正文长度适中，约 150 到 300 字，分为两到三段",
+	EmailDetailed: "正文详细，约 300 到 600 字，交代背景、逐条说明要点并写明后续步骤",
+}
+
+// EmailRequest describes the email WriteEmail writes.
+type EmailRequest struct {
+	Subject       string   // Suggested subject, refined by the LLM; optional
+	RecipientName string   // Who the email is to, e.g. "王经理"
+	RecipientRole string   // The recipient's role, e.g. "采购部负责人"
+	SenderName    string   // Who signs the email
+	Purpose       string   // What the email is for; required
+	KeyPoints     []string // Points the email must make
+}
+
+// EmailDraft is the result of WriteEmail.
+type EmailDraft struct {
+	Subject        string `json:"subject" validate:"required"`
+	Body           string `json:"body" validate:"required"` // Greeting, text and sign-off, without the subject
+	ToneAssessment string `json:"tone_assessment"`          // The LLM's one-sentence assessment of the tone of the draft
+}
+
+// EmailOption configures WriteEmail.
+type EmailOption func(*emailConfig)
+
+type emailConfig struct {
+	tone     string
+	length   EmailLength
+	language string
+}
+
+// WithEmailTone sets the tone of the email, such as "formal", "casual" or
+// "assertive". The default is "formal".
+func WithEmailTone(tone string) EmailOption {
+	return func(c *emailConfig) {
+		c.tone = tone
+	}
+}
+
+// WithEmailLength sets the length of the email. The default is EmailMedium.
+func WithEmailLength(length EmailLength) EmailOption {
+	return func(c *emailConfig) {
+		c.length = length
+	}
+}
+
+// WithEmailLanguage sets the language the email is written in, e.g. "中文"
+// or "English". By default it is the language of the request.
+func WithEmailLanguage(lang string) EmailOption {
+	return func(c *emailConfig) {
+		c.language = lang
+	}
+}
+
+// WriteEmail drafts a business email for req. It uses ExtractStructuredData,
+// so the subject and body always come back as separate fields; a subject line
+// the LLM repeats at the top of the body is removed.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for writing
+//   - req: Who the email is to and from, and what it must say
+//   - opts: Optional email options such as WithEmailTone and WithEmailLength
+//
+// Returns:
+//   - *EmailDraft: The subject and body of the email
+//   - error: Any error encountered during generation
+//
+// Example:
+//
+//	draft, err := WriteEmail(ctx, llm, EmailRequest{
+//	    RecipientName: "王经理",
+//	    RecipientRole: "采购部负责人",
+//	    SenderName:    "李明",
+//	    Purpose:       "催促尚未支付的第三季度货款",
+//	    KeyPoints:     []string{"发票已于 9 月 5 日寄出", "请在 10 月 31 日前付款"},
+//	}, WithEmailTone("assertive"), WithEmailLength(EmailBrief))
+//	fmt.Printf("主题：%s\n\n%s\n", draft.Subject, draft.Body)
+func WriteEmail(ctx context.Context, l gollm.LLM, req EmailRequest, opts ...EmailOption) (*EmailDraft, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(req.Purpose) == "" {
+		return nil, fmt.Errorf("purpose cannot be empty")
+	}
+	cfg := &emailConfig{tone: "formal", length: EmailMedium}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	lengthDirective, ok := emailLengthDirectives[cfg.length]
+	if !ok {
+		return nil, fmt.Errorf("unknown email length %q", cfg.length)
+	}
+
+	directives := []string{
+		"根据文本中的要求撰写一封商务邮件",
+		"subject 为简洁明确的邮件主题，不要加“主题：”等前缀",
+		"body 为邮件正文，包括称呼、正文和落款，不要包含主题",
+		"每个要点都必须在正文中体现，不要编造要求中没有的事实、日期或金额",
+		"邮件语气：" + cfg.tone,
+		lengthDirective,
+		"tone_assessment 为一句话评价邮件实际的语气",
+	}
+	if cfg.language != "" {
+		directives = append(directives, fmt.Sprintf("邮件使用%s撰写", cfg.language))
+	}
+
+	draft, err := ExtractStructuredData[EmailDraft](ctx, l, emailBrief(req), WithPromptOptions(gollm.WithDirectives(directives...)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write email: %w", err)
+	}
+	draft.Subject = strings.TrimSpace(draft.Subject)
+	draft.Body = stripSubjectLine(strings.TrimSpace(draft.Body), draft.Subject)
+	return draft, nil
+}
+
+// emailBrief describes req as the text WriteEmail writes the email from.
+func emailBrief(req EmailRequest) string {
+	var b strings.Builder
+	b.WriteString("邮件目的：" + strings.TrimSpace(req.Purpose) + "\n")
+	if req.Subject != "" {
+		b.WriteString("建议的主题：" + req.Subject + "\n")
+	}
+	if req.RecipientName != "" {
+		b.WriteString("收件人：" + req.RecipientName + "\n")
+	}
+	if req.RecipientRole != "" {
+		b.WriteString("收件人职位：" + req.RecipientRole + "\n")
+	}
+	if req.SenderName != "" {
+		b.WriteString("发件人：" + req.SenderName + "\n")
+	}
+	if len(req.KeyPoints) > 0 {
+		b.WriteString("要点：\n")
+		for i, point := range req.KeyPoints {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, point)
+		}
+	}
+	return b.String()
+}
+
+// stripSubjectLine removes a first line of body that only repeats subject,
+// such as "主题：…" or "Subject: …".
+func stripSubjectLine(body, subject string) string {
+	first, rest, _ := strings.Cut(body, "\n")
+	line := strings.TrimSpace(first)
+	for _, prefix := range []string{"主题：", "主题:", "Subject:", "subject:"} {
+		line = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+	}
+	if line != subject || strings.TrimSpace(rest) == "" {
+		return body
+	}
+	return strings.TrimSpace(rest)
+}
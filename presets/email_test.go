@@ -0,0 +1,50 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteEmail(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"是", `{
+		"subject": " 第三季度货款付款提醒 ",
+		"body": "主题：第三季度货款付款提醒\n\n王经理您好：\n\n第三季度的发票已于 9 月 5 日寄出，请在 10 月 31 日前付款。\n\n李明",
+		"tone_assessment": "坚定而礼貌"
+	}`}}
+
+	draft, err := WriteEmail(context.Background(), l, EmailRequest{
+		RecipientName: "王经理",
+		RecipientRole: "采购部负责人",
+		SenderName:    "李明",
+		Purpose:       "催促尚未支付的第三季度货款",
+		KeyPoints:     []string{"发票已于 9 月 5 日寄出", "请在 10 月 31 日前付款"},
+	}, WithEmailTone("assertive"), WithEmailLength(EmailBrief), WithEmailLanguage("中文"))
+	require.NoError(t, err)
+	assert.Equal(t, &EmailDraft{
+		Subject:        "第三季度货款付款提醒",
+		Body:           "王经理您好：\n\n第三季度的发票已于 9 月 5 日寄出，请在 10 月 31 日前付款。\n\n李明",
+		ToneAssessment: "坚定而礼貌",
+	}, draft, "the subject repeated in the body is removed")
+	assert.Contains(t, l.prompts[1].Input, "收件人职位：采购部负责人\n")
+	assert.Contains(t, l.prompts[1].Input, "2. 请在 10 月 31 日前付款\n")
+	assert.Contains(t, l.prompts[1].Directives, "邮件语气：assertive")
+	assert.Contains(t, l.prompts[1].Directives, emailLengthDirectives[EmailBrief])
+	assert.Contains(t, l.prompts[1].Directives, "邮件使用中文撰写")
+}
+
+func TestWriteEmail_InvalidInput(t *testing.T) {
+	ctx := context.Background()
+	_, err := WriteEmail(ctx, &scriptedLLM{}, EmailRequest{RecipientName: "王经理"})
+	assert.ErrorContains(t, err, "purpose cannot be empty")
+	_, err = WriteEmail(ctx, &scriptedLLM{}, EmailRequest{Purpose: "约见"}, WithEmailLength("long"))
+	assert.ErrorContains(t, err, `unknown email length "long"`)
+
+	l := &scriptedLLM{responses: []string{"是", `{"subject": "会议邀请", "body": ""}`, `{"subject": "会议邀请", "body": "您好，诚邀您参加下周的会议。"}`}}
+	draft, err := WriteEmail(ctx, l, EmailRequest{Purpose: "邀请参加会议"})
+	require.NoError(t, err)
+	assert.Equal(t, "您好，诚邀您参加下周的会议。", draft.Body, "a draft without a body is asked for again")
+	assert.Contains(t, l.prompts[1].Directives, "邮件语气：formal")
+}
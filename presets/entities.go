@@ -0,0 +1,181 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// Entity types recognized by ExtractEntities by default. WithEntityTypes
+// accepts these and any custom type.
+const (
+	EntityPerson   = "person"
+	EntityOrg      = "org"
+	EntityLocation = "location"
+	EntityDate     = "date"
+)
+
+// defaultEntityTypes are the entity types extracted without WithEntityTypes.
+var defaultEntityTypes = []string{EntityPerson, EntityOrg, EntityLocation, EntityDate}
+
+// Entity is a named entity found by ExtractEntities. Start and End are
+// character (rune) offsets into the original text, End exclusive, so
+// []rune(text)[Start:End] is Text. Both are -1 if the entity could not be
+// located in the text.
+type Entity struct {
+	Text  string `json:"text" validate:"required"`
+	Type  string `json:"type" validate:"required"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// EntityOption configures ExtractEntities.
+type EntityOption func(*entityConfig)
+
+type entityConfig struct {
+	types []string
+}
+
+// WithEntityTypes restricts ExtractEntities to the given entity types, such
+// as EntityPerson or a custom type like "product". Entities the LLM reports
+// with other types are dropped.
+func WithEntityTypes(types ...string) EntityOption {
+	return func(c *entityConfig) {
+		c.types = append(c.types, types...)
+	}
+}
+
+// ExtractEntities finds the named entities in text. The offsets reported by
+// the LLM are not trusted: each entity is searched for in the text, and the
+// occurrence closest to the reported offset that no earlier entity claimed is
+// used, which corrects the off-by-one errors models commonly make. Entities
+// whose text does not occur in the text keep offsets of -1 rather than a
+// guessed position, and an entity listed more often than its text occurs is
+// dropped.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for the extraction
+//   - text: The text to extract entities from
+//   - opts: Optional entity options such as WithEntityTypes
+//
+// Returns:
+//   - []Entity: The entities ordered by position, with unlocated entities last
+//   - error: Any error encountered during extraction
+//
+// Example:
+//
+//	entities, err := ExtractEntities(ctx, llm, "马云于 1999 年在杭州创立了阿里巴巴。")
+//	for _, e := range entities {
+//	    fmt.Printf("%s (%s) [%d:%d]\n", e.Text, e.Type, e.Start, e.End)
+//	}
+func ExtractEntities(ctx context.Context, l gollm.LLM, text string, opts ...EntityOption) ([]Entity, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	cfg := &entityConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(cfg.types) == 0 {
+		cfg.types = defaultEntityTypes
+	}
+
+	entities, err := ExtractStructuredList[Entity](ctx, l, text, WithPromptOptions(gollm.WithDirectives(
+		"提取文本中的命名实体，type 必须是以下类型之一："+strings.Join(cfg.types, "、"),
+		"text 必须与原文中的片段完全一致，不要改写、翻译或补全",
+		"start 为实体第一个字符在原文中的位置，end 为实体最后一个字符之后的位置，均从 0 开始按字符计数",
+		"同一实体在文本中多次出现时，每次出现分别列出",
+	)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract entities: %w", err)
+	}
+
+	runes := []rune(text)
+	claimed := make(map[[2]int]bool)
+	result := make([]Entity, 0, len(entities))
+	for _, e := range entities {
+		entityType, ok := matchCategory(cfg.types, e.Type)
+		if !ok {
+			l.GetLogger().Debug("Dropped entity of unrequested type", "text", e.Text, "type", e.Type)
+			continue
+		}
+		e.Type = entityType
+
+		occurrences := findOccurrences(runes, []rune(strings.TrimSpace(e.Text)))
+		if len(occurrences) == 0 {
+			e.Start, e.End = -1, -1
+			result = append(result, e)
+			continue
+		}
+		best := -1
+		for i, span := range occurrences {
+			if !claimed[span] && (best < 0 || abs(span[0]-e.Start) < abs(occurrences[best][0]-e.Start)) {
+				best = i
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		span := occurrences[best]
+		claimed[span] = true
+		e.Start, e.End = span[0], span[1]
+		e.Text = string(runes[span[0]:span[1]])
+		result = append(result, e)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Start < 0 || result[j].Start < 0 {
+			return result[j].Start < 0 && result[i].Start >= 0
+		}
+		return result[i].Start < result[j].Start
+	})
+	return result, nil
+}
+
+// findOccurrences returns the [start, end) rune spans of the occurrences of
+// entity in text. If entity does not occur verbatim, its case-insensitive
+// occurrences are returned instead.
+func findOccurrences(text, entity []rune) [][2]int {
+	if len(entity) == 0 {
+		return nil
+	}
+	for _, equal := range []func(a, b rune) bool{
+		func(a, b rune) bool { return a == b },
+		func(a, b rune) bool { return unicode.ToLower(a) == unicode.ToLower(b) },
+	} {
+		var spans [][2]int
+		for start := 0; start+len(entity) <= len(text); start++ {
+			match := true
+			for i, r := range entity {
+				if !equal(text[start+i], r) {
+					match = false
+					break
+				}
+			}
+			if match {
+				spans = append(spans, [2]int{start, start + len(entity)})
+			}
+		}
+		if len(spans) > 0 {
+			return spans
+		}
+	}
+	return nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,52 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractEntities(t *testing.T) {
+	text := "马云在杭州创立了阿里巴巴，马云是董事长。"
+	l := &scriptedLLM{responses: []string{`[
+		{"text": "马云", "type": "person", "start": 14, "end": 16},
+		{"text": "马云", "type": "person", "start": 1, "end": 3},
+		{"text": "杭州", "type": "LOCATION", "start": 3, "end": 5},
+		{"text": "阿里巴巴集团", "type": "org", "start": 8, "end": 14},
+		{"text": "董事长", "type": "title", "start": 16, "end": 19}
+	]`}}
+
+	entities, err := ExtractEntities(context.Background(), l, text)
+	require.NoError(t, err)
+	assert.Equal(t, []Entity{
+		{Text: "马云", Type: EntityPerson, Start: 0, End: 2},
+		{Text: "杭州", Type: EntityLocation, Start: 3, End: 5},
+		{Text: "马云", Type: EntityPerson, Start: 13, End: 15},
+		{Text: "阿里巴巴集团", Type: EntityOrg, Start: -1, End: -1},
+	}, entities, "offsets are corrected, unlocated entities get -1 and unrequested types are dropped")
+
+	runes := []rune(text)
+	for _, e := range entities[:3] {
+		assert.Equal(t, e.Text, string(runes[e.Start:e.End]))
+	}
+}
+
+func TestExtractEntities_CustomTypes(t *testing.T) {
+	l := &scriptedLLM{responses: []string{`[
+		{"text": "iphone", "type": "product", "start": 3, "end": 9},
+		{"text": "iPhone", "type": "product", "start": 0, "end": 6},
+		{"text": "Apple", "type": "org", "start": 0, "end": 5}
+	]`}}
+
+	entities, err := ExtractEntities(context.Background(), l, "新款 iPhone 很贵。", WithEntityTypes("product"))
+	require.NoError(t, err)
+	assert.Equal(t, []Entity{
+		{Text: "iPhone", Type: "product", Start: 3, End: 9},
+	}, entities, "a case-insensitive match takes the original spelling and a repeated span is dropped")
+	assert.Contains(t, l.prompts[0].Directives, "提取文本中的命名实体，type 必须是以下类型之一：product")
+
+	_, err = ExtractEntities(context.Background(), &scriptedLLM{}, " ")
+	assert.ErrorContains(t, err, "text cannot be empty")
+}
@@ -0,0 +1,123 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// Fact check verdicts.
+const (
+	VerdictSupported    = "supported"
+	VerdictRefuted      = "refuted"
+	VerdictUnverifiable = "unverifiable"
+)
+
+// FactCheckResult is the result of FactCheck.
+type FactCheckResult struct {
+	Claims             []Claim `json:"claims" validate:"dive"`
+	OverallReliability float64 `json:"overall_reliability" validate:"gte=0,lte=1"`
+}
+
+// Claim is a factual statement found in the checked text, with the verdict on
+// it.
+type Claim struct {
+	Statement  string  `json:"statement" validate:"required"`
+	Verdict    string  `json:"verdict" validate:"required,oneof=supported refuted unverifiable"`
+	Confidence float64 `json:"confidence" validate:"gte=0,lte=1"`
+	Reasoning  string  `json:"reasoning" validate:"required"`
+}
+
+// FactCheckOption configures FactCheck.
+type FactCheckOption func(*factCheckConfig)
+
+type factCheckConfig struct {
+	sources  []string
+	language string
+}
+
+// WithGrounding gives FactCheck reference material to check the claims
+// against. With sources, claims are judged on the sources only; without them,
+// on the LLM's own knowledge.
+func WithGrounding(sources []string) FactCheckOption {
+	return func(c *factCheckConfig) {
+		c.sources = append(c.sources, sources...)
+	}
+}
+
+// WithFactCheckLanguage sets the language of the statements and reasoning,
+// e.g. "中文" or "English". By default they use the language of the text.
+func WithFactCheckLanguage(lang string) FactCheckOption {
+	return func(c *factCheckConfig) {
+		c.language = lang
+	}
+}
+
+// FactCheck splits text into its factual claims and rates each one as
+// supported, refuted or unverifiable, with a confidence and the reasoning
+// behind the verdict. It uses ExtractStructuredData, so the response is
+// validated like any other extraction. The sources given with WithGrounding
+// are added to the prompt as retrieved context, cited by their position,
+// starting at 1.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for the check
+//   - text: The text whose claims to check
+//   - opts: Optional fact check options such as WithGrounding
+//
+// Returns:
+//   - *FactCheckResult: The claims in the order they appear in the text, and
+//     the reliability of the text as a whole, from 0 to 1
+//   - error: Any error encountered during the check
+//
+// Example:
+//
+//	result, err := FactCheck(ctx, llm, article, WithGrounding([]string{encyclopediaEntry}))
+//	for _, c := range result.Claims {
+//	    fmt.Printf("%s: %s (%.2f)\n", c.Statement, c.Verdict, c.Confidence)
+//	}
+func FactCheck(ctx context.Context, l gollm.LLM, text string, opts ...FactCheckOption) (*FactCheckResult, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	cfg := &factCheckConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	directives := []string{
+		"将文本拆分为独立的事实陈述，每条陈述放入 claims，statement 为陈述本身；观点和主观评价不算事实陈述",
+		"verdict 为 supported（有依据支持）、refuted（有依据反驳）或 unverifiable（无法确认）",
+		"confidence 为 0 到 1 之间对判断的把握，reasoning 简要说明判断的依据",
+		"overall_reliability 为 0 到 1 之间文本整体的可信度",
+	}
+	promptOpts := []gollm.PromptOption{}
+	if len(cfg.sources) > 0 {
+		chunks := make([]gollm.ContextChunk, len(cfg.sources))
+		for i, source := range cfg.sources {
+			chunks[i] = gollm.ContextChunk{Content: source, Source: fmt.Sprint(i + 1)}
+		}
+		promptOpts = append(promptOpts, gollm.WithContextInjection(chunks, 0))
+		directives = append(directives, "仅依据检索到的来源判断，来源未提及的陈述为 unverifiable；reasoning 中注明所依据来源的编号")
+	} else {
+		directives = append(directives, "依据公认的常识和可靠知识判断，不确定时为 unverifiable")
+	}
+	if cfg.language != "" {
+		directives = append(directives, "statement 和 reasoning 使用"+cfg.language)
+	}
+	promptOpts = append(promptOpts, gollm.WithDirectives(directives...))
+
+	result, err := ExtractStructuredData[FactCheckResult](ctx, l, text, WithPromptOptions(promptOpts...))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check facts: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,49 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFactCheck(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"是", `{
+		"claims": [
+			{"statement": "珠穆朗玛峰是世界最高峰", "verdict": "supported", "confidence": 0.95, "reasoning": "来源 1 指出其海拔为世界之最"},
+			{"statement": "珠穆朗玛峰位于日本", "verdict": "refuted", "confidence": 0.9, "reasoning": "来源 1 指出其位于中国与尼泊尔边境"},
+			{"statement": "2023 年有 600 人登顶", "verdict": "unverifiable", "confidence": 0.5, "reasoning": "来源未提及"}
+		],
+		"overall_reliability": 0.4
+	}`}}
+
+	result, err := FactCheck(context.Background(), l, "珠穆朗玛峰是世界最高峰，位于日本。2023 年有 600 人登顶。",
+		WithGrounding([]string{"珠穆朗玛峰位于中国与尼泊尔边境，海拔 8848.86 米，为世界最高峰。"}),
+		WithFactCheckLanguage("中文"),
+	)
+	require.NoError(t, err)
+	require.Len(t, result.Claims, 3)
+	assert.Equal(t, VerdictRefuted, result.Claims[1].Verdict)
+	assert.Equal(t, 0.4, result.OverallReliability)
+
+	prompt := l.prompts[1]
+	assert.Equal(t, "珠穆朗玛峰位于中国与尼泊尔边境，海拔 8848.86 米，为世界最高峰。\n[Source: 1]", prompt.RetrievedContext)
+	assert.Contains(t, prompt.Directives, "statement 和 reasoning 使用中文")
+}
+
+func TestFactCheck_Validation(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"是",
+		`{"claims": [{"statement": "水在 100°C 沸腾", "verdict": "true", "confidence": 1, "reasoning": "常识"}], "overall_reliability": 1}`,
+		`{"claims": [{"statement": "水在 100°C 沸腾", "verdict": "supported", "confidence": 1, "reasoning": "常识"}], "overall_reliability": 1}`,
+	}}
+
+	result, err := FactCheck(context.Background(), l, "水在标准大气压下 100°C 沸腾。")
+	require.NoError(t, err)
+	assert.Equal(t, VerdictSupported, result.Claims[0].Verdict, "an unknown verdict is re-asked")
+	assert.Empty(t, l.prompts[1].RetrievedContext)
+	assert.Contains(t, l.prompts[1].Directives, "依据公认的常识和可靠知识判断，不确定时为 unverifiable")
+
+	_, err = FactCheck(context.Background(), &scriptedLLM{}, " ")
+	assert.ErrorContains(t, err, "text cannot be empty")
+}
@@ -0,0 +1,263 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// Defaults of DetectIntent.
+const (
+	DefaultFallbackIntent      = "fallback"
+	defaultIntentMinConfidence = 0.5
+)
+
+// IntentSpec describes an intent DetectIntent can recognize.
+type IntentSpec struct {
+	Name        string // Name of the intent, e.g. "book_flight"
+	Description string // What users with the intent want, shown to the LLM
+	// Slots is a struct, or a pointer to one, whose fields are the slots of
+	// the intent, with json and validate tags as for ExtractStructuredData;
+	// nil for an intent without slots.
+	Slots interface{}
+}
+
+// DetectedIntent is the result of DetectIntent.
+type DetectedIntent struct {
+	Intent     string      // Name of the matched intent, or of the fallback intent
+	Confidence float64     // Confidence of the match, from 0 to 1
+	Slots      interface{} // Pointer to a filled, validated value of the intent's Slots type; nil without slots and for the fallback intent
+	Fallback   bool        // Whether Intent is the fallback intent
+	Candidate  string      // For a fallback below the confidence threshold, the intent matched with too little confidence
+}
+
+// IntentOption configures DetectIntent.
+type IntentOption func(*intentConfig)
+
+type intentConfig struct {
+	fallback      string
+	minConfidence float64
+	extraction    []ExtractionOption
+}
+
+// WithFallbackIntent sets the intent DetectIntent returns when no intent
+// matches, or the match has a confidence below minConfidence. The defaults
+// are DefaultFallbackIntent and 0.5.
+func WithFallbackIntent(name string, minConfidence float64) IntentOption {
+	return func(c *intentConfig) {
+		c.fallback = name
+		c.minConfidence = minConfidence
+	}
+}
+
+// WithIntentExtractionOptions applies extraction options, such as
+// WithExtractionRetries, to the detection.
+func WithIntentExtractionOptions(opts ...ExtractionOption) IntentOption {
+	return func(c *intentConfig) {
+		c.extraction = append(c.extraction, opts...)
+	}
+}
+
+// intentChoice is the intent the LLM chose in an intentResponse.
+type intentChoice struct {
+	Intent     string  `json:"intent" validate:"required"`
+	Confidence float64 `json:"confidence" validate:"gte=0,lte=1"`
+}
+
+// intentResponse is the structure the LLM is asked to fill in by
+// DetectIntent. Its schema is that of intentChoice with an object of slots,
+// see intentResponseSchema.
+type intentResponse struct {
+	intentChoice
+	Slots map[string]interface{} `json:"slots"`
+}
+
+// DetectIntent recognizes which of intents an utterance expresses, such as a
+// message to a chatbot, and fills in the slots of that intent. The slots are
+// extracted with the structured extraction machinery: the schema of each
+// intent's Slots type, from GenerateJSONSchema, is part of the prompt, and
+// the slots are parsed into a new value of that type and validated, with the
+// LLM asked to correct invalid slots as by ExtractStructuredData. Intent and
+// slots are detected in a single call. When no intent matches, or the match
+// has too little confidence, the fallback intent is returned without slots;
+// see WithFallbackIntent.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for detection
+//   - utterance: What the user said
+//   - intents: The intents to recognize
+//   - opts: Optional options such as WithFallbackIntent
+//
+// Returns:
+//   - *DetectedIntent: The intent, its confidence and its slots
+//   - error: Any error encountered, including invalid intents and slots that
+//     are still invalid after correction
+//
+// Example:
+//
+//	type FlightSlots struct {
+//	    From string `json:"from" validate:"required"`
+//	    To   string `json:"to" validate:"required"`
+//	    Date string `json:"date"`
+//	}
+//
+//	detected, err := DetectIntent(ctx, llm, "帮我订一张明天从上海去北京的机票", []IntentSpec{
+//	    {Name: "book_flight", Description: "预订机票", Slots: FlightSlots{}},
+//	    {Name: "check_order", Description: "查询订单状态"},
+//	}, WithFallbackIntent("chitchat", 0.6))
+//	if detected.Intent == "book_flight" {
+//	    slots := detected.Slots.(*FlightSlots)
+//	}
+func DetectIntent(ctx context.Context, l gollm.LLM, utterance string, intents []IntentSpec, opts ...IntentOption) (*DetectedIntent, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(utterance) == "" {
+		return nil, fmt.Errorf("utterance cannot be empty")
+	}
+	if len(intents) == 0 {
+		return nil, fmt.Errorf("at least one intent must be provided")
+	}
+	cfg := &intentConfig{fallback: DefaultFallbackIntent, minConfidence: defaultIntentMinConfidence}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	names := make([]string, len(intents))
+	slotTypes := make([]reflect.Type, len(intents))
+	slotSchemas := make([][]byte, len(intents))
+	var list strings.Builder
+	for i, intent := range intents {
+		if strings.TrimSpace(intent.Name) == "" {
+			return nil, fmt.Errorf("intent %d has no name", i+1)
+		}
+		if _, ok := matchCategory(names[:i], intent.Name); ok {
+			return nil, fmt.Errorf("duplicate intent %q", intent.Name)
+		}
+		if strings.EqualFold(intent.Name, cfg.fallback) {
+			return nil, fmt.Errorf("intent %q has the name of the fallback intent", intent.Name)
+		}
+		names[i] = intent.Name
+		list.WriteString("- " + intent.Name)
+		if intent.Description != "" {
+			list.WriteString("：" + intent.Description)
+		}
+		list.WriteString("\n")
+		if intent.Slots == nil {
+			continue
+		}
+		t := reflect.TypeOf(intent.Slots)
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("slots of intent %q must be a struct, got %T", intent.Name, intent.Slots)
+		}
+		schema, err := gollm.GenerateJSONSchema(reflect.New(t).Elem().Interface())
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate slot schema of intent %q: %w", intent.Name, err)
+		}
+		slotTypes[i], slotSchemas[i] = t, schema
+		fmt.Fprintf(&list, "  槽位模式：%s\n", schema)
+	}
+
+	schema, err := intentResponseSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JSON schema: %w", err)
+	}
+	config := newExtractionConfig(cfg.extraction)
+	prompt := gollm.NewPrompt(fmt.Sprintf("识别以下用户话语的意图，并填写该意图的槽位：\n\n%s\n\n可选的意图：\n%s", utterance, list.String()))
+	prompt.Apply(append(config.promptOpts,
+		gollm.WithDirectives(
+			"intent 为最符合用户意图的一个意图名称，只能从可选的意图中选择",
+			fmt.Sprintf("没有符合的意图时，intent 为 %s，confidence 为 0", cfg.fallback),
+			"confidence 为 0 到 1 之间的置信度，表示判断的把握",
+			"slots 按该意图的槽位模式填写话语中提到的槽位，话语没有提到的槽位留空，不要编造；意图没有槽位模式时 slots 为空对象",
+			"使用与此模式匹配的 JSON 对象进行响应："+string(schema),
+		),
+		gollm.WithOutput("与提供的模式匹配的 JSON 对象"),
+	)...)
+	var generateOpts []gollm.GenerateOption
+	if l.SupportsJSONSchema() {
+		generateOpts = append(generateOpts, gollm.WithStructuredOutput(schema))
+	}
+
+	var detected *DetectedIntent
+	err = generateCorrected(ctx, l, config, prompt, schema, "JSON 对象", generateOpts, func(response string) error {
+		parsed, err := parseExtraction[intentResponse](ctx, l, response)
+		if err != nil {
+			return err
+		}
+		if strings.EqualFold(strings.TrimSpace(parsed.Intent), cfg.fallback) {
+			detected = &DetectedIntent{Intent: cfg.fallback, Confidence: parsed.Confidence, Fallback: true}
+			return nil
+		}
+		name, ok := matchCategory(names, parsed.Intent)
+		if !ok {
+			return fmt.Errorf("intent %q is not one of the intents", parsed.Intent)
+		}
+		if parsed.Confidence < cfg.minConfidence {
+			detected = &DetectedIntent{Intent: cfg.fallback, Confidence: parsed.Confidence, Fallback: true, Candidate: name}
+			return nil
+		}
+		detected = &DetectedIntent{Intent: name, Confidence: parsed.Confidence}
+		for i := range names {
+			if names[i] == name && slotTypes[i] != nil {
+				detected.Slots, err = parseSlots(slotTypes[i], parsed.Slots)
+				if err != nil {
+					// The correction prompt only shows the top-level schema
+					return fmt.Errorf("slots of intent %q do not match its slot schema %s: %w", name, slotSchemas[i], err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect intent: %w", err)
+	}
+	return detected, nil
+}
+
+// intentResponseSchema returns the schema of an intentResponse. The slots are
+// an object of any shape, since each intent has its own; they are validated
+// against the intent's slot schema once parsed.
+func intentResponseSchema() ([]byte, error) {
+	data, err := gollm.GenerateJSONSchema(intentChoice{})
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	if properties == nil {
+		return nil, fmt.Errorf("schema of intent has no properties")
+	}
+	properties["slots"] = map[string]interface{}{"type": "object"}
+	return json.Marshal(schema)
+}
+
+// parseSlots decodes slots into a new value of type t and validates it,
+// returning a pointer to the value.
+func parseSlots(t reflect.Type, slots map[string]interface{}) (interface{}, error) {
+	data, err := json.Marshal(slots)
+	if err != nil {
+		return nil, err
+	}
+	value := reflect.New(t).Interface()
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, err
+	}
+	if err := gollm.Validate(value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
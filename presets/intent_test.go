@@ -0,0 +1,73 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type flightSlots struct {
+	From string `json:"from" validate:"required"`
+	To   string `json:"to" validate:"required"`
+	Date string `json:"date"`
+}
+
+var testIntents = []IntentSpec{
+	{Name: "book_flight", Description: "预订机票", Slots: flightSlots{}},
+	{Name: "check_order", Description: "查询订单状态"},
+}
+
+func TestDetectIntent(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		`{"intent": "book_flight", "confidence": 0.9, "slots": {"to": "北京", "date": "明天"}}`,
+		`{"intent": "BOOK_FLIGHT", "confidence": 0.9, "slots": {"from": "上海", "to": "北京", "date": "明天"}}`,
+	}}
+
+	detected, err := DetectIntent(context.Background(), l, "帮我订一张明天从上海去北京的机票", testIntents)
+	require.NoError(t, err)
+	assert.Equal(t, &DetectedIntent{
+		Intent:     "book_flight",
+		Confidence: 0.9,
+		Slots:      &flightSlots{From: "上海", To: "北京", Date: "明天"},
+	}, detected)
+	require.Len(t, l.prompts, 2, "invalid slots are corrected")
+	assert.Contains(t, l.prompts[0].Input, "- book_flight：预订机票\n  槽位模式：")
+	assert.Contains(t, l.prompts[0].Input, `"from"`)
+	assert.Contains(t, l.prompts[1].Input, `slots of intent "book_flight" do not match its slot schema {`)
+}
+
+func TestDetectIntent_Fallback(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		`{"intent": "check_order", "confidence": 0.55, "slots": {}}`,
+		`{"intent": "chitchat", "confidence": 0, "slots": {}}`,
+		`{"intent": "check_order", "confidence": 0.8, "slots": {}}`,
+	}}
+	ctx := context.Background()
+	opt := WithFallbackIntent("chitchat", 0.6)
+
+	detected, err := DetectIntent(ctx, l, "我的快递到哪了？", testIntents, opt)
+	require.NoError(t, err)
+	assert.Equal(t, &DetectedIntent{Intent: "chitchat", Confidence: 0.55, Fallback: true, Candidate: "check_order"}, detected)
+
+	detected, err = DetectIntent(ctx, l, "今天天气真好", testIntents, opt)
+	require.NoError(t, err)
+	assert.Equal(t, &DetectedIntent{Intent: "chitchat", Fallback: true}, detected)
+
+	detected, err = DetectIntent(ctx, l, "我的快递到哪了？", testIntents)
+	require.NoError(t, err)
+	assert.Equal(t, &DetectedIntent{Intent: "check_order", Confidence: 0.8}, detected, "intents without slots have nil slots")
+}
+
+func TestDetectIntent_InvalidIntents(t *testing.T) {
+	ctx := context.Background()
+	_, err := DetectIntent(ctx, &scriptedLLM{}, "你好", nil)
+	assert.ErrorContains(t, err, "at least one intent must be provided")
+	_, err = DetectIntent(ctx, &scriptedLLM{}, "你好", []IntentSpec{{Name: "greet"}, {Name: "Greet"}})
+	assert.ErrorContains(t, err, `duplicate intent "Greet"`)
+	_, err = DetectIntent(ctx, &scriptedLLM{}, "你好", []IntentSpec{{Name: "fallback"}})
+	assert.ErrorContains(t, err, `intent "fallback" has the name of the fallback intent`)
+	_, err = DetectIntent(ctx, &scriptedLLM{}, "你好", []IntentSpec{{Name: "greet", Slots: "name"}})
+	assert.ErrorContains(t, err, `slots of intent "greet" must be a struct, got string`)
+}
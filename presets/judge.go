@@ -0,0 +1,259 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// defaultScoreScale is the highest score Compare gives a response on a
+// criterion.
+const defaultScoreScale = 10
+
+// Winners of a Comparison.
+const (
+	WinnerA   = "A"
+	WinnerB   = "B"
+	WinnerTie = "tie"
+)
+
+// Comparison is the result of Compare.
+type Comparison struct {
+	Winner    string           // WinnerA, WinnerB or WinnerTie
+	Scores    []CriterionScore // The scores of both responses on each criterion, averaged over the judgments
+	Reasoning string           // Why the judge prefers the winner
+	// Consistent reports whether the judgments agree on the winner. When
+	// they do not, the judge's preference followed the order the responses
+	// were shown in and Winner is WinnerTie.
+	Consistent bool
+	Judgments  []Judgment // The judgments, with response A shown first and, unless WithoutPositionSwap is used, with response B shown first
+}
+
+// CriterionScore is the score of both responses on a criterion.
+type CriterionScore struct {
+	Criterion string
+	ScoreA    float64
+	ScoreB    float64
+}
+
+// Judgment is a single judgment of Compare, with the responses shown in one
+// order.
+type Judgment struct {
+	AFirst    bool             // Whether response A was shown first
+	Winner    string           // WinnerA, WinnerB or WinnerTie
+	Scores    []CriterionScore // The scores of both responses on each criterion
+	Reasoning string           // The judge's reasoning
+}
+
+// positionalJudgment is a judgment as the LLM gives it, in terms of the
+// order the responses were shown in.
+type positionalJudgment struct {
+	Reasoning string            `json:"reasoning" validate:"required"`
+	Scores    []positionalScore `json:"scores" validate:"required,min=1,dive"`
+	Winner    string            `json:"winner" validate:"required,oneof=first second tie"`
+}
+
+type positionalScore struct {
+	Criterion string  `json:"criterion" validate:"required"`
+	First     float64 `json:"first"`
+	Second    float64 `json:"second"`
+}
+
+// CompareOption configures Compare.
+type CompareOption func(*compareConfig)
+
+type compareConfig struct {
+	scale  int
+	noSwap bool
+}
+
+// WithScoreScale sets the highest score on each criterion, the lowest being 1.
+// The default is 10.
+func WithScoreScale(max int) CompareOption {
+	return func(c *compareConfig) {
+		c.scale = max
+	}
+}
+
+// WithoutPositionSwap judges the responses once, with response A shown first,
+// which halves the cost of Compare but leaves its result open to position
+// bias.
+func WithoutPositionSwap() CompareOption {
+	return func(c *compareConfig) {
+		c.noSwap = true
+	}
+}
+
+// Compare has the LLM judge which of two responses to a task is better, scoring
+// each on every criterion. LLM judges tend to prefer the response they are
+// shown first (or second), so Compare judges the responses twice, once in each
+// order, and reconciles the judgments: the winner is the response both
+// judgments prefer, or a tie when they disagree, and the scores are averaged.
+// Both judgments are kept in the result, so disagreements are visible. Each
+// judgment uses ExtractStructuredData, so a response without a score for
+// every criterion, or with scores outside the scale, is rejected and asked
+// for again.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use as the judge
+//   - task: The task or question the responses answer
+//   - responseA: The first response to compare
+//   - responseB: The second response to compare
+//   - criteria: The criteria to judge on, such as "准确性" or "简洁性"; when
+//     empty, the responses are judged on their overall quality
+//   - opts: Optional options such as WithScoreScale
+//
+// Returns:
+//   - *Comparison: The winner, the scores on each criterion, the reasoning and
+//     the judgments they were reconciled from
+//   - error: Any error encountered during judging
+//
+// Example:
+//
+//	comparison, err := Compare(ctx, llm, "解释什么是闭包", answerA, answerB,
+//	    []string{"准确性", "清晰度", "举例"},
+//	    WithScoreScale(5),
+//	)
+//	fmt.Println(comparison.Winner, comparison.Reasoning)
+//	for _, score := range comparison.Scores {
+//	    fmt.Printf("%s：A %.1f，B %.1f\n", score.Criterion, score.ScoreA, score.ScoreB)
+//	}
+func Compare(ctx context.Context, l gollm.LLM, task string, responseA, responseB string, criteria []string, opts ...CompareOption) (*Comparison, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(task) == "" {
+		return nil, fmt.Errorf("task cannot be empty")
+	}
+	if strings.TrimSpace(responseA) == "" || strings.TrimSpace(responseB) == "" {
+		return nil, fmt.Errorf("responses cannot be empty")
+	}
+	cfg := &compareConfig{scale: defaultScoreScale}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.scale < 2 {
+		return nil, fmt.Errorf("score scale must be at least 2, got %d", cfg.scale)
+	}
+	if len(criteria) == 0 {
+		criteria = []string{"总体质量"}
+	}
+
+	judgment, err := judge(ctx, l, task, responseA, responseB, criteria, cfg.scale, true)
+	if err != nil {
+		return nil, err
+	}
+	comparison := &Comparison{Judgments: []Judgment{*judgment}}
+	if !cfg.noSwap {
+		swapped, err := judge(ctx, l, task, responseB, responseA, criteria, cfg.scale, false)
+		if err != nil {
+			return nil, err
+		}
+		comparison.Judgments = append(comparison.Judgments, *swapped)
+	}
+	reconcile(comparison)
+	return comparison, nil
+}
+
+// judge asks the LLM for a judgment of the responses in the order given, and
+// maps it back to responses A and B.
+func judge(ctx context.Context, l gollm.LLM, task, first, second string, criteria []string, scale int, aFirst bool) (*Judgment, error) {
+	order := "A first"
+	if !aFirst {
+		order = "B first"
+	}
+	input := "任务：" + task + "\n\n回答一：\n" + first + "\n\n回答二：\n" + second
+	result, err := ExtractStructuredData[positionalJudgment](ctx, l, input, WithPromptOptions(gollm.WithDirectives(
+		"你是公正的评审，比较两个回答对任务的完成质量，不要因为回答的先后顺序或长短而偏向任何一方",
+		"评判标准："+strings.Join(criteria, "、"),
+		fmt.Sprintf("在 scores 中按上述顺序为每个标准给出一项，criterion 为标准名称，first 和 second 分别为回答一和回答二的得分，从 1 到 %d 的整数", scale),
+		"reasoning 为比较两个回答的理由；winner 为更好的回答：first 表示回答一，second 表示回答二，两者相当时为 tie",
+	)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to judge responses (%s): %w", order, err)
+	}
+
+	judgment := &Judgment{AFirst: aFirst, Reasoning: result.Reasoning}
+	for i, criterion := range criteria {
+		score, ok := findScore(result.Scores, criterion, i, len(criteria))
+		if !ok {
+			return nil, fmt.Errorf("judgment (%s) has no score for criterion %q", order, criterion)
+		}
+		for _, s := range []float64{score.First, score.Second} {
+			if s < 1 || s > float64(scale) {
+				return nil, fmt.Errorf("judgment (%s) scores criterion %q %g, outside 1 to %d", order, criterion, s, scale)
+			}
+		}
+		a, b := score.First, score.Second
+		if !aFirst {
+			a, b = b, a
+		}
+		judgment.Scores = append(judgment.Scores, CriterionScore{Criterion: criterion, ScoreA: a, ScoreB: b})
+	}
+	switch {
+	case result.Winner == "tie":
+		judgment.Winner = WinnerTie
+	case (result.Winner == "first") == aFirst:
+		judgment.Winner = WinnerA
+	default:
+		judgment.Winner = WinnerB
+	}
+	return judgment, nil
+}
+
+// findScore finds the score for criterion, which is the i-th of count. The
+// judge may name criteria differently, so when no score is named after it,
+// the i-th score is used as long as there is one score per criterion.
+func findScore(scores []positionalScore, criterion string, i, count int) (positionalScore, bool) {
+	for _, score := range scores {
+		if strings.TrimSpace(score.Criterion) == criterion {
+			return score, true
+		}
+	}
+	if len(scores) == count {
+		return scores[i], true
+	}
+	return positionalScore{}, false
+}
+
+// reconcile sets the winner, scores and reasoning of comparison from its
+// judgments.
+func reconcile(comparison *Comparison) {
+	judgments := comparison.Judgments
+	comparison.Winner = judgments[0].Winner
+	comparison.Consistent = true
+	for _, judgment := range judgments[1:] {
+		if judgment.Winner != comparison.Winner {
+			comparison.Winner = WinnerTie
+			comparison.Consistent = false
+		}
+	}
+
+	for i, score := range judgments[0].Scores {
+		average := CriterionScore{Criterion: score.Criterion}
+		for _, judgment := range judgments {
+			average.ScoreA += judgment.Scores[i].ScoreA / float64(len(judgments))
+			average.ScoreB += judgment.Scores[i].ScoreB / float64(len(judgments))
+		}
+		comparison.Scores = append(comparison.Scores, average)
+	}
+
+	if len(judgments) == 1 {
+		comparison.Reasoning = judgments[0].Reasoning
+		return
+	}
+	reasons := make([]string, len(judgments))
+	for i, judgment := range judgments {
+		order := "A 在前"
+		if !judgment.AFirst {
+			order = "B 在前"
+		}
+		reasons[i] = fmt.Sprintf("（%s，胜者 %s）%s", order, judgment.Winner, judgment.Reasoning)
+	}
+	comparison.Reasoning = strings.Join(reasons, "\n")
+}
@@ -0,0 +1,83 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompare(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"是", `{"reasoning": "回答二更准确", "scores": [{"criterion": "准确性", "first": 6, "second": 9}, {"criterion": "简洁性", "first": 7, "second": 8}], "winner": "second"}`,
+		"是", `{"reasoning": "回答一更准确", "scores": [{"criterion": "准确", "first": 8, "second": 5}, {"criterion": "简洁", "first": 8, "second": 8}], "winner": "first"}`,
+	}}
+
+	comparison, err := Compare(context.Background(), l, "解释什么是闭包", "闭包是函数。", "闭包是捕获了外部变量的函数。",
+		[]string{"准确性", "简洁性"})
+	require.NoError(t, err)
+	assert.Equal(t, WinnerB, comparison.Winner)
+	assert.True(t, comparison.Consistent)
+	assert.Equal(t, []CriterionScore{
+		{Criterion: "准确性", ScoreA: 5.5, ScoreB: 8.5},
+		{Criterion: "简洁性", ScoreA: 7.5, ScoreB: 8},
+	}, comparison.Scores, "scores are averaged and criteria are matched by position when named differently")
+	assert.Equal(t, "（A 在前，胜者 B）回答二更准确\n（B 在前，胜者 B）回答一更准确", comparison.Reasoning)
+	require.Len(t, comparison.Judgments, 2)
+	assert.True(t, comparison.Judgments[0].AFirst)
+	assert.False(t, comparison.Judgments[1].AFirst)
+	assert.Equal(t, CriterionScore{Criterion: "准确性", ScoreA: 5, ScoreB: 8}, comparison.Judgments[1].Scores[0])
+
+	assert.Contains(t, l.prompts[1].Input, "回答一：\n闭包是函数。\n\n回答二：\n闭包是捕获了外部变量的函数。")
+	assert.Contains(t, l.prompts[3].Input, "回答一：\n闭包是捕获了外部变量的函数。\n\n回答二：\n闭包是函数。")
+	assert.Contains(t, l.prompts[1].Directives, "评判标准：准确性、简洁性")
+}
+
+func TestCompare_PositionBias(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"是", `{"reasoning": "回答一更好", "scores": [{"criterion": "总体质量", "first": 4, "second": 3}], "winner": "first"}`,
+		"是", `{"reasoning": "回答一更好", "scores": [{"criterion": "总体质量", "first": 4, "second": 3}], "winner": "first"}`,
+	}}
+
+	comparison, err := Compare(context.Background(), l, "任务", "甲", "乙", nil, WithScoreScale(5))
+	require.NoError(t, err)
+	assert.Equal(t, WinnerTie, comparison.Winner)
+	assert.False(t, comparison.Consistent)
+	assert.Equal(t, WinnerA, comparison.Judgments[0].Winner)
+	assert.Equal(t, WinnerB, comparison.Judgments[1].Winner)
+	assert.Equal(t, []CriterionScore{{Criterion: "总体质量", ScoreA: 3.5, ScoreB: 3.5}}, comparison.Scores)
+}
+
+func TestCompare_WithoutPositionSwap(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"是", `{"reasoning": "相当", "scores": [{"criterion": "总体质量", "first": 7, "second": 7}], "winner": "tie"}`,
+	}}
+
+	comparison, err := Compare(context.Background(), l, "任务", "甲", "乙", nil, WithoutPositionSwap())
+	require.NoError(t, err)
+	assert.Equal(t, WinnerTie, comparison.Winner)
+	assert.True(t, comparison.Consistent)
+	assert.Equal(t, "相当", comparison.Reasoning)
+	assert.Len(t, comparison.Judgments, 1)
+	assert.Len(t, l.prompts, 2)
+}
+
+func TestCompare_InvalidJudgment(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"是", `{"reasoning": "好", "scores": [{"criterion": "准确性", "first": 12, "second": 3}], "winner": "first"}`,
+	}}
+	_, err := Compare(context.Background(), l, "任务", "甲", "乙", []string{"准确性"})
+	assert.ErrorContains(t, err, `judgment (A first) scores criterion "准确性" 12, outside 1 to 10`)
+
+	l = &scriptedLLM{responses: []string{
+		"是", `{"reasoning": "好", "scores": [{"criterion": "其他", "first": 5, "second": 3}], "winner": "first"}`,
+	}}
+	_, err = Compare(context.Background(), l, "任务", "甲", "乙", []string{"准确性", "简洁性"})
+	assert.ErrorContains(t, err, `has no score for criterion "准确性"`)
+
+	_, err = Compare(context.Background(), &scriptedLLM{}, "任务", "甲", " ", nil)
+	assert.ErrorContains(t, err, "responses cannot be empty")
+	_, err = Compare(context.Background(), &scriptedLLM{}, "任务", "甲", "乙", nil, WithScoreScale(1))
+	assert.ErrorContains(t, err, "score scale must be at least 2")
+}
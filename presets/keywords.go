@@ -0,0 +1,305 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// Keyword is a single term extracted by ExtractKeywords.
+type Keyword struct {
+	Term      string  `json:"term"`
+	Relevance float64 `json:"relevance"`
+	Category  string  `json:"category"`
+	// Verbatim reports whether Term appears in the text, ignoring case and
+	// spacing. It is checked by ExtractKeywords, not reported by the LLM.
+	Verbatim bool `json:"verbatim"`
+}
+
+// extractedKeyword is a keyword as the LLM is asked to fill it in.
+type extractedKeyword struct {
+	Term      string  `json:"term" validate:"required"`
+	Relevance float64 `json:"relevance" validate:"gte=0,lte=1"`
+	Category  string  `json:"category"`
+}
+
+// keywordList is the structure the LLM is asked to fill in.
+type keywordList struct {
+	Keywords []extractedKeyword `json:"keywords" validate:"required,dive"`
+}
+
+// KeywordOption configures ExtractKeywords.
+type KeywordOption func(*keywordConfig)
+
+type keywordConfig struct {
+	maxKeywords int
+	taxonomy    []string
+	language    string
+}
+
+// WithMaxKeywords limits the number of keywords returned. The default is 10.
+func WithMaxKeywords(n int) KeywordOption {
+	return func(c *keywordConfig) {
+		c.maxKeywords = n
+	}
+}
+
+// WithTaxonomy restricts keyword categories to the given set. Keywords whose
+// category is not in the taxonomy cause ExtractKeywords to fail.
+func WithTaxonomy(categories ...string) KeywordOption {
+	return func(c *keywordConfig) {
+		c.taxonomy = append(c.taxonomy, categories...)
+	}
+}
+
+// WithLanguage sets the language the keywords are written in, e.g. "中文" or
+// "English". By default keywords use the language of the text.
+func WithLanguage(lang string) KeywordOption {
+	return func(c *keywordConfig) {
+		c.language = lang
+	}
+}
+
+// ExtractKeywords extracts the most relevant keywords from text, ranked by
+// relevance. It uses ExtractStructuredData, so the response is checked against
+// the Keyword schema and validation rules before being returned. Keywords
+// that differ only in case, width or spacing, such as "Machine Learning" and
+// "machine-learning", are merged, keeping the most relevant. Whether each
+// keyword appears in the text is checked without relying on spaces between
+// words, so it works for Chinese as well.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for extraction
+//   - text: The text to extract keywords from
+//   - opts: Optional keyword options
+//
+// Returns:
+//   - []Keyword: Distinct keywords sorted by descending relevance (0 to 1)
+//   - error: Any error encountered during extraction or validation
+//
+// Example:
+//
+//	keywords, err := ExtractKeywords(ctx, llm, article,
+//	    WithMaxKeywords(5),
+//	    WithTaxonomy("技术", "公司", "人物"),
+//	)
+//	for _, k := range keywords {
+//	    fmt.Printf("%s (%s): %.2f verbatim=%t\n", k.Term, k.Category, k.Relevance, k.Verbatim)
+//	}
+func ExtractKeywords(ctx context.Context, l gollm.LLM, text string, opts ...KeywordOption) ([]Keyword, error) {
+	cfg := &keywordConfig{maxKeywords: 10}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.maxKeywords <= 0 {
+		return nil, fmt.Errorf("max keywords must be positive, got %d", cfg.maxKeywords)
+	}
+
+	directives := []string{
+		fmt.Sprintf("提取最多 %d 个最能代表文本内容的关键词或短语", cfg.maxKeywords),
+		"relevance 为 0 到 1 之间的相关度分数，越重要分数越高",
+		"不要重复提取意义相同的关键词",
+		"尽量使用文本中的原词；中文等不以空格分词的文本，关键词应是完整的词语，不要截断或拼接词语",
+	}
+	if len(cfg.taxonomy) > 0 {
+		directives = append(directives, fmt.Sprintf("category 必须是以下类别之一：%s；不属于任何类别的关键词不要提取", strings.Join(cfg.taxonomy, "、")))
+	} else {
+		directives = append(directives, "category 为关键词所属的类别，例如人物、地点、组织、技术或概念")
+	}
+	if cfg.language != "" {
+		directives = append(directives, fmt.Sprintf("关键词使用%s输出", cfg.language))
+	}
+
+	result, err := ExtractStructuredData[keywordList](ctx, l, text, WithPromptOptions(gollm.WithDirectives(directives...)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract keywords: %w", err)
+	}
+
+	keywords := make([]Keyword, len(result.Keywords))
+	for i, k := range result.Keywords {
+		keywords[i] = Keyword{Term: strings.TrimSpace(k.Term), Relevance: k.Relevance, Category: k.Category}
+		if len(cfg.taxonomy) > 0 {
+			category, ok := matchCategory(cfg.taxonomy, k.Category)
+			if !ok {
+				return nil, fmt.Errorf("keyword %q has category %q outside the taxonomy", k.Term, k.Category)
+			}
+			keywords[i].Category = category
+		}
+		keywords[i].Verbatim = appearsIn(text, k.Term)
+	}
+
+	sort.SliceStable(keywords, func(i, j int) bool {
+		return keywords[i].Relevance > keywords[j].Relevance
+	})
+	keywords = dedupeBy(keywords, func(k Keyword) string { return k.Term })
+	if len(keywords) > cfg.maxKeywords {
+		keywords = keywords[:cfg.maxKeywords]
+	}
+	return keywords, nil
+}
+
+// Topic is a theme of a text found by ExtractTopics.
+type Topic struct {
+	Label       string `json:"label" validate:"required"`       // A short name of the topic
+	Description string `json:"description" validate:"required"` // One sentence on what the text says about it
+}
+
+// TopicOption configures ExtractTopics.
+type TopicOption func(*topicConfig)
+
+type topicConfig struct {
+	maxTopics int
+}
+
+// WithMaxTopics limits the number of topics returned. The default is 5.
+func WithMaxTopics(n int) TopicOption {
+	return func(c *topicConfig) {
+		c.maxTopics = n
+	}
+}
+
+// ExtractTopics finds the main topics of text. Unlike keywords, topics need
+// not appear in the text: each is a label, such as "远程办公", with a short
+// description of what the text says about it. Topics with labels that differ
+// only in case, width or spacing are merged.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for extraction
+//   - text: The text to find the topics of
+//   - opts: Optional options such as WithMaxTopics
+//
+// Returns:
+//   - []Topic: Distinct topics, most important first
+//   - error: Any error encountered during extraction or validation
+//
+// Example:
+//
+//	topics, err := ExtractTopics(ctx, llm, transcript, WithMaxTopics(3))
+//	for _, t := range topics {
+//	    fmt.Printf("%s：%s\n", t.Label, t.Description)
+//	}
+func ExtractTopics(ctx context.Context, l gollm.LLM, text string, opts ...TopicOption) ([]Topic, error) {
+	cfg := &topicConfig{maxTopics: 5}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.maxTopics <= 0 {
+		return nil, fmt.Errorf("max topics must be positive, got %d", cfg.maxTopics)
+	}
+
+	topics, err := ExtractStructuredList[Topic](ctx, l, text, WithPromptOptions(gollm.WithDirectives(
+		fmt.Sprintf("找出文本讨论的最多 %d 个主题，每个条目是一个主题，按重要性从高到低排列", cfg.maxTopics),
+		"label 是简短的主题名称，使用文本的语言，不必是文本中的原词",
+		"description 用一句话说明文本关于这个主题讲了什么",
+		"不同主题不要重叠",
+	)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract topics: %w", err)
+	}
+	topics = dedupeBy(topics, func(t Topic) string { return t.Label })
+	if len(topics) > cfg.maxTopics {
+		topics = topics[:cfg.maxTopics]
+	}
+	return topics, nil
+}
+
+// termSeparators are ignored when comparing terms, so "machine-learning" and
+// "Machine Learning" are the same keyword.
+var termSeparators = strings.NewReplacer(" ", "", "-", "", "_", "", "·", "")
+
+// dedupeBy removes the items whose term, as returned by term, is the same as
+// that of an earlier item, ignoring case, width and spacing.
+func dedupeBy[T any](items []T, term func(T) string) []T {
+	seen := make(map[string]bool, len(items))
+	kept := items[:0]
+	for _, item := range items {
+		key := termSeparators.Replace(foldText(term(item)))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// appearsIn reports whether term occurs in text, ignoring case, width and
+// the amount of whitespace. Where term starts or ends with a letter or digit
+// of a script that separates words with spaces, the occurrence must start or
+// end at a word boundary, so "art" is not found in "party"; Chinese, Japanese
+// and Korean terms match anywhere.
+func appearsIn(text, term string) bool {
+	text, term = foldText(text), foldText(term)
+	if term == "" {
+		return false
+	}
+	for from := 0; ; {
+		i := strings.Index(text[from:], term)
+		if i < 0 {
+			return false
+		}
+		start, end := from+i, from+i+len(term)
+		first, _ := utf8.DecodeRuneInString(term)
+		last, _ := utf8.DecodeLastRuneInString(term)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !(isSpacedWordRune(first) && start > 0 && isSpacedWordRune(before)) &&
+			!(isSpacedWordRune(last) && end < len(text) && isSpacedWordRune(after)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		from = start + size
+	}
+}
+
+// isSpacedWordRune reports whether r is a letter or digit of a script whose
+// words are separated by spaces.
+func isSpacedWordRune(r rune) bool {
+	if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+		return false
+	}
+	return !unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul, unicode.Thai)
+}
+
+// foldText lower-cases s, converts full-width ASCII characters to their
+// usual width and collapses runs of whitespace to a single space.
+func foldText(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.TrimSpace(s) {
+		if r >= '！' && r <= '～' {
+			r -= '！' - '!'
+		}
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// matchCategory returns the taxonomy entry matching category, ignoring case
+// and surrounding whitespace.
+func matchCategory(taxonomy []string, category string) (string, bool) {
+	category = strings.TrimSpace(category)
+	for _, c := range taxonomy {
+		if strings.EqualFold(c, category) {
+			return c, true
+		}
+	}
+	return "", false
+}
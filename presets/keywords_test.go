@@ -0,0 +1,163 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// scriptedLLM returns its responses in order, one per Generate call, and
+// records the prompts and generate options it received.
+type scriptedLLM struct {
+	gollm.LLM
+	responses  []string
+	prompts    []*llm.Prompt
+	configs    []*llm.GenerateConfig
+	jsonSchema bool
+	model      string
+}
+
+func (s *scriptedLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	config := &llm.GenerateConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	s.prompts = append(s.prompts, prompt)
+	s.configs = append(s.configs, config)
+	response := s.responses[0]
+	s.responses = s.responses[1:]
+	return response, nil
+}
+
+func (s *scriptedLLM) FallbackPolicy(ctx context.Context) *llm.FallbackPolicy {
+	return llm.NewFallbackPolicy(llm.IsStrictMode(ctx), nil)
+}
+
+func (s *scriptedLLM) SupportsJSONSchema() bool {
+	return s.jsonSchema
+}
+
+func (s *scriptedLLM) GetModel() string {
+	return s.model
+}
+
+func (s *scriptedLLM) GetLogger() utils.Logger {
+	return utils.NewLogger(utils.LogLevelOff)
+}
+
+func TestExtractKeywords(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"yes",
+		`{"keywords": [
+			{"term": "Go", "relevance": 0.6, "category": "技术"},
+			{"term": "Google", "relevance": 0.9, "category": "公司"},
+			{"term": "并发", "relevance": 0.3, "category": "技术"}
+		]}`,
+	}}
+
+	keywords, err := ExtractKeywords(context.Background(), l, "Go 是 Google 开发的编程语言，擅长并发。",
+		WithMaxKeywords(2),
+		WithTaxonomy("技术", "公司"),
+		WithLanguage("中文"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []Keyword{
+		{Term: "Google", Relevance: 0.9, Category: "公司", Verbatim: true},
+		{Term: "Go", Relevance: 0.6, Category: "技术", Verbatim: true},
+	}, keywords)
+
+	directives := l.prompts[1].Directives
+	assert.Contains(t, directives, "提取最多 2 个最能代表文本内容的关键词或短语")
+	assert.Contains(t, directives, "关键词使用中文输出")
+}
+
+func TestExtractKeywords_RejectsCategoryOutsideTaxonomy(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"yes",
+		`{"keywords": [{"term": "Go", "relevance": 0.6, "category": "动物"}]}`,
+	}}
+
+	_, err := ExtractKeywords(context.Background(), l, "Go 是一种编程语言。", WithTaxonomy("技术"))
+	assert.ErrorContains(t, err, "outside the taxonomy")
+}
+
+func TestExtractKeywords_RejectsInvalidRelevance(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"yes",
+		`{"keywords": [{"term": "Go", "relevance": 7}]}`,
+		`{"keywords": [{"term": "Go", "relevance": 7}]}`, // The retry repeats the mistake
+	}}
+
+	_, err := ExtractKeywords(context.Background(), l, "Go 是一种编程语言。")
+	assert.ErrorContains(t, err, "validation failed")
+}
+
+func TestExtractKeywords_DedupesAndChecksVerbatim(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"yes",
+		`{"keywords": [
+			{"term": "machine-learning", "relevance": 0.5},
+			{"term": "Machine Learning", "relevance": 0.8},
+			{"term": "深度学习", "relevance": 0.7},
+			{"term": "人工智能", "relevance": 0.6},
+			{"term": "ＧＰＵ", "relevance": 0.4},
+			{"term": "art", "relevance": 0.2}
+		]}`,
+	}}
+
+	keywords, err := ExtractKeywords(context.Background(), l, "本文介绍machine  learning和深度学习模型在GPU上的训练，以及派对（party）策划。")
+	require.NoError(t, err)
+	assert.Equal(t, []Keyword{
+		{Term: "Machine Learning", Relevance: 0.8, Verbatim: true},
+		{Term: "深度学习", Relevance: 0.7, Verbatim: true},
+		{Term: "人工智能", Relevance: 0.6},
+		{Term: "ＧＰＵ", Relevance: 0.4, Verbatim: true},
+		{Term: "art", Relevance: 0.2},
+	}, keywords)
+}
+
+func TestAppearsIn(t *testing.T) {
+	tests := []struct {
+		text, term string
+		want       bool
+	}{
+		{"Go 是 Google 开发的语言", "google", true},
+		{"我们使用Go语言开发", "Go", true},
+		{"我们使用Go语言开发", "Go语言", true},
+		{"a party", "art", false},
+		{"art and party", "art", true},
+		{"smart art", "art", true},
+		{"C++ 是一种语言", "c++", true},
+		{"自然语言处理", "语言处理", true},
+		{"自然语言处理", "机器学习", false},
+		{"任何文本", " ", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, appearsIn(tt.text, tt.term), "%q in %q", tt.term, tt.text)
+	}
+}
+
+func TestExtractTopics(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		`[{"label": "远程办公", "description": "讨论远程办公对效率的影响"},
+		  {"label": "远程 办公", "description": "重复的主题"},
+		  {"label": "团队沟通", "description": "讨论分布式团队如何沟通"},
+		  {"label": "招聘", "description": "讨论跨地区招聘"}]`,
+	}}
+
+	topics, err := ExtractTopics(context.Background(), l, "会议记录……", WithMaxTopics(2))
+	require.NoError(t, err)
+	assert.Equal(t, []Topic{
+		{Label: "远程办公", Description: "讨论远程办公对效率的影响"},
+		{Label: "团队沟通", Description: "讨论分布式团队如何沟通"},
+	}, topics)
+	assert.Contains(t, l.prompts[0].Directives, "找出文本讨论的最多 2 个主题，每个条目是一个主题，按重要性从高到低排列")
+
+	_, err = ExtractTopics(context.Background(), l, "文本", WithMaxTopics(0))
+	assert.ErrorContains(t, err, "max topics must be positive")
+}
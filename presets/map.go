@@ -0,0 +1,183 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/progress"
+	"golang.org/x/time/rate"
+)
+
+// MapOption configures MapText.
+type MapOption func(*mapConfig)
+
+type mapConfig struct {
+	concurrency  int
+	limiter      *rate.Limiter
+	reporter     progress.Reporter
+	promptOpts   []gollm.PromptOption
+	generateOpts []gollm.GenerateOption
+}
+
+// WithMapConcurrency limits how many inputs MapText processes at the same
+// time. The default is 5; values below 1 are treated as 1.
+func WithMapConcurrency(n int) MapOption {
+	return func(c *mapConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithMapRateLimit makes MapText start at most r calls per second, with
+// bursts of up to burst calls, to stay within the provider's rate limits.
+func WithMapRateLimit(r rate.Limit, burst int) MapOption {
+	return func(c *mapConfig) {
+		c.limiter = rate.NewLimiter(r, burst)
+	}
+}
+
+// WithMapProgress makes MapText report the inputs done, the tokens used and
+// an ETA to r after every input.
+func WithMapProgress(r progress.Reporter) MapOption {
+	return func(c *mapConfig) {
+		c.reporter = r
+	}
+}
+
+// WithMapPromptOptions applies prompt options, such as gollm.WithExamples, to
+// the prompt of every input.
+func WithMapPromptOptions(opts ...gollm.PromptOption) MapOption {
+	return func(c *mapConfig) {
+		c.promptOpts = append(c.promptOpts, opts...)
+	}
+}
+
+// WithMapGenerateOptions applies generate options, such as
+// gollm.WithTemperature, to the call of every input.
+func WithMapGenerateOptions(opts ...gollm.GenerateOption) MapOption {
+	return func(c *mapConfig) {
+		c.generateOpts = append(c.generateOpts, opts...)
+	}
+}
+
+// MapError is returned by MapText when some inputs failed. The results of
+// the other inputs are still returned.
+type MapError struct {
+	// Errors has one entry per input, nil for the inputs that succeeded.
+	// Inputs never started because ctx was canceled have ctx.Err().
+	Errors []error
+	// Failed is the number of inputs that failed.
+	Failed int
+}
+
+// Error implements the error interface.
+func (e *MapError) Error() string {
+	for i, err := range e.Errors {
+		if err != nil {
+			return fmt.Sprintf("%d of %d inputs failed; input %d: %v", e.Failed, len(e.Errors), i, err)
+		}
+	}
+	return fmt.Sprintf("%d of %d inputs failed", e.Failed, len(e.Errors))
+}
+
+// Unwrap returns the errors of the failed inputs, so errors.Is and errors.As
+// match any of them.
+func (e *MapError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// MapText applies the same instruction, such as "翻译成英文" or "用一句话概括",
+// to every input, concurrently (see WithMapConcurrency) and optionally rate
+// limited (see WithMapRateLimit). A failed input does not stop the others:
+// MapText returns the results of those that succeeded together with a
+// *MapError giving the error of each input that failed. When ctx is
+// canceled, no new inputs are started.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use
+//   - inputs: The texts to process
+//   - instruction: What to do with each input
+//   - opts: Optional options such as WithMapConcurrency
+//
+// Returns:
+//   - []string: One result per input, in the order of inputs; "" for the
+//     inputs that failed
+//   - error: A *MapError if any input failed
+//
+// Example:
+//
+//	titles, err := MapText(ctx, llm, articles, "为这篇文章写一个不超过 20 字的标题",
+//	    WithMapConcurrency(10),
+//	    WithMapRateLimit(5, 5),
+//	)
+//	var mapErr *MapError
+//	if errors.As(err, &mapErr) {
+//	    for i, err := range mapErr.Errors {
+//	        if err != nil {
+//	            log.Printf("article %d: %v", i, err)
+//	        }
+//	    }
+//	}
+func MapText(ctx context.Context, l gollm.LLM, inputs []string, instruction string, opts ...MapOption) ([]string, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(instruction) == "" {
+		return nil, fmt.Errorf("instruction cannot be empty")
+	}
+	cfg := &mapConfig{concurrency: 5}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	results := make([]string, len(inputs))
+	errs := make([]error, len(inputs))
+	tracker := progress.NewTracker(cfg.reporter, "map", len(inputs))
+	defer tracker.Finish()
+	started := runBatch(ctx, len(inputs), cfg.concurrency, func(i int) {
+		if cfg.limiter != nil {
+			if err := cfg.limiter.Wait(ctx); err != nil {
+				errs[i] = err
+				tracker.ItemDone(0, 0)
+				return
+			}
+		}
+		prompt := gollm.NewPrompt(inputs[i], append([]gollm.PromptOption{
+			gollm.WithDirectives(instruction),
+			gollm.WithOutput("只输出结果，不要添加解释"),
+		}, cfg.promptOpts...)...)
+		itemCtx, recorder := gollm.WithUsageRecorder(ctx)
+		response, err := l.Generate(itemCtx, prompt, cfg.generateOpts...)
+		usage := recorder.Usage()
+		tracker.ItemDone(usage.InputTokens, usage.OutputTokens)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		results[i] = strings.TrimSpace(response)
+	})
+	for i := started; i < len(inputs); i++ {
+		errs[i] = ctx.Err()
+	}
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, &MapError{Errors: errs, Failed: failed}
+	}
+	return results, nil
+}
@@ -0,0 +1,98 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/progress"
+)
+
+// upperLLM upper-cases the input of every prompt, failing inputs that
+// contain "fail", and records the most calls it ran at once. It is safe for
+// concurrent use.
+type upperLLM struct {
+	gollm.LLM
+	mu      sync.Mutex
+	running int
+	peak    int
+	prompts []*llm.Prompt
+}
+
+func (u *upperLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	u.mu.Lock()
+	u.running++
+	u.peak = max(u.peak, u.running)
+	u.prompts = append(u.prompts, prompt)
+	u.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	u.mu.Lock()
+	u.running--
+	u.mu.Unlock()
+
+	gollm.AddTokenUsage(ctx, 10, 5)
+	if strings.Contains(prompt.Input, "fail") {
+		return "", errors.New("boom")
+	}
+	return " " + strings.ToUpper(prompt.Input) + "\n", nil
+}
+
+func TestMapText(t *testing.T) {
+	l := &upperLLM{}
+	var updates []progress.Update
+	var mu sync.Mutex
+	reporter := progress.ReporterFunc(func(u progress.Update) {
+		mu.Lock()
+		defer mu.Unlock()
+		updates = append(updates, u)
+	})
+
+	results, err := MapText(context.Background(), l, []string{"a", "b", "c", "d"}, "转为大写",
+		WithMapConcurrency(2), WithMapProgress(reporter))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A", "B", "C", "D"}, results)
+	assert.Equal(t, 2, l.peak)
+	assert.Equal(t, []string{"转为大写"}, l.prompts[0].Directives)
+	last := updates[len(updates)-1]
+	assert.True(t, last.Final)
+	assert.Equal(t, 4, last.Done)
+}
+
+func TestMapText_PartialFailure(t *testing.T) {
+	l := &upperLLM{}
+	results, err := MapText(context.Background(), l, []string{"a", "fail", "c"}, "转为大写")
+	assert.Equal(t, []string{"A", "", "C"}, results, "the other results are returned")
+
+	var mapErr *MapError
+	require.ErrorAs(t, err, &mapErr)
+	assert.Equal(t, 1, mapErr.Failed)
+	assert.Nil(t, mapErr.Errors[0])
+	assert.EqualError(t, mapErr.Errors[1], "boom")
+	assert.EqualError(t, err, "1 of 3 inputs failed; input 1: boom")
+}
+
+func TestMapText_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err := MapText(ctx, &upperLLM{}, []string{"a", "b"}, "转为大写")
+	assert.Equal(t, []string{"", ""}, results)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = MapText(context.Background(), &upperLLM{}, []string{"a"}, " ")
+	assert.ErrorContains(t, err, "instruction cannot be empty")
+}
+
+func TestMapText_RateLimit(t *testing.T) {
+	start := time.Now()
+	_, err := MapText(context.Background(), &upperLLM{}, []string{"a", "b", "c"}, "转为大写",
+		WithMapConcurrency(3), WithMapRateLimit(50, 1))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond, "3 calls at 50 per second take at least 40ms")
+}
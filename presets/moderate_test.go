@@ -0,0 +1,93 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// moderatedLLM is a scriptedLLM with a moderation endpoint returning result.
+type moderatedLLM struct {
+	scriptedLLM
+	result *gollm.ModerationResult
+	calls  int
+}
+
+func (m *moderatedLLM) Moderate(ctx context.Context, text string) (*gollm.ModerationResult, error) {
+	m.calls++
+	return m.result, nil
+}
+
+func TestModerate_Endpoint(t *testing.T) {
+	l := &moderatedLLM{result: &gollm.ModerationResult{
+		Flagged: true,
+		Categories: []gollm.ModerationCategoryScore{
+			{Name: "hate", Score: 0.01},
+			{Name: "self-harm", Score: 0.05},
+			{Name: "self-harm/intent", Score: 0.3},
+			{Name: "sexual", Score: 0.02},
+			{Name: "violence", Score: 0.4},
+			{Name: "violence/graphic", Score: 0.7, Flagged: true},
+		},
+		Source: gollm.ModerationSourceEndpoint,
+	}}
+
+	result, err := Moderate(context.Background(), l, "文本", WithModerationThreshold(ModerationSelfHarm, 0.2), WithModerationThreshold(ModerationViolence, 0.8))
+	require.NoError(t, err)
+	assert.True(t, result.Native)
+	assert.Empty(t, l.prompts, "no generation call")
+	assert.Equal(t, []ModerationFlag{
+		{Category: ModerationViolence, Score: 0.7},
+		{Category: ModerationSexual, Score: 0.02},
+		{Category: ModerationHate, Score: 0.01},
+		{Category: ModerationSelfHarm, Score: 0.3, Flagged: true},
+	}, result.Categories)
+	assert.True(t, result.Flagged)
+}
+
+func TestModerate_Extraction(t *testing.T) {
+	l := &moderatedLLM{scriptedLLM: scriptedLLM{responses: []string{`[
+		{"category": "Violence", "score": 0.1},
+		{"category": "politics", "score": 0.9}
+	]`}}}
+
+	result, err := Moderate(context.Background(), l, "文本", WithModerationCategories(ModerationViolence, ModerationPolitics))
+	require.NoError(t, err)
+	assert.Zero(t, l.calls, "the endpoint does not score politics")
+	assert.False(t, result.Native)
+	assert.Equal(t, []ModerationFlag{
+		{Category: ModerationViolence, Score: 0.1},
+		{Category: ModerationPolitics, Score: 0.9, Flagged: true},
+	}, result.Categories)
+	assert.True(t, result.Flagged)
+	require.Len(t, l.prompts, 1, "a single generation call")
+	assert.Contains(t, l.prompts[0].Directives[0], "politics：涉及政治敏感话题")
+}
+
+func TestModerate_ModelFallbackOfClient(t *testing.T) {
+	// A client without a moderation endpoint scores its own categories with
+	// its chat model, which include the default ones.
+	l := &moderatedLLM{result: &gollm.ModerationResult{
+		Categories: []gollm.ModerationCategoryScore{
+			{Name: "harassment", Score: 0.9},
+			{Name: "hate", Score: 0.6},
+			{Name: "self-harm"},
+			{Name: "sexual"},
+			{Name: "violence"},
+		},
+		Source: gollm.ModerationSourceModel,
+	}}
+
+	result, err := Moderate(context.Background(), l, "文本")
+	require.NoError(t, err)
+	assert.False(t, result.Native)
+	assert.True(t, result.Flagged)
+	assert.Equal(t, ModerationFlag{Category: ModerationHate, Score: 0.6, Flagged: true}, result.Categories[2])
+	assert.Empty(t, l.prompts)
+
+	_, err = Moderate(context.Background(), &scriptedLLM{responses: []string{`[{"category": "hate", "score": 0.1}]`}}, "文本")
+	assert.ErrorContains(t, err, `no moderation score for category "violence"`)
+}
@@ -0,0 +1,97 @@
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// personaRequest is a request received by the server of newPersonaTestLLM,
+// split into its system message and everything else.
+type personaRequest struct {
+	System string
+	Rest   string
+}
+
+// newPersonaTestLLM returns an OpenAI client that answers structured output
+// requests with a city and all others with "yes", and a function returning
+// the requests it received.
+func newPersonaTestLLM(t *testing.T, opts ...gollm.ConfigOption) (gollm.LLM, func() []personaRequest) {
+	var mu sync.Mutex
+	var requests []personaRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &req))
+
+		var received personaRequest
+		messages := req["messages"].([]interface{})
+		if first := messages[0].(map[string]interface{}); first["role"] == "system" {
+			received.System = first["content"].(string)
+			req["messages"] = messages[1:]
+		}
+		rest, _ := json.Marshal(req)
+		received.Rest = string(rest)
+		mu.Lock()
+		requests = append(requests, received)
+		mu.Unlock()
+
+		answer := "yes"
+		if _, ok := req["response_format"]; ok {
+			answer = `{"name":"杭州","country":"中国"}`
+		}
+		content, _ := json.Marshal(answer)
+		w.Write([]byte(`{"choices":[{"message":{"content":` + string(content) + `}}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	l, err := gollm.NewLLM(append([]gollm.ConfigOption{
+		gollm.SetProvider("openai"),
+		gollm.SetModel("gpt-4o"),
+		gollm.SetAPIKey("sk-test-key-0123456789abcdef"),
+		gollm.SetEndpoint(server.URL),
+		gollm.SetMaxRetries(0),
+		gollm.SetLogLevel(gollm.LogLevelOff),
+	}, opts...)...)
+	require.NoError(t, err)
+	return l, func() []personaRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]personaRequest(nil), requests...)
+	}
+}
+
+func TestPresets_Persona(t *testing.T) {
+	brand := gollm.Persona{Name: "小橙", Tone: []string{"亲切"}, ForbiddenPhrases: []string{"绝对保证"}}
+	ctx := context.Background()
+	run := func(l gollm.LLM) []string {
+		summary, err := Summarize(ctx, l, "杭州是浙江省的省会。")
+		require.NoError(t, err)
+		extracted, err := ExtractStructuredData[city](ctx, l, "杭州是中国浙江省的省会。")
+		require.NoError(t, err)
+		return []string{summary, extracted.Name, extracted.Country}
+	}
+
+	plain, plainRequests := newPersonaTestLLM(t)
+	branded, brandedRequests := newPersonaTestLLM(t, gollm.SetPersona(brand))
+	assert.Equal(t, run(plain), run(branded))
+
+	// The presets send the same requests, with the persona's instructions
+	// added in front of their system prompts.
+	without, with := plainRequests(), brandedRequests()
+	require.Len(t, with, len(without))
+	for i := range with {
+		assert.Equal(t, without[i].Rest, with[i].Rest)
+		assert.True(t, strings.HasPrefix(with[i].System, brand.SystemPrompt()), "request %d: %q", i, with[i].System)
+		assert.Equal(t, without[i].System, strings.TrimPrefix(strings.TrimPrefix(with[i].System, brand.SystemPrompt()), "\n\n"))
+	}
+}
@@ -0,0 +1,21 @@
+package presets
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gollm "github.com/yockii/gollm_cn"
+)
+
+func TestPersonas(t *testing.T) {
+	personas := reflect.ValueOf(Personas)
+	assert.Equal(t, 10, personas.NumField())
+	for i := 0; i < personas.NumField(); i++ {
+		p := personas.Field(i).Interface().(gollm.Persona)
+		name := personas.Type().Field(i).Name
+		assert.NotEmpty(t, p.Role, name)
+		assert.NotEmpty(t, p.ExpertiseAreas, name)
+		assert.NotEmpty(t, p.CommunicationStyle, name)
+	}
+}
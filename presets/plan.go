@@ -0,0 +1,264 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// stepReference matches a reference to the output of an earlier step, such
+// as "{{step1}}", in the arguments of a planned tool call.
+var stepReference = regexp.MustCompile(`\{\{\s*step\s*(\d+)\s*\}\}`)
+
+// PlanStep is a step of the plan made by PlanAndExecute.
+type PlanStep struct {
+	Description string `json:"description" validate:"required"` // What the step does
+	Tool        string `json:"tool"`                            // The tool to call; empty for a step the LLM carries out itself
+	Arguments   string `json:"arguments"`                       // JSON object of the tool's arguments, which may refer to earlier outputs as {{stepN}}
+}
+
+// StepResult is the execution of a PlanStep.
+type StepResult struct {
+	Step      PlanStep               // The step as planned
+	Arguments map[string]interface{} // The arguments passed to the tool, decoded and with references resolved
+	Output    string                 // The tool's result, or the LLM's output for steps without a tool
+	Err       error                  // Why the step failed, if it did
+}
+
+// ExecutionTrace is the result of PlanAndExecute.
+type ExecutionTrace struct {
+	Plan   []PlanStep       // The plan, in order
+	Steps  []StepResult     // The steps executed, in order; the last one failed if PlanAndExecute returned an error
+	Answer string           // The answer to the task, empty if execution failed
+	Usage  gollm.TokenUsage // Tokens used by planning, execution and the answer
+}
+
+// PlanAndExecute carries out task in two phases: the LLM first plans the
+// steps, each a call of one of tools or a step it carries out itself, then
+// PlanAndExecute runs them in order, and finally the LLM answers the task
+// from their outputs. Unlike ReActAgent, the plan is made once, which takes
+// fewer LLM calls and lets the plan be audited before anything runs, but
+// cannot adapt to unexpected results. Later steps use earlier outputs: tool
+// arguments may refer to the output of step N as "{{stepN}}", and steps
+// without a tool see every earlier output. WithMaxSteps caps the number of
+// steps in the plan (default 10); WithAgentDebugManager sets where each step
+// is logged.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for planning and reasoning
+//   - task: The task to carry out
+//   - tools: The tools the plan may call, each with a Handler; may be empty
+//   - opts: Optional options such as WithMaxSteps
+//
+// Returns:
+//   - *ExecutionTrace: The plan, every step executed and the answer. It is
+//     also returned, up to the failed step, when execution fails
+//   - error: Any error encountered, including a plan that is too long or
+//     calls an unknown tool, and a step that fails
+//
+// Example:
+//
+//	trace, err := PlanAndExecute(ctx, llm, "查出北京和上海今天的气温，算出温差", []gollm.Tool{weather, calculator},
+//	    WithMaxSteps(5))
+//	for _, step := range trace.Steps {
+//	    fmt.Println(step.Step.Description, "→", step.Output)
+//	}
+//	fmt.Println(trace.Answer)
+func PlanAndExecute(ctx context.Context, l gollm.LLM, task string, tools []gollm.Tool, opts ...AgentOption) (*ExecutionTrace, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(task) == "" {
+		return nil, fmt.Errorf("task cannot be empty")
+	}
+	byName := make(map[string]gollm.Tool, len(tools))
+	for _, tool := range tools {
+		if tool.Handler == nil {
+			return nil, fmt.Errorf("tool %q has no handler", tool.Function.Name)
+		}
+		byName[tool.Function.Name] = tool
+	}
+	cfg := &agentConfig{maxSteps: defaultMaxSteps}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.maxSteps < 1 {
+		return nil, fmt.Errorf("max steps must be positive, got %d", cfg.maxSteps)
+	}
+	if cfg.debugManager == nil {
+		cfg.debugManager = utils.NewDebugManager(l.GetLogger(), utils.DebugOptions{LogPrompts: true, LogResponses: true})
+	}
+	ctx, recorder := gollm.WithUsageRecorder(ctx)
+	trace := &ExecutionTrace{}
+	fail := func(err error) (*ExecutionTrace, error) {
+		trace.Usage = recorder.Usage()
+		return trace, err
+	}
+
+	plan, err := makePlan(ctx, l, task, tools, cfg.maxSteps)
+	if err != nil {
+		return fail(err)
+	}
+	trace.Plan = plan
+	arguments, err := checkPlan(plan, byName, cfg.maxSteps)
+	if err != nil {
+		return fail(err)
+	}
+	planJSON, _ := json.MarshalIndent(plan, "", "  ")
+	cfg.debugManager.LogResponse("Plan:\n" + string(planJSON))
+
+	for i, step := range plan {
+		result := executeStep(ctx, l, task, byName, trace.Steps, step, arguments[i])
+		cfg.debugManager.LogResponse(fmt.Sprintf("Plan step %d (%s): %s", i+1, step.Description, result.Output))
+		trace.Steps = append(trace.Steps, result)
+		if result.Err != nil {
+			return fail(fmt.Errorf("plan step %d failed: %w", i+1, result.Err))
+		}
+	}
+
+	answer, err := l.Generate(ctx, gollm.NewPrompt(
+		"任务："+task+"\n\n已完成的步骤："+stepOutputs(trace.Steps),
+		gollm.WithDirectives("根据各步骤的结果，直接给出任务的最终答案"),
+	))
+	if err != nil {
+		return fail(fmt.Errorf("failed to answer task: %w", err))
+	}
+	trace.Answer = strings.TrimSpace(answer)
+	trace.Usage = recorder.Usage()
+	return trace, nil
+}
+
+// makePlan asks the LLM for the steps of task.
+func makePlan(ctx context.Context, l gollm.LLM, task string, tools []gollm.Tool, maxSteps int) ([]PlanStep, error) {
+	toolList := "没有可用的工具，所有步骤都由你自己完成，tool 留空"
+	if len(tools) > 0 {
+		var b strings.Builder
+		b.WriteString("可用工具：")
+		for _, tool := range tools {
+			fmt.Fprintf(&b, "\n- %s：%s", tool.Function.Name, tool.Function.Description)
+			if len(tool.Function.Parameters) > 0 {
+				parameters, _ := json.Marshal(tool.Function.Parameters)
+				fmt.Fprintf(&b, "（参数：%s）", parameters)
+			}
+		}
+		toolList = b.String()
+	}
+	steps, err := ExtractStructuredList[PlanStep](ctx, l, "任务："+task, WithPromptOptions(gollm.WithDirectives(
+		"你是任务规划者。把任务分解为按顺序执行的步骤，每个条目是一个步骤，description 说明这一步做什么",
+		toolList,
+		"需要调用工具的步骤，tool 填工具名，arguments 填调用参数的 JSON 对象字符串；由你自己推理或整理信息的步骤，tool 和 arguments 留空",
+		"参数的值中可以用 {{step1}}、{{step2}} 等引用前面步骤的输出",
+		fmt.Sprintf("步骤尽量少，最多 %d 步；不要包含给出最终答案的步骤", maxSteps),
+	)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan task: %w", err)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("plan has no steps")
+	}
+	return steps, nil
+}
+
+// checkPlan rejects plans that are too long, call unknown tools, have
+// arguments that are not a JSON object or refer to the output of a step that
+// has not run yet. It returns the decoded arguments of each step.
+func checkPlan(plan []PlanStep, tools map[string]gollm.Tool, maxSteps int) ([]map[string]interface{}, error) {
+	if len(plan) > maxSteps {
+		return nil, fmt.Errorf("plan has %d steps, more than the maximum of %d", len(plan), maxSteps)
+	}
+	arguments := make([]map[string]interface{}, len(plan))
+	for i, step := range plan {
+		if step.Tool == "" {
+			continue
+		}
+		if _, ok := tools[step.Tool]; !ok {
+			names := make([]string, 0, len(tools))
+			for name := range tools {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("plan step %d calls unknown tool %q, available tools: %s", i+1, step.Tool, strings.Join(names, ", "))
+		}
+		arguments[i] = map[string]interface{}{}
+		if strings.TrimSpace(step.Arguments) != "" {
+			if err := json.Unmarshal([]byte(step.Arguments), &arguments[i]); err != nil {
+				return nil, fmt.Errorf("plan step %d has invalid arguments %q: %w", i+1, step.Arguments, err)
+			}
+		}
+		for _, match := range stepReference.FindAllStringSubmatch(step.Arguments, -1) {
+			if n, _ := strconv.Atoi(match[1]); n < 1 || n > i {
+				return nil, fmt.Errorf("plan step %d refers to the output of step %d, which does not run before it", i+1, n)
+			}
+		}
+	}
+	return arguments, nil
+}
+
+// executeStep runs a step: its tool, with the references in its arguments
+// resolved, or else an LLM call that sees the earlier outputs.
+func executeStep(ctx context.Context, l gollm.LLM, task string, tools map[string]gollm.Tool, done []StepResult, step PlanStep, arguments map[string]interface{}) StepResult {
+	result := StepResult{Step: step}
+	if step.Tool == "" {
+		output, err := l.Generate(ctx, gollm.NewPrompt(
+			"任务："+task+"\n\n已完成的步骤："+stepOutputs(done)+"\n\n当前步骤："+step.Description,
+			gollm.WithDirectives("只完成当前步骤，输出这一步的结果，不要进行后面的步骤"),
+		))
+		result.Output, result.Err = strings.TrimSpace(output), err
+		return result
+	}
+	result.Arguments = resolveReferences(arguments, done).(map[string]interface{})
+	result.Output, result.Err = tools[step.Tool].Handler(ctx, result.Arguments)
+	return result
+}
+
+// resolveReferences returns value with every {{stepN}} in its strings
+// replaced by the output of step N.
+func resolveReferences(value interface{}, done []StepResult) interface{} {
+	switch v := value.(type) {
+	case string:
+		return stepReference.ReplaceAllStringFunc(v, func(reference string) string {
+			n, _ := strconv.Atoi(stepReference.FindStringSubmatch(reference)[1])
+			return done[n-1].Output
+		})
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved[key] = resolveReferences(item, done)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved[i] = resolveReferences(item, done)
+		}
+		return resolved
+	}
+	return value
+}
+
+// stepOutputs lists the steps done and their outputs for a prompt.
+func stepOutputs(done []StepResult) string {
+	if len(done) == 0 {
+		return "无"
+	}
+	var b strings.Builder
+	for i, result := range done {
+		fmt.Fprintf(&b, "\n\n第 %d 步：%s", i+1, result.Step.Description)
+		if result.Step.Tool != "" {
+			arguments, _ := json.Marshal(result.Arguments)
+			fmt.Fprintf(&b, "\n行动：%s %s", result.Step.Tool, arguments)
+		}
+		b.WriteString("\n输出：" + result.Output)
+	}
+	return b.String()
+}
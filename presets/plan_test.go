@@ -0,0 +1,81 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+)
+
+func TestPlanAndExecute(t *testing.T) {
+	l := &meteredLLM{scriptedLLM{responses: []string{
+		`[{"description": "查询票价", "tool": "search", "arguments": "{\"query\": \"北京 上海 高铁票价\"}"},
+		  {"description": "提取票价数字", "tool": "", "arguments": ""},
+		  {"description": "计算三张票的价格", "tool": "calculator", "arguments": "{\"expression\": \"{{step2}}*3\"}"}]`,
+		"553",
+		"三张票共 1659 元。",
+	}}}
+
+	trace, err := PlanAndExecute(context.Background(), l, "北京到上海的高铁票价乘以 3 是多少？", agentTools())
+	require.NoError(t, err)
+	assert.Equal(t, "三张票共 1659 元。", trace.Answer)
+	require.Len(t, trace.Plan, 3)
+	require.Len(t, trace.Steps, 3)
+	assert.Equal(t, "北京到上海的二等座票价为 553 元", trace.Steps[0].Output)
+	assert.Equal(t, "553", trace.Steps[1].Output)
+	assert.Equal(t, `{"expression": "{{step2}}*3"}`, trace.Steps[2].Step.Arguments)
+	assert.Equal(t, map[string]interface{}{"expression": "553*3"}, trace.Steps[2].Arguments, "references are resolved")
+	assert.Equal(t, "1659", trace.Steps[2].Output)
+	assert.Equal(t, gollm.TokenUsage{InputTokens: 30, OutputTokens: 15}, trace.Usage)
+
+	require.Len(t, l.prompts, 3)
+	assert.Contains(t, l.prompts[0].Directives[1], "- calculator：计算算术表达式")
+	assert.Contains(t, l.prompts[1].Input, "第 1 步：查询票价\n行动：search {\"query\":\"北京 上海 高铁票价\"}\n输出：北京到上海的二等座票价为 553 元")
+	assert.Contains(t, l.prompts[1].Input, "当前步骤：提取票价数字")
+	assert.Contains(t, l.prompts[2].Input, "第 3 步：计算三张票的价格\n行动：calculator {\"expression\":\"553*3\"}\n输出：1659")
+}
+
+func TestPlanAndExecute_StepFails(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		`[{"description": "计算", "tool": "calculator", "arguments": "{\"expression\": \"553 x 3\"}"},
+		  {"description": "总结", "tool": "", "arguments": ""}]`,
+	}}
+	trace, err := PlanAndExecute(context.Background(), l, "任务", agentTools())
+	assert.ErrorContains(t, err, "plan step 1 failed: cannot parse 553 x 3")
+	require.NotNil(t, trace)
+	assert.Len(t, trace.Plan, 2)
+	require.Len(t, trace.Steps, 1)
+	assert.Error(t, trace.Steps[0].Err)
+	assert.Empty(t, trace.Answer)
+	assert.Len(t, l.prompts, 1, "no step runs after a failed one")
+}
+
+func TestPlanAndExecute_InvalidPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    string
+		opts    []AgentOption
+		wantErr string
+	}{
+		{"too long", `[{"description": "一"}, {"description": "二"}, {"description": "三"}]`, []AgentOption{WithMaxSteps(2)}, "plan has 3 steps, more than the maximum of 2"},
+		{"unknown tool", `[{"description": "查天气", "tool": "weather"}]`, nil, `plan step 1 calls unknown tool "weather", available tools: calculator, search`},
+		{"forward reference", `[{"description": "计算", "tool": "calculator", "arguments": "{\"expression\": \"{{step2}}\"}"}, {"description": "二"}]`, nil, "refers to the output of step 2"},
+		{"invalid arguments", `[{"description": "计算", "tool": "calculator", "arguments": "553*3"}]`, nil, "plan step 1 has invalid arguments"},
+		{"empty", `[]`, nil, "plan has no steps"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &scriptedLLM{responses: []string{tt.plan}}
+			_, err := PlanAndExecute(context.Background(), l, "任务", agentTools(), tt.opts...)
+			assert.ErrorContains(t, err, tt.wantErr)
+			assert.Len(t, l.prompts, 1, "nothing runs after an invalid plan")
+		})
+	}
+
+	_, err := PlanAndExecute(context.Background(), &scriptedLLM{}, " ", nil)
+	assert.ErrorContains(t, err, "task cannot be empty")
+	_, err = PlanAndExecute(context.Background(), &scriptedLLM{}, "任务", []gollm.Tool{{Function: gollm.Function{Name: "search"}}})
+	assert.ErrorContains(t, err, `tool "search" has no handler`)
+}
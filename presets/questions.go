@@ -0,0 +1,186 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// Question types accepted by WithQuestionTypes.
+const (
+	QuestionFactual        = "factual"
+	QuestionInference      = "inference"
+	QuestionMultipleChoice = "multiple-choice"
+)
+
+// Difficulties accepted by WithDifficulty.
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+// questionTypes are the question types GenerateQuestions can produce.
+var questionTypes = []string{QuestionFactual, QuestionInference, QuestionMultipleChoice}
+
+// QA is a question and answer pair generated by GenerateQuestions. Multiple
+// choice questions also have wrong but plausible answers in Distractors; the
+// choices to show are Answer and Distractors, in any order.
+type QA struct {
+	Question    string   `json:"question" validate:"required"`
+	Answer      string   `json:"answer" validate:"required"`
+	Type        string   `json:"type" validate:"required"`
+	Distractors []string `json:"distractors,omitempty" validate:"required_if=Type multiple-choice,dive,required"`
+}
+
+// QuestionOption configures GenerateQuestions.
+type QuestionOption func(*questionConfig)
+
+type questionConfig struct {
+	difficulty     string
+	types          []string
+	groundingCheck bool
+}
+
+// WithDifficulty sets the difficulty of the questions: DifficultyEasy,
+// DifficultyMedium or DifficultyHard. The default is DifficultyMedium.
+func WithDifficulty(difficulty string) QuestionOption {
+	return func(c *questionConfig) {
+		c.difficulty = difficulty
+	}
+}
+
+// WithQuestionTypes restricts GenerateQuestions to the given question types:
+// QuestionFactual, QuestionInference or QuestionMultipleChoice. By default
+// factual and inference questions are generated.
+func WithQuestionTypes(types ...string) QuestionOption {
+	return func(c *questionConfig) {
+		c.types = append(c.types, types...)
+	}
+}
+
+// WithoutGroundingCheck keeps every generated pair, skipping the check that
+// the source text supports each answer. It saves one LLM call per pair.
+func WithoutGroundingCheck() QuestionOption {
+	return func(c *questionConfig) {
+		c.groundingCheck = false
+	}
+}
+
+// GenerateQuestions produces n question and answer pairs grounded in text,
+// for instance to build a quiz or an evaluation set. The pairs are extracted
+// with ExtractStructuredList, so each one is validated on its own. Unless
+// WithoutGroundingCheck is set, the LLM is then asked for each pair whether
+// the text supports its answer, and unsupported pairs are dropped with a
+// warning logged through the LLM's logger. The result can therefore hold
+// fewer than n pairs.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for generation
+//   - text: The source text the questions are about
+//   - n: The number of pairs to generate
+//   - opts: Optional question options such as WithDifficulty and
+//     WithQuestionTypes
+//
+// Returns:
+//   - []QA: At most n pairs, in the order the LLM listed them
+//   - error: Any error encountered, including an unknown difficulty or
+//     question type
+//
+// Example:
+//
+//	pairs, err := GenerateQuestions(ctx, llm, article, 5,
+//	    WithDifficulty(DifficultyHard),
+//	    WithQuestionTypes(QuestionMultipleChoice),
+//	)
+//	for _, qa := range pairs {
+//	    fmt.Println(qa.Question, qa.Answer, qa.Distractors)
+//	}
+func GenerateQuestions(ctx context.Context, l gollm.LLM, text string, n int, opts ...QuestionOption) ([]QA, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("number of questions must be positive, got %d", n)
+	}
+	cfg := &questionConfig{difficulty: DifficultyMedium, groundingCheck: true}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if _, ok := matchCategory([]string{DifficultyEasy, DifficultyMedium, DifficultyHard}, cfg.difficulty); !ok {
+		return nil, fmt.Errorf("unknown difficulty %q", cfg.difficulty)
+	}
+	types := []string{QuestionFactual, QuestionInference}
+	if len(cfg.types) > 0 {
+		types = make([]string, len(cfg.types))
+		for i, t := range cfg.types {
+			questionType, ok := matchCategory(questionTypes, t)
+			if !ok {
+				return nil, fmt.Errorf("unknown question type %q", t)
+			}
+			types[i] = questionType
+		}
+	}
+
+	directives := []string{
+		fmt.Sprintf("根据文本生成 %d 个问题及其答案", n),
+		"答案必须能由文本直接支持或合理推出，不要使用文本以外的知识",
+		"问题的难度为 " + cfg.difficulty + "（easy 为简单，medium 为中等，hard 为困难）",
+		"type 必须是以下类型之一：" + strings.Join(types, "、") + "；factual 为文本中明确陈述的事实，inference 需要根据文本推理，multiple-choice 为选择题",
+		"选择题的 answer 为正确选项，distractors 给出 3 个似是而非的错误选项；其他类型的 distractors 留空",
+		"问题和答案使用与文本相同的语言",
+	}
+	pairs, err := ExtractStructuredList[QA](ctx, l, text, WithPromptOptions(gollm.WithDirectives(directives...)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate questions: %w", err)
+	}
+
+	result := make([]QA, 0, n)
+	for _, qa := range pairs {
+		if len(result) == n {
+			break
+		}
+		questionType, ok := matchCategory(types, qa.Type)
+		if !ok {
+			l.GetLogger().Debug("Dropped question of unrequested type", "question", qa.Question, "type", qa.Type)
+			continue
+		}
+		qa.Type = questionType
+		if questionType != QuestionMultipleChoice {
+			qa.Distractors = nil
+		}
+		if cfg.groundingCheck {
+			grounded, err := isGrounded(ctx, l, text, qa)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check answer: %w", err)
+			}
+			if !grounded {
+				l.GetLogger().Warn("Dropped question whose answer the text does not support", "question", qa.Question, "answer", qa.Answer)
+				continue
+			}
+		}
+		result = append(result, qa)
+	}
+	return result, nil
+}
+
+// isGrounded asks the LLM whether text supports the answer of qa.
+func isGrounded(ctx context.Context, l gollm.LLM, text string, qa QA) (bool, error) {
+	prompt := gollm.NewPrompt(fmt.Sprintf("文本:\n\n%s\n\n问题：%s\n答案：%s\n\n根据上面的文本，这个答案是否正确且能被文本支持？", text, qa.Question, qa.Answer),
+		gollm.WithDirectives("仅回答'是'或'否'", "只依据文本判断，不要使用文本以外的知识"),
+		gollm.WithOutput("单字回答：'是'或'否'"),
+	)
+	response, err := l.Generate(ctx, prompt, gollm.WithTemperature(0), gollm.WithMaxTokens(5))
+	if err != nil {
+		return false, err
+	}
+	return isAffirmative(response), nil
+}
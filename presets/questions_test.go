@@ -0,0 +1,66 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const questionText = "长城始建于春秋战国时期，明朝时大规模重修，全长两万多千米。"
+
+func TestGenerateQuestions(t *testing.T) {
+	l := &scriptedLLM{responses: []string{`[
+		{"question": "长城始建于什么时期？", "answer": "春秋战国时期", "type": "FACTUAL", "distractors": ["秦朝"]},
+		{"question": "长城有多长？", "answer": "一万千米", "type": "factual"},
+		{"question": "哪个朝代大规模重修了长城？", "answer": "明朝", "type": "multiple-choice", "distractors": ["唐朝", "宋朝", "元朝"]},
+		{"question": "谁设计了长城？", "answer": "蒙恬", "type": "inference"}
+	]`, "是", "否", "是。", "是"}}
+
+	pairs, err := GenerateQuestions(context.Background(), l, questionText, 3,
+		WithDifficulty(DifficultyEasy),
+		WithQuestionTypes(QuestionFactual, QuestionMultipleChoice),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []QA{
+		{Question: "长城始建于什么时期？", Answer: "春秋战国时期", Type: QuestionFactual},
+		{Question: "哪个朝代大规模重修了长城？", Answer: "明朝", Type: QuestionMultipleChoice, Distractors: []string{"唐朝", "宋朝", "元朝"}},
+	}, pairs, "unsupported answers and unrequested types are dropped")
+	assert.Len(t, l.prompts, 4, "one grounding check per pair of a requested type")
+	assert.Contains(t, l.prompts[0].Directives, "根据文本生成 3 个问题及其答案")
+	assert.Contains(t, l.prompts[0].Directives, "问题的难度为 easy（easy 为简单，medium 为中等，hard 为困难）")
+	assert.Contains(t, l.prompts[2].Input, "答案：一万千米")
+}
+
+func TestGenerateQuestions_WithoutGroundingCheck(t *testing.T) {
+	l := &scriptedLLM{responses: []string{`[
+		{"question": "长城有多长？", "answer": "两万多千米", "type": "factual"},
+		{"question": "明朝为什么重修长城？", "answer": "为了防御北方", "type": "inference"},
+		{"question": "长城在哪个国家？", "answer": "中国", "type": "factual"}
+	]`}}
+
+	pairs, err := GenerateQuestions(context.Background(), l, questionText, 2, WithoutGroundingCheck())
+	require.NoError(t, err)
+	assert.Len(t, pairs, 2, "extra pairs are dropped")
+	assert.Len(t, l.prompts, 1)
+}
+
+func TestGenerateQuestions_Validation(t *testing.T) {
+	ctx := context.Background()
+
+	l := &scriptedLLM{responses: []string{
+		`[{"question": "哪个朝代重修了长城？", "answer": "明朝", "type": "multiple-choice"}]`,
+		`[{"question": "哪个朝代重修了长城？", "answer": "明朝", "type": "multiple-choice", "distractors": ["唐朝", "宋朝", "元朝"]}]`,
+	}}
+	pairs, err := GenerateQuestions(ctx, l, questionText, 1, WithQuestionTypes(QuestionMultipleChoice), WithoutGroundingCheck())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"唐朝", "宋朝", "元朝"}, pairs[0].Distractors, "multiple-choice items without distractors are re-asked")
+
+	_, err = GenerateQuestions(ctx, &scriptedLLM{}, questionText, 0)
+	assert.ErrorContains(t, err, "must be positive")
+	_, err = GenerateQuestions(ctx, &scriptedLLM{}, questionText, 3, WithDifficulty("extreme"))
+	assert.ErrorContains(t, err, `unknown difficulty "extreme"`)
+	_, err = GenerateQuestions(ctx, &scriptedLLM{}, questionText, 3, WithQuestionTypes("essay"))
+	assert.ErrorContains(t, err, `unknown question type "essay"`)
+}
@@ -0,0 +1,237 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// defaultMaxSteps is the number of LLM calls ReActAgent makes at most, and
+// the number of steps a plan of PlanAndExecute has at most.
+const defaultMaxSteps = 10
+
+// AgentResult is the result of ReActAgent.
+type AgentResult struct {
+	Answer string           // The final answer, empty if the agent ran out of steps
+	Steps  []AgentStep      // Every step, in order, for auditing
+	Usage  gollm.TokenUsage // Tokens used by all steps
+}
+
+// AgentStep is one reason→act→observe step of ReActAgent. The last step of
+// an agent that answered has no actions; its Thought is the answer.
+type AgentStep struct {
+	Thought string        // What the LLM wrote besides its tool calls
+	Actions []AgentAction // The tool calls of the step and their results
+}
+
+// AgentAction is a tool call made by the LLM and what it observed.
+type AgentAction struct {
+	Tool        string                 // Name of the tool called
+	Arguments   map[string]interface{} // Arguments passed by the LLM
+	Observation string                 // The tool's result, or the error shown to the LLM
+	Err         error                  // Why the call failed, if it did
+}
+
+// AgentOption configures ReActAgent and PlanAndExecute.
+type AgentOption func(*agentConfig)
+
+type agentConfig struct {
+	maxSteps     int
+	debugManager *utils.DebugManager
+}
+
+// WithMaxSteps sets how many times ReActAgent calls the LLM at most, and how
+// many steps the plan of PlanAndExecute has at most. The default is 10.
+func WithMaxSteps(n int) AgentOption {
+	return func(c *agentConfig) {
+		c.maxSteps = n
+	}
+}
+
+// WithAgentDebugManager sets the DebugManager through which each step is
+// logged. By default steps are logged at debug level to the LLM's logger.
+func WithAgentDebugManager(dm *utils.DebugManager) AgentOption {
+	return func(c *agentConfig) {
+		c.debugManager = dm
+	}
+}
+
+// ReActAgent carries out task with the ReAct loop: the LLM reasons about the
+// task and calls one of tools, ReActAgent runs the tool's Handler and shows
+// the LLM the result, and so on until the LLM answers without calling a tool.
+// Tools are offered both through the provider's tool calling and as
+// "<function_call>" instructions in the prompt, so models without tool
+// calling can use them too. A tool that fails, or a call of an unknown tool,
+// is shown to the LLM as an error observation instead of ending the loop.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for reasoning
+//   - task: The task to carry out
+//   - tools: The tools the LLM may call, each with a Handler
+//   - opts: Optional options such as WithMaxSteps
+//
+// Returns:
+//   - *AgentResult: The answer, every step and the total token usage. It is
+//     also returned, without an answer, when the agent runs out of steps
+//   - error: Any error encountered, including running out of steps
+//
+// Example:
+//
+//	calculator := gollm.Tool{
+//	    Type: "function",
+//	    Function: gollm.Function{Name: "calculator", Description: "计算算术表达式", Parameters: ...},
+//	    Handler: func(ctx context.Context, args map[string]interface{}) (string, error) { ... },
+//	}
+//	result, err := ReActAgent(ctx, llm, "北京到上海的高铁票价乘以 3 是多少？", []gollm.Tool{search, calculator})
+//	fmt.Println(result.Answer)
+func ReActAgent(ctx context.Context, l gollm.LLM, task string, tools []gollm.Tool, opts ...AgentOption) (*AgentResult, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(task) == "" {
+		return nil, fmt.Errorf("task cannot be empty")
+	}
+	if len(tools) == 0 {
+		return nil, fmt.Errorf("at least one tool is required")
+	}
+	byName := make(map[string]gollm.Tool, len(tools))
+	for _, tool := range tools {
+		if tool.Handler == nil {
+			return nil, fmt.Errorf("tool %q has no handler", tool.Function.Name)
+		}
+		byName[tool.Function.Name] = tool
+	}
+	cfg := &agentConfig{maxSteps: defaultMaxSteps}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.maxSteps < 1 {
+		return nil, fmt.Errorf("max steps must be positive, got %d", cfg.maxSteps)
+	}
+	if cfg.debugManager == nil {
+		cfg.debugManager = utils.NewDebugManager(l.GetLogger(), utils.DebugOptions{LogPrompts: true, LogResponses: true})
+	}
+	ctx, recorder := gollm.WithUsageRecorder(ctx)
+
+	result := &AgentResult{}
+	for step := 1; step <= cfg.maxSteps; step++ {
+		prompt := agentPrompt(task, tools, result.Steps)
+		cfg.debugManager.LogPrompt(fmt.Sprintf("Agent step %d:\n%s", step, prompt.Input))
+		response, err := l.Generate(ctx, prompt)
+		if err != nil {
+			result.Usage = recorder.Usage()
+			return result, fmt.Errorf("agent step %d failed: %w", step, err)
+		}
+		cfg.debugManager.LogResponse(fmt.Sprintf("Agent step %d:\n%s", step, response))
+
+		thought, calls, _ := utils.CleanResponse(response)
+		current := AgentStep{Thought: strings.TrimSpace(thought)}
+		if len(calls) == 0 {
+			result.Steps = append(result.Steps, current)
+			result.Answer = current.Thought
+			result.Usage = recorder.Usage()
+			return result, nil
+		}
+		for _, call := range calls {
+			action := runTool(ctx, byName, call)
+			cfg.debugManager.LogResponse(fmt.Sprintf("Agent step %d observation from %s: %s", step, action.Tool, action.Observation))
+			current.Actions = append(current.Actions, action)
+		}
+		result.Steps = append(result.Steps, current)
+	}
+	result.Usage = recorder.Usage()
+	return result, fmt.Errorf("agent gave no final answer within %d steps", cfg.maxSteps)
+}
+
+// runTool parses a "<function_call>" payload and runs the tool it names.
+// Failures become error observations.
+func runTool(ctx context.Context, tools map[string]gollm.Tool, call string) AgentAction {
+	var parsed struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal([]byte(call), &parsed); err != nil {
+		return failedAction(AgentAction{}, fmt.Errorf("invalid tool call %s: %w", call, err))
+	}
+	action := AgentAction{Tool: parsed.Name}
+	if len(parsed.Arguments) > 0 {
+		// Arguments are an object, or an object encoded as a string.
+		var encoded string
+		if json.Unmarshal(parsed.Arguments, &encoded) == nil {
+			parsed.Arguments = json.RawMessage(encoded)
+		}
+		if err := json.Unmarshal(parsed.Arguments, &action.Arguments); err != nil {
+			return failedAction(action, fmt.Errorf("invalid arguments for tool %q: %w", parsed.Name, err))
+		}
+	}
+	tool, ok := tools[parsed.Name]
+	if !ok {
+		names := make([]string, 0, len(tools))
+		for name := range tools {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return failedAction(action, fmt.Errorf("unknown tool %q, available tools: %s", parsed.Name, strings.Join(names, ", ")))
+	}
+	observation, err := tool.Handler(ctx, action.Arguments)
+	if err != nil {
+		return failedAction(action, err)
+	}
+	action.Observation = observation
+	return action
+}
+
+// failedAction records err as the observation of action.
+func failedAction(action AgentAction, err error) AgentAction {
+	action.Err = err
+	action.Observation = "错误：" + err.Error()
+	return action
+}
+
+// agentPrompt builds the prompt of the next step from the task and the steps
+// so far.
+func agentPrompt(task string, tools []gollm.Tool, steps []AgentStep) *gollm.Prompt {
+	var input strings.Builder
+	input.WriteString("任务：" + task)
+	if len(steps) > 0 {
+		input.WriteString("\n\n已完成的步骤：")
+		for i, step := range steps {
+			fmt.Fprintf(&input, "\n\n第 %d 步", i+1)
+			if step.Thought != "" {
+				input.WriteString("\n思考：" + step.Thought)
+			}
+			for _, action := range step.Actions {
+				arguments, _ := json.Marshal(action.Arguments)
+				fmt.Fprintf(&input, "\n行动：%s %s\n观察：%s", action.Tool, arguments, action.Observation)
+			}
+		}
+	}
+
+	var toolList strings.Builder
+	toolList.WriteString("可用工具：")
+	for _, tool := range tools {
+		fmt.Fprintf(&toolList, "\n- %s：%s", tool.Function.Name, tool.Function.Description)
+		if len(tool.Function.Parameters) > 0 {
+			parameters, _ := json.Marshal(tool.Function.Parameters)
+			fmt.Fprintf(&toolList, "（参数：%s）", parameters)
+		}
+	}
+	return gollm.NewPrompt(input.String(),
+		gollm.WithTools(tools),
+		gollm.WithDirectives(
+			"逐步完成任务：每一步先简要写出思考，再调用工具获取所需的信息",
+			toolList.String(),
+			`如果无法直接调用工具，按以下格式输出调用，每个调用占一行：<function_call>{"name": "工具名", "arguments": {...}}</function_call>`,
+			"工具返回错误时，根据错误调整调用或换用其他方法",
+			"信息足够时不再调用工具，直接给出最终答案",
+		),
+	)
+}
@@ -0,0 +1,87 @@
+package presets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+)
+
+func agentTools() []gollm.Tool {
+	return []gollm.Tool{
+		{
+			Type:     "function",
+			Function: gollm.Function{Name: "search", Description: "搜索网页"},
+			Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+				return "北京到上海的二等座票价为 553 元", nil
+			},
+		},
+		{
+			Type:     "function",
+			Function: gollm.Function{Name: "calculator", Description: "计算算术表达式"},
+			Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+				if args["expression"] != "553*3" {
+					return "", fmt.Errorf("cannot parse %v", args["expression"])
+				}
+				return "1659", nil
+			},
+		},
+	}
+}
+
+func TestReActAgent(t *testing.T) {
+	l := &meteredLLM{scriptedLLM{responses: []string{
+		`先查票价。<function_call>{"name": "search", "arguments": {"query": "北京 上海 高铁票价"}}</function_call>`,
+		`<function_call>{"name": "calculator", "arguments": "{\"expression\": \"553 x 3\"}"}</function_call>`,
+		`<function_call>{"name": "weather", "arguments": {}}</function_call>`,
+		`改用乘号。<function_call>{"name": "calculator", "arguments": {"expression": "553*3"}}</function_call>`,
+		"三张票共 1659 元。",
+	}}}
+
+	result, err := ReActAgent(context.Background(), l, "北京到上海的高铁票价乘以 3 是多少？", agentTools())
+	require.NoError(t, err)
+	assert.Equal(t, "三张票共 1659 元。", result.Answer)
+	require.Len(t, result.Steps, 5)
+	assert.Equal(t, "先查票价。", result.Steps[0].Thought)
+	assert.Equal(t, AgentAction{
+		Tool:        "search",
+		Arguments:   map[string]interface{}{"query": "北京 上海 高铁票价"},
+		Observation: "北京到上海的二等座票价为 553 元",
+	}, result.Steps[0].Actions[0])
+	assert.Equal(t, "错误：cannot parse 553 x 3", result.Steps[1].Actions[0].Observation, "string arguments are decoded")
+	assert.Error(t, result.Steps[1].Actions[0].Err)
+	assert.Equal(t, `错误：unknown tool "weather", available tools: calculator, search`, result.Steps[2].Actions[0].Observation)
+	assert.Equal(t, "1659", result.Steps[3].Actions[0].Observation)
+	assert.Empty(t, result.Steps[4].Actions)
+	assert.Equal(t, gollm.TokenUsage{InputTokens: 50, OutputTokens: 25}, result.Usage)
+
+	last := l.prompts[4]
+	assert.Contains(t, last.Input, "第 2 步\n行动：calculator {\"expression\":\"553 x 3\"}\n观察：错误：cannot parse 553 x 3")
+	assert.Len(t, last.Tools, 2)
+	assert.Contains(t, last.Directives[1], "- calculator：计算算术表达式")
+}
+
+func TestReActAgent_MaxSteps(t *testing.T) {
+	call := `<function_call>{"name": "search", "arguments": {"query": "票价"}}</function_call>`
+	l := &scriptedLLM{responses: []string{call, call}}
+
+	result, err := ReActAgent(context.Background(), l, "任务", agentTools(), WithMaxSteps(2))
+	assert.ErrorContains(t, err, "no final answer within 2 steps")
+	require.NotNil(t, result)
+	assert.Len(t, result.Steps, 2)
+	assert.Empty(t, result.Answer)
+}
+
+func TestReActAgent_InvalidInput(t *testing.T) {
+	_, err := ReActAgent(context.Background(), &scriptedLLM{}, "任务", nil)
+	assert.ErrorContains(t, err, "at least one tool")
+
+	_, err = ReActAgent(context.Background(), &scriptedLLM{}, "任务", []gollm.Tool{{Function: gollm.Function{Name: "search"}}})
+	assert.ErrorContains(t, err, `tool "search" has no handler`)
+
+	_, err = ReActAgent(context.Background(), &failingLLM{}, "任务", agentTools())
+	assert.ErrorContains(t, err, "provider down")
+}
@@ -0,0 +1,250 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// Severities of a Finding, from most to least severe.
+const (
+	SeverityCritical = "critical" // A bug, vulnerability or data loss to fix before merging
+	SeverityMajor    = "major"    // A likely defect or a significant design problem
+	SeverityMinor    = "minor"    // A readability, style or small maintainability issue
+	SeverityInfo     = "info"     // A remark or an optional improvement
+)
+
+// severityRanks orders severities for sorting findings.
+var severityRanks = map[string]int{SeverityCritical: 0, SeverityMajor: 1, SeverityMinor: 2, SeverityInfo: 3}
+
+// defaultReviewChunkLines is the number of lines ReviewCode reviews at once.
+const defaultReviewChunkLines = 300
+
+// Finding is an issue found by ReviewCode.
+type Finding struct {
+	Severity     string `json:"severity" validate:"required,oneof=critical major minor info"`
+	Line         int    `json:"line" validate:"gte=0"` // Line in the reviewed code, from 1; 0 for the whole file
+	Message      string `json:"message" validate:"required"`
+	SuggestedFix string `json:"suggested_fix"` // Replacement code or a description of the fix; may be empty
+}
+
+// ReviewOption configures ReviewCode.
+type ReviewOption func(*reviewConfig)
+
+type reviewConfig struct {
+	focus      []string
+	chunkLines int
+}
+
+// WithFocus restricts the review to the given areas, e.g. "security" or
+// "performance". By default all areas are reviewed: correctness, security,
+// performance, error handling and readability.
+func WithFocus(areas ...string) ReviewOption {
+	return func(c *reviewConfig) {
+		c.focus = areas
+	}
+}
+
+// WithReviewChunkLines sets the approximate number of lines of code reviewed
+// in one call. The default is 300.
+func WithReviewChunkLines(n int) ReviewOption {
+	return func(c *reviewConfig) {
+		c.chunkLines = n
+	}
+}
+
+// ReviewCode reviews source code and returns the issues found, most severe
+// first. Long files are reviewed in chunks: Go code is split between
+// top-level declarations with go/parser, other languages at blank lines.
+// Each chunk is shown to the LLM with the line numbers of the file, so the
+// line of every finding refers to code, whatever chunk it was found in.
+// Findings are extracted with ExtractStructuredList, which validates them
+// against the JSON schema of Finding and asks the LLM to correct invalid
+// output.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for the review
+//   - code: The source code to review
+//   - language: Programming language of the code, e.g. "go" or "python"
+//   - opts: Optional options such as WithFocus
+//
+// Returns:
+//   - []Finding: The issues found, sorted by severity then line; empty if none
+//   - error: Any error encountered
+//
+// Example:
+//
+//	findings, err := ReviewCode(ctx, llm, source, "go", WithFocus("security", "performance"))
+//	for _, f := range findings {
+//	    fmt.Printf("%s:%d [%s] %s\n", path, f.Line, f.Severity, f.Message)
+//	}
+func ReviewCode(ctx context.Context, l gollm.LLM, code, language string, opts ...ReviewOption) ([]Finding, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(code) == "" {
+		return nil, fmt.Errorf("code cannot be empty")
+	}
+	cfg := &reviewConfig{chunkLines: defaultReviewChunkLines}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.chunkLines < 1 {
+		return nil, fmt.Errorf("chunk lines must be positive, got %d", cfg.chunkLines)
+	}
+
+	focus := "正确性、安全性、性能、错误处理和可读性"
+	if len(cfg.focus) > 0 {
+		focus = strings.Join(cfg.focus, "、")
+	}
+	lines := strings.Split(strings.ReplaceAll(code, "\r\n", "\n"), "\n")
+	chunks := reviewChunks(code, lines, language, cfg.chunkLines)
+
+	findings := []Finding{}
+	for i, chunk := range chunks {
+		directives := []string{
+			fmt.Sprintf("你是资深的 %s 代码审查员。审查文本中的代码，只关注：%s", language, focus),
+			"每行代码前的数字是它在文件中的行号，line 填写问题所在的行号，整个文件的问题填 0",
+			"severity 只能是 critical（必须修复的缺陷、漏洞或数据丢失）、major（可能的缺陷或明显的设计问题）、minor（可读性、风格等小问题）或 info（建议）",
+			"message 简要说明问题及其原因，suggested_fix 给出修改后的代码或修复方法",
+			"只报告确实存在的问题，不要报告代码中看不到的问题，没有问题时返回空数组",
+		}
+		if len(chunks) > 1 {
+			directives = append(directives, fmt.Sprintf("这是文件的第 %d/%d 部分，不要报告因代码位于其他部分而看不到的定义", i+1, len(chunks)))
+		}
+		items, err := ExtractStructuredList[Finding](ctx, l, numberLines(lines, chunk.start, chunk.end),
+			WithPromptOptions(gollm.WithDirectives(directives...)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to review lines %d-%d: %w", chunk.start, chunk.end, err)
+		}
+		for _, item := range items {
+			item.Line = chunk.fileLine(item.Line)
+			findings = append(findings, item)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if severityRanks[findings[i].Severity] != severityRanks[findings[j].Severity] {
+			return severityRanks[findings[i].Severity] < severityRanks[findings[j].Severity]
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// lineRange is a range of lines of a file, from 1, inclusive.
+type lineRange struct {
+	start, end int
+}
+
+// fileLine maps a line number reported by the LLM for the chunk to a line of
+// the file. Numbers outside the chunk are taken as counted from the start of
+// the chunk when they fit in it, and as the whole file otherwise.
+func (r lineRange) fileLine(line int) int {
+	switch {
+	case line >= r.start && line <= r.end:
+		return line
+	case line >= 1 && line <= r.end-r.start+1:
+		return r.start + line - 1
+	}
+	return 0
+}
+
+// numberLines returns the lines of a range prefixed with their numbers.
+func numberLines(lines []string, start, end int) string {
+	width := len(fmt.Sprint(end))
+	var b strings.Builder
+	for n := start; n <= end; n++ {
+		fmt.Fprintf(&b, "%*d | %s\n", width, n, lines[n-1])
+	}
+	return b.String()
+}
+
+// reviewChunks splits code into ranges of about size lines: between the
+// top-level declarations of Go code that parses, and at blank lines
+// otherwise.
+func reviewChunks(code string, lines []string, language string, size int) []lineRange {
+	var breaks []int
+	switch strings.ToLower(strings.TrimSpace(language)) {
+	case "go", "golang":
+		breaks = goDeclarationStarts(code)
+	}
+	if breaks == nil {
+		breaks = blankLineBreaks(lines)
+	}
+	return groupLines(len(lines), breaks, size)
+}
+
+// goDeclarationStarts returns the first line of each top-level declaration of
+// Go code, including its doc comment, or nil if the code does not parse.
+// Code without a package clause, such as a single function, is parsed as if
+// it had one.
+func goDeclarationStarts(code string) []int {
+	offset := 0
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, parser.ParseComments)
+	if err != nil {
+		offset = 1
+		if file, err = parser.ParseFile(fset, "", "package p\n"+code, parser.ParseComments); err != nil {
+			return nil
+		}
+	}
+	var starts []int
+	for _, decl := range file.Decls {
+		pos := decl.Pos()
+		var doc *ast.CommentGroup
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			doc = d.Doc
+		case *ast.GenDecl:
+			doc = d.Doc
+		}
+		if doc != nil {
+			pos = doc.Pos()
+		}
+		starts = append(starts, fset.Position(pos).Line-offset)
+	}
+	return starts
+}
+
+// blankLineBreaks returns the lines following a blank line.
+func blankLineBreaks(lines []string) []int {
+	var breaks []int
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i-1]) == "" && strings.TrimSpace(lines[i]) != "" {
+			breaks = append(breaks, i+1)
+		}
+	}
+	return breaks
+}
+
+// groupLines splits lines 1 to total into ranges of at most size lines,
+// ending them before one of breaks when possible. A stretch without a break
+// longer than size is cut every size lines.
+func groupLines(total int, breaks []int, size int) []lineRange {
+	var ranges []lineRange
+	start := 1
+	for start <= total {
+		end := min(start+size-1, total)
+		if end < total {
+			// End before the last break within reach, if any.
+			for i := len(breaks) - 1; i >= 0; i-- {
+				if breaks[i] > start && breaks[i] <= end+1 {
+					end = breaks[i] - 1
+					break
+				}
+			}
+		}
+		ranges = append(ranges, lineRange{start: start, end: end})
+		start = end + 1
+	}
+	return ranges
+}
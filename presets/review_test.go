@@ -0,0 +1,74 @@
+package presets
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reviewGoSource = `package store
+
+import "database/sql"
+
+// Find returns the name of a user.
+func Find(db *sql.DB, id string) string {
+	var name string
+	db.QueryRow("SELECT name FROM users WHERE id = " + id).Scan(&name)
+	return name
+}
+
+// Count returns the number of users.
+func Count(db *sql.DB) int {
+	var n int
+	db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n)
+	return n
+}`
+
+func TestGoDeclarationStarts(t *testing.T) {
+	assert.Equal(t, []int{3, 5, 12}, goDeclarationStarts(reviewGoSource))
+	assert.Equal(t, []int{1, 3}, goDeclarationStarts("func a() {}\n\n// b does nothing.\nfunc b() {}"), "code without a package clause")
+	assert.Nil(t, goDeclarationStarts("func broken( {"))
+}
+
+func TestGroupLines(t *testing.T) {
+	assert.Equal(t, []lineRange{{1, 11}, {12, 17}}, groupLines(17, []int{3, 5, 12}, 12))
+	assert.Equal(t, []lineRange{{1, 4}, {5, 8}, {9, 10}}, groupLines(10, nil, 4), "stretches without breaks are cut")
+	assert.Equal(t, []lineRange{{1, 17}}, groupLines(17, []int{3, 5, 12}, 300))
+}
+
+func TestReviewCode(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		`[{"severity": "minor", "line": 6, "message": "id 应为 int64", "suggested_fix": ""},
+		  {"severity": "critical", "line": 8, "message": "SQL 注入", "suggested_fix": "db.QueryRow(\"SELECT name FROM users WHERE id = ?\", id)"}]`,
+		`[{"severity": "major", "line": 4, "message": "忽略了 Scan 的错误", "suggested_fix": "if err := ...; err != nil { return 0, err }"}]`,
+	}}
+	findings, err := ReviewCode(context.Background(), l, reviewGoSource, "go", WithFocus("security", "error handling"), WithReviewChunkLines(12))
+	require.NoError(t, err)
+	assert.Equal(t, []Finding{
+		{Severity: SeverityCritical, Line: 8, Message: "SQL 注入", SuggestedFix: `db.QueryRow("SELECT name FROM users WHERE id = ?", id)`},
+		{Severity: SeverityMajor, Line: 15, Message: "忽略了 Scan 的错误", SuggestedFix: "if err := ...; err != nil { return 0, err }"},
+		{Severity: SeverityMinor, Line: 6, Message: "id 应为 int64"},
+	}, findings, "line 4 of the second chunk maps to line 15 of the file")
+
+	require.Len(t, l.prompts, 2)
+	assert.Contains(t, l.prompts[0].Input, " 8 | \tdb.QueryRow(")
+	assert.NotContains(t, l.prompts[0].Input, "func Count")
+	assert.Contains(t, l.prompts[1].Input, "12 | // Count returns")
+	directives := strings.Join(l.prompts[1].Directives, "\n")
+	assert.Contains(t, directives, "只关注：security、error handling")
+	assert.Contains(t, directives, "第 2/2 部分")
+}
+
+func TestReviewCode_NoFindings(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"[]"}}
+	findings, err := ReviewCode(context.Background(), l, "def add(a, b):\n    return a + b\n", "python")
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+	assert.NotNil(t, findings)
+
+	_, err = ReviewCode(context.Background(), l, " ", "python")
+	assert.ErrorContains(t, err, "code cannot be empty")
+}
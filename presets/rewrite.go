@@ -0,0 +1,191 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// defaultRewriteChunkTokens is the token budget of a chunk sent to the LLM by
+// Rewrite.
+const defaultRewriteChunkTokens = 1500
+
+// RewriteOption configures Rewrite.
+type RewriteOption func(*rewriteConfig)
+
+type rewriteConfig struct {
+	tone               string
+	audience           string
+	maxWords           int
+	preserveFormatting bool
+}
+
+// WithTone sets the tone of the rewritten text, such as "formal", "casual",
+// "friendly" or "concise".
+func WithTone(tone string) RewriteOption {
+	return func(c *rewriteConfig) {
+		c.tone = tone
+	}
+}
+
+// WithTargetAudience sets who the rewritten text is for, such as "初学者" or
+// "senior engineers".
+func WithTargetAudience(audience string) RewriteOption {
+	return func(c *rewriteConfig) {
+		c.audience = audience
+	}
+}
+
+// WithMaxLength limits the rewritten text to about the given number of words,
+// counting each Chinese character as a word. Long texts are rewritten in
+// chunks, each limited to its share of the words.
+func WithMaxLength(words int) RewriteOption {
+	return func(c *rewriteConfig) {
+		c.maxWords = words
+	}
+}
+
+// WithPreserveFormatting keeps the Markdown structure of the text, such as
+// headings, lists, tables and links, and rewrites only the prose in it.
+func WithPreserveFormatting() RewriteOption {
+	return func(c *rewriteConfig) {
+		c.preserveFormatting = true
+	}
+}
+
+// Rewrite rewrites text in another style, keeping its meaning and language,
+// for instance to polish documentation. Texts longer than about 1500 tokens
+// are split on paragraph boundaries, never inside a fenced code block, and
+// rewritten chunk by chunk. Fenced code blocks must come back byte for byte;
+// if the LLM changes one, the chunk is rewritten once more, and Rewrite fails
+// if the code blocks are still changed.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for rewriting
+//   - text: The text to rewrite
+//   - opts: Optional rewrite options such as WithTone and WithMaxLength
+//
+// Returns:
+//   - string: The rewritten text, chunks joined by blank lines
+//   - error: Any error encountered, including code blocks changed by the LLM
+//
+// Example:
+//
+//	polished, err := Rewrite(ctx, llm, draft,
+//	    WithTone("formal"),
+//	    WithTargetAudience("初次使用本库的开发者"),
+//	    WithPreserveFormatting(),
+//	)
+func Rewrite(ctx context.Context, l gollm.LLM, text string, opts ...RewriteOption) (string, error) {
+	if l == nil {
+		return "", fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("text cannot be empty")
+	}
+	cfg := &rewriteConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.maxWords < 0 {
+		return "", fmt.Errorf("max length must not be negative, got %d", cfg.maxWords)
+	}
+
+	chunks := splitParagraphs(text, defaultRewriteChunkTokens)
+	total := estimateTokens(text)
+	rewritten := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		maxWords := 0
+		if cfg.maxWords > 0 {
+			maxWords = max(1, cfg.maxWords*estimateTokens(chunk)/max(1, total))
+		}
+		result, err := rewriteChunk(ctx, l, rewritePrompt(chunk, cfg, maxWords), chunk)
+		if err != nil {
+			return "", fmt.Errorf("failed to rewrite chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		rewritten = append(rewritten, result)
+	}
+	return strings.Join(rewritten, "\n\n"), nil
+}
+
+// rewriteChunk generates the rewrite of chunk and checks that its code blocks
+// are unchanged, asking once more if they are not.
+func rewriteChunk(ctx context.Context, l gollm.LLM, prompt *gollm.Prompt, chunk string) (string, error) {
+	response, err := l.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	response = strings.TrimSpace(response)
+	want := codeBlocks(chunk)
+	if slices.Equal(codeBlocks(response), want) {
+		return response, nil
+	}
+
+	l.GetLogger().Warn("Rewrite changed code blocks, retrying")
+	retry := *prompt
+	retry.Directives = append(append([]string(nil), prompt.Directives...),
+		"上一次的改写修改了代码块。代码块（包括 ``` 标记行）必须与原文逐字节一致，请重新改写：\n"+response)
+	response, err = l.Generate(ctx, &retry)
+	if err != nil {
+		return "", err
+	}
+	response = strings.TrimSpace(response)
+	if !slices.Equal(codeBlocks(response), want) {
+		return "", fmt.Errorf("code blocks changed after retry")
+	}
+	return response, nil
+}
+
+// rewritePrompt builds the prompt rewriting one chunk in at most maxWords
+// words, or any number if maxWords is 0.
+func rewritePrompt(chunk string, cfg *rewriteConfig, maxWords int) *gollm.Prompt {
+	directives := []string{
+		"改写原文，保持原意和原文的语言，不要增删事实",
+		"代码块（``` 之间的内容）必须与原文逐字节一致，行内代码和 URL 保持原样",
+		"只输出改写后的文本，不要添加解释或说明",
+	}
+	if cfg.tone != "" {
+		directives = append(directives, "改写后的语气："+cfg.tone)
+	}
+	if cfg.audience != "" {
+		directives = append(directives, "目标读者："+cfg.audience)
+	}
+	if maxWords > 0 {
+		directives = append(directives, fmt.Sprintf("改写后不超过 %d 个词，中文每个汉字算一个词", maxWords))
+	}
+	if cfg.preserveFormatting {
+		directives = append(directives, "保留 Markdown 结构：标题、列表、表格、链接和强调标记保持原样，只改写其中的文字")
+	}
+	return gollm.NewPrompt(chunk, gollm.WithDirectives(directives...))
+}
+
+// codeBlocks returns the fenced code blocks of text, fences included, in
+// order.
+func codeBlocks(text string) []string {
+	var blocks []string
+	var current []string
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		fence := strings.HasPrefix(strings.TrimSpace(line), "```")
+		if inFence || fence {
+			current = append(current, line)
+		}
+		if fence {
+			if inFence {
+				blocks = append(blocks, strings.Join(current, "\n"))
+				current = nil
+			}
+			inFence = !inFence
+		}
+	}
+	if inFence {
+		blocks = append(blocks, strings.Join(current, "\n"))
+	}
+	return blocks
+}
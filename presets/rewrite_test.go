@@ -0,0 +1,63 @@
+package presets
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rewriteDoc = "这个函数可以用来算东西。\n\n```go\nsum := Add(1, 2)\n```"
+
+func TestRewrite(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"本函数用于执行计算。\n\n```go\nsum := Add(1, 2)\n```\n"}}
+
+	rewritten, err := Rewrite(context.Background(), l, rewriteDoc,
+		WithTone("formal"),
+		WithTargetAudience("初学者"),
+		WithMaxLength(40),
+		WithPreserveFormatting(),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "本函数用于执行计算。\n\n```go\nsum := Add(1, 2)\n```", rewritten)
+	directives := l.prompts[0].Directives
+	assert.Contains(t, directives, "改写后的语气：formal")
+	assert.Contains(t, directives, "目标读者：初学者")
+	assert.Contains(t, directives, "改写后不超过 40 个词，中文每个汉字算一个词")
+	assert.Contains(t, directives, "保留 Markdown 结构：标题、列表、表格、链接和强调标记保持原样，只改写其中的文字")
+}
+
+func TestRewrite_ChangedCodeBlocks(t *testing.T) {
+	mangled := "本函数用于执行计算。\n\n```go\nsum := Add(1, 2) // 求和\n```"
+	fixed := "本函数用于执行计算。\n\n```go\nsum := Add(1, 2)\n```"
+
+	l := &scriptedLLM{responses: []string{mangled, fixed}}
+	rewritten, err := Rewrite(context.Background(), l, rewriteDoc)
+	require.NoError(t, err)
+	assert.Equal(t, fixed, rewritten)
+	require.Len(t, l.prompts, 2)
+	assert.Contains(t, l.prompts[1].Directives[len(l.prompts[1].Directives)-1], "代码块（包括 ``` 标记行）必须与原文逐字节一致")
+
+	l = &scriptedLLM{responses: []string{mangled, "本函数用于执行计算。"}}
+	_, err = Rewrite(context.Background(), l, rewriteDoc)
+	assert.ErrorContains(t, err, "code blocks changed after retry")
+}
+
+func TestRewrite_Chunks(t *testing.T) {
+	first := strings.Repeat("甲", 1000)
+	second := strings.Repeat("乙", 1000)
+	l := &scriptedLLM{responses: []string{"甲", "乙"}}
+
+	rewritten, err := Rewrite(context.Background(), l, first+"\n\n"+second, WithMaxLength(100))
+	require.NoError(t, err)
+	assert.Equal(t, "甲\n\n乙", rewritten)
+	require.Len(t, l.prompts, 2)
+	assert.Contains(t, l.prompts[1].Directives, "改写后不超过 50 个词，中文每个汉字算一个词", "each chunk gets its share of the words")
+}
+
+func TestCodeBlocks(t *testing.T) {
+	text := "说明\n```sh\ngo test ./...\n```\n中间\n  ```\nindented\n  ```\n```unterminated"
+	assert.Equal(t, []string{"```sh\ngo test ./...\n```", "  ```\nindented\n  ```", "```unterminated"}, codeBlocks(text))
+}
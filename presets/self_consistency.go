@@ -0,0 +1,305 @@
+// Package presets provides a collection of high-level tools and utilities for
+// enhancing Language Learning Model interactions with specific reasoning patterns
+// and problem-solving strategies.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// finalAnswerPattern matches the line ChainOfThoughtSC asks each sample to end
+// with, e.g. "最终答案：194".
+var finalAnswerPattern = regexp.MustCompile(`(?m)^\W*最终答案\W*[：:]\s*(.+?)\s*$`)
+
+// finalAnswerDirective asks a sample to end with a line finalAnswerPattern
+// matches.
+const finalAnswerDirective = "推理结束后，在最后一行按以下格式写出最终答案，答案尽量简短：\n最终答案：<答案>"
+
+// SelfConsistencyResult is the result of ChainOfThoughtSC and
+// QuestionAnswerConsistent.
+type SelfConsistencyResult struct {
+	Answer      string           // The majority answer, as first written by a sample
+	Votes       map[string]int   // Number of samples per answer, keyed like Answer
+	Confidence  float64          // Fraction of all samples, failed ones included, that gave Answer
+	Traces      []ReasoningTrace // Every sample, in order
+	Adjudicated bool             // Whether a tie was broken by an adjudication call
+	Usage       gollm.TokenUsage // Tokens used by all samples and the adjudication
+}
+
+// ReasoningTrace is one response sampled by ChainOfThoughtSC or
+// QuestionAnswerConsistent.
+type ReasoningTrace struct {
+	Reasoning string           // The full response
+	Answer    string           // The final answer, empty if the sample has none
+	Usage     gollm.TokenUsage // Tokens used by the sample
+	Err       error            // Why the sample failed, if it did
+}
+
+// SelfConsistencyOption configures ChainOfThoughtSC and
+// QuestionAnswerConsistent.
+type SelfConsistencyOption func(*selfConsistencyConfig)
+
+type selfConsistencyConfig struct {
+	temperature float64
+	concurrency int
+	promptOpts  []gollm.PromptOption
+	normalize   func(string) string
+}
+
+// WithSampleTemperature sets the temperature of each sample. The default is
+// 0.8, high enough for the samples to reason differently.
+func WithSampleTemperature(temperature float64) SelfConsistencyOption {
+	return func(c *selfConsistencyConfig) {
+		c.temperature = temperature
+	}
+}
+
+// WithSampleConcurrency sets how many samples are generated at once. The
+// default is 3.
+func WithSampleConcurrency(n int) SelfConsistencyOption {
+	return func(c *selfConsistencyConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithSamplePromptOptions applies prompt options, such as gollm.WithContext,
+// to the prompt of every sample, as the options of ChainOfThought do.
+func WithSamplePromptOptions(opts ...gollm.PromptOption) SelfConsistencyOption {
+	return func(c *selfConsistencyConfig) {
+		c.promptOpts = append(c.promptOpts, opts...)
+	}
+}
+
+// WithAnswerNormalizer sets the function that maps each final answer to the
+// form compared when voting: answers with the same normalized form vote
+// together. The default ignores case, spaces, Markdown emphasis and final
+// punctuation; a normalizer can, for example, parse numbers so that "1,000"
+// and "1000" agree, or map synonyms to one answer.
+func WithAnswerNormalizer(normalize func(answer string) string) SelfConsistencyOption {
+	return func(c *selfConsistencyConfig) {
+		c.normalize = normalize
+	}
+}
+
+// ChainOfThoughtSC answers question by self-consistency: it samples several
+// independent chains of thought, each ending with a "最终答案：" line, and
+// returns the answer most of them reach. Answers are compared ignoring case,
+// spaces, Markdown emphasis and final punctuation, unless WithAnswerNormalizer
+// says otherwise. If answers tie for the most votes, one more call
+// adjudicates between them. Samples that fail or give no final answer do not
+// vote; ChainOfThoughtSC fails only if none votes.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for generation
+//   - question: The question or problem to reason about
+//   - samples: The number of chains of thought to sample
+//   - opts: Optional options such as WithSampleTemperature
+//
+// Returns:
+//   - *SelfConsistencyResult: The majority answer, the votes, every trace and
+//     the total token usage
+//   - error: Any error encountered, including no sample giving an answer
+//
+// Example:
+//
+//	result, err := ChainOfThoughtSC(ctx, llm, "(17 * 6) + (23 * 4) 等于多少？", 5)
+//	fmt.Println(result.Answer, result.Votes) // 194 map[194:4 184:1]
+func ChainOfThoughtSC(ctx context.Context, l gollm.LLM, question string, samples int, opts ...SelfConsistencyOption) (*SelfConsistencyResult, error) {
+	prompt, err := chainOfThoughtTemplate.Execute(map[string]interface{}{
+		"Question": question,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute chain of thought template: %w", err)
+	}
+	return selfConsistent(ctx, l, question, prompt, samples, opts)
+}
+
+// QuestionAnswerConsistent answers question by self-consistency, as
+// ChainOfThoughtSC does, but with the prompt of QuestionAnswer: each sample
+// answers briefly and ends with a "最终答案：" line, and the answer most
+// samples give is returned, with the fraction of samples giving it as its
+// Confidence. Use WithAnswerNormalizer when answers can be written in more
+// than one way.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for generation
+//   - question: The question to be answered
+//   - samples: The number of answers to sample
+//   - opts: Optional options such as WithSampleTemperature and
+//     WithAnswerNormalizer
+//
+// Returns:
+//   - *SelfConsistencyResult: The majority answer, its confidence, the votes,
+//     every sample and the total token usage
+//   - error: Any error encountered, including no sample giving an answer
+//
+// Example:
+//
+//	result, err := QuestionAnswerConsistent(ctx, llm, "一年中哪个月份的天数最少？", 5,
+//	    WithAnswerNormalizer(func(answer string) string {
+//	        return strings.TrimSuffix(strings.TrimSpace(answer), "份")
+//	    }),
+//	)
+//	fmt.Println(result.Answer, result.Confidence) // 二月 0.8
+func QuestionAnswerConsistent(ctx context.Context, l gollm.LLM, question string, samples int, opts ...SelfConsistencyOption) (*SelfConsistencyResult, error) {
+	prompt, err := QuestionAnswerTemplate.Execute(map[string]interface{}{
+		"Question": question,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute question answer template: %w", err)
+	}
+	prompt.Apply(gollm.WithOutput("简短的解答，最后一行为最终答案"))
+	return selfConsistent(ctx, l, question, prompt, samples, opts)
+}
+
+// selfConsistent samples prompt, with the final answer directive, samples
+// times and votes on the final answers.
+func selfConsistent(ctx context.Context, l gollm.LLM, question string, prompt *gollm.Prompt, samples int, opts []SelfConsistencyOption) (*SelfConsistencyResult, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(question) == "" {
+		return nil, fmt.Errorf("question cannot be empty")
+	}
+	if samples < 1 {
+		return nil, fmt.Errorf("samples must be positive, got %d", samples)
+	}
+	cfg := &selfConsistencyConfig{temperature: 0.8, concurrency: 3, normalize: normalizeAnswer}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	ctx, recorder := gollm.WithUsageRecorder(ctx)
+	prompt.Apply(cfg.promptOpts...)
+	prompt.Apply(gollm.WithDirectives(finalAnswerDirective))
+
+	traces := sampleTraces(ctx, l, prompt, samples, cfg)
+	result := &SelfConsistencyResult{Votes: make(map[string]int), Traces: traces}
+	display := make(map[string]string)
+	counts := make(map[string]int)
+	var order []string
+	for _, trace := range traces {
+		if trace.Answer == "" {
+			continue
+		}
+		key := cfg.normalize(trace.Answer)
+		if _, ok := display[key]; !ok {
+			display[key] = trace.Answer
+			order = append(order, key)
+		}
+		counts[key]++
+		result.Votes[display[key]]++
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("none of %d samples gave a final answer: %w", samples, firstTraceError(traces))
+	}
+
+	var tied []string
+	for _, key := range order {
+		switch {
+		case len(tied) == 0 || counts[key] > counts[tied[0]]:
+			tied = []string{key}
+		case counts[key] == counts[tied[0]]:
+			tied = append(tied, key)
+		}
+	}
+	winner := tied[0]
+	if len(tied) > 1 {
+		var err error
+		winner, err = adjudicate(ctx, l, question, tied, display, traces, cfg.normalize)
+		if err != nil {
+			return nil, err
+		}
+		result.Adjudicated = true
+	}
+	result.Answer = display[winner]
+	result.Confidence = float64(counts[winner]) / float64(samples)
+	result.Usage = recorder.Usage()
+	return result, nil
+}
+
+// sampleTraces generates the samples, at most cfg.concurrency at once.
+func sampleTraces(ctx context.Context, l gollm.LLM, prompt *gollm.Prompt, samples int, cfg *selfConsistencyConfig) []ReasoningTrace {
+	traces := make([]ReasoningTrace, samples)
+	sem := make(chan struct{}, max(1, cfg.concurrency))
+	var wg sync.WaitGroup
+	for i := range traces {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			sampleCtx, recorder := gollm.WithUsageRecorder(ctx)
+			response, err := l.Generate(sampleCtx, prompt, gollm.WithTemperature(cfg.temperature))
+			traces[i] = ReasoningTrace{Reasoning: response, Usage: recorder.Usage(), Err: err}
+			if err != nil {
+				l.GetLogger().Warn("Self-consistency sample failed", "sample", i+1, "error", err)
+				return
+			}
+			if m := finalAnswerPattern.FindAllStringSubmatch(response, -1); len(m) > 0 {
+				traces[i].Answer = m[len(m)-1][1]
+			} else {
+				l.GetLogger().Warn("Self-consistency sample gave no final answer", "sample", i+1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return traces
+}
+
+// adjudicate asks the LLM which of the tied answers is right and returns its
+// key.
+func adjudicate(ctx context.Context, l gollm.LLM, question string, tied []string, display map[string]string, traces []ReasoningTrace, normalize func(string) string) (string, error) {
+	var candidates strings.Builder
+	for i, key := range tied {
+		fmt.Fprintf(&candidates, "答案 %d：%s\n", i+1, display[key])
+		for _, trace := range traces {
+			if trace.Answer != "" && normalize(trace.Answer) == key {
+				fmt.Fprintf(&candidates, "推理过程：\n%s\n\n", trace.Reasoning)
+				break
+			}
+		}
+	}
+	prompt := gollm.NewPrompt(fmt.Sprintf("问题：%s\n\n%s", question, candidates.String()),
+		gollm.WithDirectives(
+			"以上答案的票数相同，请检查各自的推理过程，判断哪个答案正确",
+			"只输出正确答案的编号，不要输出其他内容",
+		),
+	)
+	response, err := l.Generate(ctx, prompt, gollm.WithTemperature(0))
+	if err != nil {
+		return "", fmt.Errorf("failed to adjudicate tied answers: %w", err)
+	}
+	choice, err := strconv.Atoi(strings.Trim(strings.TrimSpace(response), "答案 。.："))
+	if err != nil || choice < 1 || choice > len(tied) {
+		return "", fmt.Errorf("adjudication chose no tied answer: %q", response)
+	}
+	return tied[choice-1], nil
+}
+
+// normalizeAnswer returns the form of answer compared when voting, unless
+// WithAnswerNormalizer sets another.
+func normalizeAnswer(answer string) string {
+	answer = strings.NewReplacer("**", "", "`", "", "$", "").Replace(answer)
+	answer = strings.TrimRight(strings.TrimSpace(answer), "。.！!")
+	return strings.ToLower(strings.Join(strings.Fields(answer), ""))
+}
+
+// firstTraceError returns the error of the first failed trace, or an error
+// saying that the samples gave no final answer.
+func firstTraceError(traces []ReasoningTrace) error {
+	for _, trace := range traces {
+		if trace.Err != nil {
+			return trace.Err
+		}
+	}
+	return fmt.Errorf("no \"最终答案：\" line in any response")
+}
@@ -0,0 +1,117 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/llm"
+)
+
+// meteredLLM is a scriptedLLM that reports 10 input and 5 output tokens per
+// call.
+type meteredLLM struct {
+	scriptedLLM
+}
+
+func (m *meteredLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	gollm.AddTokenUsage(ctx, 10, 5)
+	return m.scriptedLLM.Generate(ctx, prompt, opts...)
+}
+
+func TestChainOfThoughtSC(t *testing.T) {
+	l := &meteredLLM{scriptedLLM{responses: []string{
+		"1. 17*6=102\n2. 23*4=92\n3. 102+92=194\n最终答案：194",
+		"1. 先算乘法\n2. 相加\n**最终答案**: 194。",
+		"1. 17*6=102\n2. 23*4=82\n最终答案：184",
+		"我不确定。",
+	}}}
+
+	result, err := ChainOfThoughtSC(context.Background(), l, "(17 * 6) + (23 * 4) 等于多少？", 4,
+		WithSampleConcurrency(1), WithSampleTemperature(0.9))
+	require.NoError(t, err)
+	assert.Equal(t, "194", result.Answer)
+	assert.Equal(t, map[string]int{"194": 2, "184": 1}, result.Votes)
+	assert.Equal(t, 0.5, result.Confidence)
+	assert.False(t, result.Adjudicated)
+	require.Len(t, result.Traces, 4)
+	assert.Equal(t, "194。", result.Traces[1].Answer)
+	assert.Empty(t, result.Traces[3].Answer, "a sample without a final answer does not vote")
+	assert.Equal(t, gollm.TokenUsage{InputTokens: 40, OutputTokens: 20}, result.Usage)
+	assert.Equal(t, gollm.TokenUsage{InputTokens: 10, OutputTokens: 5}, result.Traces[0].Usage)
+	assert.Equal(t, 0.9, *l.configs[0].Temperature)
+	assert.Contains(t, l.prompts[0].Directives[len(l.prompts[0].Directives)-1], "最终答案：<答案>")
+}
+
+func TestChainOfThoughtSC_Tie(t *testing.T) {
+	l := &meteredLLM{scriptedLLM{responses: []string{
+		"推理 A\n最终答案：12",
+		"推理 B\n最终答案：13",
+		"2",
+	}}}
+
+	result, err := ChainOfThoughtSC(context.Background(), l, "问题", 2, WithSampleConcurrency(1))
+	require.NoError(t, err)
+	assert.Equal(t, "13", result.Answer)
+	assert.True(t, result.Adjudicated)
+	assert.Contains(t, l.prompts[2].Input, "答案 1：12\n推理过程：\n推理 A")
+	assert.Contains(t, l.prompts[2].Input, "答案 2：13")
+	assert.Equal(t, 0.0, *l.configs[2].Temperature)
+	assert.Equal(t, gollm.TokenUsage{InputTokens: 30, OutputTokens: 15}, result.Usage, "the adjudication is counted")
+}
+
+// failingLLM fails every call.
+type failingLLM struct {
+	scriptedLLM
+}
+
+func (f *failingLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	return "", errors.New("provider down")
+}
+
+func TestChainOfThoughtSC_NoAnswers(t *testing.T) {
+	_, err := ChainOfThoughtSC(context.Background(), &failingLLM{}, "问题", 3)
+	assert.ErrorContains(t, err, "provider down")
+
+	_, err = ChainOfThoughtSC(context.Background(), &scriptedLLM{}, "问题", 0)
+	assert.ErrorContains(t, err, "samples must be positive")
+}
+
+func TestQuestionAnswerConsistent(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"二月只有 28 或 29 天。\n最终答案：二月",
+		"最终答案：2月份",
+		"最终答案：February",
+		"最终答案：二月",
+		"抱歉，我无法回答。",
+	}}
+	months := map[string]string{"2月": "二月", "february": "二月"}
+
+	result, err := QuestionAnswerConsistent(context.Background(), l, "一年中哪个月份的天数最少？", 5,
+		WithSampleConcurrency(1),
+		WithAnswerNormalizer(func(answer string) string {
+			answer = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(answer), "份"))
+			if month, ok := months[answer]; ok {
+				return month
+			}
+			return answer
+		}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "二月", result.Answer)
+	assert.Equal(t, map[string]int{"二月": 4}, result.Votes)
+	assert.Equal(t, 0.8, result.Confidence, "the sample without an answer counts against the confidence")
+	assert.Contains(t, l.prompts[0].Input, "一年中哪个月份的天数最少？")
+	assert.Contains(t, l.prompts[0].Directives, "提供清晰简洁的答案")
+	assert.Equal(t, 0.8, *l.configs[0].Temperature)
+
+	l = &scriptedLLM{responses: []string{"最终答案：2月份", "最终答案：二月", "最终答案：二月"}}
+	result, err = QuestionAnswerConsistent(context.Background(), l, "一年中哪个月份的天数最少？", 3, WithSampleConcurrency(1))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"2月份": 1, "二月": 2}, result.Votes, "by default only formatting is ignored")
+	assert.InDelta(t, 2.0/3, result.Confidence, 1e-9)
+}
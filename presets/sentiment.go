@@ -0,0 +1,148 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// defaultMaxSentimentLength is the default length, in characters, above which
+// AnalyzeSentiment truncates the text.
+const defaultMaxSentimentLength = 8000
+
+// truncationMarker replaces the text dropped from the middle of a long text.
+const truncationMarker = "\n[...]\n"
+
+// Sentiment polarities.
+const (
+	PolarityPositive = "positive"
+	PolarityNegative = "negative"
+	PolarityNeutral  = "neutral"
+	PolarityMixed    = "mixed"
+)
+
+// Sentiment is the result of AnalyzeSentiment.
+type Sentiment struct {
+	Polarity string            `json:"polarity" validate:"required,oneof=positive negative neutral mixed"`
+	Score    float64           `json:"score" validate:"gte=-1,lte=1"`
+	Aspects  []AspectSentiment `json:"aspects,omitempty" validate:"dive"`
+}
+
+// AspectSentiment is the sentiment of a text towards one aspect, such as the
+// price or the service in a product review.
+type AspectSentiment struct {
+	Aspect   string  `json:"aspect" validate:"required"`
+	Polarity string  `json:"polarity" validate:"required,oneof=positive negative neutral mixed"`
+	Score    float64 `json:"score" validate:"gte=-1,lte=1"`
+}
+
+// SentimentOption configures AnalyzeSentiment.
+type SentimentOption func(*sentimentConfig)
+
+type sentimentConfig struct {
+	aspects   []string
+	maxLength int
+}
+
+// WithAspects makes AnalyzeSentiment also rate the sentiment towards each of
+// the given aspects.
+func WithAspects(aspects ...string) SentimentOption {
+	return func(c *sentimentConfig) {
+		c.aspects = append(c.aspects, aspects...)
+	}
+}
+
+// WithMaxSentimentLength sets the length, in characters, above which
+// AnalyzeSentiment keeps only the beginning and end of the text. The default
+// is 8000.
+func WithMaxSentimentLength(n int) SentimentOption {
+	return func(c *sentimentConfig) {
+		c.maxLength = n
+	}
+}
+
+// AnalyzeSentiment rates the overall sentiment of text and, with WithAspects,
+// its sentiment towards specific aspects. It uses ExtractStructuredData, so
+// the response is validated like any other extraction. Texts longer than the
+// maximum length (see WithMaxSentimentLength) lose their middle, with a
+// warning logged through the LLM's logger.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for the analysis
+//   - text: The text to analyze, such as a review
+//   - opts: Optional sentiment options
+//
+// Returns:
+//   - *Sentiment: Polarity and score (-1 to 1) of the text, and the aspect
+//     sentiments in the order of WithAspects
+//   - error: Any error encountered, including an aspect that was not asked for
+//
+// Example:
+//
+//	s, err := AnalyzeSentiment(ctx, llm, review, WithAspects("价格", "服务", "质量"))
+//	fmt.Println(s.Polarity, s.Score)
+//	for _, a := range s.Aspects {
+//	    fmt.Printf("%s: %s (%.1f)\n", a.Aspect, a.Polarity, a.Score)
+//	}
+func AnalyzeSentiment(ctx context.Context, l gollm.LLM, text string, opts ...SentimentOption) (*Sentiment, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	cfg := &sentimentConfig{maxLength: defaultMaxSentimentLength}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if runes := []rune(text); cfg.maxLength > 0 && len(runes) > cfg.maxLength {
+		head := cfg.maxLength / 2
+		text = string(runes[:head]) + truncationMarker + string(runes[len(runes)-(cfg.maxLength-head):])
+		l.GetLogger().Warn("Text truncated for sentiment analysis", "length", len(runes), "max_length", cfg.maxLength)
+	}
+
+	directives := []string{
+		"polarity 为文本的整体情感倾向：positive、negative、neutral 或 mixed（同时包含明显的正面和负面情感）",
+		"score 为 -1 到 1 之间的情感分数，-1 最负面，1 最正面，0 为中性",
+	}
+	if len(cfg.aspects) > 0 {
+		directives = append(directives,
+			fmt.Sprintf("aspects 中依次给出文本对以下方面的情感：%s；aspect 使用给定的名称", strings.Join(cfg.aspects, "、")),
+			"文本未提及的方面，polarity 为 neutral，score 为 0",
+		)
+	} else {
+		directives = append(directives, "aspects 留空")
+	}
+
+	result, err := ExtractStructuredData[Sentiment](ctx, l, text, WithPromptOptions(gollm.WithDirectives(directives...)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze sentiment: %w", err)
+	}
+	if len(cfg.aspects) == 0 {
+		result.Aspects = nil
+		return result, nil
+	}
+
+	order := make(map[string]int, len(cfg.aspects))
+	for i, aspect := range cfg.aspects {
+		order[aspect] = i
+	}
+	for i, a := range result.Aspects {
+		aspect, ok := matchCategory(cfg.aspects, a.Aspect)
+		if !ok {
+			return nil, fmt.Errorf("aspect %q was not asked for", a.Aspect)
+		}
+		result.Aspects[i].Aspect = aspect
+	}
+	sort.SliceStable(result.Aspects, func(i, j int) bool {
+		return order[result.Aspects[i].Aspect] < order[result.Aspects[j].Aspect]
+	})
+	return result, nil
+}
@@ -0,0 +1,87 @@
+package presets
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// warnLogger records the messages of Warn calls.
+type warnLogger struct {
+	utils.Logger
+	warnings []string
+}
+
+func (w *warnLogger) Warn(msg string, keysAndValues ...interface{}) {
+	w.warnings = append(w.warnings, msg)
+}
+
+// loggingLLM is a scriptedLLM with a warnLogger.
+type loggingLLM struct {
+	*scriptedLLM
+	logger *warnLogger
+}
+
+func (l *loggingLLM) GetLogger() utils.Logger {
+	return l.logger
+}
+
+func TestAnalyzeSentiment(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"是", `{
+		"polarity": "mixed",
+		"score": 0.2,
+		"aspects": [
+			{"aspect": "服务", "polarity": "negative", "score": -0.6},
+			{"aspect": "价格", "polarity": "positive", "score": 0.8},
+			{"aspect": "质量", "polarity": "neutral", "score": 0}
+		]
+	}`}}
+
+	s, err := AnalyzeSentiment(context.Background(), l, "东西便宜，但客服态度很差。", WithAspects("价格", "服务", "质量"))
+	require.NoError(t, err)
+	assert.Equal(t, &Sentiment{
+		Polarity: PolarityMixed,
+		Score:    0.2,
+		Aspects: []AspectSentiment{
+			{Aspect: "价格", Polarity: PolarityPositive, Score: 0.8},
+			{Aspect: "服务", Polarity: PolarityNegative, Score: -0.6},
+			{Aspect: "质量", Polarity: PolarityNeutral, Score: 0},
+		},
+	}, s)
+	assert.Contains(t, l.prompts[1].Directives, "aspects 中依次给出文本对以下方面的情感：价格、服务、质量；aspect 使用给定的名称")
+}
+
+func TestAnalyzeSentiment_Validation(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"是", `{"polarity": "happy", "score": 1.5}`, `{"polarity": "positive", "score": 1}`}}
+	s, err := AnalyzeSentiment(context.Background(), l, "太棒了！")
+	require.NoError(t, err)
+	assert.Equal(t, PolarityPositive, s.Polarity, "invalid responses are corrected like any extraction")
+	assert.Contains(t, l.prompts[2].Input, "oneof")
+
+	l = &scriptedLLM{responses: []string{"是", `{"polarity": "positive", "score": 0.9, "aspects": [{"aspect": "物流", "polarity": "positive", "score": 0.9}]}`}}
+	_, err = AnalyzeSentiment(context.Background(), l, "物流很快。", WithAspects("价格"))
+	assert.ErrorContains(t, err, `aspect "物流" was not asked for`)
+
+	_, err = AnalyzeSentiment(context.Background(), &scriptedLLM{}, "  ")
+	assert.ErrorContains(t, err, "text cannot be empty")
+}
+
+func TestAnalyzeSentiment_TruncatesLongText(t *testing.T) {
+	logger := &warnLogger{}
+	l := &loggingLLM{
+		scriptedLLM: &scriptedLLM{responses: []string{"是", `{"polarity": "positive", "score": 0.5}`}},
+		logger:      logger,
+	}
+
+	text := "开头很好" + strings.Repeat("中", 100) + "结尾也好"
+	s, err := AnalyzeSentiment(context.Background(), l, text, WithMaxSentimentLength(8))
+	require.NoError(t, err)
+	assert.Equal(t, PolarityPositive, s.Polarity)
+	assert.Nil(t, s.Aspects)
+	assert.Contains(t, l.prompts[0].Input, "开头很好\n[...]\n结尾也好")
+	assert.Equal(t, []string{"Text truncated for sentiment analysis"}, logger.warnings)
+}
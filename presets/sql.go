@@ -0,0 +1,576 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// SQL dialects known to GenerateSQL and SchemaFromDB. Other names are passed
+// to the LLM as they are, without dialect checks.
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+	DialectMySQL    = "mysql"
+)
+
+// dialectNames are the names of the dialects in prompts.
+var dialectNames = map[string]string{
+	DialectSQLite:   "SQLite",
+	DialectPostgres: "PostgreSQL",
+	DialectMySQL:    "MySQL",
+}
+
+// writeKeywords start statements that modify the database or its schema.
+var writeKeywords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "UPSERT": true, "REPLACE": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true, "ATTACH": true, "DETACH": true, "PRAGMA": true,
+	"VACUUM": true, "REINDEX": true, "COPY": true, "CALL": true, "EXEC": true, "EXECUTE": true,
+}
+
+// DatabaseSchema describes the tables GenerateSQL may query.
+type DatabaseSchema struct {
+	Dialect string        // The SQL dialect, e.g. DialectSQLite
+	Tables  []TableSchema // The tables, in the order they are shown to the LLM
+}
+
+// TableSchema describes a table.
+type TableSchema struct {
+	Name        string         // Name of the table
+	Description string         // What the table holds, shown to the LLM; optional
+	Columns     []ColumnSchema // The columns, in order
+}
+
+// ColumnSchema describes a column.
+type ColumnSchema struct {
+	Name        string // Name of the column
+	Type        string // Declared type, e.g. "INTEGER" or "varchar(255)"
+	NotNull     bool   // Whether the column is NOT NULL
+	PrimaryKey  bool   // Whether the column is part of the primary key
+	Description string // What the column holds, shown to the LLM; optional
+}
+
+// String renders the schema as CREATE TABLE statements, with descriptions as
+// comments.
+func (s DatabaseSchema) String() string {
+	var b strings.Builder
+	for i, table := range s.Tables {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		if table.Description != "" {
+			fmt.Fprintf(&b, "-- %s\n", table.Description)
+		}
+		fmt.Fprintf(&b, "CREATE TABLE %s (", table.Name)
+		for j, column := range table.Columns {
+			fmt.Fprintf(&b, "\n  %s", column.Name)
+			if column.Type != "" {
+				b.WriteString(" " + column.Type)
+			}
+			if column.PrimaryKey {
+				b.WriteString(" PRIMARY KEY")
+			} else if column.NotNull {
+				b.WriteString(" NOT NULL")
+			}
+			if j < len(table.Columns)-1 {
+				b.WriteString(",")
+			}
+			if column.Description != "" {
+				b.WriteString(" -- " + column.Description)
+			}
+		}
+		b.WriteString("\n);")
+	}
+	return b.String()
+}
+
+// SchemaFromDB reads the schema of the tables of db, or only of the named
+// tables, by introspection: sqlite_master and PRAGMA table_info for SQLite,
+// information_schema for PostgreSQL (schema "public") and MySQL (the current
+// database). Primary keys are only read for SQLite. Add descriptions to the
+// result to help the LLM with cryptic names.
+//
+// Example:
+//
+//	schema, err := SchemaFromDB(ctx, db, DialectSQLite, "orders", "customers")
+func SchemaFromDB(ctx context.Context, db *sql.DB, dialect string, tables ...string) (DatabaseSchema, error) {
+	schema := DatabaseSchema{Dialect: dialect}
+	if len(tables) == 0 {
+		var err error
+		if tables, err = listTables(ctx, db, dialect); err != nil {
+			return schema, err
+		}
+	}
+	for _, name := range tables {
+		columns, err := tableColumns(ctx, db, dialect, name)
+		if err != nil {
+			return schema, err
+		}
+		if len(columns) == 0 {
+			return schema, fmt.Errorf("table %q not found", name)
+		}
+		schema.Tables = append(schema.Tables, TableSchema{Name: name, Columns: columns})
+	}
+	return schema, nil
+}
+
+// listTables returns the names of the tables of db.
+func listTables(ctx context.Context, db *sql.DB, dialect string) ([]string, error) {
+	var query string
+	switch dialect {
+	case DialectSQLite:
+		query = "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	case DialectPostgres:
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = 'public' AND table_type = 'BASE TABLE' ORDER BY table_name"
+	case DialectMySQL:
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name"
+	default:
+		return nil, fmt.Errorf("cannot introspect dialect %q; use sqlite, postgres or mysql", dialect)
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	return tables, nil
+}
+
+// tableColumns returns the columns of a table of db.
+func tableColumns(ctx context.Context, db *sql.DB, dialect, table string) ([]ColumnSchema, error) {
+	if dialect == DialectSQLite {
+		return sqliteColumns(ctx, db, table)
+	}
+	query := "SELECT column_name, data_type, is_nullable FROM information_schema.columns WHERE table_schema = 'public' AND table_name = $1 ORDER BY ordinal_position"
+	if dialect == DialectMySQL {
+		query = "SELECT column_name, column_type, is_nullable FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? ORDER BY ordinal_position"
+	}
+	rows, err := db.QueryContext(ctx, query, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %q: %w", table, err)
+	}
+	defer rows.Close()
+	var columns []ColumnSchema
+	for rows.Next() {
+		var column ColumnSchema
+		var nullable string
+		if err := rows.Scan(&column.Name, &column.Type, &nullable); err != nil {
+			return nil, fmt.Errorf("failed to read columns of table %q: %w", table, err)
+		}
+		column.NotNull = strings.EqualFold(nullable, "NO")
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %q: %w", table, err)
+	}
+	return columns, nil
+}
+
+// sqliteColumns returns the columns of a SQLite table.
+func sqliteColumns(ctx context.Context, db *sql.DB, table string) ([]ColumnSchema, error) {
+	// PRAGMA does not take parameters; quote the name as an identifier.
+	rows, err := db.QueryContext(ctx, `PRAGMA table_info("`+strings.ReplaceAll(table, `"`, `""`)+`")`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %q: %w", table, err)
+	}
+	defer rows.Close()
+	var columns []ColumnSchema
+	for rows.Next() {
+		var cid, notNull, pk int
+		var column ColumnSchema
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &column.Name, &column.Type, &notNull, &defaultValue, &pk); err != nil {
+			return nil, fmt.Errorf("failed to read columns of table %q: %w", table, err)
+		}
+		column.NotNull = notNull != 0
+		column.PrimaryKey = pk > 0
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read columns of table %q: %w", table, err)
+	}
+	return columns, nil
+}
+
+// SQLResult is the result of GenerateSQL.
+type SQLResult struct {
+	SQL         string // The query, validated
+	Explanation string // The LLM's explanation of the query
+}
+
+// SQLValidationError is returned by GenerateSQL when the query still fails
+// validation after the LLM was asked to fix it.
+type SQLValidationError struct {
+	SQL string // The last query generated
+	Err error  // Why it is invalid
+}
+
+// Error implements the error interface.
+func (e *SQLValidationError) Error() string {
+	return fmt.Sprintf("generated SQL is invalid: %v", e.Err)
+}
+
+// Unwrap returns why the query is invalid.
+func (e *SQLValidationError) Unwrap() error {
+	return e.Err
+}
+
+// SQLOption configures GenerateSQL.
+type SQLOption func(*sqlConfig)
+
+type sqlConfig struct {
+	allowWrites bool
+	validator   func(query string) error
+}
+
+// WithAllowWrites lets GenerateSQL return statements that modify the database,
+// such as INSERT or DROP. By default only SELECT queries are accepted.
+func WithAllowWrites() SQLOption {
+	return func(c *sqlConfig) {
+		c.allowWrites = true
+	}
+}
+
+// WithSQLValidator adds a check of the generated query, run after the
+// built-in ones, for instance a full parser for the dialect or an EXPLAIN
+// against the database. An error is shown to the LLM like those of the
+// built-in checks.
+func WithSQLValidator(validate func(query string) error) SQLOption {
+	return func(c *sqlConfig) {
+		c.validator = validate
+	}
+}
+
+// GenerateSQL translates a question in natural language into a single SQL
+// query over schema, and explains it. The query is checked before it is
+// returned: quotes, parentheses and comments must be closed, it must be a
+// single statement, it may only use tables of the schema, it must not use
+// syntax foreign to the schema's dialect, and, unless WithAllowWrites is
+// set, it must only read. A query failing a check is sent back to the LLM
+// with the error once; if the fix fails too, GenerateSQL returns a
+// *SQLValidationError. The checks are not a full parser; use
+// WithSQLValidator to add one, and run generated queries with a database user
+// whose permissions match what they may do.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for generation
+//   - question: The question to answer with a query
+//   - schema: The tables the query may use, e.g. from SchemaFromDB
+//   - opts: Optional options such as WithAllowWrites
+//
+// Returns:
+//   - *SQLResult: The query and its explanation
+//   - error: Any error encountered, including a *SQLValidationError
+//
+// Example:
+//
+//	schema, err := SchemaFromDB(ctx, db, DialectSQLite)
+//	result, err := GenerateSQL(ctx, llm, "上个月销售额最高的 5 个客户是谁？", schema)
+//	rows, err := db.QueryContext(ctx, result.SQL)
+func GenerateSQL(ctx context.Context, l gollm.LLM, question string, schema DatabaseSchema, opts ...SQLOption) (*SQLResult, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(question) == "" {
+		return nil, fmt.Errorf("question cannot be empty")
+	}
+	if len(schema.Tables) == 0 {
+		return nil, fmt.Errorf("schema has no tables")
+	}
+	cfg := &sqlConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	dialect := dialectNames[strings.ToLower(schema.Dialect)]
+	if dialect == "" {
+		dialect = schema.Dialect
+	}
+	if dialect == "" {
+		dialect = "标准 SQL"
+	}
+	directives := []string{
+		fmt.Sprintf("根据以下数据库结构，编写一条 %s 查询来回答问题：\n%s", dialect, schema),
+		"只使用上述结构中的表和列",
+		"先将 SQL 写在 ```sql 代码块中，再用一两句话解释查询的思路",
+	}
+	if cfg.allowWrites {
+		directives = append(directives, "只写一条语句")
+	} else {
+		directives = append(directives, "只写一条 SELECT 查询，不要修改数据或表结构")
+	}
+	input := "问题：" + question
+
+	var result *SQLResult
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if attempt > 0 {
+			input = fmt.Sprintf("问题：%s\n\n上次生成的 SQL 未通过校验：\n```sql\n%s\n```\n错误：%v\n\n请修正后重新输出。", question, result.SQL, err)
+		}
+		response, genErr := l.Generate(ctx, gollm.NewPrompt(input, gollm.WithDirectives(directives...)))
+		if genErr != nil {
+			return nil, fmt.Errorf("failed to generate SQL: %w", genErr)
+		}
+		result = splitSQLResponse(response)
+		if err = validateSQL(result.SQL, schema, cfg); err == nil {
+			return result, nil
+		}
+		l.GetLogger().Debug("Generated SQL is invalid", "sql", result.SQL, "error", err)
+	}
+	return nil, &SQLValidationError{SQL: result.SQL, Err: err}
+}
+
+// splitSQLResponse separates the SQL in the first code block of a response
+// from the explanation around it. A response without a code block is taken
+// as SQL.
+func splitSQLResponse(response string) *SQLResult {
+	response = strings.TrimSpace(response)
+	open := strings.Index(response, "```")
+	if open < 0 {
+		return &SQLResult{SQL: trimStatement(response)}
+	}
+	before, rest := response[:open], response[open:]
+	query := stripMarkdownFence(rest)
+	after := ""
+	if end := strings.Index(rest[3:], "```"); end >= 0 {
+		after = rest[3+end+3:]
+	}
+	explanation := strings.TrimSpace(strings.TrimSpace(before) + "\n" + strings.TrimSpace(after))
+	return &SQLResult{SQL: trimStatement(query), Explanation: explanation}
+}
+
+// trimStatement removes whitespace and a final semicolon from a statement.
+func trimStatement(query string) string {
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(query), ";"))
+}
+
+// sqlToken is a token of a SQL statement outside of strings and comments.
+type sqlToken struct {
+	text   string // The token; keywords and identifiers are upper-cased
+	word   bool   // Whether the token is a keyword or an identifier
+	quoted bool   // Whether the token is a quoted identifier
+}
+
+// tokenizeSQL splits query into tokens, skipping strings and comments. It
+// fails on unterminated strings, quoted identifiers and comments, and on
+// unbalanced parentheses.
+func tokenizeSQL(query string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	runes := []rune(query)
+	depth := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i+1 < len(runes) && (runes[i] != '*' || runes[i+1] != '/'); i++ {
+			}
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i++
+		case r == '\'' || r == '"' || r == '`':
+			start := i
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated %c quote starting at %q", r, truncateSQL(string(runes[start:])))
+				}
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						i++ // A doubled quote escapes itself
+						continue
+					}
+					break
+				}
+			}
+			if r != '\'' {
+				tokens = append(tokens, sqlToken{text: string(runes[start : i+1]), word: true, quoted: true})
+			}
+		case r == '(':
+			depth++
+			tokens = append(tokens, sqlToken{text: "("})
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses: unexpected )")
+			}
+			tokens = append(tokens, sqlToken{text: ")"})
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i+1 < len(runes) && (runes[i+1] == '_' || runes[i+1] == '$' || unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1])) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{text: strings.ToUpper(string(runes[start : i+1])), word: true})
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			tokens = append(tokens, sqlToken{text: "::"})
+			i++
+		default:
+			tokens = append(tokens, sqlToken{text: string(r)})
+		}
+	}
+	if depth > 0 {
+		return nil, fmt.Errorf("unbalanced parentheses: %d ( not closed", depth)
+	}
+	return tokens, nil
+}
+
+// truncateSQL shortens a fragment of SQL for an error message.
+func truncateSQL(fragment string) string {
+	if runes := []rune(fragment); len(runes) > 20 {
+		return string(runes[:20]) + "..."
+	}
+	return fragment
+}
+
+// validateSQL checks query against schema and the options.
+func validateSQL(query string, schema DatabaseSchema, cfg *sqlConfig) error {
+	if query == "" {
+		return fmt.Errorf("no SQL in the response")
+	}
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("no SQL in the response")
+	}
+	for _, token := range tokens {
+		if token.text == ";" {
+			return fmt.Errorf("only a single statement is allowed")
+		}
+	}
+	if err := checkDialect(tokens, strings.ToLower(schema.Dialect)); err != nil {
+		return err
+	}
+	if !cfg.allowWrites {
+		if first := tokens[0].text; first != "SELECT" && first != "WITH" {
+			return fmt.Errorf("only SELECT queries are allowed, got %s", first)
+		}
+		for i, token := range tokens {
+			// Keywords such as REPLACE are also function names.
+			if token.word && !token.quoted && writeKeywords[token.text] && (i+1 == len(tokens) || tokens[i+1].text != "(") {
+				return fmt.Errorf("only SELECT queries are allowed, found %s", token.text)
+			}
+		}
+	}
+	if err := checkTables(tokens, schema); err != nil {
+		return err
+	}
+	if cfg.validator != nil {
+		return cfg.validator(query)
+	}
+	return nil
+}
+
+// checkDialect rejects syntax that the dialect does not support.
+func checkDialect(tokens []sqlToken, dialect string) error {
+	for i, token := range tokens {
+		switch {
+		case token.quoted && token.text[0] == '`' && dialect == DialectPostgres:
+			return fmt.Errorf("backtick-quoted identifiers are not supported by PostgreSQL; use double quotes")
+		case token.text == "::" && (dialect == DialectSQLite || dialect == DialectMySQL):
+			return fmt.Errorf(":: casts are not supported by %s; use CAST", dialectNames[dialect])
+		case token.text == "ILIKE" && !token.quoted && (dialect == DialectSQLite || dialect == DialectMySQL):
+			return fmt.Errorf("ILIKE is not supported by %s; use LIKE with LOWER", dialectNames[dialect])
+		case token.text == "TOP" && i > 0 && tokens[i-1].text == "SELECT" && dialectNames[dialect] != "":
+			return fmt.Errorf("SELECT TOP is not supported by %s; use LIMIT", dialectNames[dialect])
+		}
+	}
+	return nil
+}
+
+// checkTables checks that the tables a query reads or writes, named after
+// FROM and JOIN in a query or subquery, INTO, or a leading UPDATE, are in the
+// schema or are common table expressions.
+func checkTables(tokens []sqlToken, schema DatabaseSchema) error {
+	known := make(map[string]bool)
+	for _, table := range schema.Tables {
+		known[strings.ToUpper(table.Name)] = true
+	}
+	// Common table expressions are named by "name AS (".
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i].word && tokens[i+1].text == "AS" && tokens[i+2].text == "(" {
+			known[identifierName(tokens[i])] = true
+		}
+	}
+	// queries tells, for each open parenthesis, whether it holds a query
+	// rather than, say, the arguments of EXTRACT(YEAR FROM created_at).
+	queries := []bool{true}
+	for i := 0; i+1 < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			next := tokens[i+1].text
+			queries = append(queries, next == "SELECT" || next == "WITH")
+			continue
+		case ")":
+			queries = queries[:len(queries)-1]
+			continue
+		case "FROM", "JOIN":
+			if !queries[len(queries)-1] || (i > 0 && tokens[i-1].text == "DISTINCT") {
+				continue
+			}
+		case "INTO":
+		case "UPDATE":
+			if i > 0 {
+				continue // ON CONFLICT DO UPDATE, FOR UPDATE
+			}
+		default:
+			continue
+		}
+		j := i + 1
+		if !tokens[j].word {
+			continue // A subquery
+		}
+		// Tables may be qualified by a schema: take the last name of a.b.
+		for j+2 < len(tokens) && tokens[j+1].text == "." && tokens[j+2].word {
+			j += 2
+		}
+		if tokens[i].text != "INTO" && j+1 < len(tokens) && tokens[j+1].text == "(" {
+			continue // A table-valued function
+		}
+		if name := identifierName(tokens[j]); !known[name] {
+			return fmt.Errorf("unknown table %s; available tables: %s", tokens[j].text, tableNames(schema))
+		}
+	}
+	return nil
+}
+
+// identifierName returns the upper-cased name of an identifier token,
+// without quotes.
+func identifierName(token sqlToken) string {
+	if token.quoted {
+		return strings.ToUpper(token.text[1 : len(token.text)-1])
+	}
+	return token.text
+}
+
+// tableNames lists the tables of schema for error messages.
+func tableNames(schema DatabaseSchema) string {
+	names := make([]string, len(schema.Tables))
+	for i, table := range schema.Tables {
+		names[i] = table.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,191 @@
+package presets
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sqliteSchema is the schema of a small SQLite shop database.
+var sqliteSchema = DatabaseSchema{
+	Dialect: DialectSQLite,
+	Tables: []TableSchema{
+		{Name: "customers", Columns: []ColumnSchema{
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "name", Type: "TEXT", NotNull: true},
+		}},
+		{Name: "orders", Description: "订单", Columns: []ColumnSchema{
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "customer_id", Type: "INTEGER", NotNull: true},
+			{Name: "amount", Type: "REAL", Description: "金额，单位元"},
+			{Name: "created_at", Type: "TEXT"},
+		}},
+	},
+}
+
+func TestDatabaseSchema_String(t *testing.T) {
+	assert.Equal(t, "CREATE TABLE customers (\n  id INTEGER PRIMARY KEY,\n  name TEXT NOT NULL\n);\n\n"+
+		"-- 订单\nCREATE TABLE orders (\n  id INTEGER PRIMARY KEY,\n  customer_id INTEGER NOT NULL,\n  amount REAL, -- 金额，单位元\n  created_at TEXT\n);",
+		sqliteSchema.String())
+}
+
+func TestValidateSQL(t *testing.T) {
+	postgres := DatabaseSchema{Dialect: DialectPostgres, Tables: sqliteSchema.Tables}
+	tests := []struct {
+		name    string
+		query   string
+		schema  DatabaseSchema
+		opts    []SQLOption
+		wantErr string
+	}{
+		{"select", "SELECT c.name, SUM(o.amount) FROM customers c JOIN orders o ON o.customer_id = c.id GROUP BY c.name", sqliteSchema, nil, ""},
+		{"cte and subquery", "WITH recent AS (SELECT * FROM orders WHERE created_at > '2024-01-01') SELECT * FROM (SELECT * FROM recent) r", sqliteSchema, nil, ""},
+		{"strings and comments", "SELECT 'it''s; (' AS s /* DROP ) */ FROM \"orders\" -- DELETE", sqliteSchema, nil, ""},
+		{"function keywords", "SELECT REPLACE(name, 'a', 'b'), EXTRACT(YEAR FROM created_at) FROM customers, orders", postgres, nil, ""},
+		{"unterminated string", "SELECT * FROM orders WHERE name = 'x", sqliteSchema, nil, "unterminated ' quote"},
+		{"unterminated comment", "SELECT * FROM orders /* x", sqliteSchema, nil, "unterminated comment"},
+		{"unclosed parenthesis", "SELECT COUNT(* FROM orders", sqliteSchema, nil, "1 ( not closed"},
+		{"extra parenthesis", "SELECT COUNT(*)) FROM orders", sqliteSchema, nil, "unexpected )"},
+		{"several statements", "SELECT 1; DROP TABLE orders", sqliteSchema, nil, "single statement"},
+		{"write", "DELETE FROM orders", sqliteSchema, nil, "only SELECT queries are allowed, got DELETE"},
+		{"write in cte", "WITH x AS (SELECT 1) UPDATE orders SET amount = 0", sqliteSchema, nil, "found UPDATE"},
+		{"allowed write", "INSERT INTO orders (customer_id, amount) VALUES (1, 9.5)", sqliteSchema, []SQLOption{WithAllowWrites()}, ""},
+		{"unknown table", "SELECT * FROM users", sqliteSchema, nil, "unknown table USERS; available tables: customers, orders"},
+		{"unknown table in join", "SELECT * FROM orders JOIN main.users u ON u.id = orders.customer_id", sqliteSchema, nil, "unknown table USERS"},
+		{"postgres cast in sqlite", "SELECT amount::int FROM orders", sqliteSchema, nil, ":: casts are not supported by SQLite"},
+		{"ilike in sqlite", "SELECT * FROM customers WHERE name ILIKE '%a%'", sqliteSchema, nil, "ILIKE is not supported"},
+		{"backticks in postgres", "SELECT `name` FROM customers", postgres, nil, "backtick"},
+		{"select top", "SELECT TOP 5 * FROM orders", sqliteSchema, nil, "use LIMIT"},
+		{"custom validator", "SELECT * FROM orders", sqliteSchema, []SQLOption{WithSQLValidator(func(string) error { return errors.New("no index") })}, "no index"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &sqlConfig{}
+			for _, opt := range tt.opts {
+				opt(cfg)
+			}
+			err := validateSQL(tt.query, tt.schema, cfg)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateSQL(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"```sql\nSELECT name FROM customer;\n```\n查询客户名称。",
+		"```sql\nSELECT name FROM customers;\n```\n从 customers 表查询所有客户的名称。",
+	}}
+	result, err := GenerateSQL(context.Background(), l, "所有客户叫什么？", sqliteSchema)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT name FROM customers", result.SQL)
+	assert.Equal(t, "从 customers 表查询所有客户的名称。", result.Explanation)
+	require.Len(t, l.prompts, 2)
+	assert.Contains(t, l.prompts[0].Directives[0], "SQLite")
+	assert.Contains(t, l.prompts[0].Directives[0], "CREATE TABLE orders")
+	assert.Contains(t, l.prompts[1].Input, "SELECT name FROM customer\n")
+	assert.Contains(t, l.prompts[1].Input, "unknown table CUSTOMER")
+}
+
+func TestGenerateSQL_InvalidAfterRetry(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"DROP TABLE orders", "DELETE FROM orders"}}
+	_, err := GenerateSQL(context.Background(), l, "清空订单", sqliteSchema)
+	var sqlErr *SQLValidationError
+	require.ErrorAs(t, err, &sqlErr)
+	assert.Equal(t, "DELETE FROM orders", sqlErr.SQL)
+	assert.Len(t, l.prompts, 2, "the LLM is asked to fix the query once")
+
+	_, err = GenerateSQL(context.Background(), l, "问题", DatabaseSchema{})
+	assert.ErrorContains(t, err, "schema has no tables")
+}
+
+func TestSchemaFromDB(t *testing.T) {
+	db := sql.OpenDB(fakeSQLite{tables: map[string][][]driver.Value{
+		"customers": {
+			{int64(0), "id", "INTEGER", int64(0), nil, int64(1)},
+			{int64(1), "name", "TEXT", int64(1), nil, int64(0)},
+		},
+		"orders": {
+			{int64(0), "id", "INTEGER", int64(0), nil, int64(1)},
+			{int64(1), "customer_id", "INTEGER", int64(1), nil, int64(0)},
+		},
+	}})
+	defer db.Close()
+
+	schema, err := SchemaFromDB(context.Background(), db, DialectSQLite)
+	require.NoError(t, err)
+	assert.Equal(t, DatabaseSchema{Dialect: DialectSQLite, Tables: []TableSchema{
+		{Name: "customers", Columns: []ColumnSchema{{Name: "id", Type: "INTEGER", PrimaryKey: true}, {Name: "name", Type: "TEXT", NotNull: true}}},
+		{Name: "orders", Columns: []ColumnSchema{{Name: "id", Type: "INTEGER", PrimaryKey: true}, {Name: "customer_id", Type: "INTEGER", NotNull: true}}},
+	}}, schema)
+
+	_, err = SchemaFromDB(context.Background(), db, DialectSQLite, "users")
+	assert.ErrorContains(t, err, `table "users" not found`)
+	_, err = SchemaFromDB(context.Background(), db, "oracle")
+	assert.ErrorContains(t, err, "cannot introspect")
+}
+
+// fakeSQLite is a database/sql connector answering the introspection queries
+// of SchemaFromDB for SQLite from a map of table names to PRAGMA table_info
+// rows.
+type fakeSQLite struct {
+	tables map[string][][]driver.Value
+}
+
+func (f fakeSQLite) Connect(context.Context) (driver.Conn, error) { return f, nil }
+func (f fakeSQLite) Driver() driver.Driver                        { return nil }
+func (f fakeSQLite) Prepare(query string) (driver.Stmt, error)    { return fakeStmt{f, query}, nil }
+func (f fakeSQLite) Close() error                                 { return nil }
+func (f fakeSQLite) Begin() (driver.Tx, error)                    { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	db    fakeSQLite
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "sqlite_master") {
+		rows := &fakeRows{columns: []string{"name"}}
+		for _, name := range []string{"customers", "orders"} {
+			if _, ok := s.db.tables[name]; ok {
+				rows.values = append(rows.values, []driver.Value{name})
+			}
+		}
+		return rows, nil
+	}
+	table := strings.TrimSuffix(strings.TrimPrefix(s.query, `PRAGMA table_info("`), `")`)
+	return &fakeRows{columns: []string{"cid", "name", "type", "notnull", "dflt_value", "pk"}, values: s.db.tables[table]}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
@@ -5,12 +5,125 @@ package presets
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strings"
 
 	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/progress"
+	"github.com/yockii/gollm_cn/utils"
 )
 
+// ExtractionOption configures ExtractStructuredData, ExtractStructuredList
+// and ExtractStructuredDataBatch. Prompt options are passed with
+// WithPromptOptions.
+type ExtractionOption func(*extractionConfig)
+
+type extractionConfig struct {
+	promptOpts  []gollm.PromptOption
+	retries     int
+	concurrency int
+	skipInvalid bool
+	progress    func(ExtractionProgress)
+	reporter    progress.Reporter
+}
+
+// ExtractionProgress reports the progress of an extraction to the callback
+// set with WithExtractionProgress.
+type ExtractionProgress struct {
+	Attempt  int                    // 1 for the first response, 2 for the first correction, ...
+	Raw      string                 // The response so far, or the complete response
+	Partial  map[string]interface{} // Top-level fields of the JSON object in Raw whose values are complete
+	Complete bool                   // Whether Raw is the complete response of the attempt
+	Err      error                  // Why a complete response was rejected; nil if it was accepted
+}
+
+// WithExtractionRetries sets how many times ExtractStructuredData asks the
+// LLM to correct a response that is not valid JSON or fails validation. The
+// corrective prompt includes the previous response and the errors found in
+// it. The default is 1; 0 disables retries.
+func WithExtractionRetries(n int) ExtractionOption {
+	return func(c *extractionConfig) {
+		c.retries = n
+	}
+}
+
+// WithConcurrency limits how many extractions ExtractStructuredDataBatch
+// runs at the same time. The default is 5; values below 1 are treated as 1.
+func WithConcurrency(n int) ExtractionOption {
+	return func(c *extractionConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithBatchConcurrency limits how many extractions ExtractStructuredDataBatch
+// runs at the same time.
+//
+// Deprecated: Use WithConcurrency.
+func WithBatchConcurrency(n int) ExtractionOption {
+	return WithConcurrency(n)
+}
+
+// WithSkipInvalidItems makes ExtractStructuredList drop the items that do not
+// parse or validate instead of failing. The LLM is only asked to correct its
+// response when the response as a whole is not a JSON array.
+func WithSkipInvalidItems() ExtractionOption {
+	return func(c *extractionConfig) {
+		c.skipInvalid = true
+	}
+}
+
+// WithExtractionProgress calls fn as an extraction makes progress, to give
+// feedback on slow models. fn receives every complete response, including
+// those of corrections, with the error that rejected it. When the LLM supports
+// streaming, responses are streamed and fn is also called each time another
+// top-level field of the JSON object is complete, so a UI can show fields as
+// they stabilize. Streamed requests rely on the schema in the prompt rather
+// than on native structured output; the result is validated as usual.
+//
+// Partial results are for display only; the extraction's return value is
+// still the validated result.
+func WithExtractionProgress(fn func(ExtractionProgress)) ExtractionOption {
+	return func(c *extractionConfig) {
+		c.progress = fn
+	}
+}
+
+// WithProgress makes ExtractStructuredDataBatch report the texts done, the
+// tokens used and an ETA to r after every text. Use progress.NewWriter to
+// print the updates, or supply your own Reporter.
+func WithProgress(r progress.Reporter) ExtractionOption {
+	return func(c *extractionConfig) {
+		c.reporter = r
+	}
+}
+
+// WithPromptOptions applies prompt options, such as gollm.WithDirectives, to
+// the extraction prompt. The extraction functions used to take prompt options
+// directly; wrap them in WithPromptOptions instead.
+//
+// Example:
+//
+//	person, err := presets.ExtractStructuredData[PersonInfo](ctx, llm, text,
+//	    presets.WithPromptOptions(gollm.WithDirectives("姓名使用原文中的写法")),
+//	    presets.WithExtractionRetries(2),
+//	)
+func WithPromptOptions(opts ...gollm.PromptOption) ExtractionOption {
+	return func(c *extractionConfig) {
+		c.promptOpts = append(c.promptOpts, opts...)
+	}
+}
+
+// newExtractionConfig applies opts to the default extraction settings.
+func newExtractionConfig(opts []ExtractionOption) *extractionConfig {
+	config := &extractionConfig{retries: 1, concurrency: 5}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
 // ExtractStructuredData extracts structured data from unstructured text by mapping it
 // to a strongly-typed Go struct. It uses JSON schema validation to ensure the extracted
 // data matches the expected structure and constraints.
@@ -27,7 +140,8 @@ import (
 //   - ctx: Context for cancellation and timeouts
 //   - l: LLM instance to use for extraction
 //   - text: The unstructured text to extract information from
-//   - opts: Optional prompt configuration options
+//   - opts: Optional extraction options such as WithPromptOptions,
+//     WithExtractionRetries and WithExtractionProgress
 //
 // Returns:
 //   - *T: Pointer to the extracted and validated data structure
@@ -45,10 +159,7 @@ import (
 //	text := `John Smith is a 32-year-old software engineer from Seattle.
 //	         He enjoys hiking, photography, and playing guitar in his free time.`
 //
-//	person, err := ExtractStructuredData[PersonInfo](ctx, llm, text,
-//	    gollm.WithMaxTokens(300),
-//	    gollm.WithTemperature(0.2),
-//	)
+//	person, err := ExtractStructuredData[PersonInfo](ctx, llm, text)
 //
 // Example usage with a complex nested struct:
 //
@@ -73,21 +184,21 @@ import (
 //	         considers 42 her lucky number.`
 //
 //	person, err := ExtractStructuredData[ComplexPerson](ctx, llm, text,
-//	    gollm.WithMaxTokens(500),
-//	    gollm.WithTemperature(0.1),
-//	    gollm.WithDirectives(
+//	    WithPromptOptions(gollm.WithDirectives(
 //	        "Extract all available information accurately",
 //	        "Ensure numeric values are within valid ranges",
 //	        "Leave fields as null if information is not clearly stated",
-//	    ),
+//	    )),
+//	    WithExtractionRetries(2),
 //	)
 //
 // The function performs the following steps:
 // 1. Generates a JSON schema from the target type T
 // 2. Creates a prompt that includes the input text and schema
 // 3. Instructs the LLM to extract information matching the schema
-// 4. Parses and validates the LLM's response
-// 5. Returns the validated structured data
+// 4. Repairs, parses and validates the LLM's response (see utils.RepairJSON)
+// 5. On failure, asks the LLM to correct its response (see WithExtractionRetries)
+// 6. Returns the validated structured data
 //
 // Common validation tags supported:
 //   - required: Field must be present and non-empty
@@ -100,9 +211,11 @@ import (
 // Error handling:
 //   - Schema generation errors
 //   - LLM response generation errors
-//   - JSON parsing errors
+//   - JSON parsing errors, including the offending snippet when repair fails
 //   - Validation constraint violations
-func ExtractStructuredData[T any](ctx context.Context, l gollm.LLM, text string, opts ...gollm.PromptOption) (*T, error) {
+//
+// When the retries are used up, the error wraps the errors of every attempt.
+func ExtractStructuredData[T any](ctx context.Context, l gollm.LLM, text string, opts ...ExtractionOption) (*T, error) {
 	// Validate input
 	if ctx == nil {
 		return nil, fmt.Errorf("context cannot be nil")
@@ -114,6 +227,8 @@ func ExtractStructuredData[T any](ctx context.Context, l gollm.LLM, text string,
 		return nil, fmt.Errorf("text cannot be empty")
 	}
 
+	config := newExtractionConfig(opts)
+
 	var zero T
 	schema, err := gollm.GenerateJSONSchema(zero)
 	if err != nil {
@@ -134,14 +249,14 @@ func ExtractStructuredData[T any](ctx context.Context, l gollm.LLM, text string,
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate text content: %w", err)
 	}
-	if strings.TrimSpace(strings.ToLower(validationResponse)) != "yes" {
+	if !isAffirmative(validationResponse) {
 		return nil, fmt.Errorf("text does not contain enough extractable information")
 	}
 
 	// Proceed with extraction
 	promptText := fmt.Sprintf("从给定的文本中提取以下信息:\n\n%s\n\nn请使用与此模式匹配的 JSON 对象进行响应:\n%s", text, string(schema))
 	prompt := gollm.NewPrompt(promptText)
-	prompt.Apply(append(opts,
+	prompt.Apply(append(config.promptOpts,
 		gollm.WithDirectives(
 			"从文本中提取所有相关信息",
 			"确保输出与提供的 JSON 模式完全匹配",
@@ -149,12 +264,178 @@ func ExtractStructuredData[T any](ctx context.Context, l gollm.LLM, text string,
 		),
 		gollm.WithOutput("与提供的模式匹配的 JSON 对象"),
 	)...)
-	response, err := l.Generate(ctx, prompt)
+	// Let providers with native structured output enforce the schema; for the
+	// others the schema in the prompt above is all we can do.
+	var generateOpts []gollm.GenerateOption
+	if l.SupportsJSONSchema() {
+		generateOpts = append(generateOpts, gollm.WithStructuredOutput(schema))
+	}
+	var result *T
+	err = generateCorrected(ctx, l, config, prompt, schema, "JSON 对象", generateOpts, func(response string) (err error) {
+		result, err = parseExtraction[T](ctx, l, response)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// isAffirmative reports whether a response to a yes/no question means yes.
+// The question asks for '是', but models often answer in English instead.
+func isAffirmative(response string) bool {
+	switch strings.ToLower(strings.Trim(strings.TrimSpace(response), "'\"“”‘’。.!！")) {
+	case "是", "yes", "y":
+		return true
+	default:
+		return false
+	}
+}
+
+// generateCorrected generates a response to prompt and passes it to parse.
+// While parse fails, it re-asks with the previous response and its errors
+// until parse succeeds or the retries of config are used up. Shape names the
+// expected JSON value, e.g. "JSON 对象".
+func generateCorrected(ctx context.Context, l gollm.LLM, config *extractionConfig, prompt *gollm.Prompt, schema []byte, shape string, generateOpts []gollm.GenerateOption, parse func(response string) error) error {
+	response, err := config.generate(ctx, l, prompt, generateOpts, 1)
+	if err != nil {
+		return fmt.Errorf("failed to generate structured data: %w", err)
+	}
+
+	var errs []error
+	for attempt := 0; ; attempt++ {
+		err := parse(response)
+		if config.progress != nil {
+			config.progress(ExtractionProgress{Attempt: attempt + 1, Raw: response, Partial: stableFields(response), Complete: true, Err: err})
+		}
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+		if attempt >= config.retries {
+			break
+		}
+		correction := gollm.NewPrompt(fmt.Sprintf("你之前返回的 JSON 未能通过校验:\n\n%s\n\n错误信息:\n%s\n\n请修正上述 JSON，使其与以下模式匹配:\n%s", response, err, string(schema)))
+		correction.Apply(append(config.promptOpts,
+			gollm.WithDirectives(
+				"仅返回修正后的 "+shape,
+				"不要添加任何解释、Markdown 格式或代码块",
+				"保留原有的正确字段，只修正错误信息中指出的问题",
+			),
+			gollm.WithOutput("与提供的模式匹配的 "+shape),
+		)...)
+		response, err = config.generate(ctx, l, correction, generateOpts, attempt+2)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to generate corrected structured data: %w", err))
+			break
+		}
+	}
+	return fmt.Errorf("failed to extract structured data after %d attempts: %w", len(errs), errors.Join(errs...))
+}
+
+// generate returns the response of l to prompt. With a progress callback and
+// an LLM that supports streaming, the response is streamed and the callback
+// is called whenever another top-level field is complete.
+func (c *extractionConfig) generate(ctx context.Context, l gollm.LLM, prompt *gollm.Prompt, generateOpts []gollm.GenerateOption, attempt int) (string, error) {
+	if c.progress == nil || !l.SupportsStreaming() {
+		return l.Generate(ctx, prompt, generateOpts...)
+	}
+	stream, err := l.Stream(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	var raw strings.Builder
+	reported := 0
+	for {
+		token, err := stream.Next(ctx)
+		if err == io.EOF {
+			return raw.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		raw.WriteString(token.Text)
+		if partial := stableFields(raw.String()); len(partial) > reported {
+			reported = len(partial)
+			c.progress(ExtractionProgress{Attempt: attempt, Raw: raw.String(), Partial: partial})
+		}
+	}
+}
+
+// stableFields parses the top-level fields of the JSON object that starts in
+// raw, up to the last field whose value is complete, so that it works on a
+// response that is still arriving. It returns nil while no field is complete.
+func stableFields(raw string) map[string]interface{} {
+	start := strings.IndexByte(raw, '{')
+	if start < 0 {
+		return nil
+	}
+	depth, inString, escaped := 0, false, false
+	end := -1 // Position of the delimiter after the last complete field
+scan:
+	for i := start; i < len(raw); i++ {
+		c := raw[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				end = i
+				break scan
+			}
+		case ',':
+			if depth == 1 {
+				end = i
+			}
+		}
+	}
+	if end < 0 {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(raw[start:end]+"}"), &fields); err != nil || len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// repairExtraction extracts the JSON of an extraction response, subject to
+// the fallback policy of ctx.
+func repairExtraction(ctx context.Context, l gollm.LLM, response string) (string, error) {
+	cleaned, err := utils.RepairJSON(response)
+	if err == nil {
+		cleaned, err = l.FallbackPolicy(ctx).Repair(gollm.InterventionJSONExtraction, response, cleaned)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	return cleaned, nil
+}
+
+// parseExtraction repairs, parses and validates an extraction response.
+func parseExtraction[T any](ctx context.Context, l gollm.LLM, response string) (*T, error) {
+	cleaned, err := repairExtraction(ctx, l, response)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate structured data: %w", err)
+		return nil, err
 	}
 	var result T
-	if err := json.Unmarshal([]byte(response), &result); err != nil {
+	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 	if err := gollm.Validate(&result); err != nil {
@@ -162,3 +443,213 @@ func ExtractStructuredData[T any](ctx context.Context, l gollm.LLM, text string,
 	}
 	return &result, nil
 }
+
+// ExtractStructuredList extracts every entity of type T mentioned in text, such
+// as all people in an article or all line items of an invoice. The LLM is
+// asked for a JSON array whose items match the schema of T, and each item is
+// parsed and validated on its own, so the errors name the offending items.
+//
+// By default an invalid item fails the call, after the LLM has been asked to
+// correct its response (see WithExtractionRetries). With WithSkipInvalidItems
+// invalid items are dropped instead. Providers with native structured output
+// receive the schema of an object whose "items" field holds the array, since
+// they require an object at the top level.
+//
+// Type Parameters:
+//   - T: The struct type of a single item
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for extraction
+//   - text: The unstructured text to extract items from
+//   - opts: Optional extraction options such as WithPromptOptions,
+//     WithExtractionRetries and WithSkipInvalidItems
+//
+// Returns:
+//   - []T: The extracted items in the order the LLM listed them; empty if the
+//     text mentions none
+//   - error: Any error encountered during extraction, parsing, or validation
+//
+// Example:
+//
+//	type LineItem struct {
+//	    Description string  `json:"description" validate:"required"`
+//	    Quantity    int     `json:"quantity" validate:"gte=1"`
+//	    UnitPrice   float64 `json:"unitPrice" validate:"gte=0"`
+//	}
+//
+//	items, err := ExtractStructuredList[LineItem](ctx, llm, invoiceText,
+//	    WithSkipInvalidItems(),
+//	)
+func ExtractStructuredList[T any](ctx context.Context, l gollm.LLM, text string, opts ...ExtractionOption) ([]T, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("context cannot be nil")
+	}
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+
+	config := newExtractionConfig(opts)
+
+	var zero T
+	itemSchema, err := gollm.GenerateJSONSchema(zero)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JSON schema: %w", err)
+	}
+	schema, shape, err := listSchema(itemSchema, l.SupportsJSONSchema())
+	if err != nil {
+		return nil, err
+	}
+
+	prompt := gollm.NewPrompt(fmt.Sprintf("从给定的文本中提取所有符合模式的条目:\n\n%s\n\n请使用与此模式匹配的 %s 进行响应:\n%s", text, shape, string(schema)))
+	prompt.Apply(append(config.promptOpts,
+		gollm.WithDirectives(
+			"提取文本中提到的每一个条目，不要遗漏，也不要把不同的条目合并",
+			"每个条目都必须与模式完全匹配",
+			"如果文本中没有符合条件的条目，返回空数组",
+			"如果无法自信地填充某个字段，请将其保留为 null 或适当的空字符串/数组",
+		),
+		gollm.WithOutput("与提供的模式匹配的 "+shape),
+	)...)
+	var generateOpts []gollm.GenerateOption
+	if l.SupportsJSONSchema() {
+		generateOpts = append(generateOpts, gollm.WithStructuredOutput(schema))
+	}
+
+	var items []T
+	err = generateCorrected(ctx, l, config, prompt, schema, shape, generateOpts, func(response string) (err error) {
+		items, err = parseExtractionList[T](ctx, l, response, config.skipInvalid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// listSchema returns the schema of a JSON array of items and the name of its
+// shape for prompts. With wrap, the array is the "items" field of an object.
+func listSchema(itemSchema []byte, wrap bool) ([]byte, string, error) {
+	var schema interface{} = map[string]interface{}{
+		"type":  "array",
+		"items": json.RawMessage(itemSchema),
+	}
+	shape := "JSON 数组"
+	if wrap {
+		schema = map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"items": schema},
+			"required":   []string{"items"},
+		}
+		shape = "JSON 对象"
+	}
+	encoded, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate JSON schema: %w", err)
+	}
+	return encoded, shape, nil
+}
+
+// parseExtractionList repairs and parses a JSON array, or an object with an
+// "items" array, and parses and validates each item. Invalid items are
+// dropped with skipInvalid and fail the parse otherwise.
+func parseExtractionList[T any](ctx context.Context, l gollm.LLM, response string, skipInvalid bool) ([]T, error) {
+	cleaned, err := repairExtraction(ctx, l, response)
+	if err != nil {
+		return nil, err
+	}
+	var elements []json.RawMessage
+	if strings.HasPrefix(cleaned, "{") {
+		var wrapped struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		err = json.Unmarshal([]byte(cleaned), &wrapped)
+		elements = wrapped.Items
+	} else {
+		err = json.Unmarshal([]byte(cleaned), &elements)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	items := make([]T, 0, len(elements))
+	var errs []error
+	for i, element := range elements {
+		var item T
+		if err := json.Unmarshal(element, &item); err != nil {
+			errs = append(errs, fmt.Errorf("item %d: failed to parse: %w", i, err))
+			continue
+		}
+		if err := gollm.Validate(&item); err != nil {
+			errs = append(errs, fmt.Errorf("item %d: validation failed: %w", i, err))
+			continue
+		}
+		items = append(items, item)
+	}
+	if len(errs) > 0 {
+		if !skipInvalid {
+			return nil, errors.Join(errs...)
+		}
+		l.GetLogger().Warn("Skipped invalid extracted items", "skipped", len(errs), "kept", len(items), "errors", errors.Join(errs...).Error())
+	}
+	return items, nil
+}
+
+// BatchResult is the outcome of extracting data from one text of a batch.
+// Index is the position of the text in the batch, so results can be put back
+// in order regardless of the order in which extractions complete.
+type BatchResult[T any] struct {
+	Index  int
+	Result *T               // Extracted data, nil if Err is set
+	Err    error            // Extraction error, including ctx.Err() for texts never started
+	Usage  gollm.TokenUsage // Tokens used for this text, as reported by the provider
+}
+
+// ExtractStructuredDataBatch runs ExtractStructuredData on every text
+// concurrently, bounded by WithConcurrency. A failed extraction does not stop
+// the others; its error is reported in its result. When ctx is canceled, no
+// new extractions are started, the texts not yet started get ctx.Err() as
+// their error, and ctx.Err() is returned once running extractions finish.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for extraction
+//   - texts: The texts to extract information from
+//   - opts: Options for ExtractStructuredData, plus WithConcurrency and
+//     WithProgress
+//
+// Returns:
+//   - []BatchResult[T]: One result per text, in the order of texts
+//   - error: ctx.Err() if the batch was canceled
+//
+// Example:
+//
+//	results, err := ExtractStructuredDataBatch[PersonInfo](ctx, llm, bios,
+//	    WithConcurrency(10),
+//	)
+//	for _, r := range results {
+//	    if r.Err != nil {
+//	        log.Printf("bio %d: %v", r.Index, r.Err)
+//	        continue
+//	    }
+//	    fmt.Println(r.Result.Name, r.Usage.OutputTokens)
+//	}
+func ExtractStructuredDataBatch[T any](ctx context.Context, l gollm.LLM, texts []string, opts ...ExtractionOption) ([]BatchResult[T], error) {
+	config := newExtractionConfig(opts)
+	results := make([]BatchResult[T], len(texts))
+	tracker := progress.NewTracker(config.reporter, "extract", len(texts))
+	started := runBatch(ctx, len(texts), config.concurrency, func(i int) {
+		textCtx, recorder := gollm.WithUsageRecorder(ctx)
+		result, err := ExtractStructuredData[T](textCtx, l, texts[i], opts...)
+		usage := recorder.Usage()
+		tracker.ItemDone(usage.InputTokens, usage.OutputTokens)
+		results[i] = BatchResult[T]{Index: i, Result: result, Err: err, Usage: usage}
+	})
+	for i := started; i < len(texts); i++ {
+		results[i] = BatchResult[T]{Index: i, Err: ctx.Err()}
+	}
+	return results, ctx.Err()
+}
@@ -0,0 +1,372 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/progress"
+)
+
+type city struct {
+	Name    string `json:"name" validate:"required"`
+	Country string `json:"country" validate:"required"`
+}
+
+func TestExtractStructuredData_StructuredOutput(t *testing.T) {
+	for _, native := range []bool{true, false} {
+		l := &scriptedLLM{jsonSchema: native, responses: []string{"yes", `{"name": "杭州", "country": "中国"}`}}
+
+		result, err := ExtractStructuredData[city](context.Background(), l, "杭州是中国浙江省的省会。")
+		require.NoError(t, err)
+		assert.Equal(t, &city{Name: "杭州", Country: "中国"}, result)
+
+		require.Len(t, l.configs, 2)
+		if native {
+			assert.Contains(t, string(l.configs[1].StructuredOutput), `"country"`)
+		} else {
+			assert.Nil(t, l.configs[1].StructuredOutput)
+		}
+	}
+}
+
+func TestExtractStructuredData_ValidationAnswer(t *testing.T) {
+	for _, answer := range []string{"是", " 是。\n", "Yes", "y"} {
+		l := &scriptedLLM{responses: []string{answer, `{"name": "杭州", "country": "中国"}`}}
+		result, err := ExtractStructuredData[city](context.Background(), l, "杭州是中国浙江省的省会。")
+		require.NoError(t, err, answer)
+		assert.Equal(t, &city{Name: "杭州", Country: "中国"}, result)
+	}
+
+	for _, answer := range []string{"否", "no", "是否"} {
+		l := &scriptedLLM{responses: []string{answer}}
+		_, err := ExtractStructuredData[city](context.Background(), l, "今天天气不错。")
+		assert.ErrorContains(t, err, "text does not contain enough extractable information", answer)
+	}
+}
+
+func TestExtractStructuredData_RepairsJSON(t *testing.T) {
+	fenced := "```json\n{'name': '杭州', 'country': '中国',}\n```"
+
+	l := &scriptedLLM{responses: []string{"yes", fenced}}
+	result, err := ExtractStructuredData[city](context.Background(), l, "杭州是中国浙江省的省会。")
+	require.NoError(t, err)
+	assert.Equal(t, &city{Name: "杭州", Country: "中国"}, result)
+
+	l = &scriptedLLM{responses: []string{"yes", `{"name": "杭州", "country": 中国}`}}
+	_, err = ExtractStructuredData[city](context.Background(), l, "杭州是中国浙江省的省会。", WithExtractionRetries(0))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid JSON at byte")
+}
+
+func TestExtractStructuredData_Retries(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"yes",
+		`{"name": "杭州"}`,
+		`{"name": "杭州", "country": "中国"}`,
+	}}
+	result, err := ExtractStructuredData[city](context.Background(), l, "杭州是中国浙江省的省会。")
+	require.NoError(t, err)
+	assert.Equal(t, &city{Name: "杭州", Country: "中国"}, result)
+
+	// The corrective prompt quotes the previous response and its errors.
+	require.Len(t, l.prompts, 3)
+	correction := l.prompts[2].Input
+	assert.Contains(t, correction, `{"name": "杭州"}`)
+	assert.Contains(t, correction, "Country")
+	assert.Contains(t, l.prompts[2].Directives, "仅返回修正后的 JSON 对象")
+}
+
+func TestExtractStructuredData_RetriesExhausted(t *testing.T) {
+	l := &scriptedLLM{responses: []string{
+		"yes",
+		`not json`,
+		`{"name": "杭州"}`,
+		`{"country": "中国"}`,
+	}}
+	_, err := ExtractStructuredData[city](context.Background(), l, "杭州是中国浙江省的省会。", WithExtractionRetries(2))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "after 3 attempts")
+	assert.Contains(t, err.Error(), "failed to parse response")
+	assert.Contains(t, err.Error(), "Country")
+	assert.Contains(t, err.Error(), "Name")
+	assert.Empty(t, l.responses)
+}
+
+// streamingLLM streams its scripted responses in chunks of the given number
+// of bytes, or does not support streaming when chunk is 0.
+type streamingLLM struct {
+	scriptedLLM
+	chunk int
+}
+
+func (s *streamingLLM) SupportsStreaming() bool {
+	return s.chunk > 0
+}
+
+func (s *streamingLLM) Stream(ctx context.Context, prompt *llm.Prompt, opts ...llm.StreamOption) (llm.TokenStream, error) {
+	s.prompts = append(s.prompts, prompt)
+	response := s.responses[0]
+	s.responses = s.responses[1:]
+	return &chunkStream{text: response, size: s.chunk}, nil
+}
+
+type chunkStream struct {
+	text string
+	size int
+}
+
+func (c *chunkStream) Next(ctx context.Context) (*llm.StreamToken, error) {
+	if c.text == "" {
+		return nil, io.EOF
+	}
+	n := min(c.size, len(c.text))
+	token := &llm.StreamToken{Text: c.text[:n]}
+	c.text = c.text[n:]
+	return token, nil
+}
+
+func (c *chunkStream) Close() error {
+	return nil
+}
+
+func TestExtractStructuredData_Progress(t *testing.T) {
+	var events []ExtractionProgress
+	progress := WithExtractionProgress(func(p ExtractionProgress) { events = append(events, p) })
+
+	// Without streaming, every complete response is reported.
+	l := &streamingLLM{scriptedLLM: scriptedLLM{responses: []string{"yes", `{"name": "杭州"}`, `{"name": "杭州", "country": "中国"}`}}}
+	result, err := ExtractStructuredData[city](context.Background(), l, "杭州是中国浙江省的省会。", progress)
+	require.NoError(t, err)
+	assert.Equal(t, &city{Name: "杭州", Country: "中国"}, result)
+	require.Len(t, events, 2)
+	assert.Equal(t, 1, events[0].Attempt)
+	assert.True(t, events[0].Complete)
+	assert.ErrorContains(t, events[0].Err, "Country")
+	assert.Equal(t, ExtractionProgress{
+		Attempt:  2,
+		Raw:      `{"name": "杭州", "country": "中国"}`,
+		Partial:  map[string]interface{}{"name": "杭州", "country": "中国"},
+		Complete: true,
+	}, events[1])
+
+	// Streamed responses also report fields as they are completed.
+	events = nil
+	l = &streamingLLM{chunk: 4, scriptedLLM: scriptedLLM{responses: []string{"yes", `{"name": "杭州", "country": "中国"}`}}}
+	result, err = ExtractStructuredData[city](context.Background(), l, "杭州是中国浙江省的省会。", progress)
+	require.NoError(t, err)
+	assert.Equal(t, &city{Name: "杭州", Country: "中国"}, result)
+	require.Len(t, events, 3)
+	assert.Equal(t, map[string]interface{}{"name": "杭州"}, events[0].Partial)
+	assert.False(t, events[0].Complete)
+	assert.Equal(t, map[string]interface{}{"name": "杭州", "country": "中国"}, events[1].Partial)
+	assert.True(t, events[2].Complete)
+	assert.Len(t, l.prompts, 2, "the extraction itself is streamed")
+}
+
+func TestStableFields(t *testing.T) {
+	assert.Nil(t, stableFields(`{"name": "杭`))
+	assert.Nil(t, stableFields(`no json yet`))
+	assert.Equal(t, map[string]interface{}{"name": "a,}\"b"}, stableFields(`{"name": "a,}\"b", "tags": ["x", "y`))
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": float64(1)}}, stableFields("```json\n{\"a\": {\"b\": 1}, \"c\""))
+}
+
+func TestExtractStructuredData_PromptOptions(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"yes", `{"name": "杭州", "country": "中国"}`}}
+	promptOpts := []gollm.PromptOption{gollm.WithDirectives("城市名使用中文"), gollm.WithContext("地理")}
+	_, err := ExtractStructuredData[city](context.Background(), l, "杭州", WithPromptOptions(promptOpts...))
+	require.NoError(t, err)
+	require.Len(t, l.prompts, 2)
+	assert.Contains(t, l.prompts[1].Directives, "城市名使用中文")
+	assert.Equal(t, "地理", l.prompts[1].Context)
+}
+
+type address struct {
+	City   string `json:"city" validate:"required"`
+	Street string `json:"street"`
+}
+
+type person struct {
+	Name      string    `json:"name" validate:"required"`
+	Age       int       `json:"age" validate:"gte=0"`
+	Addresses []address `json:"addresses" validate:"dive"`
+}
+
+const peopleJSON = `[
+	{"name": "张三", "age": 30, "addresses": [{"city": "杭州", "street": "文一路"}]},
+	{"name": "李四", "age": 25, "addresses": [{"city": "上海"}, {"city": "北京"}]}
+]`
+
+var people = []person{
+	{Name: "张三", Age: 30, Addresses: []address{{City: "杭州", Street: "文一路"}}},
+	{Name: "李四", Age: 25, Addresses: []address{{City: "上海"}, {City: "北京"}}},
+}
+
+func TestExtractStructuredList(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"```json\n" + peopleJSON + "\n```"}}
+	result, err := ExtractStructuredList[person](context.Background(), l, "张三住在杭州，李四在上海和北京都有房子。")
+	require.NoError(t, err)
+	assert.Equal(t, people, result)
+
+	require.Len(t, l.prompts, 1)
+	assert.Contains(t, l.prompts[0].Input, "JSON 数组")
+	assert.Contains(t, l.prompts[0].Input, `"street"`)
+	assert.Nil(t, l.configs[0].StructuredOutput)
+
+	// Native structured output needs an object at the top level.
+	l = &scriptedLLM{jsonSchema: true, responses: []string{`{"items": ` + peopleJSON + `}`}}
+	result, err = ExtractStructuredList[person](context.Background(), l, "张三住在杭州，李四在上海和北京都有房子。")
+	require.NoError(t, err)
+	assert.Equal(t, people, result)
+	assert.Contains(t, string(l.configs[0].StructuredOutput), `"items"`)
+	assert.Contains(t, string(l.configs[0].StructuredOutput), `"street"`)
+
+	l = &scriptedLLM{responses: []string{"[]"}}
+	result, err = ExtractStructuredList[person](context.Background(), l, "今天天气很好。")
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.Empty(t, result)
+}
+
+func TestExtractStructuredList_InvalidItems(t *testing.T) {
+	// The second person's nested address lacks the required city.
+	invalid := `[
+		{"name": "张三", "age": 30, "addresses": [{"city": "杭州", "street": "文一路"}]},
+		{"name": "李四", "age": 25, "addresses": [{"street": "南京路"}]},
+		{"age": 40}
+	]`
+
+	l := &scriptedLLM{responses: []string{invalid}}
+	_, err := ExtractStructuredList[person](context.Background(), l, "……", WithExtractionRetries(0))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "item 1: validation failed")
+	assert.Contains(t, err.Error(), "City")
+	assert.Contains(t, err.Error(), "item 2: validation failed")
+	assert.NotContains(t, err.Error(), "item 0")
+
+	// By default the LLM is asked to correct the array.
+	l = &scriptedLLM{responses: []string{invalid, peopleJSON}}
+	result, err := ExtractStructuredList[person](context.Background(), l, "……")
+	require.NoError(t, err)
+	assert.Equal(t, people, result)
+	assert.Contains(t, l.prompts[1].Directives, "仅返回修正后的 JSON 数组")
+
+	// WithSkipInvalidItems keeps the valid items without asking again.
+	l = &scriptedLLM{responses: []string{invalid}}
+	result, err = ExtractStructuredList[person](context.Background(), l, "……", WithSkipInvalidItems())
+	require.NoError(t, err)
+	assert.Equal(t, people[:1], result)
+	assert.Len(t, l.prompts, 1)
+}
+
+// cityLLM answers extraction prompts concurrently, returning the JSON listed
+// for the text in the prompt, and records the peak number of calls in flight.
+type cityLLM struct {
+	gollm.LLM
+	answers  map[string]string
+	mutex    sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (c *cityLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	c.mutex.Lock()
+	c.inFlight++
+	if c.inFlight > c.peak {
+		c.peak = c.inFlight
+	}
+	c.mutex.Unlock()
+	defer func() {
+		c.mutex.Lock()
+		c.inFlight--
+		c.mutex.Unlock()
+	}()
+	time.Sleep(5 * time.Millisecond)
+	gollm.AddTokenUsage(ctx, 10, 2)
+
+	if strings.HasPrefix(prompt.Input, "分析以下文本") {
+		return "yes", nil
+	}
+	for text, answer := range c.answers {
+		if strings.Contains(prompt.Input, text) {
+			return answer, nil
+		}
+	}
+	return "", errors.New("unexpected prompt")
+}
+
+func (c *cityLLM) FallbackPolicy(ctx context.Context) *llm.FallbackPolicy {
+	return llm.NewFallbackPolicy(false, nil)
+}
+
+func (c *cityLLM) SupportsJSONSchema() bool {
+	return false
+}
+
+func TestExtractStructuredDataBatch(t *testing.T) {
+	l := &cityLLM{answers: map[string]string{
+		"杭州": `{"name": "杭州", "country": "中国"}`,
+		"京都": `{"name": "京都", "country": "日本"}`,
+		"某地": `{"name": "某地"}`,
+		"里昂": `{"name": "里昂", "country": "法国"}`,
+	}}
+	texts := []string{"杭州是浙江省的省会。", "京都曾是日本的首都。", "某地没有国家信息。", "", "里昂位于法国东南部。"}
+
+	var updates []progress.Update
+	results, err := ExtractStructuredDataBatch[city](context.Background(), l, texts,
+		WithConcurrency(2), WithExtractionRetries(0),
+		WithProgress(progress.ReporterFunc(func(u progress.Update) { updates = append(updates, u) })))
+	require.NoError(t, err)
+	require.Len(t, results, len(texts))
+	for i, r := range results {
+		assert.Equal(t, i, r.Index)
+	}
+
+	assert.Equal(t, &city{Name: "杭州", Country: "中国"}, results[0].Result)
+	assert.Equal(t, &city{Name: "京都", Country: "日本"}, results[1].Result)
+	assert.Nil(t, results[2].Result)
+	assert.ErrorContains(t, results[2].Err, "validation failed")
+	assert.Nil(t, results[3].Result)
+	assert.ErrorContains(t, results[3].Err, "text cannot be empty")
+	assert.Equal(t, &city{Name: "里昂", Country: "法国"}, results[4].Result)
+	assert.NoError(t, results[4].Err)
+
+	// Each text used a validation call and an extraction call.
+	assert.Equal(t, gollm.TokenUsage{InputTokens: 20, OutputTokens: 4}, results[0].Usage)
+	assert.Equal(t, gollm.TokenUsage{}, results[3].Usage)
+
+	assert.LessOrEqual(t, l.peak, 2)
+
+	// The start and every text are reported.
+	require.Len(t, updates, len(texts)+1)
+	last := updates[len(updates)-1]
+	assert.Equal(t, "extract", last.Phase)
+	assert.Equal(t, len(texts), last.Done)
+	assert.True(t, last.Final)
+	assert.Equal(t, 80, last.InputTokens)
+	assert.Equal(t, 16, last.OutputTokens)
+}
+
+func TestExtractStructuredDataBatch_Canceled(t *testing.T) {
+	l := &cityLLM{answers: map[string]string{"杭州": `{"name": "杭州", "country": "中国"}`}}
+	texts := make([]string, 20)
+	for i := range texts {
+		texts[i] = "杭州是浙江省的省会。"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	results, err := ExtractStructuredDataBatch[city](ctx, l, texts, WithConcurrency(1))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, results, len(texts))
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[len(texts)-1].Err, context.DeadlineExceeded)
+}
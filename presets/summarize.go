@@ -121,3 +121,89 @@ func Summarize(ctx context.Context, l gollm.LLM, text string, opts ...gollm.Prom
 	}
 	return response, nil
 }
+
+// SummaryFormat is the layout of a summary produced by SummarizeWithOptions.
+type SummaryFormat string
+
+// Summary formats.
+const (
+	SummaryProse   SummaryFormat = "prose"   // One or more paragraphs
+	SummaryBullets SummaryFormat = "bullets" // A bulleted list of key points
+)
+
+// SummarizeOptions configures SummarizeWithOptions. The zero value produces
+// the same summary as Summarize.
+type SummarizeOptions struct {
+	MaxWords       int           // Approximate maximum length of the summary; 0 for no limit
+	Format         SummaryFormat // SummaryProse or SummaryBullets; empty leaves the layout to the LLM
+	Tone           string        // Tone of the summary, e.g. "正式" or "轻松"; empty for neutral
+	PreserveQuotes bool          // Whether to quote key sentences of the text verbatim
+}
+
+// directives returns the prompt directives implementing o.
+func (o SummarizeOptions) directives() ([]string, error) {
+	var directives []string
+	switch o.Format {
+	case "":
+	case SummaryProse:
+		directives = append(directives, "以连贯的段落写出摘要，不要使用列表")
+	case SummaryBullets:
+		directives = append(directives, "以要点列表写出摘要，每个要点一行，以“- ”开头，一个要点只写一件事")
+	default:
+		return nil, fmt.Errorf("unknown summary format %q", o.Format)
+	}
+	if o.MaxWords > 0 {
+		directives = append(directives, fmt.Sprintf("摘要不超过 %d 字，篇幅有限时优先保留最重要的信息", o.MaxWords))
+	}
+	if o.Tone != "" {
+		directives = append(directives, fmt.Sprintf("使用%s的语气", o.Tone))
+	}
+	if o.PreserveQuotes {
+		directives = append(directives, "逐字引用原文中的关键语句，用引号标出，不要改写引文")
+	}
+	return directives, nil
+}
+
+// SummarizeWithOptions is Summarize with control over the length, layout and
+// tone of the summary, so the same preset serves both a one-line TL;DR and an
+// executive summary. The options are turned into prompt directives; opts can
+// add further prompt options as with Summarize.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for summarization
+//   - text: The text to be summarized
+//   - options: The length, format, tone and quoting of the summary
+//   - opts: Optional prompt configuration options
+//
+// Returns:
+//   - string: The generated summary
+//   - error: Any error encountered, including an unknown Format
+//
+// Example:
+//
+//	tldr, err := SummarizeWithOptions(ctx, llm, article, SummarizeOptions{MaxWords: 50, Tone: "轻松"})
+//
+//	brief, err := SummarizeWithOptions(ctx, llm, report, SummarizeOptions{
+//	    MaxWords:       300,
+//	    Format:         SummaryBullets,
+//	    Tone:           "正式",
+//	    PreserveQuotes: true,
+//	})
+func SummarizeWithOptions(ctx context.Context, l gollm.LLM, text string, options SummarizeOptions, opts ...gollm.PromptOption) (string, error) {
+	if options.MaxWords < 0 {
+		return "", fmt.Errorf("max words cannot be negative, got %d", options.MaxWords)
+	}
+	directives, err := options.directives()
+	if err != nil {
+		return "", err
+	}
+	promptOpts := make([]gollm.PromptOption, 0, len(opts)+2)
+	if len(directives) > 0 {
+		promptOpts = append(promptOpts, gollm.WithDirectives(directives...))
+	}
+	if options.MaxWords > 0 {
+		promptOpts = append(promptOpts, gollm.WithMaxLength(options.MaxWords))
+	}
+	return Summarize(ctx, l, text, append(promptOpts, opts...)...)
+}
@@ -0,0 +1,577 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/progress"
+)
+
+// Defaults of SummarizeLong.
+const (
+	defaultSummaryLength = 200
+	defaultChunkSize     = 2000
+	defaultChunkOverlap  = 100
+)
+
+// summaryPromptTokens is the number of tokens reserved in the context window
+// for the instructions of a summarization prompt.
+const summaryPromptTokens = 500
+
+// SummaryStrategy is how SummarizeLong combines the chunks of a text.
+type SummaryStrategy string
+
+// Summary strategies.
+const (
+	// StrategyMapReduce summarizes the chunks independently, possibly
+	// concurrently, then combines the summaries.
+	StrategyMapReduce SummaryStrategy = "map-reduce"
+	// StrategyRefine builds the summary chunk by chunk, refining the running
+	// summary with each chunk in order. It is sequential, but preserves the
+	// narrative order of texts such as meeting transcripts better.
+	StrategyRefine SummaryStrategy = "refine"
+)
+
+// SummarizeOption configures SummarizeLong.
+type SummarizeOption func(*summarizeConfig)
+
+type summarizeConfig struct {
+	strategy    SummaryStrategy
+	length      int
+	chunkSize   int
+	overlap     int
+	concurrency int
+	reporter    progress.Reporter
+}
+
+// WithStrategy sets how SummarizeLong combines the chunks of a text. The
+// default is StrategyMapReduce.
+func WithStrategy(strategy SummaryStrategy) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.strategy = strategy
+	}
+}
+
+// WithSummaryLength sets the approximate length, in words, of the final
+// summary and of each partial summary. The default is 200.
+func WithSummaryLength(words int) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.length = words
+	}
+}
+
+// WithChunkSize sets the approximate token budget of each chunk of the text,
+// and of each group of partial summaries combined in one call. By default it
+// is derived from the context window of the model (see
+// gollm.RegisterContextWindow), minus room for the instructions, the summary
+// and, with StrategyRefine, the running summary, minus a quarter of the rest
+// as margin for the token estimate. It is 2000 for models with an unknown
+// context window.
+func WithChunkSize(tokens int) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.chunkSize = tokens
+	}
+}
+
+// WithChunkOverlap sets the approximate number of tokens at the end of a
+// chunk that are repeated at the start of the next one, so a passage cut by a
+// chunk boundary is seen whole. It is capped at half the chunk size. The
+// default is 100; 0 disables overlap.
+func WithChunkOverlap(tokens int) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.overlap = tokens
+	}
+}
+
+// WithSummaryConcurrency sets how many chunks SummarizeLong summarizes at
+// once with StrategyMapReduce. The default is 1.
+func WithSummaryConcurrency(n int) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.concurrency = n
+	}
+}
+
+// WithSummaryProgress reports the progress of SummarizeLong to r, one item
+// per LLM call, with the tokens it used. With StrategyRefine the total is the
+// number of chunks; with StrategyMapReduce, whose number of combining calls
+// depends on the length of the summaries, it is an upper bound that is
+// lowered before each round of combining calls, so it is exact by the last
+// call. Use progress.ReporterFunc for a callback, or a progress.Writer to
+// render it in a terminal.
+func WithSummaryProgress(r progress.Reporter) SummarizeOption {
+	return func(c *summarizeConfig) {
+		c.reporter = r
+	}
+}
+
+// SummarizeLong summarizes a text too long for a single request. The text is
+// split into chunks on paragraph boundaries, falling back to sentence
+// boundaries for paragraphs over the chunk size (see WithChunkSize and
+// WithChunkOverlap), which are combined according to the strategy (see
+// WithStrategy):
+//
+//   - StrategyMapReduce summarizes each chunk, then combines the partial
+//     summaries in groups that fit the chunk size, and the combined summaries
+//     again, until a single summary remains. Each round at least halves the
+//     number of summaries, so the recursion terminates even if the LLM returns
+//     summaries longer than the chunk size.
+//   - StrategyRefine summarizes the first chunk, then refines the running
+//     summary with each following chunk in order.
+//
+// The final summary has the target length set by WithSummaryLength. A text
+// that fits in one chunk is summarized in a single call. Use
+// SummarizeLongDetailed to also get the summary of each chunk.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for summarization
+//   - text: The text to summarize
+//   - opts: Optional summarize options
+//
+// Returns:
+//   - string: The final summary
+//   - error: The first error encountered; the remaining calls are canceled
+//
+// Example:
+//
+//	summary, err := SummarizeLong(ctx, llm, transcript,
+//	    WithStrategy(StrategyRefine),
+//	    WithSummaryLength(300),
+//	    WithSummaryProgress(progress.ReporterFunc(func(u progress.Update) {
+//	        fmt.Printf("%d/%d\n", u.Done, u.Total)
+//	    })),
+//	)
+func SummarizeLong(ctx context.Context, l gollm.LLM, text string, opts ...SummarizeOption) (string, error) {
+	result, err := SummarizeLongDetailed(ctx, l, text, opts...)
+	if err != nil {
+		return "", err
+	}
+	return result.Summary, nil
+}
+
+// LongSummary is the result of SummarizeLongDetailed.
+type LongSummary struct {
+	// Summary is the final summary.
+	Summary string
+	// ChunkSummaries has one summary per chunk of the text, in order. With
+	// StrategyMapReduce, each summarizes its chunk alone; with StrategyRefine,
+	// each is the running summary after its chunk, so the last one is Summary.
+	ChunkSummaries []string
+	// ChunkSize is the token budget the text was split with.
+	ChunkSize int
+}
+
+// SummarizeLongDetailed is SummarizeLong, also returning the summary of each
+// chunk of the text, for instance to show a summary per section next to the
+// summary of the whole text.
+//
+// Example:
+//
+//	result, err := SummarizeLongDetailed(ctx, llm, report)
+//	for i, summary := range result.ChunkSummaries {
+//	    fmt.Printf("第 %d 部分：%s\n", i+1, summary)
+//	}
+//	fmt.Println(result.Summary)
+func SummarizeLongDetailed(ctx context.Context, l gollm.LLM, text string, opts ...SummarizeOption) (*LongSummary, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	cfg := newSummarizeConfig(opts)
+	if cfg.strategy != StrategyMapReduce && cfg.strategy != StrategyRefine {
+		return nil, fmt.Errorf("unknown summary strategy %q", cfg.strategy)
+	}
+	if cfg.chunkSize == 0 {
+		size, err := summaryChunkSize(l.GetModel(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		cfg.chunkSize = size
+	}
+	if cfg.chunkSize < 1 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", cfg.chunkSize)
+	}
+
+	chunks := chunkText(text, cfg.chunkSize, cfg.overlap)
+	total := len(chunks)
+	if cfg.strategy == StrategyMapReduce {
+		// A reduction tree over n summaries has at most n-1 combining calls.
+		total = max(1, 2*len(chunks)-1)
+	}
+	tracker := progress.NewTracker(cfg.reporter, "summarize", total)
+	defer tracker.Finish()
+
+	result := &LongSummary{ChunkSize: cfg.chunkSize}
+	if len(chunks) == 1 {
+		summary, err := summarizePart(ctx, l, cfg, tracker, "请总结以下文本:\n\n"+chunks[0])
+		if err != nil {
+			return nil, err
+		}
+		result.Summary, result.ChunkSummaries = summary, []string{summary}
+		return result, nil
+	}
+	if cfg.strategy == StrategyRefine {
+		summaries, err := summarizeRefine(ctx, l, cfg, tracker, chunks)
+		if err != nil {
+			return nil, err
+		}
+		result.Summary, result.ChunkSummaries = summaries[len(summaries)-1], summaries
+		return result, nil
+	}
+	summaries, err := summarizeAll(ctx, l, cfg, tracker, chunks, func(i int, chunk string) string {
+		return fmt.Sprintf("以下是一篇长文档的第 %d/%d 部分，请总结这一部分:\n\n%s", i+1, len(chunks), chunk)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.ChunkSummaries = summaries
+
+	calls := len(chunks)
+	for round := 0; ; round++ {
+		groups := groupSummaries(summaries, cfg.chunkSize)
+		// This round makes len(groups) calls, and combining its summaries
+		// takes at most len(groups)-1 more.
+		tracker.SetTotal(calls + 2*len(groups) - 1)
+		if round == 0 {
+			tracker.SetPhase("combine")
+		}
+		combine := func(i int, group string) string {
+			return "以下是同一文档各部分按顺序排列的摘要，请将它们合并为一份连贯的摘要，去除重复内容:\n\n" + group
+		}
+		if len(groups) == 1 {
+			if result.Summary, err = summarizePart(ctx, l, cfg, tracker, combine(0, groups[0])); err != nil {
+				return nil, err
+			}
+			return result, nil
+		}
+		if summaries, err = summarizeAll(ctx, l, cfg, tracker, groups, combine); err != nil {
+			return nil, err
+		}
+		calls += len(groups)
+	}
+}
+
+// newSummarizeConfig applies opts to the defaults of SummarizeLong.
+func newSummarizeConfig(opts []SummarizeOption) *summarizeConfig {
+	cfg := &summarizeConfig{
+		strategy:    StrategyMapReduce,
+		length:      defaultSummaryLength,
+		overlap:     defaultChunkOverlap,
+		concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// summaryChunkSize derives the chunk size from the context window of model:
+// what remains after the instructions, the summary and the running summary of
+// StrategyRefine, less a quarter as margin for the token estimate.
+func summaryChunkSize(model string, cfg *summarizeConfig) (int, error) {
+	window, ok := gollm.ContextWindow(model)
+	if !ok {
+		return defaultChunkSize, nil
+	}
+	// A word of summary is about two tokens, and the summary is both the
+	// output and, when refining or combining, part of the input.
+	available := window - summaryPromptTokens - 4*cfg.length
+	if available < 1 {
+		return 0, fmt.Errorf("context window of %s (%d tokens) is too small for a summary of %d words", model, window, cfg.length)
+	}
+	return max(1, available*3/4), nil
+}
+
+// SummaryChunks returns the number of chunks SummarizeLong splits text into
+// with the given options; a text of one chunk is summarized in a single call.
+// Without WithChunkSize it assumes a chunk size of 2000 tokens, as the model
+// is not known. Programs can use it to summarize short texts with Summarize
+// instead.
+//
+// Example:
+//
+//	if presets.SummaryChunks(text) > 1 {
+//	    summary, err = presets.SummarizeLong(ctx, llm, text)
+//	} else {
+//	    summary, err = presets.Summarize(ctx, llm, text)
+//	}
+func SummaryChunks(text string, opts ...SummarizeOption) int {
+	cfg := newSummarizeConfig(opts)
+	if cfg.chunkSize == 0 {
+		cfg.chunkSize = defaultChunkSize
+	}
+	if cfg.chunkSize < 1 || strings.TrimSpace(text) == "" {
+		return 0
+	}
+	return len(chunkText(text, cfg.chunkSize, cfg.overlap))
+}
+
+// summarizeRefine summarizes the first chunk and refines the summary with
+// each following chunk in order. It returns the summary after each chunk.
+func summarizeRefine(ctx context.Context, l gollm.LLM, cfg *summarizeConfig, tracker *progress.Tracker, chunks []string) ([]string, error) {
+	summary, err := summarizePart(ctx, l, cfg, tracker,
+		fmt.Sprintf("以下是一篇长文档的第 1/%d 部分，请总结这一部分:\n\n%s", len(chunks), chunks[0]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize part 1/%d: %w", len(chunks), err)
+	}
+	summaries := []string{summary}
+	for i := 1; i < len(chunks); i++ {
+		summary, err = summarizePart(ctx, l, cfg, tracker, fmt.Sprintf(
+			"以下是一篇长文档前面部分的摘要，以及文档的下一部分。请用新部分中的信息完善摘要：按原文顺序补充新的内容，修正与新信息矛盾的地方，必要时压缩，但不要丢失已有的重要信息。\n\n当前摘要:\n%s\n\n第 %d/%d 部分:\n%s",
+			summary, i+1, len(chunks), chunks[i]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize part %d/%d: %w", i+1, len(chunks), err)
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// summarizePart generates a summary for one prompt and reports it to tracker.
+func summarizePart(ctx context.Context, l gollm.LLM, cfg *summarizeConfig, tracker *progress.Tracker, input string) (string, error) {
+	prompt := gollm.NewPrompt(input,
+		gollm.WithDirectives(
+			"抓住要点和关键细节，保留重要的名称、数字和结论",
+			"只输出摘要，不要添加评论",
+		),
+		gollm.WithMaxLength(cfg.length),
+	)
+	partCtx, recorder := gollm.WithUsageRecorder(ctx)
+	summary, err := l.Generate(partCtx, prompt)
+	usage := recorder.Usage()
+	tracker.ItemDone(usage.InputTokens, usage.OutputTokens)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate summary: %w", err)
+	}
+	return strings.TrimSpace(summary), nil
+}
+
+// summarizeAll summarizes every part, at most cfg.concurrency at a time, and
+// returns the summaries in the order of parts.
+func summarizeAll(ctx context.Context, l gollm.LLM, cfg *summarizeConfig, tracker *progress.Tracker, parts []string, input func(i int, part string) string) ([]string, error) {
+	concurrency := cfg.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	summaries := make([]string, len(parts))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i, part := range parts {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, part string) {
+			defer func() { <-sem; wg.Done() }()
+			summary, err := summarizePart(ctx, l, cfg, tracker, input(i, part))
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("failed to summarize part %d/%d: %w", i+1, len(parts), err)
+					cancel()
+				})
+				return
+			}
+			summaries[i] = summary
+		}(i, part)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// groupSummaries joins consecutive summaries into groups of roughly at most
+// budget tokens. Every group but the last holds at least two summaries, even
+// if they exceed the budget, so there are at most half as many groups as
+// summaries, rounded up.
+func groupSummaries(summaries []string, budget int) []string {
+	var groups []string
+	var group []string
+	tokens := 0
+	for _, summary := range summaries {
+		n := estimateTokens(summary)
+		if len(group) > 1 && tokens+n > budget {
+			groups = append(groups, strings.Join(group, "\n\n"))
+			group, tokens = nil, 0
+		}
+		group = append(group, summary)
+		tokens += n
+	}
+	return append(groups, strings.Join(group, "\n\n"))
+}
+
+// textUnit is a paragraph of a text, or a piece of a paragraph too long for a
+// chunk, with the separator that precedes it in the text.
+type textUnit struct {
+	text   string
+	sep    string
+	tokens int
+}
+
+// chunkText splits text into chunks of at most size estimated tokens each
+// (see estimateTokens), on paragraph boundaries where possible, then on
+// sentence boundaries, and as a last resort inside a sentence. Each chunk
+// after the first starts with the units at the end of the previous chunk
+// that fit in overlap tokens, capped at half the chunk size.
+func chunkText(text string, size, overlap int) []string {
+	if overlap > size/2 {
+		overlap = size / 2
+	}
+	var units []textUnit
+	for _, paragraph := range paragraphsOf(text) {
+		pieces := splitToFit(paragraph, size)
+		pieces[0].sep = "\n\n"
+		units = append(units, pieces...)
+	}
+	if len(units) == 0 {
+		return []string{text}
+	}
+
+	var chunks []string
+	var chunk []textUnit
+	carried := 0 // Number of units at the start of chunk carried over from the previous chunk
+	tokens := 0
+	for _, unit := range units {
+		if len(chunk) > carried && tokens+unit.tokens > size {
+			chunks = append(chunks, joinUnits(chunk))
+			var tail []textUnit
+			tailTokens := 0
+			for i := len(chunk) - 1; i >= 0 && tailTokens+chunk[i].tokens <= overlap; i-- {
+				tail = append([]textUnit{chunk[i]}, tail...)
+				tailTokens += chunk[i].tokens
+			}
+			chunk, carried, tokens = tail, len(tail), tailTokens
+		}
+		for carried > 0 && tokens+unit.tokens > size {
+			tokens -= chunk[0].tokens
+			chunk, carried = chunk[1:], carried-1
+		}
+		chunk = append(chunk, unit)
+		tokens += unit.tokens
+	}
+	return append(chunks, joinUnits(chunk))
+}
+
+// joinUnits joins units into a chunk with their separators.
+func joinUnits(units []textUnit) string {
+	var b strings.Builder
+	for i, unit := range units {
+		if i > 0 {
+			b.WriteString(unit.sep)
+		}
+		b.WriteString(unit.text)
+	}
+	return b.String()
+}
+
+// splitToFit splits a paragraph of more than size estimated tokens into its
+// sentences, and a sentence of more than size tokens into pieces of size
+// tokens. A paragraph within the size is returned whole.
+func splitToFit(paragraph string, size int) []textUnit {
+	if n := estimateTokens(paragraph); n <= size {
+		return []textUnit{{text: paragraph, tokens: n}}
+	}
+	var units []textUnit
+	for _, sentence := range splitSentences(paragraph) {
+		if sentence.tokens <= size {
+			units = append(units, sentence)
+			continue
+		}
+		pieces := splitTokens(sentence.text, size)
+		pieces[0].sep = sentence.sep
+		units = append(units, pieces...)
+	}
+	return units
+}
+
+// splitSentences splits text after sentence-ending punctuation and line
+// breaks. Latin periods, question and exclamation marks only end a sentence
+// before whitespace, so "3.14" and "e.g." inside a sentence are kept. The
+// whitespace between sentences becomes their separator.
+func splitSentences(text string) []textUnit {
+	runes := []rune(text)
+	var sentences []textUnit
+	add := func(sentence, sep string) {
+		if sentence = strings.TrimSpace(sentence); sentence != "" {
+			sentences = append(sentences, textUnit{text: sentence, sep: sep, tokens: estimateTokens(sentence)})
+		}
+	}
+	sep, start := "", 0
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '。', '！', '？', '；', '\n':
+		case '.', '!', '?':
+			if i+1 == len(runes) || !unicode.IsSpace(runes[i+1]) {
+				continue
+			}
+		default:
+			continue
+		}
+		next := i + 1
+		for next < len(runes) && unicode.IsSpace(runes[next]) {
+			next++
+		}
+		add(string(runes[start:i+1]), sep)
+		sep = string(runes[i+1 : next])
+		if runes[i] == '\n' || strings.Contains(sep, "\n") {
+			sep = "\n"
+		}
+		start, i = next, next-1
+	}
+	add(string(runes[start:]), sep)
+	return sentences
+}
+
+// splitTokens splits text into pieces of at most size estimated tokens,
+// counting tokens like estimateTokens.
+func splitTokens(text string, size int) []textUnit {
+	var pieces []textUnit
+	var piece strings.Builder
+	cjk, other := 0, 0
+	for _, r := range text {
+		isCJK := unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+		c, o := cjk, other
+		if isCJK {
+			c++
+		} else if !unicode.IsSpace(r) {
+			o++
+		}
+		if piece.Len() > 0 && c+(o+3)/4 > size {
+			pieces = append(pieces, textUnit{text: piece.String(), tokens: cjk + (other+3)/4})
+			piece.Reset()
+			c, o = 0, 0
+			if isCJK {
+				c = 1
+			} else if !unicode.IsSpace(r) {
+				o = 1
+			}
+		}
+		piece.WriteRune(r)
+		cjk, other = c, o
+	}
+	if piece.Len() > 0 {
+		pieces = append(pieces, textUnit{text: piece.String(), tokens: cjk + (other+3)/4})
+	}
+	return pieces
+}
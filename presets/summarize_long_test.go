@@ -0,0 +1,217 @@
+package presets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gollm "github.com/yockii/gollm_cn"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/progress"
+)
+
+// verboseLLM answers every call with the same response and counts the calls.
+// It is safe for concurrent use.
+type verboseLLM struct {
+	gollm.LLM
+	response string
+	err      error
+	mu       sync.Mutex
+	calls    int
+}
+
+func (v *verboseLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.calls++
+	return v.response, v.err
+}
+
+// paragraph returns a paragraph of n copies of r, n tokens by estimateTokens.
+func paragraph(r rune, n int) string {
+	return strings.Repeat(string(r), n)
+}
+
+func TestChunkText(t *testing.T) {
+	p := []string{paragraph('甲', 10), paragraph('乙', 10), paragraph('丙', 10), paragraph('丁', 10), paragraph('戊', 10)}
+	text := strings.Join(p, "\n\n")
+
+	assert.Equal(t, []string{
+		p[0] + "\n\n" + p[1],
+		p[1] + "\n\n" + p[2],
+		p[2] + "\n\n" + p[3],
+		p[3] + "\n\n" + p[4],
+	}, chunkText(text, 25, 10), "each chunk repeats the last paragraph of the previous one")
+
+	assert.Equal(t, []string{
+		p[0] + "\n\n" + p[1],
+		p[2] + "\n\n" + p[3],
+		p[4],
+	}, chunkText(text, 25, 0))
+
+	assert.Equal(t, []string{
+		p[0] + "\n\n" + p[1],
+		p[1] + "\n\n" + p[2],
+		p[2] + "\n\n" + p[3],
+		p[3] + "\n\n" + p[4],
+	}, chunkText(text, 25, 1000), "overlap is capped at half the chunk size")
+
+	assert.Equal(t, []string{text}, chunkText(text, 100, 10))
+}
+
+func TestChunkText_LongParagraphs(t *testing.T) {
+	chunks := chunkText("第一句话。第二句话！第三句话？", 6, 0)
+	assert.Equal(t, []string{"第一句话。", "第二句话！", "第三句话？"}, chunks, "paragraphs over the size are split into sentences")
+
+	chunks = chunkText("Pi is 3.14 here. Next one!\nDone?", 5, 0)
+	assert.Equal(t, []string{"Pi is 3.14 here.", "Next one!\nDone?"}, chunks, "separators between sentences are kept")
+
+	chunks = chunkText(paragraph('字', 25), 10, 0)
+	assert.Equal(t, []string{paragraph('字', 10), paragraph('字', 10), paragraph('字', 5)}, chunks, "sentences over the size are split by tokens")
+
+	for _, chunk := range chunkText(strings.Repeat(paragraph('长', 37)+"。", 9), 16, 5) {
+		assert.LessOrEqual(t, estimateTokens(chunk), 16)
+	}
+}
+
+func TestGroupSummaries(t *testing.T) {
+	summaries := []string{paragraph('甲', 4), paragraph('乙', 4), paragraph('丙', 4)}
+	assert.Equal(t, []string{strings.Join(summaries, "\n\n")}, groupSummaries(summaries, 20))
+	assert.Len(t, groupSummaries(summaries, 5), 2, "groups hold at least two summaries even over the budget")
+}
+
+func TestSummarizeLong(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"摘要一", "摘要二", "摘要三", "最终摘要"}}
+	text := strings.Join([]string{paragraph('甲', 10), paragraph('乙', 10), paragraph('丙', 10), paragraph('丁', 10), paragraph('戊', 10)}, "\n\n")
+
+	summary, err := SummarizeLong(context.Background(), l, text, WithChunkSize(25), WithChunkOverlap(0), WithSummaryLength(50))
+	require.NoError(t, err)
+	assert.Equal(t, "最终摘要", summary)
+	require.Len(t, l.prompts, 4)
+	assert.Contains(t, l.prompts[0].Input, "第 1/3 部分")
+	assert.Contains(t, l.prompts[3].Input, "摘要一\n\n摘要二\n\n摘要三")
+	assert.Equal(t, 50, l.prompts[3].MaxLength)
+
+	l = &scriptedLLM{responses: []string{"短文摘要"}}
+	summary, err = SummarizeLong(context.Background(), l, "一段短文。")
+	require.NoError(t, err)
+	assert.Equal(t, "短文摘要", summary)
+	assert.Len(t, l.prompts, 1, "a text within the chunk size is summarized in one call")
+}
+
+func TestSummarizeLong_TerminatesWhenSummariesDoNotShrink(t *testing.T) {
+	l := &verboseLLM{response: paragraph('长', 100)}
+	text := strings.Repeat(paragraph('字', 10)+"\n\n", 8)
+
+	_, err := SummarizeLong(context.Background(), l, text, WithChunkSize(10), WithChunkOverlap(0), WithSummaryConcurrency(3))
+	require.NoError(t, err)
+	assert.Equal(t, 8+4+2+1, l.calls, "every round halves the number of summaries")
+}
+
+func TestSummarizeLong_Error(t *testing.T) {
+	l := &verboseLLM{err: errors.New("boom")}
+	text := strings.Repeat(paragraph('字', 10)+"\n\n", 4)
+
+	_, err := SummarizeLong(context.Background(), l, text, WithChunkSize(10), WithSummaryConcurrency(2))
+	assert.ErrorContains(t, err, "failed to summarize part")
+	assert.ErrorContains(t, err, "boom")
+
+	_, err = SummarizeLong(context.Background(), l, " ")
+	assert.ErrorContains(t, err, "text cannot be empty")
+}
+
+func TestSummarizeLong_Refine(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"摘要一", "摘要一二", "摘要一二三"}}
+	p := []string{paragraph('甲', 10), paragraph('乙', 10), paragraph('丙', 10)}
+	var updates []progress.Update
+	reporter := progress.ReporterFunc(func(u progress.Update) { updates = append(updates, u) })
+
+	summary, err := SummarizeLong(context.Background(), l, strings.Join(p, "\n\n"),
+		WithStrategy(StrategyRefine), WithChunkSize(10), WithChunkOverlap(0), WithSummaryProgress(reporter))
+	require.NoError(t, err)
+	assert.Equal(t, "摘要一二三", summary)
+	require.Len(t, l.prompts, 3)
+	assert.Contains(t, l.prompts[0].Input, p[0])
+	assert.Contains(t, l.prompts[1].Input, "当前摘要:\n摘要一\n\n第 2/3 部分:\n"+p[1])
+	assert.Contains(t, l.prompts[2].Input, "当前摘要:\n摘要一二\n\n第 3/3 部分:\n"+p[2])
+
+	var done []int
+	for _, u := range updates {
+		assert.Equal(t, 3, u.Total)
+		done = append(done, u.Done)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3}, done, "progress is reported per chunk")
+	assert.True(t, updates[len(updates)-1].Final)
+
+	_, err = SummarizeLong(context.Background(), l, "文本", WithStrategy("tree"))
+	assert.ErrorContains(t, err, `unknown summary strategy "tree"`)
+}
+
+func TestSummarizeLong_MapReduceProgress(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"摘要一", "摘要二", "摘要三", "最终摘要"}}
+	var updates []progress.Update
+	reporter := progress.ReporterFunc(func(u progress.Update) { updates = append(updates, u) })
+
+	text := strings.Join([]string{paragraph('甲', 10), paragraph('乙', 10), paragraph('丙', 10)}, "\n\n")
+	_, err := SummarizeLong(context.Background(), l, text, WithChunkSize(10), WithChunkOverlap(0), WithSummaryProgress(reporter))
+	require.NoError(t, err)
+	assert.Equal(t, 5, updates[0].Total, "upper bound for 3 chunks")
+	var combine []progress.Update
+	for _, u := range updates {
+		if u.Phase == "combine" {
+			combine = append(combine, u)
+		}
+	}
+	require.NotEmpty(t, combine)
+	assert.Equal(t, 3, combine[0].Done)
+	assert.Equal(t, 4, combine[0].Total, "the upper bound is corrected before the combining call")
+	assert.False(t, combine[0].Final)
+	last := updates[len(updates)-1]
+	assert.Equal(t, progress.Update{Phase: "combine", Done: 4, Total: 4, Elapsed: last.Elapsed, Final: true}, last)
+}
+
+func TestSummaryChunks(t *testing.T) {
+	text := strings.Join([]string{paragraph('甲', 10), paragraph('乙', 10), paragraph('丙', 10)}, "\n\n")
+	assert.Equal(t, 3, SummaryChunks(text, WithChunkSize(10), WithChunkOverlap(0)))
+	assert.Equal(t, 1, SummaryChunks(text))
+	assert.Equal(t, 0, SummaryChunks("  "))
+}
+
+func TestSummarizeLongDetailed(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"摘要一", "摘要二", "最终摘要"}}
+	text := strings.Join([]string{paragraph('甲', 10), paragraph('乙', 10)}, "\n\n")
+
+	result, err := SummarizeLongDetailed(context.Background(), l, text, WithChunkSize(10), WithChunkOverlap(0))
+	require.NoError(t, err)
+	assert.Equal(t, &LongSummary{Summary: "最终摘要", ChunkSummaries: []string{"摘要一", "摘要二"}, ChunkSize: 10}, result)
+
+	l = &scriptedLLM{responses: []string{"摘要一", "摘要一二"}}
+	result, err = SummarizeLongDetailed(context.Background(), l, text, WithStrategy(StrategyRefine), WithChunkSize(10), WithChunkOverlap(0))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"摘要一", "摘要一二"}, result.ChunkSummaries, "refine returns the running summaries")
+	assert.Equal(t, "摘要一二", result.Summary)
+}
+
+func TestSummarizeLong_ChunkSizeFromContextWindow(t *testing.T) {
+	gollm.RegisterContextWindow("summarize-test-model", 2100)
+	l := &scriptedLLM{model: "summarize-test-model-v2", responses: []string{"摘要一", "摘要二", "最终摘要"}}
+	text := strings.Join([]string{paragraph('甲', 500), paragraph('乙', 500)}, "\n\n")
+
+	// (2100 - 500 prompt tokens - 4*100 for the summary) * 3/4 = 900 tokens.
+	result, err := SummarizeLongDetailed(context.Background(), l, text, WithSummaryLength(100), WithChunkOverlap(0))
+	require.NoError(t, err)
+	assert.Equal(t, 900, result.ChunkSize)
+	assert.Len(t, result.ChunkSummaries, 2)
+
+	l = &scriptedLLM{model: "unknown-model", responses: []string{"摘要"}}
+	result, err = SummarizeLongDetailed(context.Background(), l, text)
+	require.NoError(t, err)
+	assert.Equal(t, defaultChunkSize, result.ChunkSize, "models with an unknown context window use the default")
+
+	_, err = SummarizeLongDetailed(context.Background(), &scriptedLLM{model: "summarize-test-model"}, text, WithSummaryLength(1000))
+	assert.ErrorContains(t, err, "too small for a summary of 1000 words")
+}
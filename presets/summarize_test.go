@@ -0,0 +1,48 @@
+package presets
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeWithOptions(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"- 要点一\n- 要点二"}}
+	summary, err := SummarizeWithOptions(context.Background(), l, "一篇报告。", SummarizeOptions{
+		MaxWords:       100,
+		Format:         SummaryBullets,
+		Tone:           "正式",
+		PreserveQuotes: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "- 要点一\n- 要点二", summary)
+	require.Len(t, l.prompts, 1)
+	prompt := l.prompts[0]
+	assert.Equal(t, 100, prompt.MaxLength)
+	directives := strings.Join(prompt.Directives, "\n")
+	assert.Contains(t, directives, "要点列表")
+	assert.Contains(t, directives, "不超过 100 字")
+	assert.Contains(t, directives, "使用正式的语气")
+	assert.Contains(t, directives, "逐字引用")
+}
+
+func TestSummarizeWithOptions_ZeroValueMatchesSummarize(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"摘要", "摘要"}}
+	_, err := Summarize(context.Background(), l, "一段文本。")
+	require.NoError(t, err)
+	_, err = SummarizeWithOptions(context.Background(), l, "一段文本。", SummarizeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, l.prompts[0], l.prompts[1])
+}
+
+func TestSummarizeWithOptions_Invalid(t *testing.T) {
+	l := &scriptedLLM{}
+	_, err := SummarizeWithOptions(context.Background(), l, "一段文本。", SummarizeOptions{Format: "table"})
+	assert.ErrorContains(t, err, `unknown summary format "table"`)
+	_, err = SummarizeWithOptions(context.Background(), l, "一段文本。", SummarizeOptions{MaxWords: -1})
+	assert.Error(t, err)
+	assert.Empty(t, l.prompts)
+}
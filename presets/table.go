@@ -0,0 +1,261 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// Column types of a ColumnSpec.
+const (
+	ColumnString = "string"
+	ColumnInt    = "int"
+	ColumnFloat  = "float"
+	ColumnDate   = "date"
+)
+
+// defaultDateLayout is the layout ExtractTable writes dates in.
+const defaultDateLayout = "2006-01-02"
+
+// dateLayouts are the layouts ExtractTable reads dates in.
+var dateLayouts = []string{
+	"2006-01-02", "2006-1-2", "2006/01/02", "2006/1/2", "2006.01.02", "2006.1.2",
+	"2006年1月2日", "2006年01月02日", "20060102",
+	"Jan 2, 2006", "January 2, 2006", "2 Jan 2006", "2 January 2006",
+	time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04",
+}
+
+// numberNoise is removed from numbers before they are parsed: thousands
+// separators, spaces and currency symbols.
+var numberNoise = strings.NewReplacer(",", "", "_", "", " ", "", "¥", "", "￥", "", "$", "", "€", "", "£", "")
+
+// ColumnSpec describes a column of the table extracted by ExtractTable.
+type ColumnSpec struct {
+	Name        string // Name of the column, used as its CSV header
+	Type        string // ColumnString, ColumnInt, ColumnFloat or ColumnDate; empty means ColumnString
+	Required    bool   // Whether every row must have a value
+	Description string // What the column holds, shown to the LLM; optional
+}
+
+// Table is the result of ExtractTable.
+type Table struct {
+	Columns []ColumnSpec // The columns, in order
+	Rows    [][]string   // The rows, each with a cell per column; rows with errors are kept
+	Errors  []RowError   // The problems found in the rows, in row order
+}
+
+// RowError is a problem with a row extracted by ExtractTable: a missing
+// required value, a value that is not of its column's type, or a wrong number
+// of cells.
+type RowError struct {
+	Row     int    // Index of the row in Table.Rows
+	Column  string // Name of the column, empty for problems with the whole row
+	Value   string // The value as extracted
+	Message string // What is wrong
+}
+
+// Error describes the problem, with the row counted from 1.
+func (e RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("row %d: %s", e.Row+1, e.Message)
+	}
+	return fmt.Sprintf("row %d, column %q: %s", e.Row+1, e.Column, e.Message)
+}
+
+// WriteCSV writes the table as CSV, with the column names as header.
+func (t *Table) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := make([]string, len(t.Columns))
+	for i, column := range t.Columns {
+		header[i] = column.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// TableOption configures ExtractTable.
+type TableOption func(*tableConfig)
+
+type tableConfig struct {
+	dateLayout string
+}
+
+// WithDateLayout sets the layout dates are written in, such as "2006/01/02".
+// The default is "2006-01-02".
+func WithDateLayout(layout string) TableOption {
+	return func(c *tableConfig) {
+		c.dateLayout = layout
+	}
+}
+
+// tableRow is a row as the LLM extracts it, with a cell per column.
+type tableRow struct {
+	Cells []string `json:"cells"`
+}
+
+// ExtractTable turns the records in a text, such as a messy report, into a
+// table with the given columns. Each cell is coerced to its column's type:
+// integers and decimals lose thousands separators and currency symbols and
+// are written plainly, and dates in common layouts are written as
+// "2006-01-02". Rows with a missing required value, a value that cannot be
+// coerced, or a wrong number of cells are kept, with the value as extracted,
+// and reported in Table.Errors rather than dropped. It uses
+// ExtractStructuredList, so the whole table is extracted in a single call.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for extraction
+//   - text: The text holding the records
+//   - columns: The columns of the table, in order
+//   - opts: Optional options such as WithDateLayout
+//
+// Returns:
+//   - *Table: The rows and the problems found in them
+//   - error: Any error encountered during extraction, or invalid columns
+//
+// Example:
+//
+//	table, err := ExtractTable(ctx, llm, report, []ColumnSpec{
+//	    {Name: "门店", Required: true},
+//	    {Name: "日期", Type: ColumnDate, Required: true},
+//	    {Name: "销售额", Type: ColumnFloat, Description: "单位为元"},
+//	})
+//	for _, rowErr := range table.Errors {
+//	    log.Println(rowErr)
+//	}
+//	err = table.WriteCSV(file)
+func ExtractTable(ctx context.Context, l gollm.LLM, text string, columns []ColumnSpec, opts ...TableOption) (*Table, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text cannot be empty")
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("at least one column must be provided")
+	}
+	columns = append([]ColumnSpec(nil), columns...)
+	seen := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		if strings.TrimSpace(column.Name) == "" {
+			return nil, fmt.Errorf("column %d has no name", i+1)
+		}
+		if seen[column.Name] {
+			return nil, fmt.Errorf("duplicate column %q", column.Name)
+		}
+		seen[column.Name] = true
+		switch column.Type {
+		case "":
+			columns[i].Type = ColumnString
+		case ColumnString, ColumnInt, ColumnFloat, ColumnDate:
+		default:
+			return nil, fmt.Errorf("column %q has unknown type %q", column.Name, column.Type)
+		}
+	}
+	cfg := &tableConfig{dateLayout: defaultDateLayout}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	var columnList strings.Builder
+	columnList.WriteString("表格的列，按顺序：")
+	for i, column := range columns {
+		fmt.Fprintf(&columnList, "\n%d. %s（%s", i+1, column.Name, column.Type)
+		if column.Required {
+			columnList.WriteString("，必填")
+		}
+		columnList.WriteString("）")
+		if column.Description != "" {
+			columnList.WriteString("：" + column.Description)
+		}
+	}
+	rows, err := ExtractStructuredList[tableRow](ctx, l, text, WithPromptOptions(gollm.WithDirectives(
+		"把文本中的记录整理成表格，每个条目是一行，cells 按列的顺序给出每一列的值",
+		columnList.String(),
+		fmt.Sprintf("每行恰好 %d 个值；文本中没有的值留空字符串，不要编造", len(columns)),
+		"数字只写数值，不带单位；日期尽量写成 YYYY-MM-DD",
+	)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract table: %w", err)
+	}
+
+	table := &Table{Columns: columns, Rows: make([][]string, len(rows))}
+	for i, row := range rows {
+		cells := row.Cells
+		if len(cells) != len(columns) {
+			table.Errors = append(table.Errors, RowError{Row: i, Message: fmt.Sprintf("has %d cells, expected %d", len(cells), len(columns))})
+			cells = append(cells, make([]string, max(0, len(columns)-len(cells)))...)[:len(columns)]
+		}
+		table.Rows[i] = make([]string, len(columns))
+		for j, column := range columns {
+			value := strings.TrimSpace(cells[j])
+			if value == "" {
+				if column.Required {
+					table.Errors = append(table.Errors, RowError{Row: i, Column: column.Name, Message: "missing required value"})
+				}
+				continue
+			}
+			coerced, err := coerceCell(value, column.Type, cfg.dateLayout)
+			if err != nil {
+				table.Errors = append(table.Errors, RowError{Row: i, Column: column.Name, Value: value, Message: err.Error()})
+				coerced = value
+			}
+			table.Rows[i][j] = coerced
+		}
+	}
+	return table, nil
+}
+
+// coerceCell converts value to the canonical form of its column type.
+func coerceCell(value, columnType, dateLayout string) (string, error) {
+	switch columnType {
+	case ColumnInt:
+		number := numberNoise.Replace(halfWidth(value))
+		if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+			return strconv.FormatInt(n, 10), nil
+		}
+		if f, err := strconv.ParseFloat(number, 64); err == nil && f == float64(int64(f)) {
+			return strconv.FormatInt(int64(f), 10), nil
+		}
+		return "", fmt.Errorf("%q is not an integer", value)
+	case ColumnFloat:
+		f, err := strconv.ParseFloat(numberNoise.Replace(halfWidth(value)), 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", value)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case ColumnDate:
+		date := halfWidth(value)
+		for _, layout := range dateLayouts {
+			if t, err := time.Parse(layout, date); err == nil {
+				return t.Format(dateLayout), nil
+			}
+		}
+		return "", fmt.Errorf("%q is not a date", value)
+	}
+	return value, nil
+}
+
+// halfWidth converts the full-width ASCII variants in s, such as "１２３", to
+// ASCII.
+func halfWidth(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '！' && r <= '～' {
+			return r - ('！' - '!')
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,61 @@
+package presets
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractTable(t *testing.T) {
+	l := &scriptedLLM{responses: []string{`[
+		{"cells": ["西湖店", "2024年3月1日", "１２,３４５.５0", "12"]},
+		{"cells": ["", "2024/3/2", "¥8,000", "7.0"]},
+		{"cells": ["滨江店", "上周五", "很多", "3.5"]},
+		{"cells": ["萧山店", "2024-03-04"]}
+	]`}}
+
+	table, err := ExtractTable(context.Background(), l, "三月门店销售报告……", []ColumnSpec{
+		{Name: "门店", Required: true},
+		{Name: "日期", Type: ColumnDate, Required: true},
+		{Name: "销售额", Type: ColumnFloat, Description: "单位为元"},
+		{Name: "订单数", Type: ColumnInt},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"西湖店", "2024-03-01", "12345.5", "12"},
+		{"", "2024-03-02", "8000", "7"},
+		{"滨江店", "上周五", "很多", "3.5"},
+		{"萧山店", "2024-03-04", "", ""},
+	}, table.Rows, "rows with errors are kept")
+	assert.Equal(t, []RowError{
+		{Row: 1, Column: "门店", Message: "missing required value"},
+		{Row: 2, Column: "日期", Value: "上周五", Message: `"上周五" is not a date`},
+		{Row: 2, Column: "销售额", Value: "很多", Message: `"很多" is not a number`},
+		{Row: 2, Column: "订单数", Value: "3.5", Message: `"3.5" is not an integer`},
+		{Row: 3, Message: "has 2 cells, expected 4"},
+	}, table.Errors)
+	assert.Equal(t, `row 2, column "门店": missing required value`, table.Errors[0].Error())
+	assert.Contains(t, l.prompts[0].Directives[1], "3. 销售额（float）：单位为元")
+
+	var csv bytes.Buffer
+	require.NoError(t, table.WriteCSV(&csv))
+	assert.Equal(t, "门店,日期,销售额,订单数\n西湖店,2024-03-01,12345.5,12\n,2024-03-02,8000,7\n滨江店,上周五,很多,3.5\n萧山店,2024-03-04,,\n", csv.String())
+}
+
+func TestExtractTable_Options(t *testing.T) {
+	l := &scriptedLLM{responses: []string{`[{"cells": ["Jan 5, 2024"]}]`}}
+	table, err := ExtractTable(context.Background(), l, "报告", []ColumnSpec{{Name: "日期", Type: ColumnDate}}, WithDateLayout("2006/01/02"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"2024/01/05"}}, table.Rows)
+	assert.Empty(t, table.Errors)
+
+	_, err = ExtractTable(context.Background(), &scriptedLLM{}, "报告", nil)
+	assert.ErrorContains(t, err, "at least one column must be provided")
+	_, err = ExtractTable(context.Background(), &scriptedLLM{}, "报告", []ColumnSpec{{Name: "金额", Type: "money"}})
+	assert.ErrorContains(t, err, `column "金额" has unknown type "money"`)
+	_, err = ExtractTable(context.Background(), &scriptedLLM{}, "报告", []ColumnSpec{{Name: "门店"}, {Name: "门店"}})
+	assert.ErrorContains(t, err, `duplicate column "门店"`)
+}
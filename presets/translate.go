@@ -0,0 +1,217 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// defaultTranslateChunkTokens is the default token budget of a chunk sent to
+// the LLM by Translate.
+const defaultTranslateChunkTokens = 1500
+
+// TranslateOption configures Translate.
+type TranslateOption func(*translateConfig)
+
+type translateConfig struct {
+	glossary    map[string]string
+	chunkTokens int
+}
+
+// WithGlossary forces the translation of specific terms, such as product names
+// or technical vocabulary. Keys are source terms and values their required
+// translations. Only the terms occurring in a chunk are sent with it.
+func WithGlossary(glossary map[string]string) TranslateOption {
+	return func(c *translateConfig) {
+		if c.glossary == nil {
+			c.glossary = make(map[string]string, len(glossary))
+		}
+		for term, translation := range glossary {
+			c.glossary[term] = translation
+		}
+	}
+}
+
+// WithChunkTokens sets the approximate token budget of each chunk of a long
+// text. The default is 1500.
+func WithChunkTokens(n int) TranslateOption {
+	return func(c *translateConfig) {
+		c.chunkTokens = n
+	}
+}
+
+// Translate translates text from sourceLang to targetLang, preserving its
+// Markdown structure. Texts longer than the chunk budget (see WithChunkTokens)
+// are split on paragraph boundaries, never inside a fenced code block, and
+// translated chunk by chunk; each chunk is sent with the end of the previous
+// one and its translation, so terminology and tone stay consistent across
+// chunks.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for translation
+//   - text: The text to translate
+//   - sourceLang: Language of the text, e.g. "中文"; empty to detect it
+//   - targetLang: Language to translate into, e.g. "English"
+//   - opts: Optional translation options
+//
+// Returns:
+//   - string: The translated text, chunks joined by blank lines
+//   - error: Any error encountered during translation
+//
+// Example:
+//
+//	translated, err := Translate(ctx, llm, readme, "中文", "English",
+//	    WithGlossary(map[string]string{"协程": "goroutine", "通道": "channel"}),
+//	)
+func Translate(ctx context.Context, l gollm.LLM, text, sourceLang, targetLang string, opts ...TranslateOption) (string, error) {
+	if l == nil {
+		return "", fmt.Errorf("LLM instance cannot be nil")
+	}
+	if strings.TrimSpace(targetLang) == "" {
+		return "", fmt.Errorf("target language cannot be empty")
+	}
+	cfg := &translateConfig{chunkTokens: defaultTranslateChunkTokens}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.chunkTokens <= 0 {
+		return "", fmt.Errorf("chunk tokens must be positive, got %d", cfg.chunkTokens)
+	}
+
+	chunks := splitParagraphs(text, cfg.chunkTokens)
+	translated := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		var previous, previousTranslation string
+		if i > 0 {
+			previous = lastParagraph(chunks[i-1])
+			previousTranslation = lastParagraph(translated[i-1])
+		}
+		prompt := translatePrompt(chunk, sourceLang, targetLang, cfg.glossary, previous, previousTranslation)
+		response, err := l.Generate(ctx, prompt)
+		if err != nil {
+			return "", fmt.Errorf("failed to translate chunk %d of %d: %w", i+1, len(chunks), err)
+		}
+		translated = append(translated, strings.TrimSpace(response))
+	}
+	return strings.Join(translated, "\n\n"), nil
+}
+
+// translatePrompt builds the prompt translating one chunk.
+func translatePrompt(chunk, sourceLang, targetLang string, glossary map[string]string, previous, previousTranslation string) *gollm.Prompt {
+	directives := []string{}
+	if sourceLang == "" {
+		directives = append(directives, fmt.Sprintf("自动识别原文的语言，并将其翻译为%s", targetLang))
+	} else {
+		directives = append(directives, fmt.Sprintf("将原文从%s翻译为%s", sourceLang, targetLang))
+	}
+	directives = append(directives,
+		"保留 Markdown 结构：标题、列表、表格、链接和强调标记保持原样，只翻译其中的文字",
+		"代码块、行内代码和 URL 保持原样，不要翻译",
+		"只输出译文，不要添加解释或说明",
+	)
+	if terms := glossaryTerms(glossary, chunk); len(terms) > 0 {
+		directives = append(directives, "以下术语必须按术语表翻译："+strings.Join(terms, "；"))
+	}
+
+	opts := []gollm.PromptOption{gollm.WithDirectives(directives...)}
+	if previous != "" {
+		opts = append(opts, gollm.WithContext(fmt.Sprintf(
+			"原文是一篇长文档的一部分。上一段原文及其译文如下，仅供保持术语和语气一致，不要再次翻译：\n\n原文：\n%s\n\n译文：\n%s",
+			previous, previousTranslation)))
+	}
+	return gollm.NewPrompt(chunk, opts...)
+}
+
+// glossaryTerms returns the glossary entries whose term occurs in text, as
+// sorted "term → translation" strings.
+func glossaryTerms(glossary map[string]string, text string) []string {
+	lower := strings.ToLower(text)
+	var terms []string
+	for term, translation := range glossary {
+		if term != "" && strings.Contains(lower, strings.ToLower(term)) {
+			terms = append(terms, term+" → "+translation)
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// splitParagraphs splits text into chunks of whole paragraphs of roughly at
+// most budget tokens each (see paragraphsOf); a paragraph over the budget forms
+// a chunk of its own.
+func splitParagraphs(text string, budget int) []string {
+	paragraphs := paragraphsOf(text)
+	if len(paragraphs) == 0 {
+		return []string{text}
+	}
+
+	var chunks []string
+	var chunk []string
+	tokens := 0
+	for _, paragraph := range paragraphs {
+		n := estimateTokens(paragraph)
+		if len(chunk) > 0 && tokens+n > budget {
+			chunks = append(chunks, strings.Join(chunk, "\n\n"))
+			chunk, tokens = nil, 0
+		}
+		chunk = append(chunk, paragraph)
+		tokens += n
+	}
+	return append(chunks, strings.Join(chunk, "\n\n"))
+}
+
+// paragraphsOf returns the paragraphs of text, which are separated by blank
+// lines outside fenced code blocks, so a code block is never split.
+func paragraphsOf(text string) []string {
+	var paragraphs []string
+	var current []string
+	inFence := false
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.TrimSpace(line) == "" {
+			if len(current) > 0 {
+				paragraphs = append(paragraphs, strings.Join(current, "\n"))
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, strings.Join(current, "\n"))
+	}
+	return paragraphs
+}
+
+// lastParagraph returns the last paragraph of a chunk.
+func lastParagraph(chunk string) string {
+	chunk = strings.TrimSpace(chunk)
+	if i := strings.LastIndex(chunk, "\n\n"); i >= 0 {
+		return chunk[i+2:]
+	}
+	return chunk
+}
+
+// estimateTokens approximates the number of tokens of text without loading a
+// tokenizer: one per CJK character and one per four other characters.
+func estimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+		case !unicode.IsSpace(r):
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
@@ -0,0 +1,52 @@
+package presets
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslate_Glossary(t *testing.T) {
+	l := &scriptedLLM{responses: []string{"Goroutines communicate through channels.\n"}}
+
+	translated, err := Translate(context.Background(), l, "协程通过通道通信。", "中文", "English",
+		WithGlossary(map[string]string{"协程": "goroutine", "通道": "channel", "互斥锁": "mutex"}),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "Goroutines communicate through channels.", translated)
+
+	require.Len(t, l.prompts, 1)
+	directives := l.prompts[0].Directives
+	assert.Contains(t, directives, "将原文从中文翻译为English")
+	assert.Contains(t, directives, "以下术语必须按术语表翻译：协程 → goroutine；通道 → channel",
+		"only terms occurring in the text are sent")
+	assert.Empty(t, l.prompts[0].Context)
+}
+
+func TestTranslate_ChunksLongText(t *testing.T) {
+	code := "```go\nfmt.Println(\"你好\")\n\nfmt.Println(\"世界\")\n```"
+	text := "# 标题\n\n" + strings.Repeat("甲", 8) + "\n\n" + code + "\n\n" + strings.Repeat("乙", 8)
+	l := &scriptedLLM{responses: []string{"# Title\n\nAAAA", "CODE", "BBBB"}}
+
+	translated, err := Translate(context.Background(), l, text, "", "English", WithChunkTokens(12))
+	require.NoError(t, err)
+	assert.Equal(t, "# Title\n\nAAAA\n\nCODE\n\nBBBB", translated)
+
+	require.Len(t, l.prompts, 3)
+	assert.Equal(t, "# 标题\n\n"+strings.Repeat("甲", 8), l.prompts[0].Input)
+	assert.Equal(t, code, l.prompts[1].Input, "code blocks are never split")
+	assert.Equal(t, strings.Repeat("乙", 8), l.prompts[2].Input)
+	assert.Contains(t, l.prompts[0].Directives, "自动识别原文的语言，并将其翻译为English")
+	assert.Contains(t, l.prompts[1].Context, "原文：\n"+strings.Repeat("甲", 8)+"\n\n译文：\nAAAA")
+}
+
+func TestTranslate_Errors(t *testing.T) {
+	_, err := Translate(context.Background(), &scriptedLLM{}, "你好", "中文", "")
+	assert.ErrorContains(t, err, "target language")
+
+	_, err = Translate(context.Background(), &scriptedLLM{}, "你好", "中文", "English", WithChunkTokens(0))
+	assert.ErrorContains(t, err, "chunk tokens must be positive")
+}
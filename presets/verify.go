@@ -0,0 +1,178 @@
+// Package presets provides utilities for enhancing Language Learning Model interactions
+// with specific reasoning patterns and text processing capabilities.
+package presets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	gollm "github.com/yockii/gollm_cn"
+)
+
+// VerdictInsufficient is the verdict of VerifyClaims on a claim the evidence
+// does not settle.
+const VerdictInsufficient = "insufficient"
+
+// Verdict is the verdict of VerifyClaims on a claim.
+type Verdict struct {
+	Claim      string     // The claim
+	Verdict    string     // VerdictSupported, VerdictRefuted or VerdictInsufficient
+	Citations  []Citation // The evidence documents quoted, in order of first quote
+	Quotes     []Quote    // The spans of the evidence the verdict rests on, all found in their documents
+	Reasoning  string     // Why the evidence supports or refutes the claim
+	Downgraded bool       // Whether the verdict was made insufficient because it quoted nothing, or text not in the evidence
+}
+
+// Quote is a span quoted from an evidence document.
+type Quote struct {
+	DocumentID string
+	Text       string
+}
+
+// VerifyOption configures VerifyClaims.
+type VerifyOption func(*verifyConfig)
+
+type verifyConfig struct {
+	budget int
+}
+
+// WithEvidenceBudget sets the token budget of the evidence in the prompt.
+// The default is 3000.
+func WithEvidenceBudget(tokens int) VerifyOption {
+	return func(c *verifyConfig) {
+		c.budget = tokens
+	}
+}
+
+// claimVerdict is a verdict as the LLM gives it.
+type claimVerdict struct {
+	Claim     int             `json:"claim" validate:"gte=1"`
+	Verdict   string          `json:"verdict" validate:"required,oneof=supported refuted insufficient"`
+	Evidence  []claimEvidence `json:"evidence" validate:"dive"`
+	Reasoning string          `json:"reasoning"`
+}
+
+type claimEvidence struct {
+	DocumentID string `json:"document_id" validate:"required"`
+	Quote      string `json:"quote" validate:"required"`
+}
+
+// VerifyClaims judges each claim against evidence documents only: supported,
+// refuted, or insufficient when the evidence does not settle it. Verdicts
+// cite the documents they rest on and quote the spans that decide them. Every
+// quote is checked against the document it cites, ignoring differences in
+// whitespace; a supported or refuted verdict with a quote that does not occur
+// in its document, or with no quote at all, is downgraded to insufficient,
+// so a hallucinated justification cannot confirm a claim. Evidence is packed
+// like the documents of AnswerWithContext, so the same retrieved documents
+// can be used to post-check an answer. All claims are verified in a single
+// call with ExtractStructuredList.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - l: LLM instance to use for verification
+//   - claims: The claims to verify, such as the sentences of an answer
+//   - evidence: The documents to verify against, most important first
+//   - opts: Optional options such as WithEvidenceBudget
+//
+// Returns:
+//   - []Verdict: A verdict per claim, in the order of claims
+//   - error: Any error encountered during verification
+//
+// Example:
+//
+//	verdicts, err := VerifyClaims(ctx, llm, []string{"生鲜商品不支持无理由退货。"}, documents)
+//	for _, v := range verdicts {
+//	    if v.Verdict != VerdictSupported {
+//	        log.Printf("未被文档支持：%s（%s）", v.Claim, v.Reasoning)
+//	    }
+//	}
+func VerifyClaims(ctx context.Context, l gollm.LLM, claims []string, evidence []Document, opts ...VerifyOption) ([]Verdict, error) {
+	if l == nil {
+		return nil, fmt.Errorf("LLM instance cannot be nil")
+	}
+	if len(claims) == 0 {
+		return nil, fmt.Errorf("at least one claim must be provided")
+	}
+	var claimList strings.Builder
+	for i, claim := range claims {
+		if strings.TrimSpace(claim) == "" {
+			return nil, fmt.Errorf("claim %d is empty", i+1)
+		}
+		fmt.Fprintf(&claimList, "%d. %s\n", i+1, strings.TrimSpace(claim))
+	}
+	cfg := &verifyConfig{budget: defaultDocumentBudget}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	chunks, sent, _ := packDocuments(l, evidence, cfg.budget)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no evidence fits in the budget of %d tokens", cfg.budget)
+	}
+
+	judged, err := ExtractStructuredList[claimVerdict](ctx, l, claimList.String(), WithPromptOptions(
+		gollm.WithContextInjection(chunks, 0),
+		gollm.WithDirectives(
+			"逐条核查文本中编号的陈述，每条陈述给出一个判断，claim 为陈述的编号",
+			"仅依据检索到的文档判断：verdict 为 supported（文档支持该陈述）、refuted（文档与该陈述矛盾）或 insufficient（文档不足以判断）",
+			"evidence 列出判断所依据的文档片段：document_id 为文档 ID，quote 为从该文档中逐字摘录的原文，不要改写、翻译或省略",
+			"supported 和 refuted 必须至少有一条 evidence；reasoning 简要说明判断的依据",
+		),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify claims: %w", err)
+	}
+
+	verdicts := make([]Verdict, len(claims))
+	done := make([]bool, len(claims))
+	for _, j := range judged {
+		i := j.Claim - 1
+		if i < 0 || i >= len(claims) || done[i] {
+			l.GetLogger().Warn("Ignored verdict on unknown or already judged claim", "claim", j.Claim)
+			continue
+		}
+		done[i] = true
+		verdicts[i] = checkVerdict(l, claims[i], j, sent)
+	}
+	for i, claim := range claims {
+		if !done[i] {
+			l.GetLogger().Warn("No verdict on claim", "claim", i+1)
+			verdicts[i] = Verdict{Claim: claim, Verdict: VerdictInsufficient}
+		}
+	}
+	return verdicts, nil
+}
+
+// checkVerdict keeps the quotes of j found in the documents sent, and
+// downgrades a supported or refuted verdict to insufficient unless all of its
+// quotes were found.
+func checkVerdict(l gollm.LLM, claim string, j claimVerdict, sent map[string]Document) Verdict {
+	v := Verdict{Claim: claim, Verdict: j.Verdict, Reasoning: j.Reasoning}
+	cited := make(map[string]bool)
+	missing := 0
+	for _, e := range j.Evidence {
+		doc, ok := sent[strings.TrimSpace(e.DocumentID)]
+		quote := strings.Trim(strings.TrimSpace(e.Quote), `"“”「」『』'`)
+		if !ok || quote == "" || !strings.Contains(normalizeSpace(doc.Content), normalizeSpace(quote)) {
+			l.GetLogger().Warn("Quote not found in evidence", "document", e.DocumentID, "quote", e.Quote)
+			missing++
+			continue
+		}
+		v.Quotes = append(v.Quotes, Quote{DocumentID: doc.ID, Text: quote})
+		if !cited[doc.ID] {
+			cited[doc.ID] = true
+			v.Citations = append(v.Citations, Citation{DocumentID: doc.ID, Title: doc.Title})
+		}
+	}
+	if v.Verdict != VerdictInsufficient && (missing > 0 || len(v.Quotes) == 0) {
+		v.Verdict = VerdictInsufficient
+		v.Downgraded = true
+	}
+	return v
+}
+
+// normalizeSpace replaces each run of whitespace in s with a single space.
+func normalizeSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,62 @@
+package presets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyClaims(t *testing.T) {
+	documents := []Document{
+		{ID: "doc-1", Title: "售后政策", Content: "退货需在收货后\n  7 天内申请。生鲜商品不支持无理由退货。"},
+		{ID: "doc-2", Title: "配送说明", Content: "订单满 99 元包邮。"},
+	}
+	l := &scriptedLLM{responses: []string{`[
+		{"claim": 1, "verdict": "supported", "evidence": [{"document_id": "doc-1", "quote": "“退货需在收货后 7 天内申请。”"}], "reasoning": "售后政策写明了期限"},
+		{"claim": 2, "verdict": "refuted", "evidence": [{"document_id": "doc-2", "quote": "订单满 99 元包邮。"}, {"document_id": "doc-2", "quote": "订单满 50 元包邮"}], "reasoning": "包邮门槛是 99 元"},
+		{"claim": 3, "verdict": "supported", "evidence": [], "reasoning": "常识"},
+		{"claim": 1, "verdict": "refuted", "evidence": []},
+		{"claim": 7, "verdict": "insufficient", "evidence": []}
+	]`}}
+
+	verdicts, err := VerifyClaims(context.Background(), l, []string{
+		"退货需在 7 天内申请。",
+		"订单满 50 元包邮。",
+		"所有商品都可以开发票。",
+		"客服 24 小时在线。",
+	}, documents)
+	require.NoError(t, err)
+	assert.Equal(t, []Verdict{
+		{
+			Claim:     "退货需在 7 天内申请。",
+			Verdict:   VerdictSupported,
+			Citations: []Citation{{DocumentID: "doc-1", Title: "售后政策"}},
+			Quotes:    []Quote{{DocumentID: "doc-1", Text: "退货需在收货后 7 天内申请。"}},
+			Reasoning: "售后政策写明了期限",
+		},
+		{
+			Claim:      "订单满 50 元包邮。",
+			Verdict:    VerdictInsufficient,
+			Citations:  []Citation{{DocumentID: "doc-2", Title: "配送说明"}},
+			Quotes:     []Quote{{DocumentID: "doc-2", Text: "订单满 99 元包邮。"}},
+			Reasoning:  "包邮门槛是 99 元",
+			Downgraded: true,
+		},
+		{Claim: "所有商品都可以开发票。", Verdict: VerdictInsufficient, Reasoning: "常识", Downgraded: true},
+		{Claim: "客服 24 小时在线。", Verdict: VerdictInsufficient},
+	}, verdicts)
+	assert.Contains(t, l.prompts[0].Input, "4. 客服 24 小时在线。")
+	assert.Contains(t, l.prompts[0].RetrievedContext, "[Source: doc-2]")
+}
+
+func TestVerifyClaims_InvalidInput(t *testing.T) {
+	documents := []Document{{ID: "doc-1", Content: "退货需在收货后 7 天内申请。"}}
+	_, err := VerifyClaims(context.Background(), &scriptedLLM{}, nil, documents)
+	assert.ErrorContains(t, err, "at least one claim must be provided")
+	_, err = VerifyClaims(context.Background(), &scriptedLLM{}, []string{"退货需在 7 天内申请。", " "}, documents)
+	assert.ErrorContains(t, err, "claim 2 is empty")
+	_, err = VerifyClaims(context.Background(), &scriptedLLM{}, []string{"退货需在 7 天内申请。"}, nil)
+	assert.ErrorContains(t, err, "no evidence fits in the budget of 3000 tokens")
+}
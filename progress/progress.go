@@ -0,0 +1,162 @@
+// Package progress reports the progress of long-running work, such as batch
+// extraction over many documents, and renders it for terminals and log files.
+// Presets accept a Reporter, so programs embedding them can display progress
+// their own way.
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// etaWindow is the number of recent item durations averaged for the ETA.
+const etaWindow = 10
+
+// Update is a snapshot of the progress of a task.
+type Update struct {
+	Phase        string        // Current phase of the task, e.g. "extract"
+	Done         int           // Items finished, successfully or not
+	Total        int           // Items in the task; 0 if unknown
+	InputTokens  int           // Prompt tokens used so far
+	OutputTokens int           // Completion tokens used so far
+	Elapsed      time.Duration // Time since the task started
+	ETA          time.Duration // Estimated time until the task finishes; 0 if unknown
+	Final        bool          // Whether this is the last update of the task
+}
+
+// Reporter receives progress updates. A Tracker never calls its reporter
+// concurrently.
+type Reporter interface {
+	Report(Update)
+}
+
+// ReporterFunc adapts a function to the Reporter interface.
+type ReporterFunc func(Update)
+
+// Report calls f(u).
+func (f ReporterFunc) Report(u Update) {
+	f(u)
+}
+
+// Tracker turns item completions into Updates for a Reporter. The ETA is the
+// number of remaining items times the moving average of the time between the
+// last completions, which accounts for items running concurrently. A Tracker
+// is safe for concurrent use; a Tracker with a nil reporter does nothing.
+type Tracker struct {
+	reporter  Reporter
+	mu        sync.Mutex
+	update    Update
+	start     time.Time
+	last      time.Time
+	intervals []time.Duration
+	now       func() time.Time
+}
+
+// NewTracker starts tracking a task of total items and reports its start.
+//
+// Parameters:
+//   - reporter: Receives the updates; may be nil
+//   - phase: Initial phase of the task
+//   - total: Number of items; 0 if unknown
+//
+// Example:
+//
+//	tracker := progress.NewTracker(reporter, "extract", len(texts))
+//	for _, text := range texts {
+//	    // ... process text ...
+//	    tracker.ItemDone(usage.InputTokens, usage.OutputTokens)
+//	}
+func NewTracker(reporter Reporter, phase string, total int) *Tracker {
+	t := &Tracker{reporter: reporter, now: time.Now}
+	t.start = t.now()
+	t.last = t.start
+	t.update = Update{Phase: phase, Total: total}
+	t.report()
+	return t
+}
+
+// SetPhase moves the task to another phase and reports it.
+func (t *Tracker) SetPhase(phase string) {
+	if t.reporter == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.update.Phase = phase
+	t.reportLocked()
+}
+
+// SetTotal changes the number of items of the task and reports it, for tasks
+// that start with an estimate, such as an upper bound, and learn their size as
+// they go.
+func (t *Tracker) SetTotal(total int) {
+	if t.reporter == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.update.Total = total
+	t.reportLocked()
+}
+
+// ItemDone records a finished item and the tokens it used, and reports the
+// new progress.
+func (t *Tracker) ItemDone(inputTokens, outputTokens int) {
+	if t.reporter == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	t.intervals = append(t.intervals, now.Sub(t.last))
+	if len(t.intervals) > etaWindow {
+		t.intervals = t.intervals[1:]
+	}
+	t.last = now
+	t.update.Done++
+	t.update.InputTokens += inputTokens
+	t.update.OutputTokens += outputTokens
+	t.update.Final = t.update.Total > 0 && t.update.Done >= t.update.Total
+	t.reportLocked()
+}
+
+// Finish marks the task as complete and reports it, unless the last item
+// already did. Call it for tasks that can end before all their items are done.
+func (t *Tracker) Finish() {
+	if t.reporter == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.update.Final {
+		return
+	}
+	t.update.Total = t.update.Done
+	t.update.Final = true
+	t.reportLocked()
+}
+
+// report sends the current update to the reporter.
+func (t *Tracker) report() {
+	if t.reporter == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reportLocked()
+}
+
+// reportLocked fills in the timing of the current update and sends it. t.mu
+// must be held.
+func (t *Tracker) reportLocked() {
+	t.update.Elapsed = t.now().Sub(t.start)
+	t.update.ETA = 0
+	if remaining := t.update.Total - t.update.Done; remaining > 0 && len(t.intervals) > 0 {
+		var sum time.Duration
+		for _, d := range t.intervals {
+			sum += d
+		}
+		t.update.ETA = sum / time.Duration(len(t.intervals)) * time.Duration(remaining)
+	}
+	t.reporter.Report(t.update)
+}
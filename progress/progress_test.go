@@ -0,0 +1,87 @@
+package progress
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestTracker(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)}
+	var updates []Update
+	tracker := &Tracker{reporter: ReporterFunc(func(u Update) { updates = append(updates, u) }), now: clock.Now}
+	tracker.start, tracker.last = clock.now, clock.now
+	tracker.update = Update{Phase: "extract", Total: 4}
+
+	clock.Advance(2 * time.Second)
+	tracker.ItemDone(100, 10)
+	clock.Advance(4 * time.Second)
+	tracker.ItemDone(100, 10)
+
+	require.Len(t, updates, 2)
+	assert.Equal(t, Update{
+		Phase:        "extract",
+		Done:         2,
+		Total:        4,
+		InputTokens:  200,
+		OutputTokens: 20,
+		Elapsed:      6 * time.Second,
+		ETA:          6 * time.Second, // 2 items left at 3s each
+	}, updates[1])
+
+	tracker.SetTotal(3)
+	require.Len(t, updates, 3)
+	assert.Equal(t, 3, updates[2].Total)
+	assert.Equal(t, 3*time.Second, updates[2].ETA, "1 item left once the estimate is lowered")
+	assert.False(t, updates[2].Final)
+
+	tracker.Finish()
+	require.Len(t, updates, 4)
+	assert.True(t, updates[3].Final)
+	assert.Equal(t, 2, updates[3].Total)
+	assert.Zero(t, updates[3].ETA)
+
+	// A tracker without a reporter does nothing.
+	NewTracker(nil, "extract", 1).ItemDone(1, 1)
+}
+
+func TestWriter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)}
+	var out bytes.Buffer
+	w := NewWriter(&out, StylePlain, WithPrices(2.5, 10))
+	w.now = clock.Now
+
+	w.Report(Update{Phase: "extract", Total: 10})
+	clock.Advance(300 * time.Millisecond)
+	w.Report(Update{Phase: "extract", Done: 1, Total: 10}) // throttled
+	clock.Advance(800 * time.Millisecond)
+	w.Report(Update{Phase: "extract", Done: 2, Total: 10, InputTokens: 400000, OutputTokens: 50000, Elapsed: 1100 * time.Millisecond, ETA: 4 * time.Second})
+	w.Report(Update{Phase: "extract", Done: 10, Total: 10, Final: true}) // final updates are never throttled
+
+	assert.Equal(t, "extract 0/10 (0%) | tokens 0 in / 0 out | $0.0000 | elapsed 0s\n"+
+		"extract 2/10 (20%) | tokens 400000 in / 50000 out | $1.5000 | elapsed 1s | ETA 4s\n"+
+		"extract 10/10 (100%) | tokens 0 in / 0 out | $0.0000 | elapsed 0s\n", out.String())
+
+	out.Reset()
+	rich := NewWriter(&out, StyleRich)
+	rich.Report(Update{Phase: "optimize", Total: 2})
+	rich.Report(Update{Phase: "optimize", Done: 2, Total: 2, Final: true})
+	assert.Equal(t, "\roptimize 0/2 (0%) | tokens 0 in / 0 out | elapsed 0s\x1b[K"+
+		"\roptimize 2/2 (100%) | tokens 0 in / 0 out | elapsed 0s\x1b[K\n", out.String())
+
+	out.Reset()
+	NewWriter(&out, StyleNone).Report(Update{Phase: "extract", Final: true})
+	assert.Empty(t, out.String())
+
+	_, err := ParseStyle("fancy")
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Style selects how a Writer renders updates.
+type Style string
+
+const (
+	// StyleNone prints nothing.
+	StyleNone Style = "none"
+	// StylePlain appends one line per update, for log files.
+	StylePlain Style = "plain"
+	// StyleRich rewrites a single line with carriage returns, for terminals.
+	StyleRich Style = "rich"
+)
+
+// ParseStyle returns the style named s.
+func ParseStyle(s string) (Style, error) {
+	switch style := Style(s); style {
+	case StyleNone, StylePlain, StyleRich:
+		return style, nil
+	default:
+		return "", fmt.Errorf("unknown progress style %q, expected none, plain or rich", s)
+	}
+}
+
+// Writer is a Reporter that prints updates to an io.Writer, at most one per
+// interval, so that fast tasks do not flood the output. The first update, the
+// update of a new phase and the final update are always printed.
+type Writer struct {
+	w           io.Writer
+	style       Style
+	interval    time.Duration
+	inputPrice  float64
+	outputPrice float64
+
+	mu      sync.Mutex
+	printed time.Time
+	phase   string
+	now     func() time.Time
+}
+
+// WriterOption configures a Writer.
+type WriterOption func(*Writer)
+
+// WithInterval sets the minimum time between printed updates. The default is
+// one second.
+func WithInterval(d time.Duration) WriterOption {
+	return func(w *Writer) {
+		w.interval = d
+	}
+}
+
+// WithPrices makes the Writer show the cost of the tokens used so far, given
+// the prices in dollars per million input and output tokens.
+func WithPrices(inputPerMillion, outputPerMillion float64) WriterOption {
+	return func(w *Writer) {
+		w.inputPrice = inputPerMillion
+		w.outputPrice = outputPerMillion
+	}
+}
+
+// NewWriter returns a Writer that prints updates to w in the given style.
+//
+// Example:
+//
+//	reporter := progress.NewWriter(os.Stderr, progress.StyleRich, progress.WithPrices(2.5, 10))
+//	results, err := presets.ExtractStructuredDataBatch[Invoice](ctx, llm, texts, presets.WithProgress(reporter))
+func NewWriter(w io.Writer, style Style, opts ...WriterOption) *Writer {
+	writer := &Writer{w: w, style: style, interval: time.Second, now: time.Now}
+	for _, opt := range opts {
+		opt(writer)
+	}
+	return writer
+}
+
+// Report prints the update unless another was printed less than the interval
+// ago.
+func (w *Writer) Report(u Update) {
+	if w.style == StyleNone {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	if !w.printed.IsZero() && u.Phase == w.phase && !u.Final && now.Sub(w.printed) < w.interval {
+		return
+	}
+	w.printed = now
+	w.phase = u.Phase
+
+	line := w.format(u)
+	if w.style == StylePlain {
+		fmt.Fprintln(w.w, line)
+		return
+	}
+	// "\x1b[K" clears what is left of a longer previous line
+	fmt.Fprint(w.w, "\r"+line+"\x1b[K")
+	if u.Final {
+		fmt.Fprintln(w.w)
+	}
+}
+
+// format renders an update as a single line.
+func (w *Writer) format(u Update) string {
+	parts := []string{u.Phase}
+	if u.Total > 0 {
+		parts[0] += fmt.Sprintf(" %d/%d (%d%%)", u.Done, u.Total, u.Done*100/u.Total)
+	} else {
+		parts[0] += fmt.Sprintf(" %d", u.Done)
+	}
+	parts = append(parts, fmt.Sprintf("tokens %d in / %d out", u.InputTokens, u.OutputTokens))
+	if w.inputPrice > 0 || w.outputPrice > 0 {
+		cost := (float64(u.InputTokens)*w.inputPrice + float64(u.OutputTokens)*w.outputPrice) / 1e6
+		parts = append(parts, fmt.Sprintf("$%.4f", cost))
+	}
+	parts = append(parts, "elapsed "+u.Elapsed.Round(time.Second).String())
+	if u.ETA > 0 {
+		parts = append(parts, "ETA "+u.ETA.Round(time.Second).String())
+	}
+	return strings.Join(parts, " | ")
+}
@@ -35,6 +35,27 @@ type (
 	// Tools are higher-level abstractions over functions that include usage policies.
 	Tool = utils.Tool
 
+	// ToolHandler executes a call of a Tool, see Tool.Handler.
+	ToolHandler = utils.ToolHandler
+
+	// ImageInput is an image sent alongside the prompt text to providers with vision support.
+	ImageInput = llm.ImageInput
+
+	// PromptWarning is a problem found in a prompt by Prompt.Lint.
+	PromptWarning = llm.PromptWarning
+
+	// ContextChunk is a piece of retrieved content injected into a prompt by WithContextInjection.
+	ContextChunk = llm.ContextChunk
+
+	// FewShotExample is a labeled example, an input and its expected output, of a FewShotSet.
+	FewShotExample = llm.FewShotExample
+
+	// FewShotSet is a pool of examples of a task from which WithFewShot selects the examples of each prompt.
+	FewShotSet = llm.FewShotSet
+
+	// FewShotOption configures a FewShotSet.
+	FewShotOption = llm.FewShotOption
+
 	// PromptOption defines a function that can modify a prompt's configuration.
 	// These are used to customize prompt behavior in a flexible, chainable way.
 	PromptOption = llm.PromptOption
@@ -43,6 +64,18 @@ type (
 	// These control how prompts are validated against schemas.
 	SchemaOption = llm.SchemaOption
 
+	// GenerateOption configures a single Generate call, e.g. WithTemperature.
+	GenerateOption = llm.GenerateOption
+
+	// TokenUsage is a number of tokens reported by providers.
+	TokenUsage = llm.TokenUsage
+
+	// UsageRecorder sums the token usage of the calls made with a context from WithUsageRecorder.
+	UsageRecorder = llm.UsageRecorder
+
+	// TruncateStrategy selects which part of a prompt's input WithAutoTruncate drops.
+	TruncateStrategy = llm.TruncateStrategy
+
 	// ToolCall represents a request from the LLM to use a specific tool.
 	// It includes the tool name and any arguments needed for execution.
 	ToolCall = llm.ToolCall
@@ -51,9 +84,81 @@ type (
 	// These messages provide context for maintaining coherent conversations.
 	MemoryMessage = llm.MemoryMessage
 
+	// ConversationSummarizer condenses the oldest turns of a conversation memory into a summary turn.
+	ConversationSummarizer = llm.ConversationSummarizer
+
 	// PromptTemplate defines a reusable template for generating prompts.
 	// Templates can include variables that are filled in at runtime.
 	PromptTemplate = llm.PromptTemplate
+
+	// TemplateRegistry stores named templates that can include partials and extend each other.
+	TemplateRegistry = llm.TemplateRegistry
+
+	// TemplateLintIssue describes a problem found when linting a TemplateRegistry.
+	TemplateLintIssue = llm.TemplateLintIssue
+
+	// PromptStore persists versioned prompts for a PromptLibrary.
+	PromptStore = llm.PromptStore
+
+	// PromptLibrary manages named, versioned prompts on top of a PromptStore.
+	PromptLibrary = llm.PromptLibrary
+
+	// PromptDiff is the field-by-field comparison of two prompt versions.
+	PromptDiff = llm.PromptDiff
+
+	// PromptFieldChange describes a single field that differs between two prompt versions.
+	PromptFieldChange = llm.PromptFieldChange
+
+	// FilePromptStore stores prompt versions as JSON files on disk.
+	FilePromptStore = llm.FilePromptStore
+
+	// SQLPromptStore stores prompt versions in a SQLite table.
+	SQLPromptStore = llm.SQLPromptStore
+
+	// Embedder is implemented by LLMs that can create embeddings; check for it with a type assertion.
+	Embedder = llm.Embedder
+
+	// ProviderError is an error response from a provider's API, with its status code and message.
+	ProviderError = llm.ProviderError
+
+	// Moderator is implemented by LLMs that can moderate text; check for it with a type assertion.
+	Moderator = llm.Moderator
+
+	// ModerationResult is the verdict of Moderate on a text, with the score of every category.
+	ModerationResult = llm.ModerationResult
+
+	// ModerationCategoryScore is the verdict of Moderate on one category.
+	ModerationCategoryScore = llm.ModerationCategoryScore
+
+	// ModerationCategory is a category the chat model fallback of Moderate scores text for.
+	ModerationCategory = llm.ModerationCategory
+
+	// Batcher is implemented by LLMs that can run a batch of prompts; check for it with a type assertion.
+	Batcher = llm.Batcher
+
+	// BatchJob is a batch of prompts started by SubmitBatch; Wait returns its results.
+	BatchJob = llm.BatchJob
+
+	// BatchResult is the outcome of one prompt of a batch.
+	BatchResult = llm.BatchResult
+
+	// BatchOption configures SubmitBatch.
+	BatchOption = llm.BatchOption
+
+	// BatchNotification is sent to the URL set with WithBatchCallbackURL when a batch finishes.
+	BatchNotification = llm.BatchNotification
+
+	// Reranker is implemented by LLMs that can rank documents by relevance to a query; check for it with a type assertion.
+	Reranker = llm.Reranker
+
+	// RankedDocument is a document ranked by Rerank, with its relevance score.
+	RankedDocument = llm.RankedDocument
+
+	// LogprobsReporter is implemented by LLMs that keep the logprobs of their last response; check for it with a type assertion.
+	LogprobsReporter = llm.LogprobsReporter
+
+	// TokenLogprob is the log probability of a response token, with its most likely alternatives.
+	TokenLogprob = llm.TokenLogprob
 )
 
 // Cache type constants define the available caching strategies.
@@ -63,6 +168,45 @@ const (
 	CacheTypeEphemeral = llm.CacheTypeEphemeral
 )
 
+// Moderation settings and sources of a ModerationResult.
+const (
+	// DefaultModerationThreshold is the score from which the chat model fallback of Moderate flags a category.
+	DefaultModerationThreshold = llm.DefaultModerationThreshold
+	// ModerationSourceEndpoint marks results from the provider's moderation endpoint.
+	ModerationSourceEndpoint = llm.ModerationSourceEndpoint
+	// ModerationSourceModel marks results from the chat model fallback.
+	ModerationSourceModel = llm.ModerationSourceModel
+)
+
+// Statuses of a finished BatchJob.
+const (
+	// BatchStatusCompleted means every prompt of the batch was run; some may have failed.
+	BatchStatusCompleted = llm.BatchStatusCompleted
+	// BatchStatusFailed means the batch could not be run or followed.
+	BatchStatusFailed = llm.BatchStatusFailed
+)
+
+// Truncation strategies for WithAutoTruncate.
+const (
+	// TruncateMiddle keeps the beginning and end of the input and drops the middle.
+	TruncateMiddle = llm.TruncateMiddle
+	// TruncateHead drops the beginning of the input.
+	TruncateHead = llm.TruncateHead
+	// TruncateTail drops the end of the input.
+	TruncateTail = llm.TruncateTail
+)
+
+// Severities and codes of the warnings returned by Prompt.Lint.
+const (
+	SeverityWarning = llm.SeverityWarning
+	SeverityError   = llm.SeverityError
+
+	WarningConflictingDirectives    = llm.WarningConflictingDirectives
+	WarningMissingOutputSpec        = llm.WarningMissingOutputSpec
+	WarningInputTooLong             = llm.WarningInputTooLong
+	WarningUnsubstitutedPlaceholder = llm.WarningUnsubstitutedPlaceholder
+)
+
 // The following variables are re-exported functions from the llm package.
 // They provide the primary means of constructing and customizing prompts.
 var (
@@ -75,6 +219,84 @@ var (
 	// WithSystemPrompt adds a system-level prompt message.
 	WithSystemPrompt = llm.WithSystemPrompt
 
+	// WithPersona puts a persona's instructions before the prompt's system prompt.
+	WithPersona = llm.WithPersona
+
+	// WithCachedPrefix sends a large, stable text before the prompt and marks it as
+	// cacheable for providers with prompt caching (Anthropic).
+	WithCachedPrefix = llm.WithCachedPrefix
+
+	// SubmitBatch runs prompts as a batch, with the provider's batch API (OpenAI) or as concurrent calls.
+	SubmitBatch = llm.SubmitBatch
+
+	// WithBatchConcurrency limits how many prompts a batch of individual calls runs at the same time.
+	WithBatchConcurrency = llm.WithBatchConcurrency
+
+	// WithBatchPollInterval sets how often the status of a native batch is checked.
+	WithBatchPollInterval = llm.WithBatchPollInterval
+
+	// WithBatchCallbackURL makes a batch POST a BatchNotification to a URL when it finishes.
+	WithBatchCallbackURL = llm.WithBatchCallbackURL
+
+	// WithBatchGenerateOptions applies generate options to every prompt of a batch.
+	WithBatchGenerateOptions = llm.WithBatchGenerateOptions
+
+	// WithBatchResultHandler calls a function with the result of each prompt of a batch as soon as it is known.
+	WithBatchResultHandler = llm.WithBatchResultHandler
+
+	// WithBatchIndividualCalls runs a batch as individual calls even when the provider has a batch API.
+	WithBatchIndividualCalls = llm.WithBatchIndividualCalls
+
+	// WithResponsePrefix makes the response start with a given text, prefilled for
+	// providers that support it (Anthropic) and requested from the others.
+	WithResponsePrefix = llm.WithResponsePrefix
+
+	// CohereRerank ranks documents by relevance to a query with Cohere's rerank endpoint, most relevant first.
+	CohereRerank = llm.CohereRerank
+
+	// WithLogprobs asks for the log probabilities of the response's tokens, returned by GetLastLogprobs.
+	WithLogprobs = llm.WithLogprobs
+
+	// NewConversationSummarizer creates a summarizer that condenses the oldest half of a
+	// memory's turns once it holds more than maxTurns turns.
+	NewConversationSummarizer = llm.NewConversationSummarizer
+
+	// WithContextInjection adds retrieved chunks, with source citations and a token budget, before the prompt.
+	WithContextInjection = llm.WithContextInjection
+
+	// NewFewShotSet creates a pool of examples for WithFewShot.
+	NewFewShotSet = llm.NewFewShotSet
+
+	// WithFewShot adds k examples selected from a FewShotSet to the prompt, without embedding.
+	WithFewShot = llm.WithFewShot
+
+	// WithFewShotExamples adds examples selected with FewShotSet.Select to the prompt.
+	WithFewShotExamples = llm.WithFewShotExamples
+
+	// WithFewShotSeed selects random examples of a FewShotSet, the same for every prompt.
+	WithFewShotSeed = llm.WithFewShotSeed
+
+	// WithFewShotEmbedding makes FewShotSet.Select select the examples most similar to the prompt input.
+	WithFewShotEmbedding = llm.WithFewShotEmbedding
+
+	// WithFewShotLogger sets the logger of the warnings of WithFewShot.
+	WithFewShotLogger = llm.WithFewShotLogger
+
+	// WithFewShotMaxTokens sets the token budget of the examples selected from a FewShotSet.
+	WithFewShotMaxTokens = llm.WithFewShotMaxTokens
+
+	// WithImages adds images to the prompt for providers with vision support (OpenAI, Anthropic, Gemini).
+	WithImages = llm.WithImages
+
+	// NewImageInputFromFile reads an image file as base64 data.
+	NewImageInputFromFile = llm.NewImageInputFromFile
+
+	// NewImageInputFromBytes returns image data held in memory as base64 data.
+	NewImageInputFromBytes = llm.NewImageInputFromBytes
+
+	// NewImageInputFromURL returns an image input referring to a URL.
+	NewImageInputFromURL = llm.NewImageInputFromURL
+
 	// WithMessage adds a single message to the prompt.
 	WithMessage = llm.WithMessage
 
@@ -99,23 +321,195 @@ var (
 	// WithMaxLength sets the maximum length for generated responses.
 	WithMaxLength = llm.WithMaxLength
 
+	// WithPromptStopSequences sets sequences that stop generation for every call made with a prompt.
+	WithPromptStopSequences = llm.WithPromptStopSequences
+
+	// WithPromptTopP sets the nucleus sampling parameter for every call made with a prompt.
+	WithPromptTopP = llm.WithPromptTopP
+
+	// WithPromptTopK sets the top-k sampling parameter for every call made with a prompt.
+	WithPromptTopK = llm.WithPromptTopK
+
+	// WithPromptFrequencyPenalty sets the frequency penalty for every call made with a prompt.
+	WithPromptFrequencyPenalty = llm.WithPromptFrequencyPenalty
+
+	// WithPromptPresencePenalty sets the presence penalty for every call made with a prompt.
+	WithPromptPresencePenalty = llm.WithPromptPresencePenalty
+
+	// WithPromptRepetitionPenalty sets the repetition penalty for every call made with a prompt.
+	WithPromptRepetitionPenalty = llm.WithPromptRepetitionPenalty
+
+	// WithPromptSeed sets the sampling seed for every call made with a prompt.
+	WithPromptSeed = llm.WithPromptSeed
+
+	// WithPromptLogitBias biases token strings for every call made with a prompt.
+	WithPromptLogitBias = llm.WithPromptLogitBias
+
 	// WithExamples adds example conversations or outputs.
 	WithExamples = llm.WithExamples
 
 	// WithExpandedStruct enables detailed structure expansion.
 	WithExpandedStruct = llm.WithExpandedStruct
 
+	// WithFieldDescription sets the description of a field in a generated JSON schema.
+	WithFieldDescription = llm.WithFieldDescription
+
+	// WithFieldEnum restricts a field of a generated JSON schema to the given values.
+	WithFieldEnum = llm.WithFieldEnum
+
+	// WithFieldExample adds an example value of a field to a generated JSON schema.
+	WithFieldExample = llm.WithFieldExample
+
+	// WithDefs adds definitions to the "$defs" of a generated JSON schema.
+	WithDefs = llm.WithDefs
+
 	// NewPromptTemplate creates a new template for generating prompts.
 	NewPromptTemplate = llm.NewPromptTemplate
 
 	// WithPromptOptions adds multiple prompt options at once.
 	WithPromptOptions = llm.WithPromptOptions
 
+	// NewTemplateRegistry creates an empty template registry.
+	NewTemplateRegistry = llm.NewTemplateRegistry
+
+	// LoadPromptTemplateFile reads a template from a *.tmpl file, or a *.yaml file that can also declare directives, examples and output format.
+	LoadPromptTemplateFile = llm.LoadPromptTemplateFile
+
+	// LoadPromptTemplateDir returns a registry of the template files under a directory.
+	LoadPromptTemplateDir = llm.LoadPromptTemplateDir
+
+	// WithExtends makes a template inherit from a base template in a TemplateRegistry.
+	WithExtends = llm.WithExtends
+
+	// WithMetadata attaches provenance metadata to a template.
+	WithMetadata = llm.WithMetadata
+
+	// VersionedTemplateName returns the registry name of a template version, "name@version".
+	VersionedTemplateName = llm.VersionedTemplateName
+
 	// WithJSONSchemaValidation enables JSON schema validation.
 	WithJSONSchemaValidation = llm.WithJSONSchemaValidation
 
+	// WithTemperature overrides the client's temperature for a single Generate call.
+	WithTemperature = llm.WithTemperature
+
+	// WithMaxTokens overrides the client's maximum generated tokens for a single Generate call.
+	WithMaxTokens = llm.WithMaxTokens
+
+	// WithModel sends a single Generate call to a different model of the same provider.
+	WithModel = llm.WithModel
+
+	// WithStopSequences sets sequences that stop generation for a single Generate call.
+	WithStopSequences = llm.WithStopSequences
+
+	// WithUsageRecorder returns a context that records the token usage of every call made with it.
+	WithUsageRecorder = llm.WithUsageRecorder
+
+	// AddTokenUsage adds token usage to the recorders of a context; custom LLM
+	// implementations may call it to report their usage.
+	AddTokenUsage = llm.AddTokenUsage
+
+	// WithAutoTruncate shortens the prompt's input to fit the model's context window
+	// for a single Generate call.
+	WithAutoTruncate = llm.WithAutoTruncate
+
+	// WithStrictValidation lints the prompt before a Generate call, failing on errors such as
+	// unsubstituted placeholders and logging the other warnings.
+	WithStrictValidation = llm.WithStrictValidation
+
+	// WithMaxInputLength sets the input length, in characters, above which Prompt.Lint warns.
+	WithMaxInputLength = llm.WithMaxInputLength
+
+	// RegisterContextWindow sets the context window size, in tokens, of a model or
+	// model name prefix.
+	RegisterContextWindow = llm.RegisterContextWindow
+
+	// ContextWindow returns the context window size, in tokens, of a model, and whether it is known.
+	ContextWindow = llm.ContextWindow
+
+	// WithoutPersona leaves the client's persona out of a single Generate call.
+	WithoutPersona = llm.WithoutPersona
+
+	// WithPersonaLanguage applies a language variant of the client's persona to a
+	// single Generate call.
+	WithPersonaLanguage = llm.WithPersonaLanguage
+
+	// WithStructuredOutput makes a single Generate call return JSON conforming to a schema,
+	// using the provider's native structured output when it is supported.
+	WithStructuredOutput = llm.WithStructuredOutput
+
+	// WithTopP overrides the client's nucleus sampling parameter for a single Generate call.
+	WithTopP = llm.WithTopP
+
+	// WithTopK overrides the client's top-k sampling parameter for a single Generate call.
+	WithTopK = llm.WithTopK
+
+	// WithFrequencyPenalty overrides the client's frequency penalty for a single Generate call.
+	WithFrequencyPenalty = llm.WithFrequencyPenalty
+
+	// WithPresencePenalty overrides the client's presence penalty for a single Generate call.
+	WithPresencePenalty = llm.WithPresencePenalty
+
+	// WithRepetitionPenalty overrides the client's repetition penalty for a single Generate call.
+	WithRepetitionPenalty = llm.WithRepetitionPenalty
+
+	// WithSeed overrides the client's sampling seed for a single Generate call.
+	WithSeed = llm.WithSeed
+
+	// WithLogitBias biases token strings for a single Generate call on providers with logit bias (OpenAI).
+	WithLogitBias = llm.WithLogitBias
+
 	// WithStream enables or disables streaming responses.
 	WithStream = config.WithStream
+
+	// NewPromptLibrary creates a prompt library backed by the given store.
+	NewPromptLibrary = llm.NewPromptLibrary
+
+	// NewFilePromptStore creates a PromptStore that keeps prompts as JSON files in a directory.
+	NewFilePromptStore = llm.NewFilePromptStore
+
+	// NewSQLitePromptStore opens a SQLite-backed PromptStore. A driver registered as
+	// "sqlite3" must be imported by the application.
+	NewSQLitePromptStore = llm.NewSQLitePromptStore
+
+	// NewSQLPromptStore creates a PromptStore on an already opened database.
+	NewSQLPromptStore = llm.NewSQLPromptStore
+
+	// ErrPromptNotFound is returned when a prompt name or version does not exist in a store.
+	ErrPromptNotFound = llm.ErrPromptNotFound
+
+	// ErrTemplateVersionExists is returned when registering a template version that already exists.
+	ErrTemplateVersionExists = llm.ErrTemplateVersionExists
+
+	// ErrProviderDoesNotSupportImages is returned when a prompt with images is sent to a provider without vision support.
+	ErrProviderDoesNotSupportImages = llm.ErrProviderDoesNotSupportImages
+
+	// ErrProviderDoesNotSupportEmbeddings is returned by Embed for providers without an embeddings endpoint.
+	ErrProviderDoesNotSupportEmbeddings = llm.ErrProviderDoesNotSupportEmbeddings
+
+	// ErrProviderDoesNotSupportRerank is returned by Rerank for providers without a rerank endpoint.
+	ErrProviderDoesNotSupportRerank = llm.ErrProviderDoesNotSupportRerank
+
+	// ErrProviderDoesNotSupportLogprobs is returned when a prompt asking for logprobs is sent to a provider that cannot return them.
+	ErrProviderDoesNotSupportLogprobs = llm.ErrProviderDoesNotSupportLogprobs
+
+	// ModerationCategories are the categories the chat model fallback of Moderate scores text for.
+	ModerationCategories = llm.ModerationCategories
+
+	// ErrRateLimited matches errors of calls rejected by the provider's rate limit or quota.
+	ErrRateLimited = llm.ErrRateLimited
+
+	// ErrAuthentication matches errors of calls rejected for a missing, invalid or unauthorised API key.
+	ErrAuthentication = llm.ErrAuthentication
+
+	// ErrContextLengthExceeded matches errors of calls whose prompt does not fit in the model's context window.
+	ErrContextLengthExceeded = llm.ErrContextLengthExceeded
+
+	// ErrContentFiltered matches errors of calls rejected by the provider's safety system.
+	ErrContentFiltered = llm.ErrContentFiltered
+
+	// ErrServerError matches errors of calls that failed on the provider's side.
+	ErrServerError = llm.ErrServerError
 )
 
 // CleanResponse processes and cleans up LLM responses by removing markdown formatting
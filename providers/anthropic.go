@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/url"
 	"strings"
+	"unicode"
 
 	"github.com/yockii/gollm_cn/config"
 	"github.com/yockii/gollm_cn/utils"
@@ -87,9 +88,26 @@ func (p *AnthropicProvider) SetOption(key string, value interface{}) {
 func (p *AnthropicProvider) SetDefaultOptions(config *config.Config) {
 	p.SetOption("temperature", config.Temperature)
 	p.SetOption("max_tokens", config.MaxTokens)
-	if config.Seed != nil {
-		p.SetOption("seed", *config.Seed)
-	}
+	setSamplingDefaults(p.SetOption, config)
+}
+
+// anthropicSamplingNames maps sampling parameters to Anthropic's names.
+// Anthropic has no frequency, presence or repetition penalties and no seed.
+var anthropicSamplingNames = map[string]string{
+	"top_p":             "top_p",
+	"top_k":             "top_k",
+	"stop":              "stop_sequences",
+	"frequency_penalty": "",
+	"presence_penalty":  "",
+	"repeat_penalty":    "",
+	"seed":              "",
+}
+
+// adaptSampling adds the configured sampling defaults to a request body and
+// translates them to Anthropic's parameter names.
+func (p *AnthropicProvider) adaptSampling(requestBody map[string]interface{}) {
+	addSamplingDefaults(requestBody, p.options)
+	adaptSamplingOptions(requestBody, anthropicSamplingNames, p.Name(), p.logger)
 }
 
 // Name returns "anthropic" as the provider identifier.
@@ -109,6 +127,30 @@ func (p *AnthropicProvider) Endpoint() string {
 	return "https://api.anthropic.com/v1/messages"
 }
 
+// SupportsSystemPrompt indicates that the system prompt is sent in Anthropic's top-level system field.
+func (p *AnthropicProvider) SupportsSystemPrompt() bool {
+	return true
+}
+
+// SupportsPromptCaching indicates that Anthropic caches the prefix passed as
+// the "cached_prefix" option, which is sent as a separate content block marked
+// with cache_control.
+func (p *AnthropicProvider) SupportsPromptCaching() bool {
+	return true
+}
+
+// SupportsResponsePrefill indicates that Anthropic continues a trailing
+// assistant message, which carries the "response_prefix" option.
+func (p *AnthropicProvider) SupportsResponsePrefill() bool {
+	return true
+}
+
+// SupportsImages indicates that Claude 3 and later models accept images as
+// image content blocks.
+func (p *AnthropicProvider) SupportsImages() bool {
+	return true
+}
+
 // SupportsJSONSchema indicates that Anthropic supports structured output
 // through its system prompts and response formatting capabilities.
 func (p *AnthropicProvider) SupportsJSONSchema() bool {
@@ -146,9 +188,13 @@ func (p *AnthropicProvider) Headers() map[string]string {
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
 func (p *AnthropicProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	maxTokens := p.options["max_tokens"]
+	if override, ok := options["max_tokens"]; ok {
+		maxTokens = override
+	}
 	requestBody := map[string]interface{}{
 		"model":      p.model,
-		"max_tokens": p.options["max_tokens"],
+		"max_tokens": maxTokens,
 		"system":     []map[string]interface{}{},
 		"messages":   []map[string]interface{}{},
 	}
@@ -210,33 +256,71 @@ func (p *AnthropicProvider) PrepareRequest(prompt string, options map[string]int
 	}
 
 	// Handle user message with potential caching
+	content := userContent(prompt, options)
 	userMessage := map[string]interface{}{
-		"role": "user",
-		"content": []map[string]interface{}{
-			{
-				"type": "text",
-				"text": prompt,
-			},
-		},
+		"role":    "user",
+		"content": content,
 	}
 
 	// Add cache_control only if caching is enabled
 	if caching, ok := options["enable_caching"].(bool); ok && caching {
-		userMessage["content"].([]map[string]interface{})[0]["cache_control"] = map[string]string{"type": "ephemeral"}
+		content[len(content)-1]["cache_control"] = map[string]string{"type": "ephemeral"}
 	}
 
 	requestBody["messages"] = append(requestBody["messages"].([]map[string]interface{}), userMessage)
+	if prefill := assistantPrefill(options); prefill != nil {
+		requestBody["messages"] = append(requestBody["messages"].([]map[string]interface{}), prefill)
+	}
 
 	// Add other options
 	for k, v := range options {
-		if k != "system_prompt" && k != "max_tokens" && k != "tools" && k != "tool_choice" && k != "enable_caching" {
+		if k != "system_prompt" && k != "max_tokens" && k != "tools" && k != "tool_choice" && k != "enable_caching" && k != "cached_prefix" && k != "images" && k != "response_prefix" {
 			requestBody[k] = v
 		}
 	}
+	p.adaptSampling(requestBody)
 
 	return json.Marshal(requestBody)
 }
 
+// userContent returns the content blocks of the user message. A cached prefix
+// becomes a block of its own, marked with cache_control, in front of the
+// prompt so that Anthropic caches the request up to and including it. Images
+// follow the prefix as image blocks, ahead of the text as Anthropic recommends.
+func userContent(prompt string, options map[string]interface{}) []map[string]interface{} {
+	var content []map[string]interface{}
+	if prefix, ok := options["cached_prefix"].(string); ok && prefix != "" {
+		content = append(content, map[string]interface{}{
+			"type":          "text",
+			"text":          prefix,
+			"cache_control": map[string]string{"type": "ephemeral"},
+		})
+	}
+	for _, image := range promptImages(options) {
+		source := map[string]interface{}{"type": "url", "url": image.URL}
+		if image.URL == "" {
+			source = map[string]interface{}{"type": "base64", "media_type": image.MediaType, "data": image.Base64}
+		}
+		content = append(content, map[string]interface{}{"type": "image", "source": source})
+	}
+	return append(content, map[string]interface{}{
+		"type": "text",
+		"text": prompt,
+	})
+}
+
+// assistantPrefill returns the assistant message that starts the response
+// with the "response_prefix" option, or nil if there is none. Anthropic
+// rejects a final assistant message ending in whitespace, so it is trimmed.
+func assistantPrefill(options map[string]interface{}) map[string]interface{} {
+	prefix, _ := options["response_prefix"].(string)
+	prefix = strings.TrimRightFunc(prefix, unicode.IsSpace)
+	if prefix == "" {
+		return nil
+	}
+	return map[string]interface{}{"role": "assistant", "content": prefix}
+}
+
 // Helper function to split the system prompt into a maximum of n parts
 func splitSystemPrompt(prompt string, n int) []string {
 	if n <= 1 {
@@ -287,21 +371,25 @@ func (p *AnthropicProvider) PrepareRequestWithSchema(prompt string, options map[
 
 	// Create a system message that enforces the JSON schema
 	systemMsg := fmt.Sprintf("你必须返回一个严格遵守以下 schema 的 JSON 对象:\n%s\n不要包含任何解释性文本，只输出有效的 JSON", string(schemaJSON))
+	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
+		systemMsg = systemPrompt + "\n\n" + systemMsg
+	}
 
 	requestBody := map[string]interface{}{
 		"model":  p.model,
 		"system": systemMsg,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": userContent(prompt, options)},
 		},
 	}
 
 	// Add any additional options
 	for k, v := range options {
-		if k != "system_prompt" { // Skip system_prompt as we're using it for schema
+		if k != "system_prompt" && k != "cached_prefix" && k != "images" { // Skip system_prompt as we're using it for schema
 			requestBody[k] = v
 		}
 	}
+	p.adaptSampling(requestBody)
 
 	return json.Marshal(requestBody)
 }
@@ -460,11 +548,17 @@ func (p *AnthropicProvider) PrepareStreamRequest(prompt string, options map[stri
 		"messages": []map[string]interface{}{
 			{
 				"role":    "user",
-				"content": prompt,
+				"content": userContent(prompt, options),
 			},
 		},
 		"max_tokens": 1024, // Default max tokens
 	}
+	if prefill := assistantPrefill(options); prefill != nil {
+		requestBody["messages"] = append(requestBody["messages"].([]map[string]interface{}), prefill)
+	}
+	delete(options, "cached_prefix")
+	delete(options, "images")
+	delete(options, "response_prefix")
 
 	// Add system prompt if present
 	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
@@ -490,6 +584,7 @@ func (p *AnthropicProvider) PrepareStreamRequest(prompt string, options map[stri
 			requestBody[k] = v
 		}
 	}
+	p.adaptSampling(requestBody)
 
 	return json.Marshal(requestBody)
 }
@@ -0,0 +1,111 @@
+// Package providers implements LLM provider interfaces and implementations.
+package providers
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/yockii/gollm_cn/config"
+)
+
+// AzureOpenAIProvider implements the Provider interface for Azure OpenAI.
+// Azure serves OpenAI models from deployments of an Azure resource: requests
+// go to the chat completions endpoint of a deployment, with the API version as
+// a query parameter and the key in the api-key header. Request and response
+// bodies are the same as OpenAI's, so everything else is inherited from
+// OpenAIProvider.
+type AzureOpenAIProvider struct {
+	*OpenAIProvider
+	deployment string // Name of the deployment serving the model
+	apiVersion string // Azure OpenAI API version, e.g. "2024-10-21"
+}
+
+// NewAzureOpenAIProvider creates a new Azure OpenAI provider instance. The
+// deployment and API version are set from the configuration by
+// SetDefaultOptions (see config.SetAzureDeployment and
+// config.SetAzureAPIVersion).
+//
+// Parameters:
+//   - endpoint: URL of the Azure resource, e.g. "https://my-resource.openai.azure.com"
+//   - apiKey: Azure OpenAI API key
+//   - model: The model of the deployment (e.g., "gpt-4o"), used to count tokens
+//   - extraHeaders: Additional HTTP headers for requests
+//
+// Returns:
+//   - A configured Azure OpenAI Provider instance
+func NewAzureOpenAIProvider(endpoint, apiKey, model string, extraHeaders map[string]string) Provider {
+	openAI := NewOpenAIProvider(endpoint, apiKey, model, extraHeaders).(*OpenAIProvider)
+	// The OpenAI constructor's default endpoint does not serve Azure deployments.
+	openAI.endpoint = endpoint
+	return &AzureOpenAIProvider{OpenAIProvider: openAI}
+}
+
+// Name returns "azure" as the provider identifier.
+func (p *AzureOpenAIProvider) Name() string {
+	return "azure"
+}
+
+// SetDefaultOptions configures standard options from the global
+// configuration, including the deployment and API version.
+func (p *AzureOpenAIProvider) SetDefaultOptions(config *config.Config) {
+	p.OpenAIProvider.SetDefaultOptions(config)
+	p.deployment = config.AzureDeployment
+	p.apiVersion = config.AzureAPIVersion
+}
+
+// ValidateConfig reports a missing endpoint, deployment or API version, which
+// Azure OpenAI has no defaults for.
+func (p *AzureOpenAIProvider) ValidateConfig() error {
+	switch {
+	case p.endpoint == "":
+		return fmt.Errorf("azure provider requires the URL of the Azure resource, see SetBaseURL")
+	case p.deployment == "":
+		return fmt.Errorf("azure provider requires a deployment, see SetAzureDeployment")
+	case p.apiVersion == "":
+		return fmt.Errorf("azure provider requires an API version, see SetAzureAPIVersion")
+	}
+	return nil
+}
+
+// SupportsModeration indicates that Azure OpenAI has no moderation endpoint;
+// its content filters apply to chat completions instead.
+func (p *AzureOpenAIProvider) SupportsModeration() bool {
+	return false
+}
+
+// SupportsBatch indicates that batches are not sent to the Azure OpenAI
+// batch API, whose URLs and deployments differ from OpenAI's; SubmitBatch
+// runs them as individual calls instead.
+func (p *AzureOpenAIProvider) SupportsBatch() bool {
+	return false
+}
+
+// Endpoint returns the chat completions URL of the deployment, e.g.
+// "https://my-resource.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=2024-10-21".
+func (p *AzureOpenAIProvider) Endpoint() string {
+	u, err := url.Parse(p.endpoint)
+	if err != nil {
+		p.logger.Error("Error parsing URL", "error", err)
+		return ""
+	}
+	u = u.JoinPath("openai", "deployments", p.deployment, "chat", "completions")
+	query := u.Query()
+	query.Set("api-version", p.apiVersion)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Headers returns the HTTP headers required for Azure OpenAI API requests,
+// which authenticate with the api-key header rather than a bearer token.
+func (p *AzureOpenAIProvider) Headers() map[string]string {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		"api-key":      p.apiKey,
+	}
+
+	for key, value := range p.extraHeaders {
+		headers[key] = value
+	}
+
+	return headers
+}
@@ -0,0 +1,47 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+)
+
+func TestAzureOpenAIProvider(t *testing.T) {
+	p := NewAzureOpenAIProvider("https://my-resource.openai.azure.com/", "azure-key", "gpt-4o", nil).(*AzureOpenAIProvider)
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetAzureDeployment("gpt-4o-prod"), config.SetAzureAPIVersion("2024-10-21"))
+	p.SetDefaultOptions(cfg)
+
+	require.NoError(t, p.ValidateConfig())
+	assert.Equal(t, "azure", p.Name())
+	assert.Equal(t, "https://my-resource.openai.azure.com/openai/deployments/gpt-4o-prod/chat/completions?api-version=2024-10-21", p.Endpoint())
+	headers := p.Headers()
+	assert.Equal(t, "azure-key", headers["api-key"])
+	assert.NotContains(t, headers, "Authorization")
+	assert.False(t, p.SupportsModeration())
+	assert.False(t, p.SupportsBatch(), "the OpenAI batch URLs are not valid on Azure")
+
+	req := prepare(t, p, map[string]interface{}{"temperature": 0.2})
+	assert.Equal(t, 0.2, req["temperature"], "requests have OpenAI's shape")
+	assert.Equal(t, []interface{}{map[string]interface{}{"role": "user", "content": "hello"}}, req["messages"])
+}
+
+func TestAzureOpenAIProvider_ValidateConfig(t *testing.T) {
+	for _, tc := range []struct {
+		endpoint string
+		opts     []config.ConfigOption
+		want     string
+	}{
+		{"", []config.ConfigOption{config.SetAzureDeployment("d"), config.SetAzureAPIVersion("v")}, "SetBaseURL"},
+		{"https://r.openai.azure.com", []config.ConfigOption{config.SetAzureAPIVersion("v")}, "SetAzureDeployment"},
+		{"https://r.openai.azure.com", []config.ConfigOption{config.SetAzureDeployment("d")}, "SetAzureAPIVersion"},
+	} {
+		p := NewAzureOpenAIProvider(tc.endpoint, "key", "gpt-4o", nil).(*AzureOpenAIProvider)
+		cfg := config.NewConfig()
+		config.ApplyOptions(cfg, tc.opts...)
+		p.SetDefaultOptions(cfg)
+		assert.ErrorContains(t, p.ValidateConfig(), tc.want)
+	}
+}
@@ -0,0 +1,21 @@
+// Package providers implements LLM provider interfaces and their implementations.
+package providers
+
+// Batcher is implemented by providers with an OpenAI-style batch API: a JSONL
+// file of requests is uploaded to the files endpoint, a batch running it is
+// created on the batches endpoint and polled until it ends, and the results
+// are downloaded as another JSONL file. Batches take up to a day but cost
+// less than individual requests.
+type Batcher interface {
+	// SupportsBatch reports whether the batch API is available, which it may
+	// not be for providers serving the same API elsewhere.
+	SupportsBatch() bool
+
+	// BatchURL returns the URL of path, such as "files" or "batches/ID",
+	// under the provider's API.
+	BatchURL(path string) string
+
+	// BatchRequestURL returns the relative URL the batched requests are sent
+	// to, such as "/v1/chat/completions".
+	BatchRequestURL() string
+}
@@ -3,6 +3,7 @@ package providers
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 	"strings"
 
@@ -10,6 +11,9 @@ import (
 	"github.com/yockii/gollm_cn/utils"
 )
 
+// defaultCohereRerankModel is the model Cohere ranks documents with.
+const defaultCohereRerankModel = "rerank-v3.5"
+
 // CohereProvider implements the Provider interface for Cohere's API.
 // It supports Cohere's language models and provides access to their capabilities,
 // including chat completion and structured output
@@ -79,9 +83,16 @@ func (p *CohereProvider) SetDefaultOptions(config *config.Config) {
 	p.SetOption("temperature", config.Temperature)
 	p.SetOption("max_tokens", config.MaxTokens)
 	p.SetOption("stream", false)
-	if config.Seed != nil {
-		p.SetOption("seed", *config.Seed)
-	}
+	setSamplingDefaults(p.SetOption, config)
+}
+
+// cohereSamplingNames maps sampling parameters to Cohere's names.
+// Cohere has no repetition penalty.
+var cohereSamplingNames = map[string]string{
+	"top_p":          "p",
+	"top_k":          "k",
+	"stop":           "stop_sequences",
+	"repeat_penalty": "",
 }
 
 // Name returns "cohere" as the provider identifier.
@@ -101,12 +112,22 @@ func (p *CohereProvider) Endpoint() string {
 	return "https://api.cohere.com/v2/chat"
 }
 
+// SupportsSystemPrompt indicates that the system prompt is sent as a system message.
+func (p *CohereProvider) SupportsSystemPrompt() bool {
+	return true
+}
+
 // SupportsJSONSchema indicates that Cohere supports structured output
 // through its system prompts and response formatting capabilities.
 func (p *CohereProvider) SupportsJSONSchema() bool {
 	return true
 }
 
+// MaxStopSequences returns the number of stop sequences Cohere accepts.
+func (p *CohereProvider) MaxStopSequences() int {
+	return 5
+}
+
 // Headers returns the required HTTP headers for Cohere API requests.
 // This includes:
 //   - Content-type: application/json
@@ -140,10 +161,8 @@ func (p *CohereProvider) Headers() map[string]string {
 //   - Any error encountered during preparation
 func (p *CohereProvider) PrepareRequest(prompt string, options map[string]any) ([]byte, error) {
 	requestBody := map[string]any{
-		"model": p.model,
-		"messages": []map[string]any{
-			{"role": "user", "content": prompt},
-		},
+		"model":    p.model,
+		"messages": chatMessages(prompt, options),
 	}
 
 	// First, add default options
@@ -156,6 +175,9 @@ func (p *CohereProvider) PrepareRequest(prompt string, options map[string]any) (
 		requestBody[k] = v
 	}
 
+	delete(requestBody, "system_prompt")
+	adaptSamplingOptions(requestBody, cohereSamplingNames, p.Name(), p.logger)
+
 	return json.Marshal(requestBody)
 }
 
@@ -172,10 +194,8 @@ func (p *CohereProvider) PrepareRequest(prompt string, options map[string]any) (
 //   - Any error encountered during preparation
 func (p *CohereProvider) PrepareRequestWithSchema(prompt string, options map[string]any, schema any) ([]byte, error) {
 	requestBody := map[string]any{
-		"model": p.model,
-		"messages": []map[string]any{
-			{"role": "user", "content": prompt},
-		},
+		"model":    p.model,
+		"messages": chatMessages(prompt, options),
 		"response_format": map[string]any{
 			"type":        "json_object",
 			"json_schema": schema,
@@ -191,6 +211,8 @@ func (p *CohereProvider) PrepareRequestWithSchema(prompt string, options map[str
 	for k, v := range options {
 		requestBody[k] = v
 	}
+	delete(requestBody, "system_prompt")
+	adaptSamplingOptions(requestBody, cohereSamplingNames, p.Name(), p.logger)
 
 	return json.Marshal(requestBody)
 }
@@ -296,13 +318,71 @@ func (p *CohereProvider) PrepareStreamRequest(prompt string, options map[string]
 	return p.PrepareRequest(prompt, options)
 }
 
-// ParseStreamResponse parses a single chunk from a streaming response
+// ParseStreamResponse parses a single event of a streaming response. The text
+// of a response arrives in "content-delta" events and the stream ends with a
+// "message-end" event; other events are skipped.
 func (p *CohereProvider) ParseStreamResponse(chunk []byte) (string, error) {
+	var event struct {
+		Type  string `json:"type"`
+		Delta struct {
+			Message struct {
+				Content struct {
+					Text string `json:"text"`
+				} `json:"content"`
+			} `json:"message"`
+		} `json:"delta"`
+	}
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return "", fmt.Errorf("malformed event: %w", err)
+	}
+	switch event.Type {
+	case "content-delta":
+		if event.Delta.Message.Content.Text == "" {
+			return "", fmt.Errorf("skip token")
+		}
+		return event.Delta.Message.Content.Text, nil
+	case "message-end":
+		return "", io.EOF
+	default:
+		return "", fmt.Errorf("skip token")
+	}
+}
+
+// RerankEndpoint returns the URL of Cohere's rerank endpoint,
+// "https://api.cohere.com/v2/rerank" by default.
+func (p *CohereProvider) RerankEndpoint() string {
+	u, err := url.JoinPath(p.endpoint, "/rerank")
+	if err != nil {
+		p.logger.Error("Error joining URL", "error", err)
+		return "https://api.cohere.com/v2/rerank"
+	}
+	return u
+}
+
+// PrepareRerankRequest creates a rerank request with Cohere's rerank model.
+func (p *CohereProvider) PrepareRerankRequest(query string, documents []string, topN int) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"model":     defaultCohereRerankModel,
+		"query":     query,
+		"documents": documents,
+		"top_n":     topN,
+	})
+}
+
+// ParseRerankResponse extracts the documents' indexes and relevance scores.
+func (p *CohereProvider) ParseRerankResponse(body []byte) ([]RerankResult, error) {
 	var response struct {
-		Text string `json:"text"`
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing rerank response: %w", err)
 	}
-	if err := json.Unmarshal(chunk, &response); err != nil {
-		return "", err
+	results := make([]RerankResult, len(response.Results))
+	for i, result := range response.Results {
+		results[i] = RerankResult{Index: result.Index, Score: result.RelevanceScore}
 	}
-	return response.Text, nil
+	return results, nil
 }
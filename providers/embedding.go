@@ -0,0 +1,82 @@
+// Package providers implements LLM provider interfaces and their implementations.
+package providers
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Embedder is implemented by providers with an embeddings endpoint. Embedding
+// requests go to their own endpoint and use their own model, separate from
+// text generation.
+type Embedder interface {
+	// EmbeddingEndpoint returns the API endpoint URL for embedding requests.
+	EmbeddingEndpoint() string
+
+	// PrepareEmbeddingRequest creates the request body embedding inputs.
+	PrepareEmbeddingRequest(inputs []string) ([]byte, error)
+
+	// ParseEmbeddingResponse extracts one embedding per input, in input order.
+	ParseEmbeddingResponse(body []byte) ([][]float64, error)
+}
+
+// parseEmbeddingData extracts the embeddings of an OpenAI-style embeddings
+// response, whose "data" entries carry an index and an embedding. Embeddings
+// are returned in index order and as float64, whether the response encodes
+// them as JSON numbers or as base64 little-endian float32 values.
+func parseEmbeddingData(body []byte) ([][]float64, error) {
+	var response struct {
+		Data []struct {
+			Index     int             `json:"index"`
+			Embedding json.RawMessage `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing embedding response: %w", err)
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("empty embedding response from API")
+	}
+	sort.SliceStable(response.Data, func(i, j int) bool {
+		return response.Data[i].Index < response.Data[j].Index
+	})
+
+	embeddings := make([][]float64, len(response.Data))
+	for i, data := range response.Data {
+		embedding, err := decodeEmbedding(data.Embedding)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing embedding %d: %w", data.Index, err)
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
+// decodeEmbedding converts a raw embedding, either an array of numbers or a
+// base64 string of little-endian float32 values, to float64.
+func decodeEmbedding(raw json.RawMessage) ([]float64, error) {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		var values []float64
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("base64 embedding has %d bytes, not a multiple of 4", len(data))
+	}
+	values := make([]float64, len(data)/4)
+	for i := range values {
+		values[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:])))
+	}
+	return values, nil
+}
@@ -0,0 +1,50 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+)
+
+func TestMistralProvider_Embeddings(t *testing.T) {
+	p := NewMistralProvider("", "key", "mistral-large-latest", nil)
+	embedder, ok := p.(Embedder)
+	require.True(t, ok)
+	assert.Equal(t, "https://api.mistral.ai/v1/embeddings", embedder.EmbeddingEndpoint())
+
+	body, err := embedder.PrepareEmbeddingRequest([]string{"你好", "世界"})
+	require.NoError(t, err)
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Equal(t, "mistral-embed", req["model"])
+	assert.Equal(t, []interface{}{"你好", "世界"}, req["input"])
+	assert.NotContains(t, req, "temperature")
+
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, config.SetMistralEmbeddingModel("mistral-embed-2312"))
+	p.SetDefaultOptions(cfg)
+	body, err = embedder.PrepareEmbeddingRequest([]string{"你好"})
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(body, &req))
+	assert.Equal(t, "mistral-embed-2312", req["model"])
+}
+
+func TestParseEmbeddingData(t *testing.T) {
+	// Out of index order, with integer, exponent and base64 float32 values:
+	// "AACAPwAAAMA=" is 1.0 and -2.0 as little-endian float32.
+	embeddings, err := parseEmbeddingData([]byte(`{"data":[
+		{"index":2,"embedding":"AACAPwAAAMA="},
+		{"index":0,"embedding":[1, 2.5e-1]},
+		{"index":1,"embedding":[-0.5, 0]}
+	]}`))
+	require.NoError(t, err)
+	assert.Equal(t, [][]float64{{1, 0.25}, {-0.5, 0}, {1, -2}}, embeddings)
+
+	_, err = parseEmbeddingData([]byte(`{"data":[]}`))
+	assert.Error(t, err)
+	_, err = parseEmbeddingData([]byte(`{"data":[{"index":0,"embedding":"AAA="}]}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,389 @@
+// Package providers implements LLM provider interfaces and their implementations.
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// GeminiProvider implements the Provider interface for Google's Gemini models
+// through the Generative Language API. Requests use Gemini's contents/parts
+// format, with the system prompt sent as system_instruction and sampling
+// parameters under generationConfig.
+type GeminiProvider struct {
+	endpoint     string                 // Base URL, e.g. "https://generativelanguage.googleapis.com/v1beta"
+	apiKey       string                 // API key for authentication
+	model        string                 // Model identifier (e.g., "gemini-1.5-pro", "gemini-2.0-flash")
+	extraHeaders map[string]string      // Additional HTTP headers
+	options      map[string]interface{} // Model-specific options
+	logger       utils.Logger           // Logger instance
+}
+
+// NewGeminiProvider creates a new Gemini provider instance.
+//
+// Parameters:
+//   - endpoint: Base URL of the Generative Language API; defaults to the v1beta API
+//   - apiKey: Gemini API key for authentication
+//   - model: The model to use (e.g., "gemini-1.5-pro", "gemini-1.5-flash")
+//   - extraHeaders: Additional HTTP headers for requests
+//
+// Returns:
+//   - A configured Gemini Provider instance
+func NewGeminiProvider(endpoint, apiKey, model string, extraHeaders map[string]string) Provider {
+	if extraHeaders == nil {
+		extraHeaders = make(map[string]string)
+	}
+	if endpoint == "" {
+		endpoint = "https://generativelanguage.googleapis.com/v1beta"
+	}
+	return &GeminiProvider{
+		endpoint:     endpoint,
+		apiKey:       apiKey,
+		model:        model,
+		extraHeaders: extraHeaders,
+		options:      make(map[string]interface{}),
+		logger:       utils.NewLogger(utils.LogLevelInfo),
+	}
+}
+
+// SetLogger configures the logger for the Gemini provider.
+func (p *GeminiProvider) SetLogger(logger utils.Logger) {
+	p.logger = logger
+}
+
+func (p *GeminiProvider) SetEndpoint(endpoint string) {
+	p.endpoint = endpoint
+}
+
+// SetOption sets a specific option for the Gemini provider.
+// Supported options include:
+//   - temperature: Controls randomness (0.0 to 2.0)
+//   - max_tokens: Maximum tokens in the response (maxOutputTokens)
+//   - top_p, top_k, frequency_penalty, presence_penalty, seed, stop
+func (p *GeminiProvider) SetOption(key string, value interface{}) {
+	p.options[key] = value
+}
+
+// SetDefaultOptions configures standard options from the global configuration.
+func (p *GeminiProvider) SetDefaultOptions(config *config.Config) {
+	p.SetOption("temperature", config.Temperature)
+	p.SetOption("max_tokens", config.MaxTokens)
+	setSamplingDefaults(p.SetOption, config)
+}
+
+// geminiGenerationConfig maps our option names to Gemini's generationConfig
+// fields. Gemini has no repetition penalty.
+var geminiGenerationConfig = map[string]string{
+	"temperature":       "temperature",
+	"max_tokens":        "maxOutputTokens",
+	"top_p":             "topP",
+	"top_k":             "topK",
+	"frequency_penalty": "frequencyPenalty",
+	"presence_penalty":  "presencePenalty",
+	"seed":              "seed",
+	"stop":              "stopSequences",
+	"repeat_penalty":    "",
+}
+
+// Name returns "gemini" as the provider identifier.
+func (p *GeminiProvider) Name() string {
+	return "gemini"
+}
+
+// Endpoint returns the generateContent URL of the configured model, e.g.
+// "https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-pro:generateContent".
+func (p *GeminiProvider) Endpoint() string {
+	u, err := url.JoinPath(p.endpoint, "models", p.model+":generateContent")
+	if err != nil {
+		p.logger.Error("Error joining URL", "error", err)
+		return "https://generativelanguage.googleapis.com/v1beta/models/" + p.model + ":generateContent"
+	}
+	return u
+}
+
+// SupportsSystemPrompt indicates that the system prompt is sent as system_instruction.
+func (p *GeminiProvider) SupportsSystemPrompt() bool {
+	return true
+}
+
+// SupportsImages indicates that Gemini accepts images as inline_data parts,
+// or as file_data parts for URLs.
+func (p *GeminiProvider) SupportsImages() bool {
+	return true
+}
+
+// MaxStopSequences returns the number of stop sequences Gemini accepts.
+func (p *GeminiProvider) MaxStopSequences() int {
+	return 5
+}
+
+// SupportsJSONSchema returns false: Gemini's responseSchema accepts only a
+// subset of JSON Schema, so schemas are added to the prompt and the response
+// is requested as JSON instead.
+func (p *GeminiProvider) SupportsJSONSchema() bool {
+	return false
+}
+
+// Headers returns the required HTTP headers for Gemini API requests.
+// This includes:
+//   - x-goog-api-key: The API key
+//   - Content-Type: application/json
+//   - Any additional headers specified via SetExtraHeaders
+func (p *GeminiProvider) Headers() map[string]string {
+	headers := map[string]string{
+		"Content-Type":   "application/json",
+		"x-goog-api-key": p.apiKey,
+	}
+
+	for key, value := range p.extraHeaders {
+		headers[key] = value
+	}
+
+	return headers
+}
+
+// PrepareRequest creates the request body for a Gemini API call.
+// It handles:
+//   - Contents and parts formatting
+//   - System instructions
+//   - Tool declarations
+//   - Generation config from the default and per-call options
+//
+// Parameters:
+//   - prompt: The input text
+//   - options: Additional parameters for the request
+//
+// Returns:
+//   - Serialized JSON request body
+//   - Any error encountered during preparation
+func (p *GeminiProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	return json.Marshal(p.requestBody(prompt, options))
+}
+
+// PrepareRequestWithSchema creates a request whose response is JSON. The
+// schema itself is expected in the prompt (see SupportsJSONSchema).
+//
+// Parameters:
+//   - prompt: The input text
+//   - options: Additional request parameters
+//   - schema: JSON schema for response validation
+//
+// Returns:
+//   - Serialized JSON request body
+//   - Any error encountered during preparation
+func (p *GeminiProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
+	requestBody := p.requestBody(prompt, options)
+	requestBody["generationConfig"].(map[string]interface{})["responseMimeType"] = "application/json"
+	return json.Marshal(requestBody)
+}
+
+// requestBody builds a generateContent request from the default options and
+// the per-call options, which take precedence.
+func (p *GeminiProvider) requestBody(prompt string, options map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(p.options)+len(options))
+	for k, v := range p.options {
+		merged[k] = v
+	}
+	for k, v := range options {
+		merged[k] = v
+	}
+
+	parts := []map[string]interface{}{{"text": prompt}}
+	for _, image := range promptImages(merged) {
+		if image.URL != "" {
+			parts = append(parts, map[string]interface{}{
+				"file_data": map[string]interface{}{"mime_type": image.MediaType, "file_uri": image.URL},
+			})
+			continue
+		}
+		parts = append(parts, map[string]interface{}{
+			"inline_data": map[string]interface{}{"mime_type": image.MediaType, "data": image.Base64},
+		})
+	}
+	requestBody := map[string]interface{}{
+		"contents": []map[string]interface{}{{
+			"role":  "user",
+			"parts": parts,
+		}},
+	}
+	if systemPrompt, ok := merged["system_prompt"].(string); ok && systemPrompt != "" {
+		requestBody["system_instruction"] = map[string]interface{}{
+			"parts": []map[string]interface{}{{"text": systemPrompt}},
+		}
+	}
+	if tools, ok := merged["tools"].([]utils.Tool); ok && len(tools) > 0 {
+		declarations := make([]map[string]interface{}, len(tools))
+		for i, tool := range tools {
+			declarations[i] = map[string]interface{}{
+				"name":        tool.Function.Name,
+				"description": tool.Function.Description,
+				"parameters":  tool.Function.Parameters,
+			}
+		}
+		requestBody["tools"] = []map[string]interface{}{{"functionDeclarations": declarations}}
+	}
+
+	generationConfig := make(map[string]interface{})
+	for key, name := range geminiGenerationConfig {
+		value, ok := merged[key]
+		if !ok {
+			continue
+		}
+		if name == "" {
+			p.logger.Debug("Dropping option not supported by provider", "provider", p.Name(), "option", key)
+			continue
+		}
+		generationConfig[name] = value
+	}
+	requestBody["generationConfig"] = generationConfig
+
+	if model, ok := merged["model"].(string); ok && model != p.model {
+		p.logger.Warn("Per-call model override is not supported by Gemini, using the configured model", "model", p.model, "requested", model)
+	}
+	return requestBody
+}
+
+// geminiResponse is the generateContent response.
+type geminiResponse struct {
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text         string `json:"text"`
+				FunctionCall *struct {
+					Name string          `json:"name"`
+					Args json.RawMessage `json:"args"`
+				} `json:"functionCall"`
+			} `json:"parts"`
+		} `json:"content"`
+		FinishReason  string               `json:"finishReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"candidates"`
+	PromptFeedback struct {
+		BlockReason   string               `json:"blockReason"`
+		SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+	} `json:"promptFeedback"`
+}
+
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked"`
+}
+
+// geminiBlockedFinishReasons are the finish reasons for which Gemini withholds
+// the response.
+var geminiBlockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"RECITATION":         true,
+	"BLOCKLIST":          true,
+	"PROHIBITED_CONTENT": true,
+	"SPII":               true,
+}
+
+// ParseResponse extracts the generated text from the Gemini API response.
+// Prompts and responses blocked by Gemini's safety filters produce an error
+// naming the reason and the flagged safety categories.
+//
+// Parameters:
+//   - body: Raw API response body
+//
+// Returns:
+//   - Generated text content
+//   - Any error encountered during parsing
+func (p *GeminiProvider) ParseResponse(body []byte) (string, error) {
+	var response geminiResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("error parsing response: %w", err)
+	}
+
+	if reason := response.PromptFeedback.BlockReason; reason != "" {
+		return "", fmt.Errorf("prompt blocked by Gemini (%s)%s", reason, flaggedCategories(response.PromptFeedback.SafetyRatings))
+	}
+	if len(response.Candidates) == 0 {
+		return "", fmt.Errorf("empty response from API")
+	}
+	candidate := response.Candidates[0]
+	if geminiBlockedFinishReasons[candidate.FinishReason] {
+		return "", fmt.Errorf("response blocked by Gemini (finish reason %s)%s", candidate.FinishReason, flaggedCategories(candidate.SafetyRatings))
+	}
+
+	var finalResponse strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if part.FunctionCall != nil {
+			var args interface{}
+			if err := json.Unmarshal(part.FunctionCall.Args, &args); err != nil {
+				return "", fmt.Errorf("error parsing function arguments: %w", err)
+			}
+			functionCall, err := utils.FormatFunctionCall(part.FunctionCall.Name, args)
+			if err != nil {
+				return "", fmt.Errorf("error formatting function call: %w", err)
+			}
+			if finalResponse.Len() > 0 {
+				finalResponse.WriteString("\n")
+			}
+			finalResponse.WriteString(functionCall)
+			continue
+		}
+		finalResponse.WriteString(part.Text)
+	}
+	if finalResponse.Len() == 0 {
+		return "", fmt.Errorf("empty response from API (finish reason %s)", candidate.FinishReason)
+	}
+	return finalResponse.String(), nil
+}
+
+// flaggedCategories lists the safety categories that were blocked or rated
+// with medium or high probability, as ": category, ..." for error messages.
+func flaggedCategories(ratings []geminiSafetyRating) string {
+	var categories []string
+	for _, rating := range ratings {
+		if rating.Blocked || rating.Probability == "MEDIUM" || rating.Probability == "HIGH" {
+			categories = append(categories, fmt.Sprintf("%s=%s", rating.Category, rating.Probability))
+		}
+	}
+	if len(categories) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(categories, ", ")
+}
+
+// HandleFunctionCalls processes function calls in the response.
+func (p *GeminiProvider) HandleFunctionCalls(body []byte) ([]byte, error) {
+	response := string(body)
+	functionCalls, err := utils.ExtractFunctionCalls(response)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting function calls: %w", err)
+	}
+
+	if len(functionCalls) == 0 {
+		return nil, nil // No function calls found
+	}
+
+	return json.Marshal(functionCalls)
+}
+
+// SetExtraHeaders configures additional HTTP headers for API requests.
+func (p *GeminiProvider) SetExtraHeaders(extraHeaders map[string]string) {
+	p.extraHeaders = extraHeaders
+}
+
+// SupportsStreaming returns false: Gemini streams from a separate
+// streamGenerateContent endpoint, which the Provider interface cannot select.
+func (p *GeminiProvider) SupportsStreaming() bool {
+	return false
+}
+
+// PrepareStreamRequest returns an error as streaming is not supported.
+func (p *GeminiProvider) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
+	return nil, fmt.Errorf("streaming is not supported by the Gemini provider")
+}
+
+// ParseStreamResponse returns an error as streaming is not supported.
+func (p *GeminiProvider) ParseStreamResponse(chunk []byte) (string, error) {
+	return "", fmt.Errorf("streaming is not supported by the Gemini provider")
+}
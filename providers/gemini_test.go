@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestGeminiProvider_PrepareRequest(t *testing.T) {
+	p := NewGeminiProvider("", "key", "gemini-1.5-flash", nil)
+	assert.Equal(t, "https://generativelanguage.googleapis.com/v1beta/models/gemini-1.5-flash:generateContent", p.Endpoint())
+	assert.Equal(t, "key", p.Headers()["x-goog-api-key"])
+
+	req := prepare(t, p, map[string]interface{}{
+		"system_prompt":  "你是一名助手。",
+		"max_tokens":     42,
+		"stop":           []string{"END"},
+		"repeat_penalty": 1.1,
+		"tools": []utils.Tool{{
+			Type:     "function",
+			Function: utils.Function{Name: "get_weather", Description: "Get the weather"},
+		}},
+	}, config.SetTopK(20))
+
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"role":  "user",
+		"parts": []interface{}{map[string]interface{}{"text": "hello"}},
+	}}, req["contents"])
+	assert.Equal(t, map[string]interface{}{
+		"parts": []interface{}{map[string]interface{}{"text": "你是一名助手。"}},
+	}, req["system_instruction"])
+
+	generationConfig := req["generationConfig"].(map[string]interface{})
+	assert.Equal(t, 0.7, generationConfig["temperature"])
+	assert.Equal(t, float64(42), generationConfig["maxOutputTokens"])
+	assert.Equal(t, float64(20), generationConfig["topK"])
+	assert.Equal(t, []interface{}{"END"}, generationConfig["stopSequences"])
+	assert.NotContains(t, generationConfig, "repeat_penalty")
+	for _, key := range []string{"system_prompt", "max_tokens", "stop", "temperature", "model"} {
+		assert.NotContains(t, req, key)
+	}
+
+	tools := req["tools"].([]interface{})
+	declarations := tools[0].(map[string]interface{})["functionDeclarations"].([]interface{})
+	assert.Equal(t, "get_weather", declarations[0].(map[string]interface{})["name"])
+}
+
+func TestGeminiProvider_ParseResponse(t *testing.T) {
+	p := NewGeminiProvider("", "key", "gemini-1.5-flash", nil)
+
+	text, err := p.ParseResponse([]byte(`{
+		"candidates": [{"content": {"parts": [{"text": "你好，"}, {"text": "世界"}], "role": "model"}, "finishReason": "STOP"}],
+		"usageMetadata": {"promptTokenCount": 5, "candidatesTokenCount": 3, "totalTokenCount": 8}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, "你好，世界", text)
+
+	_, err = p.ParseResponse([]byte(`{
+		"candidates": [{"finishReason": "SAFETY", "safetyRatings": [
+			{"category": "HARM_CATEGORY_HARASSMENT", "probability": "HIGH", "blocked": true},
+			{"category": "HARM_CATEGORY_HATE_SPEECH", "probability": "NEGLIGIBLE"}
+		]}]
+	}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "response blocked by Gemini (finish reason SAFETY)")
+	assert.Contains(t, err.Error(), "HARM_CATEGORY_HARASSMENT=HIGH")
+	assert.NotContains(t, err.Error(), "HATE_SPEECH")
+
+	_, err = p.ParseResponse([]byte(`{"promptFeedback": {"blockReason": "PROHIBITED_CONTENT"}}`))
+	assert.EqualError(t, err, "prompt blocked by Gemini (PROHIBITED_CONTENT)")
+}
@@ -91,9 +91,12 @@ func (p *GroqProvider) SetOption(key string, value interface{}) {
 func (p *GroqProvider) SetDefaultOptions(config *config.Config) {
 	p.SetOption("temperature", config.Temperature)
 	p.SetOption("max_tokens", config.MaxTokens)
-	if config.Seed != nil {
-		p.SetOption("seed", *config.Seed)
-	}
+	setSamplingDefaults(p.SetOption, config)
+}
+
+// SupportsSystemPrompt indicates that the system prompt is sent as a system message.
+func (p *GroqProvider) SupportsSystemPrompt() bool {
+	return true
 }
 
 // SupportsJSONSchema indicates whether this provider supports JSON schema validation.
@@ -102,6 +105,12 @@ func (p *GroqProvider) SupportsJSONSchema() bool {
 	return false
 }
 
+// MaxStopSequences returns the number of stop sequences Groq accepts, the same
+// as OpenAI.
+func (p *GroqProvider) MaxStopSequences() int {
+	return 4
+}
+
 // Headers returns the HTTP headers required for Groq API requests.
 // This includes the authorization token and content type headers.
 func (p *GroqProvider) Headers() map[string]string {
@@ -129,10 +138,8 @@ func (p *GroqProvider) Headers() map[string]string {
 //   - Any error encountered during preparation
 func (p *GroqProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
 	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+		"model":    p.model,
+		"messages": chatMessages(prompt, options),
 	}
 
 	// First, add the default options
@@ -144,6 +151,8 @@ func (p *GroqProvider) PrepareRequest(prompt string, options map[string]interfac
 	for k, v := range options {
 		requestBody[k] = v
 	}
+	delete(requestBody, "system_prompt")
+	adaptSamplingOptions(requestBody, openaiSamplingNames, p.Name(), p.logger)
 
 	return json.Marshal(requestBody)
 }
@@ -153,10 +162,8 @@ func (p *GroqProvider) PrepareRequest(prompt string, options map[string]interfac
 // standard request preparation.
 func (p *GroqProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
 	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+		"model":    p.model,
+		"messages": chatMessages(prompt, options),
 		"response_format": map[string]interface{}{
 			"type":   "json_schema",
 			"schema": schema,
@@ -172,6 +179,8 @@ func (p *GroqProvider) PrepareRequestWithSchema(prompt string, options map[strin
 	if strict, ok := options["strict"].(bool); ok && strict {
 		requestBody["response_format"].(map[string]interface{})["strict"] = true
 	}
+	delete(requestBody, "system_prompt")
+	adaptSamplingOptions(requestBody, openaiSamplingNames, p.Name(), p.logger)
 
 	return json.Marshal(requestBody)
 }
@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+func TestPrepareRequest_Images(t *testing.T) {
+	images := []utils.ImageInput{
+		{URL: "https://example.com/cat.png", MediaType: "image/png"},
+		{Base64: "aGVsbG8=", MediaType: "image/jpeg"},
+	}
+
+	t.Run("openai", func(t *testing.T) {
+		req := prepare(t, NewOpenAIProvider("", "key", "gpt-4o", nil), map[string]interface{}{"images": images})
+		assert.NotContains(t, req, "images")
+		messages := req["messages"].([]interface{})
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"type": "text", "text": "hello"},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "https://example.com/cat.png"}},
+			map[string]interface{}{"type": "image_url", "image_url": map[string]interface{}{"url": "data:image/jpeg;base64,aGVsbG8="}},
+		}, messages[0].(map[string]interface{})["content"])
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		req := prepare(t, NewAnthropicProvider("", "key", "claude-3-5-sonnet-latest", nil), map[string]interface{}{"images": images})
+		assert.NotContains(t, req, "images")
+		messages := req["messages"].([]interface{})
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "url", "url": "https://example.com/cat.png"}},
+			map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "base64", "media_type": "image/jpeg", "data": "aGVsbG8="}},
+			map[string]interface{}{"type": "text", "text": "hello"},
+		}, messages[0].(map[string]interface{})["content"])
+	})
+
+	t.Run("gemini", func(t *testing.T) {
+		req := prepare(t, NewGeminiProvider("", "key", "gemini-1.5-flash", nil), map[string]interface{}{"images": images})
+		assert.Equal(t, []interface{}{map[string]interface{}{
+			"role": "user",
+			"parts": []interface{}{
+				map[string]interface{}{"text": "hello"},
+				map[string]interface{}{"file_data": map[string]interface{}{"mime_type": "image/png", "file_uri": "https://example.com/cat.png"}},
+				map[string]interface{}{"inline_data": map[string]interface{}{"mime_type": "image/jpeg", "data": "aGVsbG8="}},
+			},
+		}}, req["contents"])
+	})
+}
@@ -0,0 +1,38 @@
+package providers
+
+// ProviderInfo describes a provider compiled into gollm and the version of
+// its API that gollm targets.
+type ProviderInfo struct {
+	Name            string `json:"name"`
+	APIVersion      string `json:"api_version"`
+	DefaultEndpoint string `json:"default_endpoint"`
+	RequiresAPIKey  bool   `json:"requires_api_key"`
+}
+
+// knownProviderInfo lists the providers registered by NewProviderRegistry.
+// Update it together with the provider implementations.
+var knownProviderInfo = []ProviderInfo{
+	{Name: "anthropic", APIVersion: "2023-06-01", DefaultEndpoint: "https://api.anthropic.com/v1/messages", RequiresAPIKey: true},
+	{Name: "azure", APIVersion: "2024-10-21", DefaultEndpoint: "https://{resource}.openai.azure.com/openai/deployments/{deployment}/chat/completions", RequiresAPIKey: true},
+	{Name: "cohere", APIVersion: "v2", DefaultEndpoint: "https://api.cohere.com/v2/chat", RequiresAPIKey: true},
+	{Name: "gemini", APIVersion: "v1beta", DefaultEndpoint: "https://generativelanguage.googleapis.com/v1beta/models/{model}:generateContent", RequiresAPIKey: true},
+	{Name: "groq", APIVersion: "openai/v1", DefaultEndpoint: "https://api.groq.com/openai/v1/chat/completions", RequiresAPIKey: true},
+	{Name: "mistral", APIVersion: "v1", DefaultEndpoint: "https://api.mistral.ai/v1/chat/completions", RequiresAPIKey: true},
+	{Name: "ollama", APIVersion: "api", DefaultEndpoint: "http://localhost:11434/api/generate", RequiresAPIKey: false},
+	{Name: "openai", APIVersion: "v1", DefaultEndpoint: "https://api.openai.com/v1/chat/completions", RequiresAPIKey: true},
+}
+
+// KnownProviders returns the providers compiled into gollm, sorted by name.
+func KnownProviders() []ProviderInfo {
+	return append([]ProviderInfo(nil), knownProviderInfo...)
+}
+
+// LookupProvider returns the description of a compiled-in provider.
+func LookupProvider(name string) (ProviderInfo, bool) {
+	for _, info := range knownProviderInfo {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return ProviderInfo{}, false
+}
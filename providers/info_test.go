@@ -0,0 +1,19 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnownProviders_MatchRegistry(t *testing.T) {
+	registry := NewProviderRegistry()
+	known := KnownProviders()
+	assert.Len(t, known, len(registry.providers))
+	for _, info := range known {
+		provider, err := registry.Get(info.Name, "", "key", "model", nil)
+		require.NoError(t, err, info.Name)
+		assert.Equal(t, info.Name, provider.Name())
+	}
+}
@@ -0,0 +1,70 @@
+// Package providers implements LLM provider interfaces and their implementations.
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// LogprobsProvider is implemented by providers that return the log
+// probabilities of the response's tokens when the "logprobs" option is true,
+// with the most likely alternatives at each position when "top_logprobs" is
+// set.
+type LogprobsProvider interface {
+	// SupportsLogprobs reports whether log probabilities are available, which
+	// they may not be for providers serving the same API elsewhere.
+	SupportsLogprobs() bool
+
+	// MaxTopLogprobs returns the largest "top_logprobs" the provider accepts.
+	MaxTopLogprobs() int
+
+	// ParseLogprobs extracts the log probabilities of the response's tokens.
+	ParseLogprobs(body []byte) ([]TokenLogprob, error)
+}
+
+// TokenLogprob is the log probability of a token of a response.
+type TokenLogprob struct {
+	Token       string         // The token's text
+	Logprob     float64        // The natural log of the token's probability
+	TopLogprobs []TokenLogprob // The most likely tokens at this position, most likely first
+}
+
+// Probability returns the probability of the token, from 0 to 1.
+func (t TokenLogprob) Probability() float64 {
+	return math.Exp(t.Logprob)
+}
+
+// parseChatLogprobs extracts the log probabilities of an OpenAI-style chat
+// completion, found in the "logprobs" of its first choice.
+func parseChatLogprobs(body []byte) ([]TokenLogprob, error) {
+	type logprob struct {
+		Token   string  `json:"token"`
+		Logprob float64 `json:"logprob"`
+	}
+	var response struct {
+		Choices []struct {
+			Logprobs *struct {
+				Content []struct {
+					logprob
+					TopLogprobs []logprob `json:"top_logprobs"`
+				} `json:"content"`
+			} `json:"logprobs"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing logprobs: %w", err)
+	}
+	if len(response.Choices) == 0 || response.Choices[0].Logprobs == nil {
+		return nil, fmt.Errorf("response has no logprobs")
+	}
+	content := response.Choices[0].Logprobs.Content
+	tokens := make([]TokenLogprob, len(content))
+	for i, token := range content {
+		tokens[i] = TokenLogprob{Token: token.Token, Logprob: token.Logprob}
+		for _, top := range token.TopLogprobs {
+			tokens[i].TopLogprobs = append(tokens[i].TopLogprobs, TokenLogprob{Token: top.Token, Logprob: top.Logprob})
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,37 @@
+// Package providers implements LLM provider interfaces and their implementations.
+package providers
+
+import "github.com/yockii/gollm_cn/utils"
+
+// chatMessages builds the messages of an OpenAI-style chat request: the
+// "system_prompt" option as a system message, if it is set, followed by the
+// prompt as the user message. Images passed as the "images" option turn the
+// user message into text and image_url content parts.
+func chatMessages(prompt string, options map[string]interface{}) []map[string]interface{} {
+	var messages []map[string]interface{}
+	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
+		messages = append(messages, map[string]interface{}{"role": "system", "content": systemPrompt})
+	}
+	images := promptImages(options)
+	if len(images) == 0 {
+		return append(messages, map[string]interface{}{"role": "user", "content": prompt})
+	}
+	content := []map[string]interface{}{{"type": "text", "text": prompt}}
+	for _, image := range images {
+		imageURL := image.URL
+		if imageURL == "" {
+			imageURL = "data:" + image.MediaType + ";base64," + image.Base64
+		}
+		content = append(content, map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]interface{}{"url": imageURL},
+		})
+	}
+	return append(messages, map[string]interface{}{"role": "user", "content": content})
+}
+
+// promptImages returns the images passed as the "images" option.
+func promptImages(options map[string]interface{}) []utils.ImageInput {
+	images, _ := options["images"].([]utils.ImageInput)
+	return images
+}
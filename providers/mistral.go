@@ -15,14 +15,25 @@ import (
 // It supports Mistral's language models and provides access to their capabilities,
 // including chat completion and structured output.
 type MistralProvider struct {
-	endpoint     string
-	apiKey       string                 // API key for authentication
-	model        string                 // Model identifier (e.g., "mistral-large", "mistral-medium")
-	extraHeaders map[string]string      // Additional HTTP headers
-	options      map[string]interface{} // Model-specific options
-	logger       utils.Logger           // Logger instance
+	endpoint       string
+	apiKey         string                 // API key for authentication
+	model          string                 // Model identifier (e.g., "mistral-large", "mistral-medium")
+	embeddingModel string                 // Model used by Embed (e.g., "mistral-embed")
+	extraHeaders   map[string]string      // Additional HTTP headers
+	options        map[string]interface{} // Model-specific options
+	logger         utils.Logger           // Logger instance
 }
 
+// Defaults of the Mistral provider.
+const (
+	// mistralBaseURL is the base of the chat and embeddings endpoints unless
+	// config.SetEndpoint selects another.
+	mistralBaseURL = "https://api.mistral.ai/v1"
+	// defaultMistralEmbeddingModel is the embedding model used unless
+	// config.SetMistralEmbeddingModel selects another.
+	defaultMistralEmbeddingModel = "mistral-embed"
+)
+
 // NewMistralProvider creates a new Mistral provider instance.
 // It initializes the provider with the given API key, model, and optional headers.
 //
@@ -38,15 +49,16 @@ func NewMistralProvider(endpoint, apiKey, model string, extraHeaders map[string]
 		extraHeaders = make(map[string]string)
 	}
 	if endpoint == "" {
-		endpoint = "https://api.mistral.ai/v1"
+		endpoint = mistralBaseURL
 	}
 	return &MistralProvider{
-		endpoint:     endpoint,
-		apiKey:       apiKey,
-		model:        model,
-		extraHeaders: extraHeaders,
-		options:      make(map[string]interface{}),
-		logger:       utils.NewLogger(utils.LogLevelInfo),
+		endpoint:       endpoint,
+		apiKey:         apiKey,
+		model:          model,
+		embeddingModel: defaultMistralEmbeddingModel,
+		extraHeaders:   extraHeaders,
+		options:        make(map[string]interface{}),
+		logger:         utils.NewLogger(utils.LogLevelInfo),
 	}
 }
 
@@ -71,15 +83,25 @@ func (p *MistralProvider) SetOption(key string, value interface{}) {
 }
 
 // SetDefaultOptions configures standard options from the global configuration.
-// This includes temperature, max tokens, and sampling parameters.
+// This includes temperature, max tokens, sampling parameters and the
+// embedding model.
 func (p *MistralProvider) SetDefaultOptions(config *config.Config) {
 	p.SetOption("temperature", config.Temperature)
 	p.SetOption("max_tokens", config.MaxTokens)
-	if config.Seed != nil {
-		p.SetOption("seed", *config.Seed)
+	setSamplingDefaults(p.SetOption, config)
+	if config.MistralEmbeddingModel != "" {
+		p.embeddingModel = config.MistralEmbeddingModel
 	}
 }
 
+// mistralSamplingNames maps sampling parameters to Mistral's names.
+// Mistral has no top-k sampling and no repetition penalty.
+var mistralSamplingNames = map[string]string{
+	"seed":           "random_seed",
+	"top_k":          "",
+	"repeat_penalty": "",
+}
+
 // Name returns "mistral" as the provider identifier.
 func (p *MistralProvider) Name() string {
 	return "mistral"
@@ -88,13 +110,75 @@ func (p *MistralProvider) Name() string {
 // Endpoint returns the Mistral API endpoint URL.
 // This is "https://api.mistral.ai/v1/chat/completions".
 func (p *MistralProvider) Endpoint() string {
-	u, err := url.JoinPath(p.endpoint, "/chat/completions")
+	return p.endpointPath("/chat/completions")
+}
+
+// EmbeddingEndpoint returns the Mistral embeddings endpoint URL.
+// This is "https://api.mistral.ai/v1/embeddings".
+func (p *MistralProvider) EmbeddingEndpoint() string {
+	return p.endpointPath("/embeddings")
+}
+
+// mistralModerationModel is the model of Mistral's moderation endpoint.
+const mistralModerationModel = "mistral-moderation-latest"
+
+// SupportsModeration indicates that Mistral has a moderation endpoint.
+func (p *MistralProvider) SupportsModeration() bool {
+	return true
+}
+
+// ModerationEndpoint returns the Mistral moderation endpoint URL.
+// This is "https://api.mistral.ai/v1/moderations".
+func (p *MistralProvider) ModerationEndpoint() string {
+	return p.endpointPath("/moderations")
+}
+
+// PrepareModerationRequest creates the request body moderating text with
+// "mistral-moderation-latest".
+func (p *MistralProvider) PrepareModerationRequest(text string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"model": mistralModerationModel,
+		"input": []string{text},
+	})
+}
+
+// ParseModerationResponse extracts the verdict from a Mistral moderation
+// response, which flags the text if any category is flagged.
+func (p *MistralProvider) ParseModerationResponse(body []byte) (*ModerationVerdict, error) {
+	return parseModerationResults(body)
+}
+
+// endpointPath joins path to the provider's endpoint, falling back to the
+// default base URL if the endpoint is not a valid URL.
+func (p *MistralProvider) endpointPath(path string) string {
+	u, err := url.JoinPath(p.endpoint, path)
 	if err != nil {
 		p.logger.Error("Error joining URL", "error", err)
-	} else {
-		return u
+		return mistralBaseURL + path
 	}
-	return "https://api.mistral.ai/v1/chat/completions"
+	return u
+}
+
+// PrepareEmbeddingRequest creates the request body embedding inputs with the
+// embedding model, "mistral-embed" unless SetMistralEmbeddingModel selects
+// another. Generation options do not apply to embeddings.
+func (p *MistralProvider) PrepareEmbeddingRequest(inputs []string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"model":           p.embeddingModel,
+		"input":           inputs,
+		"encoding_format": "float",
+	})
+}
+
+// ParseEmbeddingResponse extracts the embeddings from a Mistral embeddings
+// response, in input order.
+func (p *MistralProvider) ParseEmbeddingResponse(body []byte) ([][]float64, error) {
+	return parseEmbeddingData(body)
+}
+
+// SupportsSystemPrompt indicates that the system prompt is sent as a system message.
+func (p *MistralProvider) SupportsSystemPrompt() bool {
+	return true
 }
 
 // SupportsJSONSchema indicates that Mistral supports structured output
@@ -137,10 +221,8 @@ func (p *MistralProvider) Headers() map[string]string {
 //   - Any error encountered during preparation
 func (p *MistralProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
 	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]interface{}{
-			{"role": "user", "content": prompt},
-		},
+		"model":    p.model,
+		"messages": chatMessages(prompt, options),
 	}
 
 	// First, add the default options
@@ -152,6 +234,8 @@ func (p *MistralProvider) PrepareRequest(prompt string, options map[string]inter
 	for k, v := range options {
 		requestBody[k] = v
 	}
+	delete(requestBody, "system_prompt")
+	adaptSamplingOptions(requestBody, mistralSamplingNames, p.Name(), p.logger)
 
 	return json.Marshal(requestBody)
 }
@@ -169,10 +253,8 @@ func (p *MistralProvider) PrepareRequest(prompt string, options map[string]inter
 //   - Any error encountered during preparation
 func (p *MistralProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
 	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
+		"model":    p.model,
+		"messages": chatMessages(prompt, options),
 		"response_format": map[string]interface{}{
 			"type":   "json_schema",
 			"schema": schema,
@@ -188,6 +270,8 @@ func (p *MistralProvider) PrepareRequestWithSchema(prompt string, options map[st
 	if strict, ok := options["strict"].(bool); ok && strict {
 		requestBody["response_format"].(map[string]interface{})["strict"] = true
 	}
+	delete(requestBody, "system_prompt")
+	adaptSamplingOptions(requestBody, mistralSamplingNames, p.Name(), p.logger)
 
 	return json.Marshal(requestBody)
 }
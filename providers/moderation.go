@@ -0,0 +1,62 @@
+// Package providers implements LLM provider interfaces and their implementations.
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Moderator is implemented by providers with a moderation endpoint, which
+// classifies text against the provider's content policy. Moderation requests
+// go to their own endpoint and use their own model, separate from text
+// generation.
+type Moderator interface {
+	// SupportsModeration reports whether the moderation endpoint is available,
+	// which it may not be for providers serving the same API elsewhere.
+	SupportsModeration() bool
+
+	// ModerationEndpoint returns the API endpoint URL for moderation requests.
+	ModerationEndpoint() string
+
+	// PrepareModerationRequest creates the request body moderating text.
+	PrepareModerationRequest(text string) ([]byte, error)
+
+	// ParseModerationResponse extracts the verdict on the text.
+	ParseModerationResponse(body []byte) (*ModerationVerdict, error)
+}
+
+// ModerationVerdict is a moderation endpoint's verdict on a text.
+type ModerationVerdict struct {
+	Flagged    bool               // Whether the text violates the content policy
+	Categories map[string]bool    // Whether the text violates each category
+	Scores     map[string]float64 // The confidence of each category, from 0 to 1
+}
+
+// parseModerationResults extracts the verdict of an OpenAI-style moderation
+// response, whose "results" hold one entry per input. A result without a
+// "flagged" field is flagged if any of its categories is.
+func parseModerationResults(body []byte) (*ModerationVerdict, error) {
+	var response struct {
+		Results []struct {
+			Flagged        *bool              `json:"flagged"`
+			Categories     map[string]bool    `json:"categories"`
+			CategoryScores map[string]float64 `json:"category_scores"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing moderation response: %w", err)
+	}
+	if len(response.Results) == 0 {
+		return nil, fmt.Errorf("empty moderation response from API")
+	}
+	result := response.Results[0]
+	verdict := &ModerationVerdict{Categories: result.Categories, Scores: result.CategoryScores}
+	if result.Flagged != nil {
+		verdict.Flagged = *result.Flagged
+	} else {
+		for _, flagged := range result.Categories {
+			verdict.Flagged = verdict.Flagged || flagged
+		}
+	}
+	return verdict, nil
+}
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"reflect"
 	"strings"
 
 	"github.com/yockii/gollm_cn/config"
@@ -28,6 +30,8 @@ type OllamaProvider struct {
 	options map[string]interface{} // Model-specific options
 	// logger is the logger instance for this provider
 	logger utils.Logger // Logger instance
+	// client is the HTTP client set with config.SetHTTPClient, if any
+	client *http.Client // HTTP client used by Generate
 }
 
 // NewOllamaProvider creates a new Ollama provider instance.
@@ -44,6 +48,9 @@ func NewOllamaProvider(endpoint, apiKey, model string, extraHeaders map[string]s
 	if extraHeaders == nil {
 		extraHeaders = make(map[string]string)
 	}
+	if endpoint == "" {
+		endpoint = "http://localhost:11434"
+	}
 	return &OllamaProvider{
 		endpoint:     endpoint,
 		model:        model,
@@ -67,7 +74,13 @@ func (p *OllamaProvider) Name() string {
 // Endpoint returns the configured Ollama API endpoint URL.
 // This is typically "http://localhost:11434/api/generate".
 func (p *OllamaProvider) Endpoint() string {
-	return p.endpoint + "/api/generate"
+	u, err := url.JoinPath(p.endpoint, "/api/generate")
+	if err != nil {
+		p.logger.Error("Error joining URL", "error", err)
+	} else {
+		return u
+	}
+	return "http://localhost:11434/api/generate"
 }
 
 // SetOption sets a model-specific option for the Ollama provider.
@@ -89,13 +102,11 @@ func (p *OllamaProvider) SetOption(key string, value interface{}) {
 func (p *OllamaProvider) SetDefaultOptions(config *config.Config) {
 	p.SetOption("temperature", config.Temperature)
 	p.SetOption("num_predict", config.MaxTokens)
-	if config.Seed != nil {
-		p.SetOption("seed", *config.Seed)
-	}
+	setSamplingDefaults(p.SetOption, config)
 	if config.Endpoint != "" {
 		p.SetEndpoint(config.Endpoint)
 	}
-	p.SetOption("top_p", config.TopP)
+	p.client = config.HTTPClient
 	p.SetOption("min_p", config.MinP)
 	p.SetOption("repeat_penalty", config.RepeatPenalty)
 	p.SetOption("repeat_last_n", config.RepeatLastN)
@@ -105,10 +116,15 @@ func (p *OllamaProvider) SetDefaultOptions(config *config.Config) {
 	p.SetOption("tfs_z", config.TfsZ)
 }
 
-// SupportsJSONSchema indicates whether this provider supports JSON schema validation.
-// Currently, Ollama does not natively support JSON schema validation.
+// SupportsSystemPrompt indicates that the system prompt is sent in Ollama's system field.
+func (p *OllamaProvider) SupportsSystemPrompt() bool {
+	return true
+}
+
+// SupportsJSONSchema indicates that Ollama supports native JSON schema
+// validation through the "format" request parameter.
 func (p *OllamaProvider) SupportsJSONSchema() bool {
-	return false
+	return true
 }
 
 // Headers returns the HTTP headers required for Ollama API requests.
@@ -129,26 +145,91 @@ func (p *OllamaProvider) Headers() map[string]string {
 // Returns:
 //   - Serialized JSON request body
 //   - Any error encountered during preparation
+//
+// Sampling parameters (temperature, num_predict, stop, ...) are sent in the
+// "options" object as Ollama expects. Defaults from SetDefaultOptions are
+// applied first and per-request options override them; "max_tokens" is
+// translated to "num_predict" and "system_prompt" to "system".
 func (p *OllamaProvider) PrepareRequest(prompt string, options map[string]interface{}) ([]byte, error) {
 	requestBody := map[string]interface{}{
 		"model":  p.model,
 		"prompt": prompt,
 	}
 
+	modelOptions := make(map[string]interface{})
+	for k, v := range p.options {
+		if ollamaModelOptions[k] && !isNilValue(v) {
+			modelOptions[k] = v
+		}
+	}
 	for k, v := range options {
+		switch k {
+		case "max_tokens":
+			k = "num_predict"
+		case "system_prompt":
+			k = "system"
+		}
+		if ollamaModelOptions[k] {
+			if !isNilValue(v) {
+				modelOptions[k] = v
+			}
+			continue
+		}
 		requestBody[k] = v
 	}
+	if len(modelOptions) > 0 {
+		requestBody["options"] = modelOptions
+	}
 
 	return json.Marshal(requestBody)
 }
 
+// ollamaModelOptions lists the parameters Ollama reads from the "options" object.
+var ollamaModelOptions = map[string]bool{
+	"temperature":       true,
+	"num_predict":       true,
+	"top_p":             true,
+	"top_k":             true,
+	"seed":              true,
+	"stop":              true,
+	"frequency_penalty": true,
+	"presence_penalty":  true,
+	"min_p":             true,
+	"repeat_penalty":    true,
+	"repeat_last_n":     true,
+	"mirostat":          true,
+	"mirostat_eta":      true,
+	"mirostat_tau":      true,
+	"tfs_z":             true,
+}
+
+// isNilValue reports whether v is nil or a nil pointer, such as an unset
+// optional sampling parameter from the configuration.
+func isNilValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
 // PrepareRequestWithSchema creates a request with JSON schema validation.
-// Since Ollama doesn't support schema validation natively, this falls back to
-// standard request preparation.
+// The schema is sent as Ollama's "format" parameter, which constrains the
+// model's output to JSON matching the schema.
 func (p *OllamaProvider) PrepareRequestWithSchema(prompt string, options map[string]interface{}, schema interface{}) ([]byte, error) {
-	// Ollama doesn't support JSON schema validation natively
-	// We'll just use the regular PrepareRequest method
-	return p.PrepareRequest(prompt, options)
+	schemaOptions := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		schemaOptions[k] = v
+	}
+	switch s := schema.(type) {
+	case string:
+		schemaOptions["format"] = json.RawMessage(s)
+	case []byte:
+		schemaOptions["format"] = json.RawMessage(s)
+	default:
+		schemaOptions["format"] = schema
+	}
+	return p.PrepareRequest(prompt, schemaOptions)
 }
 
 // ParseResponse extracts the generated text from the Ollama API response.
@@ -236,7 +317,10 @@ func (p *OllamaProvider) Generate(ctx context.Context, prompt string) (string, s
 		req.Header.Set(k, v)
 	}
 
-	client := &http.Client{}
+	client := p.client
+	if client == nil {
+		client = &http.Client{}
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", "", err
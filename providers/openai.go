@@ -80,12 +80,18 @@ func (p *OpenAIProvider) SetOption(key string, value interface{}) {
 func (p *OpenAIProvider) SetDefaultOptions(config *config.Config) {
 	p.SetOption("temperature", config.Temperature)
 	p.SetOption("max_tokens", config.MaxTokens)
-	if config.Seed != nil {
-		p.SetOption("seed", *config.Seed)
-	}
+	setSamplingDefaults(p.SetOption, config)
 	p.logger.Debug("Default options set", "temperature", config.Temperature, "max_tokens", config.MaxTokens, "seed", config.Seed)
 }
 
+// openaiSamplingNames maps sampling parameters to OpenAI's names. The OpenAI
+// chat API has no top-k sampling and no repetition penalty; the Groq API
+// follows it.
+var openaiSamplingNames = map[string]string{
+	"top_k":          "",
+	"repeat_penalty": "",
+}
+
 // Name returns "openai" as the provider identifier.
 func (p *OpenAIProvider) Name() string {
 	return "openai"
@@ -103,6 +109,102 @@ func (p *OpenAIProvider) Endpoint() string {
 	return "https://api.openai.com/v1/chat/completions"
 }
 
+// openAIModerationModel is the model of OpenAI's moderation endpoint.
+const openAIModerationModel = "omni-moderation-latest"
+
+// SupportsModeration indicates that OpenAI has a moderation endpoint.
+func (p *OpenAIProvider) SupportsModeration() bool {
+	return true
+}
+
+// ModerationEndpoint returns the OpenAI moderation endpoint URL.
+// This is "https://api.openai.com/v1/moderations".
+func (p *OpenAIProvider) ModerationEndpoint() string {
+	u, err := url.JoinPath(p.endpoint, "/moderations")
+	if err != nil {
+		p.logger.Error("Error joining URL", "error", err)
+		return "https://api.openai.com/v1/moderations"
+	}
+	return u
+}
+
+// PrepareModerationRequest creates the request body moderating text with
+// "omni-moderation-latest".
+func (p *OpenAIProvider) PrepareModerationRequest(text string) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"model": openAIModerationModel,
+		"input": text,
+	})
+}
+
+// ParseModerationResponse extracts the verdict from an OpenAI moderation
+// response.
+func (p *OpenAIProvider) ParseModerationResponse(body []byte) (*ModerationVerdict, error) {
+	return parseModerationResults(body)
+}
+
+// SupportsBatch indicates that OpenAI has a batch API.
+func (p *OpenAIProvider) SupportsBatch() bool {
+	return true
+}
+
+// BatchURL returns the URL of path under the OpenAI API, such as
+// "https://api.openai.com/v1/batches".
+func (p *OpenAIProvider) BatchURL(path string) string {
+	u, err := url.JoinPath(p.endpoint, path)
+	if err != nil {
+		p.logger.Error("Error joining URL", "error", err)
+		return "https://api.openai.com/v1/" + path
+	}
+	return u
+}
+
+// BatchRequestURL returns "/v1/chat/completions", the endpoint of batched
+// requests.
+func (p *OpenAIProvider) BatchRequestURL() string {
+	return "/v1/chat/completions"
+}
+
+// SupportsSystemPrompt indicates that the system prompt is sent as a system message.
+func (p *OpenAIProvider) SupportsSystemPrompt() bool {
+	return true
+}
+
+// SupportsImages indicates that OpenAI vision models accept images as
+// image_url content parts.
+func (p *OpenAIProvider) SupportsImages() bool {
+	return true
+}
+
+// SupportsLogitBias indicates that OpenAI accepts the "logit_bias" option,
+// keyed by token ID.
+func (p *OpenAIProvider) SupportsLogitBias() bool {
+	return true
+}
+
+// SupportsLogprobs indicates that OpenAI returns the log probabilities of the
+// response's tokens.
+func (p *OpenAIProvider) SupportsLogprobs() bool {
+	return true
+}
+
+// MaxTopLogprobs returns the number of alternatives per token OpenAI returns
+// at most.
+func (p *OpenAIProvider) MaxTopLogprobs() int {
+	return 20
+}
+
+// ParseLogprobs extracts the log probabilities from the "logprobs" of the
+// response's first choice.
+func (p *OpenAIProvider) ParseLogprobs(body []byte) ([]TokenLogprob, error) {
+	return parseChatLogprobs(body)
+}
+
+// MaxStopSequences returns the number of stop sequences OpenAI accepts.
+func (p *OpenAIProvider) MaxStopSequences() int {
+	return 4
+}
+
 // SupportsJSONSchema indicates that OpenAI supports native JSON schema validation
 // through its function calling and JSON mode capabilities.
 func (p *OpenAIProvider) SupportsJSONSchema() bool {
@@ -148,19 +250,7 @@ func (p *OpenAIProvider) PrepareRequest(prompt string, options map[string]interf
 		"messages": []map[string]interface{}{},
 	}
 
-	// Handle system prompt as developer message
-	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
-		request["messages"] = append(request["messages"].([]map[string]interface{}), map[string]interface{}{
-			"role":    "developer",
-			"content": systemPrompt,
-		})
-	}
-
-	// Add user message
-	request["messages"] = append(request["messages"].([]map[string]interface{}), map[string]interface{}{
-		"role":    "user",
-		"content": prompt,
-	})
+	request["messages"] = chatMessages(prompt, options)
 
 	// Handle tool_choice
 	if toolChoice, ok := options["tool_choice"].(string); ok {
@@ -191,10 +281,11 @@ func (p *OpenAIProvider) PrepareRequest(prompt string, options map[string]interf
 		}
 	}
 	for k, v := range options {
-		if k != "tools" && k != "tool_choice" && k != "system_prompt" {
+		if k != "tools" && k != "tool_choice" && k != "system_prompt" && k != "images" {
 			request[k] = v
 		}
 	}
+	adaptSamplingOptions(request, openaiSamplingNames, p.Name(), p.logger)
 
 	return json.Marshal(request)
 }
@@ -245,10 +336,8 @@ func (p *OpenAIProvider) PrepareRequestWithSchema(prompt string, options map[str
 	p.logger.Debug("Cleaned schema for OpenAI", "schema", string(cleanSchemaJSON))
 
 	request := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]interface{}{
-			{"role": "user", "content": prompt},
-		},
+		"model":    p.model,
+		"messages": chatMessages(prompt, options),
 		"response_format": map[string]interface{}{
 			"type": "json_schema",
 			"json_schema": map[string]interface{}{
@@ -259,19 +348,13 @@ func (p *OpenAIProvider) PrepareRequestWithSchema(prompt string, options map[str
 		},
 	}
 
-	// Handle system prompt as system message
-	if systemPrompt, ok := options["system_prompt"].(string); ok && systemPrompt != "" {
-		request["messages"] = append([]map[string]interface{}{
-			{"role": "system", "content": systemPrompt},
-		}, request["messages"].([]map[string]interface{})...)
-	}
-
 	// Add other options
 	for k, v := range options {
-		if k != "system_prompt" {
+		if k != "system_prompt" && k != "images" {
 			request[k] = v
 		}
 	}
+	adaptSamplingOptions(request, openaiSamplingNames, p.Name(), p.logger)
 
 	reqJSON, err := json.Marshal(request)
 	if err != nil {
@@ -405,23 +488,8 @@ func (p *OpenAIProvider) SupportsStreaming() bool {
 
 // PrepareStreamRequest creates a request body for streaming API calls
 func (p *OpenAIProvider) PrepareStreamRequest(prompt string, options map[string]interface{}) ([]byte, error) {
-	// Start with regular request preparation
-	requestBody := map[string]interface{}{
-		"model": p.model,
-		"messages": []map[string]string{
-			{"role": "user", "content": prompt},
-		},
-		"stream": true,
-	}
-
-	// Add other options
-	for k, v := range options {
-		if k != "stream" { // Don't override stream setting
-			requestBody[k] = v
-		}
-	}
-
-	return json.Marshal(requestBody)
+	options["stream"] = true
+	return p.PrepareRequest(prompt, options)
 }
 
 // ParseStreamResponse processes a single chunk from a streaming response
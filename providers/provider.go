@@ -49,6 +49,11 @@ type Provider interface {
 	// SupportsJSONSchema indicates whether the provider supports native JSON schema validation.
 	SupportsJSONSchema() bool
 
+	// SupportsSystemPrompt indicates whether the provider sends the "system_prompt"
+	// option as a system message. For other providers the system prompt is
+	// prepended to the user prompt.
+	SupportsSystemPrompt() bool
+
 	// SetDefaultOptions configures provider-specific defaults from the global configuration.
 	SetDefaultOptions(config *config.Config)
 
@@ -94,6 +99,9 @@ type ProviderRegistry struct {
 //   - "groq": Groq's LLM services
 //   - "ollama": Local LLM deployment
 //   - "mistral": Mistral AI's models
+//   - "cohere": Cohere's Command models
+//   - "gemini": Google's Gemini models
+//   - "azure": OpenAI models deployed on Azure OpenAI
 //
 // Example usage:
 //
@@ -115,6 +123,8 @@ func NewProviderRegistry(providerNames ...string) *ProviderRegistry {
 		"ollama":    NewOllamaProvider,
 		"mistral":   NewMistralProvider,
 		"cohere":    NewCohereProvider,
+		"gemini":    NewGeminiProvider,
+		"azure":     NewAzureOpenAIProvider,
 		// Add other providers here as they are implemented
 	}
 
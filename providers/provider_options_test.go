@@ -0,0 +1,254 @@
+package providers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/config"
+)
+
+func prepare(t *testing.T, p Provider, options map[string]interface{}, opts ...config.ConfigOption) map[string]interface{} {
+	cfg := config.NewConfig()
+	config.ApplyOptions(cfg, opts...)
+	p.SetDefaultOptions(cfg)
+	body, err := p.PrepareRequest("hello", options)
+	require.NoError(t, err)
+	var request map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &request))
+	return request
+}
+
+func TestPrepareRequest_PerCallOverrides(t *testing.T) {
+	overrides := map[string]interface{}{
+		"model":       "override-model",
+		"temperature": 0.1,
+		"max_tokens":  42,
+		"stop":        []string{"END"},
+	}
+
+	t.Run("anthropic", func(t *testing.T) {
+		req := prepare(t, NewAnthropicProvider("", "key", "claude", nil), overrides)
+		assert.Equal(t, "override-model", req["model"])
+		assert.Equal(t, float64(42), req["max_tokens"])
+		assert.Equal(t, []interface{}{"END"}, req["stop_sequences"])
+		assert.NotContains(t, req, "stop")
+	})
+
+	t.Run("anthropic uses client max tokens without override", func(t *testing.T) {
+		req := prepare(t, NewAnthropicProvider("", "key", "claude", nil), map[string]interface{}{})
+		assert.Equal(t, float64(300), req["max_tokens"])
+	})
+
+	t.Run("cohere", func(t *testing.T) {
+		req := prepare(t, NewCohereProvider("", "key", "command-r", nil), overrides)
+		assert.Equal(t, "override-model", req["model"])
+		assert.Equal(t, []interface{}{"END"}, req["stop_sequences"])
+		assert.NotContains(t, req, "stop")
+	})
+
+	t.Run("ollama", func(t *testing.T) {
+		req := prepare(t, NewOllamaProvider("http://localhost:11434", "", "llama3", nil), overrides)
+		assert.Equal(t, "override-model", req["model"])
+		opts, ok := req["options"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, 0.1, opts["temperature"])
+		assert.Equal(t, float64(42), opts["num_predict"])
+		assert.Equal(t, []interface{}{"END"}, opts["stop"])
+		assert.NotContains(t, req, "temperature")
+		assert.NotContains(t, req, "max_tokens")
+	})
+
+	t.Run("ollama sends client defaults", func(t *testing.T) {
+		req := prepare(t, NewOllamaProvider("http://localhost:11434", "", "llama3", nil), map[string]interface{}{})
+		opts, ok := req["options"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, 0.7, opts["temperature"])
+		assert.Equal(t, float64(300), opts["num_predict"])
+		assert.NotContains(t, opts, "seed")
+	})
+}
+
+func TestPrepareRequest_SamplingParameters(t *testing.T) {
+	sampling := []config.ConfigOption{
+		config.SetTopP(0.5),
+		config.SetFrequencyPenalty(0.3),
+		config.SetPresencePenalty(0.4),
+		config.SetSeed(7),
+		config.SetStopSequences("END"),
+	}
+	stop := []interface{}{"END"}
+
+	for _, tc := range []struct {
+		name     string
+		provider Provider
+	}{
+		{"openai", NewOpenAIProvider("", "key", "gpt-4o-mini", nil)},
+		{"groq", NewGroqProvider("", "key", "llama3", nil)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := prepare(t, tc.provider, map[string]interface{}{}, sampling...)
+			assert.Equal(t, 0.5, req["top_p"])
+			assert.Equal(t, 0.3, req["frequency_penalty"])
+			assert.Equal(t, 0.4, req["presence_penalty"])
+			assert.Equal(t, float64(7), req["seed"])
+			assert.Equal(t, stop, req["stop"])
+		})
+	}
+
+	t.Run("mistral", func(t *testing.T) {
+		req := prepare(t, NewMistralProvider("", "key", "mistral-small", nil), map[string]interface{}{}, sampling...)
+		assert.Equal(t, 0.5, req["top_p"])
+		assert.Equal(t, 0.3, req["frequency_penalty"])
+		assert.Equal(t, 0.4, req["presence_penalty"])
+		assert.Equal(t, float64(7), req["random_seed"])
+		assert.NotContains(t, req, "seed")
+		assert.Equal(t, stop, req["stop"])
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		req := prepare(t, NewAnthropicProvider("", "key", "claude", nil), map[string]interface{}{}, sampling...)
+		assert.Equal(t, 0.5, req["top_p"])
+		assert.Equal(t, stop, req["stop_sequences"])
+		assert.NotContains(t, req, "stop")
+		assert.NotContains(t, req, "frequency_penalty")
+		assert.NotContains(t, req, "presence_penalty")
+		assert.NotContains(t, req, "seed")
+	})
+
+	t.Run("cohere", func(t *testing.T) {
+		req := prepare(t, NewCohereProvider("", "key", "command-r", nil), map[string]interface{}{}, sampling...)
+		assert.Equal(t, 0.5, req["p"])
+		assert.NotContains(t, req, "top_p")
+		assert.Equal(t, 0.3, req["frequency_penalty"])
+		assert.Equal(t, 0.4, req["presence_penalty"])
+		assert.Equal(t, float64(7), req["seed"])
+		assert.Equal(t, stop, req["stop_sequences"])
+	})
+
+	t.Run("ollama", func(t *testing.T) {
+		req := prepare(t, NewOllamaProvider("http://localhost:11434", "", "llama3", nil), map[string]interface{}{}, sampling...)
+		opts, ok := req["options"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, 0.5, opts["top_p"])
+		assert.Equal(t, 0.3, opts["frequency_penalty"])
+		assert.Equal(t, 0.4, opts["presence_penalty"])
+		assert.Equal(t, float64(7), opts["seed"])
+		assert.Equal(t, stop, opts["stop"])
+	})
+
+	t.Run("per-call options override the client", func(t *testing.T) {
+		overrides := map[string]interface{}{"top_p": 0.2, "seed": 99}
+		req := prepare(t, NewAnthropicProvider("", "key", "claude", nil), overrides, sampling...)
+		assert.Equal(t, 0.2, req["top_p"])
+		assert.NotContains(t, req, "seed")
+
+		req = prepare(t, NewMistralProvider("", "key", "mistral-small", nil), overrides, sampling...)
+		assert.Equal(t, 0.2, req["top_p"])
+		assert.Equal(t, float64(99), req["random_seed"])
+	})
+
+	t.Run("neutral values are not sent", func(t *testing.T) {
+		req := prepare(t, NewOpenAIProvider("", "key", "gpt-4o-mini", nil), map[string]interface{}{}, config.SetTopP(1))
+		for _, key := range samplingKeys {
+			assert.NotContains(t, req, key)
+		}
+	})
+}
+
+func TestPrepareRequest_TopKAndRepetitionPenalty(t *testing.T) {
+	overrides := map[string]interface{}{"repeat_penalty": 1.2}
+
+	for _, tc := range []struct {
+		name     string
+		provider Provider
+		topK     string // request field for top_k, "" if dropped
+	}{
+		{"openai", NewOpenAIProvider("", "key", "gpt-4o-mini", nil), ""},
+		{"groq", NewGroqProvider("", "key", "llama3", nil), ""},
+		{"mistral", NewMistralProvider("", "key", "mistral-small", nil), ""},
+		{"anthropic", NewAnthropicProvider("", "key", "claude", nil), "top_k"},
+		{"cohere", NewCohereProvider("", "key", "command-r", nil), "k"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := prepare(t, tc.provider, overrides, config.SetTopK(40))
+			assert.NotContains(t, req, "repeat_penalty")
+			if tc.topK == "" {
+				assert.NotContains(t, req, "top_k")
+			} else {
+				assert.Equal(t, float64(40), req[tc.topK])
+			}
+		})
+	}
+
+	t.Run("ollama", func(t *testing.T) {
+		req := prepare(t, NewOllamaProvider("http://localhost:11434", "", "llama3", nil), overrides, config.SetTopK(40))
+		opts, ok := req["options"].(map[string]interface{})
+		require.True(t, ok)
+		assert.Equal(t, float64(40), opts["top_k"])
+		assert.Equal(t, 1.2, opts["repeat_penalty"])
+	})
+}
+
+func TestPrepareRequest_SystemPrompt(t *testing.T) {
+	options := map[string]interface{}{"system_prompt": "你是一名翻译。"}
+
+	for _, tc := range []struct {
+		name     string
+		provider Provider
+	}{
+		{"openai", NewOpenAIProvider("", "key", "gpt-4o-mini", nil)},
+		{"groq", NewGroqProvider("", "key", "llama3", nil)},
+		{"mistral", NewMistralProvider("", "key", "mistral-small", nil)},
+		{"cohere", NewCohereProvider("", "key", "command-r", nil)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.True(t, tc.provider.SupportsSystemPrompt())
+			req := prepare(t, tc.provider, options)
+			assert.Equal(t, []interface{}{
+				map[string]interface{}{"role": "system", "content": "你是一名翻译。"},
+				map[string]interface{}{"role": "user", "content": "hello"},
+			}, req["messages"])
+			assert.NotContains(t, req, "system_prompt")
+		})
+	}
+
+	t.Run("anthropic", func(t *testing.T) {
+		req := prepare(t, NewAnthropicProvider("", "key", "claude", nil), options)
+		system, ok := req["system"].([]interface{})
+		require.True(t, ok)
+		require.NotEmpty(t, system)
+		assert.Equal(t, "你是一名翻译。", system[0].(map[string]interface{})["text"])
+		assert.NotContains(t, req, "system_prompt")
+	})
+
+	t.Run("ollama", func(t *testing.T) {
+		req := prepare(t, NewOllamaProvider("http://localhost:11434", "", "llama3", nil), options)
+		assert.Equal(t, "你是一名翻译。", req["system"])
+		assert.Equal(t, "hello", req["prompt"])
+		assert.NotContains(t, req, "system_prompt")
+	})
+}
+
+func TestEndpoint_BaseURL(t *testing.T) {
+	registry := NewProviderRegistry()
+	for _, tc := range []struct {
+		provider, base, want string
+	}{
+		{"openai", "http://localhost:8000/v1", "http://localhost:8000/v1/chat/completions"},
+		{"openai", "http://localhost:8000/v1/", "http://localhost:8000/v1/chat/completions"},
+		{"openai", "https://example.openai.azure.com/openai/deployments/gpt-4o?api-version=2024-06-01", "https://example.openai.azure.com/openai/deployments/gpt-4o/chat/completions?api-version=2024-06-01"},
+		{"anthropic", "https://proxy.example.com/v1/", "https://proxy.example.com/v1/messages"},
+		{"groq", "https://proxy.example.com/groq", "https://proxy.example.com/groq/chat/completions"},
+		{"mistral", "https://proxy.example.com/mistral/", "https://proxy.example.com/mistral/chat/completions"},
+		{"cohere", "https://proxy.example.com/cohere", "https://proxy.example.com/cohere/chat"},
+		{"gemini", "https://proxy.example.com/gemini/", "https://proxy.example.com/gemini/models/m:generateContent"},
+		{"ollama", "http://gpu-box:11434/", "http://gpu-box:11434/api/generate"},
+		{"ollama", "", "http://localhost:11434/api/generate"},
+	} {
+		p, err := registry.Get(tc.provider, tc.base, "key", "m", nil)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, p.Endpoint(), "%s with base %q", tc.provider, tc.base)
+	}
+}
@@ -0,0 +1,24 @@
+// Package providers implements LLM provider interfaces and their implementations.
+package providers
+
+// Reranker is implemented by providers with a rerank endpoint, which orders
+// documents by their relevance to a query. Rerank requests go to their own
+// endpoint and use their own model, separate from text generation.
+type Reranker interface {
+	// RerankEndpoint returns the API endpoint URL for rerank requests.
+	RerankEndpoint() string
+
+	// PrepareRerankRequest creates the request body ranking documents by
+	// their relevance to query, keeping the topN most relevant.
+	PrepareRerankRequest(query string, documents []string, topN int) ([]byte, error)
+
+	// ParseRerankResponse extracts the ranked documents.
+	ParseRerankResponse(body []byte) ([]RerankResult, error)
+}
+
+// RerankResult is the relevance of a document to the query of a rerank
+// request.
+type RerankResult struct {
+	Index int     // Index of the document in the request
+	Score float64 // Relevance to the query, from 0 to 1
+}
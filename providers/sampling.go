@@ -0,0 +1,69 @@
+// Package providers implements LLM provider interfaces and their implementations.
+package providers
+
+import (
+	"github.com/yockii/gollm_cn/config"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// samplingKeys are the names of the optional sampling parameters. OpenAI names
+// are used where OpenAI has the parameter; top_k and repeat_penalty use the
+// Ollama names.
+var samplingKeys = []string{"top_p", "top_k", "frequency_penalty", "presence_penalty", "repeat_penalty", "seed", "stop"}
+
+// setSamplingDefaults stores the optional sampling parameters configured in cfg
+// as provider options, using the names top_p, top_k, frequency_penalty,
+// presence_penalty, seed and stop. Parameters left at their neutral value are
+// not stored, so the provider's own defaults apply.
+func setSamplingDefaults(setOption func(key string, value interface{}), cfg *config.Config) {
+	if cfg.TopP > 0 && cfg.TopP < 1 {
+		setOption("top_p", cfg.TopP)
+	}
+	if cfg.TopK != nil {
+		setOption("top_k", *cfg.TopK)
+	}
+	if cfg.FrequencyPenalty != 0 {
+		setOption("frequency_penalty", cfg.FrequencyPenalty)
+	}
+	if cfg.PresencePenalty != 0 {
+		setOption("presence_penalty", cfg.PresencePenalty)
+	}
+	if cfg.Seed != nil {
+		setOption("seed", *cfg.Seed)
+	}
+	if len(cfg.StopSequences) > 0 {
+		setOption("stop", cfg.StopSequences)
+	}
+}
+
+// addSamplingDefaults copies the sampling parameters stored by
+// setSamplingDefaults into body unless the request already sets them. It is
+// used by providers that build request bodies from per-call options only.
+func addSamplingDefaults(body, defaults map[string]interface{}) {
+	for _, key := range samplingKeys {
+		if value, ok := defaults[key]; ok {
+			if _, set := body[key]; !set {
+				body[key] = value
+			}
+		}
+	}
+}
+
+// adaptSamplingOptions renames sampling parameters in a request body from
+// their OpenAI names to the names used by the provider's API. Parameters
+// mapped to "" are not supported by the provider; they are dropped and logged
+// at debug level.
+func adaptSamplingOptions(body map[string]interface{}, names map[string]string, provider string, logger utils.Logger) {
+	for key, name := range names {
+		value, ok := body[key]
+		if !ok || name == key {
+			continue
+		}
+		delete(body, key)
+		if name == "" {
+			logger.Debug("Dropping option not supported by provider", "provider", provider, "option", key)
+			continue
+		}
+		body[name] = value
+	}
+}
@@ -0,0 +1,261 @@
+// Package gollm provides a high-level interface for interacting with Language Learning Models (LLMs).
+package gollm
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/yockii/gollm_cn/llm"
+)
+
+// ShadowRecord captures one shadowed call for offline comparison of a
+// candidate model against the primary one. Prompt and responses have already
+// been passed through the shadow's redactor when one is configured.
+type ShadowRecord struct {
+	// Shadow is always true and tags the record as shadow traffic
+	Shadow bool
+
+	// Timestamp is when the primary call completed
+	Timestamp time.Time
+
+	// Prompt is the rendered prompt sent to both models
+	Prompt string
+
+	// PrimaryProvider and PrimaryModel identify the model serving users
+	PrimaryProvider string
+	PrimaryModel    string
+
+	// CandidateProvider and CandidateModel identify the model under evaluation
+	CandidateProvider string
+	CandidateModel    string
+
+	// PrimaryResponse is the response returned to the caller
+	PrimaryResponse string
+	PrimaryLatency  time.Duration
+
+	// CandidateResponse is the candidate's response, empty if CandidateError is set
+	CandidateResponse string
+	CandidateLatency  time.Duration
+	CandidateError    error
+
+	// JudgeScore is the judge's score for the candidate, nil without a judge or on judge failure
+	JudgeScore *float64
+	JudgeError error
+}
+
+// ShadowRecorder stores shadow records, for example in a database or a JSONL file.
+// Record is called from a background goroutine and may be called concurrently.
+type ShadowRecorder interface {
+	Record(ctx context.Context, record ShadowRecord) error
+}
+
+// ShadowJudge scores the candidate response against the primary response for
+// the same prompt. Higher is better; the scale is up to the judge.
+type ShadowJudge func(ctx context.Context, prompt, primaryResponse, candidateResponse string) (float64, error)
+
+// ShadowOption configures a ShadowLLM.
+type ShadowOption func(*ShadowLLM)
+
+// WithShadowTimeout bounds each shadow call, including judging and recording.
+// The default is 30 seconds.
+func WithShadowTimeout(timeout time.Duration) ShadowOption {
+	return func(s *ShadowLLM) {
+		s.timeout = timeout
+	}
+}
+
+// WithShadowJudge scores every shadowed pair of responses.
+func WithShadowJudge(judge ShadowJudge) ShadowOption {
+	return func(s *ShadowLLM) {
+		s.judge = judge
+	}
+}
+
+// WithShadowRedactor scrubs prompts and responses before they are recorded or
+// logged. The candidate still receives the original prompt.
+func WithShadowRedactor(redact func(string) string) ShadowOption {
+	return func(s *ShadowLLM) {
+		s.redact = redact
+	}
+}
+
+// WithShadowMaxInFlight limits the number of concurrent shadow calls. When the
+// limit is reached, sampled calls are skipped rather than queued so shadow
+// traffic never builds up. The default is 4; 0 or less for no limit.
+func WithShadowMaxInFlight(n int) ShadowOption {
+	return func(s *ShadowLLM) {
+		if n <= 0 {
+			s.slots = nil
+			return
+		}
+		s.slots = make(chan struct{}, n)
+	}
+}
+
+// ShadowLLM serves every call from the primary LLM and mirrors a sample of
+// Generate calls to a candidate LLM in the background. Shadow calls use their
+// own timeout and concurrency budget, and their errors are only logged and
+// recorded, so they never change the latency or result seen by the caller.
+// Streaming calls are not shadowed.
+type ShadowLLM struct {
+	LLM
+
+	candidate  LLM
+	sampleRate float64
+	recorder   ShadowRecorder
+	judge      ShadowJudge
+	redact     func(string) string
+	timeout    time.Duration
+	slots      chan struct{} // Concurrency budget; nil for no limit
+	sample     func() float64
+	wg         sync.WaitGroup
+}
+
+// NewShadow creates an LLM that answers with primary and sends a sampleRate
+// fraction (0 to 1) of Generate calls to candidate as well, reporting both
+// responses to recorder.
+//
+// Example:
+//
+//	shadow := NewShadow(primary, candidate, 0.05, recorder,
+//	    WithShadowTimeout(20*time.Second),
+//	    WithShadowRedactor(scrubEmails),
+//	)
+//	defer shadow.Wait()
+//
+//	response, err := shadow.Generate(ctx, prompt)
+func NewShadow(primary, candidate LLM, sampleRate float64, recorder ShadowRecorder, opts ...ShadowOption) *ShadowLLM {
+	s := &ShadowLLM{
+		LLM:        primary,
+		candidate:  candidate,
+		sampleRate: sampleRate,
+		recorder:   recorder,
+		timeout:    30 * time.Second,
+		slots:      make(chan struct{}, 4),
+		sample:     rand.Float64,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Generate returns the primary response and, for sampled calls, mirrors the
+// prompt to the candidate in the background.
+func (s *ShadowLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	start := time.Now()
+	response, err := s.LLM.Generate(ctx, prompt, opts...)
+	if err == nil {
+		s.maybeShadow(ctx, prompt, response, time.Since(start), func(ctx context.Context) (string, error) {
+			return s.candidate.Generate(ctx, prompt, opts...)
+		})
+	}
+	return response, err
+}
+
+// GenerateWithSchema returns the primary response and, for sampled calls,
+// mirrors the prompt and schema to the candidate in the background.
+func (s *ShadowLLM) GenerateWithSchema(ctx context.Context, prompt *llm.Prompt, schema interface{}, opts ...llm.GenerateOption) (string, error) {
+	start := time.Now()
+	response, err := s.LLM.GenerateWithSchema(ctx, prompt, schema, opts...)
+	if err == nil {
+		s.maybeShadow(ctx, prompt, response, time.Since(start), func(ctx context.Context) (string, error) {
+			return s.candidate.GenerateWithSchema(ctx, prompt, schema, opts...)
+		})
+	}
+	return response, err
+}
+
+// Wait blocks until all in-flight shadow calls have been recorded. Call it
+// before shutdown so sampled calls are not lost.
+func (s *ShadowLLM) Wait() {
+	s.wg.Wait()
+}
+
+// maybeShadow starts a background candidate call if the call is sampled and
+// the concurrency budget allows it. It never blocks the caller.
+func (s *ShadowLLM) maybeShadow(ctx context.Context, prompt *llm.Prompt, primaryResponse string, primaryLatency time.Duration, call func(context.Context) (string, error)) {
+	if s.sampleRate <= 0 || s.sample() >= s.sampleRate {
+		return
+	}
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			s.Debug("Shadow call skipped, budget exhausted", "shadow", true, "candidate_model", s.candidate.GetModel())
+			return
+		}
+	}
+
+	record := ShadowRecord{
+		Shadow:            true,
+		Timestamp:         time.Now(),
+		Prompt:            prompt.String(),
+		PrimaryProvider:   s.GetProvider(),
+		PrimaryModel:      s.GetModel(),
+		CandidateProvider: s.candidate.GetProvider(),
+		CandidateModel:    s.candidate.GetModel(),
+		PrimaryResponse:   primaryResponse,
+		PrimaryLatency:    primaryLatency,
+	}
+
+	// Keep request-scoped values but not the caller's cancellation, which
+	// usually fires as soon as the primary response is returned. The shadow
+	// call is tagged for tracing and metrics, and its tokens stay out of the
+	// caller's usage recorders.
+	shadowCtx := llm.WithShadowCall(context.WithoutCancel(ctx))
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if s.slots != nil {
+			defer func() { <-s.slots }()
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				s.GetLogger().Error("Shadow call panicked", "shadow", true, "panic", fmt.Sprint(r))
+			}
+		}()
+		s.runShadow(shadowCtx, record, call)
+	}()
+}
+
+func (s *ShadowLLM) runShadow(ctx context.Context, record ShadowRecord, call func(context.Context) (string, error)) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	record.CandidateResponse, record.CandidateError = call(ctx)
+	record.CandidateLatency = time.Since(start)
+
+	if s.judge != nil && record.CandidateError == nil {
+		score, err := s.judge(ctx, record.Prompt, record.PrimaryResponse, record.CandidateResponse)
+		if err != nil {
+			record.JudgeError = err
+		} else {
+			record.JudgeScore = &score
+		}
+	}
+
+	if s.redact != nil {
+		record.Prompt = s.redact(record.Prompt)
+		record.PrimaryResponse = s.redact(record.PrimaryResponse)
+		record.CandidateResponse = s.redact(record.CandidateResponse)
+	}
+
+	logger := s.GetLogger()
+	logger.Debug("Shadow call completed", "shadow", true,
+		"candidate_provider", record.CandidateProvider, "candidate_model", record.CandidateModel,
+		"candidate_latency", record.CandidateLatency, "primary_latency", record.PrimaryLatency,
+		"candidate_error", record.CandidateError)
+
+	if s.recorder == nil {
+		return
+	}
+	if err := s.recorder.Record(ctx, record); err != nil {
+		logger.Warn("Failed to record shadow call", "shadow", true, "error", err)
+	}
+}
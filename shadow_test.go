@@ -0,0 +1,163 @@
+package gollm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yockii/gollm_cn/llm"
+	"github.com/yockii/gollm_cn/utils"
+)
+
+// fakeLLM implements the parts of LLM used by ShadowLLM.
+type fakeLLM struct {
+	LLM
+	model    string
+	response string
+	err      error
+	delay    time.Duration
+	tokens   int  // Reported as input and output usage of each call
+	shadow   bool // Whether the last call was tagged as a shadow call
+	calls    int
+	mu       sync.Mutex
+}
+
+func (f *fakeLLM) Generate(ctx context.Context, prompt *llm.Prompt, opts ...llm.GenerateOption) (string, error) {
+	f.mu.Lock()
+	f.calls++
+	f.shadow = llm.IsShadowCall(ctx)
+	f.mu.Unlock()
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	llm.AddTokenUsage(ctx, f.tokens, f.tokens)
+	return f.response, f.err
+}
+
+func (f *fakeLLM) callCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func (f *fakeLLM) GetProvider() string                            { return "fake" }
+func (f *fakeLLM) GetModel() string                               { return f.model }
+func (f *fakeLLM) GetLogger() utils.Logger                        { return utils.NewLogger(utils.LogLevelOff) }
+func (f *fakeLLM) Debug(msg string, keysAndValues ...interface{}) {}
+
+type memoryRecorder struct {
+	mu      sync.Mutex
+	records []ShadowRecord
+}
+
+func (r *memoryRecorder) Record(ctx context.Context, record ShadowRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, record)
+	return nil
+}
+
+func TestShadow_RecordsSampledCalls(t *testing.T) {
+	primary := &fakeLLM{model: "prod", response: "primary answer"}
+	candidate := &fakeLLM{model: "next", response: "candidate answer with secret"}
+	recorder := &memoryRecorder{}
+	shadow := NewShadow(primary, candidate, 1, recorder,
+		WithShadowJudge(func(ctx context.Context, prompt, a, b string) (float64, error) { return 0.8, nil }),
+		WithShadowRedactor(func(s string) string { return strings.ReplaceAll(s, "secret", "[REDACTED]") }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	response, err := shadow.Generate(ctx, llm.NewPrompt("question with secret"))
+	cancel() // the caller's context ending must not cancel the shadow call
+	require.NoError(t, err)
+	assert.Equal(t, "primary answer", response)
+
+	shadow.Wait()
+	require.Len(t, recorder.records, 1)
+	record := recorder.records[0]
+	assert.True(t, record.Shadow)
+	assert.Equal(t, "prod", record.PrimaryModel)
+	assert.Equal(t, "next", record.CandidateModel)
+	assert.Equal(t, "candidate answer with [REDACTED]", record.CandidateResponse)
+	assert.NotContains(t, record.Prompt, "secret")
+	assert.NoError(t, record.CandidateError)
+	require.NotNil(t, record.JudgeScore)
+	assert.Equal(t, 0.8, *record.JudgeScore)
+}
+
+func TestShadow_KeepsCandidateOutOfCallerUsage(t *testing.T) {
+	primary := &fakeLLM{model: "prod", response: "ok", tokens: 10}
+	candidate := &fakeLLM{model: "next", response: "ok", tokens: 1000}
+	shadow := NewShadow(primary, candidate, 1, &memoryRecorder{})
+
+	ctx, usage := llm.WithUsageRecorder(context.Background())
+	_, err := shadow.Generate(ctx, llm.NewPrompt("hi"))
+	require.NoError(t, err)
+	shadow.Wait()
+
+	require.Equal(t, 1, candidate.callCount())
+	assert.Equal(t, llm.TokenUsage{InputTokens: 10, OutputTokens: 10}, usage.Usage())
+	assert.True(t, candidate.shadow, "candidate call tagged as shadow traffic")
+	assert.False(t, primary.shadow)
+}
+
+func TestShadow_NeverAffectsCaller(t *testing.T) {
+	primary := &fakeLLM{model: "prod", response: "ok"}
+	candidate := &fakeLLM{model: "next", err: errors.New("candidate down"), delay: 200 * time.Millisecond}
+	recorder := &memoryRecorder{}
+	shadow := NewShadow(primary, candidate, 1, recorder, WithShadowTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	response, err := shadow.Generate(context.Background(), llm.NewPrompt("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	shadow.Wait()
+	require.Len(t, recorder.records, 1)
+	assert.ErrorIs(t, recorder.records[0].CandidateError, context.DeadlineExceeded)
+}
+
+func TestShadow_SamplingAndBudget(t *testing.T) {
+	primary := &fakeLLM{model: "prod", response: "ok"}
+	candidate := &fakeLLM{model: "next", response: "ok", delay: 50 * time.Millisecond}
+	shadow := NewShadow(primary, candidate, 0.5, &memoryRecorder{}, WithShadowMaxInFlight(1))
+
+	shadow.sample = func() float64 { return 0.9 }
+	_, err := shadow.Generate(context.Background(), llm.NewPrompt("hi"))
+	require.NoError(t, err)
+	shadow.Wait()
+	assert.Equal(t, 0, candidate.callCount())
+
+	// With a budget of one, the second sampled call is skipped instead of queued.
+	shadow.sample = func() float64 { return 0.1 }
+	_, err = shadow.Generate(context.Background(), llm.NewPrompt("hi"))
+	require.NoError(t, err)
+	_, err = shadow.Generate(context.Background(), llm.NewPrompt("hi"))
+	require.NoError(t, err)
+	shadow.Wait()
+	assert.Equal(t, 1, candidate.callCount())
+}
+
+func TestShadow_UnlimitedInFlight(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		primary := &fakeLLM{model: "prod", response: "ok"}
+		candidate := &fakeLLM{model: "next", response: "ok", delay: 50 * time.Millisecond}
+		shadow := NewShadow(primary, candidate, 1, &memoryRecorder{}, WithShadowMaxInFlight(n))
+		shadow.sample = func() float64 { return 0 }
+
+		for i := 0; i < 6; i++ {
+			_, err := shadow.Generate(context.Background(), llm.NewPrompt("hi"))
+			require.NoError(t, err)
+		}
+		shadow.Wait()
+		assert.Equal(t, 6, candidate.callCount(), "a limit of %d shadows every sampled call", n)
+	}
+}
@@ -18,9 +18,12 @@ type (
 	// StreamConfig holds configuration options for streaming.
 	StreamConfig = llm.StreamConfig
 
-	// RetryStrategy defines the interface for handling stream interruptions.
+	// RetryStrategy decides whether and when failed calls and interrupted streams are retried.
 	RetryStrategy = llm.RetryStrategy
 )
 
 // StreamOption is a function type that modifies StreamConfig
 type StreamOption = llm.StreamOption
+
+// WithStreamGenerateOptions applies generate options to a streamed call.
+var WithStreamGenerateOptions = llm.WithStreamGenerateOptions
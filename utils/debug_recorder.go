@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DebugRecord is one API call captured by a DebugRecorder.
+type DebugRecord struct {
+	Time       time.Time       `json:"time"`
+	Method     string          `json:"method"`
+	URL        string          `json:"url"`                // Request URL, with API keys in the query redacted
+	Request    json.RawMessage `json:"request,omitempty"`  // Request body; a JSON string if it was not JSON
+	Status     int             `json:"status,omitempty"`   // HTTP status, 0 if no response was received
+	Response   string          `json:"response,omitempty"` // Raw response body, including streamed events
+	Error      string          `json:"error,omitempty"`    // Transport or body read error
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// DebugRecorder writes the raw request and response bodies of API calls to a
+// writer as newline-delimited JSON, one DebugRecord per call, so production
+// failures can be inspected and replayed with ReplayRecording. Request
+// headers are not recorded, since they carry API keys. It is safe for
+// concurrent use.
+type DebugRecorder struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewDebugRecorder creates a recorder writing to w, such as a log file or
+// os.Stderr. Attach it to a client with config.SetDebugRecorder.
+//
+// Example:
+//
+//	f, _ := os.OpenFile("llm-calls.jsonl", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+//	client, err := gollm.NewLLM(
+//	    gollm.SetProvider("openai"),
+//	    gollm.SetDebugRecorder(utils.NewDebugRecorder(f)),
+//	)
+func NewDebugRecorder(w io.Writer) *DebugRecorder {
+	return &DebugRecorder{encoder: json.NewEncoder(w)}
+}
+
+// Record writes a record as one line.
+func (r *DebugRecorder) Record(record DebugRecord) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.encoder.Encode(record)
+}
+
+// Transport returns an HTTP transport that records every request sent through
+// base, or http.DefaultTransport if base is nil. A response is recorded once
+// its body has been read to the end or closed, so streamed responses are
+// recorded whole. Failures to write a record do not fail the request.
+func (r *DebugRecorder) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &recordingTransport{base: base, recorder: r}
+}
+
+type recordingTransport struct {
+	base     http.RoundTripper
+	recorder *DebugRecorder
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	record := DebugRecord{Time: start.UTC(), Method: req.Method, URL: redactURL(req.URL)}
+	if req.Body != nil && req.Body != http.NoBody {
+		var body []byte
+		if req.GetBody != nil {
+			if copied, err := req.GetBody(); err == nil {
+				body, _ = io.ReadAll(copied)
+				copied.Close()
+			}
+		} else {
+			body, _ = io.ReadAll(req.Body)
+			req.Body.Close()
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		record.Request = rawJSON(body)
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		record.Error = err.Error()
+		record.DurationMs = time.Since(start).Milliseconds()
+		t.recorder.Record(record)
+		return nil, err
+	}
+	record.Status = resp.StatusCode
+	resp.Body = &recordingBody{ReadCloser: resp.Body, finish: func(body []byte, err error) {
+		record.Response = string(body)
+		if err != nil {
+			record.Error = err.Error()
+		}
+		record.DurationMs = time.Since(start).Milliseconds()
+		t.recorder.Record(record)
+	}}
+	return resp, nil
+}
+
+// recordingBody keeps a copy of a response body and passes it to finish when
+// the body is read to the end, fails or is closed, whichever comes first.
+type recordingBody struct {
+	io.ReadCloser
+	copy   bytes.Buffer
+	once   sync.Once
+	finish func(body []byte, err error)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.copy.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.finish(b.copy.Bytes(), nil) })
+	} else if err != nil {
+		b.once.Do(func() { b.finish(b.copy.Bytes(), err) })
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.once.Do(func() { b.finish(b.copy.Bytes(), nil) })
+	return b.ReadCloser.Close()
+}
+
+// rawJSON returns body as is if it is JSON, or as a JSON string otherwise.
+func rawJSON(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		return append(json.RawMessage(nil), body...)
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
+
+// redactURL returns u with the values of query parameters that carry API
+// keys, such as Gemini's "key", replaced.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	redacted := false
+	for name := range query {
+		switch strings.ToLower(name) {
+		case "key", "api_key", "apikey", "access_token":
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.String()
+	}
+	copied := *u
+	copied.RawQuery = query.Encode()
+	return copied.String()
+}
+
+// ReadRecording reads the records written by a DebugRecorder, skipping blank
+// lines.
+func ReadRecording(r io.Reader) ([]DebugRecord, error) {
+	var records []DebugRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record DebugRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("failed to parse record on line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	return records, nil
+}
+
+// ReplayRecording starts a local HTTP server that answers requests with the
+// recorded responses, in the order they were recorded and regardless of the
+// request. Calls that failed without a response, such as on a timeout, are
+// replayed by closing the connection. Point a client at the server with
+// config.SetEndpoint to reproduce a recorded failure in a test; the caller
+// must close the server.
+//
+// Example:
+//
+//	f, _ := os.Open("testdata/incident.jsonl")
+//	server, err := utils.ReplayRecording(f)
+//	defer server.Close()
+//	client, err := gollm.NewLLM(gollm.SetProvider("openai"), gollm.SetEndpoint(server.URL), ...)
+func ReplayRecording(r io.Reader) (*httptest.Server, error) {
+	records, err := ReadRecording(r)
+	if err != nil {
+		return nil, err
+	}
+	var mutex sync.Mutex
+	next := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mutex.Lock()
+		if next >= len(records) {
+			mutex.Unlock()
+			http.Error(w, fmt.Sprintf("recording exhausted after %d calls", len(records)), http.StatusInternalServerError)
+			return
+		}
+		record := records[next]
+		next++
+		mutex.Unlock()
+
+		if record.Status == 0 {
+			if hijacker, ok := w.(http.Hijacker); ok {
+				if conn, _, err := hijacker.Hijack(); err == nil {
+					conn.Close()
+					return
+				}
+			}
+			http.Error(w, record.Error, http.StatusBadGateway)
+			return
+		}
+		contentType := "application/json"
+		if trimmed := strings.TrimSpace(record.Response); strings.HasPrefix(trimmed, "data:") || strings.HasPrefix(trimmed, "event:") {
+			contentType = "text/event-stream"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(record.Status)
+		io.WriteString(w, record.Response)
+	})), nil
+}
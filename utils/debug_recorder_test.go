@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *testing.T, client *http.Client, url, body string) (int, string) {
+	t.Helper()
+	resp, err := client.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, string(data)
+}
+
+func TestDebugRecorder(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 2 {
+			http.Error(w, `{"error":"rate limited"}`, http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("data: {\"text\":\"你\"}\n\ndata: {\"text\":\"好\"}\n\n"))
+	}))
+	defer server.Close()
+
+	var recording bytes.Buffer
+	client := &http.Client{Transport: NewDebugRecorder(&recording).Transport(nil)}
+	post(t, client, server.URL+"/v1/chat?key=secret", `{"model":"gpt-4o","stream":true}`)
+	post(t, client, server.URL, "not json")
+
+	records, err := ReadRecording(bytes.NewReader(recording.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "POST", records[0].Method)
+	assert.Equal(t, server.URL+"/v1/chat?key=REDACTED", records[0].URL)
+	assert.JSONEq(t, `{"model":"gpt-4o","stream":true}`, string(records[0].Request))
+	assert.Equal(t, http.StatusOK, records[0].Status)
+	assert.Equal(t, "data: {\"text\":\"你\"}\n\ndata: {\"text\":\"好\"}\n\n", records[0].Response, "streamed responses are recorded whole")
+	assert.Equal(t, `"not json"`, string(records[1].Request))
+	assert.Equal(t, http.StatusTooManyRequests, records[1].Status)
+	assert.NotContains(t, recording.String(), "secret")
+
+	replay, err := ReplayRecording(bytes.NewReader(recording.Bytes()))
+	require.NoError(t, err)
+	defer replay.Close()
+	status, body := post(t, http.DefaultClient, replay.URL, "{}")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, records[0].Response, body)
+	status, body = post(t, http.DefaultClient, replay.URL, "{}")
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Equal(t, records[1].Response, body)
+	status, body = post(t, http.DefaultClient, replay.URL, "{}")
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, body, "recording exhausted after 2 calls")
+}
+
+func TestReplayRecording_TransportError(t *testing.T) {
+	replay, err := ReplayRecording(strings.NewReader(`{"method":"POST","url":"http://example.com","error":"context deadline exceeded"}` + "\n\n"))
+	require.NoError(t, err)
+	defer replay.Close()
+
+	_, err = http.Post(replay.URL, "application/json", strings.NewReader("{}"))
+	assert.Error(t, err, "calls that got no response fail again")
+
+	_, err = ReplayRecording(strings.NewReader("{not json}\n"))
+	assert.ErrorContains(t, err, "failed to parse record on line 1")
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RepairJSON extracts JSON from a raw LLM response and fixes the mistakes
+// models commonly make. It:
+//   - strips byte order marks, markdown code fences and prose around the JSON
+//   - removes trailing commas before closing braces and brackets
+//   - converts single-quoted strings to double-quoted strings
+//   - converts strings delimited by typographic quotes (“ ” ‘ ’) to
+//     double-quoted strings; such quotes inside strings are left unchanged
+//   - closes braces and brackets of a truncated response, when the response
+//     ends between values
+//   - picks the largest valid JSON object or array when there are several
+//
+// Responses that already are valid JSON are returned trimmed but otherwise
+// unchanged. When no valid JSON can be recovered, the error names the byte
+// offset into the extracted JSON text and the snippet around it.
+func RepairJSON(response string) (string, error) {
+	text := strings.TrimSpace(strings.ReplaceAll(response, "\ufeff", ""))
+	if json.Valid([]byte(text)) {
+		return text, nil
+	}
+	fenced := stripCodeFence(text)
+	if json.Valid([]byte(fenced)) {
+		return fenced, nil
+	}
+
+	best, failedErr := extractJSON(fenced)
+	if best == "" && fenced != text {
+		// The first code block may hold something else, such as an example
+		// in another language, with the JSON in a later block or in prose.
+		best, _ = extractJSON(text)
+	}
+	if best != "" {
+		return best, nil
+	}
+	if failedErr != nil {
+		return "", failedErr
+	}
+	return "", fmt.Errorf("no JSON object or array found near %q", jsonSnippet(fenced, 0))
+}
+
+// extractJSON returns the largest valid JSON object or array in text, after
+// repairs. If there is none, it returns the error of the largest candidate
+// that could not be repaired, or nil if text has no candidate at all.
+func extractJSON(text string) (string, error) {
+	var best, failed string
+	var failedErr error
+	for start := 0; start < len(text); {
+		i := strings.IndexAny(text[start:], "{[")
+		if i < 0 {
+			break
+		}
+		i += start
+		raw, end := scanJSONValue(text, i)
+		candidate := repairJSONCandidate(raw)
+		err := checkJSON(candidate)
+		if err != nil && strings.ContainsAny(raw, smartQuotes) {
+			if normalized := repairJSONCandidate(normalizeSmartQuotes(raw)); checkJSON(normalized) == nil {
+				candidate, err = normalized, nil
+			}
+		}
+		if err != nil {
+			if len(candidate) > len(failed) {
+				failed, failedErr = candidate, err
+			}
+			start = i + 1
+			continue
+		}
+		if len(candidate) > len(best) {
+			best = candidate
+		}
+		start = end
+	}
+	if best != "" {
+		return best, nil
+	}
+	return "", failedErr
+}
+
+// smartQuotes are the typographic quotes some models use as JSON delimiters.
+const smartQuotes = "“”‘’"
+
+// normalizeSmartQuotes converts strings delimited by typographic quotes
+// outside ASCII-quoted strings into double-quoted strings, escaping any
+// double quote inside them.
+func normalizeSmartQuotes(raw string) string {
+	var out strings.Builder
+	var quote rune // Delimiter of the current ASCII string
+	var smart rune // Closing delimiter of the current typographic string
+	escaped := false
+	for _, r := range raw {
+		switch {
+		case smart != 0:
+			switch r {
+			case smart:
+				out.WriteByte('"')
+				smart = 0
+			case '"':
+				out.WriteString(`\"`)
+			default:
+				out.WriteRune(r)
+			}
+		case quote != 0:
+			out.WriteRune(r)
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			out.WriteRune(r)
+		case r == '“':
+			smart = '”'
+			out.WriteByte('"')
+		case r == '‘':
+			smart = '’'
+			out.WriteByte('"')
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// stripCodeFence returns the contents of the first markdown code block in
+// text, or text itself if it has none. A block without a closing fence, as
+// in a truncated response, runs to the end of text.
+func stripCodeFence(text string) string {
+	open := strings.Index(text, "```")
+	if open < 0 {
+		return text
+	}
+	body := text[open+3:]
+	if newline := strings.IndexByte(body, '\n'); newline >= 0 {
+		body = body[newline+1:] // Skip the language tag, e.g. "json"
+	} else {
+		body = strings.TrimPrefix(body, "json")
+	}
+	if end := strings.Index(body, "```"); end >= 0 {
+		body = body[:end]
+	}
+	return strings.TrimSpace(body)
+}
+
+// scanJSONValue returns the JSON object or array starting at text[start] and
+// the offset just past it. Strings in either quote style are skipped. If the
+// value is not closed, it runs to the end of text.
+func scanJSONValue(text string, start int) (string, int) {
+	depth := 0
+	var quote byte
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		if quote != 0 {
+			switch c {
+			case '\\':
+				i++
+			case quote:
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote = c
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return text[start : i+1], i + 1
+			}
+		}
+	}
+	return text[start:], len(text)
+}
+
+// repairJSONCandidate fixes quoting, trailing commas and unclosed braces in a
+// JSON value extracted by scanJSONValue.
+func repairJSONCandidate(raw string) string {
+	out := make([]byte, 0, len(raw)+8)
+	var stack []byte
+	var quote byte
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		if quote != 0 {
+			switch {
+			case c == '\\' && i+1 < len(raw):
+				i++
+				if quote == '\'' && raw[i] == '\'' {
+					out = append(out, '\'')
+				} else {
+					out = append(out, c, raw[i])
+				}
+			case c == quote:
+				out = append(out, '"')
+				quote = 0
+			case c == '"':
+				out = append(out, '\\', '"')
+			default:
+				out = append(out, c)
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote = c
+			out = append(out, '"')
+		case '{':
+			stack = append(stack, '}')
+			out = append(out, c)
+		case '[':
+			stack = append(stack, ']')
+			out = append(out, c)
+		case '}', ']':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			out = append(trimTrailingComma(out), c)
+		default:
+			out = append(out, c)
+		}
+	}
+
+	// Close a truncated value, unless it was cut off inside a string or
+	// between a key and its value, where the intended value is unknown.
+	if quote == 0 && len(stack) > 0 {
+		out = trimTrailingComma(out)
+		if len(out) > 0 && out[len(out)-1] != ':' {
+			for i := len(stack) - 1; i >= 0; i-- {
+				out = append(out, stack[i])
+			}
+		}
+	}
+	return string(out)
+}
+
+// trimTrailingComma removes trailing whitespace and a trailing comma.
+func trimTrailingComma(out []byte) []byte {
+	out = []byte(strings.TrimRight(string(out), " \t\r\n"))
+	if len(out) > 0 && out[len(out)-1] == ',' {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// checkJSON reports whether text is valid JSON. Syntax errors include the
+// byte offset and the snippet around it.
+func checkJSON(text string) error {
+	var v interface{}
+	err := json.Unmarshal([]byte(text), &v)
+	if err == nil {
+		return nil
+	}
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+	return fmt.Errorf("invalid JSON at byte %d near %q: %w", offset, jsonSnippet(text, offset), err)
+}
+
+// jsonSnippet returns up to 20 bytes of text on either side of offset.
+func jsonSnippet(text string, offset int64) string {
+	start := int(offset) - 20
+	if start < 0 {
+		start = 0
+	}
+	end := int(offset) + 20
+	if end > len(text) {
+		end = len(text)
+	}
+	if start > end {
+		start = end
+	}
+	return text[start:end]
+}
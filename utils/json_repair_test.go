@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairJSON(t *testing.T) {
+	cases := []struct {
+		name     string
+		response string
+		want     string
+	}{
+		{"valid", `{"a": 1}`, `{"a": 1}`},
+		{"surrounding whitespace", "\n  {\"a\": 1}\n", `{"a": 1}`},
+		{"json fence", "```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"bare fence with prose", "结果如下：\n```\n{\"a\": 1}\n```\n希望对你有帮助。", `{"a": 1}`},
+		{"unclosed fence", "```json\n{\"a\": 1}", `{"a": 1}`},
+		{"leading and trailing prose", `Here is the JSON you asked for: {"name": "Go", "year": 2009}. Let me know!`, `{"name": "Go", "year": 2009}`},
+		{"trailing commas", "{\"tags\": [\"a\", \"b\",], \"n\": 1,\n}", `{"tags": ["a", "b"], "n": 1}`},
+		{"single quotes", `{'name': 'Sarah', 'city': 'Berkeley'}`, `{"name": "Sarah", "city": "Berkeley"}`},
+		{"single quotes with inner quotes", `{'quote': 'she said "hi"', 'it': 'it\'s'}`, `{"quote": "she said \"hi\"", "it": "it's"}`},
+		{"apostrophe in double quotes", `{"text": "it's fine"}`, `{"text": "it's fine"}`},
+		{"truncated object", `{"name": "Go", "tags": ["fast", "simple"`, `{"name": "Go", "tags": ["fast", "simple"]}`},
+		{"truncated after comma", "{\"a\": {\"b\": 1},", `{"a": {"b": 1}}`},
+		{"largest of several objects", `示例：{"a": 1}。实际结果：{"name": "Go", "tags": ["fast"]}`, `{"name": "Go", "tags": ["fast"]}`},
+		{"array", "```json\n[{\"a\": 1}, {\"a\": 2},]\n```", `[{"a": 1}, {"a": 2}]`},
+		{"braces inside strings", `Result: {"pattern": "{x}", "close": "]"} done`, `{"pattern": "{x}", "close": "]"}`},
+		{"prose bracket before object", `[注意] 输出：{"ok": true}`, `{"ok": true}`},
+		{"byte order mark", "\ufeff{\"a\": 1}", `{"a": 1}`},
+		{"byte order mark in fence", "```json\n\ufeff{\"a\": 1}\n```", `{"a": 1}`},
+		{"smart quotes", `{“name”: “Go”, “tags”: [‘fast’]}`, `{"name": "Go", "tags": ["fast"]}`},
+		{"smart quotes with apostrophe and quote", `Result: {“text”: “it’s a "test"”}`, `{"text": "it’s a \"test\""}`},
+		{"smart quotes inside strings kept", `{"quote": "他说“你好”"}`, `{"quote": "他说“你好”"}`},
+		{"smart quotes inside single quotes kept", `{'quote': '“hi”'}`, `{"quote": "“hi”"}`},
+		{"json after non-json fence", "用法：\n```bash\ngollm -h\n```\n结果：\n```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{"explanation after fence", "```json\n{\"score\": 8}\n```\n\n说明：评分依据是 {清晰度} 和 {完整性}。", `{"score": 8}`},
+		{"fenced with trailing prose in fence", "```json\n{\"a\": [1, 2]}\nNote: values are sorted.\n```", `{"a": [1, 2]}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RepairJSON(tc.response)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRepairJSON_Errors(t *testing.T) {
+	_, err := RepairJSON("抱歉，我无法完成这个请求。")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no JSON object or array found")
+
+	// Truncated inside a string: the intended value is unknown.
+	_, err = RepairJSON(`{"name": "Go", "description": "a fast lang`)
+	require.Error(t, err)
+
+	_, err = RepairJSON(`{"name": "Go", "year": two thousand nine}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid JSON at byte 25")
+	assert.Contains(t, err.Error(), `two thousand nine`)
+}
@@ -17,6 +17,8 @@ const (
 	LogLevelDebug
 )
 
+// Logger is the logger gollm writes to. NewSlogLogger and
+// NewDefaultSlogLogger implement it on top of log/slog.
 type Logger interface {
 	Debug(msg string, keysAndValues ...interface{})
 	Info(msg string, keysAndValues ...interface{})
@@ -25,11 +27,21 @@ type Logger interface {
 	SetLevel(level LogLevel)
 }
 
+// DefaultLogger is the Logger returned by NewLogger, writing with the
+// standard log package.
+//
+// Deprecated: Use NewSlogLogger or NewDefaultSlogLogger. DefaultLogger will
+// be removed in the next major version.
 type DefaultLogger struct {
 	logger *log.Logger
 	level  LogLevel
 }
 
+// NewLogger returns a Logger writing the messages of level and above to
+// stderr.
+//
+// Deprecated: Use NewDefaultSlogLogger, or NewSlogLogger with the
+// application's slog handler.
 func NewLogger(level LogLevel) Logger {
 	return &DefaultLogger{
 		logger: log.New(os.Stderr, "", log.LstdFlags),
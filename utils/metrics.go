@@ -0,0 +1,26 @@
+package utils
+
+import "time"
+
+// MetricsCollector records metrics about LLM calls so that metrics backends
+// such as Prometheus can be plugged in without gollm depending on them. See
+// the contrib/gollmprom module for a Prometheus implementation.
+//
+// Implementations must be safe for concurrent use.
+type MetricsCollector interface {
+	// RecordRequest is invoked once when a Generate or Stream call starts.
+	RecordRequest(info CallInfo)
+
+	// RecordLatency is invoked once when a call ends, successfully or not.
+	// The latency includes retries and retry delays.
+	RecordLatency(info CallInfo, latency time.Duration)
+
+	// RecordTokens is invoked when a call ends and the provider reported
+	// token usage.
+	RecordTokens(info CallInfo, inputTokens, outputTokens int)
+
+	// RecordError is invoked when a call fails. errorType is the
+	// llm.LLMError type of the final error, e.g. "RateLimitError", or
+	// "Canceled", "Timeout" or "UnknownError" for other errors.
+	RecordError(info CallInfo, errorType string)
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryStrategy decides whether and when a failed LLM call is retried.
+type RetryStrategy interface {
+	// NextDelay is called after the attempt-th attempt failed with err, starting
+	// at 1. It returns the delay before the next attempt and whether to retry
+	// at all.
+	NextDelay(attempt int, err error) (time.Duration, bool)
+}
+
+// ConstantRetry retries up to MaxRetries times, waiting Delay before each retry.
+type ConstantRetry struct {
+	MaxRetries int
+	Delay      time.Duration
+}
+
+// NextDelay implements RetryStrategy.
+func (r ConstantRetry) NextDelay(attempt int, err error) (time.Duration, bool) {
+	if attempt > r.MaxRetries {
+		return 0, false
+	}
+	return r.Delay, true
+}
+
+// ExponentialBackoff retries up to MaxRetries times. The first retry waits
+// InitialDelay and each following retry waits Multiplier times longer, up to
+// MaxDelay when it is set. Multiplier defaults to 2.
+type ExponentialBackoff struct {
+	MaxRetries   int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+}
+
+// NextDelay implements RetryStrategy.
+func (r ExponentialBackoff) NextDelay(attempt int, err error) (time.Duration, bool) {
+	if attempt > r.MaxRetries {
+		return 0, false
+	}
+	multiplier := r.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(r.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if r.MaxDelay > 0 && delay > float64(r.MaxDelay) {
+		return r.MaxDelay, true
+	}
+	return time.Duration(delay), true
+}
+
+// JitteredExponentialBackoff behaves like ExponentialBackoff but waits a random
+// duration between zero and the exponential delay ("full jitter"), so that
+// clients failing together do not retry together.
+type JitteredExponentialBackoff struct {
+	MaxRetries   int
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+}
+
+// NextDelay implements RetryStrategy.
+func (r JitteredExponentialBackoff) NextDelay(attempt int, err error) (time.Duration, bool) {
+	delay, retry := ExponentialBackoff(r).NextDelay(attempt, err)
+	if !retry || delay <= 0 {
+		return delay, retry
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1)), true
+}
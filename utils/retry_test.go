@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryStrategies(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	constant := ConstantRetry{MaxRetries: 2, Delay: time.Second}
+	delay, retry := constant.NextDelay(1, errFailed)
+	assert.True(t, retry)
+	assert.Equal(t, time.Second, delay)
+	_, retry = constant.NextDelay(3, errFailed)
+	assert.False(t, retry)
+
+	exponential := ExponentialBackoff{MaxRetries: 5, InitialDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	var delays []time.Duration
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay, retry := exponential.NextDelay(attempt, errFailed)
+		assert.True(t, retry)
+		delays = append(delays, delay)
+	}
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}, delays)
+	_, retry = exponential.NextDelay(6, errFailed)
+	assert.False(t, retry)
+
+	jittered := JitteredExponentialBackoff{MaxRetries: 3, InitialDelay: 100 * time.Millisecond, Multiplier: 3}
+	for i := 0; i < 20; i++ {
+		delay, retry := jittered.NextDelay(3, errFailed)
+		assert.True(t, retry)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+		assert.LessOrEqual(t, delay, 900*time.Millisecond)
+	}
+	_, retry = jittered.NextDelay(4, errFailed)
+	assert.False(t, retry)
+}
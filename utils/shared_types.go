@@ -1,6 +1,8 @@
 // File: utils/shared_types.go
 package utils
 
+import "context"
+
 type Function struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description"`
@@ -10,4 +12,25 @@ type Function struct {
 type Tool struct {
 	Type     string   `json:"type"`
 	Function Function `json:"function"`
+
+	// Handler executes the tool when the LLM calls it, for callers that run
+	// tools themselves such as presets.ReActAgent. It is not sent to providers.
+	Handler ToolHandler `json:"-"`
+}
+
+// ToolHandler executes a tool call with the arguments the LLM passed and
+// returns the result shown to the LLM.
+type ToolHandler func(ctx context.Context, arguments map[string]interface{}) (string, error)
+
+// ImageInput is an image sent alongside the prompt text, given either as a URL
+// or as base64-encoded data with its media type (e.g. "image/png").
+type ImageInput struct {
+	URL       string `json:"url,omitempty"`
+	Base64    string `json:"base64,omitempty"`
+	MediaType string `json:"mediaType,omitempty"`
 }
+
+// EmbeddingFunc returns the embedding vector of text, for instance from an
+// embeddings API. Any embedding model can be used, as long as the same one
+// embeds every text compared.
+type EmbeddingFunc func(ctx context.Context, text string) ([]float64, error)
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync/atomic"
+)
+
+// slogAdapter is a Logger writing to a slog.Logger. Debug, Info, Warn and
+// Error map to the slog levels of the same names.
+type slogAdapter struct {
+	logger *slog.Logger
+	level  slog.LevelVar // Records below level are dropped before the handler sees them
+	off    atomic.Bool   // Whether SetLevel(LogLevelOff) was called
+}
+
+// NewSlogLogger returns a Logger writing to h, so gollm logs through the
+// application's own slog handler. The handler decides which records to keep
+// until SetLevel is called, which then also drops the records below the
+// given level.
+//
+// Example:
+//
+//	logger := utils.NewSlogLogger(slog.NewJSONHandler(os.Stderr, nil))
+func NewSlogLogger(h slog.Handler) Logger {
+	a := &slogAdapter{logger: slog.New(h)}
+	a.level.Set(slog.LevelDebug)
+	return a
+}
+
+// NewDefaultSlogLogger returns a Logger writing slog text records of level
+// and above to stderr.
+//
+// Example:
+//
+//	memory, err := llm.NewMemory(4000, "gpt-4o", utils.NewDefaultSlogLogger(slog.LevelInfo))
+func NewDefaultSlogLogger(level slog.Level) Logger {
+	a := &slogAdapter{logger: slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+	a.level.Set(level)
+	return a
+}
+
+// SetLevel drops the records below level; LogLevelOff drops them all.
+func (a *slogAdapter) SetLevel(level LogLevel) {
+	a.off.Store(level == LogLevelOff)
+	if level != LogLevelOff {
+		a.level.Set(slogLevel(level))
+	}
+}
+
+func (a *slogAdapter) log(level slog.Level, msg string, keysAndValues ...interface{}) {
+	if a.off.Load() || level < a.level.Level() {
+		return
+	}
+	a.logger.Log(context.Background(), level, msg, keysAndValues...)
+}
+
+func (a *slogAdapter) Debug(msg string, keysAndValues ...interface{}) {
+	a.log(slog.LevelDebug, msg, keysAndValues...)
+}
+
+func (a *slogAdapter) Info(msg string, keysAndValues ...interface{}) {
+	a.log(slog.LevelInfo, msg, keysAndValues...)
+}
+
+func (a *slogAdapter) Warn(msg string, keysAndValues ...interface{}) {
+	a.log(slog.LevelWarn, msg, keysAndValues...)
+}
+
+func (a *slogAdapter) Error(msg string, keysAndValues ...interface{}) {
+	a.log(slog.LevelError, msg, keysAndValues...)
+}
+
+// slogLevel returns the slog level of a LogLevel other than LogLevelOff.
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LogLevelError:
+		return slog.LevelError
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelInfo:
+		return slog.LevelInfo
+	default:
+		return slog.LevelDebug
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	logger.Debug("dropped by the handler")
+	logger.Info("Sending request", "provider", "openai", "attempt", 1)
+	logger.Error("Request failed", "error", "timeout")
+	assert.Equal(t, "level=INFO msg=\"Sending request\" provider=openai attempt=1\nlevel=ERROR msg=\"Request failed\" error=timeout\n", buf.String())
+
+	buf.Reset()
+	logger.SetLevel(LogLevelWarn)
+	logger.Info("dropped by the level")
+	logger.Warn("Retrying")
+	assert.Equal(t, "level=WARN msg=Retrying\n", buf.String())
+
+	buf.Reset()
+	logger.SetLevel(LogLevelOff)
+	logger.Error("dropped while off")
+	assert.Empty(t, buf.String())
+
+	logger.SetLevel(LogLevelDebug)
+	logger.Debug("still dropped by the handler")
+	logger.Error("Request failed")
+	assert.Equal(t, "level=ERROR msg=\"Request failed\"\n", buf.String())
+}
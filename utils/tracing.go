@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// Tracer observes LLM calls so that tracing backends such as OpenTelemetry can
+// be plugged in without gollm depending on them. See the contrib/gollmotel
+// module for an OpenTelemetry implementation.
+type Tracer interface {
+	// StartCall is invoked before a Generate or Stream call. The returned
+	// context is used for the call itself, so spans started here nest under
+	// the caller's spans and propagate to outgoing requests.
+	StartCall(ctx context.Context, info CallInfo) (context.Context, CallSpan)
+}
+
+// CallSpan is the handle for one traced call returned by Tracer.StartCall.
+type CallSpan interface {
+	// End is called exactly once when the call has finished.
+	End(result CallResult)
+}
+
+// CallInfo describes a call when it starts.
+type CallInfo struct {
+	Operation string // "generate", "generate_with_schema" or "stream"
+	Provider  string // Provider name, e.g. "openai"
+	Model     string // Model used for the call, including per-call overrides
+	Shadow    bool   // True for shadow traffic mirrored to a candidate model
+}
+
+// CallResult describes a call when it ends.
+type CallResult struct {
+	Attempts     int           // Number of attempts made, including retries
+	Latency      time.Duration // Total duration of the call, including retry delays
+	InputTokens  int           // Prompt tokens reported by the provider, 0 if unknown
+	OutputTokens int           // Completion tokens reported by the provider, 0 if unknown
+	Err          error         // Final error, nil on success
+
+	CacheCreationInputTokens int // Prompt tokens written to the provider's prompt cache
+	CacheReadInputTokens     int // Prompt tokens read from the provider's prompt cache
+
+	// SystemFingerprint identifies the backend configuration that served the
+	// call, as reported by OpenAI-compatible providers; empty if unknown.
+	SystemFingerprint string
+}
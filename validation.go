@@ -81,10 +81,12 @@ func Validate(s interface{}) error {
 //
 // Parameters:
 //   - v: The struct to generate schema for. Must be a pointer to a struct.
+//   - opts: Optional schema options such as WithFieldDescription, WithFieldEnum,
+//     WithFieldExample and WithDefs
 //
 // Returns:
 //   - []byte: The generated JSON schema as a byte slice
 //   - error: Any error encountered during schema generation
-func GenerateJSONSchema(v interface{}) ([]byte, error) {
-	return llm.GenerateJSONSchema(v)
+func GenerateJSONSchema(v interface{}, opts ...SchemaOption) ([]byte, error) {
+	return llm.GenerateJSONSchema(v, opts...)
 }